@@ -17,9 +17,11 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	sharelink "github.com/router-for-me/CLIProxyAPI/v6/internal/access/share_link"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/configsync"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
@@ -51,6 +53,11 @@ func init() {
 // It parses command-line flags, loads configuration, and starts the appropriate
 // service based on the provided flags (login, codex-login, or server mode).
 func main() {
+	// Operator subcommands (auth, proxy, usage, bench, mcp) run instead of the server.
+	if len(os.Args) > 1 && cmd.IsSubcommand(os.Args[1]) {
+		os.Exit(cmd.RunSubcommand(os.Args[1:], DefaultConfigPath))
+	}
+
 	fmt.Printf("CLIProxyAPI Version: %s, Commit: %s, BuiltAt: %s\n", buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate)
 
 	// Command-line flags to control the application's behavior.
@@ -88,6 +95,7 @@ func main() {
 	flag.CommandLine.Usage = func() {
 		out := flag.CommandLine.Output()
 		_, _ = fmt.Fprintf(out, "Usage of %s\n", os.Args[0])
+		_, _ = fmt.Fprintf(out, "  %s auth|proxy|usage|bench|mcp <command> [flags]\n    Operator subcommands; run \"%s auth\" for details\n", os.Args[0], os.Args[0])
 		flag.CommandLine.VisitAll(func(f *flag.Flag) {
 			if f.Name == "password" {
 				return
@@ -139,6 +147,8 @@ func main() {
 		objectStoreBucket    string
 		objectStoreLocalPath string
 		objectStoreInst      *store.ObjectTokenStore
+		configSyncOpts       configsync.Options
+		configSyncer         *configsync.Syncer
 	)
 
 	wd, err := os.Getwd()
@@ -214,6 +224,42 @@ func main() {
 	if value, ok := lookupEnv("OBJECTSTORE_LOCAL_PATH", "objectstore_local_path"); ok {
 		objectStoreLocalPath = value
 	}
+	if value, ok := lookupEnv("CONFIGSYNC_GIT_URL", "configsync_git_url"); ok {
+		configSyncOpts.GitURL = value
+	} else if value, ok = lookupEnv("CONFIGSYNC_URL", "configsync_url"); ok {
+		configSyncOpts.URL = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_BRANCH", "configsync_branch"); ok {
+		configSyncOpts.Branch = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_CONFIG_PATH", "configsync_config_path"); ok {
+		configSyncOpts.ConfigPath = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_AUTH_PATH", "configsync_auth_path"); ok {
+		configSyncOpts.AuthPath = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_USERNAME", "configsync_username"); ok {
+		configSyncOpts.Username = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_TOKEN", "configsync_token"); ok {
+		configSyncOpts.Token = value
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_INTERVAL", "configsync_interval"); ok {
+		interval, errParse := time.ParseDuration(value)
+		if errParse != nil {
+			log.Errorf("invalid CONFIGSYNC_INTERVAL %q: %v", value, errParse)
+			return
+		}
+		configSyncOpts.Interval = interval
+	}
+	if value, ok := lookupEnv("CONFIGSYNC_LOCAL_PATH", "configsync_local_path"); ok {
+		configSyncOpts.LocalDir = value
+	}
+	if configSyncOpts.GitURL != "" || configSyncOpts.URL != "" {
+		// The synced config is the only source of changes: management writes would be
+		// overwritten by the next sync, and the proxy must not rewrite the config itself.
+		config.ForceReadOnly()
+	}
 
 	// Check for cloud deploy mode only on first execution
 	// Read env var name in uppercase: DEPLOY
@@ -386,6 +432,41 @@ func main() {
 		cfg = &config.Config{}
 	}
 
+	// Pull the reviewed config before anything reads it; the background loop starts with the service.
+	if configSyncOpts.GitURL != "" || configSyncOpts.URL != "" {
+		if configSyncOpts.LocalDir == "" {
+			if writableBase != "" {
+				configSyncOpts.LocalDir = filepath.Join(writableBase, "configsync")
+			} else {
+				configSyncOpts.LocalDir = filepath.Join(wd, "configsync")
+			}
+		}
+		configSyncOpts.TargetConfig = configFilePath
+		// Token stores keep auth files in a directory of their own; otherwise synced auth files
+		// follow the auth-dir of the synced config.
+		if usePostgresStore || useObjectStore || useGitStore {
+			if configSyncOpts.AuthDir, err = util.ResolveAuthDir(cfg.AuthDir); err != nil {
+				log.Errorf("failed to resolve auth directory: %v", err)
+				return
+			}
+		}
+		configSyncer = configsync.New(configSyncOpts)
+		applied, errSync := configSyncer.Sync(context.Background())
+		if errSync != nil {
+			log.Errorf("config sync: %v", errSync)
+		}
+		if applied {
+			authDir := cfg.AuthDir
+			if cfg, err = config.LoadConfigOptional(configFilePath, isCloudDeploy); err != nil {
+				log.Errorf("failed to load synced config: %v", err)
+				return
+			}
+			if usePostgresStore || useObjectStore || useGitStore {
+				cfg.AuthDir = authDir
+			}
+		}
+	}
+
 	// In cloud deploy mode, check if we have a valid configuration
 	var configFileExists bool
 	if isCloudDeploy {
@@ -446,6 +527,8 @@ func main() {
 
 	// Register built-in access providers before constructing services.
 	configaccess.Register()
+	sharelink.Register()
+	sharelink.SetUsageFile(filepath.Join(filepath.Dir(configFilePath), ".share-link-usage.json"))
 
 	// Handle different command modes based on the provided flags.
 
@@ -481,6 +564,9 @@ func main() {
 			return
 		}
 		// Start the main proxy service
+		if configSyncer != nil {
+			go configSyncer.Run(context.Background())
+		}
 		managementasset.StartAutoUpdater(context.Background(), configFilePath)
 		cmd.StartService(cfg, configFilePath, password)
 	}
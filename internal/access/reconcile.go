@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	sharelink "github.com/router-for-me/CLIProxyAPI/v6/internal/access/share_link"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkConfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
@@ -183,6 +184,9 @@ func accessProviderMap(cfg *config.Config) map[string]*sdkConfig.AccessProvider
 			}
 		}
 	}
+	if provider := sharelink.MakeProviderConfig(cfg.ShareLinks); provider != nil {
+		result[providerIdentifier(provider)] = provider
+	}
 	return result
 }
 
@@ -202,6 +206,9 @@ func collectProviderEntries(cfg *config.Config) []*sdkConfig.AccessProvider {
 			entries = append(entries, inline)
 		}
 	}
+	if links := sharelink.MakeProviderConfig(cfg.ShareLinks); links != nil {
+		entries = append(entries, links)
+	}
 	return entries
 }
 
@@ -209,7 +216,7 @@ func inlineAPIKeyProvider(cfg *config.Config) *sdkConfig.AccessProvider {
 	if cfg == nil || len(cfg.APIKeys) == 0 {
 		return nil
 	}
-	provider := sdkConfig.MakeInlineAPIKeyProvider(cfg.APIKeys)
+	provider := sdkConfig.MakeInlineAPIKeyProvider(cfg.EnabledAPIKeys())
 	if provider == nil {
 		return nil
 	}
@@ -0,0 +1,259 @@
+// Package sharelink implements the access provider for temporary share links: client keys
+// that expire at a fixed time and accept a limited number of requests.
+package sharelink
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var registerOnce sync.Once
+
+// Register ensures the share link provider is available to the access manager.
+func Register() {
+	registerOnce.Do(func() {
+		sdkaccess.RegisterProvider(sdkconfig.AccessProviderTypeShareLink, newProvider)
+	})
+}
+
+// counters tracks how many model requests each share link has made, keyed by the SHA-256 of
+// the link key. It lives outside the provider so counts survive provider rebuilds on
+// configuration reloads, and in the usage file, when set, so they survive restarts.
+var counters = struct {
+	mu   sync.Mutex
+	used map[string]int
+	path string
+}{used: make(map[string]int)}
+
+// SetUsageFile keeps the request counts in path, next to the configuration, and loads the counts
+// saved there. Counts already made by this process are kept when they are higher.
+func SetUsageFile(path string) {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+	counters.path = path
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Warnf("share links: failed to read usage file %s: %v", path, err)
+		}
+		return
+	}
+	var saved map[string]int
+	if err = json.Unmarshal(data, &saved); err != nil {
+		log.Warnf("share links: failed to parse usage file %s: %v", path, err)
+		return
+	}
+	for id, used := range saved {
+		if used > counters.used[id] {
+			counters.used[id] = used
+		}
+	}
+}
+
+// Used returns the number of model requests made with the share link key.
+func Used(key string) int {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+	return counters.used[counterID(key)]
+}
+
+// Forget drops the request counter of a deleted share link.
+func Forget(key string) {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+	delete(counters.used, counterID(key))
+	saveCountersLocked()
+}
+
+// admit reports whether key still has requests left under limit (when positive), recording one
+// request when count is set.
+func admit(key string, limit int, count bool) bool {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+	id := counterID(key)
+	if limit > 0 && counters.used[id] >= limit {
+		return false
+	}
+	if count {
+		counters.used[id]++
+		saveCountersLocked()
+	}
+	return true
+}
+
+// saveCountersLocked writes the counts to the usage file through a rename, so a crash never
+// leaves a partial file. Counts are saved on every request so limits hold after a crash.
+func saveCountersLocked() {
+	if counters.path == "" {
+		return
+	}
+	data, err := json.Marshal(counters.used)
+	if err != nil {
+		return
+	}
+	tmp := counters.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err == nil {
+		err = os.Rename(tmp, counters.path)
+	}
+	if err != nil {
+		log.Warnf("share links: failed to save usage file %s: %v", counters.path, err)
+	}
+}
+
+func counterID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// executesModel reports whether r asks a model for output. Only such requests count against a
+// share link's limit; model listings, token counts and assistant or thread bookkeeping do not.
+func executesModel(r *http.Request) bool {
+	if r.Method != http.MethodPost || r.URL == nil {
+		return false
+	}
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/count_tokens"), strings.HasSuffix(path, ":countTokens"), strings.HasSuffix(path, "/cancel"):
+		return false
+	case strings.HasPrefix(path, "/v1/assistants"), strings.HasPrefix(path, "/v1/threads"):
+		return strings.Contains(path, "/runs")
+	}
+	return true
+}
+
+type link struct {
+	expiresAt   time.Time
+	maxRequests int
+}
+
+type provider struct {
+	name  string
+	links map[string]link
+	now   func() time.Time
+}
+
+// MakeProviderConfig builds the access provider configuration for links.
+// It returns nil when there are no links.
+func MakeProviderConfig(links []sdkconfig.ShareLink) *sdkconfig.AccessProvider {
+	if len(links) == 0 {
+		return nil
+	}
+	return &sdkconfig.AccessProvider{
+		Name:   sdkconfig.AccessProviderTypeShareLink,
+		Type:   sdkconfig.AccessProviderTypeShareLink,
+		Config: map[string]any{"links": append([]sdkconfig.ShareLink(nil), links...)},
+	}
+}
+
+func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+	name := cfg.Name
+	if name == "" {
+		name = sdkconfig.AccessProviderTypeShareLink
+	}
+	links := make(map[string]link)
+	entries, _ := cfg.Config["links"].([]sdkconfig.ShareLink)
+	for _, entry := range entries {
+		if entry.Key == "" {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, entry.ExpiresAt)
+		if err != nil {
+			continue
+		}
+		links[entry.Key] = link{expiresAt: expiresAt, maxRequests: entry.MaxRequests}
+	}
+	return &provider{name: name, links: links, now: time.Now}, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return sdkconfig.AccessProviderTypeShareLink
+	}
+	return p.name
+}
+
+// Authenticate accepts share link keys that have not expired and still have requests left.
+// Every accepted model request counts against the link's limit. Used-up links are reported as
+// expired.
+func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if p == nil || len(p.links) == 0 {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	for _, candidate := range credentialCandidates(r) {
+		if candidate.value == "" {
+			continue
+		}
+		entry, ok := p.links[candidate.value]
+		if !ok {
+			continue
+		}
+		if !entry.expiresAt.After(p.now()) {
+			return nil, sdkaccess.ErrExpiredCredential
+		}
+		if !admit(candidate.value, entry.maxRequests, executesModel(r)) {
+			return nil, sdkaccess.ErrExpiredCredential
+		}
+		metadata := map[string]string{"source": candidate.source}
+		if entry.maxRequests > 0 {
+			metadata["remaining-requests"] = strconv.Itoa(entry.maxRequests - Used(candidate.value))
+		}
+		return &sdkaccess.Result{
+			Provider:  p.Identifier(),
+			Principal: candidate.value,
+			Metadata:  metadata,
+		}, nil
+	}
+	return nil, sdkaccess.ErrNotHandled
+}
+
+type credential struct {
+	value  string
+	source string
+}
+
+func credentialCandidates(r *http.Request) []credential {
+	candidates := []credential{
+		{extractBearerToken(r.Header.Get("Authorization")), "authorization"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+	}
+	if r.URL != nil {
+		query := r.URL.Query()
+		candidates = append(candidates,
+			credential{query.Get("key"), "query-key"},
+			credential{query.Get("auth_token"), "query-auth-token"},
+		)
+	}
+	return candidates
+}
+
+func extractBearerToken(header string) string {
+	if header == "" {
+		return ""
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return header
+	}
+	if strings.ToLower(parts[0]) != "bearer" {
+		return header
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,116 @@
+package sharelink
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func authenticate(t *testing.T, p sdkaccess.Provider, key string) (*sdkaccess.Result, error) {
+	t.Helper()
+	return authenticateRequest(t, p, key, http.MethodPost, "/v1/chat/completions")
+}
+
+func authenticateRequest(t *testing.T, p sdkaccess.Provider, key, method, path string) (*sdkaccess.Result, error) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	return p.Authenticate(req.Context(), req)
+}
+
+func TestProviderEnforcesLimitAndExpiry(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	cfg := MakeProviderConfig([]sdkconfig.ShareLink{
+		{Key: "share-limited", MaxRequests: 2, ExpiresAt: future},
+		{Key: "share-expired", ExpiresAt: past},
+	})
+	t.Cleanup(func() { Forget("share-limited") })
+	p, err := newProvider(cfg, nil)
+	if err != nil {
+		t.Fatalf("newProvider: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		res, errAuth := authenticate(t, p, "share-limited")
+		if errAuth != nil {
+			t.Fatalf("request %d: %v", i+1, errAuth)
+		}
+		if res.Principal != "share-limited" || res.Provider != sdkconfig.AccessProviderTypeShareLink {
+			t.Fatalf("unexpected result %+v", res)
+		}
+	}
+	if _, err = authenticate(t, p, "share-limited"); !errors.Is(err, sdkaccess.ErrExpiredCredential) {
+		t.Fatalf("used-up link error = %v", err)
+	}
+	if Used("share-limited") != 2 {
+		t.Fatalf("used = %d", Used("share-limited"))
+	}
+	if _, err = authenticate(t, p, "share-expired"); !errors.Is(err, sdkaccess.ErrExpiredCredential) {
+		t.Fatalf("expired link error = %v", err)
+	}
+	if _, err = authenticate(t, p, "other-key"); !errors.Is(err, sdkaccess.ErrNotHandled) {
+		t.Fatalf("unknown key error = %v", err)
+	}
+}
+
+func TestProviderCountsOnlyModelRequestsAndPersistsCounts(t *testing.T) {
+	usageFile := filepath.Join(t.TempDir(), ".share-link-usage.json")
+	SetUsageFile(usageFile)
+	t.Cleanup(func() {
+		SetUsageFile("")
+		Forget("share-persisted")
+	})
+	cfg := MakeProviderConfig([]sdkconfig.ShareLink{
+		{Key: "share-persisted", MaxRequests: 2, ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)},
+	})
+	p, err := newProvider(cfg, nil)
+	if err != nil {
+		t.Fatalf("newProvider: %v", err)
+	}
+
+	for _, path := range []string{"/v1/models", "/v1beta/models"} {
+		if _, err = authenticateRequest(t, p, "share-persisted", http.MethodGet, path); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+	}
+	if _, err = authenticateRequest(t, p, "share-persisted", http.MethodPost, "/v1/messages/count_tokens"); err != nil {
+		t.Fatalf("count_tokens: %v", err)
+	}
+	if _, err = authenticate(t, p, "share-persisted"); err != nil {
+		t.Fatalf("model request: %v", err)
+	}
+	if Used("share-persisted") != 1 {
+		t.Fatalf("used = %d, want only the model request counted", Used("share-persisted"))
+	}
+
+	// A restart starts with empty counters and reloads them from the usage file.
+	counters.mu.Lock()
+	counters.used = make(map[string]int)
+	counters.mu.Unlock()
+	SetUsageFile(usageFile)
+	if Used("share-persisted") != 1 {
+		t.Fatalf("used after reload = %d, want 1", Used("share-persisted"))
+	}
+	data, err := os.ReadFile(usageFile)
+	if err != nil {
+		t.Fatalf("read usage file: %v", err)
+	}
+	if strings.Contains(string(data), "share-persisted") {
+		t.Fatalf("usage file stores the link key: %s", data)
+	}
+	if _, err = authenticate(t, p, "share-persisted"); err != nil {
+		t.Fatalf("second model request: %v", err)
+	}
+	if _, err = authenticate(t, p, "share-persisted"); !errors.Is(err, sdkaccess.ErrExpiredCredential) {
+		t.Fatalf("used-up link error = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package management
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+// VerifyAuditChain checks the configured audit chain file and reports whether it is intact,
+// answering 404 when no audit chain is configured or written yet.
+func (h *Handler) VerifyAuditChain(c *gin.Context) {
+	h.mu.Lock()
+	path := h.cfg.AuditChain.File
+	h.mu.Unlock()
+	if path == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit chain not configured"})
+		return
+	}
+	status, err := logging.VerifyAuditChain(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "audit chain not written yet"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audit-chain": status})
+}
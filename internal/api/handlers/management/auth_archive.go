@@ -0,0 +1,218 @@
+package management
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// archiveTimeLayout prefixes archived file names with the deletion time, keeping the
+// original file name recoverable: "<time>-<name>".
+const archiveTimeLayout = "20060102T150405Z"
+
+// ArchivedAuthFile describes an auth file kept in the archive after deletion.
+type ArchivedAuthFile struct {
+	// ID is the archived file name, used to restore or purge the entry.
+	ID string `json:"id"`
+	// Name is the original auth file name.
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	DeletedAt time.Time `json:"deleted-at"`
+	PurgeAt   time.Time `json:"purge-at"`
+}
+
+func (h *Handler) authArchiveDir() string {
+	dir := h.cfg.AuthArchive.ArchiveDir(h.cfg.AuthDir)
+	if resolved, err := util.ResolveAuthDir(dir); err == nil {
+		return resolved
+	}
+	return dir
+}
+
+// removeAuthFile deletes the auth file at path, or moves it to the archive when archiving is
+// enabled and permanent is false. It returns the archive ID of a moved file.
+func (h *Handler) removeAuthFile(path string, permanent bool) (string, error) {
+	if permanent || !h.cfg.AuthArchive.Enabled {
+		return "", os.Remove(path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	dir := h.authArchiveDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create archive dir: %w", err)
+	}
+	h.purgeExpiredArchives()
+	id := time.Now().UTC().Format(archiveTimeLayout) + "-" + filepath.Base(path)
+	if err := moveFile(path, filepath.Join(dir, id)); err != nil {
+		return "", fmt.Errorf("failed to archive file: %w", err)
+	}
+	return id, nil
+}
+
+// ListArchivedAuthFiles lists archived auth files that are still within the retention period.
+func (h *Handler) ListArchivedAuthFiles(c *gin.Context) {
+	h.purgeExpiredArchives()
+	files, err := h.archivedAuthFiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read archive dir: %v", err)})
+		return
+	}
+	writeListJSON(c, "files", files)
+}
+
+// RestoreAuthFile moves an archived auth file back into the auth directory and registers it.
+func (h *Handler) RestoreAuthFile(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	id := c.Query("id")
+	name, _, ok := parseArchiveID(id)
+	if !ok || strings.Contains(id, string(os.PathSeparator)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	src := filepath.Join(h.authArchiveDir(), id)
+	dst := filepath.Join(h.cfg.AuthDir, name)
+	if !filepath.IsAbs(dst) {
+		if abs, errAbs := filepath.Abs(dst); errAbs == nil {
+			dst = abs
+		}
+	}
+	if _, err := os.Stat(dst); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("auth file %s already exists", name)})
+		return
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "archived file not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read archived file: %v", err)})
+		}
+		return
+	}
+	if err = moveFile(src, dst); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to restore file: %v", err)})
+		return
+	}
+	if err = h.registerAuthFromFile(c.Request.Context(), dst, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "name": name})
+}
+
+// DeleteArchivedAuthFile permanently removes an archived auth file.
+func (h *Handler) DeleteArchivedAuthFile(c *gin.Context) {
+	id := c.Query("id")
+	if _, _, ok := parseArchiveID(id); !ok || strings.Contains(id, string(os.PathSeparator)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := os.Remove(filepath.Join(h.authArchiveDir(), id)); err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "archived file not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to remove file: %v", err)})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Handler) archivedAuthFiles() ([]ArchivedAuthFile, error) {
+	entries, err := os.ReadDir(h.authArchiveDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ArchivedAuthFile{}, nil
+		}
+		return nil, err
+	}
+	retention := h.cfg.AuthArchive.Retention()
+	files := make([]ArchivedAuthFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name, deletedAt, ok := parseArchiveID(entry.Name())
+		if !ok {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil {
+			continue
+		}
+		files = append(files, ArchivedAuthFile{
+			ID:        entry.Name(),
+			Name:      name,
+			Size:      info.Size(),
+			DeletedAt: deletedAt,
+			PurgeAt:   deletedAt.Add(retention),
+		})
+	}
+	return files, nil
+}
+
+// purgeExpiredArchives removes archived files older than the retention period.
+func (h *Handler) purgeExpiredArchives() {
+	files, err := h.archivedAuthFiles()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, file := range files {
+		if file.PurgeAt.After(now) {
+			continue
+		}
+		if errRemove := os.Remove(filepath.Join(h.authArchiveDir(), file.ID)); errRemove != nil && !os.IsNotExist(errRemove) {
+			log.WithError(errRemove).Warnf("failed to purge archived auth file %s", file.ID)
+		}
+	}
+}
+
+// parseArchiveID splits an archived file name into the original name and deletion time.
+func parseArchiveID(id string) (name string, deletedAt time.Time, ok bool) {
+	stamp, name, found := strings.Cut(id, "-")
+	if !found || name == "" || !strings.HasSuffix(strings.ToLower(name), ".json") {
+		return "", time.Time{}, false
+	}
+	deletedAt, err := time.Parse(archiveTimeLayout, stamp)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return name, deletedAt, true
+}
+
+// moveFile renames src to dst, copying across file systems when a rename is not possible.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		_ = in.Close()
+		return err
+	}
+	_, errCopy := io.Copy(out, in)
+	errs := errors.Join(errCopy, out.Close(), in.Close())
+	if errs != nil {
+		_ = os.Remove(dst)
+		return errs
+	}
+	return os.Remove(src)
+}
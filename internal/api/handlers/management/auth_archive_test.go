@@ -0,0 +1,102 @@
+package management
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestDeleteAuthFile_ArchivesAndRestores(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	root := t.TempDir()
+	authDir := filepath.Join(root, "auths")
+	if err := os.MkdirAll(authDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	content := []byte(`{"type":"codex","email":"user@example.com"}`)
+	if err := os.WriteFile(filepath.Join(authDir, "codex-user.json"), content, 0o600); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	h := &Handler{
+		cfg:         &config.Config{AuthDir: authDir, AuthArchive: config.AuthArchiveConfig{Enabled: true}},
+		authManager: coreauth.NewManager(&memoryAuthStore{}, nil, nil),
+		tokenStore:  &memoryAuthStore{},
+	}
+	router := gin.New()
+	router.DELETE("/auth-files", h.DeleteAuthFile)
+	router.GET("/auth-files/archive", h.ListArchivedAuthFiles)
+	router.POST("/auth-files/archive/restore", h.RestoreAuthFile)
+
+	rec := doManagementRequest(router, http.MethodDelete, "/auth-files?name=codex-user.json", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"archived"`) {
+		t.Fatalf("delete = %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "codex-user.json")); !os.IsNotExist(err) {
+		t.Fatalf("auth file still present: %v", err)
+	}
+	files, err := h.archivedAuthFiles()
+	if err != nil || len(files) != 1 || files[0].Name != "codex-user.json" {
+		t.Fatalf("archive = %+v, %v", files, err)
+	}
+	if h.authArchiveDir() != authDir+"-archive" {
+		t.Fatalf("archive dir = %s", h.authArchiveDir())
+	}
+
+	rec = doManagementRequest(router, http.MethodPost, "/auth-files/archive/restore?id="+files[0].ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore = %d %s", rec.Code, rec.Body.String())
+	}
+	restored, err := os.ReadFile(filepath.Join(authDir, "codex-user.json"))
+	if err != nil || string(restored) != string(content) {
+		t.Fatalf("restored = %q, %v", restored, err)
+	}
+	if _, ok := h.authManager.GetByID("codex-user.json"); !ok {
+		t.Fatal("restored auth not registered")
+	}
+	if rec = doManagementRequest(router, http.MethodPost, "/auth-files/archive/restore?id="+files[0].ID, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("restore over existing file = %d", rec.Code)
+	}
+
+	rec = doManagementRequest(router, http.MethodDelete, "/auth-files?name=codex-user.json&permanent=true", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"archived"`) {
+		t.Fatalf("permanent delete = %d %s", rec.Code, rec.Body.String())
+	}
+	if files, _ = h.archivedAuthFiles(); len(files) != 0 {
+		t.Fatalf("permanent delete archived %+v", files)
+	}
+}
+
+func TestPurgeExpiredArchives(t *testing.T) {
+	root := t.TempDir()
+	h := &Handler{cfg: &config.Config{
+		AuthDir:     filepath.Join(root, "auths"),
+		AuthArchive: config.AuthArchiveConfig{Enabled: true, Dir: filepath.Join(root, "archive"), RetentionDays: 7},
+	}}
+	if err := os.MkdirAll(h.authArchiveDir(), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	old := time.Now().UTC().Add(-8*24*time.Hour).Format(archiveTimeLayout) + "-old.json"
+	recent := time.Now().UTC().Add(-time.Hour).Format(archiveTimeLayout) + "-recent.json"
+	for _, name := range []string{old, recent, "unrelated.txt"} {
+		if err := os.WriteFile(filepath.Join(h.authArchiveDir(), name), []byte("{}"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	h.purgeExpiredArchives()
+
+	files, err := h.archivedAuthFiles()
+	if err != nil || len(files) != 1 || files[0].Name != "recent.json" {
+		t.Fatalf("archive after purge = %+v, %v", files, err)
+	}
+	if _, err = os.Stat(filepath.Join(h.authArchiveDir(), "unrelated.txt")); err != nil {
+		t.Fatalf("purge removed unrelated file: %v", err)
+	}
+}
@@ -302,7 +302,7 @@ func (h *Handler) ListAuthFiles(c *gin.Context) {
 		nameJ, _ := files[j]["name"].(string)
 		return strings.ToLower(nameI) < strings.ToLower(nameJ)
 	})
-	c.JSON(200, gin.H{"files": files})
+	writeListJSON(c, "files", files)
 }
 
 // GetAuthFileModels returns the models supported by a specific auth file
@@ -497,7 +497,7 @@ func (h *Handler) listAuthFilesFromDisk(c *gin.Context) {
 			files = append(files, fileData)
 		}
 	}
-	c.JSON(200, gin.H{"files": files})
+	writeListJSON(c, "files", files)
 }
 
 func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
@@ -801,6 +801,7 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 		return
 	}
 	ctx := c.Request.Context()
+	permanent := c.Query("permanent") == "true" || c.Query("permanent") == "1"
 	if all := c.Query("all"); all == "true" || all == "1" || all == "*" {
 		entries, err := os.ReadDir(h.cfg.AuthDir)
 		if err != nil {
@@ -822,7 +823,7 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 					full = abs
 				}
 			}
-			if err = os.Remove(full); err == nil {
+			if _, err = h.removeAuthFile(full, permanent); err == nil {
 				if errDel := h.deleteTokenRecord(ctx, full); errDel != nil {
 					c.JSON(500, gin.H{"error": errDel.Error()})
 					return
@@ -846,7 +847,8 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 			full = abs
 		}
 	}
-	if err := os.Remove(full); err != nil {
+	archived, err := h.removeAuthFile(full, permanent)
+	if err != nil {
 		if os.IsNotExist(err) {
 			c.JSON(404, gin.H{"error": "file not found"})
 		} else {
@@ -854,12 +856,16 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 		}
 		return
 	}
-	if err := h.deleteTokenRecord(ctx, full); err != nil {
+	if err = h.deleteTokenRecord(ctx, full); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 	h.disableAuth(ctx, full)
 	h.cleanupAuthMappings("", "", filepath.Base(full), full)
+	if archived != "" {
+		c.JSON(200, gin.H{"status": "ok", "archived": archived})
+		return
+	}
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
@@ -1098,6 +1104,12 @@ func matchRemovedAuthRef(candidates []string, refs []string) []string {
 		if ref == "" {
 			continue
 		}
+		// Pattern, provider and tag references outlive any single auth file. Exclusions are
+		// kept too: dropping the last one would turn the entry into deny-all.
+		if coreauth.IsAuthRefPattern(ref) || strings.HasPrefix(ref, "!") {
+			keep = append(keep, ref)
+			continue
+		}
 		matched := false
 		for _, c := range candidates {
 			if strings.EqualFold(ref, c) {
@@ -2535,3 +2547,12 @@ func (h *Handler) GetAuthStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "wait"})
 }
+
+// ListDuplicateAuths reports auth files that sign in to the same upstream account.
+func (h *Handler) ListDuplicateAuths(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	writeListJSON(c, "duplicates", h.authManager.DuplicateGroups())
+}
@@ -0,0 +1,84 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+const authProbeTimeout = 20 * time.Second
+
+// AuthProbeResult reports whether an auth answered a minimal model request upstream.
+type AuthProbeResult struct {
+	ID    string `json:"id"`
+	Model string `json:"model"`
+	OK    bool   `json:"ok"`
+	// Status is the upstream HTTP status of a failed probe, when the error carries one.
+	Status    int    `json:"status,omitempty"`
+	LatencyMs int64  `json:"latency-ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TestAuth sends a one-token chat request upstream through the auth's executor, bypassing
+// routing, and reports the outcome. The model defaults to the first one registered for the
+// auth and can be chosen with the model query parameter. Upstream failures are reported in the
+// result with ok set to false; the auth's cooldown state is left untouched.
+func (h *Handler) TestAuth(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	auth, ok := h.authManager.GetByID(id)
+	if !ok || auth == nil {
+		auth = h.authByIndex(id)
+	}
+	if auth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+		return
+	}
+	model := strings.TrimSpace(c.Query("model"))
+	if model == "" {
+		if models := registry.GetGlobalRegistry().GetModelsForClient(auth.ID); len(models) > 0 {
+			model = models[0].ID
+		}
+	}
+	if model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth has no registered models"})
+		return
+	}
+
+	payload, _ := json.Marshal(map[string]any{
+		"model":      model,
+		"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+		"max_tokens": 1,
+	})
+	format := sdktranslator.FromString("openai")
+	req := cliproxyexecutor.Request{Model: model, Payload: payload, Format: format}
+	opts := cliproxyexecutor.Options{OriginalRequest: payload, SourceFormat: format}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), authProbeTimeout)
+	defer cancel()
+	result := AuthProbeResult{ID: auth.ID, Model: model}
+	start := time.Now()
+	_, err := h.authManager.ExecuteWithAuth(ctx, auth, req, opts)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		var se cliproxyexecutor.StatusError
+		if errors.As(err, &se) && se != nil {
+			result.Status = se.StatusCode()
+		}
+	} else {
+		result.OK = true
+	}
+	c.JSON(http.StatusOK, result)
+}
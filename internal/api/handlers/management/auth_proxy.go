@@ -0,0 +1,126 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// proxyEchoURL is the service queried through the proxy to learn the egress IP. It answers
+// with {"ip": "..."}; plain-text answers are accepted as well.
+var proxyEchoURL = "https://api.ipify.org?format=json"
+
+const proxyTestTimeout = 15 * time.Second
+
+// AuthProxyTestResult reports whether an auth's outbound proxy works and which IP upstreams see.
+type AuthProxyTestResult struct {
+	ID string `json:"id"`
+	// ProxyURL is the effective proxy with its password redacted; empty for direct connections.
+	ProxyURL string `json:"proxy-url,omitempty"`
+	// Source is "auth" for a per-auth proxy, "global" for the proxy-url setting or "direct".
+	Source     string `json:"source"`
+	OK         bool   `json:"ok"`
+	ExternalIP string `json:"external-ip,omitempty"`
+	LatencyMs  int64  `json:"latency-ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TestAuthProxy checks the proxy an auth would use by fetching the egress IP from an echo
+// service through it. Invalid proxy URLs are rejected; connection failures are reported in
+// the result with ok set to false.
+func (h *Handler) TestAuthProxy(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	auth, ok := h.authManager.GetByID(id)
+	if !ok || auth == nil {
+		auth = h.authByIndex(id)
+	}
+	if auth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+		return
+	}
+
+	result := AuthProxyTestResult{ID: auth.ID, Source: "direct"}
+	proxyStr := strings.TrimSpace(auth.ProxyURL)
+	if proxyStr != "" {
+		result.Source = "auth"
+	} else if h.cfg != nil && strings.TrimSpace(h.cfg.ProxyURL) != "" {
+		proxyStr = strings.TrimSpace(h.cfg.ProxyURL)
+		result.Source = "global"
+	}
+	if proxyStr != "" {
+		proxyURL, err := util.ParseProxyURL(proxyStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		result.ProxyURL = proxyURL.Redacted()
+	}
+
+	transport := h.proxyTestTransport(auth, proxyStr)
+	if transport == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to set up proxy transport"})
+		return
+	}
+	client := &http.Client{Timeout: proxyTestTimeout, Transport: transport}
+	start := time.Now()
+	ip, err := fetchEgressIP(c.Request.Context(), client)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.OK = true
+		result.ExternalIP = ip
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) proxyTestTransport(auth *coreauth.Auth, proxyStr string) http.RoundTripper {
+	if proxyStr == "" {
+		return h.apiCallTransport(auth)
+	}
+	if transport := buildProxyTransport(h.cfg, proxyStr); transport != nil {
+		return transport
+	}
+	return nil
+}
+
+func fetchEgressIP(ctx context.Context, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, proxyEchoURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("echo service returned status %d", resp.StatusCode)
+	}
+	var payload struct {
+		IP string `json:"ip"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.IP != "" {
+		return payload.IP, nil
+	}
+	if ip := strings.TrimSpace(string(body)); ip != "" && !strings.ContainsAny(ip, "{}<> \n") {
+		return ip, nil
+	}
+	return "", fmt.Errorf("echo service returned no IP")
+}
@@ -0,0 +1,147 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func newAuthProxyRouter(t *testing.T, auths ...*coreauth.Auth) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	manager := coreauth.NewManager(nil, nil, nil)
+	for _, auth := range auths {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+	h := &Handler{cfg: &config.Config{}, authManager: manager}
+	router := gin.New()
+	router.POST("/auths/:id/test-proxy", h.TestAuthProxy)
+	return router
+}
+
+func TestTestAuthProxy_ReportsEgressIP(t *testing.T) {
+	var proxied bool
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.Host == "echo.test"
+		_, _ = w.Write([]byte(`{"ip":"203.0.113.7"}`))
+	}))
+	defer proxyServer.Close()
+	prevEcho := proxyEchoURL
+	proxyEchoURL = "http://echo.test/"
+	defer func() { proxyEchoURL = prevEcho }()
+
+	proxyURL := strings.Replace(proxyServer.URL, "http://", "http://user:secret@", 1)
+	router := newAuthProxyRouter(t, &coreauth.Auth{ID: "codex.json", Provider: "codex", ProxyURL: proxyURL})
+
+	rec := doManagementRequest(router, http.MethodPost, "/auths/codex.json/test-proxy", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var result AuthProxyTestResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !proxied || !result.OK || result.ExternalIP != "203.0.113.7" || result.Source != "auth" {
+		t.Fatalf("result = %+v, proxied = %v", result, proxied)
+	}
+	if strings.Contains(result.ProxyURL, "secret") {
+		t.Fatalf("proxy password not redacted: %s", result.ProxyURL)
+	}
+}
+
+func TestTestAuthProxy_RejectsInvalidProxy(t *testing.T) {
+	router := newAuthProxyRouter(t, &coreauth.Auth{ID: "claude.json", Provider: "claude", ProxyURL: "ftp://proxy.example.com"})
+
+	if rec := doManagementRequest(router, http.MethodPost, "/auths/claude.json/test-proxy", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodPost, "/auths/missing.json/test-proxy", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing auth status = %d", rec.Code)
+	}
+}
+
+type probeExecutor struct {
+	status int
+	models []string
+}
+
+type probeStatusError int
+
+func (e probeStatusError) Error() string   { return http.StatusText(int(e)) }
+func (e probeStatusError) StatusCode() int { return int(e) }
+
+func (e *probeExecutor) Identifier() string { return "codex" }
+
+func (e *probeExecutor) Execute(_ context.Context, _ *coreauth.Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.models = append(e.models, req.Model)
+	if e.status != 0 {
+		return cliproxyexecutor.Response{}, probeStatusError(e.status)
+	}
+	return cliproxyexecutor.Response{Payload: []byte(`{}`)}, nil
+}
+
+func (e *probeExecutor) ExecuteStream(context.Context, *coreauth.Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, nil
+}
+
+func (e *probeExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *probeExecutor) CountTokens(context.Context, *coreauth.Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *probeExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func TestTestAuth_ProbesUpstreamThroughExecutor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := coreauth.NewManager(nil, nil, nil)
+	exec := &probeExecutor{}
+	manager.RegisterExecutor(exec)
+	auth := &coreauth.Auth{ID: "codex-probe.json", Provider: "codex", Attributes: map[string]string{"api_key": "k"}}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(auth.ID, "codex", []*registry.ModelInfo{{ID: "gpt-probe"}})
+	defer reg.UnregisterClient(auth.ID)
+	h := &Handler{cfg: &config.Config{}, authManager: manager}
+	router := gin.New()
+	router.POST("/auths/:id/test", h.TestAuth)
+
+	rec := doManagementRequest(router, http.MethodPost, "/auths/codex-probe.json/test", "")
+	var result AuthProbeResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, err = %v: %s", rec.Code, err, rec.Body.String())
+	}
+	if !result.OK || result.Model != "gpt-probe" || len(exec.models) != 1 || exec.models[0] != "gpt-probe" {
+		t.Fatalf("result = %+v, executed = %v", result, exec.models)
+	}
+
+	exec.status = http.StatusUnauthorized
+	rec = doManagementRequest(router, http.MethodPost, "/auths/codex-probe.json/test?model=gpt-other", "")
+	result = AuthProbeResult{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.OK || result.Status != http.StatusUnauthorized || result.Model != "gpt-other" {
+		t.Fatalf("failed probe result = %+v", result)
+	}
+	if current, _ := manager.GetByID(auth.ID); current.Unavailable {
+		t.Fatalf("probe failure must not change auth state")
+	}
+}
@@ -0,0 +1,114 @@
+package management
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// GetClientKeyGroups lists the client key groups.
+func (h *Handler) GetClientKeyGroups(c *gin.Context) {
+	h.mu.Lock()
+	groups := slices.Clone(h.cfg.ClientKeyGroups)
+	h.mu.Unlock()
+
+	writeListJSON(c, "client-key-groups", groups)
+}
+
+// PutClientKeyGroups replaces the client key groups.
+func (h *Handler) PutClientKeyGroups(c *gin.Context) {
+	var groups []config.ClientKeyGroup
+	if err := c.ShouldBindJSON(&groups); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if err := config.ValidateClientKeyGroups(groups); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.mu.Lock()
+	h.cfg.ClientKeyGroups = groups
+	h.cfg.SanitizeClientKeyGroups()
+	h.mu.Unlock()
+	h.persist(c)
+}
+
+// PatchClientKeyGroup adds a client key group, replacing the group with the same name if there
+// is one.
+func (h *Handler) PatchClientKeyGroup(c *gin.Context) {
+	var group config.ClientKeyGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	h.mu.Lock()
+	idx := slices.IndexFunc(h.cfg.ClientKeyGroups, func(existing config.ClientKeyGroup) bool {
+		return existing.Name == group.Name
+	})
+	if idx >= 0 {
+		h.cfg.ClientKeyGroups[idx] = group
+	} else {
+		h.cfg.ClientKeyGroups = append(h.cfg.ClientKeyGroups, group)
+	}
+	h.cfg.SanitizeClientKeyGroups()
+	h.mu.Unlock()
+	h.persist(c)
+}
+
+// DeleteClientKeyGroup removes the client key group named by the name query parameter. Per-key
+// settings addressed to the group are removed with it.
+func (h *Handler) DeleteClientKeyGroup(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	h.mu.Lock()
+	if _, ok := h.cfg.ClientKeyGroup(name); !ok {
+		h.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "client key group not found"})
+		return
+	}
+	h.cfg.ClientKeyGroups = slices.DeleteFunc(h.cfg.ClientKeyGroups, func(group config.ClientKeyGroup) bool {
+		return group.Name == name
+	})
+	delete(h.cfg.APIKeyAuth, config.ClientKeyGroupPrefix+name)
+	delete(h.cfg.APIKeyCatalogs, config.ClientKeyGroupPrefix+name)
+	h.mu.Unlock()
+	h.persist(c)
+}
+
+// setClientKeyGroupsLocked makes key a member of exactly the named groups. It reports the first
+// unknown group name.
+func (h *Handler) setClientKeyGroupsLocked(key string, names []string) (string, bool) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := h.cfg.ClientKeyGroup(name); !ok {
+			return name, false
+		}
+		wanted[name] = true
+	}
+	for i := range h.cfg.ClientKeyGroups {
+		group := &h.cfg.ClientKeyGroups[i]
+		member := slices.Contains(group.Keys, key)
+		switch {
+		case wanted[group.Name] && !member:
+			group.Keys = append(group.Keys, key)
+		case !wanted[group.Name] && member:
+			group.Keys = slices.DeleteFunc(group.Keys, func(existing string) bool { return existing == key })
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,252 @@
+package management
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// clientKeyPrefix marks generated client API keys.
+const clientKeyPrefix = "sk-"
+
+// ClientKey is a client API key as returned by the provisioning endpoints.
+type ClientKey struct {
+	Key         string   `json:"key"`
+	Enabled     bool     `json:"enabled"`
+	Owner       string   `json:"owner,omitempty"`
+	Description string   `json:"description,omitempty"`
+	CreatedBy   string   `json:"created-by,omitempty"`
+	CreatedAt   string   `json:"created-at,omitempty"`
+	Auth        []string `json:"auth,omitempty"`
+	ExpiresAt   string   `json:"expires-at,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+}
+
+// ClientKeyRequest is the body of client key create and update requests. Absent fields are
+// left unchanged on update; an empty auth list or expires-at clears the restriction. Groups
+// replaces the groups the key belongs to.
+type ClientKeyRequest struct {
+	Key         *string   `json:"key"`
+	Owner       *string   `json:"owner"`
+	Description *string   `json:"description"`
+	CreatedBy   *string   `json:"created-by"`
+	Auth        *[]string `json:"auth"`
+	ExpiresAt   *string   `json:"expires-at"`
+	Disabled    *bool     `json:"disabled"`
+	Groups      *[]string `json:"groups"`
+}
+
+// GetClientKeys lists client API keys with their metadata, auth restrictions and expiry.
+func (h *Handler) GetClientKeys(c *gin.Context) {
+	h.mu.Lock()
+	keys := make([]ClientKey, 0, len(h.cfg.APIKeys))
+	for _, key := range h.cfg.APIKeys {
+		keys = append(keys, h.clientKeyLocked(key))
+	}
+	h.mu.Unlock()
+
+	writeListJSON(c, "keys", keys)
+}
+
+// CreateClientKey provisions a client API key. A random key is generated when none is given.
+func (h *Handler) CreateClientKey(c *gin.Context) {
+	var req ClientKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	key := ""
+	if req.Key != nil {
+		key = strings.TrimSpace(*req.Key)
+	}
+	if key == "" {
+		generated, err := generateClientKey()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to generate key: %v", err)})
+			return
+		}
+		key = generated
+	}
+	if err := validateClientKeyRequest(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if slices.Contains(h.cfg.APIKeys, key) {
+		c.JSON(http.StatusConflict, gin.H{"error": "key already exists"})
+		return
+	}
+	if !h.applyClientKeyGroupsLocked(c, key, req) {
+		return
+	}
+	meta := config.APIKeyMetadata{CreatedBy: "management-api", CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	h.cfg.APIKeys = append(h.cfg.APIKeys, key)
+	h.applyClientKeyLocked(key, meta, req)
+	if !h.saveClientKeysLocked(c) {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"key": h.clientKeyLocked(key)})
+}
+
+// UpdateClientKey changes the metadata, auth restriction, expiry or disabled state of a key.
+func (h *Handler) UpdateClientKey(c *gin.Context) {
+	key := c.Param("key")
+	var req ClientKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if req.Key != nil && *req.Key != key {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key cannot be changed"})
+		return
+	}
+	if err := validateClientKeyRequest(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !slices.Contains(h.cfg.APIKeys, key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "key not found"})
+		return
+	}
+	if !h.applyClientKeyGroupsLocked(c, key, req) {
+		return
+	}
+	h.applyClientKeyLocked(key, h.cfg.APIKeyMetadata[key], req)
+	if !h.saveClientKeysLocked(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"key": h.clientKeyLocked(key)})
+}
+
+// DeleteClientKey removes a client API key together with its metadata, auth restriction,
+// expiry, catalog assignment and group memberships.
+func (h *Handler) DeleteClientKey(c *gin.Context) {
+	key := c.Param("key")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	idx := slices.Index(h.cfg.APIKeys, key)
+	if idx < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "key not found"})
+		return
+	}
+	h.cfg.APIKeys = slices.Delete(h.cfg.APIKeys, idx, idx+1)
+	delete(h.cfg.APIKeyMetadata, key)
+	delete(h.cfg.APIKeyAuth, key)
+	delete(h.cfg.APIKeyExpiry, key)
+	delete(h.cfg.APIKeyCatalogs, key)
+	h.setClientKeyGroupsLocked(key, nil)
+	if !h.saveClientKeysLocked(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// applyClientKeyLocked writes the fields set in req to the metadata, auth and expiry maps.
+func (h *Handler) applyClientKeyLocked(key string, meta config.APIKeyMetadata, req ClientKeyRequest) {
+	if req.Owner != nil {
+		meta.Owner = strings.TrimSpace(*req.Owner)
+	}
+	if req.Description != nil {
+		meta.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.CreatedBy != nil {
+		meta.CreatedBy = strings.TrimSpace(*req.CreatedBy)
+	}
+	if req.Disabled != nil {
+		meta.Disabled = *req.Disabled
+	}
+	if h.cfg.APIKeyMetadata == nil {
+		h.cfg.APIKeyMetadata = make(map[string]config.APIKeyMetadata)
+	}
+	h.cfg.APIKeyMetadata[key] = meta
+
+	if req.Auth != nil {
+		if h.cfg.APIKeyAuth == nil {
+			h.cfg.APIKeyAuth = make(map[string][]string)
+		}
+		h.cfg.APIKeyAuth[key] = append([]string(nil), (*req.Auth)...)
+		h.cfg.APIKeyAuth = config.NormalizeAPIKeyAuthForKnownKeys(h.cfg.APIKeyAuth, h.cfg.ClientKeyRefs())
+	}
+	if req.ExpiresAt != nil {
+		if h.cfg.APIKeyExpiry == nil {
+			h.cfg.APIKeyExpiry = make(map[string]string)
+		}
+		h.cfg.APIKeyExpiry[key] = *req.ExpiresAt
+		h.cfg.APIKeyExpiry = config.NormalizeAPIKeyExpiry(h.cfg.APIKeyExpiry)
+	}
+}
+
+// applyClientKeyGroupsLocked sets the groups of key when req lists them, answering 400 when
+// one of them does not exist.
+func (h *Handler) applyClientKeyGroupsLocked(c *gin.Context, key string, req ClientKeyRequest) bool {
+	if req.Groups == nil {
+		return true
+	}
+	if unknown, ok := h.setClientKeyGroupsLocked(key, *req.Groups); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown client key group %q", unknown)})
+		return false
+	}
+	return true
+}
+
+// saveClientKeysLocked rebuilds the access providers and persists the configuration.
+func (h *Handler) saveClientKeysLocked(c *gin.Context) bool {
+	h.cfg.Access.Providers = nil
+	h.cfg.SanitizeAPIKeyMetadata()
+	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) clientKeyLocked(key string) ClientKey {
+	meta := h.cfg.APIKeyMetadata[key]
+	return ClientKey{
+		Key:         key,
+		Enabled:     !meta.Disabled,
+		Owner:       meta.Owner,
+		Description: meta.Description,
+		CreatedBy:   meta.CreatedBy,
+		CreatedAt:   meta.CreatedAt,
+		Auth:        h.cfg.APIKeyAuth[key],
+		ExpiresAt:   h.cfg.APIKeyExpiry[key],
+		Groups:      h.cfg.ClientKeyGroupsOf(key),
+	}
+}
+
+func validateClientKeyRequest(req ClientKeyRequest) error {
+	if req.ExpiresAt == nil {
+		return nil
+	}
+	if value := strings.TrimSpace(*req.ExpiresAt); value != "" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("expires-at must be an RFC3339 timestamp")
+		}
+	}
+	return nil
+}
+
+func generateClientKey() (string, error) {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return clientKeyPrefix + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,184 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+func newClientKeysRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router := gin.New()
+	router.GET("/client-keys", h.GetClientKeys)
+	router.POST("/client-keys", h.CreateClientKey)
+	router.PATCH("/client-keys/:key", h.UpdateClientKey)
+	router.DELETE("/client-keys/:key", h.DeleteClientKey)
+	return router
+}
+
+func doManagementRequest(router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestClientKeys_Lifecycle(t *testing.T) {
+	cfg := &config.Config{}
+	router := newClientKeysRouter(t, cfg)
+
+	rec := doManagementRequest(router, http.MethodPost, "/client-keys", `{"owner":"team-a","description":"ci","auth":["auth-1"],"expires-at":"2030-01-01T00:00:00Z"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Key ClientKey `json:"key"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	key := created.Key.Key
+	if !strings.HasPrefix(key, clientKeyPrefix) || !created.Key.Enabled || created.Key.Owner != "team-a" || created.Key.CreatedBy != "management-api" {
+		t.Fatalf("unexpected created key %+v", created.Key)
+	}
+	if len(cfg.APIKeys) != 1 || cfg.APIKeyAuth[key][0] != "auth-1" || cfg.APIKeyExpiry[key] != "2030-01-01T00:00:00Z" {
+		t.Fatalf("config not updated: %+v %+v %+v", cfg.APIKeys, cfg.APIKeyAuth, cfg.APIKeyExpiry)
+	}
+
+	if rec = doManagementRequest(router, http.MethodPost, "/client-keys", `{"key":"`+key+`"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d", rec.Code)
+	}
+
+	rec = doManagementRequest(router, http.MethodPatch, "/client-keys/"+key, `{"disabled":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("disable status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := cfg.EnabledAPIKeys(); len(got) != 0 {
+		t.Fatalf("enabled keys after disable = %v", got)
+	}
+	if len(cfg.APIKeyAuth[key]) != 1 || cfg.APIKeyMetadata[key].Owner != "team-a" {
+		t.Fatalf("disable dropped settings: %+v %+v", cfg.APIKeyAuth, cfg.APIKeyMetadata)
+	}
+
+	rec = doManagementRequest(router, http.MethodGet, "/client-keys", "")
+	if !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Fatalf("list = %s", rec.Body.String())
+	}
+
+	if rec = doManagementRequest(router, http.MethodDelete, "/client-keys/"+key, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+	if len(cfg.APIKeys) != 0 || len(cfg.APIKeyMetadata) != 0 || len(cfg.APIKeyAuth) != 0 || len(cfg.APIKeyExpiry) != 0 {
+		t.Fatalf("delete left state: %+v", cfg)
+	}
+}
+
+func TestClientKeys_RejectsInvalidExpiry(t *testing.T) {
+	cfg := &config.Config{}
+	router := newClientKeysRouter(t, cfg)
+	if rec := doManagementRequest(router, http.MethodPost, "/client-keys", `{"expires-at":"tomorrow"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(cfg.APIKeys) != 0 {
+		t.Fatalf("key added despite invalid request: %v", cfg.APIKeys)
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/client-keys/missing", `{}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing key status = %d", rec.Code)
+	}
+}
+
+func TestClientKeyGroups_Membership(t *testing.T) {
+	cfg := &config.Config{SDKConfig: config.SDKConfig{APIKeys: []string{"k1"}}}
+	router := newClientKeysRouter(t, cfg)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router.PATCH("/client-key-groups", h.PatchClientKeyGroup)
+	router.DELETE("/client-key-groups", h.DeleteClientKeyGroup)
+
+	if rec := doManagementRequest(router, http.MethodPatch, "/client-key-groups", `{"name":"team-a","keys":["k1"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch group status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/client-keys/k1", `{"groups":["missing"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown group status = %d, want 400", rec.Code)
+	}
+	rec := doManagementRequest(router, http.MethodPost, "/client-keys", `{"key":"k2","groups":["team-a"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"groups":["team-a"]`) {
+		t.Fatalf("created key does not report its group: %s", rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/client-keys/k1", `{"groups":[]}`); rec.Code != http.StatusOK {
+		t.Fatalf("clear groups status = %d", rec.Code)
+	}
+	if keys := cfg.ClientKeyGroups[0].Keys; len(keys) != 1 || keys[0] != "k2" {
+		t.Fatalf("team-a keys = %v, want [k2]", keys)
+	}
+
+	cfg.APIKeyAuth = map[string][]string{"group:team-a": {"provider:codex"}}
+	if rec := doManagementRequest(router, http.MethodDelete, "/client-key-groups?name=team-a", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete group status = %d", rec.Code)
+	}
+	if len(cfg.ClientKeyGroups) != 0 || len(cfg.APIKeyAuth) != 0 {
+		t.Fatalf("group not removed: %+v %+v", cfg.ClientKeyGroups, cfg.APIKeyAuth)
+	}
+	if rec := doManagementRequest(router, http.MethodDelete, "/client-key-groups?name=team-a", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete missing group status = %d, want 404", rec.Code)
+	}
+}
+
+func TestTraceWatermark(t *testing.T) {
+	cfg := &config.Config{SDKConfig: config.SDKConfig{APIKeys: []string{"sk-watermark-key"}}}
+	cfg.APIKeyMetadata = map[string]config.APIKeyMetadata{"sk-watermark-key": {Owner: "team-a"}}
+	router := newClientKeysRouter(t, cfg)
+	h := &Handler{cfg: cfg}
+	router.GET("/watermark", h.TraceWatermark)
+
+	rec := doManagementRequest(router, http.MethodGet, "/watermark?value="+util.Watermark("sk-watermark-key", "req-1"), "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"owner":"team-a"`) || !strings.Contains(rec.Body.String(), `"request-id":"req-1"`) {
+		t.Fatalf("trace status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodGet, "/watermark?value="+util.Watermark("sk-other", "req-2"), ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown key status = %d, want 404", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodGet, "/watermark?value=nonsense", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid watermark status = %d, want 400", rec.Code)
+	}
+}
+
+func TestTraceWatermarkFindsShareLinksAndGroupMembers(t *testing.T) {
+	cfg := &config.Config{SDKConfig: config.SDKConfig{
+		ShareLinks:      []config.ShareLink{{Key: "sk-share-link-key", Name: "contractor"}},
+		ClientKeyGroups: []config.ClientKeyGroup{{Name: "team-b", Keys: []string{"sk-group-member-key"}}},
+	}}
+	router := newClientKeysRouter(t, cfg)
+	h := &Handler{cfg: cfg}
+	router.GET("/watermark", h.TraceWatermark)
+
+	rec := doManagementRequest(router, http.MethodGet, "/watermark?value="+util.Watermark("sk-share-link-key", "req-3"), "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"owner":"contractor"`) || !strings.Contains(rec.Body.String(), `"source":"share-link"`) {
+		t.Fatalf("share link trace status = %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doManagementRequest(router, http.MethodGet, "/watermark?value="+util.Watermark("sk-group-member-key", "req-4"), "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"source":"group:team-b"`) {
+		t.Fatalf("group member trace status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
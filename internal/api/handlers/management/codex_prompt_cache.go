@@ -0,0 +1,23 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+)
+
+// GetCodexPromptCache reports the hit rate of the Codex prompt cache together with its live
+// session entries. The optional key query parameter lists only entries whose key contains it.
+func (h *Handler) GetCodexPromptCache(c *gin.Context) {
+	stats, entries := executor.CodexPromptCacheSnapshot(strings.TrimSpace(c.Query("key")))
+	c.JSON(http.StatusOK, gin.H{"stats": stats, "entries": entries})
+}
+
+// DeleteCodexPromptCache purges Codex prompt cache entries so the next request of the session
+// starts a fresh prompt_cache_key. Without a key query parameter every entry is purged.
+func (h *Handler) DeleteCodexPromptCache(c *gin.Context) {
+	purged := executor.PurgeCodexPromptCache(strings.TrimSpace(c.Query("key")))
+	c.JSON(http.StatusOK, gin.H{"purged": len(purged), "keys": purged})
+}
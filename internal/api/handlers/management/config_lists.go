@@ -123,8 +123,8 @@ func (h *Handler) DeleteAPIKeys(c *gin.Context) {
 // api-key-auth
 func (h *Handler) GetAPIKeyAuth(c *gin.Context) {
 	mapping := h.cfg.APIKeyAuth
-	clean := config.NormalizeAPIKeyAuthForKnownKeys(mapping, append([]string{}, h.cfg.APIKeys...))
-	if !reflect.DeepEqual(mapping, clean) {
+	clean := config.NormalizeAPIKeyAuthForKnownKeys(mapping, h.cfg.ClientKeyRefs())
+	if !reflect.DeepEqual(mapping, clean) && !h.cfg.ReadOnly {
 		h.mu.Lock()
 		h.cfg.APIKeyAuth = clean
 		if strings.TrimSpace(h.configFilePath) != "" {
@@ -160,7 +160,7 @@ func (h *Handler) PutAPIKeyAuth(c *gin.Context) {
 		}
 		mapping = obj.Mapping
 	}
-	clean := config.NormalizeAPIKeyAuthForKnownKeys(mapping, append([]string{}, h.cfg.APIKeys...))
+	clean := config.NormalizeAPIKeyAuthForKnownKeys(mapping, h.cfg.ClientKeyRefs())
 	h.cfg.APIKeyAuth = clean
 	h.persist(c)
 }
@@ -0,0 +1,19 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/configsync"
+)
+
+// GetConfigSync reports the revision the running config was synced from, answering 404 when
+// config sync is not enabled.
+func (h *Handler) GetConfigSync(c *gin.Context) {
+	status, ok := configsync.CurrentStatus()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config sync not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"config-sync": status})
+}
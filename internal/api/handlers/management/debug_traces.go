@@ -0,0 +1,25 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+)
+
+// GetDebugTrace returns the debug trace collected for a request sent with the
+// X-CLIProxy-Debug header, looked up by its request ID.
+func (h *Handler) GetDebugTrace(c *gin.Context) {
+	requestID := strings.TrimSpace(c.Param("id"))
+	if requestID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing request ID"})
+		return
+	}
+	trace, ok := debugtrace.Get(requestID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "debug trace not found"})
+		return
+	}
+	c.JSON(http.StatusOK, trace)
+}
@@ -223,34 +223,27 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			return
 		}
 
+		role := ""
 		if localClient {
-			if lp := h.localPassword; lp != "" {
-				if subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
-					c.Next()
-					return
-				}
+			if lp := h.localPassword; lp != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
+				role = managementRoleAdmin
 			}
 		}
-
-		if envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
-			if !localClient {
-				h.attemptsMu.Lock()
-				if ai := h.failedAttempts[clientIP]; ai != nil {
-					ai.count = 0
-					ai.blockedUntil = time.Time{}
-				}
-				h.attemptsMu.Unlock()
-			}
-			c.Next()
-			return
+		if role == "" && envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
+			role = managementRoleAdmin
 		}
-
-		if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) != nil {
-			if !localClient {
-				fail()
+		if role == "" {
+			if secretHash != "" && bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) == nil {
+				role = managementRoleAdmin
+			} else if cfg != nil && matchViewerKey(cfg.RemoteManagement.ViewerKeys, provided) {
+				role = managementRoleViewer
+			} else {
+				if !localClient {
+					fail()
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid management key"})
+				return
 			}
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid management key"})
-			return
 		}
 
 		if !localClient {
@@ -262,10 +255,27 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			h.attemptsMu.Unlock()
 		}
 
+		if !isReadOnlyRequest(c) {
+			if cfg != nil && cfg.ReadOnly {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "server is in read-only mode"})
+				return
+			}
+			if role == managementRoleViewer {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "viewer management keys are read-only"})
+				return
+			}
+		}
+		c.Set(managementRoleKey, role)
 		c.Next()
 	}
 }
 
+// isReadOnlyRequest reports whether c targets a route in readOnlyRoutes, which neither change
+// the configuration nor add auths.
+func isReadOnlyRequest(c *gin.Context) bool {
+	return readOnlyRoutes[c.Request.Method+" "+c.FullPath()]
+}
+
 // persist saves the current in-memory config to disk.
 func (h *Handler) persist(c *gin.Context) bool {
 	h.mu.Lock()
@@ -0,0 +1,242 @@
+package management
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listQuery holds the pagination, filtering, sorting and field selection parameters shared by
+// the management list endpoints:
+//
+//	offset=N            skip the first N matching items
+//	limit=N             return at most N items
+//	sort=field          sort ascending by field; "-field" sorts descending
+//	filter=field:value  keep items whose field equals value (case-insensitive); repeatable
+//	fields=a,b          return only the listed fields of each item
+//
+// Without parameters a list is returned whole and in its default order.
+type listQuery struct {
+	offset  int
+	limit   int
+	sortBy  string
+	desc    bool
+	filters map[string]string
+	fields  []string
+}
+
+func parseListQuery(c *gin.Context) (listQuery, error) {
+	var q listQuery
+	if raw := strings.TrimSpace(c.Query("offset")); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return q, fmt.Errorf("invalid offset: must be a non-negative integer")
+		}
+		q.offset = offset
+	}
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		return q, fmt.Errorf("invalid limit: %v", err)
+	}
+	q.limit = limit
+	if sortBy := strings.TrimSpace(c.Query("sort")); sortBy != "" {
+		q.desc = strings.HasPrefix(sortBy, "-")
+		q.sortBy = strings.TrimPrefix(sortBy, "-")
+	}
+	for _, raw := range c.QueryArray("filter") {
+		field, value, ok := strings.Cut(raw, ":")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return q, fmt.Errorf("invalid filter %q: expected field:value", raw)
+		}
+		if q.filters == nil {
+			q.filters = make(map[string]string)
+		}
+		q.filters[field] = strings.TrimSpace(value)
+	}
+	for _, field := range strings.Split(c.Query("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			q.fields = append(q.fields, field)
+		}
+	}
+	return q, nil
+}
+
+// apply filters, sorts, paginates and projects items. total is the number of items that
+// matched the filters before pagination.
+func (q listQuery) apply(items []map[string]any) (page []map[string]any, total int) {
+	matched := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if q.matches(item) {
+			matched = append(matched, item)
+		}
+	}
+	if q.sortBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			cmp := compareListValues(matched[i][q.sortBy], matched[j][q.sortBy])
+			if q.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+	total = len(matched)
+	start := min(q.offset, total)
+	end := total
+	if q.limit > 0 {
+		end = min(start+q.limit, total)
+	}
+	page = matched[start:end]
+	if len(q.fields) == 0 {
+		return page, total
+	}
+	projected := make([]map[string]any, 0, len(page))
+	for _, item := range page {
+		selected := make(map[string]any, len(q.fields))
+		for _, field := range q.fields {
+			if value, ok := item[field]; ok {
+				selected[field] = value
+			}
+		}
+		projected = append(projected, selected)
+	}
+	return projected, total
+}
+
+func (q listQuery) matches(item map[string]any) bool {
+	for field, want := range q.filters {
+		value, ok := item[field]
+		if !ok || !strings.EqualFold(listValueString(value), want) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeListJSON responds with the page of items selected by the request's list parameters
+// under key, together with the total number of matching items.
+func writeListJSON(c *gin.Context, key string, items any) {
+	q, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	normalized, err := toListItems(items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	page, total := q.apply(normalized)
+	body := gin.H{key: page, "total": total}
+	if q.offset > 0 || q.limit > 0 {
+		body["offset"] = q.offset
+		body["limit"] = q.limit
+	}
+	writeJSONWithETag(c, http.StatusOK, body)
+}
+
+// writeJSONWithETag responds with body and a strong ETag derived from it, or with
+// 304 Not Modified when the request's If-None-Match already names that ETag.
+func writeJSONWithETag(c *gin.Context, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", data)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// toListItems converts a slice of structs or maps into JSON-shaped maps so that list
+// parameters address items by their JSON field names. Numbers are kept as json.Number.
+func toListItems(items any) ([]map[string]any, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out []map[string]any
+	if err = decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []map[string]any{}
+	}
+	return out, nil
+}
+
+func listValueString(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// compareListValues orders numbers numerically, strings case-insensitively and false before
+// true. Missing values sort first.
+func compareListValues(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if na, ok := a.(json.Number); ok {
+		if nb, okB := b.(json.Number); okB {
+			fa, _ := na.Float64()
+			fb, _ := nb.Float64()
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, okB := b.(bool); okB {
+			switch {
+			case ba == bb:
+				return 0
+			case !ba:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(strings.ToLower(listValueString(a)), strings.ToLower(listValueString(b)))
+}
@@ -0,0 +1,72 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func serveList(t *testing.T, target, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	proxies := []config.ReverseProxy{
+		{ID: "1", Name: "Bravo", Enabled: true, Timeout: 30},
+		{ID: "2", Name: "alpha", Enabled: false, Timeout: 5},
+		{ID: "3", Name: "Charlie", Enabled: true, Timeout: 10},
+	}
+	router := gin.New()
+	router.GET("/list", func(c *gin.Context) { writeListJSON(c, "reverse-proxies", proxies) })
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWriteListJSON_FilterSortPaginateFields(t *testing.T) {
+	rec := serveList(t, "/list?filter=enabled:true&sort=-timeout&limit=1&offset=1&fields=name,timeout", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Items []map[string]any `json:"reverse-proxies"`
+		Total int              `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Total != 2 || len(body.Items) != 1 {
+		t.Fatalf("total = %d, items = %v", body.Total, body.Items)
+	}
+	if item := body.Items[0]; item["name"] != "Charlie" || len(item) != 2 {
+		t.Fatalf("unexpected item %v", item)
+	}
+
+	rec = serveList(t, "/list?sort=name&fields=name", "")
+	if got := rec.Body.String(); got != `{"reverse-proxies":[{"name":"alpha"},{"name":"Bravo"},{"name":"Charlie"}],"total":3}` {
+		t.Fatalf("sort by name = %s", got)
+	}
+	if rec = serveList(t, "/list?filter=nocolon", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid filter status = %d", rec.Code)
+	}
+}
+
+func TestWriteListJSON_ETag(t *testing.T) {
+	first := serveList(t, "/list", "")
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	if rec := serveList(t, "/list", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("status = %d, body = %q, want 304", rec.Code, rec.Body.String())
+	}
+	if rec := serveList(t, "/list?limit=1", etag); rec.Code != http.StatusOK {
+		t.Fatalf("different page should not match ETag, status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package management
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// LogLevelRequest sets a runtime log level; an empty module sets the global level.
+type LogLevelRequest struct {
+	Level  string `json:"level"`
+	Module string `json:"module,omitempty"`
+}
+
+// GetLogLevel reports the effective log levels and the overrides set at runtime.
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"log-level": util.CurrentLogLevels()})
+}
+
+// PutLogLevel sets a log level at runtime. The level is not written to the config and lasts
+// until it is cleared or the server restarts.
+func (h *Handler) PutLogLevel(c *gin.Context) {
+	var body LogLevelRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	level, err := log.ParseLevel(strings.TrimSpace(body.Level))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	module, ok := logLevelModule(body.Module)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown module", "modules": config.LogModules})
+		return
+	}
+	util.SetLogLevelOverride(module, level)
+	c.JSON(http.StatusOK, gin.H{"log-level": util.CurrentLogLevels()})
+}
+
+// DeleteLogLevel clears the runtime level of ?module=, or all runtime levels, returning to the
+// configured ones.
+func (h *Handler) DeleteLogLevel(c *gin.Context) {
+	module, ok := logLevelModule(c.Query("module"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown module", "modules": config.LogModules})
+		return
+	}
+	util.ClearLogLevelOverride(module)
+	c.JSON(http.StatusOK, gin.H{"log-level": util.CurrentLogLevels()})
+}
+
+// logLevelModule normalizes a module name; empty means global.
+func logLevelModule(module string) (string, bool) {
+	module = strings.ToLower(strings.TrimSpace(module))
+	return module, module == "" || slices.Contains(config.LogModules, module)
+}
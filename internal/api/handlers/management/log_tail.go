@@ -0,0 +1,87 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLogTailBacklog = 100
+	logTailKeepAlive      = 15 * time.Second
+)
+
+// TailLogs streams log entries as server-sent events: first up to backlog recent entries,
+// then new ones as they are logged, until the client disconnects. Entries can be filtered by
+// minimum level, provider, auth and request ID.
+func (h *Handler) TailLogs(c *gin.Context) {
+	filter := logging.TailFilter{
+		Level:     log.TraceLevel,
+		Provider:  strings.TrimSpace(c.Query("provider")),
+		AuthID:    strings.TrimSpace(c.Query("auth")),
+		RequestID: strings.TrimSpace(c.Query("request-id")),
+	}
+	if raw := strings.TrimSpace(c.Query("level")); raw != "" {
+		level, err := log.ParseLevel(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid level: %s", raw)})
+			return
+		}
+		filter.Level = level
+	}
+	backlog := defaultLogTailBacklog
+	if raw := strings.TrimSpace(c.Query("backlog")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "backlog must be a non-negative integer"})
+			return
+		}
+		backlog = n
+	}
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming not supported"})
+		return
+	}
+
+	recent, entries, cancel := logging.SubscribeLogTail(filter, backlog)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	write := func(entry logging.TailEntry) {
+		data, _ := json.Marshal(entry)
+		_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+	}
+	for _, entry := range recent {
+		write(entry)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(logTailKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			write(entry)
+			flusher.Flush()
+		case <-keepAlive.C:
+			_, _ = c.Writer.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		}
+	}
+}
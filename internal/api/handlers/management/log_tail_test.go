@@ -0,0 +1,64 @@
+package management
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestTailLogsStreamsFilteredEntries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logging.SetupBaseLogger()
+	h := &Handler{}
+	router := gin.New()
+	router.GET("/logs/tail", h.TailLogs)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	if rec := doManagementRequest(router, http.MethodGet, "/logs/tail?level=loud", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid level status = %d", rec.Code)
+	}
+
+	log.WithField("request_id", "tail-mgmt-1").Warn("before subscribe")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/logs/tail?level=warn&request-id=tail-mgmt-1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() string {
+		for {
+			line, errRead := reader.ReadString('\n')
+			if errRead != nil {
+				t.Fatalf("read: %v", errRead)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				return line
+			}
+		}
+	}
+	if line := next(); !strings.Contains(line, "before subscribe") {
+		t.Fatalf("backlog line = %s", line)
+	}
+	log.WithField("request_id", "tail-mgmt-1").Info("below level")
+	log.WithField("request_id", "tail-mgmt-2").Error("other request")
+	log.WithField("request_id", "tail-mgmt-1").Error("after subscribe")
+	if line := next(); !strings.Contains(line, "after subscribe") {
+		t.Fatalf("live line = %s", line)
+	}
+}
@@ -87,13 +87,7 @@ func (h *Handler) GetLogs(c *gin.Context) {
 
 // GetMonitorRequestLogs returns recent request entries for the monitor request log.
 func (h *Handler) GetMonitorRequestLogs(c *gin.Context) {
-	limit, errLimit := parseLimit(c.Query("limit"))
-	if errLimit != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid limit: %v", errLimit)})
-		return
-	}
-	logs := usage.SnapshotRequestLogs(limit)
-	c.JSON(http.StatusOK, gin.H{"logs": logs})
+	writeListJSON(c, "logs", usage.SnapshotRequestLogs(0))
 }
 
 // DeleteLogs removes all rotated log files and truncates the active log.
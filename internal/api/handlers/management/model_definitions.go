@@ -31,3 +31,19 @@ func (h *Handler) GetStaticModelDefinitions(c *gin.Context) {
 		"models":  models,
 	})
 }
+
+// GetModelMetadata returns merged capability metadata for registered models.
+// An optional ?model= query returns a single entry.
+func (h *Handler) GetModelMetadata(c *gin.Context) {
+	modelRegistry := registry.GetGlobalRegistry()
+	if model := strings.TrimSpace(c.Query("model")); model != "" {
+		meta, ok := modelRegistry.GetModelMetadata(model)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "model not found", "model": model})
+			return
+		}
+		c.JSON(http.StatusOK, meta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"models": modelRegistry.ListModelMetadata()})
+}
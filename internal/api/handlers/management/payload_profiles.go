@@ -0,0 +1,98 @@
+package management
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// GetPayloadProfiles returns the payload profiles and their bindings.
+func (h *Handler) GetPayloadProfiles(c *gin.Context) {
+	h.mu.Lock()
+	profiles := h.cfg.PayloadProfiles
+	h.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"payload-profiles": profiles})
+}
+
+// PutPayloadProfiles replaces the payload profiles and their bindings. The configuration is
+// left unchanged when a profile inherits an unknown profile, inheritance forms a cycle or a
+// binding names an unknown profile.
+func (h *Handler) PutPayloadProfiles(c *gin.Context) {
+	var profiles config.PayloadProfilesConfig
+	if err := c.ShouldBindJSON(&profiles); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if err := profiles.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.cfg.PayloadProfiles = profiles
+	h.cfg.SanitizePayloadProfiles()
+	h.persist(c)
+}
+
+// PatchPayloadProfile adds a payload profile, replacing the profile with the same name if there
+// is one.
+func (h *Handler) PatchPayloadProfile(c *gin.Context) {
+	var profile config.PayloadProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.Inherits = strings.TrimSpace(profile.Inherits)
+
+	updated := h.cfg.PayloadProfiles
+	updated.Profiles = slices.Clone(updated.Profiles)
+	idx := slices.IndexFunc(updated.Profiles, func(existing config.PayloadProfile) bool {
+		return existing.Name == profile.Name
+	})
+	if idx >= 0 {
+		updated.Profiles[idx] = profile
+	} else {
+		updated.Profiles = append(updated.Profiles, profile)
+	}
+	if err := updated.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.cfg.PayloadProfiles = updated
+	h.cfg.SanitizePayloadProfiles()
+	h.persist(c)
+}
+
+// DeletePayloadProfile removes the payload profile named by the name query parameter. Profiles
+// still inherited from or bound to something cannot be deleted.
+func (h *Handler) DeletePayloadProfile(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	profiles := &h.cfg.PayloadProfiles
+	if _, ok := profiles.Profile(name); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payload profile not found"})
+		return
+	}
+	remaining := slices.DeleteFunc(slices.Clone(profiles.Profiles), func(profile config.PayloadProfile) bool {
+		return profile.Name == name
+	})
+	check := config.PayloadProfilesConfig{
+		Profiles:   remaining,
+		Base:       profiles.Base,
+		Providers:  profiles.Providers,
+		Models:     profiles.Models,
+		ClientKeys: profiles.ClientKeys,
+	}
+	if err := check.Validate(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "payload profile is in use: " + err.Error()})
+		return
+	}
+	profiles.Profiles = remaining
+	h.persist(c)
+}
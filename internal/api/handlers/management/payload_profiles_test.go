@@ -0,0 +1,66 @@
+package management
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestPayloadProfiles_ValidateUpsertDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router := gin.New()
+	router.PUT("/payload-profiles", h.PutPayloadProfiles)
+	router.PATCH("/payload-profiles", h.PatchPayloadProfile)
+	router.DELETE("/payload-profiles", h.DeletePayloadProfile)
+
+	cycle := `{"profiles":[{"name":"a","inherits":"b"},{"name":"b","inherits":"a"}]}`
+	if rec := doManagementRequest(router, http.MethodPut, "/payload-profiles", cycle); rec.Code != http.StatusBadRequest {
+		t.Fatalf("cycle status = %d", rec.Code)
+	}
+	unknown := `{"profiles":[{"name":"a"}],"providers":{"codex":"missing"}}`
+	if rec := doManagementRequest(router, http.MethodPut, "/payload-profiles", unknown); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown binding status = %d", rec.Code)
+	}
+
+	valid := `{"profiles":[{"name":"base","default":{"temperature":0.2}},{"name":"codex","inherits":"base"}],"base":"base","providers":{"Codex":"codex"}}`
+	if rec := doManagementRequest(router, http.MethodPut, "/payload-profiles", valid); rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if cfg.PayloadProfiles.Providers["codex"] != "codex" {
+		t.Fatalf("providers = %+v", cfg.PayloadProfiles.Providers)
+	}
+
+	if rec := doManagementRequest(router, http.MethodPatch, "/payload-profiles", `{"name":"base","inherits":"codex"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("patch cycle status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/payload-profiles", `{"name":"base","override":{"store":false}}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if profile, _ := cfg.PayloadProfiles.Profile("base"); len(profile.Override) != 1 || len(cfg.PayloadProfiles.Profiles) != 2 {
+		t.Fatalf("profiles after patch = %+v", cfg.PayloadProfiles.Profiles)
+	}
+
+	if rec := doManagementRequest(router, http.MethodDelete, "/payload-profiles?name=base", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("delete in-use status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodDelete, "/payload-profiles?name=missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete missing status = %d", rec.Code)
+	}
+	cfg.PayloadProfiles.Providers = nil
+	if rec := doManagementRequest(router, http.MethodDelete, "/payload-profiles?name=codex", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(cfg.PayloadProfiles.Profiles) != 1 {
+		t.Fatalf("profiles after delete = %+v", cfg.PayloadProfiles.Profiles)
+	}
+}
@@ -0,0 +1,152 @@
+package management
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/metrics"
+	runtimepprof "runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCPUProfileSeconds = 30
+	maxCPUProfileSeconds     = 300
+)
+
+// RuntimeMetrics is a snapshot of the Go runtime of the proxy process.
+type RuntimeMetrics struct {
+	Goroutines   int    `json:"goroutines"`
+	CPUs         int    `json:"cpus"`
+	GoVersion    string `json:"go-version"`
+	HeapAlloc    uint64 `json:"heap-alloc-bytes"`
+	HeapInuse    uint64 `json:"heap-inuse-bytes"`
+	HeapObjects  uint64 `json:"heap-objects"`
+	Sys          uint64 `json:"sys-bytes"`
+	NextGC       uint64 `json:"next-gc-bytes"`
+	GCCycles     uint32 `json:"gc-cycles"`
+	GCCPUPercent string `json:"gc-cpu-percent"`
+	// LastGCPause is the duration of the most recent stop-the-world pause.
+	LastGCPause string `json:"last-gc-pause"`
+	// GCPauseP50 and GCPauseP99 are percentiles of all pauses since the process started.
+	GCPauseP50 string `json:"gc-pause-p50"`
+	GCPauseP99 string `json:"gc-pause-p99"`
+	LastGC     string `json:"last-gc,omitempty"`
+}
+
+// GetRuntimeMetrics reports goroutine, heap and garbage collector statistics.
+func (h *Handler) GetRuntimeMetrics(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	out := RuntimeMetrics{
+		Goroutines:   runtime.NumGoroutine(),
+		CPUs:         runtime.NumCPU(),
+		GoVersion:    runtime.Version(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NextGC:       mem.NextGC,
+		GCCycles:     mem.NumGC,
+		GCCPUPercent: strconv.FormatFloat(mem.GCCPUFraction*100, 'f', 3, 64),
+	}
+	if mem.NumGC > 0 {
+		out.LastGCPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String()
+		out.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+	}
+	sample := []metrics.Sample{{Name: "/sched/pauses/total/gc:seconds"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindFloat64Histogram {
+		hist := sample[0].Value.Float64Histogram()
+		out.GCPauseP50 = histogramQuantile(hist, 0.5).String()
+		out.GCPauseP99 = histogramQuantile(hist, 0.99).String()
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// histogramQuantile returns the upper bound of the bucket holding quantile q of a seconds
+// histogram.
+func histogramQuantile(hist *metrics.Float64Histogram, q float64) time.Duration {
+	var total uint64
+	for _, count := range hist.Counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	target := uint64(q * float64(total))
+	var seen uint64
+	for i, count := range hist.Counts {
+		seen += count
+		if seen > target {
+			upper := hist.Buckets[i+1]
+			if upper > 1e9 {
+				upper = hist.Buckets[i]
+			}
+			return time.Duration(upper * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// GetPprof serves the net/http/pprof profiles: the index, cmdline, profile, symbol, trace and
+// every named runtime profile such as heap or goroutine.
+func (h *Handler) GetPprof(c *gin.Context) {
+	name := strings.Trim(c.Param("name"), "/")
+	switch name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		if runtimepprof.Lookup(name) == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown profile"})
+			return
+		}
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// CaptureCPUProfile records a CPU profile for the number of seconds in the seconds query
+// parameter (default 30) and returns it as a download for go tool pprof.
+func (h *Handler) CaptureCPUProfile(c *gin.Context) {
+	seconds := defaultCPUProfileSeconds
+	if raw := strings.TrimSpace(c.Query("seconds")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxCPUProfileSeconds {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("seconds must be between 1 and %d", maxCPUProfileSeconds)})
+			return
+		}
+		seconds = n
+	}
+	var buf bytes.Buffer
+	if err := runtimepprof.StartCPUProfile(&buf); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot start CPU profile: %v", err)})
+		return
+	}
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.Request.Context().Done():
+	}
+	runtimepprof.StopCPUProfile()
+	if c.Request.Context().Err() != nil {
+		return
+	}
+	filename := fmt.Sprintf("cpu-%s.pprof", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
@@ -0,0 +1,46 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProfilingEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{}
+	router := gin.New()
+	router.GET("/runtime-metrics", h.GetRuntimeMetrics)
+	router.GET("/debug/pprof/*name", h.GetPprof)
+	router.POST("/debug/cpu-profile", h.CaptureCPUProfile)
+
+	rec := doManagementRequest(router, http.MethodGet, "/runtime-metrics", "")
+	var metrics RuntimeMetrics
+	if err := json.Unmarshal(rec.Body.Bytes(), &metrics); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("runtime metrics status = %d, err = %v", rec.Code, err)
+	}
+	if metrics.Goroutines <= 0 || metrics.HeapAlloc == 0 || metrics.GoVersion == "" {
+		t.Fatalf("metrics = %+v", metrics)
+	}
+
+	if rec = doManagementRequest(router, http.MethodGet, "/debug/pprof/", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Fatalf("index status = %d", rec.Code)
+	}
+	if rec = doManagementRequest(router, http.MethodGet, "/debug/pprof/goroutine?debug=1", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Fatalf("goroutine status = %d", rec.Code)
+	}
+	if rec = doManagementRequest(router, http.MethodGet, "/debug/pprof/bogus", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown profile status = %d", rec.Code)
+	}
+
+	if rec = doManagementRequest(router, http.MethodPost, "/debug/cpu-profile?seconds=0", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid seconds status = %d", rec.Code)
+	}
+	rec = doManagementRequest(router, http.MethodPost, "/debug/cpu-profile?seconds=1", "")
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 || !strings.Contains(rec.Header().Get("Content-Disposition"), "cpu-") {
+		t.Fatalf("cpu profile status = %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+}
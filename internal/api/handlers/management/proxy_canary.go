@@ -0,0 +1,139 @@
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+)
+
+// GetProxyRoutingCanary reports the proxy routing canary; canary is null when none is active.
+func (h *Handler) GetProxyRoutingCanary(c *gin.Context) {
+	status, ok := executor.ProxyRoutingCanaryState()
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"canary": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"canary": status})
+}
+
+// StartProxyRoutingCanary routes a share of traffic through a candidate proxy routing. The
+// canary is rolled back automatically when its error rate crosses the threshold within the
+// window; otherwise it keeps serving until promoted or stopped.
+func (h *Handler) StartProxyRoutingCanary(c *gin.Context) {
+	var spec executor.ProxyRoutingCanary
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	h.mu.Lock()
+	ids := canaryProxyIDs(spec)
+	for _, id := range ids {
+		if !reverseProxyEnabled(h.cfg, id) {
+			h.mu.Unlock()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reverse proxy %q not found or disabled", id)})
+			return
+		}
+	}
+	h.mu.Unlock()
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "canary changes no proxy routing"})
+		return
+	}
+
+	status, err := executor.StartProxyRoutingCanary(spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"canary": status})
+}
+
+// PromoteProxyRoutingCanary writes the canary's routing into proxy-routing and
+// proxy-routing-auth and ends the canary. Rolled-back canaries cannot be promoted.
+func (h *Handler) PromoteProxyRoutingCanary(c *gin.Context) {
+	status, ok := executor.ProxyRoutingCanaryState()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no proxy routing canary"})
+		return
+	}
+	if status.State == executor.CanaryStateRolledBack {
+		c.JSON(http.StatusConflict, gin.H{"error": "canary was rolled back: " + status.Reason})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg.ProxyRouting = mergeProxyRouting(h.cfg.ProxyRouting, status.ProxyRouting)
+	if len(status.ProxyRoutingAuth) > 0 && h.cfg.ProxyRoutingAuth == nil {
+		h.cfg.ProxyRoutingAuth = make(map[string]string, len(status.ProxyRoutingAuth))
+	}
+	for key, proxyID := range status.ProxyRoutingAuth {
+		h.cfg.ProxyRoutingAuth[key] = proxyID
+	}
+	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
+		return
+	}
+	executor.StopProxyRoutingCanary()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "proxy routing canary promoted",
+		"proxy-routing":      h.cfg.ProxyRouting,
+		"proxy-routing-auth": h.cfg.ProxyRoutingAuth,
+	})
+}
+
+// StopProxyRoutingCanary ends the canary without changing the configured routing.
+func (h *Handler) StopProxyRoutingCanary(c *gin.Context) {
+	status, ok := executor.StopProxyRoutingCanary()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no proxy routing canary"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"canary": status})
+}
+
+// canaryProxyIDs lists the reverse proxy IDs a canary routes to.
+func canaryProxyIDs(spec executor.ProxyRoutingCanary) []string {
+	var ids []string
+	for _, id := range proxyRoutingFields(&spec.ProxyRouting) {
+		if v := strings.TrimSpace(*id); v != "" {
+			ids = append(ids, v)
+		}
+	}
+	for _, id := range spec.ProxyRoutingAuth {
+		if v := strings.TrimSpace(id); v != "" {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
+func reverseProxyEnabled(cfg *config.Config, id string) bool {
+	for _, proxy := range cfg.ReverseProxies {
+		if proxy.ID == id {
+			return proxy.Enabled
+		}
+	}
+	return false
+}
+
+// mergeProxyRouting returns base with the non-empty routes of overlay applied.
+func mergeProxyRouting(base, overlay config.ProxyRouting) config.ProxyRouting {
+	baseFields := proxyRoutingFields(&base)
+	for i, route := range proxyRoutingFields(&overlay) {
+		if v := strings.TrimSpace(*route); v != "" {
+			*baseFields[i] = v
+		}
+	}
+	return base
+}
+
+func proxyRoutingFields(r *config.ProxyRouting) []*string {
+	return []*string{&r.Codex, &r.Antigravity, &r.Claude, &r.Gemini, &r.GeminiCLI, &r.Vertex, &r.AIStudio, &r.Qwen, &r.IFlow}
+}
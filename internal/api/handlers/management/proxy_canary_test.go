@@ -0,0 +1,52 @@
+package management
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+)
+
+func TestProxyRoutingCanary_StartAndPromote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { executor.StopProxyRoutingCanary() })
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := &config.Config{
+		ProxyRouting: config.ProxyRouting{Codex: "old", Claude: "old"},
+		ReverseProxies: []config.ReverseProxy{
+			{ID: "old", BaseURL: "https://old.example.com", Enabled: true},
+			{ID: "new", BaseURL: "https://new.example.com", Enabled: true},
+			{ID: "off", BaseURL: "https://off.example.com"},
+		},
+	}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router := gin.New()
+	router.POST("/proxy-routing-canary", h.StartProxyRoutingCanary)
+	router.POST("/proxy-routing-canary/promote", h.PromoteProxyRoutingCanary)
+
+	if rec := doManagementRequest(router, http.MethodPost, "/proxy-routing-canary/promote", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("promote without canary status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodPost, "/proxy-routing-canary", `{"proxy-routing":{"codex":"off"},"percent":10}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("disabled proxy status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodPost, "/proxy-routing-canary", `{"proxy-routing":{"codex":"new"},"proxy-routing-auth":{"a.json":"new"},"percent":10}`); rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodPost, "/proxy-routing-canary/promote", ""); rec.Code != http.StatusOK {
+		t.Fatalf("promote status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if cfg.ProxyRouting.Codex != "new" || cfg.ProxyRouting.Claude != "old" || cfg.ProxyRoutingAuth["a.json"] != "new" {
+		t.Fatalf("routing = %+v, auth = %v", cfg.ProxyRouting, cfg.ProxyRoutingAuth)
+	}
+	if _, ok := executor.ProxyRoutingCanaryState(); ok {
+		t.Fatalf("expected canary to end after promotion")
+	}
+}
@@ -0,0 +1,86 @@
+package management
+
+// readOnlyRoutes lists the management routes, as "METHOD path", that neither change the
+// configuration nor add or modify auths. They stay available in read-only mode and to viewer
+// keys; every other route, including those added later, is refused there until listed here.
+// OAuth login routes (*-auth-url) are left out because they add auths.
+var readOnlyRoutes = map[string]bool{
+	"GET /v0/management/usage":                                    true,
+	"GET /v0/management/usage/latency":                            true,
+	"GET /v0/management/usage/export":                             true,
+	"GET /v0/management/monitor/request-logs":                     true,
+	"GET /v0/management/config":                                   true,
+	"GET /v0/management/config.yaml":                              true,
+	"GET /v0/management/latest-version":                           true,
+	"GET /v0/management/debug":                                    true,
+	"GET /v0/management/log-level":                                true,
+	"GET /v0/management/debug/pprof/*name":                        true,
+	"GET /v0/management/runtime-metrics":                          true,
+	"GET /v0/management/startup-report":                           true,
+	"GET /v0/management/logging-to-file":                          true,
+	"GET /v0/management/logs-max-total-size-mb":                   true,
+	"GET /v0/management/error-logs-max-files":                     true,
+	"GET /v0/management/usage-statistics-enabled":                 true,
+	"GET /v0/management/proxy-url":                                true,
+	"GET /v0/management/reverse-proxies":                          true,
+	"GET /v0/management/reverse-proxy-bans":                       true,
+	"GET /v0/management/reverse-proxy-worker-url":                 true,
+	"GET /v0/management/proxy-routing":                            true,
+	"GET /v0/management/proxy-routing-auth":                       true,
+	"GET /v0/management/proxy-routing-canary":                     true,
+	"GET /v0/management/quota-exceeded/switch-project":            true,
+	"GET /v0/management/quota-exceeded/switch-preview-model":      true,
+	"GET /v0/management/api-keys":                                 true,
+	"GET /v0/management/api-key-auth":                             true,
+	"GET /v0/management/api-key-expiry":                           true,
+	"GET /v0/management/client-keys":                              true,
+	"GET /v0/management/client-key-groups":                        true,
+	"GET /v0/management/watermark":                                true,
+	"GET /v0/management/audit-chain/verify":                       true,
+	"GET /v0/management/config-sync":                              true,
+	"GET /v0/management/share-links":                              true,
+	"GET /v0/management/gemini-api-key":                           true,
+	"GET /v0/management/logs":                                     true,
+	"GET /v0/management/logs/tail":                                true,
+	"GET /v0/management/request-error-logs":                       true,
+	"GET /v0/management/request-error-logs/:name":                 true,
+	"GET /v0/management/request-log-by-id/:id":                    true,
+	"GET /v0/management/debug-traces/:id":                         true,
+	"GET /v0/management/translator-conformance":                   true,
+	"GET /v0/management/request-log":                              true,
+	"GET /v0/management/ws-auth":                                  true,
+	"GET /v0/management/ampcode":                                  true,
+	"GET /v0/management/ampcode/upstream-url":                     true,
+	"GET /v0/management/ampcode/upstream-api-key":                 true,
+	"GET /v0/management/ampcode/restrict-management-to-localhost": true,
+	"GET /v0/management/ampcode/model-mappings":                   true,
+	"GET /v0/management/ampcode/force-model-mappings":             true,
+	"GET /v0/management/ampcode/upstream-api-keys":                true,
+	"GET /v0/management/request-retry":                            true,
+	"GET /v0/management/max-retry-interval":                       true,
+	"GET /v0/management/force-model-prefix":                       true,
+	"GET /v0/management/routing/strategy":                         true,
+	"GET /v0/management/routing/session":                          true,
+	"GET /v0/management/claude-api-key":                           true,
+	"GET /v0/management/codex-api-key":                            true,
+	"GET /v0/management/codex-prompt-cache":                       true,
+	"GET /v0/management/openai-compatibility":                     true,
+	"GET /v0/management/vertex-api-key":                           true,
+	"GET /v0/management/oauth-excluded-models":                    true,
+	"GET /v0/management/oauth-model-alias":                        true,
+	"GET /v0/management/auth-files":                               true,
+	"GET /v0/management/auth-files/models":                        true,
+	"GET /v0/management/auth-files/duplicates":                    true,
+	"GET /v0/management/model-definitions/:channel":               true,
+	"GET /v0/management/model-metadata":                           true,
+	"GET /v0/management/thinking-suffixes":                        true,
+	"GET /v0/management/payload-profiles":                         true,
+	"GET /v0/management/auth-files/download":                      true,
+	"GET /v0/management/auth-files/archive":                       true,
+	"GET /v0/management/get-auth-status":                          true,
+	"GET /v0/management/openapi.json":                             true,
+	"POST /v0/management/routing/rules/evaluate":                  true,
+	"POST /v0/management/auths/:id/test-proxy":                    true,
+	"POST /v0/management/auths/:id/test":                          true,
+	"POST /v0/management/debug/cpu-profile":                       true,
+}
@@ -6,12 +6,14 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -25,9 +27,7 @@ func (h *Handler) GetReverseProxies(c *gin.Context) {
 		proxies = []config.ReverseProxy{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"reverse-proxies": proxies,
-	})
+	writeListJSON(c, "reverse-proxies", proxies)
 }
 
 // CreateReverseProxy creates a new reverse proxy configuration.
@@ -247,6 +247,29 @@ func (h *Handler) DeleteReverseProxyWorkerURL(c *gin.Context) {
 	})
 }
 
+// GetReverseProxyBans lists reverse proxies temporarily banned after upstream errors.
+func (h *Handler) GetReverseProxyBans(c *gin.Context) {
+	bans := executor.ReverseProxyBans()
+	h.mu.Lock()
+	names := make(map[string]string, len(h.cfg.ReverseProxies))
+	for _, proxy := range h.cfg.ReverseProxies {
+		names[proxy.ID] = proxy.Name
+	}
+	h.mu.Unlock()
+
+	entries := make([]gin.H, 0, len(bans))
+	for id, until := range bans {
+		entries = append(entries, gin.H{
+			"id":           id,
+			"name":         names[id],
+			"banned-until": until.UTC(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i]["id"].(string) < entries[j]["id"].(string) })
+
+	writeListJSON(c, "bans", entries)
+}
+
 // GetProxyRouting retrieves the proxy routing configuration.
 func (h *Handler) GetProxyRouting(c *gin.Context) {
 	h.mu.Lock()
@@ -0,0 +1,133 @@
+package management
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/routingrules"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// RoutingRulesEvaluateRequest is a hypothetical request to evaluate the routing rules against.
+type RoutingRulesEvaluateRequest struct {
+	Model     string `json:"model"`
+	ClientKey string `json:"client-key,omitempty"`
+	// Tokens is the prompt size to assume.
+	Tokens int `json:"tokens,omitempty"`
+	// Time is an RFC 3339 timestamp to evaluate at. Defaults to now.
+	Time string `json:"time,omitempty"`
+}
+
+// RoutingRulesEvaluation explains how a hypothetical request would be routed.
+type RoutingRulesEvaluation struct {
+	File string `json:"file"`
+	// Providers are the providers serving the model before the rules apply.
+	Providers []string `json:"providers"`
+	// RoutedProviders are the providers left after the matching rule applied.
+	RoutedProviders []string            `json:"routed-providers"`
+	Result          routingrules.Result `json:"result"`
+	// Auths are the IDs of the enabled auths the request could be served by.
+	Auths []string `json:"auths"`
+}
+
+// EvaluateRoutingRules evaluates the routing rules file against a hypothetical request and
+// explains which rule matched and why the rules before it did not, without sending anything
+// upstream.
+func (h *Handler) EvaluateRoutingRules(c *gin.Context) {
+	var req RoutingRulesEvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Model) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+		return
+	}
+	path := ""
+	if h.cfg != nil {
+		path = strings.TrimSpace(h.cfg.RoutingRulesFile)
+	}
+	if path == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "routing-rules-file is not configured"})
+		return
+	}
+	at := time.Now()
+	if req.Time != "" {
+		parsed, err := time.Parse(time.RFC3339, req.Time)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "time must be an RFC 3339 timestamp"})
+			return
+		}
+		at = parsed
+	}
+	rules, err := routingrules.Load(path)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	model := strings.TrimSpace(req.Model)
+	baseModel := thinking.ParseSuffix(model).ModelName
+	providers := util.GetProviderName(baseModel)
+	result := rules.Evaluate(routingrules.Input{Model: model, ClientKey: req.ClientKey, Tokens: req.Tokens, Time: at})
+	routed := providers
+	if result.Matched && result.Route.Provider != "" {
+		routed = nil
+		for _, provider := range providers {
+			if strings.EqualFold(provider, result.Route.Provider) {
+				routed = []string{result.Route.Provider}
+			}
+		}
+	}
+
+	out := RoutingRulesEvaluation{File: path, Providers: nonNilStrings(providers), RoutedProviders: nonNilStrings(routed), Result: result, Auths: []string{}}
+	if h.authManager != nil {
+		out.Auths = eligibleAuthIDs(h.authManager.List(), routed, baseModel, result.Route.AuthTag)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// eligibleAuthIDs lists the enabled auths of providers that serve model and carry tag.
+func eligibleAuthIDs(auths []*coreauth.Auth, providers []string, model, tag string) []string {
+	ids := []string{}
+	reg := registry.GetGlobalRegistry()
+	for _, auth := range auths {
+		if auth == nil || auth.Disabled {
+			continue
+		}
+		served := false
+		for _, provider := range providers {
+			if strings.EqualFold(auth.Provider, provider) {
+				served = true
+				break
+			}
+		}
+		if !served || (reg != nil && !reg.ClientSupportsModel(auth.ID, model)) {
+			continue
+		}
+		if tag != "" && !hasTag(coreauth.AuthTags(auth), tag) {
+			continue
+		}
+		ids = append(ids, auth.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, candidate := range tags {
+		if strings.EqualFold(candidate, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
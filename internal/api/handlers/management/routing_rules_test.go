@@ -0,0 +1,65 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestEvaluateRoutingRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "routing.yaml")
+	rules := "rules:\n  - name: nights\n    when: {hours: \"22:00-06:00\", timezone: UTC}\n    route: {provider: codex, auth-tag: batch}\n"
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	manager := coreauth.NewManager(nil, nil, nil)
+	for _, auth := range []*coreauth.Auth{
+		{ID: "eval-plain", Provider: "codex"},
+		{ID: "eval-tagged", Provider: "codex", Attributes: map[string]string{"tags": "batch, cheap"}},
+	} {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("manager.Register: %v", err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "eval-model"}})
+		id := auth.ID
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	}
+	h := &Handler{cfg: &config.Config{}, authManager: manager}
+	router := gin.New()
+	router.POST("/routing/rules/evaluate", h.EvaluateRoutingRules)
+
+	if rec := doManagementRequest(router, http.MethodPost, "/routing/rules/evaluate", `{"model":"eval-model"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("unconfigured status = %d", rec.Code)
+	}
+	h.cfg.RoutingRulesFile = path
+
+	rec := doManagementRequest(router, http.MethodPost, "/routing/rules/evaluate", `{"model":"eval-model","time":"2026-10-15T23:00:00Z"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var out RoutingRulesEvaluation
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !out.Result.Matched || out.Result.Rule != "nights" || len(out.Auths) != 1 || out.Auths[0] != "eval-tagged" {
+		t.Fatalf("evaluation = %+v", out)
+	}
+
+	rec = doManagementRequest(router, http.MethodPost, "/routing/rules/evaluate", `{"model":"eval-model","time":"2026-10-15T12:00:00Z"}`)
+	out = RoutingRulesEvaluation{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Result.Matched || len(out.Result.Explanations) != 1 || len(out.Auths) != 2 {
+		t.Fatalf("evaluation = %+v", out)
+	}
+}
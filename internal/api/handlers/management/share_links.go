@@ -0,0 +1,141 @@
+package management
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sharelink "github.com/router-for-me/CLIProxyAPI/v6/internal/access/share_link"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const (
+	// shareLinkPrefix marks generated share link keys.
+	shareLinkPrefix = "sk-share-"
+	// defaultShareLinkTTL applies when a share link request sets neither ttl nor expires-at.
+	defaultShareLinkTTL = 24 * time.Hour
+)
+
+// ShareLinkStatus is a share link together with its request counter.
+type ShareLinkStatus struct {
+	config.ShareLink
+	Used int `json:"used"`
+}
+
+// ShareLinkRequest is the body of a share link mint request. TTL is a Go duration such as
+// "24h"; ExpiresAt is an RFC3339 timestamp and takes precedence when both are set.
+type ShareLinkRequest struct {
+	Name        string `json:"name"`
+	Model       string `json:"model"`
+	Auth        string `json:"auth"`
+	MaxRequests int    `json:"max-requests"`
+	TTL         string `json:"ttl"`
+	ExpiresAt   string `json:"expires-at"`
+}
+
+// GetShareLinks lists the active share links and how many requests each has made.
+func (h *Handler) GetShareLinks(c *gin.Context) {
+	h.mu.Lock()
+	h.cfg.SanitizeShareLinks()
+	links := make([]ShareLinkStatus, 0, len(h.cfg.ShareLinks))
+	for _, link := range h.cfg.ShareLinks {
+		links = append(links, ShareLinkStatus{ShareLink: link, Used: sharelink.Used(link.Key)})
+	}
+	h.mu.Unlock()
+
+	writeListJSON(c, "share-links", links)
+}
+
+// CreateShareLink mints a temporary key limited to a model, auth, request count and expiry.
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	var req ShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if req.MaxRequests < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max-requests must not be negative"})
+		return
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(defaultShareLinkTTL)
+	switch {
+	case strings.TrimSpace(req.ExpiresAt) != "":
+		parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(req.ExpiresAt))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires-at must be an RFC3339 timestamp"})
+			return
+		}
+		expiresAt = parsed
+	case strings.TrimSpace(req.TTL) != "":
+		ttl, err := time.ParseDuration(strings.TrimSpace(req.TTL))
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration such as 24h"})
+			return
+		}
+		expiresAt = now.Add(ttl)
+	}
+	if !expiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiry must be in the future"})
+		return
+	}
+	key, err := generateShareLinkKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to generate key: %v", err)})
+		return
+	}
+	link := config.ShareLink{
+		Key:         key,
+		Name:        strings.TrimSpace(req.Name),
+		Model:       strings.TrimSpace(req.Model),
+		Auth:        strings.TrimSpace(req.Auth),
+		MaxRequests: req.MaxRequests,
+		ExpiresAt:   expiresAt.Format(time.RFC3339),
+		CreatedAt:   now.Format(time.RFC3339),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cfg.ShareLinks = append(h.cfg.ShareLinks, link)
+	h.cfg.SanitizeShareLinks()
+	if err = config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"share-link": ShareLinkStatus{ShareLink: link}})
+}
+
+// DeleteShareLink revokes a share link.
+func (h *Handler) DeleteShareLink(c *gin.Context) {
+	key := c.Param("key")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	idx := slices.IndexFunc(h.cfg.ShareLinks, func(link config.ShareLink) bool { return link.Key == key })
+	if idx < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+		return
+	}
+	h.cfg.ShareLinks = slices.Delete(h.cfg.ShareLinks, idx, idx+1)
+	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
+		return
+	}
+	sharelink.Forget(key)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func generateShareLinkKey() (string, error) {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return shareLinkPrefix + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,68 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestShareLinks_MintListRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router := gin.New()
+	router.GET("/share-links", h.GetShareLinks)
+	router.POST("/share-links", h.CreateShareLink)
+	router.DELETE("/share-links/:key", h.DeleteShareLink)
+
+	rec := doManagementRequest(router, http.MethodPost, "/share-links", `{"name":"friend","model":"gpt-5","max-requests":50,"ttl":"24h"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Link ShareLinkStatus `json:"share-link"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	link := created.Link
+	if !strings.HasPrefix(link.Key, shareLinkPrefix) || link.Model != "gpt-5" || link.MaxRequests != 50 {
+		t.Fatalf("unexpected link %+v", link)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, link.ExpiresAt)
+	if err != nil || time.Until(expiresAt) < 23*time.Hour {
+		t.Fatalf("expires-at = %q", link.ExpiresAt)
+	}
+	if len(cfg.ShareLinks) != 1 {
+		t.Fatalf("share links = %+v", cfg.ShareLinks)
+	}
+
+	rec = doManagementRequest(router, http.MethodGet, "/share-links", "")
+	if !strings.Contains(rec.Body.String(), `"used":0`) || !strings.Contains(rec.Body.String(), link.Key) {
+		t.Fatalf("list = %s", rec.Body.String())
+	}
+
+	for _, body := range []string{`{"ttl":"-1h"}`, `{"expires-at":"2000-01-01T00:00:00Z"}`, `{"max-requests":-1}`} {
+		if rec = doManagementRequest(router, http.MethodPost, "/share-links", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d", body, rec.Code)
+		}
+	}
+
+	if rec = doManagementRequest(router, http.MethodDelete, "/share-links/"+link.Key, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+	if len(cfg.ShareLinks) != 0 {
+		t.Fatalf("share links after delete = %+v", cfg.ShareLinks)
+	}
+}
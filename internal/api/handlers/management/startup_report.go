@@ -0,0 +1,23 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/startupreport"
+)
+
+// GetStartupReport returns the report recorded at startup. With refresh=true, or when no report
+// was recorded, the report is rebuilt from the current configuration and auths.
+func (h *Handler) GetStartupReport(c *gin.Context) {
+	if c.Query("refresh") != "true" {
+		if report, ok := startupreport.Latest(); ok {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+	}
+	h.mu.Lock()
+	cfg := h.cfg
+	h.mu.Unlock()
+	c.JSON(http.StatusOK, startupreport.Build(cfg, h.authManager))
+}
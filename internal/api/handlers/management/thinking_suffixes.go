@@ -0,0 +1,108 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+)
+
+// GetThinkingSuffixes lists the custom thinking suffixes.
+func (h *Handler) GetThinkingSuffixes(c *gin.Context) {
+	h.mu.Lock()
+	suffixes := append([]config.ThinkingSuffix{}, h.cfg.ThinkingSuffixes...)
+	h.mu.Unlock()
+
+	writeListJSON(c, "thinking-suffixes", suffixes)
+}
+
+// PutThinkingSuffixes replaces the custom thinking suffixes. Every entry is validated; the
+// list is left unchanged when any entry is invalid.
+func (h *Handler) PutThinkingSuffixes(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var arr []config.ThinkingSuffix
+	if err = json.Unmarshal(data, &arr); err != nil {
+		var obj struct {
+			Items []config.ThinkingSuffix `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &obj); err2 != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		arr = obj.Items
+	}
+	for i, entry := range arr {
+		if errValidate := validateThinkingSuffix(entry); errValidate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("entry %d: %v", i, errValidate)})
+			return
+		}
+	}
+	h.cfg.ThinkingSuffixes = arr
+	h.cfg.SanitizeThinkingSuffixes()
+	h.persist(c)
+}
+
+// PatchThinkingSuffix adds a custom thinking suffix, replacing the entry with the same name
+// and providers if there is one.
+func (h *Handler) PatchThinkingSuffix(c *gin.Context) {
+	var entry config.ThinkingSuffix
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if err := validateThinkingSuffix(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	normalized := config.Config{ThinkingSuffixes: []config.ThinkingSuffix{entry}}
+	normalized.SanitizeThinkingSuffixes()
+	entry = normalized.ThinkingSuffixes[0]
+
+	idx := slices.IndexFunc(h.cfg.ThinkingSuffixes, func(existing config.ThinkingSuffix) bool {
+		return existing.Name == entry.Name && slices.Equal(existing.Providers, entry.Providers)
+	})
+	if idx >= 0 {
+		h.cfg.ThinkingSuffixes[idx] = entry
+	} else {
+		h.cfg.ThinkingSuffixes = append(h.cfg.ThinkingSuffixes, entry)
+	}
+	h.persist(c)
+}
+
+// DeleteThinkingSuffix removes the custom thinking suffixes named by the name query parameter.
+// With provider set, only entries applying to that provider are removed.
+func (h *Handler) DeleteThinkingSuffix(c *gin.Context) {
+	name := strings.ToLower(strings.TrimSpace(c.Query("name")))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	provider := strings.ToLower(strings.TrimSpace(c.Query("provider")))
+	before := len(h.cfg.ThinkingSuffixes)
+	h.cfg.ThinkingSuffixes = slices.DeleteFunc(h.cfg.ThinkingSuffixes, func(entry config.ThinkingSuffix) bool {
+		return entry.Name == name && (provider == "" || slices.Contains(entry.Providers, provider))
+	})
+	if len(h.cfg.ThinkingSuffixes) == before {
+		c.JSON(http.StatusNotFound, gin.H{"error": "thinking suffix not found"})
+		return
+	}
+	h.persist(c)
+}
+
+func validateThinkingSuffix(entry config.ThinkingSuffix) error {
+	return thinking.ValidateSuffixAlias(thinking.SuffixAlias{
+		Name:            entry.Name,
+		Providers:       entry.Providers,
+		Thinking:        entry.Thinking,
+		MaxOutputTokens: entry.MaxOutputTokens,
+	})
+}
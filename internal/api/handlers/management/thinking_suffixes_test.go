@@ -0,0 +1,52 @@
+package management
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestThinkingSuffixes_ValidateAndUpsert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, configFilePath: path}
+	router := gin.New()
+	router.PUT("/thinking-suffixes", h.PutThinkingSuffixes)
+	router.PATCH("/thinking-suffixes", h.PatchThinkingSuffix)
+	router.DELETE("/thinking-suffixes", h.DeleteThinkingSuffix)
+
+	if rec := doManagementRequest(router, http.MethodPut, "/thinking-suffixes", `[{"name":"max","thinking":"ultra"}]`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid thinking status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/thinking-suffixes", `{"name":"max","providers":["bogus"],"thinking":"high"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid provider status = %d", rec.Code)
+	}
+
+	if rec := doManagementRequest(router, http.MethodPatch, "/thinking-suffixes", `{"name":"Max","providers":["Claude"],"thinking":"high","max-output-tokens":64000}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doManagementRequest(router, http.MethodPatch, "/thinking-suffixes", `{"name":"max","providers":["claude"],"thinking":"medium"}`); rec.Code != http.StatusOK {
+		t.Fatalf("upsert status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(cfg.ThinkingSuffixes) != 1 || cfg.ThinkingSuffixes[0].Name != "max" || cfg.ThinkingSuffixes[0].Thinking != "medium" {
+		t.Fatalf("suffixes = %+v", cfg.ThinkingSuffixes)
+	}
+
+	if rec := doManagementRequest(router, http.MethodDelete, "/thinking-suffixes?name=max&provider=codex", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete other provider status = %d", rec.Code)
+	}
+	if rec := doManagementRequest(router, http.MethodDelete, "/thinking-suffixes?name=max", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+	if len(cfg.ThinkingSuffixes) != 0 {
+		t.Fatalf("suffixes after delete = %+v", cfg.ThinkingSuffixes)
+	}
+}
@@ -0,0 +1,35 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/conformance"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// GetTranslatorConformance runs the translator conformance battery and returns the
+// supported/lossy/unsupported matrix. The optional from and to query parameters narrow
+// the report to one source or target format; with both set the pair is run even when
+// no translator is registered for it.
+func (h *Handler) GetTranslatorConformance(c *gin.Context) {
+	from := sdktranslator.FromString(strings.TrimSpace(c.Query("from")))
+	to := sdktranslator.FromString(strings.TrimSpace(c.Query("to")))
+	if from != "" && to != "" {
+		report, err := conformance.Run(from, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reports": []conformance.Report{report}})
+		return
+	}
+	reports := make([]conformance.Report, 0)
+	for _, report := range conformance.RunAll() {
+		if (from == "" || report.From == from.String()) && (to == "" || report.To == to.String()) {
+			reports = append(reports, report)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
@@ -26,12 +26,26 @@ func (h *Handler) GetUsageStatistics(c *gin.Context) {
 	if h != nil && h.usageStats != nil {
 		snapshot = h.usageStats.Snapshot()
 	}
-	c.JSON(http.StatusOK, gin.H{
+	writeJSONWithETag(c, http.StatusOK, gin.H{
 		"usage":           snapshot,
 		"failed_requests": snapshot.FailureCount,
 	})
 }
 
+// GetUsageLatency returns time-to-first-token and tokens-per-second percentiles of streamed
+// responses. The group-by query parameter selects "auth", "provider" or "model"; without it
+// summaries cover each auth, provider and model combination.
+func (h *Handler) GetUsageLatency(c *gin.Context) {
+	groupBy := c.Query("group-by")
+	switch groupBy {
+	case "", usage.LatencyGroupAuth, usage.LatencyGroupProvider, usage.LatencyGroupModel:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group-by must be auth, provider or model"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"latency": usage.LatencySummaries(groupBy)})
+}
+
 // ExportUsageStatistics returns a complete usage snapshot for backup/migration.
 func (h *Handler) ExportUsageStatistics(c *gin.Context) {
 	var snapshot usage.StatisticsSnapshot
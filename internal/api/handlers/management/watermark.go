@@ -0,0 +1,82 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// WatermarkTrace identifies the client key and request a response watermark came from.
+type WatermarkTrace struct {
+	KeyHash   string `json:"key-hash"`
+	RequestID string `json:"request-id,omitempty"`
+	// Key is the masked client key whose hash matches, empty when no configured key does.
+	Key   string `json:"key,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	// Source is where the key is configured: "api-keys", "access-provider:<name>",
+	// "group:<name>" or "share-link".
+	Source string `json:"source,omitempty"`
+}
+
+// TraceWatermark resolves the watermark given in the value query parameter to the client key
+// that produced the response, answering 404 when no configured key matches. Every source of
+// client keys is searched: api-keys, access provider keys, group members and share links.
+func (h *Handler) TraceWatermark(c *gin.Context) {
+	keyHash, requestID, ok := util.ParseWatermark(c.Query("value"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "value is not a response watermark"})
+		return
+	}
+	trace := WatermarkTrace{KeyHash: keyHash, RequestID: requestID}
+
+	h.mu.Lock()
+	traceWatermarkKey(h.cfg, &trace)
+	h.mu.Unlock()
+
+	if trace.Key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no client key matches the watermark", "watermark": trace})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"watermark": trace})
+}
+
+// traceWatermarkKey fills in the key, owner and source of the first configured client key whose
+// hash matches trace.KeyHash.
+func traceWatermarkKey(cfg *config.Config, trace *WatermarkTrace) {
+	match := func(key, source, owner string) bool {
+		if key == "" || util.ClientKeyHash(key) != trace.KeyHash {
+			return false
+		}
+		if owner == "" {
+			owner = cfg.APIKeyMetadata[key].Owner
+		}
+		trace.Key, trace.Owner, trace.Source = util.HideAPIKey(key), owner, source
+		return true
+	}
+	for _, key := range cfg.APIKeys {
+		if match(key, "api-keys", "") {
+			return
+		}
+	}
+	for _, provider := range cfg.Access.Providers {
+		for _, key := range provider.APIKeys {
+			if match(key, "access-provider:"+provider.Name, "") {
+				return
+			}
+		}
+	}
+	for _, group := range cfg.ClientKeyGroups {
+		for _, key := range group.Keys {
+			if match(key, "group:"+group.Name, "") {
+				return
+			}
+		}
+	}
+	for _, link := range cfg.ShareLinks {
+		if match(link.Key, "share-link", link.Name) {
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package openapi generates OpenAPI 3.1 documents from gin route tables and the Go types the
+// handlers exchange, so the served documents always match the routes actually registered.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI specification version of generated documents.
+const Version = "3.1.0"
+
+// Operation annotates a single route.
+type Operation struct {
+	// Summary is a short description of the route.
+	Summary string
+	// Tags group the route in documentation UIs.
+	Tags []string
+	// Request is a value of the JSON request body type, or nil for a free-form object.
+	Request any
+	// Response is a value of the JSON response body type, or nil for a free-form object.
+	Response any
+	// ResponseKey wraps Response in an object under this key, as most management getters do.
+	ResponseKey string
+	// List marks management list endpoints that accept the shared pagination, filtering,
+	// sorting and field selection parameters and report the total number of matches.
+	List bool
+	// Stream marks routes that answer with server-sent events when the client asks for it.
+	Stream bool
+}
+
+// Spec describes one generated document.
+type Spec struct {
+	Title       string
+	Description string
+	Version     string
+	// Include selects the routes documented by the spec.
+	Include func(method, path string) bool
+	// Operations annotates routes keyed by "METHOD /path" in gin path syntax.
+	Operations map[string]Operation
+	// Error is a value of the JSON error body type shared by all routes.
+	Error any
+}
+
+// Build returns the OpenAPI document for the routes selected by the spec.
+func (s Spec) Build(routes gin.RoutesInfo) map[string]any {
+	schemas := newSchemaBuilder()
+	paths := make(map[string]map[string]any)
+
+	sorted := append(gin.RoutesInfo(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	var errorSchema map[string]any
+	if s.Error != nil {
+		errorSchema = schemas.schemaFor(reflect.TypeOf(s.Error))
+	} else {
+		errorSchema = map[string]any{"type": "object"}
+	}
+
+	for _, route := range sorted {
+		if s.Include != nil && !s.Include(route.Method, route.Path) {
+			continue
+		}
+		op := s.Operations[route.Method+" "+route.Path]
+		path, params := convertPath(route.Path)
+		item, ok := paths[path]
+		if !ok {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = s.operation(route, op, params, errorSchema, schemas)
+	}
+
+	doc := map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":   s.Title,
+			"version": s.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}
+	if s.Description != "" {
+		doc["info"].(map[string]any)["description"] = s.Description
+	}
+	return doc
+}
+
+func (s Spec) operation(route gin.RouteInfo, op Operation, params []string, errorSchema map[string]any, schemas *schemaBuilder) map[string]any {
+	out := map[string]any{
+		"operationId": operationID(route.Method, route.Path),
+	}
+	summary := op.Summary
+	if summary == "" {
+		summary = handlerName(route.Handler)
+	}
+	if summary != "" {
+		out["summary"] = summary
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+
+	parameters := make([]any, 0, len(params)+5)
+	for _, name := range params {
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	if op.List {
+		parameters = append(parameters, listParameters()...)
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+
+	if hasBody(route.Method) {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemas.valueSchema(op.Request)},
+			},
+		}
+	}
+
+	success := map[string]any{
+		"application/json": map[string]any{"schema": responseSchema(op, schemas)},
+	}
+	if op.Stream {
+		success["text/event-stream"] = map[string]any{"schema": map[string]any{"type": "string"}}
+	}
+	errorContent := map[string]any{
+		"application/json": map[string]any{"schema": errorSchema},
+	}
+	responses := map[string]any{
+		"200":     map[string]any{"description": "Successful response", "content": success},
+		"default": map[string]any{"description": "Error response", "content": errorContent},
+	}
+	if op.List {
+		responses["304"] = map[string]any{"description": "Not modified; the If-None-Match header names the current ETag"}
+	}
+	out["responses"] = responses
+	return out
+}
+
+func responseSchema(op Operation, schemas *schemaBuilder) map[string]any {
+	schema := schemas.valueSchema(op.Response)
+	if op.ResponseKey == "" {
+		return schema
+	}
+	properties := map[string]any{op.ResponseKey: schema}
+	if op.List {
+		properties["total"] = map[string]any{"type": "integer"}
+		properties["offset"] = map[string]any{"type": "integer"}
+		properties["limit"] = map[string]any{"type": "integer"}
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func listParameters() []any {
+	query := func(name, description string, schema map[string]any) map[string]any {
+		return map[string]any{"name": name, "in": "query", "description": description, "schema": schema}
+	}
+	return []any{
+		query("offset", "Skip the first N matching items.", map[string]any{"type": "integer", "minimum": 0}),
+		query("limit", "Return at most N items.", map[string]any{"type": "integer", "minimum": 1}),
+		query("sort", `Sort by field; prefix with "-" to sort descending.`, map[string]any{"type": "string"}),
+		map[string]any{
+			"name":        "filter",
+			"in":          "query",
+			"description": "Keep items whose field equals value (field:value); repeatable.",
+			"schema":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"style":       "form",
+			"explode":     true,
+		},
+		query("fields", "Comma-separated list of fields to return for each item.", map[string]any{"type": "string"}),
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// convertPath turns gin path parameters (":id", "*action") into OpenAPI templates ("{id}")
+// and returns the parameter names in order.
+func convertPath(path string) (string, []string) {
+	var params []string
+	converted := pathParamPattern.ReplaceAllStringFunc(path, func(match string) string {
+		name := match[1:]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	return converted, params
+}
+
+var operationIDPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func operationID(method, path string) string {
+	id := operationIDPattern.ReplaceAllString(strings.ToLower(method)+"_"+path, "_")
+	return strings.Trim(id, "_")
+}
+
+// handlerName returns the method name of a handler bound to a value, e.g. "GetDebug" for
+// "management.(*Handler).GetDebug-fm", or "" for anonymous functions.
+func handlerName(handler string) string {
+	name := strings.TrimSuffix(handler, "-fm")
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if name == "" || strings.HasPrefix(name, "func") || !strings.Contains(handler, ")") {
+		return ""
+	}
+	return name
+}
+
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
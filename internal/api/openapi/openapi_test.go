@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testItem struct {
+	ID       string            `json:"id"`
+	Note     string            `json:"note,omitempty"`
+	Hidden   string            `json:"-"`
+	Created  time.Time         `json:"created"`
+	Labels   map[string]string `json:"labels"`
+	Children []*testItem       `json:"children"`
+	internal string
+}
+
+func noop(*gin.Context) {}
+
+func buildTestDocument(t *testing.T, spec Spec) map[string]any {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/items", noop)
+	engine.PUT("/api/items/:id", noop)
+	engine.POST("/api/models/*action", noop)
+	engine.GET("/other", noop)
+
+	data, err := json.Marshal(spec.Build(engine.Routes()))
+	if err != nil {
+		t.Fatalf("marshal document: %v", err)
+	}
+	var doc map[string]any
+	if err = json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal document: %v", err)
+	}
+	return doc
+}
+
+func TestBuildConvertsRoutes(t *testing.T) {
+	doc := buildTestDocument(t, Spec{
+		Title:   "Test",
+		Version: "1",
+		Include: func(_, path string) bool { return path != "/other" },
+		Operations: map[string]Operation{
+			"GET /api/items":     {Summary: "List items", Response: []testItem{}, ResponseKey: "items", List: true},
+			"PUT /api/items/:id": {Request: testItem{}},
+		},
+	})
+
+	if doc["openapi"] != Version {
+		t.Fatalf("openapi = %v, want %s", doc["openapi"], Version)
+	}
+	paths := doc["paths"].(map[string]any)
+	if _, ok := paths["/other"]; ok {
+		t.Fatal("excluded route was documented")
+	}
+	for _, path := range []string{"/api/items", "/api/items/{id}", "/api/models/{action}"} {
+		if _, ok := paths[path]; !ok {
+			t.Fatalf("missing path %s in %v", path, paths)
+		}
+	}
+
+	list := paths["/api/items"].(map[string]any)["get"].(map[string]any)
+	if list["summary"] != "List items" {
+		t.Fatalf("summary = %v", list["summary"])
+	}
+	if got := len(list["parameters"].([]any)); got != 5 {
+		t.Fatalf("list parameters = %d, want 5", got)
+	}
+	schema := list["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	items := schema["properties"].(map[string]any)["items"].(map[string]any)
+	if ref := items["items"].(map[string]any)["$ref"]; ref != "#/components/schemas/testItem" {
+		t.Fatalf("items ref = %v", ref)
+	}
+
+	update := paths["/api/items/{id}"].(map[string]any)["put"].(map[string]any)
+	param := update["parameters"].([]any)[0].(map[string]any)
+	if param["name"] != "id" || param["in"] != "path" || param["required"] != true {
+		t.Fatalf("path parameter = %v", param)
+	}
+	if _, ok := update["requestBody"]; !ok {
+		t.Fatal("PUT route has no request body")
+	}
+	if update["operationId"] != "put_api_items_id" {
+		t.Fatalf("operationId = %v", update["operationId"])
+	}
+}
+
+func TestBuildDerivesSchemasFromTypes(t *testing.T) {
+	doc := buildTestDocument(t, Spec{
+		Operations: map[string]Operation{"GET /api/items": {Response: testItem{}}},
+	})
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	props := schemas["testItem"].(map[string]any)["properties"].(map[string]any)
+
+	for _, name := range []string{"Hidden", "internal", "-"} {
+		if _, ok := props[name]; ok {
+			t.Fatalf("property %q should be skipped", name)
+		}
+	}
+	if format := props["created"].(map[string]any)["format"]; format != "date-time" {
+		t.Fatalf("created format = %v", format)
+	}
+	if typ := props["labels"].(map[string]any)["type"]; typ != "object" {
+		t.Fatalf("labels type = %v", typ)
+	}
+	children := props["children"].(map[string]any)["items"].(map[string]any)
+	if children["$ref"] != "#/components/schemas/testItem" {
+		t.Fatalf("self reference = %v", children)
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	cases := map[string]string{
+		"github.com/example/management.(*Handler).GetDebug-fm": "GetDebug",
+		"github.com/example/api.(*Server).setupRoutes.func1":   "",
+		"github.com/example/api.noop":                          "",
+	}
+	for in, want := range cases {
+		if got := handlerName(in); got != want {
+			t.Errorf("handlerName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if hasBody(http.MethodGet) || !hasBody(http.MethodPatch) {
+		t.Fatal("hasBody mismatch")
+	}
+}
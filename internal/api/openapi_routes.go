@@ -0,0 +1,193 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	managementHandlers "github.com/router-for-me/CLIProxyAPI/v6/internal/api/handlers/management"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/openapi"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/configsync"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/startupreport"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/conformance"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+const managementPathPrefix = "/v0/management"
+
+// managementError is the error body returned by management endpoints.
+type managementError struct {
+	Error string `json:"error"`
+}
+
+// managementValue is the body accepted by management endpoints that update a single setting.
+type managementValue[T any] struct {
+	Value T `json:"value"`
+}
+
+// proxyAPISpec documents the client-facing proxy endpoints.
+func proxyAPISpec() openapi.Spec {
+	return openapi.Spec{
+		Title:       "CLI Proxy API",
+		Description: "OpenAI, Claude and Gemini compatible endpoints served by the proxy.",
+		Version:     buildinfo.Version,
+		Include: func(_, path string) bool {
+			for _, prefix := range []string{"/v1/", "/v1beta/", "/v0/client/", "/v1internal"} {
+				if strings.HasPrefix(path, prefix) {
+					return true
+				}
+			}
+			return false
+		},
+		Error: handlers.ErrorResponse{},
+		Operations: map[string]openapi.Operation{
+			"GET /v1/models":                                               {Summary: "List available models", Tags: []string{"models"}},
+			"POST /v1/chat/completions":                                    {Summary: "Create an OpenAI chat completion", Tags: []string{"openai"}, Stream: true},
+			"POST /v1/chat/completions/compact":                            {Summary: "Compact an OpenAI chat conversation", Tags: []string{"openai"}},
+			"POST /v1/completions":                                         {Summary: "Create an OpenAI text completion", Tags: []string{"openai"}, Stream: true},
+			"POST /v1/moderations":                                         {Summary: "Classify input with the moderation backend", Tags: []string{"openai"}},
+			"POST /v1/responses":                                           {Summary: "Create an OpenAI response", Tags: []string{"openai"}, Stream: true},
+			"POST /v1/responses/compact":                                   {Summary: "Compact an OpenAI Responses conversation", Tags: []string{"openai"}},
+			"POST /v1/assistants":                                          {Summary: "Create an assistant", Tags: []string{"assistants"}},
+			"GET /v1/assistants":                                           {Summary: "List assistants", Tags: []string{"assistants"}},
+			"GET /v1/assistants/:assistant_id":                             {Summary: "Get an assistant", Tags: []string{"assistants"}},
+			"POST /v1/assistants/:assistant_id":                            {Summary: "Modify an assistant", Tags: []string{"assistants"}},
+			"DELETE /v1/assistants/:assistant_id":                          {Summary: "Delete an assistant", Tags: []string{"assistants"}},
+			"POST /v1/threads":                                             {Summary: "Create a thread", Tags: []string{"assistants"}},
+			"POST /v1/threads/runs":                                        {Summary: "Create a thread and run it", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id":                                   {Summary: "Get a thread", Tags: []string{"assistants"}},
+			"POST /v1/threads/:thread_id":                                  {Summary: "Modify a thread", Tags: []string{"assistants"}},
+			"DELETE /v1/threads/:thread_id":                                {Summary: "Delete a thread", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id/export":                            {Summary: "Export a thread transcript as OpenAI or Claude messages, Markdown or text", Tags: []string{"assistants"}},
+			"POST /v1/threads/:thread_id/messages":                         {Summary: "Add a message to a thread", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id/messages":                          {Summary: "List thread messages", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id/messages/:message_id":              {Summary: "Get a thread message", Tags: []string{"assistants"}},
+			"POST /v1/threads/:thread_id/runs":                             {Summary: "Run an assistant on a thread", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id/runs":                              {Summary: "List thread runs", Tags: []string{"assistants"}},
+			"GET /v1/threads/:thread_id/runs/:run_id":                      {Summary: "Get a run", Tags: []string{"assistants"}},
+			"POST /v1/threads/:thread_id/runs/:run_id/cancel":              {Summary: "Cancel a run", Tags: []string{"assistants"}},
+			"POST /v1/threads/:thread_id/runs/:run_id/submit_tool_outputs": {Summary: "Submit tool outputs and continue a run", Tags: []string{"assistants"}},
+			"POST /v1/messages":                                            {Summary: "Create a Claude message", Tags: []string{"claude"}, Stream: true},
+			"POST /v1/messages/count_tokens":                               {Summary: "Count the tokens of a Claude message", Tags: []string{"claude"}},
+			"POST /v1/messages/compact":                                    {Summary: "Compact a Claude conversation", Tags: []string{"claude"}},
+			"GET /v1beta/models":                                           {Summary: "List Gemini models", Tags: []string{"gemini"}},
+			"GET /v1beta/models/*action":                                   {Summary: "Get a Gemini model", Tags: []string{"gemini"}},
+			"POST /v1beta/models/*action":                                  {Summary: "Call a Gemini model method such as generateContent", Tags: []string{"gemini"}, Stream: true},
+			"POST /v1internal:method":                                      {Summary: "Call a Gemini CLI internal method", Tags: []string{"gemini"}, Stream: true},
+			"POST /mcp":                                                    {Summary: "Send MCP JSON-RPC messages (streamable HTTP transport)", Tags: []string{"mcp"}},
+			"GET /mcp/sse":                                                 {Summary: "Open an MCP SSE session", Tags: []string{"mcp"}, Stream: true},
+			"POST /mcp/message":                                            {Summary: "Send MCP JSON-RPC messages to an SSE session", Tags: []string{"mcp"}},
+			"GET /v0/client/usage/auth-files":                              {Summary: "Report auth file usage for the calling key", Tags: []string{"usage"}},
+			"GET /v0/client/me":                                            {Summary: "Report usage, budget, rate limits and allowed models of the calling key", Tags: []string{"usage"}, Response: handlers.ClientStanding{}},
+		},
+	}
+}
+
+// managementAPISpec documents the management endpoints.
+func managementAPISpec() openapi.Spec {
+	p := managementPathPrefix
+	return openapi.Spec{
+		Title:       "CLI Proxy API Management",
+		Description: "Runtime configuration, credentials and monitoring of the proxy.",
+		Version:     buildinfo.Version,
+		Include: func(_, path string) bool {
+			return strings.HasPrefix(path, p+"/")
+		},
+		Error: managementError{},
+		Operations: map[string]openapi.Operation{
+			"GET " + p + "/usage":                         {Summary: "Get usage statistics", Tags: []string{"usage"}},
+			"GET " + p + "/usage/latency":                 {Summary: "Get streaming latency percentiles", Tags: []string{"usage"}},
+			"GET " + p + "/usage/export":                  {Summary: "Export usage statistics", Tags: []string{"usage"}},
+			"POST " + p + "/usage/import":                 {Summary: "Import usage statistics", Tags: []string{"usage"}},
+			"GET " + p + "/logs/tail":                     {Summary: "Stream recent and new log lines filtered by level, provider, auth or request ID", Tags: []string{"monitor"}, Stream: true},
+			"GET " + p + "/monitor/request-logs":          {Summary: "List live request logs", Tags: []string{"monitor"}, Response: []usage.RequestLogEntry{}, ResponseKey: "logs", List: true},
+			"GET " + p + "/debug-traces/:id":              {Summary: "Get the debug trace of a request by request ID", Tags: []string{"monitor"}, Response: debugtrace.Trace{}},
+			"GET " + p + "/translator-conformance":        {Summary: "Run the translator conformance battery and return the feature matrix", Tags: []string{"monitor"}, Response: []conformance.Report{}, ResponseKey: "reports", List: true},
+			"GET " + p + "/config":                        {Summary: "Get the running configuration", Tags: []string{"config"}, Response: config.Config{}},
+			"GET " + p + "/debug":                         {Tags: []string{"config"}, Response: map[string]bool{}},
+			"PUT " + p + "/debug":                         {Tags: []string{"config"}, Request: managementValue[bool]{}},
+			"PATCH " + p + "/debug":                       {Tags: []string{"config"}, Request: managementValue[bool]{}},
+			"GET " + p + "/log-level":                     {Summary: "Show the effective and runtime log levels", Tags: []string{"monitor"}, Response: util.LogLevelStatus{}, ResponseKey: "log-level"},
+			"PUT " + p + "/log-level":                     {Summary: "Set a runtime log level, globally or for one module", Tags: []string{"monitor"}, Request: managementHandlers.LogLevelRequest{}, Response: util.LogLevelStatus{}, ResponseKey: "log-level"},
+			"DELETE " + p + "/log-level":                  {Summary: "Clear runtime log levels (?module= clears one)", Tags: []string{"monitor"}, Response: util.LogLevelStatus{}, ResponseKey: "log-level"},
+			"GET " + p + "/debug/pprof/*name":             {Summary: "Serve a net/http/pprof profile (index, heap, goroutine, profile, trace, ...)", Tags: []string{"monitor"}},
+			"POST " + p + "/debug/cpu-profile":            {Summary: "Capture a CPU profile for the given number of seconds (default 30)", Tags: []string{"monitor"}},
+			"GET " + p + "/runtime-metrics":               {Summary: "Get goroutine, heap and GC pause statistics", Tags: []string{"monitor"}, Response: managementHandlers.RuntimeMetrics{}},
+			"GET " + p + "/startup-report":                {Summary: "Get the startup report of providers, auths and likely misconfigurations", Tags: []string{"monitor"}, Response: startupreport.Report{}},
+			"GET " + p + "/audit-chain/verify":            {Summary: "Verify the upstream request audit chain", Tags: []string{"monitor"}, Response: logging.AuditChainStatus{}, ResponseKey: "audit-chain"},
+			"GET " + p + "/config-sync":                   {Summary: "Show the revision the config was synced from", Tags: []string{"monitor"}, Response: configsync.Status{}, ResponseKey: "config-sync"},
+			"GET " + p + "/client-keys":                   {Summary: "List client API keys with their metadata", Tags: []string{"client-keys"}, Response: []managementHandlers.ClientKey{}, ResponseKey: "keys", List: true},
+			"POST " + p + "/client-keys":                  {Summary: "Provision a client API key", Tags: []string{"client-keys"}, Request: managementHandlers.ClientKeyRequest{}},
+			"PATCH " + p + "/client-keys/:key":            {Summary: "Update or disable a client API key", Tags: []string{"client-keys"}, Request: managementHandlers.ClientKeyRequest{}},
+			"DELETE " + p + "/client-keys/:key":           {Summary: "Delete a client API key", Tags: []string{"client-keys"}},
+			"GET " + p + "/client-key-groups":             {Summary: "List client key groups", Tags: []string{"client-keys"}, Response: []config.ClientKeyGroup{}, ResponseKey: "client-key-groups", List: true},
+			"GET " + p + "/watermark":                     {Summary: "Trace a response watermark to its client key and request", Tags: []string{"client-keys"}, Response: managementHandlers.WatermarkTrace{}, ResponseKey: "watermark"},
+			"PUT " + p + "/client-key-groups":             {Summary: "Replace client key groups", Tags: []string{"client-keys"}, Request: []config.ClientKeyGroup{}},
+			"PATCH " + p + "/client-key-groups":           {Summary: "Add or replace a client key group", Tags: []string{"client-keys"}, Request: config.ClientKeyGroup{}},
+			"DELETE " + p + "/client-key-groups":          {Summary: "Delete a client key group", Tags: []string{"client-keys"}},
+			"GET " + p + "/share-links":                   {Summary: "List share links and their request counts", Tags: []string{"share-links"}, Response: []managementHandlers.ShareLinkStatus{}, ResponseKey: "share-links", List: true},
+			"POST " + p + "/share-links":                  {Summary: "Mint a temporary scoped share link", Tags: []string{"share-links"}, Request: managementHandlers.ShareLinkRequest{}},
+			"DELETE " + p + "/share-links/:key":           {Summary: "Revoke a share link", Tags: []string{"share-links"}},
+			"GET " + p + "/reverse-proxies":               {Summary: "List reverse proxies", Tags: []string{"reverse-proxies"}, Response: []config.ReverseProxy{}, ResponseKey: "reverse-proxies", List: true},
+			"POST " + p + "/reverse-proxies":              {Summary: "Create a reverse proxy", Tags: []string{"reverse-proxies"}, Request: config.ReverseProxy{}},
+			"PUT " + p + "/reverse-proxies/:id":           {Summary: "Update a reverse proxy", Tags: []string{"reverse-proxies"}, Request: config.ReverseProxy{}},
+			"PATCH " + p + "/reverse-proxies/:id":         {Summary: "Update a reverse proxy", Tags: []string{"reverse-proxies"}, Request: config.ReverseProxy{}},
+			"DELETE " + p + "/reverse-proxies/:id":        {Summary: "Delete a reverse proxy", Tags: []string{"reverse-proxies"}},
+			"GET " + p + "/reverse-proxy-bans":            {Summary: "List temporarily banned reverse proxies", Tags: []string{"reverse-proxies"}, ResponseKey: "bans", List: true},
+			"GET " + p + "/proxy-routing":                 {Summary: "Get reverse proxy routing", Tags: []string{"reverse-proxies"}},
+			"PUT " + p + "/proxy-routing":                 {Summary: "Update reverse proxy routing", Tags: []string{"reverse-proxies"}, Request: config.ProxyRouting{}},
+			"PATCH " + p + "/proxy-routing":               {Summary: "Update reverse proxy routing", Tags: []string{"reverse-proxies"}, Request: config.ProxyRouting{}},
+			"GET " + p + "/proxy-routing-canary":          {Summary: "Get the proxy routing canary", Tags: []string{"reverse-proxies"}, Response: executor.ProxyRoutingCanaryStatus{}, ResponseKey: "canary"},
+			"POST " + p + "/proxy-routing-canary":         {Summary: "Start a proxy routing canary with automatic rollback", Tags: []string{"reverse-proxies"}, Request: executor.ProxyRoutingCanary{}, Response: executor.ProxyRoutingCanaryStatus{}, ResponseKey: "canary"},
+			"POST " + p + "/proxy-routing-canary/promote": {Summary: "Promote the proxy routing canary into the config", Tags: []string{"reverse-proxies"}},
+			"DELETE " + p + "/proxy-routing-canary":       {Summary: "Stop the proxy routing canary", Tags: []string{"reverse-proxies"}},
+			"GET " + p + "/api-keys":                      {Summary: "List client API keys", Tags: []string{"api-keys"}, Response: []string{}, ResponseKey: "api-keys"},
+			"PUT " + p + "/api-keys":                      {Summary: "Replace client API keys", Tags: []string{"api-keys"}, Request: []string{}},
+			"GET " + p + "/gemini-api-key":                {Summary: "List Gemini API keys", Tags: []string{"providers"}, Response: []config.GeminiKey{}, ResponseKey: "gemini-api-key"},
+			"PUT " + p + "/gemini-api-key":                {Summary: "Replace Gemini API keys", Tags: []string{"providers"}, Request: []config.GeminiKey{}},
+			"GET " + p + "/claude-api-key":                {Summary: "List Claude API keys", Tags: []string{"providers"}, Response: []config.ClaudeKey{}, ResponseKey: "claude-api-key"},
+			"PUT " + p + "/claude-api-key":                {Summary: "Replace Claude API keys", Tags: []string{"providers"}, Request: []config.ClaudeKey{}},
+			"GET " + p + "/codex-api-key":                 {Summary: "List Codex API keys", Tags: []string{"providers"}, Response: []config.CodexKey{}, ResponseKey: "codex-api-key"},
+			"PUT " + p + "/codex-api-key":                 {Summary: "Replace Codex API keys", Tags: []string{"providers"}, Request: []config.CodexKey{}},
+			"GET " + p + "/codex-prompt-cache":            {Summary: "Inspect Codex prompt cache entries and hit rate", Tags: []string{"providers"}, Response: executor.CodexPromptCacheStats{}, ResponseKey: "stats"},
+			"DELETE " + p + "/codex-prompt-cache":         {Summary: "Purge Codex prompt cache entries", Tags: []string{"providers"}},
+			"GET " + p + "/openai-compatibility":          {Summary: "List OpenAI compatible providers", Tags: []string{"providers"}, Response: []config.OpenAICompatibility{}, ResponseKey: "openai-compatibility"},
+			"PUT " + p + "/openai-compatibility":          {Summary: "Replace OpenAI compatible providers", Tags: []string{"providers"}, Request: []config.OpenAICompatibility{}},
+			"GET " + p + "/vertex-api-key":                {Summary: "List Vertex API keys", Tags: []string{"providers"}, Response: []config.VertexCompatKey{}, ResponseKey: "vertex-api-key"},
+			"PUT " + p + "/vertex-api-key":                {Summary: "Replace Vertex API keys", Tags: []string{"providers"}, Request: []config.VertexCompatKey{}},
+			"GET " + p + "/thinking-suffixes":             {Summary: "List custom thinking suffixes", Tags: []string{"thinking"}, Response: []config.ThinkingSuffix{}, ResponseKey: "thinking-suffixes", List: true},
+			"PUT " + p + "/thinking-suffixes":             {Summary: "Replace custom thinking suffixes", Tags: []string{"thinking"}, Request: []config.ThinkingSuffix{}},
+			"PATCH " + p + "/thinking-suffixes":           {Summary: "Add or replace a custom thinking suffix", Tags: []string{"thinking"}, Request: config.ThinkingSuffix{}},
+			"DELETE " + p + "/thinking-suffixes":          {Summary: "Delete a custom thinking suffix", Tags: []string{"thinking"}},
+			"GET " + p + "/payload-profiles":              {Summary: "Get payload profiles and their bindings", Tags: []string{"config"}, Response: config.PayloadProfilesConfig{}, ResponseKey: "payload-profiles"},
+			"PUT " + p + "/payload-profiles":              {Summary: "Replace payload profiles and their bindings", Tags: []string{"config"}, Request: config.PayloadProfilesConfig{}},
+			"PATCH " + p + "/payload-profiles":            {Summary: "Add or replace a payload profile", Tags: []string{"config"}, Request: config.PayloadProfile{}},
+			"DELETE " + p + "/payload-profiles":           {Summary: "Delete an unused payload profile", Tags: []string{"config"}},
+			"GET " + p + "/auth-files":                    {Summary: "List auth files", Tags: []string{"auth-files"}, ResponseKey: "files", List: true},
+			"GET " + p + "/auth-files/duplicates":         {Summary: "List auth files signing in to the same account", Tags: []string{"auth-files"}, Response: []coreauth.DuplicateGroup{}, ResponseKey: "duplicates", List: true},
+			"GET " + p + "/auth-files/archive":            {Summary: "List archived auth files", Tags: []string{"auth-files"}, Response: []managementHandlers.ArchivedAuthFile{}, ResponseKey: "files", List: true},
+			"POST " + p + "/auth-files/archive/restore":   {Summary: "Restore an archived auth file", Tags: []string{"auth-files"}},
+			"DELETE " + p + "/auth-files/archive":         {Summary: "Permanently delete an archived auth file", Tags: []string{"auth-files"}},
+			"POST " + p + "/auths/:id/test-proxy":         {Summary: "Test the outbound proxy of an auth and report its egress IP", Tags: []string{"auth-files"}, Response: managementHandlers.AuthProxyTestResult{}},
+			"POST " + p + "/auths/:id/test":               {Summary: "Send a one-token request upstream through an auth and report the outcome", Tags: []string{"auth-files"}, Response: managementHandlers.AuthProbeResult{}},
+			"POST " + p + "/routing/rules/evaluate":       {Summary: "Explain how the routing rules file routes a hypothetical request", Tags: []string{"routing"}, Request: managementHandlers.RoutingRulesEvaluateRequest{}, Response: managementHandlers.RoutingRulesEvaluation{}},
+			"GET " + p + "/openapi.json":                  {Summary: "Get this OpenAPI document", Tags: []string{"meta"}},
+		},
+	}
+}
+
+// serveOpenAPI responds with the document for spec, built from the routes registered at the
+// time of the request so that late-registered routes are included.
+func (s *Server) serveOpenAPI(spec openapi.Spec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec.Build(s.engine.Routes()))
+	}
+}
@@ -7,6 +7,7 @@ package api
 import (
 	"context"
 	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -27,17 +28,21 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementui"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/claude"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/gemini"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/mcp"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/openai"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v3"
 )
 
@@ -59,10 +64,14 @@ type ServerOption func(*serverOptionConfig)
 
 func defaultRequestLoggerFactory(cfg *config.Config, configPath string) logging.RequestLogger {
 	configDir := filepath.Dir(configPath)
+	logsDir := "logs"
 	if base := util.WritablePath(); base != "" {
-		return logging.NewFileRequestLogger(cfg.RequestLog, filepath.Join(base, "logs"), configDir, cfg.ErrorLogsMaxFiles)
+		logsDir = filepath.Join(base, "logs")
 	}
-	return logging.NewFileRequestLogger(cfg.RequestLog, "logs", configDir, cfg.ErrorLogsMaxFiles)
+	requestLogger := logging.NewFileRequestLogger(cfg.RequestLog, logsDir, configDir, cfg.ErrorLogsMaxFiles)
+	requestLogger.SetRequestLogDiff(cfg.RequestLogDiff)
+	requestLogger.SetRequestLogLimits(cfg.RequestLogMaxBodyBytes, cfg.RequestLogSampleRate)
+	return requestLogger
 }
 
 // WithMiddleware appends additional Gin middleware during server construction.
@@ -260,6 +269,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	webhook.Configure(cfg)
 	misc.SetCodexInstructionsEnabled(cfg.CodexInstructionsEnabled)
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
@@ -320,11 +330,26 @@ func (s *Server) setupRoutes() {
 	s.engine.GET("/management.html", func(c *gin.Context) {
 		c.Redirect(http.StatusTemporaryRedirect, "/panel")
 	})
+	// Embedded dashboard bundled with the binary.
+	s.engine.GET(managementui.BasePath, func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, managementui.BasePath+"/")
+	})
+	s.engine.GET(managementui.BasePath+"/*filepath", s.serveEmbeddedManagementUI(managementui.Handler()))
 	openaiHandlers := openai.NewOpenAIAPIHandler(s.handlers)
 	geminiHandlers := gemini.NewGeminiAPIHandler(s.handlers)
 	geminiCLIHandlers := gemini.NewGeminiCLIAPIHandler(s.handlers)
 	claudeCodeHandlers := claude.NewClaudeCodeAPIHandler(s.handlers)
 	openaiResponsesHandlers := openai.NewOpenAIResponsesAPIHandler(s.handlers)
+	openaiAssistantsHandlers := openai.NewOpenAIAssistantsAPIHandler(s.handlers)
+	mcpHandlers := mcp.NewMCPAPIHandler(s.handlers)
+	mcpHandlers.RegisterTool(mcp.Tool{
+		Name:        "usage",
+		Description: "Report request and token usage of the upstream accounts this API key may use.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		Management:  true,
+	}, func(c *gin.Context, _ gjson.Result) (any, error) {
+		return s.clientAuthFileUsage(handlers.ClientAPIKey(c)), nil
+	})
 
 	// OpenAI compatible API routes
 	v1 := s.engine.Group("/v1")
@@ -332,11 +357,33 @@ func (s *Server) setupRoutes() {
 	{
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
+		v1.POST("/chat/completions/compact", openaiHandlers.ChatCompletionsCompact)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/moderations", openaiHandlers.Moderations)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
+		v1.POST("/messages/compact", claudeCodeHandlers.ClaudeCompact)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
 		v1.POST("/responses/compact", openaiResponsesHandlers.Compact)
+		v1.POST("/assistants", openaiAssistantsHandlers.CreateAssistant)
+		v1.GET("/assistants", openaiAssistantsHandlers.ListAssistants)
+		v1.GET("/assistants/:assistant_id", openaiAssistantsHandlers.GetAssistant)
+		v1.POST("/assistants/:assistant_id", openaiAssistantsHandlers.ModifyAssistant)
+		v1.DELETE("/assistants/:assistant_id", openaiAssistantsHandlers.DeleteAssistant)
+		v1.POST("/threads", openaiAssistantsHandlers.CreateThread)
+		v1.POST("/threads/runs", openaiAssistantsHandlers.CreateThreadAndRun)
+		v1.GET("/threads/:thread_id", openaiAssistantsHandlers.GetThread)
+		v1.POST("/threads/:thread_id", openaiAssistantsHandlers.ModifyThread)
+		v1.DELETE("/threads/:thread_id", openaiAssistantsHandlers.DeleteThread)
+		v1.GET("/threads/:thread_id/export", openaiAssistantsHandlers.ExportThread)
+		v1.POST("/threads/:thread_id/messages", openaiAssistantsHandlers.CreateMessage)
+		v1.GET("/threads/:thread_id/messages", openaiAssistantsHandlers.ListMessages)
+		v1.GET("/threads/:thread_id/messages/:message_id", openaiAssistantsHandlers.GetMessage)
+		v1.POST("/threads/:thread_id/runs", openaiAssistantsHandlers.CreateRun)
+		v1.GET("/threads/:thread_id/runs", openaiAssistantsHandlers.ListRuns)
+		v1.GET("/threads/:thread_id/runs/:run_id", openaiAssistantsHandlers.GetRun)
+		v1.POST("/threads/:thread_id/runs/:run_id/cancel", openaiAssistantsHandlers.CancelRun)
+		v1.POST("/threads/:thread_id/runs/:run_id/submit_tool_outputs", openaiAssistantsHandlers.SubmitToolOutputs)
 	}
 
 	// Gemini compatible API routes
@@ -348,11 +395,21 @@ func (s *Server) setupRoutes() {
 		v1beta.GET("/models/*action", geminiHandlers.GeminiGetHandler)
 	}
 
+	// Model Context Protocol endpoints, enabled with mcp.enabled
+	mcpGroup := s.engine.Group("/mcp")
+	mcpGroup.Use(AuthMiddleware(s.accessManager))
+	{
+		mcpGroup.POST("", mcpHandlers.HandleMessage)
+		mcpGroup.GET("/sse", mcpHandlers.HandleSSE)
+		mcpGroup.POST("/message", mcpHandlers.HandleSSEMessage)
+	}
+
 	// Client-scoped endpoints (API-key auth; no management key required).
 	v0client := s.engine.Group("/v0/client")
 	v0client.Use(AuthMiddleware(s.accessManager))
 	{
 		v0client.GET("/usage/auth-files", s.GetClientAuthFileUsage)
+		v0client.GET("/me", s.GetClientStanding)
 	}
 
 	// Root endpoint
@@ -367,6 +424,7 @@ func (s *Server) setupRoutes() {
 		})
 	})
 	s.engine.POST("/v1internal:method", geminiCLIHandlers.CLIHandler)
+	s.engine.GET("/openapi.json", s.serveOpenAPI(proxyAPISpec()))
 
 	// OAuth callback endpoints (reuse main server port)
 	// These endpoints receive provider redirects and persist
@@ -492,9 +550,10 @@ func (s *Server) registerManagementRoutes() {
 	log.Info("management routes registered after secret key configuration")
 
 	mgmt := s.engine.Group("/v0/management")
-	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
+	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware(), s.mgmt.RedactSecrets())
 	{
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
+		mgmt.GET("/usage/latency", s.mgmt.GetUsageLatency)
 		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
 		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
 		mgmt.GET("/monitor/request-logs", s.mgmt.GetMonitorRequestLogs)
@@ -506,6 +565,13 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/debug", s.mgmt.GetDebug)
 		mgmt.PUT("/debug", s.mgmt.PutDebug)
 		mgmt.PATCH("/debug", s.mgmt.PutDebug)
+		mgmt.GET("/log-level", s.mgmt.GetLogLevel)
+		mgmt.PUT("/log-level", s.mgmt.PutLogLevel)
+		mgmt.DELETE("/log-level", s.mgmt.DeleteLogLevel)
+		mgmt.GET("/debug/pprof/*name", s.mgmt.GetPprof)
+		mgmt.POST("/debug/cpu-profile", s.mgmt.CaptureCPUProfile)
+		mgmt.GET("/runtime-metrics", s.mgmt.GetRuntimeMetrics)
+		mgmt.GET("/startup-report", s.mgmt.GetStartupReport)
 
 		mgmt.GET("/logging-to-file", s.mgmt.GetLoggingToFile)
 		mgmt.PUT("/logging-to-file", s.mgmt.PutLoggingToFile)
@@ -534,6 +600,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/reverse-proxies/:id", s.mgmt.UpdateReverseProxy)
 		mgmt.PATCH("/reverse-proxies/:id", s.mgmt.UpdateReverseProxy)
 		mgmt.DELETE("/reverse-proxies/:id", s.mgmt.DeleteReverseProxy)
+		mgmt.GET("/reverse-proxy-bans", s.mgmt.GetReverseProxyBans)
 		mgmt.GET("/reverse-proxy-worker-url", s.mgmt.GetReverseProxyWorkerURL)
 		mgmt.PUT("/reverse-proxy-worker-url", s.mgmt.PutReverseProxyWorkerURL)
 		mgmt.PATCH("/reverse-proxy-worker-url", s.mgmt.PutReverseProxyWorkerURL)
@@ -549,6 +616,12 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/proxy-routing-auth", s.mgmt.UpdateProxyRoutingAuth)
 		mgmt.PATCH("/proxy-routing-auth", s.mgmt.UpdateProxyRoutingAuth)
 
+		// Canary rollout of proxy routing changes
+		mgmt.GET("/proxy-routing-canary", s.mgmt.GetProxyRoutingCanary)
+		mgmt.POST("/proxy-routing-canary", s.mgmt.StartProxyRoutingCanary)
+		mgmt.POST("/proxy-routing-canary/promote", s.mgmt.PromoteProxyRoutingCanary)
+		mgmt.DELETE("/proxy-routing-canary", s.mgmt.StopProxyRoutingCanary)
+
 		mgmt.POST("/api-call", s.mgmt.APICall)
 
 		mgmt.GET("/quota-exceeded/switch-project", s.mgmt.GetSwitchProject)
@@ -570,16 +643,35 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/api-key-expiry", s.mgmt.PutAPIKeyExpiry)
 		mgmt.PATCH("/api-key-expiry", s.mgmt.PutAPIKeyExpiry)
 
+		mgmt.GET("/client-keys", s.mgmt.GetClientKeys)
+		mgmt.POST("/client-keys", s.mgmt.CreateClientKey)
+		mgmt.PATCH("/client-keys/:key", s.mgmt.UpdateClientKey)
+		mgmt.DELETE("/client-keys/:key", s.mgmt.DeleteClientKey)
+		mgmt.GET("/client-key-groups", s.mgmt.GetClientKeyGroups)
+		mgmt.GET("/watermark", s.mgmt.TraceWatermark)
+		mgmt.GET("/audit-chain/verify", s.mgmt.VerifyAuditChain)
+		mgmt.GET("/config-sync", s.mgmt.GetConfigSync)
+		mgmt.PUT("/client-key-groups", s.mgmt.PutClientKeyGroups)
+		mgmt.PATCH("/client-key-groups", s.mgmt.PatchClientKeyGroup)
+		mgmt.DELETE("/client-key-groups", s.mgmt.DeleteClientKeyGroup)
+
+		mgmt.GET("/share-links", s.mgmt.GetShareLinks)
+		mgmt.POST("/share-links", s.mgmt.CreateShareLink)
+		mgmt.DELETE("/share-links/:key", s.mgmt.DeleteShareLink)
+
 		mgmt.GET("/gemini-api-key", s.mgmt.GetGeminiKeys)
 		mgmt.PUT("/gemini-api-key", s.mgmt.PutGeminiKeys)
 		mgmt.PATCH("/gemini-api-key", s.mgmt.PatchGeminiKey)
 		mgmt.DELETE("/gemini-api-key", s.mgmt.DeleteGeminiKey)
 
 		mgmt.GET("/logs", s.mgmt.GetLogs)
+		mgmt.GET("/logs/tail", s.mgmt.TailLogs)
 		mgmt.DELETE("/logs", s.mgmt.DeleteLogs)
 		mgmt.GET("/request-error-logs", s.mgmt.GetRequestErrorLogs)
 		mgmt.GET("/request-error-logs/:name", s.mgmt.DownloadRequestErrorLog)
 		mgmt.GET("/request-log-by-id/:id", s.mgmt.GetRequestLogByID)
+		mgmt.GET("/debug-traces/:id", s.mgmt.GetDebugTrace)
+		mgmt.GET("/translator-conformance", s.mgmt.GetTranslatorConformance)
 		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
 		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
 		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
@@ -629,6 +721,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/routing/session", s.mgmt.GetRoutingSession)
 		mgmt.PUT("/routing/session", s.mgmt.PutRoutingSession)
 		mgmt.PATCH("/routing/session", s.mgmt.PutRoutingSession)
+		mgmt.POST("/routing/rules/evaluate", s.mgmt.EvaluateRoutingRules)
 
 		mgmt.GET("/claude-api-key", s.mgmt.GetClaudeKeys)
 		mgmt.PUT("/claude-api-key", s.mgmt.PutClaudeKeys)
@@ -639,6 +732,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/codex-api-key", s.mgmt.PutCodexKeys)
 		mgmt.PATCH("/codex-api-key", s.mgmt.PatchCodexKey)
 		mgmt.DELETE("/codex-api-key", s.mgmt.DeleteCodexKey)
+		mgmt.GET("/codex-prompt-cache", s.mgmt.GetCodexPromptCache)
+		mgmt.DELETE("/codex-prompt-cache", s.mgmt.DeleteCodexPromptCache)
 
 		mgmt.GET("/openai-compatibility", s.mgmt.GetOpenAICompat)
 		mgmt.PUT("/openai-compatibility", s.mgmt.PutOpenAICompat)
@@ -662,11 +757,26 @@ func (s *Server) registerManagementRoutes() {
 
 		mgmt.GET("/auth-files", s.mgmt.ListAuthFiles)
 		mgmt.GET("/auth-files/models", s.mgmt.GetAuthFileModels)
+		mgmt.GET("/auth-files/duplicates", s.mgmt.ListDuplicateAuths)
 		mgmt.GET("/model-definitions/:channel", s.mgmt.GetStaticModelDefinitions)
+		mgmt.GET("/model-metadata", s.mgmt.GetModelMetadata)
+		mgmt.GET("/thinking-suffixes", s.mgmt.GetThinkingSuffixes)
+		mgmt.PUT("/thinking-suffixes", s.mgmt.PutThinkingSuffixes)
+		mgmt.PATCH("/thinking-suffixes", s.mgmt.PatchThinkingSuffix)
+		mgmt.DELETE("/thinking-suffixes", s.mgmt.DeleteThinkingSuffix)
+		mgmt.GET("/payload-profiles", s.mgmt.GetPayloadProfiles)
+		mgmt.PUT("/payload-profiles", s.mgmt.PutPayloadProfiles)
+		mgmt.PATCH("/payload-profiles", s.mgmt.PatchPayloadProfile)
+		mgmt.DELETE("/payload-profiles", s.mgmt.DeletePayloadProfile)
 		mgmt.GET("/auth-files/download", s.mgmt.DownloadAuthFile)
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
+		mgmt.GET("/auth-files/archive", s.mgmt.ListArchivedAuthFiles)
+		mgmt.POST("/auth-files/archive/restore", s.mgmt.RestoreAuthFile)
+		mgmt.DELETE("/auth-files/archive", s.mgmt.DeleteArchivedAuthFile)
 		mgmt.PATCH("/auth-files/status", s.mgmt.PatchAuthFileStatus)
+		mgmt.POST("/auths/:id/test-proxy", s.mgmt.TestAuthProxy)
+		mgmt.POST("/auths/:id/test", s.mgmt.TestAuth)
 		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
 
 		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
@@ -678,6 +788,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.POST("/iflow-auth-url", s.mgmt.RequestIFlowCookieToken)
 		mgmt.POST("/oauth-callback", s.mgmt.PostOAuthCallback)
 		mgmt.GET("/get-auth-status", s.mgmt.GetAuthStatus)
+
+		mgmt.GET("/openapi.json", s.serveOpenAPI(managementAPISpec()))
 	}
 }
 
@@ -718,6 +830,17 @@ func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	c.File(filePath)
 }
 
+// serveEmbeddedManagementUI serves the embedded dashboard unless the control panel is disabled.
+func (s *Server) serveEmbeddedManagementUI(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cfg == nil || s.cfg.RemoteManagement.DisableControlPanel {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		next(c)
+	}
+}
+
 func (s *Server) enableKeepAlive(timeout time.Duration, onTimeout func()) {
 	if timeout <= 0 || onTimeout == nil {
 		return
@@ -884,6 +1007,7 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "*")
+		c.Header("Access-Control-Expose-Headers", logging.RequestIDHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -945,6 +1069,18 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if s.requestLogger != nil && (oldCfg == nil || oldCfg.RequestLogDiff != cfg.RequestLogDiff) {
+		if setter, ok := s.requestLogger.(interface{ SetRequestLogDiff(bool) }); ok {
+			setter.SetRequestLogDiff(cfg.RequestLogDiff)
+		}
+	}
+
+	if s.requestLogger != nil && (oldCfg == nil || oldCfg.RequestLogMaxBodyBytes != cfg.RequestLogMaxBodyBytes || oldCfg.RequestLogSampleRate != cfg.RequestLogSampleRate) {
+		if setter, ok := s.requestLogger.(interface{ SetRequestLogLimits(int, float64) }); ok {
+			setter.SetRequestLogLimits(cfg.RequestLogMaxBodyBytes, cfg.RequestLogSampleRate)
+		}
+	}
+
 	if oldCfg == nil || oldCfg.DisableCooling != cfg.DisableCooling {
 		auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 	}
@@ -953,6 +1089,8 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		misc.SetCodexInstructionsEnabled(cfg.CodexInstructionsEnabled)
 	}
 
+	webhook.Configure(cfg)
+
 	if s.handlers != nil && s.handlers.AuthManager != nil {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
@@ -1108,11 +1246,24 @@ func (s *Server) GetClientAuthFileUsage(c *gin.Context) {
 	apiKey, _ := c.Get("apiKey")
 	clientKey, _ := apiKey.(string)
 
+	c.JSON(http.StatusOK, s.clientAuthFileUsage(clientKey))
+}
+
+// GetClientStanding returns the usage, remaining budget, rate-limit status and allowed models
+// of the calling client API key.
+func (s *Server) GetClientStanding(c *gin.Context) {
+	standing := s.handlers.ClientStanding(c)
+	standing.ExpiresAt = s.cfg.APIKeyExpiry[handlers.ClientAPIKey(c)]
+	c.JSON(http.StatusOK, standing)
+}
+
+// clientAuthFileUsage reports usage statistics for the auth files accessible to clientKey.
+func (s *Server) clientAuthFileUsage(clientKey string) gin.H {
 	// Check if usage statistics are enabled
 	usageEnabled := s.cfg.UsageStatisticsEnabled
 
 	// Get allowed auth accounts for this API key
-	allowedAuths := s.cfg.APIKeyAuth[clientKey]
+	allowedAuths, _ := config.LookupClientKey(&s.cfg.SDKConfig, s.cfg.APIKeyAuth, clientKey)
 	restricted := len(allowedAuths) > 0
 
 	// Build response
@@ -1247,10 +1398,10 @@ func (s *Server) GetClientAuthFileUsage(c *gin.Context) {
 		authFiles = []authFileUsage{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"usage_statistics_enabled": usageEnabled,
 		"restricted":               restricted,
 		"totals":                   totals,
 		"auth_files":               authFiles,
-	})
+	}
 }
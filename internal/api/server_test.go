@@ -109,3 +109,73 @@ func TestAmpProviderModelRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestEmbeddedManagementUIRoutes(t *testing.T) {
+	server := newTestServer(t)
+
+	testCases := []struct {
+		path         string
+		wantStatus   int
+		wantContains string
+	}{
+		{path: "/ui", wantStatus: http.StatusMovedPermanently},
+		{path: "/ui/", wantStatus: http.StatusOK, wantContains: "/ui/app.js"},
+		{path: "/ui/app.js", wantStatus: http.StatusOK, wantContains: "/v0/management"},
+		{path: "/ui/usage", wantStatus: http.StatusOK, wantContains: "<title>"},
+	}
+	for _, tc := range testCases {
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rr.Code != tc.wantStatus {
+			t.Fatalf("unexpected status code for %s: got %d want %d", tc.path, rr.Code, tc.wantStatus)
+		}
+		if !strings.Contains(rr.Body.String(), tc.wantContains) {
+			t.Fatalf("response body for %s missing %q", tc.path, tc.wantContains)
+		}
+	}
+
+	server.cfg.RemoteManagement.DisableControlPanel = true
+	rr := httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected disabled control panel to hide /ui, got %d", rr.Code)
+	}
+}
+
+func TestProxyOpenAPIDocument(t *testing.T) {
+	server := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: got %d want %d; body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{`"openapi":"3.1.0"`, `"/v1/chat/completions"`, `"/v1beta/models/{action}"`, `"ErrorResponse"`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("document missing %s", want)
+		}
+	}
+	if strings.Contains(body, "/v0/management/") {
+		t.Fatal("proxy document should not include management routes")
+	}
+}
+
+func TestCompactRoutesRegistered(t *testing.T) {
+	server := newTestServer(t)
+	registered := make(map[string]bool)
+	for _, route := range server.engine.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range []string{
+		"POST /v1/messages/compact",
+		"POST /v1/chat/completions/compact",
+		"POST /v1/responses/compact",
+	} {
+		if !registered[route] {
+			t.Errorf("route %s is not registered", route)
+		}
+	}
+}
@@ -3,12 +3,14 @@
 package claude
 
 import (
+	"crypto/x509"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 
 	tls "github.com/refraction-networking/utls"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/http2"
@@ -26,6 +28,8 @@ type utlsRoundTripper struct {
 	pending map[string]*sync.Cond
 	// dialer is used to create network connections, supporting proxies
 	dialer proxy.Dialer
+	// verifyPins checks the configured tls-pins on every handshake; nil without pins
+	verifyPins func(serverName string, certs []*x509.Certificate) error
 }
 
 // newUtlsRoundTripper creates a new utls-based round tripper with optional proxy support
@@ -49,6 +53,7 @@ func newUtlsRoundTripper(cfg *config.SDKConfig) *utlsRoundTripper {
 		connections: make(map[string]*http2.ClientConn),
 		pending:     make(map[string]*sync.Cond),
 		dialer:      dialer,
+		verifyPins:  util.TLSPinVerifier(cfg),
 	}
 }
 
@@ -108,6 +113,11 @@ func (t *utlsRoundTripper) createConnection(host, addr string) (*http2.ClientCon
 	}
 
 	tlsConfig := &tls.Config{ServerName: host}
+	if t.verifyPins != nil {
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return t.verifyPins(state.ServerName, state.PeerCertificates)
+		}
+	}
 	tlsConn := tls.UClient(conn, tlsConfig, tls.HelloFirefox_Auto)
 
 	if err := tlsConn.Handshake(); err != nil {
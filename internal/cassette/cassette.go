@@ -0,0 +1,203 @@
+// Package cassette records upstream responses together with the client output the response
+// translators produced from them, and replays recorded cassettes to catch translator
+// regressions and upstream format drift.
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Cassette is one recorded upstream response and the client output translated from it.
+type Cassette struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	// Upstream is the format of the upstream response; Client is the format it was translated to.
+	Upstream string `json:"upstream"`
+	Client   string `json:"client"`
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	// Alt is the "alt" context value seen by the translator.
+	Alt string `json:"alt,omitempty"`
+	// OriginalRequest is the client request and Request the translated upstream request, both
+	// with identifying fields removed.
+	OriginalRequest string `json:"original_request"`
+	Request         string `json:"request"`
+	Steps           []Step `json:"steps"`
+}
+
+// Step is one upstream chunk or body and the client output produced from it.
+type Step struct {
+	Response string   `json:"response"`
+	Output   []string `json:"output"`
+}
+
+// sanitizedRequestPaths are request fields that identify users or sessions; they are removed
+// before a cassette is written.
+var sanitizedRequestPaths = []string{"user", "metadata.user_id", "safety_identifier", "prompt_cache_key"}
+
+// volatileOutputField matches output fields that translators fill with generated IDs or
+// timestamps; Verify ignores their values.
+var volatileOutputField = regexp.MustCompile(`"(id|call_id|item_id|tool_use_id|responseId|created|created_at)":\s*("(?:[^"\\]|\\.)*"|-?\d+)`)
+
+// Recorder collects the response translations of one client request into cassettes.
+type Recorder struct {
+	dir       string
+	mu        sync.Mutex
+	cassettes []*Cassette
+	byState   map[*any]*Cassette
+}
+
+// NewRecorder returns a recorder that writes cassettes to dir.
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{dir: dir, byState: make(map[*any]*Cassette)}
+}
+
+// Attach returns a context under which response translations are recorded.
+func (r *Recorder) Attach(ctx context.Context) context.Context {
+	return sdktranslator.WithResponseRecorder(ctx, r.observe)
+}
+
+func (r *Recorder) observe(rec sdktranslator.ResponseRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.byState[rec.State]
+	if !ok || rec.State == nil {
+		c = &Cassette{
+			RecordedAt:      time.Now().UTC(),
+			Upstream:        rec.From.String(),
+			Client:          rec.To.String(),
+			Model:           rec.Model,
+			Stream:          rec.Stream,
+			Alt:             rec.Alt,
+			OriginalRequest: sanitizeRequest(rec.OriginalRequest),
+			Request:         sanitizeRequest(rec.Request),
+		}
+		r.cassettes = append(r.cassettes, c)
+		if rec.State != nil {
+			r.byState[rec.State] = c
+		}
+	}
+	c.Steps = append(c.Steps, Step{Response: string(rec.Response), Output: rec.Output})
+}
+
+// Flush writes the recorded cassettes and resets the recorder. It is safe on a nil recorder.
+func (r *Recorder) Flush() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	cassettes := r.cassettes
+	r.cassettes = nil
+	r.byState = make(map[*any]*Cassette)
+	r.mu.Unlock()
+
+	for _, c := range cassettes {
+		if _, err := Save(r.dir, c); err != nil {
+			log.Warnf("cassette: failed to save %s -> %s cassette: %v", c.Upstream, c.Client, err)
+		}
+	}
+}
+
+// Save writes c to a new file in dir and returns its path.
+func Save(dir string, c *Cassette) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	pattern := fmt.Sprintf("%s_%s-to-%s_%s_*.json", c.RecordedAt.Format("20060102T150405"), c.Upstream, c.Client, fileSafe(c.Model))
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &c, nil
+}
+
+// Verify replays c through the registered response translators and reports the first step
+// whose output differs from the recording, ignoring generated IDs and timestamps.
+func Verify(c *Cassette) error {
+	ctx := context.Background()
+	if c.Alt != "" {
+		ctx = context.WithValue(ctx, "alt", c.Alt)
+	}
+	from := sdktranslator.FromString(c.Upstream)
+	to := sdktranslator.FromString(c.Client)
+	originalRequest := []byte(c.OriginalRequest)
+	request := []byte(c.Request)
+	var param any
+	for i, step := range c.Steps {
+		var got []string
+		if c.Stream {
+			got = sdktranslator.TranslateStream(ctx, from, to, c.Model, originalRequest, request, []byte(step.Response), &param)
+		} else {
+			got = []string{sdktranslator.TranslateNonStream(ctx, from, to, c.Model, originalRequest, request, []byte(step.Response), &param)}
+		}
+		if len(got) != len(step.Output) {
+			return fmt.Errorf("step %d: got %d output chunks, recorded %d", i, len(got), len(step.Output))
+		}
+		for j := range got {
+			if normalizeOutput(got[j]) != normalizeOutput(step.Output[j]) {
+				return fmt.Errorf("step %d chunk %d:\n got: %s\nwant: %s", i, j, got[j], step.Output[j])
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeOutput(output string) string {
+	return volatileOutputField.ReplaceAllString(output, `"$1":"*"`)
+}
+
+func sanitizeRequest(raw []byte) string {
+	out := raw
+	for _, path := range sanitizedRequestPaths {
+		if gjson.GetBytes(out, path).Exists() {
+			if updated, err := sjson.DeleteBytes(out, path); err == nil {
+				out = updated
+			}
+		}
+	}
+	return string(out)
+}
+
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,83 @@
+package cassette
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// TestReplayTestdata replays every recorded cassette under testdata. New cassettes recorded
+// with cassette-record-dir can be dropped there to pin the translation they captured.
+func TestReplayTestdata(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no cassettes in testdata")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			c, errLoad := Load(path)
+			if errLoad != nil {
+				t.Fatalf("Load() error = %v", errLoad)
+			}
+			if errVerify := Verify(c); errVerify != nil {
+				t.Fatalf("Verify() error = %v", errVerify)
+			}
+		})
+	}
+}
+
+func TestRecorderGroupsStreamsAndSanitizes(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecorder(dir)
+	ctx := recorder.Attach(context.Background())
+	from, to := sdktranslator.FromString("openai"), sdktranslator.FromString("openai")
+	original := []byte(`{"model":"m","user":"alice","messages":[]}`)
+
+	var first, second any
+	sdktranslator.TranslateStream(ctx, from, to, "m", original, original, []byte(`data: {"choices":[]}`), &first)
+	sdktranslator.TranslateStream(ctx, from, to, "m", original, original, []byte(`data: {"choices":[]}`), &second)
+	sdktranslator.TranslateStream(ctx, from, to, "m", original, original, []byte(`data: [DONE]`), &first)
+	recorder.Flush()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 cassettes, got %d", len(paths))
+	}
+	var steps []int
+	for _, path := range paths {
+		c, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if strings.Contains(c.OriginalRequest, "alice") || strings.Contains(c.Request, "alice") {
+			t.Fatalf("expected user to be removed: %s", c.OriginalRequest)
+		}
+		steps = append(steps, len(c.Steps))
+	}
+	if steps[0]+steps[1] != 3 || (steps[0] != 1 && steps[0] != 2) {
+		t.Fatalf("unexpected step grouping: %v", steps)
+	}
+}
+
+func TestVerifyDetectsDrift(t *testing.T) {
+	c, err := Load(filepath.Join("testdata", "claude-to-openai.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for i := range c.Steps {
+		if len(c.Steps[i].Output) > 0 && strings.Contains(c.Steps[i].Output[0], `"content":"`) {
+			c.Steps[i].Output[0] = strings.Replace(c.Steps[i].Output[0], `"content":"`, `"content":"drift`, 1)
+			break
+		}
+	}
+	if err = Verify(c); err == nil {
+		t.Fatalf("expected Verify to report changed output")
+	}
+}
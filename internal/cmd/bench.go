@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/claude"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/openai"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// benchProvider is the provider key of the mock upstream used by bench runs.
+const benchProvider = "bench"
+
+// benchOptions configures a synthetic load run against the in-process pipeline.
+type benchOptions struct {
+	requests    int
+	duration    time.Duration
+	concurrency int
+	models      string
+	promptSizes string
+	formats     string
+	streamRatio float64
+	chunks      int
+	cpuProfile  string
+	memProfile  string
+}
+
+func (o *benchOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.requests, "requests", 1000, "Bench: number of requests (ignored when --duration is set)")
+	fs.DurationVar(&o.duration, "duration", 0, "Bench: run for this long instead of a fixed request count")
+	fs.IntVar(&o.concurrency, "concurrency", 16, "Bench: concurrent clients")
+	fs.StringVar(&o.models, "models", "bench-small=3,bench-large=1", "Bench: model mix as name=weight pairs")
+	fs.StringVar(&o.promptSizes, "prompt-sizes", "50,500,4000", "Bench: prompt sizes in words, picked uniformly")
+	fs.StringVar(&o.formats, "formats", "openai,claude", "Bench: client formats (openai, claude), picked uniformly")
+	fs.Float64Var(&o.streamRatio, "stream-ratio", 0.5, "Bench: share of streaming requests (0-1)")
+	fs.IntVar(&o.chunks, "chunks", 32, "Bench: content chunks per mock upstream response")
+	fs.StringVar(&o.cpuProfile, "cpu-profile", "", "Bench: write a CPU profile to this file")
+	fs.StringVar(&o.memProfile, "mem-profile", "", "Bench: write an allocation profile to this file")
+}
+
+type benchModel struct {
+	name   string
+	weight int
+}
+
+// benchPlan is the validated form of benchOptions.
+type benchPlan struct {
+	models      []benchModel
+	totalWeight int
+	promptSizes []int
+	formats     []string
+	streamRatio float64
+	chunks      int
+}
+
+func (o *benchOptions) plan() (*benchPlan, error) {
+	if o.concurrency <= 0 {
+		return nil, errors.New("--concurrency must be positive")
+	}
+	if o.duration <= 0 && o.requests <= 0 {
+		return nil, errors.New("--requests or --duration must be positive")
+	}
+	if o.streamRatio < 0 || o.streamRatio > 1 {
+		return nil, errors.New("--stream-ratio must be between 0 and 1")
+	}
+	p := &benchPlan{streamRatio: o.streamRatio, chunks: max(o.chunks, 1)}
+	for _, entry := range splitList(o.models) {
+		name, weightText, hasWeight := strings.Cut(entry, "=")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(weightText)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid model weight %q", entry)
+			}
+			weight = w
+		}
+		p.models = append(p.models, benchModel{name: strings.TrimSpace(name), weight: weight})
+		p.totalWeight += weight
+	}
+	if len(p.models) == 0 {
+		return nil, errors.New("--models is empty")
+	}
+	for _, entry := range splitList(o.promptSizes) {
+		size, err := strconv.Atoi(entry)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid prompt size %q", entry)
+		}
+		p.promptSizes = append(p.promptSizes, size)
+	}
+	if len(p.promptSizes) == 0 {
+		return nil, errors.New("--prompt-sizes is empty")
+	}
+	for _, format := range splitList(o.formats) {
+		switch format {
+		case "openai", "claude":
+			p.formats = append(p.formats, format)
+		default:
+			return nil, fmt.Errorf("unsupported format %q", format)
+		}
+	}
+	if len(p.formats) == 0 {
+		return nil, errors.New("--formats is empty")
+	}
+	return p, nil
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// benchResult summarizes a bench run.
+type benchResult struct {
+	Requests        int64         `json:"requests"`
+	Errors          int64         `json:"errors"`
+	Streamed        int64         `json:"streamed"`
+	Duration        time.Duration `json:"duration_ns"`
+	RequestsPerSec  float64       `json:"requests_per_second"`
+	LatencyP50Ms    float64       `json:"latency_p50_ms"`
+	LatencyP90Ms    float64       `json:"latency_p90_ms"`
+	LatencyP99Ms    float64       `json:"latency_p99_ms"`
+	AllocsPerReq    float64       `json:"allocs_per_request"`
+	BytesPerReq     float64       `json:"bytes_allocated_per_request"`
+	ResponseBytes   int64         `json:"response_bytes"`
+	FirstError      string        `json:"first_error,omitempty"`
+	firstErrorMutex sync.Mutex
+}
+
+func (e *subcommandEnv) benchRun() error {
+	plan, err := e.bench.plan()
+	if err != nil {
+		return err
+	}
+	if e.bench.cpuProfile != "" {
+		f, errCreate := os.Create(e.bench.cpuProfile)
+		if errCreate != nil {
+			return errCreate
+		}
+		defer func() { _ = f.Close() }()
+		if errStart := pprof.StartCPUProfile(f); errStart != nil {
+			return errStart
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	result, err := runBench(plan, e.bench.requests, e.bench.duration, e.bench.concurrency)
+	if err != nil {
+		return err
+	}
+
+	if e.bench.memProfile != "" {
+		f, errCreate := os.Create(e.bench.memProfile)
+		if errCreate != nil {
+			return errCreate
+		}
+		defer func() { _ = f.Close() }()
+		if errWrite := pprof.Lookup("allocs").WriteTo(f, 0); errWrite != nil {
+			return errWrite
+		}
+	}
+
+	if e.jsonOutput {
+		raw, errMarshal := json.Marshal(result)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		return e.printJSON(raw)
+	}
+	tw := e.table("METRIC", "VALUE")
+	_, _ = fmt.Fprintf(tw, "requests\t%d (%d streamed, %d errors)\n", result.Requests, result.Streamed, result.Errors)
+	_, _ = fmt.Fprintf(tw, "duration\t%s\n", result.Duration.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(tw, "throughput\t%.1f req/s\n", result.RequestsPerSec)
+	_, _ = fmt.Fprintf(tw, "latency p50/p90/p99\t%.2f / %.2f / %.2f ms\n", result.LatencyP50Ms, result.LatencyP90Ms, result.LatencyP99Ms)
+	_, _ = fmt.Fprintf(tw, "allocations\t%.0f allocs/req, %.0f B/req\n", result.AllocsPerReq, result.BytesPerReq)
+	_, _ = fmt.Fprintf(tw, "response bytes\t%d\n", result.ResponseBytes)
+	if result.FirstError != "" {
+		_, _ = fmt.Fprintf(tw, "first error\t%s\n", result.FirstError)
+	}
+	return tw.Flush()
+}
+
+// runBench fires synthetic traffic at the request handlers, auth manager, translators and
+// OpenAI-compatible executor, backed by a mock upstream. It runs until requests have been sent,
+// or for duration when that is positive.
+func runBench(plan *benchPlan, requests int, duration time.Duration, concurrency int) (*benchResult, error) {
+	previousLevel := log.GetLevel()
+	log.SetLevel(log.WarnLevel)
+	defer log.SetLevel(previousLevel)
+	statsEnabled := usage.StatisticsEnabled()
+	usage.SetStatisticsEnabled(false)
+	defer usage.SetStatisticsEnabled(statsEnabled)
+	gin.SetMode(gin.ReleaseMode)
+
+	upstream := httptest.NewServer(benchUpstream(plan.chunks))
+	defer upstream.Close()
+
+	cfg := &config.Config{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor.NewOpenAICompatExecutor(benchProvider, cfg))
+	auth := &coreauth.Auth{
+		ID:         "bench-upstream",
+		Provider:   benchProvider,
+		Status:     coreauth.StatusActive,
+		Attributes: map[string]string{"base_url": upstream.URL, "api_key": "bench"},
+	}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		return nil, err
+	}
+	models := make([]*registry.ModelInfo, 0, len(plan.models))
+	for _, model := range plan.models {
+		models = append(models, &registry.ModelInfo{ID: model.name, Object: "model", OwnedBy: benchProvider})
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, benchProvider, models)
+	defer registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+
+	base := handlers.NewBaseAPIHandlers(&cfg.SDKConfig, manager)
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", openai.NewOpenAIAPIHandler(base).ChatCompletions)
+	engine.POST("/v1/messages", claude.NewClaudeCodeAPIHandler(base).ClaudeMessages)
+
+	result := &benchResult{}
+	var issued atomic.Int64
+	var deadline time.Time
+	if duration > 0 {
+		deadline = time.Now().Add(duration)
+	}
+	latencies := make([][]float64, concurrency)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(worker) + 1))
+			for {
+				if !deadline.IsZero() {
+					if time.Now().After(deadline) {
+						return
+					}
+				} else if issued.Add(1) > int64(requests) {
+					return
+				}
+				elapsed, size, stream, err := benchRequest(engine, plan, rng)
+				latencies[worker] = append(latencies[worker], float64(elapsed.Microseconds())/1000)
+				result.record(size, stream, err)
+			}
+		}(worker)
+	}
+	wg.Wait()
+	result.Duration = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	var all []float64
+	for _, samples := range latencies {
+		all = append(all, samples...)
+	}
+	sort.Float64s(all)
+	result.Requests = int64(len(all))
+	if result.Requests > 0 {
+		result.RequestsPerSec = float64(result.Requests) / result.Duration.Seconds()
+		result.LatencyP50Ms = benchPercentile(all, 50)
+		result.LatencyP90Ms = benchPercentile(all, 90)
+		result.LatencyP99Ms = benchPercentile(all, 99)
+		result.AllocsPerReq = float64(after.Mallocs-before.Mallocs) / float64(result.Requests)
+		result.BytesPerReq = float64(after.TotalAlloc-before.TotalAlloc) / float64(result.Requests)
+	}
+	return result, nil
+}
+
+func (r *benchResult) record(size int, stream bool, err error) {
+	atomic.AddInt64(&r.ResponseBytes, int64(size))
+	if stream {
+		atomic.AddInt64(&r.Streamed, 1)
+	}
+	if err == nil {
+		return
+	}
+	atomic.AddInt64(&r.Errors, 1)
+	r.firstErrorMutex.Lock()
+	if r.FirstError == "" {
+		r.FirstError = err.Error()
+	}
+	r.firstErrorMutex.Unlock()
+}
+
+// benchRequest sends one synthetic request through engine and returns its latency and response size.
+func benchRequest(engine http.Handler, plan *benchPlan, rng *rand.Rand) (time.Duration, int, bool, error) {
+	model := plan.pickModel(rng)
+	prompt := strings.Repeat("lorem ", plan.promptSizes[rng.Intn(len(plan.promptSizes))])
+	stream := rng.Float64() < plan.streamRatio
+	format := plan.formats[rng.Intn(len(plan.formats))]
+
+	var path string
+	var body map[string]any
+	switch format {
+	case "claude":
+		path = "/v1/messages"
+		body = map[string]any{"model": model, "max_tokens": 1024, "stream": stream, "messages": []map[string]any{{"role": "user", "content": prompt}}}
+	default:
+		path = "/v1/chat/completions"
+		body = map[string]any{"model": model, "stream": stream, "messages": []map[string]any{{"role": "user", "content": prompt}}}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, 0, stream, err
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(payload)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	started := time.Now()
+	engine.ServeHTTP(rec, req)
+	elapsed := time.Since(started)
+	if rec.Code != http.StatusOK {
+		return elapsed, rec.Body.Len(), stream, fmt.Errorf("%s %s: status %d: %s", format, model, rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	return elapsed, rec.Body.Len(), stream, nil
+}
+
+func (p *benchPlan) pickModel(rng *rand.Rand) string {
+	n := rng.Intn(p.totalWeight)
+	for _, model := range p.models {
+		if n < model.weight {
+			return model.name
+		}
+		n -= model.weight
+	}
+	return p.models[len(p.models)-1].name
+}
+
+// benchUpstream is a mock OpenAI-compatible chat completions endpoint. Streaming responses send
+// chunks content deltas followed by a usage chunk.
+func benchUpstream(chunks int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		model := gjson.GetBytes(body, "model").String()
+		promptTokens := len(body) / 4
+		if !gjson.GetBytes(body, "stream").Bool() {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"id":"chatcmpl-bench","object":"chat.completion","created":%d,"model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":%d,"completion_tokens":%d,"total_tokens":%d}}`,
+				time.Now().Unix(), model, strings.Repeat("token ", chunks), promptTokens, chunks, promptTokens+chunks)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+		created := time.Now().Unix()
+		for i := 0; i < chunks; i++ {
+			_, _ = fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-bench\",\"object\":\"chat.completion.chunk\",\"created\":%d,\"model\":%q,\"choices\":[{\"index\":0,\"delta\":{\"content\":\"token \"},\"finish_reason\":null}]}\n\n", created, model)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		_, _ = fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-bench\",\"object\":\"chat.completion.chunk\",\"created\":%d,\"model\":%q,\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n", created, model)
+		_, _ = fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-bench\",\"object\":\"chat.completion.chunk\",\"created\":%d,\"model\":%q,\"choices\":[],\"usage\":{\"prompt_tokens\":%d,\"completion_tokens\":%d,\"total_tokens\":%d}}\n\n", created, model, promptTokens, chunks, promptTokens+chunks)
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+}
+
+// benchPercentile returns the nearest-rank percentile of sorted values.
+func benchPercentile(sorted []float64, p float64) float64 {
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(idx, 0)]
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+)
+
+func TestBenchPlanParsesMix(t *testing.T) {
+	opts := &benchOptions{requests: 1, concurrency: 1, models: "a=3, b", promptSizes: "10,20", formats: "openai", streamRatio: 0.5}
+	plan, err := opts.plan()
+	if err != nil {
+		t.Fatalf("plan() error = %v", err)
+	}
+	if len(plan.models) != 2 || plan.totalWeight != 4 || len(plan.promptSizes) != 2 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	opts.formats = "grpc"
+	if _, err = opts.plan(); err == nil {
+		t.Fatalf("expected unsupported format to be rejected")
+	}
+}
+
+func TestRunBenchServesMixedTraffic(t *testing.T) {
+	opts := &benchOptions{models: "bench-test-a=1,bench-test-b=1", promptSizes: "5,50", formats: "openai,claude", streamRatio: 0.5, chunks: 4, concurrency: 1, requests: 1}
+	plan, err := opts.plan()
+	if err != nil {
+		t.Fatalf("plan() error = %v", err)
+	}
+	result, err := runBench(plan, 24, 0, 4)
+	if err != nil {
+		t.Fatalf("runBench() error = %v", err)
+	}
+	if result.Requests != 24 || result.Errors != 0 {
+		t.Fatalf("requests = %d, errors = %d, first error = %q", result.Requests, result.Errors, result.FirstError)
+	}
+	if result.Streamed == 0 || result.Streamed == result.Requests {
+		t.Fatalf("expected a mix of streamed requests, got %d", result.Streamed)
+	}
+	if result.AllocsPerReq <= 0 || result.RequestsPerSec <= 0 || result.ResponseBytes == 0 {
+		t.Fatalf("missing measurements: %+v", result)
+	}
+}
@@ -0,0 +1,509 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	"github.com/tidwall/gjson"
+)
+
+// cliTimeout bounds management API calls made by operator subcommands.
+const cliTimeout = 30 * time.Second
+
+// subcommandUsage documents the operator subcommands accepted by RunSubcommand.
+const subcommandUsage = `Usage:
+  %[1]s auth list [--local]                           List auth accounts (running instance, or auth-dir with --local)
+  %[1]s auth add <provider> [--oauth|--device]       Log in and store a new account (codex, claude, gemini, qwen, iflow, antigravity)
+  %[1]s auth test <id|name> [--model M] [--local]     Send a one-token request upstream through an account
+  %[1]s proxy ban-list                                List reverse proxies temporarily banned after upstream errors
+  %[1]s usage top [--by tokens|requests] [--limit N]  Show the heaviest API key and model pairs
+  %[1]s bench run [--requests N] [--concurrency N]    Fire synthetic traffic at the pipeline with a mock upstream
+  %[1]s mcp stdio [--api-key <key>]                   Serve MCP over stdio, relaying to the running instance
+
+Common flags:
+  --config <path>   Configuration file (default: config.yaml in the working directory)
+  --url <url>       Management API base URL (default: http://127.0.0.1:<port>)
+  --key <key>       Management key (default: $MANAGEMENT_PASSWORD)
+  --api-key <key>   Client API key for mcp (default: $CLIPROXY_API_KEY)
+  --json            Print raw JSON instead of tables
+`
+
+// subcommandEnv carries the shared state of an operator subcommand invocation.
+type subcommandEnv struct {
+	cfg        *config.Config
+	baseURL    string
+	key        string
+	apiKey     string
+	jsonOutput bool
+	local      bool
+	noBrowser  bool
+	oauth      bool
+	device     bool
+	cookie     bool
+	callback   int
+	projectID  string
+	model      string
+	by         string
+	limit      int
+	bench      benchOptions
+	out        io.Writer
+	client     *http.Client
+}
+
+// IsSubcommand reports whether arg names an operator subcommand rather than a server flag.
+func IsSubcommand(arg string) bool {
+	switch arg {
+	case "auth", "proxy", "usage", "bench", "mcp":
+		return true
+	}
+	return false
+}
+
+// RunSubcommand executes an operator subcommand such as "auth list" and returns the process exit code.
+// Subcommands either call the management API of a running instance or operate on auth-dir directly.
+func RunSubcommand(args []string, defaultConfigPath string) int {
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, subcommandUsage, filepath.Base(os.Args[0]))
+		return 2
+	}
+	group, action := args[0], args[1]
+
+	env := &subcommandEnv{out: os.Stdout, client: &http.Client{Timeout: cliTimeout}}
+	var configPath string
+	fs := flag.NewFlagSet(group+" "+action, flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", defaultConfigPath, "Configuration file path")
+	fs.StringVar(&env.baseURL, "url", "", "Management API base URL")
+	fs.StringVar(&env.key, "key", "", "Management key")
+	fs.StringVar(&env.apiKey, "api-key", "", "Client API key (mcp only)")
+	fs.BoolVar(&env.jsonOutput, "json", false, "Print raw JSON")
+	fs.BoolVar(&env.local, "local", false, "Operate on auth-dir instead of a running instance")
+	fs.BoolVar(&env.noBrowser, "no-browser", false, "Don't open browser automatically for OAuth")
+	fs.BoolVar(&env.oauth, "oauth", false, "Log in with the browser OAuth flow (default)")
+	fs.BoolVar(&env.device, "device", false, "Log in with the device code flow instead of OAuth (codex only)")
+	fs.BoolVar(&env.cookie, "cookie", false, "Log in with a cookie instead of OAuth (iflow only)")
+	fs.IntVar(&env.callback, "oauth-callback-port", 0, "Override OAuth callback port")
+	fs.StringVar(&env.projectID, "project_id", "", "Project ID (gemini only)")
+	fs.StringVar(&env.model, "model", "", "Model to probe (auth test only; default: the account's first model)")
+	fs.StringVar(&env.by, "by", "tokens", "Rank usage by tokens or requests")
+	fs.IntVar(&env.limit, "limit", 10, "Number of usage rows to show")
+	env.bench.register(fs)
+
+	positional, errParse := parseInterspersed(fs, args[2:])
+	if errParse != nil {
+		return 2
+	}
+	cfg, errCfg := loadSubcommandConfig(configPath)
+	if errCfg != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", errCfg)
+		return 1
+	}
+	env.cfg = cfg
+	if env.key == "" {
+		env.key = strings.TrimSpace(os.Getenv("MANAGEMENT_PASSWORD"))
+	}
+	if env.apiKey == "" {
+		env.apiKey = strings.TrimSpace(os.Getenv("CLIPROXY_API_KEY"))
+	}
+	if env.baseURL == "" {
+		env.baseURL = defaultManagementBaseURL(cfg)
+	}
+
+	var err error
+	switch group + " " + action {
+	case "auth list":
+		err = env.authList()
+	case "auth add":
+		err = env.authAdd(positional)
+	case "auth test":
+		err = env.authTest(positional)
+	case "proxy ban-list":
+		err = env.proxyBanList()
+	case "usage top":
+		err = env.usageTop()
+	case "bench run":
+		err = env.benchRun()
+	case "mcp stdio":
+		err = env.mcpStdio(os.Stdin)
+	default:
+		fmt.Fprintf(os.Stderr, subcommandUsage, filepath.Base(os.Args[0]))
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", group, action, err)
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func loadSubcommandConfig(configPath string) (*config.Config, error) {
+	if configPath == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		configPath = filepath.Join(wd, "config.yaml")
+	}
+	cfg, err := config.LoadConfigOptional(configPath, true)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	authDir, err := util.ResolveAuthDir(cfg.AuthDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AuthDir = authDir
+	return cfg, nil
+}
+
+func defaultManagementBaseURL(cfg *config.Config) string {
+	port := cfg.Port
+	if port == 0 {
+		port = 8317
+	}
+	scheme := "http"
+	if cfg.TLS.Enable {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d", scheme, port)
+}
+
+// management performs a GET against the management API and returns the response body.
+func (e *subcommandEnv) management(path string, query url.Values) ([]byte, error) {
+	return e.managementDo(http.MethodGet, path, query)
+}
+
+// managementDo calls the management API with method and returns the response body.
+func (e *subcommandEnv) managementDo(method, path string, query url.Values) ([]byte, error) {
+	endpoint := strings.TrimSuffix(e.baseURL, "/") + "/v0/management" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if e.key != "" {
+		req.Header.Set("Authorization", "Bearer "+e.key)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("management API unreachable at %s: %w", e.baseURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if msg := gjson.GetBytes(body, "error").String(); msg != "" {
+			return nil, fmt.Errorf("management API returned %d: %s", resp.StatusCode, msg)
+		}
+		return nil, fmt.Errorf("management API returned %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+func (e *subcommandEnv) printJSON(raw []byte) error {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(e.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+func (e *subcommandEnv) table(header ...string) *tabwriter.Writer {
+	tw := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+	return tw
+}
+
+func (e *subcommandEnv) authList() error {
+	raw, err := e.authFiles()
+	if err != nil {
+		return err
+	}
+	if e.jsonOutput {
+		return e.printJSON(raw)
+	}
+	tw := e.table("NAME", "PROVIDER", "ACCOUNT", "STATUS")
+	for _, file := range gjson.GetBytes(raw, "files").Array() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", file.Get("name").String(), authFileProvider(file), authFileAccount(file), authFileStatus(file))
+	}
+	return tw.Flush()
+}
+
+// authFiles returns the auth file listing from the running instance, or from auth-dir with --local.
+func (e *subcommandEnv) authFiles() ([]byte, error) {
+	if !e.local {
+		return e.management("/auth-files", nil)
+	}
+	if e.cfg.AuthDir == "" {
+		return nil, errors.New("auth-dir is not configured")
+	}
+	entries, err := os.ReadDir(e.cfg.AuthDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth dir: %w", err)
+	}
+	files := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".json") {
+			continue
+		}
+		file := map[string]any{"name": name}
+		if data, errRead := os.ReadFile(filepath.Join(e.cfg.AuthDir, name)); errRead == nil {
+			file["type"] = gjson.GetBytes(data, "type").String()
+			file["email"] = gjson.GetBytes(data, "email").String()
+			file["disabled"] = gjson.GetBytes(data, "disabled").Bool()
+		} else {
+			file["status_message"] = errRead.Error()
+		}
+		files = append(files, file)
+	}
+	return json.Marshal(map[string]any{"files": files})
+}
+
+func (e *subcommandEnv) authAdd(positional []string) error {
+	if len(positional) != 1 {
+		return errors.New("usage: auth add <provider>")
+	}
+	sdkAuth.RegisterTokenStore(sdkAuth.NewFileTokenStore())
+	options := &LoginOptions{NoBrowser: e.noBrowser, CallbackPort: e.callback}
+	switch strings.ToLower(positional[0]) {
+	case "codex":
+		if e.oauth && e.device {
+			return errors.New("--oauth and --device are mutually exclusive")
+		}
+		if e.device {
+			DoCodexDeviceLogin(e.cfg, options)
+		} else {
+			DoCodexLogin(e.cfg, options)
+		}
+	case "claude":
+		DoClaudeLogin(e.cfg, options)
+	case "gemini":
+		DoLogin(e.cfg, e.projectID, options)
+	case "qwen":
+		DoQwenLogin(e.cfg, options)
+	case "iflow":
+		if e.cookie {
+			DoIFlowCookieAuth(e.cfg, options)
+		} else {
+			DoIFlowLogin(e.cfg, options)
+		}
+	case "antigravity":
+		DoAntigravityLogin(e.cfg, options)
+	default:
+		return fmt.Errorf("unsupported provider %q", positional[0])
+	}
+	return nil
+}
+
+// authTest shows an account's status and, against a running instance, sends a one-token
+// request upstream through it. With --local, or for unusable accounts, no request is sent.
+func (e *subcommandEnv) authTest(positional []string) error {
+	if len(positional) != 1 {
+		return errors.New("usage: auth test <id|name>")
+	}
+	target := positional[0]
+	raw, err := e.authFiles()
+	if err != nil {
+		return err
+	}
+	var file gjson.Result
+	for _, candidate := range gjson.GetBytes(raw, "files").Array() {
+		if candidate.Get("id").String() == target || candidate.Get("name").String() == target || candidate.Get("auth_index").String() == target {
+			file = candidate
+			break
+		}
+	}
+	if !file.Exists() {
+		return fmt.Errorf("auth %q not found", target)
+	}
+
+	status := authFileStatus(file)
+	var probe gjson.Result
+	if !e.local && status == "ok" {
+		id := file.Get("id").String()
+		if id == "" {
+			id = file.Get("name").String()
+		}
+		var query url.Values
+		if e.model != "" {
+			query = url.Values{"model": {e.model}}
+		}
+		rawProbe, errProbe := e.managementDo(http.MethodPost, "/auths/"+url.PathEscape(id)+"/test", query)
+		if errProbe != nil {
+			return errProbe
+		}
+		probe = gjson.ParseBytes(rawProbe)
+	}
+	if e.jsonOutput {
+		probeRaw := probe.Raw
+		if probeRaw == "" {
+			probeRaw = "null"
+		}
+		return e.printJSON([]byte(fmt.Sprintf(`{"auth":%s,"probe":%s}`, file.Raw, probeRaw)))
+	}
+
+	_, _ = fmt.Fprintf(e.out, "name:     %s\nprovider: %s\naccount:  %s\nstatus:   %s\n", file.Get("name").String(), authFileProvider(file), authFileAccount(file), status)
+	if status != "ok" {
+		return fmt.Errorf("auth %q is not usable: %s", target, status)
+	}
+	if e.local {
+		return nil
+	}
+	_, _ = fmt.Fprintf(e.out, "model:    %s\nlatency:  %dms\n", probe.Get("model").String(), probe.Get("latency-ms").Int())
+	if !probe.Get("ok").Bool() {
+		_, _ = fmt.Fprintf(e.out, "probe:    failed\n")
+		return fmt.Errorf("auth %q failed the upstream probe: %s", target, probe.Get("error").String())
+	}
+	_, _ = fmt.Fprintf(e.out, "probe:    ok\n")
+	return nil
+}
+
+func (e *subcommandEnv) proxyBanList() error {
+	raw, err := e.management("/reverse-proxy-bans", nil)
+	if err != nil {
+		return err
+	}
+	if e.jsonOutput {
+		return e.printJSON(raw)
+	}
+	tw := e.table("ID", "NAME", "BANNED UNTIL")
+	for _, ban := range gjson.GetBytes(raw, "bans").Array() {
+		until := ban.Get("banned-until").Time().Local().Format(time.RFC3339)
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", ban.Get("id").String(), ban.Get("name").String(), until)
+	}
+	return tw.Flush()
+}
+
+// usageRow aggregates usage for an API key and model pair.
+type usageRow struct {
+	APIKey   string `json:"api_key"`
+	Model    string `json:"model"`
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"`
+}
+
+func (e *subcommandEnv) usageTop() error {
+	if e.by != "tokens" && e.by != "requests" {
+		return fmt.Errorf("--by must be tokens or requests, got %q", e.by)
+	}
+	raw, err := e.management("/usage", nil)
+	if err != nil {
+		return err
+	}
+	rows := topUsageRows(raw, e.by, e.limit)
+	if e.jsonOutput {
+		data, errMarshal := json.Marshal(rows)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		return e.printJSON(data)
+	}
+	tw := e.table("API KEY", "MODEL", "REQUESTS", "TOKENS")
+	for _, row := range rows {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", row.APIKey, row.Model, row.Requests, row.Tokens)
+	}
+	return tw.Flush()
+}
+
+// topUsageRows ranks API key and model pairs from a management usage snapshot.
+func topUsageRows(raw []byte, by string, limit int) []usageRow {
+	rows := make([]usageRow, 0)
+	gjson.GetBytes(raw, "usage.apis").ForEach(func(apiKey, api gjson.Result) bool {
+		api.Get("models").ForEach(func(model, stats gjson.Result) bool {
+			rows = append(rows, usageRow{
+				APIKey:   apiKey.String(),
+				Model:    model.String(),
+				Requests: stats.Get("total_requests").Int(),
+				Tokens:   stats.Get("total_tokens").Int(),
+			})
+			return true
+		})
+		return true
+	})
+	sort.SliceStable(rows, func(i, j int) bool {
+		if by == "requests" && rows[i].Requests != rows[j].Requests {
+			return rows[i].Requests > rows[j].Requests
+		}
+		if rows[i].Tokens != rows[j].Tokens {
+			return rows[i].Tokens > rows[j].Tokens
+		}
+		return rows[i].Requests > rows[j].Requests
+	})
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func authFileProvider(file gjson.Result) string {
+	if provider := file.Get("provider").String(); provider != "" {
+		return provider
+	}
+	return file.Get("type").String()
+}
+
+func authFileAccount(file gjson.Result) string {
+	for _, key := range []string{"email", "account", "label"} {
+		if value := file.Get(key).String(); value != "" {
+			return value
+		}
+	}
+	return "-"
+}
+
+// authFileStatus summarizes an auth file entry as "ok" or the reason it cannot serve requests.
+func authFileStatus(file gjson.Result) string {
+	switch {
+	case file.Get("disabled").Bool():
+		return "disabled"
+	case file.Get("cooldown_active").Bool():
+		return "cooling down until " + file.Get("cooldown_until").String()
+	case file.Get("unavailable").Bool():
+		if msg := file.Get("status_message").String(); msg != "" {
+			return "unavailable: " + msg
+		}
+		return "unavailable"
+	case file.Get("status").String() == "error":
+		return "error: " + file.Get("status_message").String()
+	}
+	return "ok"
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestTopUsageRowsRanksPairs(t *testing.T) {
+	raw := []byte(`{"usage":{"apis":{
+		"key-a":{"models":{"gpt-5":{"total_requests":2,"total_tokens":900},"claude":{"total_requests":9,"total_tokens":100}}},
+		"key-b":{"models":{"gemini":{"total_requests":4,"total_tokens":500}}}
+	}}}`)
+
+	byTokens := topUsageRows(raw, "tokens", 2)
+	if len(byTokens) != 2 || byTokens[0].Model != "gpt-5" || byTokens[1].Model != "gemini" {
+		t.Fatalf("unexpected token ranking: %+v", byTokens)
+	}
+	byRequests := topUsageRows(raw, "requests", 0)
+	if len(byRequests) != 3 || byRequests[0].Model != "claude" || byRequests[0].APIKey != "key-a" {
+		t.Fatalf("unexpected request ranking: %+v", byRequests)
+	}
+}
+
+func TestAuthTestUsesManagementAPI(t *testing.T) {
+	var probed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid management key"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v0/management/auth-files":
+			_, _ = w.Write([]byte(`{"files":[{"id":"a1","name":"codex-a.json","provider":"codex","email":"a@example.com","status":"active"},{"id":"b1","name":"claude-b.json","provider":"claude","disabled":true}]}`))
+		case "/v0/management/auths/a1/test":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			probed = append(probed, r.URL.Query().Get("model"))
+			if r.URL.Query().Get("model") == "gpt-broken" {
+				_, _ = w.Write([]byte(`{"id":"a1","model":"gpt-broken","ok":false,"status":401,"latency-ms":12,"error":"unauthorized"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"a1","model":"gpt-5","ok":true,"latency-ms":34}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	env := &subcommandEnv{cfg: &config.Config{}, baseURL: server.URL, key: "secret", out: &out, client: server.Client()}
+	if err := env.authTest([]string{"codex-a.json"}); err != nil {
+		t.Fatalf("expected healthy auth, got %v", err)
+	}
+	if !strings.Contains(out.String(), "model:    gpt-5") || !strings.Contains(out.String(), "probe:    ok") {
+		t.Fatalf("missing probe result in output: %s", out.String())
+	}
+	env.model = "gpt-broken"
+	if err := env.authTest([]string{"codex-a.json"}); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("expected failed probe to fail, got %v", err)
+	}
+	if err := env.authTest([]string{"b1"}); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Fatalf("expected disabled auth to fail, got %v", err)
+	}
+	if len(probed) != 2 || probed[0] != "" || probed[1] != "gpt-broken" {
+		t.Fatalf("probes = %q, want one default and one gpt-broken probe and none for the disabled auth", probed)
+	}
+
+	env.key = "wrong"
+	if err := env.authList(); err == nil || !strings.Contains(err.Error(), "invalid management key") {
+		t.Fatalf("expected management error to surface, got %v", err)
+	}
+}
+
+func TestAuthListLocalReadsAuthDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gemini-x.json"), []byte(`{"type":"gemini","email":"x@example.com"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	env := &subcommandEnv{cfg: &config.Config{AuthDir: dir}, local: true, out: &out}
+	if err := env.authList(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "gemini-x.json") || !strings.Contains(out.String(), "x@example.com") || strings.Contains(out.String(), "notes.txt") {
+		t.Fatalf("unexpected listing:\n%s", out.String())
+	}
+}
+
+func TestMCPStdioRelaysMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mcp" || r.Header.Get("Authorization") != "Bearer client-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"Invalid API key"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"id"`) {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	}))
+	defer server.Close()
+
+	in := strings.NewReader("{\"jsonrpc\":\"2.0\",\"method\":\"notifications/initialized\"}\n\n{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n")
+	var out bytes.Buffer
+	env := &subcommandEnv{cfg: &config.Config{}, baseURL: server.URL, apiKey: "client-key", out: &out, client: server.Client()}
+	if err := env.mcpStdio(in); err != nil {
+		t.Fatalf("mcpStdio: %v", err)
+	}
+	if got := out.String(); got != "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	out.Reset()
+	env.apiKey = "wrong"
+	if err := env.mcpStdio(strings.NewReader("{\"jsonrpc\":\"2.0\",\"id\":7,\"method\":\"ping\"}\n")); err != nil {
+		t.Fatalf("mcpStdio: %v", err)
+	}
+	if !strings.Contains(out.String(), `"id":7`) || !strings.Contains(out.String(), "Invalid API key") {
+		t.Fatalf("expected JSON-RPC error for rejected key, got %q", out.String())
+	}
+}
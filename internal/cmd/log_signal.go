@@ -0,0 +1,35 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchLogLevelSignal toggles debug logging on SIGUSR1 until ctx is done, so a running server
+// can be debugged briefly without editing its config.
+func watchLogLevelSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if util.ToggleDebugLogging() {
+					log.Warn("SIGUSR1: debug logging forced on; send SIGUSR1 again to restore the configured levels")
+				} else {
+					log.Warn("SIGUSR1: restored the configured log levels")
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,7 @@
+package cmd
+
+import "context"
+
+// watchLogLevelSignal is a no-op: Windows has no SIGUSR1. Use the management log-level
+// endpoint instead.
+func watchLogLevelSignal(context.Context) {}
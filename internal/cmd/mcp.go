@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// mcpMaxMessageBytes bounds one JSON-RPC message read from stdin.
+const mcpMaxMessageBytes = 16 << 20
+
+// mcpStdio serves the MCP stdio transport by relaying each newline-delimited JSON-RPC message
+// read from in to the running instance's /mcp endpoint and writing the responses to e.out.
+// Messages are relayed concurrently so a long sampling call does not hold up pings.
+func (e *subcommandEnv) mcpStdio(in io.Reader) error {
+	endpoint := strings.TrimSuffix(e.baseURL, "/") + "/mcp"
+	// Sampling calls can outlast the management API timeout.
+	client := &http.Client{Transport: e.client.Transport}
+
+	var outMu sync.Mutex
+	write := func(message []byte) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		_, _ = e.out.Write(append(bytes.TrimSpace(message), '\n'))
+	}
+
+	var wg sync.WaitGroup
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), mcpMaxMessageBytes)
+	for scanner.Scan() {
+		message := bytes.TrimSpace(scanner.Bytes())
+		if len(message) == 0 {
+			continue
+		}
+		message = append([]byte(nil), message...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := e.relayMCP(client, endpoint, message)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "mcp: %v\n", err)
+				if id := gjson.GetBytes(message, "id"); id.Exists() {
+					errResp := `{"jsonrpc":"2.0","id":null,"error":{"code":-32603,"message":""}}`
+					errResp, _ = sjson.SetRaw(errResp, "id", id.Raw)
+					errResp, _ = sjson.Set(errResp, "error.message", err.Error())
+					write([]byte(errResp))
+				}
+				return
+			}
+			if len(resp) > 0 {
+				write(resp)
+			}
+		}()
+	}
+	wg.Wait()
+	return scanner.Err()
+}
+
+// relayMCP posts one message to endpoint and returns the JSON-RPC response body, which is
+// empty for notifications.
+func (e *subcommandEnv) relayMCP(client *http.Client, endpoint string, message []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("MCP endpoint unreachable at %s: %w", e.baseURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		msg := gjson.GetBytes(body, "error.message").String()
+		if msg == "" {
+			msg = gjson.GetBytes(body, "error").String()
+		}
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("MCP endpoint returned %d: %s", resp.StatusCode, msg)
+	}
+	return body, nil
+}
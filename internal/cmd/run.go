@@ -32,6 +32,7 @@ func StartService(cfg *config.Config, configPath string, localPassword string) {
 
 	ctxSignal, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
+	watchLogLevelSignal(ctxSignal)
 
 	runCtx := ctxSignal
 	if localPassword != "" {
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientKeyGroupPrefix marks a per-key setting that applies to every key of a group, as in
+// api-key-auth: {"group:team-a": [...]}.
+const ClientKeyGroupPrefix = "group:"
+
+// ClientKeyGroup names a set of client API keys sharing permissions. Settings keyed by client
+// key (api-key-auth, api-key-catalogs, token-budgets, cost-ceiling key limits, stream limits,
+// moderation and repeated-prompt overrides, codex built-in tool policies and payload profiles)
+// accept "group:<name>" entries, which apply to the group's keys that have no entry of their own.
+type ClientKeyGroup struct {
+	// Name identifies the group.
+	Name string `yaml:"name" json:"name"`
+
+	// Description is free text for operators.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Keys are the client API keys in the group. A key in several groups inherits from the
+	// first one listed.
+	Keys []string `yaml:"keys,omitempty" json:"keys,omitempty"`
+}
+
+// ClientKeyGroup returns the group with the given name.
+func (c *SDKConfig) ClientKeyGroup(name string) (ClientKeyGroup, bool) {
+	if c == nil {
+		return ClientKeyGroup{}, false
+	}
+	for _, group := range c.ClientKeyGroups {
+		if group.Name == name {
+			return group, true
+		}
+	}
+	return ClientKeyGroup{}, false
+}
+
+// ClientKeyGroupsOf returns the names of the groups containing key, in config order.
+func (c *SDKConfig) ClientKeyGroupsOf(key string) []string {
+	if c == nil || key == "" {
+		return nil
+	}
+	var names []string
+	for _, group := range c.ClientKeyGroups {
+		for _, member := range group.Keys {
+			if member == key {
+				names = append(names, group.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// ClientKeyRefs returns the keys accepted by per-key settings: the client API keys followed by
+// a "group:<name>" reference for every group.
+func (c *SDKConfig) ClientKeyRefs() []string {
+	if c == nil {
+		return nil
+	}
+	refs := append([]string{}, c.APIKeys...)
+	for _, group := range c.ClientKeyGroups {
+		refs = append(refs, ClientKeyGroupPrefix+group.Name)
+	}
+	return refs
+}
+
+// LookupClientKey returns the entry of a per-key setting for key: its own entry when it has
+// one, otherwise the entry of its first group that has one.
+func LookupClientKey[V any](c *SDKConfig, entries map[string]V, key string) (V, bool) {
+	if value, ok := entries[key]; ok || key == "" || len(entries) == 0 {
+		return value, ok
+	}
+	for _, name := range c.ClientKeyGroupsOf(key) {
+		if value, ok := entries[ClientKeyGroupPrefix+name]; ok {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// ValidateClientKeyGroups reports the first problem with groups: a missing or duplicate name.
+func ValidateClientKeyGroups(groups []ClientKeyGroup) error {
+	names := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		name := strings.TrimSpace(group.Name)
+		if name == "" {
+			return fmt.Errorf("client key group without a name")
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate client key group %q", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// SanitizeClientKeyGroups trims group names and keys, drops unnamed and duplicate groups, and
+// removes blank and repeated keys.
+func (cfg *Config) SanitizeClientKeyGroups() {
+	if cfg == nil || len(cfg.ClientKeyGroups) == 0 {
+		return
+	}
+	groups := make([]ClientKeyGroup, 0, len(cfg.ClientKeyGroups))
+	names := make(map[string]bool, len(cfg.ClientKeyGroups))
+	for _, group := range cfg.ClientKeyGroups {
+		group.Name = strings.TrimSpace(group.Name)
+		group.Description = strings.TrimSpace(group.Description)
+		if group.Name == "" || names[group.Name] {
+			log.Warnf("client-key-groups: dropping unnamed or duplicate group %q", group.Name)
+			continue
+		}
+		names[group.Name] = true
+		seen := make(map[string]bool, len(group.Keys))
+		keys := make([]string, 0, len(group.Keys))
+		for _, key := range group.Keys {
+			if key = strings.TrimSpace(key); key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		group.Keys = keys
+		groups = append(groups, group)
+	}
+	cfg.ClientKeyGroups = groups
+}
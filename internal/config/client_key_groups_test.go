@@ -0,0 +1,43 @@
+package config
+
+import "testing"
+
+func TestLookupClientKey_FallsBackToGroup(t *testing.T) {
+	cfg := &Config{
+		SDKConfig: SDKConfig{
+			APIKeys: []string{"k1", "k2", "k3"},
+			ClientKeyGroups: []ClientKeyGroup{
+				{Name: " team-a ", Keys: []string{"k1", " k2", "k1", ""}},
+				{Name: "team-b", Keys: []string{"k2"}},
+				{Name: "team-a"},
+			},
+		},
+		APIKeyAuth: map[string][]string{
+			"group:team-a":  {"codex-*.json"},
+			"group:team-b":  {"provider:claude"},
+			"k1":            {"gemini-a.json"},
+			"group:unknown": {"x"},
+		},
+	}
+	cfg.SanitizeClientKeyGroups()
+	cfg.SanitizeAPIKeyAuth()
+
+	if len(cfg.ClientKeyGroups) != 2 || len(cfg.ClientKeyGroups[0].Keys) != 2 {
+		t.Fatalf("groups = %+v", cfg.ClientKeyGroups)
+	}
+	if _, ok := cfg.APIKeyAuth["group:unknown"]; ok {
+		t.Fatalf("api-key-auth kept an entry for an unknown group")
+	}
+	if refs, _ := LookupClientKey(&cfg.SDKConfig, cfg.APIKeyAuth, "k1"); len(refs) != 1 || refs[0] != "gemini-a.json" {
+		t.Fatalf("k1 refs = %v, want its own entry", refs)
+	}
+	if refs, _ := LookupClientKey(&cfg.SDKConfig, cfg.APIKeyAuth, "k2"); len(refs) != 1 || refs[0] != "codex-*.json" {
+		t.Fatalf("k2 refs = %v, want the first group's entry", refs)
+	}
+	if refs, ok := LookupClientKey(&cfg.SDKConfig, cfg.APIKeyAuth, "k3"); ok {
+		t.Fatalf("k3 refs = %v, want none", refs)
+	}
+	if budget, ok := LookupClientKey(&cfg.SDKConfig, map[string]int64{"group:team-b": 100}, "k2"); !ok || budget != 100 {
+		t.Fatalf("k2 budget = %d, %v", budget, ok)
+	}
+}
@@ -6,11 +6,20 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -39,15 +48,33 @@ type Config struct {
 	// RemoteManagement nests management-related options under 'remote-management'.
 	RemoteManagement RemoteManagement `yaml:"remote-management" json:"-"`
 
+	// ReadOnly rejects mutating management requests and never writes the config file, for
+	// instances running from an immutable config. The READ_ONLY environment variable overrides it.
+	ReadOnly bool `yaml:"read-only,omitempty" json:"read-only,omitempty"`
+
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
+	// DisableDuplicateAuths disables auth files that sign in to an account already provided by
+	// another enabled auth file. Duplicates are always reported; this only controls disabling.
+	DisableDuplicateAuths bool `yaml:"disable-duplicate-auths,omitempty" json:"disable-duplicate-auths,omitempty"`
+
+	// AuthArchive moves deleted auth files to an archive directory instead of removing them.
+	AuthArchive AuthArchiveConfig `yaml:"auth-archive,omitempty" json:"auth-archive,omitempty"`
+
 	// Debug enables or disables debug-level logging and other debug features.
 	Debug bool `yaml:"debug" json:"debug"`
 
+	// LogLevels sets the log level of individual modules (executor, translator, access,
+	// management), e.g. debug for the executor alone while the rest logs at info.
+	LogLevels map[string]string `yaml:"log-levels,omitempty" json:"log-levels,omitempty"`
+
 	// Pprof config controls the optional pprof HTTP debug server.
 	Pprof PprofConfig `yaml:"pprof" json:"pprof"`
 
+	// LeaderElection coordinates background jobs across instances sharing one auth store.
+	LeaderElection LeaderElectionConfig `yaml:"leader-election" json:"leader-election"`
+
 	// CommercialMode disables high-overhead HTTP middleware features to minimize per-request memory usage.
 	CommercialMode bool `yaml:"commercial-mode" json:"commercial-mode"`
 
@@ -62,6 +89,18 @@ type Config struct {
 	// When exceeded, the oldest error log files are deleted. Default is 10. Set to 0 to disable cleanup.
 	ErrorLogsMaxFiles int `yaml:"error-logs-max-files" json:"error-logs-max-files"`
 
+	// RequestLogDiff logs the body of a request as a structural diff against the previous request
+	// of the same session, instead of repeating the unchanged conversation history every turn.
+	RequestLogDiff bool `yaml:"request-log-diff,omitempty" json:"request-log-diff,omitempty"`
+
+	// RequestLogMaxBodyBytes caps each body in a request log, keeping its head and tail and the
+	// SHA-256 of the full body. 0 disables the cap.
+	RequestLogMaxBodyBytes int `yaml:"request-log-max-body-bytes,omitempty" json:"request-log-max-body-bytes,omitempty"`
+
+	// RequestLogSampleRate is the fraction (0-1) of successful requests that are logged; failed
+	// requests are always logged. 0 logs every request.
+	RequestLogSampleRate float64 `yaml:"request-log-sample-rate,omitempty" json:"request-log-sample-rate,omitempty"`
+
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
@@ -87,6 +126,20 @@ type Config struct {
 	// When true, the original instruction injection logic is used.
 	CodexInstructionsEnabled bool `yaml:"codex-instructions-enabled" json:"codex-instructions-enabled"`
 
+	// CodexClient overrides the Codex CLI version and User-Agent presented to Codex upstreams.
+	CodexClient CodexClientConfig `yaml:"codex-client,omitempty" json:"codex-client,omitempty"`
+
+	// CodexPromptCache bounds the prompt cache keys assigned to Claude sessions served by Codex.
+	CodexPromptCache CodexPromptCacheConfig `yaml:"codex-prompt-cache,omitempty" json:"codex-prompt-cache,omitempty"`
+
+	// CodexSession selects how Session_id, Conversation_id and prompt_cache_key are derived for
+	// requests served by Codex.
+	CodexSession CodexSessionConfig `yaml:"codex-session,omitempty" json:"codex-session,omitempty"`
+
+	// CodexBuiltinTools allows or strips Responses built-in tools (web_search, file_search, ...)
+	// on requests served by Codex auths.
+	CodexBuiltinTools CodexBuiltinToolsConfig `yaml:"codex-builtin-tools,omitempty" json:"codex-builtin-tools,omitempty"`
+
 	// GeminiKey defines Gemini API key configurations with optional routing overrides.
 	GeminiKey []GeminiKey `yaml:"gemini-api-key" json:"gemini-api-key"`
 
@@ -103,6 +156,9 @@ type Config struct {
 	// Used for services that use Vertex AI-style paths but with simple API key authentication.
 	VertexCompatAPIKey []VertexCompatKey `yaml:"vertex-api-key" json:"vertex-api-key"`
 
+	// MockProviders defines in-process fake upstreams for integration testing.
+	MockProviders []MockProvider `yaml:"mock-provider,omitempty" json:"mock-provider,omitempty"`
+
 	// AmpCode contains Amp CLI upstream configuration, management restrictions, and model mappings.
 	AmpCode AmpCode `yaml:"ampcode" json:"ampcode"`
 
@@ -120,6 +176,33 @@ type Config struct {
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
+	// PayloadProfiles defines named payload parameter profiles with inheritance, applied per
+	// provider, model and client key before the payload rules.
+	PayloadProfiles PayloadProfilesConfig `yaml:"payload-profiles,omitempty" json:"payload-profiles,omitempty"`
+
+	// RequestTimeouts configures upstream HTTP timeouts globally, per provider, and per endpoint.
+	RequestTimeouts RequestTimeoutConfig `yaml:"request-timeouts,omitempty" json:"request-timeouts,omitempty"`
+
+	// UpstreamTransport tunes HTTP/2 and connection reuse for upstream requests, globally and per provider.
+	UpstreamTransport UpstreamTransportConfig `yaml:"upstream-transport,omitempty" json:"upstream-transport,omitempty"`
+
+	// Hedging sends a duplicate streaming request on another auth when the first one is slow to respond.
+	Hedging HedgingConfig `yaml:"hedging,omitempty" json:"hedging,omitempty"`
+
+	// Chaos injects upstream faults to exercise failover, retry and cooldown handling.
+	Chaos ChaosConfig `yaml:"chaos,omitempty" json:"chaos,omitempty"`
+
+	// ModelDiscovery controls periodic re-discovery of the models each auth can access.
+	ModelDiscovery ModelDiscoveryConfig `yaml:"model-discovery,omitempty" json:"model-discovery,omitempty"`
+
+	// ModelMetadata overrides or supplements model capabilities reported by providers
+	// (context window, output limit, tool/vision/reasoning support).
+	ModelMetadata []ModelMetadataEntry `yaml:"model-metadata,omitempty" json:"model-metadata,omitempty"`
+
+	// ThinkingSuffixes defines custom model suffixes, such as "max" in "claude-sonnet-4-5(max)",
+	// that expand to a built-in thinking setting and an optional output token limit.
+	ThinkingSuffixes []ThinkingSuffix `yaml:"thinking-suffixes,omitempty" json:"thinking-suffixes,omitempty"`
+
 	// ReverseProxies defines reverse proxy endpoints for routing traffic.
 	ReverseProxies []ReverseProxy `yaml:"reverse-proxies,omitempty" json:"reverse-proxies,omitempty"`
 
@@ -129,6 +212,11 @@ type Config struct {
 	// so one Worker can fan out to multiple Deno proxy hosts.
 	ReverseProxyWorkerURL string `yaml:"reverse-proxy-worker-url,omitempty" json:"reverse-proxy-worker-url,omitempty"`
 
+	// ReverseProxyProbeIntervalSeconds enables half-open probing of banned reverse proxies: at
+	// most once per interval, one real request is sent through a banned proxy and a success lifts
+	// the ban early. 0 disables probing, keeping traffic direct until the ban expires.
+	ReverseProxyProbeIntervalSeconds int `yaml:"reverse-proxy-probe-interval-seconds,omitempty" json:"reverse-proxy-probe-interval-seconds,omitempty"`
+
 	// ProxyRouting defines which reverse proxy each provider should use.
 	ProxyRouting ProxyRouting `yaml:"proxy-routing,omitempty" json:"proxy-routing,omitempty"`
 
@@ -137,8 +225,11 @@ type Config struct {
 	ProxyRoutingAuth map[string]string `yaml:"proxy-routing-auth,omitempty" json:"proxy-routing-auth,omitempty"`
 
 	// APIKeyAuth defines which auth accounts each client API key can access.
-	// Keys are client API keys (from top-level api-keys). Values can be auth ID, auth index, or auth file name.
-	// When a client key is not listed, it can access all accounts (default behavior).
+	// Keys are client API keys (from top-level api-keys). Values can be auth ID, auth index, or auth file name,
+	// a file name pattern such as "codex-*.json", "provider:<name>", "tag:<tag>" or "label:<label>".
+	// Entries prefixed with "!" exclude the auths they match; a list holding only exclusions allows every other auth.
+	// When a client key is not listed, it can access all accounts (default behavior), except
+	// auths whose files limit them to other keys with "allowed-clients".
 	APIKeyAuth map[string][]string `yaml:"api-key-auth,omitempty" json:"api-key-auth,omitempty"`
 
 	// APIKeyExpiry defines per-client API key expiration timestamps.
@@ -146,6 +237,25 @@ type Config struct {
 	// If a key is not listed, it never expires.
 	APIKeyExpiry map[string]string `yaml:"api-key-expiry,omitempty" json:"api-key-expiry,omitempty"`
 
+	// APIKeyMetadata records who owns each client API key and whether it is disabled.
+	// Keys are client API keys (from top-level api-keys). Disabled keys stay listed but are rejected.
+	APIKeyMetadata map[string]APIKeyMetadata `yaml:"api-key-metadata,omitempty" json:"api-key-metadata,omitempty"`
+
+	// Webhooks are notified of notable events such as auth refresh failures and quota exhaustion.
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+
+	// Notifiers send the same events as chat messages to Telegram chats and Discord channels.
+	Notifiers NotifiersConfig `yaml:"notifiers,omitempty" json:"notifiers,omitempty"`
+
+	// UsageReports schedules usage summaries delivered by webhook or email.
+	UsageReports UsageReportsConfig `yaml:"usage-reports,omitempty" json:"usage-reports,omitempty"`
+
+	// AuditChain records every upstream request in a tamper-evident hash chain.
+	AuditChain AuditChainConfig `yaml:"audit-chain,omitempty" json:"audit-chain,omitempty"`
+
+	// ExecutorPlugins load additional provider executors from Go plugins or sidecar processes.
+	ExecutorPlugins []ExecutorPluginConfig `yaml:"executor-plugins,omitempty" json:"executor-plugins,omitempty"`
+
 	legacyMigrationPending bool `yaml:"-" json:"-"`
 }
 
@@ -167,13 +277,33 @@ type PprofConfig struct {
 	Addr string `yaml:"addr" json:"addr"`
 }
 
+// LeaderElectionConfig controls single-flight background jobs across multiple instances.
+// When enabled, only the instance holding the lease runs token refresh and other fleet-wide daemons.
+type LeaderElectionConfig struct {
+	// Enable toggles leader election. When false, every instance runs background jobs.
+	Enable bool `yaml:"enable" json:"enable"`
+	// LockFile is the shared lease file path. Defaults to "<auth-dir>/.leader.lock".
+	LockFile string `yaml:"lock-file,omitempty" json:"lock-file,omitempty"`
+	// LeaseSeconds is how long a lease stays valid without renewal. Defaults to 30.
+	LeaseSeconds int `yaml:"lease-seconds,omitempty" json:"lease-seconds,omitempty"`
+}
+
 // RemoteManagement holds management API configuration under 'remote-management'.
 type RemoteManagement struct {
 	// AllowRemote toggles remote (non-localhost) access to management API.
 	AllowRemote bool `yaml:"allow-remote"`
 	// SecretKey is the management key (plaintext or bcrypt hashed). YAML key intentionally 'secret-key'.
 	SecretKey string `yaml:"secret-key"`
-	// DisableControlPanel skips serving and syncing the bundled management UI when true.
+	// SecretKeyFile reads SecretKey from a file such as a Docker secret. The key is hashed in
+	// memory only and the file is re-read when it changes.
+	SecretKeyFile string `yaml:"secret-key-file,omitempty"`
+	// ViewerKeys are additional management keys (plaintext or bcrypt hashed) limited to GET
+	// requests, whose responses always have their secrets masked.
+	ViewerKeys []string `yaml:"viewer-keys,omitempty"`
+	// RedactSecrets masks API keys, header values and other secrets in management GET
+	// responses; admin keys can pass ?reveal=true to see them.
+	RedactSecrets bool `yaml:"redact-secrets,omitempty"`
+	// DisableControlPanel skips serving and syncing the bundled management UI, including the embedded /ui dashboard, when true.
 	DisableControlPanel bool `yaml:"disable-control-panel"`
 	// PanelGitHubRepository overrides the GitHub repository used to fetch the management panel asset.
 	// Accepts either a repository URL (https://github.com/org/repo) or an API releases endpoint.
@@ -193,7 +323,8 @@ type QuotaExceeded struct {
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first", "session".
+	// Supported values: "round-robin" (default), "fill-first", "session", or the name of a
+	// selector registered by an SDK embedder with coreauth.RegisterSelector.
 	// When set to "session", the Session config below is used for session-aware routing.
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
 	// Session configures session-aware routing (sticky sessions + scoring).
@@ -308,6 +439,424 @@ type AmpUpstreamAPIKeyEntry struct {
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 }
 
+// Upstream endpoint kinds accepted as keys of ProviderRequestTimeouts.Endpoints.
+const (
+	// RequestEndpointExecute covers non-streaming generation requests.
+	RequestEndpointExecute = "execute"
+	// RequestEndpointStream covers streaming generation requests.
+	RequestEndpointStream = "stream"
+	// RequestEndpointCountTokens covers token counting requests.
+	RequestEndpointCountTokens = "count-tokens"
+)
+
+// RequestTimeouts holds upstream HTTP timeouts in seconds.
+// Zero inherits the value from the enclosing level; a negative value disables the timeout.
+type RequestTimeouts struct {
+	// ConnectSeconds bounds establishing the TCP connection (including proxy dialing).
+	ConnectSeconds int `yaml:"connect-seconds,omitempty" json:"connect-seconds,omitempty"`
+
+	// TLSHandshakeSeconds bounds the TLS handshake.
+	TLSHandshakeSeconds int `yaml:"tls-handshake-seconds,omitempty" json:"tls-handshake-seconds,omitempty"`
+
+	// ResponseHeaderSeconds bounds the wait for response headers after the request is sent.
+	ResponseHeaderSeconds int `yaml:"response-header-seconds,omitempty" json:"response-header-seconds,omitempty"`
+
+	// NonStreamSeconds bounds the whole exchange of non-streaming requests, including reading the body.
+	// It never applies to streaming requests.
+	NonStreamSeconds int `yaml:"non-stream-seconds,omitempty" json:"non-stream-seconds,omitempty"`
+}
+
+// RequestTimeoutConfig configures upstream HTTP timeouts.
+// Resolution order is endpoint, provider, global, then built-in defaults.
+type RequestTimeoutConfig struct {
+	RequestTimeouts `yaml:",inline"`
+
+	// Providers overrides timeouts per provider key (e.g. "codex", "claude", "gemini-cli",
+	// or an openai-compatibility name).
+	Providers map[string]ProviderRequestTimeouts `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// ProviderRequestTimeouts overrides timeouts for one provider.
+type ProviderRequestTimeouts struct {
+	RequestTimeouts `yaml:",inline"`
+
+	// Endpoints overrides timeouts per endpoint kind ("execute", "stream", "count-tokens").
+	Endpoints map[string]RequestTimeouts `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+}
+
+// TransportTuning adjusts the HTTP transport used for upstream requests.
+// Unset fields inherit the value from the enclosing level.
+type TransportTuning struct {
+	// ForceHTTP2 set to true attempts HTTP/2 even on transports with a custom dialer, such as
+	// SOCKS5 proxies; false pins HTTP/1.1 for proxies with broken HTTP/2 support. Unset keeps
+	// the standard library's choice.
+	ForceHTTP2 *bool `yaml:"force-http2,omitempty" json:"force-http2,omitempty"`
+
+	// H2ReadIdleSeconds sends an HTTP/2 health-check ping when no frame was received for this long.
+	// A negative value disables health checks.
+	H2ReadIdleSeconds int `yaml:"h2-read-idle-seconds,omitempty" json:"h2-read-idle-seconds,omitempty"`
+
+	// H2PingTimeoutSeconds closes the connection when a health-check ping is not answered in time.
+	H2PingTimeoutSeconds int `yaml:"h2-ping-timeout-seconds,omitempty" json:"h2-ping-timeout-seconds,omitempty"`
+
+	// IdleConnTimeoutSeconds closes pooled keep-alive connections after this much idle time.
+	// Defaults to 90.
+	IdleConnTimeoutSeconds int `yaml:"idle-conn-timeout-seconds,omitempty" json:"idle-conn-timeout-seconds,omitempty"`
+
+	// DisableStreamCompression stops requesting gzip for streaming requests so SSE events
+	// are not held back by upstream compression buffers.
+	DisableStreamCompression *bool `yaml:"disable-stream-compression,omitempty" json:"disable-stream-compression,omitempty"`
+}
+
+// UpstreamTransportConfig tunes upstream HTTP transports.
+// Provider entries override the global values field by field.
+type UpstreamTransportConfig struct {
+	TransportTuning `yaml:",inline"`
+
+	// Providers overrides tuning per provider key (e.g. "codex", "claude", or an openai-compatibility name).
+	Providers map[string]TransportTuning `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// HedgingConfig configures hedged streaming requests. When a stream has produced no data
+// after the delay, the same request is sent on the next available auth (and therefore
+// possibly another reverse proxy); whichever responds first is used and the other is canceled.
+type HedgingConfig struct {
+	// Enabled toggles hedging.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// DelayMs is how long to wait for the first chunk before hedging. Defaults to 2000.
+	DelayMs int `yaml:"delay-ms,omitempty" json:"delay-ms,omitempty"`
+
+	// Providers restricts hedging to these provider keys. Empty applies it to every provider.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// ChaosConfig configures fault injection on upstream requests. Rates are the share (0-1) of
+// matching requests that get each fault.
+type ChaosConfig struct {
+	// Enabled toggles fault injection.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Providers restricts fault injection to these provider keys. Empty applies it to every provider.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// RateLimitRate answers requests with a synthetic 429, carrying Retry-After when RetryAfterSeconds is set.
+	RateLimitRate     float64 `yaml:"rate-limit-rate,omitempty" json:"rate-limit-rate,omitempty"`
+	RetryAfterSeconds int     `yaml:"retry-after-seconds,omitempty" json:"retry-after-seconds,omitempty"`
+
+	// ServerErrorRate answers requests with a synthetic 500.
+	ServerErrorRate float64 `yaml:"server-error-rate,omitempty" json:"server-error-rate,omitempty"`
+
+	// ProxyErrorRate answers requests with a synthetic 502, which bans the reverse proxy when
+	// the request was routed through one.
+	ProxyErrorRate float64 `yaml:"proxy-error-rate,omitempty" json:"proxy-error-rate,omitempty"`
+
+	// TruncateRate cuts successful streaming responses off part-way through.
+	TruncateRate float64 `yaml:"truncate-rate,omitempty" json:"truncate-rate,omitempty"`
+
+	// SlowChunkRate delays every read of a successful streaming response by SlowChunkDelayMs.
+	SlowChunkRate    float64 `yaml:"slow-chunk-rate,omitempty" json:"slow-chunk-rate,omitempty"`
+	SlowChunkDelayMs int     `yaml:"slow-chunk-delay-ms,omitempty" json:"slow-chunk-delay-ms,omitempty"`
+}
+
+// ModelDiscoveryConfig configures how model lists are kept in sync with upstream accounts.
+type ModelDiscoveryConfig struct {
+	// RefreshIntervalSeconds re-queries provider model lists for every auth at this interval.
+	// <= 0 disables periodic refresh; models are still discovered when an auth is added or updated.
+	RefreshIntervalSeconds int `yaml:"refresh-interval-seconds,omitempty" json:"refresh-interval-seconds,omitempty"`
+
+	// HideExhausted removes models from /v1/models while every backing auth is quota-exhausted or suspended.
+	HideExhausted bool `yaml:"hide-exhausted,omitempty" json:"hide-exhausted,omitempty"`
+}
+
+// WebhookConfig configures one webhook endpoint.
+type WebhookConfig struct {
+	// URL receives a JSON POST for every matching event.
+	URL string `yaml:"url" json:"url"`
+
+	// Secret signs each delivery with HMAC-SHA256; the signature is sent in X-Webhook-Signature.
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+
+	// Events restricts deliveries to these event types (e.g. "quota.exhausted"). Empty sends every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+
+	// MaxRetries is how many times a failed delivery is retried with exponential backoff.
+	// < 0 disables retries; 0 uses 3.
+	MaxRetries int `yaml:"max-retries,omitempty" json:"max-retries,omitempty"`
+}
+
+// NotifiersConfig configures built-in chat notifiers. Messages are rendered from Go text
+// templates over the event data; Templates overrides the built-in template of an event type.
+type NotifiersConfig struct {
+	Telegram  []TelegramNotifier `yaml:"telegram,omitempty" json:"telegram,omitempty"`
+	Discord   []DiscordNotifier  `yaml:"discord,omitempty" json:"discord,omitempty"`
+	Templates map[string]string  `yaml:"templates,omitempty" json:"templates,omitempty"`
+}
+
+// TelegramNotifier sends messages to a Telegram chat through a bot.
+type TelegramNotifier struct {
+	// BotToken is the token issued by @BotFather.
+	BotToken string `yaml:"bot-token" json:"bot-token"`
+
+	// ChatID is the numeric chat ID or @channel username to post to.
+	ChatID string `yaml:"chat-id" json:"chat-id"`
+
+	// Events restricts messages to these event types. Empty sends every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+}
+
+// DiscordNotifier posts messages to a Discord channel webhook.
+type DiscordNotifier struct {
+	// WebhookURL is the channel webhook URL (https://discord.com/api/webhooks/...).
+	WebhookURL string `yaml:"webhook-url" json:"webhook-url"`
+
+	// Events restricts messages to these event types. Empty sends every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+}
+
+// Usage report periods.
+const (
+	UsageReportDaily  = "daily"
+	UsageReportWeekly = "weekly"
+)
+
+// UsageReportsConfig configures scheduled usage summaries built from the in-memory usage
+// statistics. Each instance reports the requests it served.
+type UsageReportsConfig struct {
+	// SMTP is the mail server used by reports with email recipients.
+	SMTP SMTPConfig `yaml:"smtp,omitempty" json:"smtp,omitempty"`
+
+	// Reports lists the scheduled reports.
+	Reports []UsageReport `yaml:"reports,omitempty" json:"reports,omitempty"`
+}
+
+// AuditChainConfig configures the upstream request audit chain. Each upstream request appends
+// a JSON line holding the hash of the previous line, so editing, removing or reordering lines
+// breaks the chain. Periodic anchors publish the chain head outside the audit file, so
+// truncating or rewriting the whole file can be detected too.
+type AuditChainConfig struct {
+	// File receives the chain, one JSON record per upstream request. Empty disables the chain.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+
+	// AnchorFile receives one JSON line with the chain head at every anchor.
+	AnchorFile string `yaml:"anchor-file,omitempty" json:"anchor-file,omitempty"`
+
+	// AnchorURL receives the chain head as a JSON POST at every anchor.
+	AnchorURL string `yaml:"anchor-url,omitempty" json:"anchor-url,omitempty"`
+
+	// AnchorIntervalSeconds is the time between anchors; the head is only anchored when new
+	// records were appended. Defaults to 300.
+	AnchorIntervalSeconds int `yaml:"anchor-interval-seconds,omitempty" json:"anchor-interval-seconds,omitempty"`
+}
+
+// SMTPConfig holds outgoing mail server settings.
+type SMTPConfig struct {
+	Host     string `yaml:"host,omitempty" json:"host,omitempty"`
+	Port     int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	From     string `yaml:"from,omitempty" json:"from,omitempty"`
+}
+
+// UsageReport schedules one usage summary.
+type UsageReport struct {
+	// Name identifies the report in subjects and logs.
+	Name string `yaml:"name" json:"name"`
+
+	// Period is "daily" or "weekly" and sets the span the report covers, ending when it runs.
+	Period string `yaml:"period,omitempty" json:"period,omitempty"`
+
+	// Schedule is a cron expression (minute hour day-of-month month day-of-week) in local time.
+	// Empty runs daily reports at midnight and weekly reports at midnight on Mondays.
+	Schedule string `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+
+	// WebhookURL receives the report as a JSON POST, signed like webhooks when Secret is set.
+	WebhookURL string `yaml:"webhook-url,omitempty" json:"webhook-url,omitempty"`
+	Secret     string `yaml:"secret,omitempty" json:"secret,omitempty"`
+
+	// EmailTo lists recipients of the report, sent through the SMTP settings.
+	EmailTo []string `yaml:"email-to,omitempty" json:"email-to,omitempty"`
+}
+
+// APIKeyMetadata describes a client API key provisioned through the management API.
+type APIKeyMetadata struct {
+	// Owner is the person or team the key was issued to.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	// Description is a free-form note about the key's purpose.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// CreatedBy identifies who provisioned the key.
+	CreatedBy string `yaml:"created-by,omitempty" json:"created-by,omitempty"`
+	// CreatedAt is the RFC3339 creation time.
+	CreatedAt string `yaml:"created-at,omitempty" json:"created-at,omitempty"`
+	// Disabled rejects requests made with the key without removing it.
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// AuthArchiveConfig configures soft deletion of auth files.
+type AuthArchiveConfig struct {
+	// Enabled archives auth files deleted through the management API so they can be restored.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Dir is the archive directory. Defaults to "<auth-dir>-archive"; it must not be inside
+	// auth-dir, which is scanned recursively for credentials.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// RetentionDays is how long archived files are kept before being purged. Defaults to 30.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+}
+
+// ArchiveDir returns the directory holding archived auth files of authDir.
+func (c AuthArchiveConfig) ArchiveDir(authDir string) string {
+	if dir := strings.TrimSpace(c.Dir); dir != "" {
+		return dir
+	}
+	return filepath.Clean(authDir) + "-archive"
+}
+
+// Retention returns how long archived auth files are kept.
+func (c AuthArchiveConfig) Retention() time.Duration {
+	if c.RetentionDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+// ThinkingSuffix maps a custom model suffix to a built-in thinking suffix.
+type ThinkingSuffix struct {
+	// Name is the suffix written inside the parentheses of the model name.
+	Name string `yaml:"name" json:"name"`
+
+	// Providers limits the suffix to these provider formats (gemini, gemini-cli, claude,
+	// openai, codex, iflow, antigravity). Empty applies to all of them. The same name may be
+	// defined once per provider; the first matching entry wins.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// Thinking is the built-in suffix the alias expands to: minimal, low, medium, high,
+	// xhigh, a token budget, none or auto.
+	Thinking string `yaml:"thinking" json:"thinking"`
+
+	// MaxOutputTokens sets the request's output token limit when positive, capped at the
+	// model's own limit. Ignored for codex, which has no output limit parameter.
+	MaxOutputTokens int `yaml:"max-output-tokens,omitempty" json:"max-output-tokens,omitempty"`
+}
+
+// CodexClientConfig controls the Codex CLI identity sent upstream when clients do not send
+// their own Version and User-Agent headers.
+type CodexClientConfig struct {
+	// Version is the Codex CLI version sent in the Version header. Empty uses the built-in version.
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+
+	// UserAgent is the default User-Agent. Its codex_cli_rs/<version> token is rewritten to the
+	// effective version. Empty uses the built-in User-Agent.
+	UserAgent string `yaml:"user-agent,omitempty" json:"user-agent,omitempty"`
+
+	// AutoDetect raises the version to the newest plausible one seen in real Codex client
+	// User-Agents or published at VersionURL.
+	AutoDetect bool `yaml:"auto-detect,omitempty" json:"auto-detect,omitempty"`
+
+	// VersionURL is polled for the latest Codex CLI version when AutoDetect is on. It may answer
+	// with a GitHub release ({"tag_name": "rust-v0.99.0"}) or the version as plain text.
+	VersionURL string `yaml:"version-url,omitempty" json:"version-url,omitempty"`
+
+	// VersionRefreshHours is how often VersionURL is polled. <= 0 uses 6.
+	VersionRefreshHours int `yaml:"version-refresh-hours,omitempty" json:"version-refresh-hours,omitempty"`
+}
+
+// CodexPromptCacheConfig tunes the cache of prompt_cache_key values the Codex executor assigns
+// per model and Claude user_id, so repeated Claude requests reuse the upstream prompt cache.
+type CodexPromptCacheConfig struct {
+	// TTLSeconds is how long a key is kept after it was created. <= 0 uses 3600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// MaxEntries bounds the cache; the least recently used key is evicted first. <= 0 uses 10000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+}
+
+// Supported CodexSessionConfig strategies.
+const (
+	// CodexSessionAuto reuses a client-supplied session or prompt cache key, and keys Claude
+	// requests by model and metadata.user_id through the Codex prompt cache.
+	CodexSessionAuto = "auto"
+	// CodexSessionPerRequest sends a fresh UUID with every request.
+	CodexSessionPerRequest = "per-request"
+	// CodexSessionPerClient derives a stable ID from the client API key and model.
+	CodexSessionPerClient = "per-client"
+	// CodexSessionSystemPrompt derives a stable ID from the model and the instructions.
+	CodexSessionSystemPrompt = "system-prompt"
+	// CodexSessionPassthrough forwards only the session header supplied by the client.
+	CodexSessionPassthrough = "passthrough"
+)
+
+// CodexSessionConfig selects the session ID strategy of the Codex executor. Upstream prompt
+// caching is keyed by these values, so the strategy decides which requests share a cache.
+type CodexSessionConfig struct {
+	// Strategy is "auto" (default), "per-request", "per-client", "system-prompt" or "passthrough".
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// Supported ExecutorPluginConfig.Type values.
+const (
+	// ExecutorPluginSharedObject loads a Go plugin (.so) built against the same proxy version.
+	ExecutorPluginSharedObject = "so"
+	// ExecutorPluginSidecar starts an executable that serves the executor over the sidecar protocol.
+	ExecutorPluginSidecar = "sidecar"
+)
+
+// ExecutorPluginConfig declares an executor loaded at runtime, so third-party providers can be
+// added without rebuilding the proxy. Auths whose provider matches use the plugin executor.
+type ExecutorPluginConfig struct {
+	// Provider is the provider key the executor serves. Required for sidecars; for Go plugins it
+	// defaults to the executor's identifier.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Path is the .so file or sidecar executable.
+	Path string `yaml:"path" json:"path"`
+
+	// Type is "so" or "sidecar". Empty infers it from the file extension.
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Args and Env are passed to a sidecar executable; Env entries are KEY=VALUE. A sidecar
+	// inherits only a minimal part of the proxy's environment, so it needs Env for the rest.
+	Args []string `yaml:"args,omitempty" json:"args,omitempty"`
+	Env  []string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Supported CodexBuiltinToolsConfig actions.
+const (
+	// CodexBuiltinToolAllow forwards the built-in tool to Codex.
+	CodexBuiltinToolAllow = "allow"
+	// CodexBuiltinToolStrip removes the built-in tool, and tool_choice and include entries
+	// referring to it, from the request.
+	CodexBuiltinToolStrip = "strip"
+)
+
+// CodexBuiltinToolsConfig maps Responses built-in tool types to "allow" or "strip". A type
+// also covers its dated and preview variants ("web_search" covers "web_search_preview").
+// Types without an entry are allowed.
+type CodexBuiltinToolsConfig struct {
+	// Tools is the policy for client keys without an entry in KeyTools.
+	Tools map[string]string `yaml:"tools,omitempty" json:"tools,omitempty"`
+
+	// KeyTools overrides Tools per client API key (key -> tool type -> action). Types missing
+	// from a key's map fall back to Tools.
+	KeyTools map[string]map[string]string `yaml:"key-tools,omitempty" json:"key-tools,omitempty"`
+}
+
+// ModelMetadataEntry declares capabilities for models matching a name pattern.
+// Unset fields keep the values discovered from providers.
+type ModelMetadataEntry struct {
+	// Model is the model name or wildcard pattern (e.g., "gpt-*", "local-llama").
+	Model string `yaml:"model" json:"model"`
+	// ContextWindow is the input context size in tokens.
+	ContextWindow int `yaml:"context-window,omitempty" json:"context-window,omitempty"`
+	// MaxOutputTokens is the maximum completion size in tokens.
+	MaxOutputTokens int `yaml:"max-output-tokens,omitempty" json:"max-output-tokens,omitempty"`
+	// SupportsTools reports whether the model accepts tool definitions.
+	SupportsTools *bool `yaml:"supports-tools,omitempty" json:"supports-tools,omitempty"`
+	// SupportsVision reports whether the model accepts image input.
+	SupportsVision *bool `yaml:"supports-vision,omitempty" json:"supports-vision,omitempty"`
+	// SupportsReasoning reports whether the model supports reasoning/thinking controls.
+	SupportsReasoning *bool `yaml:"supports-reasoning,omitempty" json:"supports-reasoning,omitempty"`
+}
+
 // PayloadConfig defines default and override parameter rules applied to provider payloads.
 type PayloadConfig struct {
 	// Default defines rules that only set parameters when they are missing in the payload.
@@ -320,6 +869,63 @@ type PayloadConfig struct {
 	OverrideRaw []PayloadRule `yaml:"override-raw" json:"override-raw"`
 	// Filter defines rules that remove parameters from the payload by JSON path.
 	Filter []PayloadFilterRule `yaml:"filter" json:"filter"`
+	// Compat defines shims that strip, truncate, or emulate parameters unsupported by a backend.
+	Compat []PayloadCompatRule `yaml:"compat,omitempty" json:"compat,omitempty"`
+	// Transform defines conditional rename, set and delete rules applied after filter rules.
+	Transform []PayloadTransformRule `yaml:"transform,omitempty" json:"transform,omitempty"`
+}
+
+// PayloadTransformRule patches matching payloads when all of its conditions hold. Renames run
+// first, then sets, then deletes.
+type PayloadTransformRule struct {
+	// Models lists model entries with name pattern and protocol constraint.
+	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When lists conditions on the payload that must all hold for the rule to apply.
+	When []PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
+	// Rename maps JSON paths to the paths their values move to. Missing source paths are skipped.
+	Rename map[string]string `yaml:"rename,omitempty" json:"rename,omitempty"`
+	// Set maps JSON paths (gjson/sjson syntax) to values written into the payload.
+	Set map[string]any `yaml:"set,omitempty" json:"set,omitempty"`
+	// Delete lists JSON paths to remove from the payload.
+	Delete []string `yaml:"delete,omitempty" json:"delete,omitempty"`
+}
+
+// PayloadCondition tests one JSON path of a payload. Exists, Equals and Matches may be
+// combined; a condition with none of them only requires the path to exist.
+type PayloadCondition struct {
+	// Path is the JSON path (gjson syntax) to test.
+	Path string `yaml:"path" json:"path"`
+	// Exists requires the path to be present (true) or absent (false).
+	Exists *bool `yaml:"exists,omitempty" json:"exists,omitempty"`
+	// Equals requires the value at the path to equal this value.
+	Equals any `yaml:"equals,omitempty" json:"equals,omitempty"`
+	// Matches requires the string form of the value at the path to match this regular expression.
+	Matches string `yaml:"matches,omitempty" json:"matches,omitempty"`
+}
+
+// Supported PayloadCompatRule.ToolChoice modes.
+const (
+	// PayloadCompatToolChoiceAuto downgrades forced tool selection to automatic selection.
+	// A forced named tool is emulated by restricting the tool list to that tool.
+	PayloadCompatToolChoiceAuto = "auto"
+	// PayloadCompatToolChoiceStrip removes the tool choice parameter entirely.
+	PayloadCompatToolChoiceStrip = "strip"
+)
+
+// PayloadCompatRule adapts payloads for backends that reject parameters other backends accept.
+// Every adjustment is logged as a warning so silent behavior changes remain visible.
+type PayloadCompatRule struct {
+	// Models lists model entries with name pattern and protocol constraint.
+	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// MaxStopSequences truncates stop sequence lists to at most this many entries. <= 0 disables truncation.
+	MaxStopSequences int `yaml:"max-stop-sequences,omitempty" json:"max-stop-sequences,omitempty"`
+	// StripParallelToolCalls removes parallel tool call controls (parallel_tool_calls,
+	// tool_choice.disable_parallel_tool_use).
+	StripParallelToolCalls bool `yaml:"strip-parallel-tool-calls,omitempty" json:"strip-parallel-tool-calls,omitempty"`
+	// ToolChoice rewrites tool selection: "auto" downgrades forced choices, "strip" removes it.
+	ToolChoice string `yaml:"tool-choice,omitempty" json:"tool-choice,omitempty"`
+	// Strip lists additional JSON paths (gjson/sjson syntax) the backend rejects.
+	Strip []string `yaml:"strip,omitempty" json:"strip,omitempty"`
 }
 
 // PayloadFilterRule describes a rule to remove specific JSON paths from matching model payloads.
@@ -372,6 +978,9 @@ type ClaudeKey struct {
 	// APIKey is the authentication key for accessing Claude API services.
 	APIKey string `yaml:"api-key" json:"api-key"`
 
+	// APIKeyFile reads APIKey from a file such as a Docker secret, re-read when it changes.
+	APIKeyFile string `yaml:"api-key-file,omitempty" json:"api-key-file,omitempty"`
+
 	// Priority controls selection preference when multiple credentials match.
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
@@ -389,12 +998,20 @@ type ClaudeKey struct {
 	// Models defines upstream model names and aliases for request routing.
 	Models []ClaudeModel `yaml:"models" json:"models"`
 
-	// Headers optionally adds extra HTTP headers for requests sent with this key.
+	// Headers optionally adds extra HTTP headers for requests sent with this key. Values may
+	// use {{name}} templates expanded per request; see util.ApplyCustomHeadersWithVars.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
 
+	// DiscoverModels restricts the built-in model list to the models this API key can list
+	// upstream, adding listed models the proxy does not know yet. Ignored when Models is set.
+	DiscoverModels bool `yaml:"discover-models,omitempty" json:"discover-models,omitempty"`
+
 	// Cloak configures request cloaking for non-Claude-Code clients.
 	Cloak *CloakConfig `yaml:"cloak,omitempty" json:"cloak,omitempty"`
 }
@@ -420,6 +1037,9 @@ type CodexKey struct {
 	// APIKey is the authentication key for accessing Codex API services.
 	APIKey string `yaml:"api-key" json:"api-key"`
 
+	// APIKeyFile reads APIKey from a file such as a Docker secret, re-read when it changes.
+	APIKeyFile string `yaml:"api-key-file,omitempty" json:"api-key-file,omitempty"`
+
 	// Priority controls selection preference when multiple credentials match.
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
@@ -437,9 +1057,13 @@ type CodexKey struct {
 	// Models defines upstream model names and aliases for request routing.
 	Models []CodexModel `yaml:"models" json:"models"`
 
-	// Headers optionally adds extra HTTP headers for requests sent with this key.
+	// Headers optionally adds extra HTTP headers for requests sent with this key. Values may
+	// use {{name}} templates expanded per request; see util.ApplyCustomHeadersWithVars.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
 }
@@ -465,6 +1089,9 @@ type GeminiKey struct {
 	// APIKey is the authentication key for accessing Gemini API services.
 	APIKey string `yaml:"api-key" json:"api-key"`
 
+	// APIKeyFile reads APIKey from a file such as a Docker secret, re-read when it changes.
+	APIKeyFile string `yaml:"api-key-file,omitempty" json:"api-key-file,omitempty"`
+
 	// Priority controls selection preference when multiple credentials match.
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
@@ -481,11 +1108,19 @@ type GeminiKey struct {
 	// Models defines upstream model names and aliases for request routing.
 	Models []GeminiModel `yaml:"models,omitempty" json:"models,omitempty"`
 
-	// Headers optionally adds extra HTTP headers for requests sent with this key.
+	// Headers optionally adds extra HTTP headers for requests sent with this key. Values may
+	// use {{name}} templates expanded per request; see util.ApplyCustomHeadersWithVars.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
+
+	// DiscoverModels restricts the built-in model list to the models this API key can list
+	// upstream, adding listed models the proxy does not know yet. Ignored when Models is set.
+	DiscoverModels bool `yaml:"discover-models,omitempty" json:"discover-models,omitempty"`
 }
 
 func (k GeminiKey) GetAPIKey() string  { return k.APIKey }
@@ -525,8 +1160,16 @@ type OpenAICompatibility struct {
 	// Models defines the model configurations including aliases for routing.
 	Models []OpenAICompatibilityModel `yaml:"models" json:"models"`
 
-	// Headers optionally adds extra HTTP headers for requests sent to this provider.
+	// Headers optionally adds extra HTTP headers for requests sent to this provider. Values may
+	// use {{name}} templates expanded per request; see util.ApplyCustomHeadersWithVars.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
+	// DiscoverModels queries the provider's /models endpoint per API key and exposes the
+	// returned models in addition to the configured ones.
+	DiscoverModels bool `yaml:"discover-models,omitempty" json:"discover-models,omitempty"`
 }
 
 // OpenAICompatibilityAPIKey represents an API key configuration with optional proxy setting.
@@ -534,6 +1177,9 @@ type OpenAICompatibilityAPIKey struct {
 	// APIKey is the authentication key for accessing the external API services.
 	APIKey string `yaml:"api-key" json:"api-key"`
 
+	// APIKeyFile reads APIKey from a file such as a Docker secret, re-read when it changes.
+	APIKeyFile string `yaml:"api-key-file,omitempty" json:"api-key-file,omitempty"`
+
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
 }
@@ -570,12 +1216,15 @@ func LoadConfig(configFile string) (*Config, error) {
 // If optional is true and the file is empty or invalid, it returns an empty Config.
 func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Perform oauth-model-alias migration before loading config.
-	// This migrates oauth-model-mappings to oauth-model-alias if needed.
-	if migrated, err := MigrateOAuthModelAlias(configFile); err != nil {
-		// Log warning but don't fail - config loading should still work
-		fmt.Printf("Warning: oauth-model-alias migration failed: %v\n", err)
-	} else if migrated {
-		fmt.Println("Migrated oauth-model-mappings to oauth-model-alias")
+	// This migrates oauth-model-mappings to oauth-model-alias if needed; read-only configs are
+	// never rewritten.
+	if !configFileReadOnly(configFile) {
+		if migrated, err := MigrateOAuthModelAlias(configFile); err != nil {
+			// Log warning but don't fail - config loading should still work
+			fmt.Printf("Warning: oauth-model-alias migration failed: %v\n", err)
+		} else if migrated {
+			fmt.Println("Migrated oauth-model-mappings to oauth-model-alias")
+		}
 	}
 
 	// Read the entire configuration file into memory.
@@ -587,136 +1236,1077 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 				return &Config{}, nil
 			}
 		}
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// In cloud deploy mode (optional=true), if file is empty or contains only whitespace, return empty config.
+	if optional && len(data) == 0 {
+		return &Config{}, nil
+	}
+
+	// Unmarshal the YAML data into the Config struct.
+	var cfg Config
+	// Set defaults before unmarshal so that absent keys keep defaults.
+	cfg.Host = "" // Default empty: binds to all interfaces (IPv4 + IPv6)
+	cfg.LoggingToFile = false
+	cfg.LogsMaxTotalSizeMB = 0
+	cfg.ErrorLogsMaxFiles = 10
+	cfg.UsageStatisticsEnabled = false
+	cfg.DisableCooling = false
+	cfg.Pprof.Enable = false
+	cfg.Pprof.Addr = DefaultPprofAddr
+	cfg.AmpCode.RestrictManagementToLocalhost = false // Default to false: API key auth is sufficient
+	cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		if optional {
+			// In cloud deploy mode, if YAML parsing fails, return empty config instead of error.
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if readOnly, ok := readOnlyFromEnv(); ok {
+		cfg.ReadOnly = readOnly
+	}
+
+	// Read credentials referenced by *-file settings before anything inspects them.
+	if err = cfg.ResolveSecretFiles(); err != nil {
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	var legacy legacyConfigData
+	if errLegacy := yaml.Unmarshal(data, &legacy); errLegacy == nil {
+		if cfg.migrateLegacyGeminiKeys(legacy.LegacyGeminiKeys) {
+			cfg.legacyMigrationPending = true
+		}
+		if cfg.migrateLegacyOpenAICompatibilityKeys(legacy.OpenAICompat) {
+			cfg.legacyMigrationPending = true
+		}
+		if cfg.migrateLegacyAmpConfig(&legacy) {
+			cfg.legacyMigrationPending = true
+		}
+	}
+
+	// Hash remote management key if plaintext is detected (nested)
+	// We consider a value to be already hashed if it looks like a bcrypt hash ($2a$, $2b$, or $2y$ prefix).
+	if cfg.RemoteManagement.SecretKey != "" && !looksLikeBcrypt(cfg.RemoteManagement.SecretKey) {
+		hashed, errHash := hashSecret(cfg.RemoteManagement.SecretKey)
+		if errHash != nil {
+			return nil, fmt.Errorf("failed to hash remote management key: %w", errHash)
+		}
+		cfg.RemoteManagement.SecretKey = hashed
+
+		// Persist the hashed value back to the config file to avoid re-hashing on next startup.
+		// Preserve YAML comments and ordering; update only the nested key. Keys read from a
+		// secret file stay out of the config file.
+		if !cfg.ReadOnly && cfg.RemoteManagement.SecretKeyFile == "" {
+			_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+		}
+	}
+
+	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
+	if cfg.RemoteManagement.PanelGitHubRepository == "" {
+		cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
+	}
+
+	cfg.Pprof.Addr = strings.TrimSpace(cfg.Pprof.Addr)
+	if cfg.Pprof.Addr == "" {
+		cfg.Pprof.Addr = DefaultPprofAddr
+	}
+
+	cfg.LeaderElection.LockFile = strings.TrimSpace(cfg.LeaderElection.LockFile)
+	if cfg.LeaderElection.LeaseSeconds < 0 {
+		cfg.LeaderElection.LeaseSeconds = 0
+	}
+
+	if cfg.LogsMaxTotalSizeMB < 0 {
+		cfg.LogsMaxTotalSizeMB = 0
+	}
+
+	if cfg.ErrorLogsMaxFiles < 0 {
+		cfg.ErrorLogsMaxFiles = 10
+	}
+
+	if cfg.RequestLogMaxBodyBytes < 0 {
+		cfg.RequestLogMaxBodyBytes = 0
+	}
+	if cfg.RequestLogSampleRate < 0 || cfg.RequestLogSampleRate > 1 {
+		cfg.RequestLogSampleRate = 0
+	}
+
+	// Sync request authentication providers with inline API keys for backwards compatibility.
+	syncInlineAccessProvider(&cfg)
+
+	// Normalize per-module log levels.
+	cfg.SanitizeLogLevels()
+
+	// Normalize client key groups before the per-key settings that reference them.
+	cfg.SanitizeClientKeyGroups()
+
+	// Normalize per-client API key auth permissions.
+	cfg.SanitizeAPIKeyAuth()
+
+	// Normalize per-client API key expiry timestamps.
+	cfg.SanitizeAPIKeyExpiry()
+
+	// Normalize client API key metadata and drop entries of removed keys.
+	cfg.SanitizeAPIKeyMetadata()
+
+	// Normalize virtual model catalogs and their client key assignments.
+	cfg.SanitizeModelCatalogs()
+
+	// Drop malformed and expired share links.
+	cfg.SanitizeShareLinks()
+
+	// Sanitize Gemini API key configuration and migrate legacy entries.
+	cfg.SanitizeGeminiKeys()
+
+	// Sanitize Vertex-compatible API keys: drop entries without base-url
+	cfg.SanitizeVertexCompatKeys()
+
+	// Sanitize Codex keys: drop entries without base-url
+	cfg.SanitizeCodexKeys()
+
+	// Sanitize Claude key headers
+	cfg.SanitizeClaudeKeys()
+
+	// Sanitize OpenAI compatibility providers: drop entries without base-url
+	cfg.SanitizeOpenAICompatibility()
+
+	// Sanitize mock providers: drop entries without a name or models
+	cfg.SanitizeMockProviders()
+
+	// Normalize OAuth provider model exclusion map.
+	cfg.OAuthExcludedModels = NormalizeOAuthExcludedModels(cfg.OAuthExcludedModels)
+
+	// Normalize global OAuth model name aliases.
+	cfg.SanitizeOAuthModelAlias()
+
+	// Validate raw payload rules and drop invalid entries.
+	cfg.SanitizePayloadRules()
+
+	// Drop payload profiles and bindings that cannot be resolved.
+	cfg.SanitizePayloadProfiles()
+
+	// Drop incomplete refusal fallback rules.
+	cfg.SanitizeRefusalFallback()
+
+	// Drop response rules that change nothing.
+	cfg.SanitizeResponseRules()
+
+	// Drop request scripts without a source and fill in their default limits.
+	cfg.SanitizeRequestScripts()
+
+	// Normalize context overflow strategy and sibling routes.
+	cfg.SanitizeContextOverflow()
+
+	// Normalize prompt compression strategy and thresholds.
+	cfg.SanitizePromptCompression()
+
+	// Normalize system prompt deduplication strategy and defaults.
+	cfg.SanitizeSystemPromptDedup()
+
+	// Normalize moderation actions and drop invalid per-key overrides.
+	cfg.SanitizeModeration()
+
+	// Normalize the cost ceiling action and drop unusable pricing entries.
+	cfg.SanitizeCostCeiling()
+
+	// Drop webhooks without a URL and normalize their event filters.
+	cfg.SanitizeWebhooks()
+
+	// Drop chat notifiers missing their destination.
+	cfg.SanitizeNotifiers()
+
+	// Normalize usage report periods and drop reports without a destination.
+	cfg.SanitizeUsageReports()
+
+	// Drop model metadata entries without a model pattern.
+	cfg.SanitizeModelMetadata()
+
+	// Normalize custom thinking suffixes.
+	cfg.SanitizeThinkingSuffixes()
+
+	// Drop an unparseable Codex client version.
+	cfg.SanitizeCodexClient()
+
+	// Normalize Codex built-in tool actions.
+	cfg.SanitizeCodexBuiltinTools()
+
+	// Apply Codex prompt cache defaults.
+	cfg.SanitizeCodexPromptCache()
+
+	// Normalize the Codex session ID strategy.
+	cfg.SanitizeCodexSession()
+
+	// Normalize upstream request timeout overrides.
+	cfg.SanitizeRequestTimeouts()
+
+	// Normalize executor plugin declarations.
+	cfg.SanitizeExecutorPlugins()
+
+	// Normalize upstream transport tuning overrides.
+	cfg.SanitizeUpstreamTransport()
+
+	// Normalize TLS pins and drop malformed ones.
+	cfg.SanitizeTLSPins()
+
+	// Normalize the audit chain paths.
+	cfg.SanitizeAuditChain()
+
+	// Normalize hedging provider keys.
+	cfg.SanitizeHedging()
+
+	// Clamp chaos fault rates.
+	cfg.SanitizeChaos()
+
+	// Drop MCP tool servers without a name or transport.
+	cfg.SanitizeMCPTools()
+
+	// Disable web search with an unknown or incomplete backend.
+	cfg.SanitizeWebSearch()
+
+	// Disable code execution with an unknown sandbox and fill in its limits.
+	cfg.SanitizeCodeExecution()
+
+	// Normalize repeated prompt actions.
+	cfg.SanitizeRepeatedPrompts()
+
+	if cfg.legacyMigrationPending {
+		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
+		if !optional && configFile != "" && !cfg.ReadOnly {
+			if err := SaveConfigPreserveComments(configFile, &cfg); err != nil {
+				return nil, fmt.Errorf("failed to persist migrated legacy config: %w", err)
+			}
+			fmt.Println("Legacy configuration normalized and persisted.")
+		} else {
+			fmt.Println("Legacy configuration normalized in memory; persistence skipped.")
+		}
+	}
+
+	// Return the populated configuration struct.
+	return &cfg, nil
+}
+
+// SanitizeMCPTools trims MCP tool server entries and drops those without a name, those with
+// neither a URL nor a command and duplicate names.
+func (cfg *Config) SanitizeMCPTools() {
+	if cfg == nil {
+		return
+	}
+	mt := &cfg.MCPTools
+	if mt.MaxIterations <= 0 {
+		mt.MaxIterations = 8
+	}
+	mt.ClientKeys = trimNonEmpty(mt.ClientKeys)
+	seen := make(map[string]struct{}, len(mt.Servers))
+	servers := make([]MCPServer, 0, len(mt.Servers))
+	for _, server := range mt.Servers {
+		server.Name = strings.TrimSpace(server.Name)
+		server.URL = strings.TrimSpace(server.URL)
+		server.Command = strings.TrimSpace(server.Command)
+		server.Tools = trimNonEmpty(server.Tools)
+		if server.Name == "" || (server.URL == "" && server.Command == "") {
+			log.Warnf("mcp-tools: dropping server %q without a name, url or command", server.Name)
+			continue
+		}
+		if _, dup := seen[server.Name]; dup {
+			log.Warnf("mcp-tools: dropping duplicate server %q", server.Name)
+			continue
+		}
+		seen[server.Name] = struct{}{}
+		if server.TimeoutSeconds <= 0 {
+			server.TimeoutSeconds = 60
+		}
+		servers = append(servers, server)
+	}
+	mt.Servers = servers
+}
+
+// SanitizeWebSearch lower-cases the web search provider, fills in the default limits and
+// disables the tool when the provider is unknown or SearxNG has no base URL.
+func (cfg *Config) SanitizeWebSearch() {
+	if cfg == nil {
+		return
+	}
+	ws := &cfg.WebSearch
+	ws.Provider = strings.ToLower(strings.TrimSpace(ws.Provider))
+	ws.BaseURL = strings.TrimRight(strings.TrimSpace(ws.BaseURL), "/")
+	ws.APIKey = strings.TrimSpace(ws.APIKey)
+	ws.ClientKeys = trimNonEmpty(ws.ClientKeys)
+	switch ws.Provider {
+	case "":
+	case WebSearchProviderSearxNG:
+		if ws.BaseURL == "" {
+			log.Warn("web-search: searxng requires base-url, disabling web search")
+			ws.Provider = ""
+		}
+	case WebSearchProviderBrave, WebSearchProviderBing:
+		if ws.APIKey == "" {
+			log.Warnf("web-search: %s requires api-key, disabling web search", ws.Provider)
+			ws.Provider = ""
+		}
+	default:
+		log.Warnf("web-search: unknown provider %q, disabling web search", ws.Provider)
+		ws.Provider = ""
+	}
+	if ws.MaxResults <= 0 {
+		ws.MaxResults = 5
+	}
+	if ws.MaxResultBytes <= 0 {
+		ws.MaxResultBytes = 8192
+	}
+	if ws.TimeoutSeconds <= 0 {
+		ws.TimeoutSeconds = 15
+	}
+}
+
+// SanitizeCodeExecution lower-cases the code execution sandbox, fills in the default limits
+// and disables code execution when the sandbox is unknown.
+func (cfg *Config) SanitizeCodeExecution() {
+	if cfg == nil {
+		return
+	}
+	ce := &cfg.CodeExecution
+	ce.Sandbox = strings.ToLower(strings.TrimSpace(ce.Sandbox))
+	ce.Binary = strings.TrimSpace(ce.Binary)
+	ce.Image = strings.TrimSpace(ce.Image)
+	ce.AuditFile = strings.TrimSpace(ce.AuditFile)
+	ce.ClientKeys = trimNonEmpty(ce.ClientKeys)
+	switch ce.Sandbox {
+	case "", CodeSandboxDocker, CodeSandboxFirejail:
+	default:
+		log.Warnf("code-execution: unknown sandbox %q, disabling code execution", ce.Sandbox)
+		ce.Sandbox = ""
+	}
+	if ce.Image == "" {
+		ce.Image = "python:3.12-alpine"
+	}
+	if ce.TimeoutSeconds <= 0 {
+		ce.TimeoutSeconds = 10
+	}
+	if ce.MemoryMB <= 0 {
+		ce.MemoryMB = 256
+	}
+	if ce.MaxProcesses <= 0 {
+		ce.MaxProcesses = 32
+	}
+	if ce.MaxOutputBytes <= 0 {
+		ce.MaxOutputBytes = 16384
+	}
+}
+
+// SanitizeRepeatedPrompts lower-cases the repeated prompt actions and falls back to delaying
+// when an action is unknown.
+func (cfg *Config) SanitizeRepeatedPrompts() {
+	if cfg == nil {
+		return
+	}
+	rp := &cfg.RepeatedPrompts
+	rp.AuditFile = strings.TrimSpace(rp.AuditFile)
+	normalize := func(where, action string) string {
+		action = strings.ToLower(strings.TrimSpace(action))
+		switch action {
+		case "", RepeatedPromptDelay, RepeatedPromptBlock:
+			return action
+		default:
+			log.Warnf("repeated-prompts: unknown action %q for %s, delaying instead", action, where)
+			return RepeatedPromptDelay
+		}
+	}
+	rp.Action = normalize("all keys", rp.Action)
+	for key, override := range rp.Keys {
+		override.Action = normalize("key "+maskKey(key), override.Action)
+		rp.Keys[key] = override
+	}
+}
+
+// SanitizePayloadRules validates raw JSON payload rule params and drops invalid rules.
+func (cfg *Config) SanitizePayloadRules() {
+	if cfg == nil {
+		return
+	}
+	cfg.Payload.DefaultRaw = sanitizePayloadRawRules(cfg.Payload.DefaultRaw, "default-raw")
+	cfg.Payload.OverrideRaw = sanitizePayloadRawRules(cfg.Payload.OverrideRaw, "override-raw")
+	cfg.Payload.Compat = sanitizePayloadCompatRules(cfg.Payload.Compat)
+	cfg.Payload.Transform = sanitizePayloadTransformRules(cfg.Payload.Transform)
+}
+
+// SanitizeRequestTimeouts lower-cases provider and endpoint keys and drops unknown endpoint kinds.
+func (cfg *Config) SanitizeRequestTimeouts() {
+	if cfg == nil || len(cfg.RequestTimeouts.Providers) == 0 {
+		return
+	}
+	providers := make(map[string]ProviderRequestTimeouts, len(cfg.RequestTimeouts.Providers))
+	for rawProvider, entry := range cfg.RequestTimeouts.Providers {
+		provider := strings.ToLower(strings.TrimSpace(rawProvider))
+		if provider == "" {
+			continue
+		}
+		var endpoints map[string]RequestTimeouts
+		for rawEndpoint, timeouts := range entry.Endpoints {
+			endpoint := strings.ToLower(strings.TrimSpace(rawEndpoint))
+			switch endpoint {
+			case RequestEndpointExecute, RequestEndpointStream, RequestEndpointCountTokens:
+			default:
+				continue
+			}
+			if endpoints == nil {
+				endpoints = make(map[string]RequestTimeouts, len(entry.Endpoints))
+			}
+			endpoints[endpoint] = timeouts
+		}
+		entry.Endpoints = endpoints
+		providers[provider] = entry
+	}
+	if len(providers) == 0 {
+		providers = nil
+	}
+	cfg.RequestTimeouts.Providers = providers
+}
+
+// SanitizeUpstreamTransport lower-cases provider keys of upstream transport overrides.
+func (cfg *Config) SanitizeUpstreamTransport() {
+	if cfg == nil || len(cfg.UpstreamTransport.Providers) == 0 {
+		return
+	}
+	providers := make(map[string]TransportTuning, len(cfg.UpstreamTransport.Providers))
+	for rawProvider, tuning := range cfg.UpstreamTransport.Providers {
+		provider := strings.ToLower(strings.TrimSpace(rawProvider))
+		if provider == "" {
+			continue
+		}
+		providers[provider] = tuning
+	}
+	if len(providers) == 0 {
+		providers = nil
+	}
+	cfg.UpstreamTransport.Providers = providers
+}
+
+// SanitizeTLSPins lower-cases pinned hosts, strips "sha256/" prefixes and colons from pins and
+// drops malformed pins and entries without a host or with an IP address host. An entry left
+// without pins stays, so connections to its host keep failing rather than going unpinned.
+func (cfg *Config) SanitizeTLSPins() {
+	if cfg == nil || len(cfg.TLSPins) == 0 {
+		return
+	}
+	pins := make([]TLSPin, 0, len(cfg.TLSPins))
+	for _, pin := range cfg.TLSPins {
+		pin.Host = strings.ToLower(strings.TrimSpace(pin.Host))
+		if pin.Host == "" || net.ParseIP(strings.Trim(pin.Host, "[]")) != nil {
+			log.Warnf("tls-pins: dropping entry with missing or IP address host %q", pin.Host)
+			continue
+		}
+		spki := make([]string, 0, len(pin.SPKISHA256))
+		for _, raw := range pin.SPKISHA256 {
+			value := strings.TrimPrefix(strings.TrimSpace(raw), "sha256/")
+			if digest, err := base64.StdEncoding.DecodeString(value); err != nil || len(digest) != sha256.Size {
+				log.Warnf("tls-pins: dropping malformed spki-sha256 pin %q for %s", raw, pin.Host)
+				continue
+			}
+			spki = append(spki, value)
+		}
+		certs := make([]string, 0, len(pin.CertSHA256))
+		for _, raw := range pin.CertSHA256 {
+			value := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), ":", ""))
+			if digest, err := hex.DecodeString(value); err != nil || len(digest) != sha256.Size {
+				log.Warnf("tls-pins: dropping malformed cert-sha256 pin %q for %s", raw, pin.Host)
+				continue
+			}
+			certs = append(certs, value)
+		}
+		if len(spki) == 0 && len(certs) == 0 {
+			log.Warnf("tls-pins: %s has no valid pins, connections to it will fail", pin.Host)
+		}
+		pin.SPKISHA256, pin.CertSHA256 = spki, certs
+		pins = append(pins, pin)
+	}
+	cfg.TLSPins = pins
+}
+
+// SanitizeAuditChain trims the audit chain paths and clears a negative anchor interval.
+func (cfg *Config) SanitizeAuditChain() {
+	if cfg == nil {
+		return
+	}
+	ac := &cfg.AuditChain
+	ac.File = strings.TrimSpace(ac.File)
+	ac.AnchorFile = strings.TrimSpace(ac.AnchorFile)
+	ac.AnchorURL = strings.TrimSpace(ac.AnchorURL)
+	if ac.AnchorIntervalSeconds < 0 {
+		ac.AnchorIntervalSeconds = 0
+	}
+}
+
+// SanitizeHedging lower-cases hedging provider keys and drops empty entries.
+func (cfg *Config) SanitizeHedging() {
+	if cfg == nil {
+		return
+	}
+	if cfg.Hedging.DelayMs < 0 {
+		cfg.Hedging.DelayMs = 0
+	}
+	if len(cfg.Hedging.Providers) == 0 {
+		return
+	}
+	providers := make([]string, 0, len(cfg.Hedging.Providers))
+	for _, raw := range cfg.Hedging.Providers {
+		if provider := strings.ToLower(strings.TrimSpace(raw)); provider != "" {
+			providers = append(providers, provider)
+		}
+	}
+	cfg.Hedging.Providers = providers
+}
+
+// SanitizeChaos clamps fault rates to [0, 1], drops negative delays and lower-cases provider keys.
+func (cfg *Config) SanitizeChaos() {
+	if cfg == nil {
+		return
+	}
+	c := &cfg.Chaos
+	for _, rate := range []*float64{&c.RateLimitRate, &c.ServerErrorRate, &c.ProxyErrorRate, &c.TruncateRate, &c.SlowChunkRate} {
+		*rate = min(max(*rate, 0), 1)
+	}
+	c.RetryAfterSeconds = max(c.RetryAfterSeconds, 0)
+	c.SlowChunkDelayMs = max(c.SlowChunkDelayMs, 0)
+	if len(c.Providers) == 0 {
+		return
+	}
+	providers := make([]string, 0, len(c.Providers))
+	for _, raw := range c.Providers {
+		if provider := strings.ToLower(strings.TrimSpace(raw)); provider != "" {
+			providers = append(providers, provider)
+		}
+	}
+	c.Providers = providers
+}
+
+// SanitizeModelMetadata trims model metadata entries and drops those without a model pattern.
+func (cfg *Config) SanitizeModelMetadata() {
+	if cfg == nil || len(cfg.ModelMetadata) == 0 {
+		return
+	}
+	out := make([]ModelMetadataEntry, 0, len(cfg.ModelMetadata))
+	for _, entry := range cfg.ModelMetadata {
+		entry.Model = strings.TrimSpace(entry.Model)
+		if entry.Model == "" {
+			continue
+		}
+		if entry.ContextWindow < 0 {
+			entry.ContextWindow = 0
+		}
+		if entry.MaxOutputTokens < 0 {
+			entry.MaxOutputTokens = 0
+		}
+		out = append(out, entry)
+	}
+	cfg.ModelMetadata = out
+}
+
+// SanitizeThinkingSuffixes trims and lowercases thinking suffix names and providers, and drops
+// entries without a name or thinking value.
+func (cfg *Config) SanitizeThinkingSuffixes() {
+	if cfg == nil || len(cfg.ThinkingSuffixes) == 0 {
+		return
+	}
+	out := make([]ThinkingSuffix, 0, len(cfg.ThinkingSuffixes))
+	for _, entry := range cfg.ThinkingSuffixes {
+		entry.Name = strings.ToLower(strings.TrimSpace(entry.Name))
+		entry.Thinking = strings.ToLower(strings.TrimSpace(entry.Thinking))
+		if entry.Name == "" || entry.Thinking == "" {
+			continue
+		}
+		providers := make([]string, 0, len(entry.Providers))
+		for _, provider := range entry.Providers {
+			if provider = strings.ToLower(strings.TrimSpace(provider)); provider != "" {
+				providers = append(providers, provider)
+			}
+		}
+		entry.Providers = providers
+		if entry.MaxOutputTokens < 0 {
+			entry.MaxOutputTokens = 0
+		}
+		out = append(out, entry)
+	}
+	cfg.ThinkingSuffixes = out
+}
+
+// codexVersionPattern matches a Codex CLI version such as 0.98.0.
+var codexVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// SanitizeCodexClient trims the Codex client settings and drops a version that is not of the
+// form major.minor.patch.
+func (cfg *Config) SanitizeCodexClient() {
+	if cfg == nil {
+		return
+	}
+	c := &cfg.CodexClient
+	c.Version = strings.TrimPrefix(strings.TrimSpace(c.Version), "v")
+	c.UserAgent = strings.TrimSpace(c.UserAgent)
+	c.VersionURL = strings.TrimSpace(c.VersionURL)
+	if c.Version != "" && !codexVersionPattern.MatchString(c.Version) {
+		log.Warnf("codex-client.version %q is not of the form major.minor.patch; using the built-in version", c.Version)
+		c.Version = ""
+	}
+	if c.VersionRefreshHours < 0 {
+		c.VersionRefreshHours = 0
+	}
+}
+
+// SanitizeCodexPromptCache applies the Codex prompt cache defaults.
+func (cfg *Config) SanitizeCodexPromptCache() {
+	if cfg == nil {
+		return
+	}
+	if cfg.CodexPromptCache.TTLSeconds <= 0 {
+		cfg.CodexPromptCache.TTLSeconds = 3600
+	}
+	if cfg.CodexPromptCache.MaxEntries <= 0 {
+		cfg.CodexPromptCache.MaxEntries = 10000
+	}
+}
+
+// SanitizeCodexSession normalizes the Codex session ID strategy, falling back to "auto" for
+// unknown values.
+func (cfg *Config) SanitizeCodexSession() {
+	if cfg == nil {
+		return
+	}
+	strategy := strings.ToLower(strings.TrimSpace(cfg.CodexSession.Strategy))
+	switch strategy {
+	case CodexSessionAuto, CodexSessionPerRequest, CodexSessionPerClient, CodexSessionSystemPrompt, CodexSessionPassthrough:
+	case "":
+		strategy = CodexSessionAuto
+	default:
+		log.Warnf("codex-session.strategy %q is not supported; using auto", cfg.CodexSession.Strategy)
+		strategy = CodexSessionAuto
+	}
+	cfg.CodexSession.Strategy = strategy
+}
+
+// SanitizeExecutorPlugins lowercases provider keys, infers plugin types and drops entries that
+// cannot be loaded.
+func (cfg *Config) SanitizeExecutorPlugins() {
+	if cfg == nil || len(cfg.ExecutorPlugins) == 0 {
+		return
+	}
+	plugins := make([]ExecutorPluginConfig, 0, len(cfg.ExecutorPlugins))
+	for _, plugin := range cfg.ExecutorPlugins {
+		plugin.Path = strings.TrimSpace(plugin.Path)
+		plugin.Provider = strings.ToLower(strings.TrimSpace(plugin.Provider))
+		plugin.Type = strings.ToLower(strings.TrimSpace(plugin.Type))
+		if plugin.Path == "" {
+			log.Warn("executor-plugins: entry without path ignored")
+			continue
+		}
+		if plugin.Type == "" {
+			plugin.Type = ExecutorPluginSidecar
+			if strings.EqualFold(filepath.Ext(plugin.Path), ".so") {
+				plugin.Type = ExecutorPluginSharedObject
+			}
+		}
+		switch plugin.Type {
+		case ExecutorPluginSharedObject:
+		case ExecutorPluginSidecar:
+			if plugin.Provider == "" {
+				log.Warnf("executor-plugins: sidecar %s has no provider and is ignored", plugin.Path)
+				continue
+			}
+		default:
+			log.Warnf("executor-plugins: type %q of %s is not supported", plugin.Type, plugin.Path)
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+	cfg.ExecutorPlugins = plugins
+}
+
+// SanitizeContextOverflow normalizes the overflow strategy and drops incomplete sibling routes.
+func (cfg *Config) SanitizeContextOverflow() {
+	if cfg == nil {
+		return
+	}
+	strategy := strings.ToLower(strings.TrimSpace(cfg.ContextOverflow.Strategy))
+	switch strategy {
+	case "", ContextOverflowReject, ContextOverflowTruncate, ContextOverflowRoute:
+	default:
+		log.Warnf("context-overflow: unknown strategy %q, overflow handling disabled", strategy)
+		strategy = ""
+	}
+	cfg.ContextOverflow.Strategy = strategy
+	if len(cfg.ContextOverflow.Siblings) == 0 {
+		return
+	}
+	out := make([]ContextOverflowSibling, 0, len(cfg.ContextOverflow.Siblings))
+	for _, entry := range cfg.ContextOverflow.Siblings {
+		entry.Model = strings.TrimSpace(entry.Model)
+		entry.Sibling = strings.TrimSpace(entry.Sibling)
+		if entry.Model == "" || entry.Sibling == "" {
+			continue
+		}
+		out = append(out, entry)
+	}
+	cfg.ContextOverflow.Siblings = out
+}
+
+// SanitizePromptCompression normalizes the compression strategy and disables it when it cannot run.
+func (cfg *Config) SanitizePromptCompression() {
+	if cfg == nil {
+		return
+	}
+	pc := &cfg.PromptCompression
+	pc.Strategy = strings.ToLower(strings.TrimSpace(pc.Strategy))
+	pc.SummaryModel = strings.TrimSpace(pc.SummaryModel)
+	switch pc.Strategy {
+	case "", PromptCompressionDropMiddle:
+	case PromptCompressionSummarize:
+		if pc.SummaryModel == "" {
+			log.Warnf("prompt-compression: summarize strategy requires summary-model, falling back to %s", PromptCompressionDropMiddle)
+			pc.Strategy = PromptCompressionDropMiddle
+		}
+	default:
+		log.Warnf("prompt-compression: unknown strategy %q, compression disabled", pc.Strategy)
+		pc.Strategy = ""
+	}
+	if pc.Strategy != "" && pc.ThresholdTokens <= 0 {
+		log.Warnf("prompt-compression: threshold-tokens must be positive, compression disabled")
+		pc.Strategy = ""
+	}
+	if pc.KeepRecent < 0 {
+		pc.KeepRecent = 0
+	}
+	models := make([]string, 0, len(pc.Models))
+	for _, model := range pc.Models {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	pc.Models = models
+}
+
+// SanitizeSystemPromptDedup normalizes the deduplication strategy and applies defaults.
+func (cfg *Config) SanitizeSystemPromptDedup() {
+	if cfg == nil {
+		return
+	}
+	sd := &cfg.SystemPromptDedup
+	sd.Strategy = strings.ToLower(strings.TrimSpace(sd.Strategy))
+	sd.SummaryModel = strings.TrimSpace(sd.SummaryModel)
+	switch sd.Strategy {
+	case "", SystemPromptDedupCacheKey:
+	case SystemPromptDedupSummarize:
+		if sd.SummaryModel == "" {
+			log.Warnf("system-prompt-dedup: summarize strategy requires summary-model, falling back to %s", SystemPromptDedupCacheKey)
+			sd.Strategy = SystemPromptDedupCacheKey
+		}
+	default:
+		log.Warnf("system-prompt-dedup: unknown strategy %q, deduplication disabled", sd.Strategy)
+		sd.Strategy = ""
+	}
+	if sd.MinTokens <= 0 {
+		sd.MinTokens = 1024
+	}
+	if sd.TTLSeconds <= 0 {
+		sd.TTLSeconds = 3600
+	}
+	models := make([]string, 0, len(sd.Models))
+	for _, model := range sd.Models {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	sd.Models = models
+}
+
+// SanitizeModeration trims the moderation backend settings and normalizes pre-flight actions.
+// Unknown actions are treated as "off".
+func (cfg *Config) SanitizeModeration() {
+	if cfg == nil {
+		return
+	}
+	m := &cfg.Moderation
+	m.BaseURL = strings.TrimRight(strings.TrimSpace(m.BaseURL), "/")
+	m.APIKey = strings.TrimSpace(m.APIKey)
+	m.Model = strings.TrimSpace(m.Model)
+	m.Action = normalizeModerationAction(m.Action)
+	if len(m.KeyActions) == 0 {
+		return
+	}
+	actions := make(map[string]string, len(m.KeyActions))
+	for key, action := range m.KeyActions {
+		if key = strings.TrimSpace(key); key != "" {
+			actions[key] = normalizeModerationAction(action)
+		}
+	}
+	m.KeyActions = actions
+}
+
+// SanitizeCodexBuiltinTools lower-cases Codex built-in tool types and actions and drops
+// entries with an unknown action.
+func (cfg *Config) SanitizeCodexBuiltinTools() {
+	if cfg == nil {
+		return
+	}
+	b := &cfg.CodexBuiltinTools
+	b.Tools = normalizeCodexBuiltinToolActions(b.Tools)
+	if len(b.KeyTools) == 0 {
+		return
+	}
+	keyTools := make(map[string]map[string]string, len(b.KeyTools))
+	for key, tools := range b.KeyTools {
+		if key = strings.TrimSpace(key); key != "" {
+			keyTools[key] = normalizeCodexBuiltinToolActions(tools)
+		}
+	}
+	b.KeyTools = keyTools
+}
+
+func normalizeCodexBuiltinToolActions(tools map[string]string) map[string]string {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(tools))
+	for tool, action := range tools {
+		tool = strings.ToLower(strings.TrimSpace(tool))
+		action = strings.ToLower(strings.TrimSpace(action))
+		if tool == "" {
+			continue
+		}
+		if action != CodexBuiltinToolAllow && action != CodexBuiltinToolStrip {
+			log.Warnf("codex-builtin-tools: unknown action %q for %s, ignoring", action, tool)
+			continue
+		}
+		out[tool] = action
+	}
+	return out
+}
+
+func normalizeModerationAction(action string) string {
+	switch action = strings.ToLower(strings.TrimSpace(action)); action {
+	case "", ModerationActionOff:
+		return ModerationActionOff
+	case ModerationActionFlag, ModerationActionReject:
+		return action
+	default:
+		log.Warnf("moderation: unknown action %q, treating as %s", action, ModerationActionOff)
+		return ModerationActionOff
+	}
+}
+
+// SanitizeCostCeiling normalizes the cost ceiling action and drops pricing entries without a
+// model pattern or with negative prices.
+func (cfg *Config) SanitizeCostCeiling() {
+	if cfg == nil {
+		return
+	}
+	cc := &cfg.CostCeiling
+	switch cc.Action = strings.ToLower(strings.TrimSpace(cc.Action)); cc.Action {
+	case CostCeilingReject, CostCeilingClamp:
+	case "":
+		cc.Action = CostCeilingReject
+	default:
+		log.Warnf("cost-ceiling: unknown action %q, using %s", cc.Action, CostCeilingReject)
+		cc.Action = CostCeilingReject
+	}
+	pricing := make([]ModelPricing, 0, len(cc.Pricing))
+	for _, entry := range cc.Pricing {
+		entry.Model = strings.TrimSpace(entry.Model)
+		if entry.Model == "" || entry.InputPerMillion < 0 || entry.OutputPerMillion < 0 || entry.CachedInputPerMillion < 0 {
+			continue
+		}
+		pricing = append(pricing, entry)
+	}
+	cc.Pricing = pricing
+}
+
+// SanitizeWebhooks trims webhook URLs and event filters and drops webhooks without a URL.
+func (cfg *Config) SanitizeWebhooks() {
+	if cfg == nil || len(cfg.Webhooks) == 0 {
+		return
+	}
+	out := make([]WebhookConfig, 0, len(cfg.Webhooks))
+	for _, hook := range cfg.Webhooks {
+		hook.URL = strings.TrimSpace(hook.URL)
+		if hook.URL == "" {
+			continue
+		}
+		hook.Events = normalizeEventTypes(hook.Events)
+		out = append(out, hook)
+	}
+	cfg.Webhooks = out
+}
+
+// SanitizeNotifiers trims notifier settings and drops notifiers missing their destination.
+func (cfg *Config) SanitizeNotifiers() {
+	if cfg == nil {
+		return
+	}
+	n := &cfg.Notifiers
+	telegram := make([]TelegramNotifier, 0, len(n.Telegram))
+	for _, notifier := range n.Telegram {
+		notifier.BotToken = strings.TrimSpace(notifier.BotToken)
+		notifier.ChatID = strings.TrimSpace(notifier.ChatID)
+		if notifier.BotToken == "" || notifier.ChatID == "" {
+			continue
+		}
+		notifier.Events = normalizeEventTypes(notifier.Events)
+		telegram = append(telegram, notifier)
+	}
+	n.Telegram = telegram
+	discord := make([]DiscordNotifier, 0, len(n.Discord))
+	for _, notifier := range n.Discord {
+		notifier.WebhookURL = strings.TrimSpace(notifier.WebhookURL)
+		if notifier.WebhookURL == "" {
+			continue
+		}
+		notifier.Events = normalizeEventTypes(notifier.Events)
+		discord = append(discord, notifier)
+	}
+	n.Discord = discord
+	if len(n.Templates) > 0 {
+		templates := make(map[string]string, len(n.Templates))
+		for event, text := range n.Templates {
+			if event = strings.ToLower(strings.TrimSpace(event)); event != "" && strings.TrimSpace(text) != "" {
+				templates[event] = text
+			}
+		}
+		n.Templates = templates
 	}
+}
 
-	// In cloud deploy mode (optional=true), if file is empty or contains only whitespace, return empty config.
-	if optional && len(data) == 0 {
-		return &Config{}, nil
+// SanitizeUsageReports normalizes report periods, fills in names and drops reports that have
+// neither a webhook nor email recipients.
+func (cfg *Config) SanitizeUsageReports() {
+	if cfg == nil || len(cfg.UsageReports.Reports) == 0 {
+		return
 	}
-
-	// Unmarshal the YAML data into the Config struct.
-	var cfg Config
-	// Set defaults before unmarshal so that absent keys keep defaults.
-	cfg.Host = "" // Default empty: binds to all interfaces (IPv4 + IPv6)
-	cfg.LoggingToFile = false
-	cfg.LogsMaxTotalSizeMB = 0
-	cfg.ErrorLogsMaxFiles = 10
-	cfg.UsageStatisticsEnabled = false
-	cfg.DisableCooling = false
-	cfg.Pprof.Enable = false
-	cfg.Pprof.Addr = DefaultPprofAddr
-	cfg.AmpCode.RestrictManagementToLocalhost = false // Default to false: API key auth is sufficient
-	cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
-	if err = yaml.Unmarshal(data, &cfg); err != nil {
-		if optional {
-			// In cloud deploy mode, if YAML parsing fails, return empty config instead of error.
-			return &Config{}, nil
+	cfg.UsageReports.SMTP.Host = strings.TrimSpace(cfg.UsageReports.SMTP.Host)
+	out := make([]UsageReport, 0, len(cfg.UsageReports.Reports))
+	for _, report := range cfg.UsageReports.Reports {
+		report.WebhookURL = strings.TrimSpace(report.WebhookURL)
+		recipients := make([]string, 0, len(report.EmailTo))
+		for _, to := range report.EmailTo {
+			if to = strings.TrimSpace(to); to != "" {
+				recipients = append(recipients, to)
+			}
 		}
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	var legacy legacyConfigData
-	if errLegacy := yaml.Unmarshal(data, &legacy); errLegacy == nil {
-		if cfg.migrateLegacyGeminiKeys(legacy.LegacyGeminiKeys) {
-			cfg.legacyMigrationPending = true
+		report.EmailTo = recipients
+		if report.WebhookURL == "" && len(report.EmailTo) == 0 {
+			continue
 		}
-		if cfg.migrateLegacyOpenAICompatibilityKeys(legacy.OpenAICompat) {
-			cfg.legacyMigrationPending = true
+		switch report.Period = strings.ToLower(strings.TrimSpace(report.Period)); report.Period {
+		case UsageReportDaily, UsageReportWeekly:
+		case "":
+			report.Period = UsageReportDaily
+		default:
+			log.Warnf("usage-reports: unknown period %q, using %s", report.Period, UsageReportDaily)
+			report.Period = UsageReportDaily
 		}
-		if cfg.migrateLegacyAmpConfig(&legacy) {
-			cfg.legacyMigrationPending = true
+		report.Schedule = strings.TrimSpace(report.Schedule)
+		if report.Name = strings.TrimSpace(report.Name); report.Name == "" {
+			report.Name = report.Period + " usage"
 		}
+		out = append(out, report)
 	}
+	cfg.UsageReports.Reports = out
+}
 
-	// Hash remote management key if plaintext is detected (nested)
-	// We consider a value to be already hashed if it looks like a bcrypt hash ($2a$, $2b$, or $2y$ prefix).
-	if cfg.RemoteManagement.SecretKey != "" && !looksLikeBcrypt(cfg.RemoteManagement.SecretKey) {
-		hashed, errHash := hashSecret(cfg.RemoteManagement.SecretKey)
-		if errHash != nil {
-			return nil, fmt.Errorf("failed to hash remote management key: %w", errHash)
+func normalizeEventTypes(events []string) []string {
+	out := make([]string, 0, len(events))
+	for _, event := range events {
+		if event = strings.ToLower(strings.TrimSpace(event)); event != "" {
+			out = append(out, event)
 		}
-		cfg.RemoteManagement.SecretKey = hashed
-
-		// Persist the hashed value back to the config file to avoid re-hashing on next startup.
-		// Preserve YAML comments and ordering; update only the nested key.
-		_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
 	}
+	return out
+}
 
-	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
-	if cfg.RemoteManagement.PanelGitHubRepository == "" {
-		cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
+// SanitizeResponseRules trims response rule fields and drops rules that change nothing.
+func (cfg *Config) SanitizeResponseRules() {
+	if cfg == nil || len(cfg.ResponseRules) == 0 {
+		return
 	}
-
-	cfg.Pprof.Addr = strings.TrimSpace(cfg.Pprof.Addr)
-	if cfg.Pprof.Addr == "" {
-		cfg.Pprof.Addr = DefaultPprofAddr
+	rules := make([]ResponseRule, 0, len(cfg.ResponseRules))
+	for _, rule := range cfg.ResponseRules {
+		rule.Models = trimNonEmpty(rule.Models)
+		rule.ClientKeys = trimNonEmpty(rule.ClientKeys)
+		rule.Strip = trimNonEmpty(rule.Strip)
+		rule.ServedByHeader = strings.TrimSpace(rule.ServedByHeader)
+		rule.ServedByField = strings.TrimSpace(rule.ServedByField)
+		rule.WatermarkField = strings.TrimSpace(rule.WatermarkField)
+		rule.WatermarkHeader = strings.TrimSpace(rule.WatermarkHeader)
+		if len(rule.Strip) == 0 && !rule.RewriteModel && rule.ServedByHeader == "" && rule.ServedByField == "" && rule.WatermarkField == "" && rule.WatermarkHeader == "" {
+			continue
+		}
+		rules = append(rules, rule)
 	}
+	cfg.ResponseRules = rules
+}
 
-	if cfg.LogsMaxTotalSizeMB < 0 {
-		cfg.LogsMaxTotalSizeMB = 0
+// SanitizeRequestScripts trims request script entries, drops those with neither a path nor an
+// inline source and fills in the default limits.
+func (cfg *Config) SanitizeRequestScripts() {
+	if cfg == nil || len(cfg.RequestScripts) == 0 {
+		return
 	}
-
-	if cfg.ErrorLogsMaxFiles < 0 {
-		cfg.ErrorLogsMaxFiles = 10
+	scripts := make([]RequestScript, 0, len(cfg.RequestScripts))
+	for _, script := range cfg.RequestScripts {
+		script.Name = strings.TrimSpace(script.Name)
+		script.Path = strings.TrimSpace(script.Path)
+		script.Models = trimNonEmpty(script.Models)
+		script.ClientKeys = trimNonEmpty(script.ClientKeys)
+		if script.Path == "" && strings.TrimSpace(script.Source) == "" {
+			log.Warnf("request-scripts: dropping entry %q without path or source", script.Name)
+			continue
+		}
+		if script.Name == "" {
+			script.Name = script.Path
+		}
+		if script.Name == "" {
+			script.Name = fmt.Sprintf("request-script-%d", len(scripts)+1)
+		}
+		if script.TimeoutMs <= 0 {
+			script.TimeoutMs = 50
+		}
+		if script.MaxSteps <= 0 {
+			script.MaxSteps = 1000000
+		}
+		if script.MaxInputKB <= 0 {
+			script.MaxInputKB = 1024
+		}
+		if script.MaxAllocMB <= 0 {
+			script.MaxAllocMB = 64
+		}
+		scripts = append(scripts, script)
 	}
+	cfg.RequestScripts = scripts
+}
 
-	// Sync request authentication providers with inline API keys for backwards compatibility.
-	syncInlineAccessProvider(&cfg)
-
-	// Normalize per-client API key auth permissions.
-	cfg.SanitizeAPIKeyAuth()
-
-	// Normalize per-client API key expiry timestamps.
-	cfg.SanitizeAPIKeyExpiry()
-
-	// Sanitize Gemini API key configuration and migrate legacy entries.
-	cfg.SanitizeGeminiKeys()
-
-	// Sanitize Vertex-compatible API keys: drop entries without base-url
-	cfg.SanitizeVertexCompatKeys()
-
-	// Sanitize Codex keys: drop entries without base-url
-	cfg.SanitizeCodexKeys()
-
-	// Sanitize Claude key headers
-	cfg.SanitizeClaudeKeys()
-
-	// Sanitize OpenAI compatibility providers: drop entries without base-url
-	cfg.SanitizeOpenAICompatibility()
-
-	// Normalize OAuth provider model exclusion map.
-	cfg.OAuthExcludedModels = NormalizeOAuthExcludedModels(cfg.OAuthExcludedModels)
-
-	// Normalize global OAuth model name aliases.
-	cfg.SanitizeOAuthModelAlias()
-
-	// Validate raw payload rules and drop invalid entries.
-	cfg.SanitizePayloadRules()
-
-	if cfg.legacyMigrationPending {
-		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
-		if !optional && configFile != "" {
-			if err := SaveConfigPreserveComments(configFile, &cfg); err != nil {
-				return nil, fmt.Errorf("failed to persist migrated legacy config: %w", err)
-			}
-			fmt.Println("Legacy configuration normalized and persisted.")
-		} else {
-			fmt.Println("Legacy configuration normalized in memory; persistence skipped.")
+func trimNonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			out = append(out, value)
 		}
 	}
-
-	// Return the populated configuration struct.
-	return &cfg, nil
+	return out
 }
 
-// SanitizePayloadRules validates raw JSON payload rule params and drops invalid rules.
-func (cfg *Config) SanitizePayloadRules() {
-	if cfg == nil {
+// SanitizeRefusalFallback trims refusal fallback rules and drops entries missing a model or fallback.
+func (cfg *Config) SanitizeRefusalFallback() {
+	if cfg == nil || len(cfg.RefusalFallback.Rules) == 0 {
 		return
 	}
-	cfg.Payload.DefaultRaw = sanitizePayloadRawRules(cfg.Payload.DefaultRaw, "default-raw")
-	cfg.Payload.OverrideRaw = sanitizePayloadRawRules(cfg.Payload.OverrideRaw, "override-raw")
+	out := make([]RefusalFallbackRule, 0, len(cfg.RefusalFallback.Rules))
+	for _, rule := range cfg.RefusalFallback.Rules {
+		rule.Model = strings.TrimSpace(rule.Model)
+		rule.Fallback = strings.TrimSpace(rule.Fallback)
+		if rule.Model == "" || rule.Fallback == "" || strings.EqualFold(rule.Model, rule.Fallback) {
+			continue
+		}
+		out = append(out, rule)
+	}
+	cfg.RefusalFallback.Rules = out
 }
 
 // SanitizeAPIKeyAuth normalizes per-client API key auth permissions.
@@ -724,7 +2314,98 @@ func (cfg *Config) SanitizeAPIKeyAuth() {
 	if cfg == nil {
 		return
 	}
-	cfg.APIKeyAuth = NormalizeAPIKeyAuthForKnownKeys(cfg.APIKeyAuth, append([]string{}, cfg.APIKeys...))
+	cfg.APIKeyAuth = NormalizeAPIKeyAuthForKnownKeys(cfg.APIKeyAuth, cfg.ClientKeyRefs())
+}
+
+// SanitizeModelCatalogs trims catalog definitions, drops empty entries, and removes
+// catalog assignments for blank keys. Assignments to unknown catalogs are kept so the
+// affected keys see no models instead of silently gaining access to all of them.
+func (cfg *Config) SanitizeModelCatalogs() {
+	if cfg == nil {
+		return
+	}
+	if len(cfg.ModelCatalogs) > 0 {
+		catalogs := make([]ModelCatalog, 0, len(cfg.ModelCatalogs))
+		seen := make(map[string]struct{}, len(cfg.ModelCatalogs))
+		for _, catalog := range cfg.ModelCatalogs {
+			catalog.Name = strings.TrimSpace(catalog.Name)
+			key := strings.ToLower(catalog.Name)
+			if catalog.Name == "" {
+				continue
+			}
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			entries := make([]ModelCatalogEntry, 0, len(catalog.Models))
+			for _, entry := range catalog.Models {
+				entry.Name = strings.TrimSpace(entry.Name)
+				entry.Alias = strings.TrimSpace(entry.Alias)
+				if entry.Name == "" {
+					continue
+				}
+				if strings.Contains(entry.Name, "*") || strings.EqualFold(entry.Name, entry.Alias) {
+					entry.Alias = ""
+				}
+				entries = append(entries, entry)
+			}
+			catalog.Models = entries
+			catalogs = append(catalogs, catalog)
+		}
+		cfg.ModelCatalogs = catalogs
+	}
+	if len(cfg.APIKeyCatalogs) > 0 {
+		assignments := make(map[string]string, len(cfg.APIKeyCatalogs))
+		for rawKey, rawCatalog := range cfg.APIKeyCatalogs {
+			key := strings.TrimSpace(rawKey)
+			catalog := strings.TrimSpace(rawCatalog)
+			if key == "" || catalog == "" {
+				continue
+			}
+			assignments[key] = catalog
+		}
+		if len(assignments) == 0 {
+			assignments = nil
+		}
+		cfg.APIKeyCatalogs = assignments
+	}
+}
+
+// SanitizeShareLinks trims share links and drops entries without a key, with a duplicate key,
+// or whose expiry is missing, invalid or already past.
+func (cfg *Config) SanitizeShareLinks() {
+	if cfg == nil || len(cfg.ShareLinks) == 0 {
+		return
+	}
+	now := time.Now()
+	seen := make(map[string]struct{}, len(cfg.ShareLinks))
+	out := make([]ShareLink, 0, len(cfg.ShareLinks))
+	for _, link := range cfg.ShareLinks {
+		link.Key = strings.TrimSpace(link.Key)
+		link.Name = strings.TrimSpace(link.Name)
+		link.Model = strings.TrimSpace(link.Model)
+		link.Auth = strings.TrimSpace(link.Auth)
+		if link.Key == "" {
+			continue
+		}
+		if _, exists := seen[link.Key]; exists {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, strings.TrimSpace(link.ExpiresAt))
+		if err != nil || !expiresAt.After(now) {
+			continue
+		}
+		link.ExpiresAt = expiresAt.Format(time.RFC3339)
+		if link.MaxRequests < 0 {
+			link.MaxRequests = 0
+		}
+		seen[link.Key] = struct{}{}
+		out = append(out, link)
+	}
+	if len(out) == 0 {
+		out = nil
+	}
+	cfg.ShareLinks = out
 }
 
 // SanitizeAPIKeyExpiry normalizes per-client API key expiry timestamps.
@@ -735,6 +2416,131 @@ func (cfg *Config) SanitizeAPIKeyExpiry() {
 	cfg.APIKeyExpiry = NormalizeAPIKeyExpiry(cfg.APIKeyExpiry)
 }
 
+// SanitizeAPIKeyMetadata trims metadata fields and drops entries for keys not in api-keys.
+func (cfg *Config) SanitizeAPIKeyMetadata() {
+	if cfg == nil || len(cfg.APIKeyMetadata) == 0 {
+		return
+	}
+	known := make(map[string]struct{}, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		known[strings.TrimSpace(key)] = struct{}{}
+	}
+	out := make(map[string]APIKeyMetadata, len(cfg.APIKeyMetadata))
+	for rawKey, meta := range cfg.APIKeyMetadata {
+		key := strings.TrimSpace(rawKey)
+		if _, ok := known[key]; !ok || key == "" {
+			continue
+		}
+		meta.Owner = strings.TrimSpace(meta.Owner)
+		meta.Description = strings.TrimSpace(meta.Description)
+		meta.CreatedBy = strings.TrimSpace(meta.CreatedBy)
+		meta.CreatedAt = strings.TrimSpace(meta.CreatedAt)
+		if meta == (APIKeyMetadata{}) {
+			continue
+		}
+		out[key] = meta
+	}
+	if len(out) == 0 {
+		out = nil
+	}
+	cfg.APIKeyMetadata = out
+}
+
+// EnabledAPIKeys returns the client API keys that are not disabled in api-key-metadata.
+func (cfg *Config) EnabledAPIKeys() []string {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.APIKeyMetadata) == 0 {
+		return cfg.APIKeys
+	}
+	keys := make([]string, 0, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		if cfg.APIKeyMetadata[key].Disabled {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func sanitizePayloadCompatRules(rules []PayloadCompatRule) []PayloadCompatRule {
+	if len(rules) == 0 {
+		return rules
+	}
+	out := make([]PayloadCompatRule, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		rule.ToolChoice = strings.ToLower(strings.TrimSpace(rule.ToolChoice))
+		switch rule.ToolChoice {
+		case "", PayloadCompatToolChoiceAuto, PayloadCompatToolChoiceStrip:
+		default:
+			log.WithFields(log.Fields{
+				"section":     "compat",
+				"rule_index":  i + 1,
+				"tool_choice": rule.ToolChoice,
+			}).Warn("payload compat rule: unsupported tool-choice mode ignored")
+			rule.ToolChoice = ""
+		}
+		if rule.MaxStopSequences < 0 {
+			rule.MaxStopSequences = 0
+		}
+		strip := make([]string, 0, len(rule.Strip))
+		for _, path := range rule.Strip {
+			if trimmed := strings.TrimSpace(path); trimmed != "" {
+				strip = append(strip, trimmed)
+			}
+		}
+		rule.Strip = strip
+		if rule.MaxStopSequences == 0 && !rule.StripParallelToolCalls && rule.ToolChoice == "" && len(rule.Strip) == 0 {
+			continue
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+// sanitizePayloadTransformRules drops transform rules without an action and rules whose
+// conditions have no path or an invalid regular expression.
+func sanitizePayloadTransformRules(rules []PayloadTransformRule) []PayloadTransformRule {
+	if len(rules) == 0 {
+		return rules
+	}
+	out := make([]PayloadTransformRule, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if len(rule.Rename) == 0 && len(rule.Set) == 0 && len(rule.Delete) == 0 {
+			continue
+		}
+		valid := true
+		for j := range rule.When {
+			cond := &rule.When[j]
+			cond.Path = strings.TrimSpace(cond.Path)
+			if cond.Path == "" {
+				valid = false
+				break
+			}
+			if cond.Matches == "" {
+				continue
+			}
+			if _, errCompile := regexp.Compile(cond.Matches); errCompile != nil {
+				log.WithFields(log.Fields{
+					"section":    "transform",
+					"rule_index": i + 1,
+					"path":       cond.Path,
+					"error":      errCompile,
+				}).Warn("payload transform rule dropped: invalid matches expression")
+				valid = false
+				break
+			}
+		}
+		if valid {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
 func sanitizePayloadRawRules(rules []PayloadRule, section string) []PayloadRule {
 	if len(rules) == 0 {
 		return rules
@@ -842,6 +2648,35 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 	cfg.OpenAICompatibility = out
 }
 
+// LogModules are the modules whose log level can be set on their own.
+var LogModules = []string{"executor", "translator", "access", "management"}
+
+// SanitizeLogLevels lowercases module names and levels and drops unknown modules and levels
+// logrus cannot parse.
+func (cfg *Config) SanitizeLogLevels() {
+	if cfg == nil || len(cfg.LogLevels) == 0 {
+		return
+	}
+	out := make(map[string]string, len(cfg.LogLevels))
+	for module, level := range cfg.LogLevels {
+		module = strings.ToLower(strings.TrimSpace(module))
+		level = strings.ToLower(strings.TrimSpace(level))
+		if !slices.Contains(LogModules, module) {
+			log.Warnf("log-levels: unknown module %q ignored", module)
+			continue
+		}
+		if _, err := log.ParseLevel(level); err != nil {
+			log.Warnf("log-levels: invalid level %q for %s ignored", level, module)
+			continue
+		}
+		out[module] = level
+	}
+	if len(out) == 0 {
+		out = nil
+	}
+	cfg.LogLevels = out
+}
+
 // SanitizeCodexKeys removes Codex API key entries missing a BaseURL.
 // It trims whitespace and preserves order for remaining entries.
 func (cfg *Config) SanitizeCodexKeys() {
@@ -887,7 +2722,8 @@ func (cfg *Config) SanitizeGeminiKeys() {
 	for i := range cfg.GeminiKey {
 		entry := cfg.GeminiKey[i]
 		entry.APIKey = strings.TrimSpace(entry.APIKey)
-		if entry.APIKey == "" {
+		entry.APIKeyFile = strings.TrimSpace(entry.APIKeyFile)
+		if entry.APIKey == "" && entry.APIKeyFile == "" {
 			continue
 		}
 		entry.Prefix = normalizeModelPrefix(entry.Prefix)
@@ -895,10 +2731,15 @@ func (cfg *Config) SanitizeGeminiKeys() {
 		entry.ProxyURL = strings.TrimSpace(entry.ProxyURL)
 		entry.Headers = NormalizeHeaders(entry.Headers)
 		entry.ExcludedModels = NormalizeExcludedModels(entry.ExcludedModels)
-		if _, exists := seen[entry.APIKey]; exists {
+		// Entries whose key file is not read yet are told apart by the file.
+		uniqueKey := entry.APIKey
+		if uniqueKey == "" {
+			uniqueKey = "file:" + entry.APIKeyFile
+		}
+		if _, exists := seen[uniqueKey]; exists {
 			continue
 		}
-		seen[entry.APIKey] = struct{}{}
+		seen[uniqueKey] = struct{}{}
 		out = append(out, entry)
 	}
 	cfg.GeminiKey = out
@@ -971,7 +2812,7 @@ func NormalizeAPIKeyAuthForKnownKeys(entries map[string][]string, knownAPIKeys [
 		clean := make([]string, 0, len(auths))
 		for _, raw := range auths {
 			ref := strings.TrimSpace(raw)
-			if ref == "" {
+			if ref == "" || ref == "!" {
 				continue
 			}
 			if _, exists := seen[ref]; exists {
@@ -1098,9 +2939,58 @@ func hashSecret(secret string) (string, error) {
 	return string(hashedBytes), nil
 }
 
+// ErrConfigReadOnly is returned when saving a config that is in read-only mode.
+var ErrConfigReadOnly = errors.New("config is read-only")
+
+// forcedReadOnly is set by ForceReadOnly.
+var forcedReadOnly atomic.Bool
+
+// ForceReadOnly loads every config in read-only mode from now on, whatever its read-only
+// setting and READ_ONLY say. Config sync uses it so the synced config is the only source of
+// changes.
+func ForceReadOnly() {
+	forcedReadOnly.Store(true)
+}
+
+// readOnlyFromEnv reads the READ_ONLY environment variable, unless ForceReadOnly was called.
+func readOnlyFromEnv() (readOnly, ok bool) {
+	if forcedReadOnly.Load() {
+		return true, true
+	}
+	value, set := os.LookupEnv("READ_ONLY")
+	if !set || strings.TrimSpace(value) == "" {
+		return false, false
+	}
+	readOnly, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, false
+	}
+	return readOnly, true
+}
+
+// configFileReadOnly reports whether configFile is loaded in read-only mode, before it is parsed
+// in full.
+func configFileReadOnly(configFile string) bool {
+	if readOnly, ok := readOnlyFromEnv(); ok {
+		return readOnly
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return false
+	}
+	var probe struct {
+		ReadOnly bool `yaml:"read-only"`
+	}
+	return yaml.Unmarshal(data, &probe) == nil && probe.ReadOnly
+}
+
 // SaveConfigPreserveComments writes the config back to YAML while preserving existing comments
 // and key ordering by loading the original file into a yaml.Node tree and updating values in-place.
+// It fails with ErrConfigReadOnly when cfg is in read-only mode.
 func SaveConfigPreserveComments(configFile string, cfg *Config) error {
+	if cfg != nil && cfg.ReadOnly {
+		return ErrConfigReadOnly
+	}
 	persistCfg := sanitizeConfigForPersist(cfg)
 	// Load original YAML as a node tree to preserve comments and ordering.
 	data, err := os.ReadFile(configFile)
@@ -1145,6 +3035,7 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "oauth-model-alias")
 	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "api-key-auth")
 	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "api-key-expiry")
+	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "api-key-metadata")
 	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "proxy-routing-auth")
 
 	// Merge generated into original in-place, preserving comments/order of existing nodes.
@@ -1179,6 +3070,7 @@ func sanitizeConfigForPersist(cfg *Config) *Config {
 	clone := *cfg
 	clone.SDKConfig = cfg.SDKConfig
 	clone.SDKConfig.Access = AccessConfig{}
+	clone.dropSecretFileValues()
 	return &clone
 }
 
@@ -1938,6 +3830,9 @@ type ReverseProxy struct {
 	// Headers are custom HTTP headers to include in requests to this proxy.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
 	// Timeout is the request timeout in seconds for this proxy.
 	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 
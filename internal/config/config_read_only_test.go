@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOnlyConfigIsNeverWritten(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	initial := "read-only: true\nremote-management:\n  secret-key: plain-secret\napi-keys:\n  - key-a\n"
+	if err := os.WriteFile(configFile, []byte(initial), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !cfg.ReadOnly {
+		t.Fatal("read-only not loaded")
+	}
+	cfg.APIKeys = append(cfg.APIKeys, "key-b")
+	if err = SaveConfigPreserveComments(configFile, cfg); !errors.Is(err, ErrConfigReadOnly) {
+		t.Fatalf("save error = %v, want ErrConfigReadOnly", err)
+	}
+	data, _ := os.ReadFile(configFile)
+	if string(data) != initial {
+		t.Fatalf("read-only config was rewritten:\n%s", data)
+	}
+
+	t.Setenv("READ_ONLY", "false")
+	if cfg, err = LoadConfig(configFile); err != nil || cfg.ReadOnly {
+		t.Fatalf("READ_ONLY=false: read-only = %v, err = %v", cfg != nil && cfg.ReadOnly, err)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import "strings"
+
+// MockProvider configures an in-process fake upstream served by the mock executor. Each entry
+// is registered like a real provider credential, so translators, routing and client SDKs can
+// be exercised end to end without real accounts.
+type MockProvider struct {
+	// Name identifies the mock provider; it is used as the credential label.
+	Name string `yaml:"name" json:"name"`
+
+	// Priority controls selection preference when multiple credentials match.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Prefix optionally namespaces the models of this provider (e.g., "mock/gpt-test").
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// Models lists the models served by this provider and their canned responses.
+	Models []MockModel `yaml:"models" json:"models"`
+}
+
+// MockModel describes the canned behaviour of one mock model.
+type MockModel struct {
+	// Name is the model ID clients request.
+	Name string `yaml:"name" json:"name"`
+
+	// Format is the upstream wire format of the fixtures: openai (default), claude, gemini
+	// or codex. Responses are translated from it to the client's format.
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Response is the non-streaming response body. ResponseFile reads it from disk instead.
+	// When both are empty a short reply is generated in Format.
+	Response     string `yaml:"response,omitempty" json:"response,omitempty"`
+	ResponseFile string `yaml:"response-file,omitempty" json:"response-file,omitempty"`
+
+	// Stream is the streaming response as SSE text, one event line per line. StreamFile reads
+	// it from disk instead. When both are empty a short reply is generated in Format.
+	Stream     string `yaml:"stream,omitempty" json:"stream,omitempty"`
+	StreamFile string `yaml:"stream-file,omitempty" json:"stream-file,omitempty"`
+
+	// LatencyMS delays the response, or the first stream chunk, by this many milliseconds.
+	LatencyMS int `yaml:"latency-ms,omitempty" json:"latency-ms,omitempty"`
+
+	// ChunkDelayMS is the delay between stream chunks in milliseconds.
+	ChunkDelayMS int `yaml:"chunk-delay-ms,omitempty" json:"chunk-delay-ms,omitempty"`
+
+	// ErrorRate is the share of requests (0-1) that fail with ErrorStatus and ErrorBody.
+	ErrorRate   float64 `yaml:"error-rate,omitempty" json:"error-rate,omitempty"`
+	ErrorStatus int     `yaml:"error-status,omitempty" json:"error-status,omitempty"`
+	ErrorBody   string  `yaml:"error-body,omitempty" json:"error-body,omitempty"`
+
+	// MalformedRate is the share of stream chunks (0-1) replaced with truncated JSON.
+	MalformedRate float64 `yaml:"malformed-rate,omitempty" json:"malformed-rate,omitempty"`
+}
+
+// SanitizeMockProviders drops mock providers without a name or models, normalizes model
+// formats and clamps rates and delays to valid ranges.
+func (cfg *Config) SanitizeMockProviders() {
+	if cfg == nil || len(cfg.MockProviders) == 0 {
+		return
+	}
+	out := make([]MockProvider, 0, len(cfg.MockProviders))
+	for _, provider := range cfg.MockProviders {
+		provider.Name = strings.TrimSpace(provider.Name)
+		provider.Prefix = normalizeModelPrefix(provider.Prefix)
+		models := make([]MockModel, 0, len(provider.Models))
+		for _, model := range provider.Models {
+			model.Name = strings.TrimSpace(model.Name)
+			if model.Name == "" {
+				continue
+			}
+			model.Format = strings.ToLower(strings.TrimSpace(model.Format))
+			switch model.Format {
+			case "", "openai", "claude", "gemini", "codex":
+			default:
+				continue
+			}
+			if model.Format == "" {
+				model.Format = "openai"
+			}
+			model.ResponseFile = strings.TrimSpace(model.ResponseFile)
+			model.StreamFile = strings.TrimSpace(model.StreamFile)
+			model.LatencyMS = max(model.LatencyMS, 0)
+			model.ChunkDelayMS = max(model.ChunkDelayMS, 0)
+			model.ErrorRate = min(max(model.ErrorRate, 0), 1)
+			model.MalformedRate = min(max(model.MalformedRate, 0), 1)
+			if model.ErrorStatus < 400 || model.ErrorStatus > 599 {
+				model.ErrorStatus = 500
+			}
+			models = append(models, model)
+		}
+		if provider.Name == "" || len(models) == 0 {
+			continue
+		}
+		provider.Models = models
+		out = append(out, provider)
+	}
+	cfg.MockProviders = out
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PayloadProfilesConfig defines named payload profiles and where they apply. For each request
+// the base profile, the provider's profile, the first matching model profile and the client
+// key's profile are layered in that order, later layers winning for the same parameter. Each
+// profile is itself layered on top of the profile it inherits from. Profiles apply before the
+// payload rules, so payload rules still win.
+type PayloadProfilesConfig struct {
+	// Profiles are the named profiles.
+	Profiles []PayloadProfile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+
+	// Base names the profile applied to every request.
+	Base string `yaml:"base,omitempty" json:"base,omitempty"`
+
+	// Providers maps provider keys (e.g. "codex") to profile names.
+	Providers map[string]string `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// Models binds model patterns to profile names; the first matching entry applies.
+	Models []PayloadProfileBinding `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// ClientKeys maps client API keys to profile names.
+	ClientKeys map[string]string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+}
+
+// PayloadProfile is a named set of payload parameters.
+type PayloadProfile struct {
+	// Name identifies the profile.
+	Name string `yaml:"name" json:"name"`
+
+	// Inherits names the profile this one extends.
+	Inherits string `yaml:"inherits,omitempty" json:"inherits,omitempty"`
+
+	// Default maps JSON paths to values set only when the client did not send them.
+	Default map[string]any `yaml:"default,omitempty" json:"default,omitempty"`
+
+	// Override maps JSON paths to values that always replace the client's.
+	Override map[string]any `yaml:"override,omitempty" json:"override,omitempty"`
+
+	// Filter lists JSON paths removed from the payload.
+	Filter []string `yaml:"filter,omitempty" json:"filter,omitempty"`
+}
+
+// PayloadProfileBinding applies a profile to models matching a pattern.
+type PayloadProfileBinding struct {
+	// Model is a model name or wildcard pattern (e.g. "gpt-5*").
+	Model string `yaml:"model" json:"model"`
+
+	// Profile is the name of the profile applied.
+	Profile string `yaml:"profile" json:"profile"`
+}
+
+// Profile returns the profile with the given name.
+func (p *PayloadProfilesConfig) Profile(name string) (PayloadProfile, bool) {
+	if p == nil {
+		return PayloadProfile{}, false
+	}
+	for _, profile := range p.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return PayloadProfile{}, false
+}
+
+// Chain returns the profile named name preceded by the profiles it inherits from, root first.
+// Unknown names and inheritance cycles end the chain.
+func (p *PayloadProfilesConfig) Chain(name string) []PayloadProfile {
+	var chain []PayloadProfile
+	seen := make(map[string]bool)
+	for name != "" && !seen[name] {
+		seen[name] = true
+		profile, ok := p.Profile(name)
+		if !ok {
+			break
+		}
+		chain = append([]PayloadProfile{profile}, chain...)
+		name = profile.Inherits
+	}
+	return chain
+}
+
+// Validate reports the first problem with the profiles: a missing or duplicate name, an
+// unknown parent, an inheritance cycle or a binding to an unknown profile.
+func (p *PayloadProfilesConfig) Validate() error {
+	if p == nil {
+		return nil
+	}
+	names := make(map[string]bool, len(p.Profiles))
+	for _, profile := range p.Profiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			return fmt.Errorf("profile without a name")
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("duplicate profile %q", profile.Name)
+		}
+		names[profile.Name] = true
+	}
+	for _, profile := range p.Profiles {
+		if profile.Inherits == "" {
+			continue
+		}
+		if !names[profile.Inherits] {
+			return fmt.Errorf("profile %q inherits unknown profile %q", profile.Name, profile.Inherits)
+		}
+		if chain := p.Chain(profile.Name); len(chain) == 0 || chain[0].Inherits != "" {
+			return fmt.Errorf("profile %q is part of an inheritance cycle", profile.Name)
+		}
+	}
+	check := func(where, name string) error {
+		if name != "" && !names[name] {
+			return fmt.Errorf("%s uses unknown profile %q", where, name)
+		}
+		return nil
+	}
+	if err := check("base", p.Base); err != nil {
+		return err
+	}
+	for provider, name := range p.Providers {
+		if err := check("provider "+provider, name); err != nil {
+			return err
+		}
+	}
+	for _, binding := range p.Models {
+		if binding.Model == "" {
+			return fmt.Errorf("model binding without a model")
+		}
+		if err := check("model "+binding.Model, binding.Profile); err != nil {
+			return err
+		}
+	}
+	for key, name := range p.ClientKeys {
+		if err := check("client key "+maskKey(key), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SanitizePayloadProfiles trims profile names, lower-cases provider keys and drops what
+// Validate would reject: unnamed and duplicate profiles, unknown parents, cycles and
+// bindings to unknown profiles.
+func (cfg *Config) SanitizePayloadProfiles() {
+	if cfg == nil {
+		return
+	}
+	p := &cfg.PayloadProfiles
+	profiles := make([]PayloadProfile, 0, len(p.Profiles))
+	names := make(map[string]bool, len(p.Profiles))
+	for _, profile := range p.Profiles {
+		profile.Name = strings.TrimSpace(profile.Name)
+		profile.Inherits = strings.TrimSpace(profile.Inherits)
+		profile.Filter = trimNonEmpty(profile.Filter)
+		if profile.Name == "" || names[profile.Name] {
+			log.Warnf("payload-profiles: dropping unnamed or duplicate profile %q", profile.Name)
+			continue
+		}
+		names[profile.Name] = true
+		profiles = append(profiles, profile)
+	}
+	p.Profiles = profiles
+	for i := range p.Profiles {
+		profile := &p.Profiles[i]
+		if profile.Inherits != "" && !names[profile.Inherits] {
+			log.Warnf("payload-profiles: profile %q inherits unknown profile %q, ignoring the parent", profile.Name, profile.Inherits)
+			profile.Inherits = ""
+		}
+	}
+	for i := range p.Profiles {
+		profile := &p.Profiles[i]
+		if chain := p.Chain(profile.Name); profile.Inherits != "" && (len(chain) == 0 || chain[0].Inherits != "") {
+			log.Warnf("payload-profiles: profile %q is part of an inheritance cycle, ignoring its parent", profile.Name)
+			profile.Inherits = ""
+		}
+	}
+
+	known := func(where, name string) string {
+		name = strings.TrimSpace(name)
+		if name != "" && !names[name] {
+			log.Warnf("payload-profiles: %s uses unknown profile %q, ignoring it", where, name)
+			return ""
+		}
+		return name
+	}
+	p.Base = known("base", p.Base)
+	if len(p.Providers) > 0 {
+		providers := make(map[string]string, len(p.Providers))
+		for provider, name := range p.Providers {
+			provider = strings.ToLower(strings.TrimSpace(provider))
+			if name = known("provider "+provider, name); provider != "" && name != "" {
+				providers[provider] = name
+			}
+		}
+		p.Providers = providers
+	}
+	models := make([]PayloadProfileBinding, 0, len(p.Models))
+	for _, binding := range p.Models {
+		binding.Model = strings.TrimSpace(binding.Model)
+		if binding.Profile = known("model "+binding.Model, binding.Profile); binding.Model != "" && binding.Profile != "" {
+			models = append(models, binding)
+		}
+	}
+	p.Models = models
+	if len(p.ClientKeys) > 0 {
+		keys := make(map[string]string, len(p.ClientKeys))
+		for key, name := range p.ClientKeys {
+			key = strings.TrimSpace(key)
+			if name = known("client key "+maskKey(key), name); key != "" && name != "" {
+				keys[key] = name
+			}
+		}
+		p.ClientKeys = keys
+	}
+}
+
+// maskKey shortens a client key for log and error messages.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "***"
+	}
+	return key[:4] + "..." + key[len(key)-4:]
+}
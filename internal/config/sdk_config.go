@@ -9,6 +9,29 @@ type SDKConfig struct {
 	// ProxyURL is the URL of an optional proxy server to use for outbound requests.
 	ProxyURL string `yaml:"proxy-url" json:"proxy-url"`
 
+	// ProxyCredentialsFile names a file holding "username:password" for ProxyURL, replacing any
+	// credentials in the URL. The file is re-read when it changes, so credentials can be rotated
+	// without a restart.
+	ProxyCredentialsFile string `yaml:"proxy-credentials-file,omitempty" json:"proxy-credentials-file,omitempty"`
+
+	// ProxyCredentialsEnv names an environment variable holding "username:password" for ProxyURL.
+	// It is used when ProxyCredentialsFile is empty.
+	ProxyCredentialsEnv string `yaml:"proxy-credentials-env,omitempty" json:"proxy-credentials-env,omitempty"`
+
+	// ProxyBypass lists hosts that connect directly instead of through a proxy, in NO_PROXY
+	// style: "example.com" (the domain and its subdomains), ".example.com" or "*.example.com"
+	// (subdomains only), IP addresses, CIDR ranges, an optional ":port" suffix, or "*".
+	ProxyBypass []string `yaml:"proxy-bypass,omitempty" json:"proxy-bypass,omitempty"`
+
+	// ProxyHostOverrides routes matching hosts through a different proxy. The first matching
+	// entry wins and takes precedence over ProxyBypass.
+	ProxyHostOverrides []ProxyHostOverride `yaml:"proxy-host-overrides,omitempty" json:"proxy-host-overrides,omitempty"`
+
+	// TLSPins pin the certificates presented by upstream hosts and HTTPS proxies, so that a
+	// forward proxy intercepting TLS is detected. Connections to a pinned host fail when no
+	// certificate of the chain matches one of its pins.
+	TLSPins []TLSPin `yaml:"tls-pins,omitempty" json:"tls-pins,omitempty"`
+
 	// ForceModelPrefix requires explicit model prefixes (e.g., "teamA/gemini-3-pro-preview")
 	// to target prefixed credentials. When false, unprefixed model requests may use prefixed
 	// credentials as well.
@@ -23,14 +46,585 @@ type SDKConfig struct {
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
 
-	// Streaming configures server-side streaming behavior (keep-alives and safe bootstrap retries).
+	// Streaming configures server-side streaming behavior (keep-alives, safe bootstrap retries
+	// and concurrent stream limits).
 	Streaming StreamingConfig `yaml:"streaming" json:"streaming"`
 
+	// ErrorResponses controls how upstream errors are presented to clients.
+	ErrorResponses ErrorResponseConfig `yaml:"error-responses,omitempty" json:"error-responses,omitempty"`
+
+	// ResponseRules rewrite successful responses before they are returned to clients.
+	ResponseRules []ResponseRule `yaml:"response-rules,omitempty" json:"response-rules,omitempty"`
+
+	// RoutingRulesFile is a YAML file of declarative routing rules that send requests matching
+	// conditions on model, client key, prompt size and time of day to a provider, to auths
+	// carrying a tag or through a proxy. The file is reloaded when it changes.
+	RoutingRulesFile string `yaml:"routing-rules-file,omitempty" json:"routing-rules-file,omitempty"`
+
+	// RequestScripts run Starlark policy scripts on matching requests and responses.
+	RequestScripts []RequestScript `yaml:"request-scripts,omitempty" json:"request-scripts,omitempty"`
+
+	// EchoRequestedModel reports the model name the client requested in every response, instead
+	// of the upstream name an alias or route resolved it to.
+	EchoRequestedModel bool `yaml:"echo-requested-model,omitempty" json:"echo-requested-model,omitempty"`
+
+	// ReloginNotice tells clients once when accounts of the provider serving them need a new login.
+	ReloginNotice ReloginNoticeConfig `yaml:"relogin-notice,omitempty" json:"relogin-notice,omitempty"`
+
+	// CassetteRecordDir, when set, records every upstream response and the client output
+	// translated from it as a cassette file in this directory, for translator regression tests.
+	CassetteRecordDir string `yaml:"cassette-record-dir,omitempty" json:"cassette-record-dir,omitempty"`
+
+	// DebugTraceKeys lists the client API keys allowed to request a verbose trace of a request
+	// with the X-CLIProxy-Debug header; "*" allows every key. Traces are fetched through the
+	// management API by request ID. Empty ignores the header.
+	DebugTraceKeys []string `yaml:"debug-trace-keys,omitempty" json:"debug-trace-keys,omitempty"`
+
+	// RefusalFallback retries requests blocked by upstream content policies against a fallback model.
+	RefusalFallback RefusalFallbackConfig `yaml:"refusal-fallback,omitempty" json:"refusal-fallback,omitempty"`
+
+	// ContextOverflow controls what happens when a request exceeds the target model's context window.
+	ContextOverflow ContextOverflowConfig `yaml:"context-overflow,omitempty" json:"context-overflow,omitempty"`
+
+	// PromptCompression shrinks long conversation histories before they are sent upstream.
+	PromptCompression PromptCompressionConfig `yaml:"prompt-compression,omitempty" json:"prompt-compression,omitempty"`
+
+	// SystemPromptDedup replaces system prompts repeated within a session with a provider cache
+	// reference or a compacted summary.
+	SystemPromptDedup SystemPromptDedupConfig `yaml:"system-prompt-dedup,omitempty" json:"system-prompt-dedup,omitempty"`
+
+	// Moderation configures the /v1/moderations passthrough and the optional pre-flight prompt check.
+	Moderation ModerationConfig `yaml:"moderation,omitempty" json:"moderation,omitempty"`
+
+	// CostCeiling caps the estimated cost of a single request.
+	CostCeiling CostCeilingConfig `yaml:"cost-ceiling,omitempty" json:"cost-ceiling,omitempty"`
+
+	// TokenBudgets limits how many tokens each client API key may consume per window.
+	TokenBudgets TokenBudgetConfig `yaml:"token-budgets,omitempty" json:"token-budgets,omitempty"`
+
+	// UsageAnomaly flags client keys whose hourly token usage spikes above their trailing average.
+	UsageAnomaly UsageAnomalyConfig `yaml:"usage-anomaly,omitempty" json:"usage-anomaly,omitempty"`
+
+	// RepeatedPrompts slows down or blocks client keys sending the same prompt over and over.
+	RepeatedPrompts RepeatedPromptConfig `yaml:"repeated-prompts,omitempty" json:"repeated-prompts,omitempty"`
+
+	// MemoryGuard sheds new requests while the process is short of memory.
+	MemoryGuard MemoryGuardConfig `yaml:"memory-guard,omitempty" json:"memory-guard,omitempty"`
+
+	// ModelCatalogs defines named virtual model catalogs: subsets of the available models
+	// with optional client-visible renames.
+	ModelCatalogs []ModelCatalog `yaml:"model-catalogs,omitempty" json:"model-catalogs,omitempty"`
+
+	// APIKeyCatalogs assigns a model catalog to client API keys (key -> catalog name).
+	// Keys without an assignment see every available model.
+	APIKeyCatalogs map[string]string `yaml:"api-key-catalogs,omitempty" json:"api-key-catalogs,omitempty"`
+
+	// ClientKeyGroups name sets of client API keys. Per-key settings accept "group:<name>"
+	// entries that the group's keys inherit when they have no entry of their own.
+	ClientKeyGroups []ClientKeyGroup `yaml:"client-key-groups,omitempty" json:"client-key-groups,omitempty"`
+
+	// MCP exposes the model pool to Model Context Protocol clients at /mcp.
+	MCP MCPConfig `yaml:"mcp,omitempty" json:"mcp,omitempty"`
+
+	// MCPTools attaches external MCP servers whose tools are offered to the model on chat
+	// completions requests and executed by the proxy.
+	MCPTools MCPToolsConfig `yaml:"mcp-tools,omitempty" json:"mcp-tools,omitempty"`
+
+	// WebSearch offers a built-in web_search tool on chat completions requests and executes
+	// the model's searches against the configured search backend.
+	WebSearch WebSearchConfig `yaml:"web-search,omitempty" json:"web-search,omitempty"`
+
+	// CodeExecution offers a sandboxed code interpreter on chat completions requests that
+	// include a code_interpreter tool and executes the model's code in the sandbox.
+	CodeExecution CodeExecutionConfig `yaml:"code-execution,omitempty" json:"code-execution,omitempty"`
+
+	// JSONValidation checks non-streaming JSON-mode responses against the requested schema and
+	// can re-ask the model once when they do not match.
+	JSONValidation JSONValidationConfig `yaml:"json-validation,omitempty" json:"json-validation,omitempty"`
+
+	// ShareLinks are short-lived client keys limited to one model, auth and request count.
+	ShareLinks []ShareLink `yaml:"share-links,omitempty" json:"share-links,omitempty"`
+
 	// NonStreamKeepAliveInterval controls how often blank lines are emitted for non-streaming responses.
 	// <= 0 disables keep-alives. Value is in seconds.
 	NonStreamKeepAliveInterval int `yaml:"nonstream-keepalive-interval,omitempty" json:"nonstream-keepalive-interval,omitempty"`
 }
 
+// ProxyDirect is the ProxyHostOverride.ProxyURL value that connects without a proxy.
+const ProxyDirect = "direct"
+
+// ProxyHostOverride selects the proxy for hosts matching Host.
+type ProxyHostOverride struct {
+	// Host is a ProxyBypass-style host pattern.
+	Host string `yaml:"host" json:"host"`
+
+	// ProxyURL is the proxy for matching hosts, or "direct" to connect without one.
+	ProxyURL string `yaml:"proxy-url" json:"proxy-url"`
+}
+
+// TLSPin lists the accepted certificates of hosts matching Host.
+type TLSPin struct {
+	// Host is a ProxyBypass-style host pattern without a port. Hosts are matched by TLS server
+	// name, so IP addresses cannot be pinned.
+	Host string `yaml:"host" json:"host"`
+
+	// SPKISHA256 are base64 SHA-256 digests of a certificate's SubjectPublicKeyInfo, as
+	// printed by "openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst
+	// -sha256 -binary | base64". A "sha256/" prefix is accepted.
+	SPKISHA256 []string `yaml:"spki-sha256,omitempty" json:"spki-sha256,omitempty"`
+
+	// CertSHA256 are hex SHA-256 fingerprints of whole certificates; colons are accepted.
+	CertSHA256 []string `yaml:"cert-sha256,omitempty" json:"cert-sha256,omitempty"`
+}
+
+// Supported ModerationConfig action values.
+const (
+	// ModerationActionOff skips the pre-flight check.
+	ModerationActionOff = "off"
+	// ModerationActionFlag lets flagged prompts through and only records the verdict.
+	ModerationActionFlag = "flag"
+	// ModerationActionReject fails flagged prompts with a content_policy_violation error.
+	ModerationActionReject = "reject"
+)
+
+// ModerationConfig configures the OpenAI-compatible moderation backend.
+type ModerationConfig struct {
+	// BaseURL is the moderation backend base URL (e.g. "https://api.openai.com/v1").
+	// Empty disables both the passthrough endpoint and the pre-flight check.
+	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
+
+	// APIKey is sent to the moderation backend as a bearer token.
+	APIKey string `yaml:"api-key,omitempty" json:"api-key,omitempty"`
+
+	// Model is the moderation model used by the pre-flight check. Empty lets the backend choose.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+
+	// Action is the pre-flight policy for client keys without an entry in KeyActions:
+	// "off", "flag", or "reject". Empty means "off".
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+
+	// KeyActions overrides Action per client API key (key -> "off", "flag", or "reject").
+	KeyActions map[string]string `yaml:"key-actions,omitempty" json:"key-actions,omitempty"`
+}
+
+// MCPConfig configures the Model Context Protocol server. MCP clients connect with a client
+// API key over streamable HTTP (/mcp), SSE (/mcp/sse) or the "mcp stdio" subcommand.
+type MCPConfig struct {
+	// Enabled turns the /mcp endpoints on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// ManagementTools adds the list_auths and usage tools. They only report the auths the
+	// calling key may use.
+	ManagementTools bool `yaml:"management-tools,omitempty" json:"management-tools,omitempty"`
+
+	// DefaultModel serves sampling requests whose model hints match no available model.
+	DefaultModel string `yaml:"default-model,omitempty" json:"default-model,omitempty"`
+}
+
+// MCPToolsConfig configures server-side tool execution. When servers are configured, chat
+// completions requests from the selected client keys get the servers' tools appended; the
+// proxy runs the model's calls to them and sends the results back until the model answers
+// without calling one, so plain chat clients receive only the final answer.
+type MCPToolsConfig struct {
+	// Servers are the MCP servers to attach.
+	Servers []MCPServer `yaml:"servers,omitempty" json:"servers,omitempty"`
+
+	// ClientKeys limits server-side tools to these client API keys. Empty applies them to all keys.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// MaxIterations bounds the model calls made for one request. The last call forbids further
+	// tool use. Defaults to 8.
+	MaxIterations int `yaml:"max-iterations,omitempty" json:"max-iterations,omitempty"`
+
+	// StreamEvents sends streaming clients every step of the loop as it runs: each server-side
+	// tool call and its result as server_tool.call and server_tool.result events, followed by
+	// the final answer. Disabled, streaming clients receive only the final answer.
+	StreamEvents bool `yaml:"stream-events,omitempty" json:"stream-events,omitempty"`
+}
+
+// Supported WebSearchConfig provider values.
+const (
+	// WebSearchProviderSearxNG queries the JSON API of a SearxNG instance at base-url.
+	WebSearchProviderSearxNG = "searxng"
+	// WebSearchProviderBrave queries the Brave Search API.
+	WebSearchProviderBrave = "brave"
+	// WebSearchProviderBing queries the Bing Web Search API.
+	WebSearchProviderBing = "bing"
+)
+
+// WebSearchConfig configures the built-in web_search tool. Its calls run in the same
+// server-side tool loop as mcp-tools, bounded by mcp-tools.max-iterations. The tool is not
+// offered on requests that ask for the backend's native search via web_search_options.
+type WebSearchConfig struct {
+	// Provider selects the search backend: "searxng", "brave" or "bing". Empty disables the tool.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// BaseURL is the SearxNG instance URL. For brave and bing it overrides the API endpoint.
+	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
+
+	// APIKey authenticates with the Brave or Bing API.
+	APIKey string `yaml:"api-key,omitempty" json:"api-key,omitempty"`
+
+	// ClientKeys limits the tool to these client API keys. Empty offers it to all keys.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// MaxResults caps the results returned for one search. Defaults to 5.
+	MaxResults int `yaml:"max-results,omitempty" json:"max-results,omitempty"`
+
+	// MaxResultBytes truncates the formatted results handed to the model. Defaults to 8192.
+	MaxResultBytes int `yaml:"max-result-bytes,omitempty" json:"max-result-bytes,omitempty"`
+
+	// TimeoutSeconds bounds one search. Defaults to 15.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+}
+
+// JSONValidationConfig configures validation of JSON-mode output. Requests asking for JSON
+// (OpenAI response_format, Responses text.format or Gemini responseMimeType application/json)
+// have their final non-streaming output parsed and checked against the requested schema.
+// Streaming responses are not validated.
+type JSONValidationConfig struct {
+	// Enable turns on validation.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// ClientKeys limits validation to these client API keys. Empty applies it to all keys.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// Repair re-asks the model once, quoting the validation errors, when the output is invalid.
+	// The repaired answer is returned when it validates; otherwise the original one is.
+	Repair bool `yaml:"repair,omitempty" json:"repair,omitempty"`
+}
+
+// Supported CodeExecutionConfig sandbox values.
+const (
+	// CodeSandboxDocker runs code in a throwaway container without network access.
+	CodeSandboxDocker = "docker"
+	// CodeSandboxFirejail runs code under firejail with a private home and no network.
+	CodeSandboxFirejail = "firejail"
+)
+
+// CodeExecutionConfig configures the sandboxed code interpreter. Clients opt in per request
+// by sending a {"type":"code_interpreter"} tool, which the proxy replaces with its own
+// function tool; the calls run in the server-side tool loop. Every execution is audited.
+type CodeExecutionConfig struct {
+	// Sandbox selects the isolation: "docker" or "firejail". Empty disables code execution.
+	Sandbox string `yaml:"sandbox,omitempty" json:"sandbox,omitempty"`
+
+	// Binary overrides the path of the docker or firejail executable.
+	Binary string `yaml:"binary,omitempty" json:"binary,omitempty"`
+
+	// Image is the container image for the docker sandbox. Defaults to "python:3.12-alpine".
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+
+	// ClientKeys limits code execution to these client API keys. Empty allows all keys.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// TimeoutSeconds bounds one execution. Defaults to 10.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+
+	// MemoryMB caps the memory of one execution. Defaults to 256.
+	MemoryMB int `yaml:"memory-mb,omitempty" json:"memory-mb,omitempty"`
+
+	// MaxProcesses caps the processes of one execution. Defaults to 32.
+	MaxProcesses int `yaml:"max-processes,omitempty" json:"max-processes,omitempty"`
+
+	// MaxOutputBytes truncates the combined stdout and stderr returned to the model. Defaults to 16384.
+	MaxOutputBytes int `yaml:"max-output-bytes,omitempty" json:"max-output-bytes,omitempty"`
+
+	// AuditFile receives one JSON line per execution with the code and its outcome. Empty
+	// writes the audit entries to the main log.
+	AuditFile string `yaml:"audit-file,omitempty" json:"audit-file,omitempty"`
+}
+
+// MCPServer is an MCP server reached over streamable HTTP (URL) or started as a stdio
+// subprocess (Command).
+type MCPServer struct {
+	// Name identifies the server; its tools are offered as "mcp__<name>__<tool>".
+	Name string `yaml:"name" json:"name"`
+
+	// URL is the streamable HTTP endpoint of the server.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Headers are sent with every HTTP request, e.g. an Authorization header.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Command and Args start a stdio server when URL is empty.
+	Command string   `yaml:"command,omitempty" json:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty" json:"args,omitempty"`
+
+	// Env adds KEY=VALUE entries to the environment of a stdio server.
+	Env []string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Tools limits the tools offered from this server. Empty offers all of them.
+	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
+
+	// TimeoutSeconds bounds each call to the server. Defaults to 60.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+}
+
+// ResponseRule post-processes successful responses, the response-side counterpart of the
+// payload rules. Streaming responses are processed event by event.
+type ResponseRule struct {
+	// Models limits the rule to requested models matching these patterns; '*' matches any
+	// substring. Empty matches every model.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// ClientKeys limits the rule to these client API keys. Empty matches every key.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// Strip lists JSON paths removed from the response.
+	Strip []string `yaml:"strip,omitempty" json:"strip,omitempty"`
+
+	// RewriteModel reports the model name the client requested instead of the upstream one.
+	RewriteModel bool `yaml:"rewrite-model,omitempty" json:"rewrite-model,omitempty"`
+
+	// ServedByHeader names a response header set to the label of the auth that served the request.
+	ServedByHeader string `yaml:"served-by-header,omitempty" json:"served-by-header,omitempty"`
+
+	// ServedByField is a JSON path set to the label of the auth that served the request.
+	ServedByField string `yaml:"served-by-field,omitempty" json:"served-by-field,omitempty"`
+
+	// WatermarkField is a JSON path set to a watermark made of a hash of the client key and the
+	// request ID, so leaked output can be traced back to the key that produced it.
+	WatermarkField string `yaml:"watermark-field,omitempty" json:"watermark-field,omitempty"`
+
+	// WatermarkHeader names a response header set to the same watermark.
+	WatermarkHeader string `yaml:"watermark-header,omitempty" json:"watermark-header,omitempty"`
+}
+
+// RequestScript is a Starlark policy script evaluated for every matching request. Its
+// on_request function may change the payload, route the request to another model or reject
+// it; its on_response function may change non-streaming responses. Scripts run in order, each
+// seeing the changes of the previous ones.
+type RequestScript struct {
+	// Name identifies the script in logs. Defaults to Path.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Path is the script file, reloaded when it changes.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Source is an inline script, used when Path is empty.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+
+	// Models limits the script to requested models matching these patterns; '*' matches any
+	// substring. Empty matches every model.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// ClientKeys limits the script to these client API keys. Empty matches every key.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
+	// TimeoutMs bounds each call of the script. Defaults to 50.
+	TimeoutMs int `yaml:"timeout-ms,omitempty" json:"timeout-ms,omitempty"`
+
+	// MaxSteps bounds the Starlark execution steps of each call. Defaults to 1000000.
+	MaxSteps int64 `yaml:"max-steps,omitempty" json:"max-steps,omitempty"`
+
+	// MaxInputKB bounds the request or response body passed to each call; larger bodies fail
+	// the call. Defaults to 1024.
+	MaxInputKB int `yaml:"max-input-kb,omitempty" json:"max-input-kb,omitempty"`
+
+	// MaxAllocMB bounds the memory each call, and loading the script, may allocate. Scripts
+	// run in a worker process that is killed when the limit is passed. Defaults to 64.
+	MaxAllocMB int `yaml:"max-alloc-mb,omitempty" json:"max-alloc-mb,omitempty"`
+
+	// FailClosed rejects requests when the script cannot be loaded, fails or exceeds a limit.
+	// By default such requests continue as if the script had returned None.
+	FailClosed bool `yaml:"fail-closed,omitempty" json:"fail-closed,omitempty"`
+}
+
+// ReloginNoticeConfig configures the notice sent to clients served by a provider whose other
+// accounts need to log in again, so users learn capacity dropped. Each client API key receives
+// the notice once per account needing a login.
+type ReloginNoticeConfig struct {
+	// Enable turns on the notice.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// Message is the notice text; {provider} and {count} are replaced. Empty uses a default text.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+
+	// Header names the response header carrying the notice. Defaults to X-Proxy-Notice.
+	Header string `yaml:"header,omitempty" json:"header,omitempty"`
+
+	// Field is a JSON path set to the notice in the response, or in the first event of a stream.
+	// Empty sends the notice in the header only.
+	Field string `yaml:"field,omitempty" json:"field,omitempty"`
+}
+
+// Supported CostCeilingConfig.Action values.
+const (
+	// CostCeilingReject fails requests whose estimated cost exceeds the ceiling.
+	CostCeilingReject = "reject"
+	// CostCeilingClamp lowers the requested output tokens so the estimate fits the ceiling,
+	// rejecting only requests whose input alone exceeds it.
+	CostCeilingClamp = "clamp"
+)
+
+// CostCeilingConfig configures per-request cost ceilings. Costs are estimated from the
+// counted input tokens plus the requested maximum output tokens, using Pricing.
+// Clients may lower the ceiling for a request with the X-Max-Cost header.
+type CostCeilingConfig struct {
+	// Action is "reject" (default) or "clamp".
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+
+	// MaxCostPerRequest is the operator ceiling for client keys without a KeyLimits entry.
+	// <= 0 means no operator ceiling.
+	MaxCostPerRequest float64 `yaml:"max-cost-per-request,omitempty" json:"max-cost-per-request,omitempty"`
+
+	// KeyLimits overrides MaxCostPerRequest per client API key.
+	KeyLimits map[string]float64 `yaml:"key-limits,omitempty" json:"key-limits,omitempty"`
+
+	// Pricing lists token prices per model. Requests for models without pricing are not checked.
+	Pricing []ModelPricing `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+}
+
+// ModelPricing holds the token prices of matching models, per million tokens.
+type ModelPricing struct {
+	// Model matches the requested model name case-insensitively; '*' matches any substring.
+	Model string `yaml:"model" json:"model"`
+
+	// InputPerMillion is the price of one million input tokens.
+	InputPerMillion float64 `yaml:"input-per-million" json:"input-per-million"`
+
+	// OutputPerMillion is the price of one million output tokens.
+	OutputPerMillion float64 `yaml:"output-per-million" json:"output-per-million"`
+
+	// CachedInputPerMillion is the price of one million input tokens read from the prompt cache.
+	// Zero bills cached tokens at InputPerMillion.
+	CachedInputPerMillion float64 `yaml:"cached-input-per-million,omitempty" json:"cached-input-per-million,omitempty"`
+}
+
+// Cost estimates the price of a request. cachedTokens is the part of inputTokens read from the
+// prompt cache.
+func (p ModelPricing) Cost(inputTokens, cachedTokens, outputTokens int64) float64 {
+	cachedTokens = min(max(cachedTokens, 0), inputTokens)
+	cachedPrice := p.CachedInputPerMillion
+	if cachedPrice <= 0 {
+		cachedPrice = p.InputPerMillion
+	}
+	return (float64(inputTokens-cachedTokens)*p.InputPerMillion + float64(cachedTokens)*cachedPrice + float64(outputTokens)*p.OutputPerMillion) / 1e6
+}
+
+// TokenBudgetConfig configures per-client-key token budgets. Each request reserves its
+// estimated tokens when it starts; the reservation is released when the request finishes and
+// the actual usage is counted instead.
+type TokenBudgetConfig struct {
+	// WindowSeconds is the length of the budget window. Windows are aligned to the Unix epoch.
+	// <= 0 uses one day.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// ReserveOutputTokens is reserved for output when a request sets no output limit.
+	// <= 0 uses 4096.
+	ReserveOutputTokens int `yaml:"reserve-output-tokens,omitempty" json:"reserve-output-tokens,omitempty"`
+
+	// Keys maps client API keys to their token budget per window. Unlisted keys are unlimited.
+	Keys map[string]int64 `yaml:"keys,omitempty" json:"keys,omitempty"`
+
+	// AlertPercent is the share of a budget, in percent, whose use in a window fires a
+	// budget.threshold webhook. <= 0 uses 80. Exhausting a budget always fires one.
+	AlertPercent int `yaml:"alert-percent,omitempty" json:"alert-percent,omitempty"`
+}
+
+// UsageAnomalyConfig configures detection of token usage spikes per client API key. A key is
+// flagged when its tokens in the current hour exceed Multiplier times its average hourly tokens
+// over the trailing hours, firing a usage.anomaly webhook once per hour.
+type UsageAnomalyConfig struct {
+	// Enabled turns detection on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Multiplier is how many times the trailing average an hour must exceed. <= 0 uses 5.
+	Multiplier float64 `yaml:"multiplier,omitempty" json:"multiplier,omitempty"`
+
+	// TrailingHours is the number of previous hours averaged. <= 0 uses 24; at most 168.
+	TrailingHours int `yaml:"trailing-hours,omitempty" json:"trailing-hours,omitempty"`
+
+	// MinTokens is the hourly usage below which a key is never flagged. <= 0 uses 10000.
+	MinTokens int64 `yaml:"min-tokens,omitempty" json:"min-tokens,omitempty"`
+
+	// ThrottleMinutes applies a stricter rate limit to a flagged key for this long.
+	// <= 0 only alerts.
+	ThrottleMinutes int `yaml:"throttle-minutes,omitempty" json:"throttle-minutes,omitempty"`
+
+	// ThrottleRequestsPerMinute is the rate limit of a throttled key. <= 0 uses 5.
+	ThrottleRequestsPerMinute int `yaml:"throttle-requests-per-minute,omitempty" json:"throttle-requests-per-minute,omitempty"`
+}
+
+// RepeatedPromptConfig configures detection of client keys sending identical requests over and
+// over, typically runaway scripts. Once a key sends the same request more than Threshold times
+// within the window, each further repeat is delayed exponentially or blocked.
+type RepeatedPromptConfig struct {
+	// Enabled turns detection on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Threshold is the number of identical requests allowed per window. <= 0 uses 5.
+	Threshold int `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+
+	// WindowSeconds is how long identical requests are counted. <= 0 uses 60.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// Action is "delay" (default) or "block".
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+
+	// BaseDelayMs is the delay of the first repeat over the threshold, doubling with every
+	// further repeat. <= 0 uses 500.
+	BaseDelayMs int `yaml:"base-delay-ms,omitempty" json:"base-delay-ms,omitempty"`
+
+	// MaxDelayMs caps the delay. <= 0 uses 30000.
+	MaxDelayMs int `yaml:"max-delay-ms,omitempty" json:"max-delay-ms,omitempty"`
+
+	// BlockSeconds is how long the repeated request is rejected with the block action.
+	// <= 0 uses 300.
+	BlockSeconds int `yaml:"block-seconds,omitempty" json:"block-seconds,omitempty"`
+
+	// Keys overrides the threshold and action per client API key.
+	Keys map[string]RepeatedPromptKeyConfig `yaml:"keys,omitempty" json:"keys,omitempty"`
+
+	// AuditFile receives one JSON line per delayed or blocked request. Empty writes the audit
+	// entries to the main log.
+	AuditFile string `yaml:"audit-file,omitempty" json:"audit-file,omitempty"`
+}
+
+// Supported RepeatedPromptConfig actions.
+const (
+	// RepeatedPromptDelay delays each repeat over the threshold, doubling the delay every time.
+	RepeatedPromptDelay = "delay"
+	// RepeatedPromptBlock rejects repeats over the threshold for BlockSeconds.
+	RepeatedPromptBlock = "block"
+)
+
+// RepeatedPromptKeyConfig overrides repeated prompt detection for one client API key.
+type RepeatedPromptKeyConfig struct {
+	// Exempt disables detection for the key.
+	Exempt bool `yaml:"exempt,omitempty" json:"exempt,omitempty"`
+
+	// Threshold replaces the global threshold when > 0.
+	Threshold int `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+
+	// Action replaces the global action when set.
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+}
+
+// MemoryGuardConfig configures load shedding under memory pressure. Memory use is the resident
+// set size of the process (or the memory held by the Go runtime where it cannot be read).
+// Shed requests fail with 503 and a Retry-After header.
+type MemoryGuardConfig struct {
+	// Enabled turns the guard on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// SoftLimitMB is the memory use above which large requests are shed. <= 0 disables it.
+	SoftLimitMB int `yaml:"soft-limit-mb,omitempty" json:"soft-limit-mb,omitempty"`
+
+	// HardLimitMB is the memory use above which every request is shed. <= 0 disables it.
+	HardLimitMB int `yaml:"hard-limit-mb,omitempty" json:"hard-limit-mb,omitempty"`
+
+	// LargeRequestKB is the request body size shed above the soft limit. <= 0 uses 256.
+	LargeRequestKB int `yaml:"large-request-kb,omitempty" json:"large-request-kb,omitempty"`
+
+	// PriorityKeys are client API keys whose requests are never shed.
+	PriorityKeys []string `yaml:"priority-keys,omitempty" json:"priority-keys,omitempty"`
+
+	// RetryAfterSeconds is sent in the Retry-After header. <= 0 uses 10.
+	RetryAfterSeconds int `yaml:"retry-after-seconds,omitempty" json:"retry-after-seconds,omitempty"`
+}
+
 // StreamingConfig holds server streaming behavior configuration.
 type StreamingConfig struct {
 	// KeepAliveSeconds controls how often the server emits SSE heartbeats (": keep-alive\n\n").
@@ -41,6 +635,198 @@ type StreamingConfig struct {
 	// to allow auth rotation / transient recovery.
 	// <= 0 disables bootstrap retries. Default is 0.
 	BootstrapRetries int `yaml:"bootstrap-retries,omitempty" json:"bootstrap-retries,omitempty"`
+
+	// MaxStreams caps the streams open at once across all clients. <= 0 is unlimited.
+	MaxStreams int `yaml:"max-streams,omitempty" json:"max-streams,omitempty"`
+
+	// MaxStreamsPerKey caps the streams each client API key may have open at once.
+	// <= 0 is unlimited.
+	MaxStreamsPerKey int `yaml:"max-streams-per-key,omitempty" json:"max-streams-per-key,omitempty"`
+
+	// KeyMaxStreams overrides MaxStreamsPerKey for individual client API keys.
+	KeyMaxStreams map[string]int `yaml:"key-max-streams,omitempty" json:"key-max-streams,omitempty"`
+
+	// SalvageMinBytes ends a stream that fails after at least this many bytes of output were
+	// sent with a final chunk marking the response as truncated (finish reason "length" or
+	// its equivalent in the client's format) instead of an error event. <= 0 disables it.
+	SalvageMinBytes int `yaml:"salvage-min-bytes,omitempty" json:"salvage-min-bytes,omitempty"`
+}
+
+// ErrorResponseConfig controls normalization of upstream error bodies. Error bodies generated
+// by the proxy itself are never normalized.
+type ErrorResponseConfig struct {
+	// Normalize rewrites upstream errors into the error schema of the client's API format
+	// (OpenAI error object, Anthropic error type, Gemini status) instead of passing them through raw.
+	Normalize bool `yaml:"normalize,omitempty" json:"normalize,omitempty"`
+
+	// HideUpstreamDetail replaces upstream error messages with generic text when Normalize is enabled.
+	HideUpstreamDetail bool `yaml:"hide-upstream-detail,omitempty" json:"hide-upstream-detail,omitempty"`
+}
+
+// RefusalFallbackConfig configures rerouting of content-policy refusals.
+type RefusalFallbackConfig struct {
+	// Enable turns on refusal detection and fallback routing.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// Rules maps requested models to the model that should answer when the original is refused.
+	// Rules are evaluated in order; the first matching rule wins.
+	Rules []RefusalFallbackRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// RefusalFallbackRule routes refusals for matching models to a fallback model.
+type RefusalFallbackRule struct {
+	// Model matches the requested model name case-insensitively; '*' matches any substring.
+	Model string `yaml:"model" json:"model"`
+
+	// Fallback is the model used to retry the refused request (e.g. a local OpenAI-compatible model).
+	Fallback string `yaml:"fallback" json:"fallback"`
+}
+
+// Supported ContextOverflowConfig.Strategy values.
+const (
+	// ContextOverflowReject fails oversized requests with a context_length_exceeded error.
+	ContextOverflowReject = "reject"
+	// ContextOverflowTruncate drops the oldest conversation turns until the request fits.
+	ContextOverflowTruncate = "truncate"
+	// ContextOverflowRoute sends oversized requests to a larger-context sibling model.
+	ContextOverflowRoute = "route"
+)
+
+// ContextOverflowConfig configures context-window overflow handling.
+// Context sizes come from the model registry; models without a known size are never checked.
+type ContextOverflowConfig struct {
+	// Strategy is one of "reject", "truncate", or "route". Empty disables overflow handling.
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// Siblings lists larger-context models used by the "route" strategy.
+	// When no sibling fits, the request is rejected.
+	Siblings []ContextOverflowSibling `yaml:"siblings,omitempty" json:"siblings,omitempty"`
+}
+
+// ContextOverflowSibling maps a model to a larger-context alternative.
+type ContextOverflowSibling struct {
+	// Model matches the requested model name case-insensitively; '*' matches any substring.
+	Model string `yaml:"model" json:"model"`
+
+	// Sibling is the model that receives requests too large for Model.
+	Sibling string `yaml:"sibling" json:"sibling"`
+}
+
+// Supported PromptCompressionConfig.Strategy values.
+const (
+	// PromptCompressionDropMiddle replaces the middle of the conversation with a short omission note.
+	PromptCompressionDropMiddle = "drop-middle"
+	// PromptCompressionSummarize replaces the middle of the conversation with a summary written by SummaryModel.
+	PromptCompressionSummarize = "summarize"
+)
+
+// PromptCompressionConfig configures compression of long conversation histories.
+// System turns, the first user turn, and the most recent turns are always kept verbatim.
+type PromptCompressionConfig struct {
+	// Strategy is "drop-middle" or "summarize". Empty disables compression.
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// ThresholdTokens triggers compression when the estimated input exceeds this many tokens.
+	ThresholdTokens int `yaml:"threshold-tokens,omitempty" json:"threshold-tokens,omitempty"`
+
+	// KeepRecent is the number of most recent turns kept verbatim. Defaults to 6.
+	KeepRecent int `yaml:"keep-recent,omitempty" json:"keep-recent,omitempty"`
+
+	// SummaryModel is the model used by the "summarize" strategy, typically a cheap model served by this proxy.
+	// When the summary fails, the middle turns are dropped instead.
+	SummaryModel string `yaml:"summary-model,omitempty" json:"summary-model,omitempty"`
+
+	// Models restricts compression to matching requested models ('*' matches any substring).
+	// Empty applies it to every model.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// Supported SystemPromptDedupConfig.Strategy values.
+const (
+	// SystemPromptDedupCacheKey marks repeated system prompts for provider prompt caching
+	// (a prompt_cache_key for OpenAI and Codex, a cache_control breakpoint for Claude).
+	SystemPromptDedupCacheKey = "cache-key"
+	// SystemPromptDedupSummarize replaces repeated system prompts with a summary written by SummaryModel.
+	SystemPromptDedupSummarize = "summarize"
+)
+
+// SystemPromptDedupConfig configures detection of system prompts resent unchanged within a session.
+// The first request of a session always carries the system prompt verbatim.
+type SystemPromptDedupConfig struct {
+	// Strategy is "cache-key" or "summarize". Empty disables deduplication.
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// MinTokens skips system prompts estimated below this many tokens. Defaults to 1024.
+	MinTokens int `yaml:"min-tokens,omitempty" json:"min-tokens,omitempty"`
+
+	// TTLSeconds is how long a system prompt is remembered for a session. Defaults to 3600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// SummaryModel is the model used by the "summarize" strategy. Summaries are cached per prompt;
+	// when one fails, the request falls back to "cache-key".
+	SummaryModel string `yaml:"summary-model,omitempty" json:"summary-model,omitempty"`
+
+	// Models restricts deduplication to matching requested models ('*' matches any substring).
+	// Empty applies it to every model.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// ModelCatalog is a named subset of the available models exposed to selected client API keys.
+type ModelCatalog struct {
+	// Name identifies the catalog in api-key-catalogs.
+	Name string `yaml:"name" json:"name"`
+
+	// Models lists the models in the catalog. Models outside the catalog are neither
+	// listed nor accepted for keys assigned to it.
+	Models []ModelCatalogEntry `yaml:"models" json:"models"`
+}
+
+// ShareLink is a temporary client key with a narrow scope, minted through the management API.
+type ShareLink struct {
+	// Key is the client key presented by the link holder.
+	Key string `yaml:"key" json:"key"`
+
+	// Name is an optional label such as the person the link was shared with.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Model restricts the link to matching models; '*' matches any substring. Empty allows all models.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+
+	// Auth restricts the link to one auth account (auth ID, auth index, or auth file name).
+	Auth string `yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// MaxRequests caps the number of model requests. 0 means unlimited.
+	// Counters are saved next to the config file and survive restarts.
+	MaxRequests int `yaml:"max-requests,omitempty" json:"max-requests,omitempty"`
+
+	// ExpiresAt is the RFC3339 time after which the link is rejected.
+	ExpiresAt string `yaml:"expires-at" json:"expires-at"`
+
+	// CreatedAt is the RFC3339 creation time.
+	CreatedAt string `yaml:"created-at,omitempty" json:"created-at,omitempty"`
+}
+
+// ShareLink returns the share link with the given key.
+func (c *SDKConfig) ShareLink(key string) (ShareLink, bool) {
+	if c == nil || key == "" {
+		return ShareLink{}, false
+	}
+	for _, link := range c.ShareLinks {
+		if link.Key == key {
+			return link, true
+		}
+	}
+	return ShareLink{}, false
+}
+
+// ModelCatalogEntry selects models for a catalog and optionally renames them.
+type ModelCatalogEntry struct {
+	// Name matches available model IDs case-insensitively; '*' matches any substring.
+	Name string `yaml:"name" json:"name"`
+
+	// Alias is the client-visible name of the model. It only applies when Name has no wildcard;
+	// the original name is then hidden from keys using the catalog.
+	Alias string `yaml:"alias,omitempty" json:"alias,omitempty"`
 }
 
 // AccessConfig groups request authentication providers.
@@ -73,6 +859,9 @@ const (
 
 	// DefaultAccessProviderName is applied when no provider name is supplied.
 	DefaultAccessProviderName = "config-inline"
+
+	// AccessProviderTypeShareLink is the built-in provider validating share links.
+	AccessProviderTypeShareLink = "share-link"
 )
 
 // ConfigAPIKeyProvider returns the first inline API key provider if present.
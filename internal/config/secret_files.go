@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// ResolveSecretFiles replaces credentials with the contents of the files their *-file settings
+// reference (api-key-file, header-files and remote-management.secret-key-file), so secrets can
+// be mounted into containers instead of written into config.yaml. Relative paths resolve
+// against the working directory.
+func (cfg *Config) ResolveSecretFiles() error {
+	if cfg == nil {
+		return nil
+	}
+	var err error
+	resolve := func(value *string, path, setting string) {
+		if err != nil || strings.TrimSpace(path) == "" {
+			return
+		}
+		secret, errRead := readSecretFile(path)
+		if errRead != nil {
+			err = fmt.Errorf("%s: %w", setting, errRead)
+			return
+		}
+		*value = secret
+	}
+	resolveHeaders := func(headers *map[string]string, files map[string]string, setting string) {
+		if len(files) == 0 {
+			return
+		}
+		resolved := make(map[string]string, len(*headers)+len(files))
+		maps.Copy(resolved, *headers)
+		for name, path := range files {
+			value := ""
+			resolve(&value, path, setting+"."+name)
+			resolved[name] = value
+		}
+		*headers = resolved
+	}
+
+	resolve(&cfg.RemoteManagement.SecretKey, cfg.RemoteManagement.SecretKeyFile, "remote-management.secret-key-file")
+	for i := range cfg.GeminiKey {
+		key := &cfg.GeminiKey[i]
+		resolve(&key.APIKey, key.APIKeyFile, fmt.Sprintf("gemini-api-key[%d].api-key-file", i))
+		resolveHeaders(&key.Headers, key.HeaderFiles, fmt.Sprintf("gemini-api-key[%d].header-files", i))
+	}
+	for i := range cfg.ClaudeKey {
+		key := &cfg.ClaudeKey[i]
+		resolve(&key.APIKey, key.APIKeyFile, fmt.Sprintf("claude-api-key[%d].api-key-file", i))
+		resolveHeaders(&key.Headers, key.HeaderFiles, fmt.Sprintf("claude-api-key[%d].header-files", i))
+	}
+	for i := range cfg.CodexKey {
+		key := &cfg.CodexKey[i]
+		resolve(&key.APIKey, key.APIKeyFile, fmt.Sprintf("codex-api-key[%d].api-key-file", i))
+		resolveHeaders(&key.Headers, key.HeaderFiles, fmt.Sprintf("codex-api-key[%d].header-files", i))
+	}
+	for i := range cfg.VertexCompatAPIKey {
+		key := &cfg.VertexCompatAPIKey[i]
+		resolve(&key.APIKey, key.APIKeyFile, fmt.Sprintf("vertex-api-key[%d].api-key-file", i))
+		resolveHeaders(&key.Headers, key.HeaderFiles, fmt.Sprintf("vertex-api-key[%d].header-files", i))
+	}
+	for i := range cfg.OpenAICompatibility {
+		compat := &cfg.OpenAICompatibility[i]
+		resolveHeaders(&compat.Headers, compat.HeaderFiles, fmt.Sprintf("openai-compatibility[%d].header-files", i))
+		for j := range compat.APIKeyEntries {
+			entry := &compat.APIKeyEntries[j]
+			resolve(&entry.APIKey, entry.APIKeyFile, fmt.Sprintf("openai-compatibility[%d].api-key-entries[%d].api-key-file", i, j))
+		}
+	}
+	for i := range cfg.ReverseProxies {
+		proxy := &cfg.ReverseProxies[i]
+		resolveHeaders(&proxy.Headers, proxy.HeaderFiles, fmt.Sprintf("reverse-proxies[%d].header-files", i))
+	}
+	return err
+}
+
+// SecretFiles returns the files referenced by *-file settings, in config order.
+func (cfg *Config) SecretFiles() []string {
+	if cfg == nil {
+		return nil
+	}
+	var files []string
+	seen := make(map[string]bool)
+	add := func(paths ...string) {
+		for _, path := range paths {
+			if path = strings.TrimSpace(path); path != "" && !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+	addHeaders := func(headerFiles map[string]string) {
+		for _, path := range headerFiles {
+			add(path)
+		}
+	}
+
+	add(cfg.RemoteManagement.SecretKeyFile)
+	for _, key := range cfg.GeminiKey {
+		add(key.APIKeyFile)
+		addHeaders(key.HeaderFiles)
+	}
+	for _, key := range cfg.ClaudeKey {
+		add(key.APIKeyFile)
+		addHeaders(key.HeaderFiles)
+	}
+	for _, key := range cfg.CodexKey {
+		add(key.APIKeyFile)
+		addHeaders(key.HeaderFiles)
+	}
+	for _, key := range cfg.VertexCompatAPIKey {
+		add(key.APIKeyFile)
+		addHeaders(key.HeaderFiles)
+	}
+	for _, compat := range cfg.OpenAICompatibility {
+		addHeaders(compat.HeaderFiles)
+		for _, entry := range compat.APIKeyEntries {
+			add(entry.APIKeyFile)
+		}
+	}
+	for _, proxy := range cfg.ReverseProxies {
+		addHeaders(proxy.HeaderFiles)
+	}
+	return files
+}
+
+// readSecretFile returns the trimmed contents of a secret file, which must not be empty.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
+// dropSecretFileValues clears the credentials read from secret files, so saving the config
+// never writes them into config.yaml. Slices and maps are copied before they are changed.
+func (cfg *Config) dropSecretFileValues() {
+	if cfg.RemoteManagement.SecretKeyFile != "" {
+		cfg.RemoteManagement.SecretKey = ""
+	}
+	dropHeaders := func(headers map[string]string, files map[string]string) map[string]string {
+		if len(files) == 0 || len(headers) == 0 {
+			return headers
+		}
+		kept := maps.Clone(headers)
+		for name := range files {
+			delete(kept, name)
+		}
+		return kept
+	}
+
+	cfg.GeminiKey = append([]GeminiKey(nil), cfg.GeminiKey...)
+	for i := range cfg.GeminiKey {
+		key := &cfg.GeminiKey[i]
+		if key.APIKeyFile != "" {
+			key.APIKey = ""
+		}
+		key.Headers = dropHeaders(key.Headers, key.HeaderFiles)
+	}
+	cfg.ClaudeKey = append([]ClaudeKey(nil), cfg.ClaudeKey...)
+	for i := range cfg.ClaudeKey {
+		key := &cfg.ClaudeKey[i]
+		if key.APIKeyFile != "" {
+			key.APIKey = ""
+		}
+		key.Headers = dropHeaders(key.Headers, key.HeaderFiles)
+	}
+	cfg.CodexKey = append([]CodexKey(nil), cfg.CodexKey...)
+	for i := range cfg.CodexKey {
+		key := &cfg.CodexKey[i]
+		if key.APIKeyFile != "" {
+			key.APIKey = ""
+		}
+		key.Headers = dropHeaders(key.Headers, key.HeaderFiles)
+	}
+	cfg.VertexCompatAPIKey = append([]VertexCompatKey(nil), cfg.VertexCompatAPIKey...)
+	for i := range cfg.VertexCompatAPIKey {
+		key := &cfg.VertexCompatAPIKey[i]
+		if key.APIKeyFile != "" {
+			key.APIKey = ""
+		}
+		key.Headers = dropHeaders(key.Headers, key.HeaderFiles)
+	}
+	cfg.OpenAICompatibility = append([]OpenAICompatibility(nil), cfg.OpenAICompatibility...)
+	for i := range cfg.OpenAICompatibility {
+		compat := &cfg.OpenAICompatibility[i]
+		compat.Headers = dropHeaders(compat.Headers, compat.HeaderFiles)
+		compat.APIKeyEntries = append([]OpenAICompatibilityAPIKey(nil), compat.APIKeyEntries...)
+		for j := range compat.APIKeyEntries {
+			if compat.APIKeyEntries[j].APIKeyFile != "" {
+				compat.APIKeyEntries[j].APIKey = ""
+			}
+		}
+	}
+	cfg.ReverseProxies = append([]ReverseProxy(nil), cfg.ReverseProxies...)
+	for i := range cfg.ReverseProxies {
+		proxy := &cfg.ReverseProxies[i]
+		proxy.Headers = dropHeaders(proxy.Headers, proxy.HeaderFiles)
+	}
+}
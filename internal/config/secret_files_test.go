@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretFilesAreReadAndNeverPersisted(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, value string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(value), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+	keyFile := writeSecret("codex_key", "sk-from-file\n")
+	tokenFile := writeSecret("org_token", "org-token")
+	managementFile := writeSecret("management_key", "management-secret\n")
+
+	configFile := filepath.Join(dir, "config.yaml")
+	initial := "remote-management:\n  secret-key-file: " + managementFile + "\n" +
+		"gemini-api-key:\n  - api-key-file: " + keyFile + "\n" +
+		"codex-api-key:\n  - api-key-file: " + keyFile + "\n    base-url: https://example.com\n" +
+		"    headers:\n      X-Static: static\n    header-files:\n      X-Org-Token: " + tokenFile + "\n"
+	if err := os.WriteFile(configFile, []byte(initial), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.CodexKey) != 1 || cfg.CodexKey[0].APIKey != "sk-from-file" {
+		t.Fatalf("codex keys = %+v", cfg.CodexKey)
+	}
+	if got := cfg.CodexKey[0].Headers["X-Org-Token"]; got != "org-token" {
+		t.Fatalf("X-Org-Token = %q", got)
+	}
+	if len(cfg.GeminiKey) != 1 || cfg.GeminiKey[0].APIKey != "sk-from-file" {
+		t.Fatalf("gemini keys = %+v", cfg.GeminiKey)
+	}
+	if !looksLikeBcrypt(cfg.RemoteManagement.SecretKey) {
+		t.Fatal("management key from file was not hashed")
+	}
+	if files := cfg.SecretFiles(); len(files) != 3 {
+		t.Fatalf("secret files = %v", files)
+	}
+
+	if err = SaveConfigPreserveComments(configFile, cfg); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	data, _ := os.ReadFile(configFile)
+	for _, secret := range []string{"sk-from-file", "org-token", "$2"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("saved config contains %q:\n%s", secret, data)
+		}
+	}
+	if cfg.CodexKey[0].APIKey != "sk-from-file" {
+		t.Fatal("saving cleared the key in memory")
+	}
+	if cfg, err = LoadConfig(configFile); err != nil || len(cfg.CodexKey) != 1 || cfg.CodexKey[0].APIKey != "sk-from-file" {
+		t.Fatalf("reload after save: cfg = %+v, err = %v", cfg, err)
+	}
+
+	if err = os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadConfig(configFile); err == nil {
+		t.Fatal("missing secret file did not fail the load")
+	}
+}
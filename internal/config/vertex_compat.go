@@ -13,6 +13,9 @@ type VertexCompatKey struct {
 	// Maps to the x-goog-api-key header.
 	APIKey string `yaml:"api-key" json:"api-key"`
 
+	// APIKeyFile reads APIKey from a file such as a Docker secret, re-read when it changes.
+	APIKeyFile string `yaml:"api-key-file,omitempty" json:"api-key-file,omitempty"`
+
 	// Priority controls selection preference when multiple credentials match.
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
@@ -32,6 +35,9 @@ type VertexCompatKey struct {
 	// Commonly used for cookies, user-agent, and other authentication headers.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// HeaderFiles read header values from files, keyed by header name; they override Headers.
+	HeaderFiles map[string]string `yaml:"header-files,omitempty" json:"header-files,omitempty"`
+
 	// Models defines the model configurations including aliases for routing.
 	Models []VertexCompatModel `yaml:"models,omitempty" json:"models,omitempty"`
 }
@@ -63,7 +69,8 @@ func (cfg *Config) SanitizeVertexCompatKeys() {
 	for i := range cfg.VertexCompatAPIKey {
 		entry := cfg.VertexCompatAPIKey[i]
 		entry.APIKey = strings.TrimSpace(entry.APIKey)
-		if entry.APIKey == "" {
+		entry.APIKeyFile = strings.TrimSpace(entry.APIKeyFile)
+		if entry.APIKey == "" && entry.APIKeyFile == "" {
 			continue
 		}
 		entry.Prefix = normalizeModelPrefix(entry.Prefix)
@@ -88,6 +95,9 @@ func (cfg *Config) SanitizeVertexCompatKeys() {
 
 		// Use API key + base URL as uniqueness key
 		uniqueKey := entry.APIKey + "|" + entry.BaseURL
+		if entry.APIKey == "" {
+			uniqueKey = "file:" + entry.APIKeyFile + "|" + entry.BaseURL
+		}
 		if _, exists := seen[uniqueKey]; exists {
 			continue
 		}
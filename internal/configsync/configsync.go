@@ -0,0 +1,529 @@
+// Package configsync keeps the local config.yaml, and optionally the auth files, in sync with
+// a git repository or an HTTPS URL, so configuration changes flow through review instead of
+// live management API edits. The proxy runs in read-only mode while it is enabled.
+package configsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	nethttp "net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval   = time.Minute
+	defaultConfigPath = "config.yaml"
+	fetchTimeout      = time.Minute
+	maxConfigSize     = 8 << 20
+)
+
+// Options configure a Syncer. Exactly one of GitURL and URL is set.
+type Options struct {
+	// GitURL is the repository holding the config; Branch defaults to the remote HEAD.
+	GitURL string
+	Branch string
+	// URL serves the config file itself.
+	URL string
+	// Username and Token authenticate to the repository (HTTP basic auth) or the URL (bearer
+	// token when Username is empty).
+	Username string
+	Token    string
+	// ConfigPath is the path of the config file within the repository.
+	ConfigPath string
+	// AuthPath is a directory of the repository whose JSON files are mirrored into AuthDir.
+	AuthPath string
+	// LocalDir holds the repository clone.
+	LocalDir string
+	// Interval is the time between syncs.
+	Interval time.Duration
+
+	// TargetConfig is the config file the proxy runs from.
+	TargetConfig string
+	// AuthDir overrides the auth-dir of the synced config, for token stores that keep auth
+	// files in a directory of their own. Empty uses the auth-dir of each synced revision.
+	AuthDir string
+}
+
+// Status describes the last sync.
+type Status struct {
+	Source string `json:"source"`
+	// Revision is the commit of the repository, or the content digest of the URL, that
+	// the running config was synced from.
+	Revision  string    `json:"revision,omitempty"`
+	SyncedAt  time.Time `json:"synced-at,omitempty"`
+	CheckedAt time.Time `json:"checked-at,omitempty"`
+	// AuthFiles counts the auth files mirrored from the repository.
+	AuthFiles int `json:"auth-files,omitempty"`
+	// Error is the failure of the last check; the previous revision stays applied.
+	Error string `json:"error,omitempty"`
+}
+
+// Syncer pulls the config on an interval and applies changes that pass validation.
+type Syncer struct {
+	opts Options
+
+	mu       sync.Mutex
+	status   Status
+	etag     string
+	branch   string
+	manifest manifest
+}
+
+// manifest records the auth files a Syncer wrote. It is kept next to the target config so
+// files removed from the repository are deleted locally after a restart too.
+type manifest struct {
+	AuthDir string   `json:"auth-dir"`
+	Files   []string `json:"files"`
+}
+
+var (
+	activeMu sync.RWMutex
+	active   *Syncer
+)
+
+// New returns a Syncer and makes it the one reported by CurrentStatus.
+func New(opts Options) *Syncer {
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = defaultConfigPath
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	source := opts.URL
+	if opts.GitURL != "" {
+		source = opts.GitURL
+	}
+	s := &Syncer{opts: opts, status: Status{Source: source}, branch: opts.Branch}
+	if data, err := os.ReadFile(s.manifestPath()); err == nil {
+		if errUnmarshal := json.Unmarshal(data, &s.manifest); errUnmarshal != nil {
+			log.Warnf("config sync: ignoring unreadable manifest %s: %v", s.manifestPath(), errUnmarshal)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		log.Warnf("config sync: read manifest: %v", err)
+	}
+	activeMu.Lock()
+	active = s
+	activeMu.Unlock()
+	return s
+}
+
+// CurrentStatus returns the status of the running Syncer, if config sync is enabled.
+func CurrentStatus() (Status, bool) {
+	activeMu.RLock()
+	s := active
+	activeMu.RUnlock()
+	if s == nil {
+		return Status{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status, true
+}
+
+// Run syncs every interval until ctx is canceled.
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Sync(ctx); err != nil {
+			log.Errorf("config sync: %v", err)
+		}
+	}
+}
+
+// Sync fetches the config once and applies it when it changed and is valid. It reports
+// whether the config file or auth files were replaced.
+func (s *Syncer) Sync(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.CheckedAt = time.Now()
+	applied, err := s.syncLocked(ctx)
+	if err != nil {
+		s.status.Error = err.Error()
+		return false, err
+	}
+	s.status.Error = ""
+	return applied, nil
+}
+
+type snapshot struct {
+	revision string
+	config   []byte
+	// auths maps auth file names to their content; nil when no auth bundle is synced.
+	auths map[string][]byte
+}
+
+func (s *Syncer) syncLocked(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	var (
+		snap *snapshot
+		err  error
+	)
+	if s.opts.GitURL != "" {
+		snap, err = s.fetchGit(ctx)
+	} else {
+		snap, err = s.fetchURL(ctx)
+	}
+	if err != nil || snap == nil {
+		return false, err
+	}
+	if snap.revision == s.status.Revision {
+		return false, nil
+	}
+	cfg, err := s.validate(snap)
+	if err != nil {
+		return false, fmt.Errorf("revision %s rejected: %w", snap.revision, err)
+	}
+	authDir := s.opts.AuthDir
+	if authDir == "" && snap.auths != nil {
+		if authDir, err = util.ResolveAuthDir(cfg.AuthDir); err != nil {
+			return false, fmt.Errorf("revision %s rejected: %w", snap.revision, err)
+		}
+		if authDir == "" {
+			return false, fmt.Errorf("revision %s rejected: auth-dir is not set", snap.revision)
+		}
+	}
+
+	applied, err := s.apply(snap, authDir)
+	if err != nil {
+		return false, err
+	}
+	if snap.auths != nil {
+		s.status.AuthFiles = len(snap.auths)
+	}
+	s.status.Revision = snap.revision
+	s.status.SyncedAt = time.Now()
+	log.Infof("config sync: applied revision %s", snap.revision)
+	return applied, nil
+}
+
+// validate loads the config from a temporary file next to the target and checks that every
+// auth file is JSON.
+func (s *Syncer) validate(snap *snapshot) (*config.Config, error) {
+	dir := filepath.Dir(s.opts.TargetConfig)
+	tmp, err := os.CreateTemp(dir, "config-sync-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	_, err = tmp.Write(snap.config)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadConfigOptional(tmpPath, false)
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range snap.auths {
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("auth file %s is not valid JSON", name)
+		}
+	}
+	return cfg, nil
+}
+
+// stagedFile is new content written next to its target, waiting to replace it.
+type stagedFile struct {
+	tmp      string
+	path     string
+	previous []byte
+	existed  bool
+	perm     os.FileMode
+}
+
+// apply replaces the config and the auth files of snap as one set: every changed file is
+// staged first, and the files already replaced are restored when a later one fails. Auth
+// files this Syncer wrote before that are no longer in the bundle, or that were written to
+// a previous auth-dir, are removed afterwards. Auth files created locally are left alone.
+func (s *Syncer) apply(snap *snapshot, authDir string) (applied bool, err error) {
+	var staged []stagedFile
+	defer func() {
+		if err != nil {
+			for _, file := range staged {
+				_ = os.Remove(file.tmp)
+			}
+		}
+	}()
+	stage := func(path string, data []byte, perm os.FileMode) error {
+		current, errRead := os.ReadFile(path)
+		if errRead == nil && bytes.Equal(current, data) {
+			return nil
+		}
+		tmp, errStage := stageFile(path, data, perm)
+		if errStage != nil {
+			return errStage
+		}
+		staged = append(staged, stagedFile{tmp: tmp, path: path, previous: current, existed: errRead == nil, perm: perm})
+		return nil
+	}
+
+	next := s.manifest
+	if snap.auths != nil {
+		if err = os.MkdirAll(authDir, 0o700); err != nil {
+			return false, fmt.Errorf("write auth files: %w", err)
+		}
+		next = manifest{AuthDir: authDir, Files: make([]string, 0, len(snap.auths))}
+		for name, data := range snap.auths {
+			if err = stage(filepath.Join(authDir, name), data, 0o600); err != nil {
+				return false, fmt.Errorf("write auth files: %w", err)
+			}
+			next.Files = append(next.Files, name)
+		}
+		sort.Strings(next.Files)
+	}
+	// The config holds API keys: it keeps the mode it has, and a new one is private. It is
+	// replaced last, after the auth files it may point at.
+	perm := os.FileMode(0o600)
+	if info, errStat := os.Stat(s.opts.TargetConfig); errStat == nil {
+		perm = info.Mode().Perm()
+	}
+	if err = stage(s.opts.TargetConfig, snap.config, perm); err != nil {
+		return false, fmt.Errorf("write config: %w", err)
+	}
+
+	for i, file := range staged {
+		if err = os.Rename(file.tmp, file.path); err != nil {
+			for _, done := range staged[:i] {
+				restoreFile(done)
+			}
+			return false, fmt.Errorf("replace %s: %w", file.path, err)
+		}
+	}
+	applied = len(staged) > 0
+
+	if snap.auths != nil {
+		keep := make(map[string]bool, len(next.Files))
+		for _, name := range next.Files {
+			keep[filepath.Join(next.AuthDir, name)] = true
+		}
+		for _, name := range s.manifest.Files {
+			path := filepath.Join(s.manifest.AuthDir, name)
+			if keep[path] {
+				continue
+			}
+			if errRemove := os.Remove(path); errRemove != nil && !errors.Is(errRemove, fs.ErrNotExist) {
+				log.Warnf("config sync: remove auth file %s: %v", path, errRemove)
+				continue
+			}
+			applied = true
+		}
+		if errManifest := s.saveManifest(next); errManifest != nil {
+			log.Errorf("config sync: write manifest: %v", errManifest)
+		}
+	}
+	return applied, nil
+}
+
+// restoreFile puts back the content a staged file replaced.
+func restoreFile(file stagedFile) {
+	var err error
+	if file.existed {
+		err = writeFileAtomic(file.path, file.previous, file.perm)
+	} else {
+		err = os.Remove(file.path)
+	}
+	if err != nil {
+		log.Errorf("config sync: restore %s: %v", file.path, err)
+	}
+}
+
+func (s *Syncer) manifestPath() string {
+	return filepath.Join(filepath.Dir(s.opts.TargetConfig), ".config-sync-manifest.json")
+}
+
+func (s *Syncer) saveManifest(next manifest) error {
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(s.manifestPath(), data, 0o600); err != nil {
+		return err
+	}
+	s.manifest = next
+	return nil
+}
+
+func (s *Syncer) fetchURL(ctx context.Context) (*snapshot, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, s.opts.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case s.opts.Username != "":
+		req.SetBasicAuth(s.opts.Username, s.opts.Token)
+	case s.opts.Token != "":
+		req.Header.Set("Authorization", "Bearer "+s.opts.Token)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("config sync: close response body error: %v", errClose)
+		}
+	}()
+	if resp.StatusCode == nethttp.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", s.opts.URL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxConfigSize {
+		return nil, fmt.Errorf("fetch %s: config larger than %d bytes", s.opts.URL, maxConfigSize)
+	}
+	s.etag = resp.Header.Get("ETag")
+	sum := sha256.Sum256(data)
+	return &snapshot{revision: "sha256:" + hex.EncodeToString(sum[:6]), config: data}, nil
+}
+
+func (s *Syncer) fetchGit(ctx context.Context) (*snapshot, error) {
+	var auth transport.AuthMethod
+	if s.opts.Username != "" || s.opts.Token != "" {
+		user := s.opts.Username
+		if user == "" {
+			user = "git"
+		}
+		auth = &http.BasicAuth{Username: user, Password: s.opts.Token}
+	}
+
+	repo, err := git.PlainOpen(s.opts.LocalDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		options := &git.CloneOptions{URL: s.opts.GitURL, Auth: auth, SingleBranch: true, Depth: 1}
+		if s.branch != "" {
+			options.ReferenceName = plumbing.NewBranchReferenceName(s.branch)
+		}
+		if repo, err = git.PlainCloneContext(ctx, s.opts.LocalDir, options); err != nil {
+			_ = os.RemoveAll(s.opts.LocalDir)
+			return nil, fmt.Errorf("clone %s: %w", s.opts.GitURL, err)
+		}
+		if head, errHead := repo.Head(); errHead == nil && s.branch == "" {
+			s.branch = head.Name().Short()
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("open %s: %w", s.opts.LocalDir, err)
+	} else {
+		if s.branch == "" {
+			head, errHead := repo.Head()
+			if errHead != nil {
+				return nil, fmt.Errorf("read local branch: %w", errHead)
+			}
+			s.branch = head.Name().Short()
+		}
+		refSpec := gitconfig.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", s.branch, s.branch))
+		errFetch := repo.FetchContext(ctx, &git.FetchOptions{RemoteName: "origin", RefSpecs: []gitconfig.RefSpec{refSpec}, Auth: auth, Depth: 1, Force: true})
+		if errFetch != nil && !errors.Is(errFetch, git.NoErrAlreadyUpToDate) {
+			return nil, fmt.Errorf("fetch %s: %w", s.opts.GitURL, errFetch)
+		}
+		ref, errRef := repo.Reference(plumbing.NewRemoteReferenceName("origin", s.branch), true)
+		if errRef != nil {
+			return nil, fmt.Errorf("resolve origin/%s: %w", s.branch, errRef)
+		}
+		worktree, errWorktree := repo.Worktree()
+		if errWorktree != nil {
+			return nil, errWorktree
+		}
+		// The repository is the source of truth: local edits to the clone are discarded.
+		if errReset := worktree.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset}); errReset != nil {
+			return nil, fmt.Errorf("check out %s: %w", ref.Hash(), errReset)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{revision: head.Hash().String()}
+	if snap.config, err = os.ReadFile(filepath.Join(s.opts.LocalDir, filepath.FromSlash(s.opts.ConfigPath))); err != nil {
+		return nil, fmt.Errorf("read %s: %w", s.opts.ConfigPath, err)
+	}
+	if s.opts.AuthPath != "" {
+		entries, errDir := os.ReadDir(filepath.Join(s.opts.LocalDir, filepath.FromSlash(s.opts.AuthPath)))
+		if errDir != nil {
+			return nil, fmt.Errorf("read %s: %w", s.opts.AuthPath, errDir)
+		}
+		snap.auths = make(map[string][]byte)
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+				continue
+			}
+			data, errRead := os.ReadFile(filepath.Join(s.opts.LocalDir, filepath.FromSlash(s.opts.AuthPath), entry.Name()))
+			if errRead != nil {
+				return nil, errRead
+			}
+			snap.auths[entry.Name()] = data
+		}
+	}
+	return snap, nil
+}
+
+// writeFileAtomic replaces path with data through a rename, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := stageFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// stageFile writes data to a temporary file next to path and returns its name.
+func stageFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
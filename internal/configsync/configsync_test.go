@@ -0,0 +1,237 @@
+package configsync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func TestSyncURL(t *testing.T) {
+	body := "port: 8317\n"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(target, []byte("port: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{URL: server.URL, Token: "token", TargetConfig: target})
+
+	applied, err := s.Sync(context.Background())
+	if err != nil || !applied {
+		t.Fatalf("first sync: applied=%v err=%v", applied, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != body {
+		t.Fatalf("config = %q, want %q", data, body)
+	}
+	if info, errStat := os.Stat(target); errStat != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("config mode = %v, want the existing 0644 kept", info.Mode().Perm())
+	}
+	status, ok := CurrentStatus()
+	if !ok || status.Revision == "" || status.SyncedAt.IsZero() {
+		t.Fatalf("status = %+v", status)
+	}
+
+	if applied, err = s.Sync(context.Background()); err != nil || applied {
+		t.Fatalf("unchanged sync: applied=%v err=%v", applied, err)
+	}
+
+	body = "port: [\n"
+	if applied, err = s.Sync(context.Background()); err == nil || applied {
+		t.Fatalf("invalid config: applied=%v err=%v", applied, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "port: 8317\n" {
+		t.Fatalf("invalid config replaced the running one: %q", data)
+	}
+	if status, _ = CurrentStatus(); status.Error == "" {
+		t.Fatal("status error is empty after a rejected revision")
+	}
+	if requests != 3 {
+		t.Fatalf("requests = %d, want 3", requests)
+	}
+}
+
+// newTestRemote creates a repository and returns a function committing files to it.
+func newTestRemote(t *testing.T) (*git.Repository, string, func(files map[string]string, remove ...string)) {
+	t.Helper()
+	remote := t.TempDir()
+	repo, err := git.PlainInit(remote, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(files map[string]string, remove ...string) {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(remote, name)
+			if errMkdir := os.MkdirAll(filepath.Dir(path), 0o755); errMkdir != nil {
+				t.Fatal(errMkdir)
+			}
+			if errWrite := os.WriteFile(path, []byte(content), 0o644); errWrite != nil {
+				t.Fatal(errWrite)
+			}
+			if _, errAdd := worktree.Add(name); errAdd != nil {
+				t.Fatal(errAdd)
+			}
+		}
+		for _, name := range remove {
+			if _, errRemove := worktree.Remove(name); errRemove != nil {
+				t.Fatal(errRemove)
+			}
+		}
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		if _, errCommit := worktree.Commit("update", &git.CommitOptions{Author: signature}); errCommit != nil {
+			t.Fatal(errCommit)
+		}
+	}
+	return repo, remote, commit
+}
+
+func TestSyncGit(t *testing.T) {
+	repo, remote, commit := newTestRemote(t)
+	var err error
+	commit(map[string]string{
+		"deploy/config.yaml": "port: 8317\n",
+		"auths/a.json":       `{"type":"codex"}`,
+		"auths/b.json":       `{"type":"claude"}`,
+	})
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "config.yaml")
+	authDir := filepath.Join(dir, "auths")
+	if err = os.MkdirAll(authDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(authDir, "local.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{
+		GitURL:       remote,
+		ConfigPath:   "deploy/config.yaml",
+		AuthPath:     "auths",
+		LocalDir:     filepath.Join(dir, "clone"),
+		TargetConfig: target,
+		AuthDir:      authDir,
+	})
+	if applied, errSync := s.Sync(context.Background()); errSync != nil || !applied {
+		t.Fatalf("clone sync: applied=%v err=%v", applied, errSync)
+	}
+	if data, _ := os.ReadFile(filepath.Join(authDir, "b.json")); string(data) != `{"type":"claude"}` {
+		t.Fatalf("b.json = %q", data)
+	}
+	if info, errStat := os.Stat(target); errStat != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("new config mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	commit(map[string]string{"deploy/config.yaml": "port: 9000\n"}, "auths/b.json")
+	if applied, errSync := s.Sync(context.Background()); errSync != nil || !applied {
+		t.Fatalf("fetch sync: applied=%v err=%v", applied, errSync)
+	}
+	head, _ := repo.Head()
+	if status, _ := CurrentStatus(); status.Revision != head.Hash().String() || status.AuthFiles != 1 {
+		t.Fatalf("status = %+v, want revision %s with 1 auth file", status, head.Hash())
+	}
+	if data, _ := os.ReadFile(target); string(data) != "port: 9000\n" {
+		t.Fatalf("config = %q", data)
+	}
+	if _, errStat := os.Stat(filepath.Join(authDir, "b.json")); !os.IsNotExist(errStat) {
+		t.Fatalf("b.json was not removed: %v", errStat)
+	}
+	if _, errStat := os.Stat(filepath.Join(authDir, "local.json")); errStat != nil {
+		t.Fatalf("local auth file was removed: %v", errStat)
+	}
+
+	commit(map[string]string{"auths/c.json": "not json"})
+	if applied, errSync := s.Sync(context.Background()); errSync == nil || applied {
+		t.Fatalf("invalid auth file: applied=%v err=%v", applied, errSync)
+	}
+	if _, errStat := os.Stat(filepath.Join(authDir, "c.json")); !os.IsNotExist(errStat) {
+		t.Fatalf("c.json was written: %v", errStat)
+	}
+}
+
+func TestSyncGitRemovesAuthsAfterRestart(t *testing.T) {
+	_, remote, commit := newTestRemote(t)
+	dir := t.TempDir()
+	authDir := filepath.Join(dir, "synced-auths")
+	commit(map[string]string{
+		"config.yaml":  "auth-dir: " + authDir + "\n",
+		"auths/a.json": `{"type":"codex"}`,
+		"auths/b.json": `{"type":"claude"}`,
+	})
+	opts := Options{GitURL: remote, AuthPath: "auths", LocalDir: filepath.Join(dir, "clone"), TargetConfig: filepath.Join(dir, "config.yaml")}
+	if applied, err := New(opts).Sync(context.Background()); err != nil || !applied {
+		t.Fatalf("first sync: applied=%v err=%v", applied, err)
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "b.json")); err != nil {
+		t.Fatalf("auth file not written to the synced auth-dir: %v", err)
+	}
+
+	// A new Syncer stands in for a restarted proxy.
+	commit(nil, "auths/b.json")
+	if applied, err := New(opts).Sync(context.Background()); err != nil || !applied {
+		t.Fatalf("sync after restart: applied=%v err=%v", applied, err)
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "b.json")); !os.IsNotExist(err) {
+		t.Fatalf("b.json was not removed after a restart: %v", err)
+	}
+
+	movedDir := filepath.Join(dir, "moved-auths")
+	commit(map[string]string{"config.yaml": "auth-dir: " + movedDir + "\n"})
+	if applied, err := New(opts).Sync(context.Background()); err != nil || !applied {
+		t.Fatalf("auth-dir change: applied=%v err=%v", applied, err)
+	}
+	if _, err := os.Stat(filepath.Join(movedDir, "a.json")); err != nil {
+		t.Fatalf("a.json not written to the new auth-dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "a.json")); !os.IsNotExist(err) {
+		t.Fatalf("a.json left in the previous auth-dir: %v", err)
+	}
+}
+
+func TestSyncGitRestoresAuthsWhenConfigFails(t *testing.T) {
+	_, remote, commit := newTestRemote(t)
+	commit(map[string]string{"config.yaml": "port: 8317\n", "auths/a.json": `{"type":"codex"}`})
+	dir := t.TempDir()
+	authDir := filepath.Join(dir, "auths")
+	target := filepath.Join(dir, "config.yaml")
+	// A directory in place of the config makes replacing it fail after the auth files were staged.
+	if err := os.MkdirAll(filepath.Join(target, "blocker"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{GitURL: remote, AuthPath: "auths", LocalDir: filepath.Join(dir, "clone"), TargetConfig: target, AuthDir: authDir})
+	if applied, err := s.Sync(context.Background()); err == nil || applied {
+		t.Fatalf("sync: applied=%v err=%v, want a failure", applied, err)
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "a.json")); !os.IsNotExist(err) {
+		t.Fatalf("a.json kept although the config was not replaced: %v", err)
+	}
+	entries, _ := os.ReadDir(authDir)
+	if len(entries) != 0 {
+		t.Fatalf("staged files left behind: %v", entries)
+	}
+}
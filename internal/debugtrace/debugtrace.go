@@ -0,0 +1,206 @@
+// Package debugtrace collects verbose per-request traces: translation inputs and outputs,
+// the selected auth, proxy routing, retries and timing. Traces are only collected for
+// requests that opt in with the debug header and are kept in memory for a short while so
+// they can be fetched through the management API by request ID.
+package debugtrace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Header is the request header that asks for a trace of the request.
+const Header = "X-CLIProxy-Debug"
+
+const (
+	// maxTraces bounds how many finished and in-flight traces are kept.
+	maxTraces = 100
+	// traceTTL is how long a trace stays retrievable after it started.
+	traceTTL = 30 * time.Minute
+	// maxEvents bounds the events recorded for one request; later events are dropped.
+	maxEvents = 1000
+	// maxValueBytes bounds each recorded payload.
+	maxValueBytes = 64 << 10
+)
+
+// Event is one step of a traced request.
+type Event struct {
+	// ElapsedMs is the time since the trace started.
+	ElapsedMs float64 `json:"elapsed_ms"`
+	// Kind groups events: request, auth, attempt, retry, proxy, upstream_request,
+	// upstream_response, translate.
+	Kind    string         `json:"kind"`
+	Message string         `json:"message,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Trace is the collected trace of one request.
+type Trace struct {
+	RequestID     string    `json:"request_id"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	StartedAt     time.Time `json:"started_at"`
+	CompletedAt   time.Time `json:"completed_at,omitempty"`
+	DurationMs    int64     `json:"duration_ms"`
+	StatusCode    int       `json:"status_code,omitempty"`
+	Pending       bool      `json:"pending"`
+	DroppedEvents int       `json:"dropped_events,omitempty"`
+	Events        []Event   `json:"events"`
+
+	mu sync.Mutex
+}
+
+type traceKey struct{}
+
+type store struct {
+	mu     sync.Mutex
+	order  []string
+	traces map[string]*Trace
+}
+
+var defaultStore = &store{traces: make(map[string]*Trace)}
+
+// Start begins a trace for requestID, stores it for retrieval and returns a context that
+// carries it. An empty requestID leaves ctx untraced.
+func Start(ctx context.Context, requestID, method, path string) (context.Context, *Trace) {
+	if requestID == "" {
+		return ctx, nil
+	}
+	t := &Trace{RequestID: requestID, Method: method, Path: path, StartedAt: time.Now(), Pending: true}
+	defaultStore.put(t)
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// FromContext returns the trace carried by ctx, or nil.
+func FromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Enabled reports whether ctx carries a trace. Callers use it to skip building event data.
+func Enabled(ctx context.Context) bool {
+	return FromContext(ctx) != nil
+}
+
+// Record appends an event to the trace carried by ctx. It is a no-op for untraced requests.
+// String and []byte values in data are truncated to a bounded size.
+func Record(ctx context.Context, kind, message string, data map[string]any) {
+	if t := FromContext(ctx); t != nil {
+		t.Record(kind, message, data)
+	}
+}
+
+// Record appends an event to t. It is safe on a nil trace.
+func (t *Trace) Record(kind, message string, data map[string]any) {
+	if t == nil {
+		return
+	}
+	for key, value := range data {
+		switch v := value.(type) {
+		case []byte:
+			data[key] = truncate(string(v))
+		case string:
+			data[key] = truncate(v)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.Events) >= maxEvents {
+		t.DroppedEvents++
+		return
+	}
+	elapsed := time.Since(t.StartedAt)
+	t.Events = append(t.Events, Event{
+		ElapsedMs: float64(elapsed.Microseconds()) / 1000,
+		Kind:      kind,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// Finish marks t as completed with status. It is safe on a nil trace.
+func (t *Trace) Finish(status int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.Pending {
+		return
+	}
+	t.Pending = false
+	t.StatusCode = status
+	t.CompletedAt = time.Now()
+	t.DurationMs = t.CompletedAt.Sub(t.StartedAt).Milliseconds()
+}
+
+// snapshot returns a copy of t that is safe to serialize while the request is still running.
+func (t *Trace) snapshot() *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := &Trace{
+		RequestID:     t.RequestID,
+		Method:        t.Method,
+		Path:          t.Path,
+		StartedAt:     t.StartedAt,
+		CompletedAt:   t.CompletedAt,
+		DurationMs:    t.DurationMs,
+		StatusCode:    t.StatusCode,
+		Pending:       t.Pending,
+		DroppedEvents: t.DroppedEvents,
+		Events:        append([]Event(nil), t.Events...),
+	}
+	if out.Pending {
+		out.DurationMs = time.Since(t.StartedAt).Milliseconds()
+	}
+	return out
+}
+
+// Get returns a snapshot of the trace recorded for requestID.
+func Get(requestID string) (*Trace, bool) {
+	defaultStore.mu.Lock()
+	defaultStore.pruneLocked(time.Now())
+	t, ok := defaultStore.traces[requestID]
+	defaultStore.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return t.snapshot(), true
+}
+
+func (s *store) put(t *Trace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(t.StartedAt)
+	if _, exists := s.traces[t.RequestID]; !exists {
+		s.order = append(s.order, t.RequestID)
+	}
+	s.traces[t.RequestID] = t
+	for len(s.order) > maxTraces {
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// pruneLocked drops traces that started more than traceTTL before now.
+func (s *store) pruneLocked(now time.Time) {
+	for len(s.order) > 0 {
+		t := s.traces[s.order[0]]
+		if t != nil && now.Sub(t.StartedAt) < traceTTL {
+			return
+		}
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func truncate(value string) string {
+	if len(value) <= maxValueBytes {
+		return value
+	}
+	return value[:maxValueBytes] + "...(truncated)"
+}
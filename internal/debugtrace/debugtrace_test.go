@@ -0,0 +1,48 @@
+package debugtrace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecordIsNoopWithoutTrace(t *testing.T) {
+	ctx := context.Background()
+	if Enabled(ctx) {
+		t.Fatal("plain context reported as traced")
+	}
+	Record(ctx, "request", "ignored", nil)
+}
+
+func TestTraceRecordsAndTruncates(t *testing.T) {
+	ctx, trace := Start(context.Background(), "debugtrace-record", "POST", "/v1/messages")
+	Record(ctx, "upstream_request", "POST https://example.com", map[string]any{
+		"body": []byte(strings.Repeat("x", maxValueBytes+10)),
+	})
+	trace.Finish(200)
+
+	got, ok := Get("debugtrace-record")
+	if !ok {
+		t.Fatal("trace not stored")
+	}
+	if got.Pending || got.StatusCode != 200 || len(got.Events) != 1 {
+		t.Fatalf("unexpected trace: %+v", got)
+	}
+	body, _ := got.Events[0].Data["body"].(string)
+	if !strings.HasSuffix(body, "(truncated)") || len(body) > maxValueBytes+32 {
+		t.Fatalf("body not truncated: %d bytes", len(body))
+	}
+}
+
+func TestStoreEvictsOldestTraces(t *testing.T) {
+	for i := 0; i <= maxTraces; i++ {
+		Start(context.Background(), fmt.Sprintf("debugtrace-evict-%d", i), "GET", "/")
+	}
+	if _, ok := Get("debugtrace-evict-0"); ok {
+		t.Fatal("oldest trace not evicted")
+	}
+	if _, ok := Get(fmt.Sprintf("debugtrace-evict-%d", maxTraces)); !ok {
+		t.Fatal("newest trace missing")
+	}
+}
@@ -0,0 +1,278 @@
+// Package leader implements lease-based leader election so that background jobs
+// run exactly once across a fleet of proxy instances sharing the same auth store.
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultLease is used when no positive lease duration is supplied.
+const DefaultLease = 30 * time.Second
+
+const (
+	// guardSuffix names the file that serializes lease updates across instances.
+	guardSuffix = ".guard"
+	// guardAttempts and guardRetryDelay bound the wait for another instance's lease update.
+	guardAttempts   = 5
+	guardRetryDelay = 20 * time.Millisecond
+)
+
+// errGuardBusy reports that another instance kept the lease guard for the whole wait.
+var errGuardBusy = errors.New("lease is being updated by another instance")
+
+// lease is the on-disk representation of the current leadership claim.
+type lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileElector elects a leader by holding a time-bound lease file on a shared filesystem.
+// The holder renews the lease periodically; other instances take over once it expires.
+// Lease updates are serialized by a guard file created with O_EXCL, so reading the lease and
+// replacing it is a compare-and-swap on its holder and expiry.
+type FileElector struct {
+	path  string
+	id    string
+	lease time.Duration
+
+	leader atomic.Bool
+	// expiresAt is the expiry, in Unix nanoseconds, of the lease this instance last wrote.
+	expiresAt atomic.Int64
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewFileElector constructs an elector for the given lease file path.
+func NewFileElector(path string, leaseDuration time.Duration) *FileElector {
+	if leaseDuration <= 0 {
+		leaseDuration = DefaultLease
+	}
+	host, _ := os.Hostname()
+	return &FileElector{
+		path:  filepath.Clean(path),
+		id:    fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8]),
+		lease: leaseDuration,
+	}
+}
+
+// ID returns the identifier written into the lease file by this instance.
+func (e *FileElector) ID() string {
+	if e == nil {
+		return ""
+	}
+	return e.id
+}
+
+// Path returns the lease file path.
+func (e *FileElector) Path() string {
+	if e == nil {
+		return ""
+	}
+	return e.path
+}
+
+// IsLeader reports whether this instance currently holds the lease.
+func (e *FileElector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	return e.leader.Load()
+}
+
+// Start launches the acquire/renew loop. Calling Start twice restarts the loop.
+func (e *FileElector) Start(parent context.Context) {
+	if e == nil {
+		return
+	}
+	e.Stop()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	done := make(chan struct{})
+	e.mu.Lock()
+	e.cancel = cancel
+	e.done = done
+	e.mu.Unlock()
+
+	e.tick(time.Now())
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				e.tick(now)
+			}
+		}
+	}()
+}
+
+// Stop halts the loop and releases the lease if this instance holds it.
+func (e *FileElector) Stop() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel, e.done = nil, nil
+	e.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	if e.leader.Swap(false) {
+		if errRelease := e.release(); errRelease != nil {
+			log.Warnf("leader: failed to release lease %s: %v", e.path, errRelease)
+		}
+	}
+}
+
+func (e *FileElector) tick(now time.Time) {
+	acquired, errAcquire := e.tryAcquire(now)
+	if errAcquire != nil {
+		log.Warnf("leader: lease check failed for %s: %v", e.path, errAcquire)
+	}
+	if previous := e.leader.Swap(acquired); previous != acquired {
+		if acquired {
+			log.Infof("leader: this instance (%s) became leader", e.id)
+		} else {
+			log.Infof("leader: this instance (%s) is now a follower", e.id)
+		}
+	}
+}
+
+// tryAcquire claims or renews the lease when it is free, expired, or already ours. When
+// another instance holds the guard, leadership is kept only while the last renewal is valid.
+func (e *FileElector) tryAcquire(now time.Time) (bool, error) {
+	unlock, errLock := e.lockGuard()
+	if errLock != nil {
+		held := e.leader.Load() && now.Before(time.Unix(0, e.expiresAt.Load()))
+		if errors.Is(errLock, errGuardBusy) {
+			return held, nil
+		}
+		return held, errLock
+	}
+	defer unlock()
+
+	current, errRead := e.read()
+	if errRead != nil && !errors.Is(errRead, os.ErrNotExist) {
+		return false, errRead
+	}
+	if current != nil && current.Holder != e.id && now.Before(current.ExpiresAt) {
+		return false, nil
+	}
+	next := lease{Holder: e.id, ExpiresAt: now.Add(e.lease)}
+	if errWrite := e.write(next); errWrite != nil {
+		return false, errWrite
+	}
+	e.expiresAt.Store(next.ExpiresAt.UnixNano())
+	return true, nil
+}
+
+// lockGuard creates the guard file exclusively and returns the function removing it. A guard
+// older than the lease was left behind by a crashed instance and is removed.
+func (e *FileElector) lockGuard() (func(), error) {
+	guard := e.path + guardSuffix
+	if errMkdir := os.MkdirAll(filepath.Dir(guard), 0o700); errMkdir != nil {
+		return nil, errMkdir
+	}
+	for attempt := 1; ; attempt++ {
+		file, errOpen := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if errOpen == nil {
+			_, _ = file.WriteString(e.id)
+			_ = file.Close()
+			return func() { _ = os.Remove(guard) }, nil
+		}
+		if !errors.Is(errOpen, os.ErrExist) {
+			return nil, errOpen
+		}
+		if info, errStat := os.Stat(guard); errStat == nil && time.Since(info.ModTime()) > e.lease {
+			log.Warnf("leader: removing stale lease guard %s", guard)
+			_ = os.Remove(guard)
+		}
+		if attempt == guardAttempts {
+			return nil, errGuardBusy
+		}
+		time.Sleep(guardRetryDelay)
+	}
+}
+
+func (e *FileElector) read() (*lease, error) {
+	data, errRead := os.ReadFile(e.path)
+	if errRead != nil {
+		return nil, errRead
+	}
+	var current lease
+	if errUnmarshal := json.Unmarshal(data, &current); errUnmarshal != nil {
+		// Treat a corrupt lease file as free so the fleet can recover.
+		return &lease{}, nil
+	}
+	return &current, nil
+}
+
+func (e *FileElector) write(next lease) error {
+	data, errMarshal := json.Marshal(next)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	dir := filepath.Dir(e.path)
+	if errMkdir := os.MkdirAll(dir, 0o700); errMkdir != nil {
+		return errMkdir
+	}
+	tmp, errTemp := os.CreateTemp(dir, ".leader-*.tmp")
+	if errTemp != nil {
+		return errTemp
+	}
+	tmpName := tmp.Name()
+	if _, errWrite := tmp.Write(data); errWrite != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return errWrite
+	}
+	if errClose := tmp.Close(); errClose != nil {
+		_ = os.Remove(tmpName)
+		return errClose
+	}
+	if errRename := os.Rename(tmpName, e.path); errRename != nil {
+		_ = os.Remove(tmpName)
+		return errRename
+	}
+	return nil
+}
+
+func (e *FileElector) release() error {
+	unlock, errLock := e.lockGuard()
+	if errLock != nil {
+		return errLock
+	}
+	defer unlock()
+	current, errRead := e.read()
+	if errRead != nil {
+		if errors.Is(errRead, os.ErrNotExist) {
+			return nil
+		}
+		return errRead
+	}
+	if current.Holder != e.id {
+		return nil
+	}
+	return e.write(lease{Holder: "", ExpiresAt: time.Time{}})
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileElectorSingleLeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".leader.lock")
+	first := NewFileElector(path, time.Minute)
+	second := NewFileElector(path, time.Minute)
+	now := time.Now()
+
+	acquired, err := first.tryAcquire(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !acquired {
+		t.Fatal("expected first elector to acquire the lease")
+	}
+
+	acquired, err = second.tryAcquire(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if acquired {
+		t.Fatal("expected second elector to remain follower while lease is valid")
+	}
+
+	acquired, err = first.tryAcquire(now.Add(30 * time.Second))
+	if err != nil || !acquired {
+		t.Fatalf("expected first elector to renew, acquired=%t err=%v", acquired, err)
+	}
+}
+
+func TestFileElectorTakeoverAfterExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".leader.lock")
+	first := NewFileElector(path, time.Minute)
+	second := NewFileElector(path, time.Minute)
+	now := time.Now()
+
+	if acquired, _ := first.tryAcquire(now); !acquired {
+		t.Fatal("expected first elector to acquire the lease")
+	}
+	acquired, err := second.tryAcquire(now.Add(2 * time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !acquired {
+		t.Fatal("expected second elector to take over expired lease")
+	}
+}
+
+func TestFileElectorStopReleasesLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".leader.lock")
+	first := NewFileElector(path, time.Minute)
+	first.Start(nil)
+	if !first.IsLeader() {
+		t.Fatal("expected first elector to lead after start")
+	}
+	first.Stop()
+	if first.IsLeader() {
+		t.Fatal("expected elector to drop leadership after stop")
+	}
+
+	second := NewFileElector(path, time.Minute)
+	acquired, err := second.tryAcquire(time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !acquired {
+		t.Fatal("expected released lease to be immediately acquirable")
+	}
+}
+
+func TestFileElectorConcurrentAcquireElectsOne(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".leader.lock")
+	now := time.Now()
+	var leaders atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		elector := NewFileElector(path, time.Minute)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if acquired, _ := elector.tryAcquire(now); acquired {
+				leaders.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := leaders.Load(); got != 1 {
+		t.Fatalf("leaders = %d, want exactly one", got)
+	}
+}
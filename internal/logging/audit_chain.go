@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one line of the upstream request audit chain. Hash is the SHA-256 of Prev
+// followed by the JSON encoding of the record with an empty Hash, so every line commits to
+// all lines before it.
+type AuditRecord struct {
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	AuthID     string    `json:"auth_id,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	BodySHA256 string    `json:"body_sha256"`
+	Prev       string    `json:"prev"`
+	Hash       string    `json:"hash"`
+}
+
+// AuditChainHead identifies the last record of an audit chain.
+type AuditChainHead struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// AuditChainStatus is the result of verifying an audit chain file.
+type AuditChainStatus struct {
+	Records int64          `json:"records"`
+	Head    AuditChainHead `json:"head"`
+	Valid   bool           `json:"valid"`
+	// BrokenAt is the line number of the first record that does not follow its predecessor.
+	BrokenAt int64  `json:"broken-at,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// auditChain appends records to one chain file.
+type auditChain struct {
+	path string
+	head AuditChainHead
+}
+
+var (
+	auditChainMu sync.Mutex
+	auditChains  = make(map[string]*auditChain)
+)
+
+// AppendAuditRecord links record to the chain in path and appends it. Seq, Prev and Hash are
+// filled in; the chain head is read from the file the first time path is used.
+func AppendAuditRecord(path string, record AuditRecord) (AuditRecord, error) {
+	auditChainMu.Lock()
+	defer auditChainMu.Unlock()
+	chain, ok := auditChains[path]
+	if !ok {
+		status, err := VerifyAuditChain(path)
+		if err != nil && !os.IsNotExist(err) {
+			return record, err
+		}
+		if err == nil && !status.Valid {
+			return record, fmt.Errorf("audit chain %s is broken at line %d: %s", path, status.BrokenAt, status.Error)
+		}
+		chain = &auditChain{path: path, head: status.Head}
+		auditChains[path] = chain
+	}
+
+	record.Seq = chain.head.Seq + 1
+	record.Time = record.Time.UTC()
+	record.Prev = chain.head.Hash
+	record.Hash = ""
+	record.Hash = auditRecordHash(record)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return record, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return record, err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return record, err
+	}
+	chain.head = AuditChainHead{Seq: record.Seq, Hash: record.Hash}
+	return record, nil
+}
+
+// AuditChainHeadOf returns the head of the chain in path as last appended by this process,
+// or as read from the file when nothing was appended yet.
+func AuditChainHeadOf(path string) (AuditChainHead, error) {
+	auditChainMu.Lock()
+	chain, ok := auditChains[path]
+	var head AuditChainHead
+	if ok {
+		head = chain.head
+	}
+	auditChainMu.Unlock()
+	if ok {
+		return head, nil
+	}
+	status, err := VerifyAuditChain(path)
+	if err != nil {
+		return AuditChainHead{}, err
+	}
+	return status.Head, nil
+}
+
+// VerifyAuditChain reads the chain in path and checks that every record follows the previous
+// one. A broken chain is reported in the status, not as an error.
+func VerifyAuditChain(path string) (AuditChainStatus, error) {
+	var status AuditChainStatus
+	f, err := os.Open(path)
+	if err != nil {
+		return status, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var line int64
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if errUnmarshal := json.Unmarshal(raw, &record); errUnmarshal != nil {
+			status.BrokenAt, status.Error = line, "malformed record"
+			return status, nil
+		}
+		hash := record.Hash
+		record.Hash = ""
+		switch {
+		case record.Seq != status.Head.Seq+1:
+			status.BrokenAt, status.Error = line, fmt.Sprintf("sequence %d follows %d", record.Seq, status.Head.Seq)
+		case record.Prev != status.Head.Hash:
+			status.BrokenAt, status.Error = line, "previous hash does not match"
+		case auditRecordHash(record) != hash:
+			status.BrokenAt, status.Error = line, "record hash does not match its content"
+		}
+		if status.BrokenAt != 0 {
+			return status, nil
+		}
+		status.Records++
+		status.Head = AuditChainHead{Seq: record.Seq, Hash: hash}
+	}
+	if err = scanner.Err(); err != nil {
+		return status, err
+	}
+	status.Valid = true
+	return status, nil
+}
+
+// auditRecordHash hashes record, whose Hash must be empty, onto its predecessor.
+func auditRecordHash(record AuditRecord) string {
+	data, _ := json.Marshal(record)
+	sum := sha256.New()
+	sum.Write([]byte(record.Prev))
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil))
+}
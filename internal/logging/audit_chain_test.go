@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditChain_AppendResumeAndTamper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, url := range []string{"https://a.example/v1", "https://b.example/v1", "https://c.example/v1"} {
+		if _, err := AppendAuditRecord(path, AuditRecord{Time: time.Now(), Provider: "codex", URL: url}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	// A restarted process picks the chain up from the file.
+	auditChainMu.Lock()
+	delete(auditChains, path)
+	auditChainMu.Unlock()
+	record, err := AppendAuditRecord(path, AuditRecord{Time: time.Now(), URL: "https://d.example/v1"})
+	if err != nil {
+		t.Fatalf("append after restart: %v", err)
+	}
+	if record.Seq != 4 || record.Prev == "" {
+		t.Fatalf("resumed record = %+v, want seq 4 linked to the previous head", record)
+	}
+
+	status, err := VerifyAuditChain(path)
+	if err != nil || !status.Valid || status.Records != 4 || status.Head.Hash != record.Hash {
+		t.Fatalf("verify = %+v, %v", status, err)
+	}
+	if head, errHead := AuditChainHeadOf(path); errHead != nil || head != status.Head {
+		t.Fatalf("head = %+v, %v, want %+v", head, errHead, status.Head)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	tampered := append([]string{}, lines...)
+	tampered[1] = strings.Replace(tampered[1], "b.example", "x.example", 1)
+	dropped := append(append([]string{}, lines[:1]...), lines[2:]...)
+	for name, content := range map[string][]string{"edited": tampered, "dropped": dropped} {
+		if errWrite := os.WriteFile(path, []byte(strings.Join(content, "\n")+"\n"), 0o600); errWrite != nil {
+			t.Fatal(errWrite)
+		}
+		status, err = VerifyAuditChain(path)
+		if err != nil || status.Valid || status.BrokenAt != 2 {
+			t.Fatalf("%s: verify = %+v, %v, want broken at line 2", name, status, err)
+		}
+	}
+}
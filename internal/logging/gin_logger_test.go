@@ -58,3 +58,50 @@ func TestGinLogrusRecoveryHandlesRegularPanic(t *testing.T) {
 		t.Fatalf("expected 500, got %d", recorder.Code)
 	}
 }
+
+func TestGinLogrusLoggerEchoesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(GinLogrusLogger())
+	var seen string
+	engine.GET("/v1/models", func(c *gin.Context) {
+		seen = GetRequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set(RequestIDHeader, "client-trace.42")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	if got := recorder.Header().Get(RequestIDHeader); got != "client-trace.42" {
+		t.Fatalf("expected echoed request id, got %q", got)
+	}
+	if seen != "client-trace.42" {
+		t.Fatalf("expected request context id to match, got %q", seen)
+	}
+}
+
+func TestGinLogrusLoggerReplacesInvalidRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(GinLogrusLogger())
+	engine.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	got := recorder.Header().Get(RequestIDHeader)
+	if got == "" || got == "bad id\nwith newline" {
+		t.Fatalf("expected generated request id, got %q", got)
+	}
+	if len(got) != 8 {
+		t.Fatalf("expected 8-character generated id, got %q", got)
+	}
+}
@@ -32,8 +32,12 @@ type LogFormatter struct{}
 // logFieldOrder defines the display order for common log fields.
 var logFieldOrder = []string{"provider", "model", "mode", "budget", "level", "original_mode", "original_value", "min", "max", "clamped_to", "error"}
 
-// Format renders a single log entry with custom formatting.
+// Format renders a single log entry with custom formatting. Entries below the level of the
+// module that logged them render as nothing; see util.LogEntryEnabled.
 func (m *LogFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if !util.LogEntryEnabled(entry) {
+		return nil, nil
+	}
 	var buffer *bytes.Buffer
 	if entry.Buffer != nil {
 		buffer = entry.Buffer
@@ -87,6 +91,7 @@ func SetupBaseLogger() {
 		log.SetOutput(os.Stdout)
 		log.SetReportCaller(true)
 		log.SetFormatter(&LogFormatter{})
+		log.AddHook(tail)
 
 		ginInfoWriter = log.StandardLogger().Writer()
 		gin.DefaultWriter = ginInfoWriter
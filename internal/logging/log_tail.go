@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// logTailCapacity is the number of recent log entries kept for new tail subscribers.
+const logTailCapacity = 1000
+
+// TailEntry is a log entry delivered to live tail subscribers.
+type TailEntry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	RequestID string    `json:"request-id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	AuthID    string    `json:"auth-id,omitempty"`
+	Message   string    `json:"message"`
+	// Line is the entry as written to the log output.
+	Line string `json:"line"`
+
+	level log.Level
+}
+
+// TailFilter selects the entries delivered to a tail subscriber. Empty fields match everything.
+type TailFilter struct {
+	// Level is the least severe level delivered (e.g. "warn" delivers warnings and errors).
+	Level log.Level
+	// Provider matches the entry's provider field or, failing that, its message.
+	Provider string
+	// AuthID matches the entry's auth field or, failing that, its message.
+	AuthID string
+	// RequestID matches the entry's request ID exactly.
+	RequestID string
+}
+
+// Match reports whether the entry passes the filter.
+func (f TailFilter) Match(entry TailEntry) bool {
+	if entry.level > f.Level {
+		return false
+	}
+	if f.RequestID != "" && entry.RequestID != f.RequestID {
+		return false
+	}
+	if f.Provider != "" && !strings.EqualFold(entry.Provider, f.Provider) && !containsFold(entry.Message, f.Provider) {
+		return false
+	}
+	if f.AuthID != "" && entry.AuthID != f.AuthID && !strings.Contains(entry.Message, f.AuthID) {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// logTail is a logrus hook keeping recent entries and fanning new ones out to subscribers.
+type logTail struct {
+	mu          sync.Mutex
+	ring        []TailEntry
+	next        int
+	full        bool
+	subscribers map[chan TailEntry]TailFilter
+}
+
+var tail = &logTail{ring: make([]TailEntry, logTailCapacity), subscribers: make(map[chan TailEntry]TailFilter)}
+
+// Levels implements log.Hook.
+func (t *logTail) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements log.Hook. Slow subscribers miss entries rather than block logging.
+func (t *logTail) Fire(entry *log.Entry) error {
+	if !util.LogEntryEnabled(entry) {
+		return nil
+	}
+	formatted := *entry
+	formatted.Buffer = nil
+	line, _ := (&LogFormatter{}).Format(&formatted)
+	item := TailEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: strings.TrimRight(entry.Message, "\r\n"),
+		Line:    strings.TrimRight(string(line), "\n"),
+		level:   entry.Level,
+	}
+	if item.Level == "warning" {
+		item.Level = "warn"
+	}
+	item.RequestID, _ = entry.Data["request_id"].(string)
+	item.Provider, _ = entry.Data["provider"].(string)
+	item.AuthID, _ = entry.Data["auth_id"].(string)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ring[t.next] = item
+	t.next = (t.next + 1) % len(t.ring)
+	if t.next == 0 {
+		t.full = true
+	}
+	for ch, filter := range t.subscribers {
+		if !filter.Match(item) {
+			continue
+		}
+		select {
+		case ch <- item:
+		default:
+		}
+	}
+	return nil
+}
+
+// SubscribeLogTail returns up to backlog recent entries matching filter, oldest first, and a
+// channel receiving matching entries logged from now on. cancel releases the subscription and
+// closes the channel.
+func SubscribeLogTail(filter TailFilter, backlog int) (recent []TailEntry, entries <-chan TailEntry, cancel func()) {
+	t := tail
+	ch := make(chan TailEntry, 256)
+
+	t.mu.Lock()
+	count := t.next
+	if t.full {
+		count = len(t.ring)
+	}
+	for i := 0; i < count && len(recent) < backlog; i++ {
+		item := t.ring[(t.next-1-i+len(t.ring))%len(t.ring)]
+		if filter.Match(item) {
+			recent = append(recent, item)
+		}
+	}
+	t.subscribers[ch] = filter
+	t.mu.Unlock()
+
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers, ch)
+			t.mu.Unlock()
+			close(ch)
+		})
+	}
+	return recent, ch, cancel
+}
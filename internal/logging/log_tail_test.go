@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSubscribeLogTailBacklogAndFilter(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(log.DebugLevel)
+	logger.AddHook(tail)
+
+	logger.WithField("request_id", "tail-req-1").Info("backlog info")
+	logger.WithField("request_id", "tail-req-1").Warn("backlog warn")
+	logger.WithField("request_id", "tail-req-2").Error("other request")
+
+	recent, entries, cancel := SubscribeLogTail(TailFilter{Level: log.WarnLevel, RequestID: "tail-req-1"}, 10)
+	defer cancel()
+	if len(recent) != 1 || recent[0].Message != "backlog warn" || recent[0].Level != "warn" {
+		t.Fatalf("recent = %+v", recent)
+	}
+
+	logger.WithField("request_id", "tail-req-1").Debug("filtered by level")
+	logger.WithFields(log.Fields{"request_id": "tail-req-1", "provider": "codex"}).Error("live error")
+	select {
+	case entry := <-entries:
+		if entry.Message != "live error" || entry.Provider != "codex" || entry.Line == "" {
+			t.Fatalf("entry = %+v", entry)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected live entry")
+	}
+
+	cancel()
+	if _, ok := <-entries; ok {
+		t.Fatal("expected channel closed after cancel")
+	}
+}
+
+func TestTailFilterMatchesMessage(t *testing.T) {
+	entry := TailEntry{Message: "Use OAuth codex-user.json for model gpt-5", level: log.InfoLevel}
+	if !(TailFilter{Level: log.InfoLevel, Provider: "Codex", AuthID: "codex-user.json"}).Match(entry) {
+		t.Fatal("expected provider and auth to match the message")
+	}
+	if (TailFilter{Level: log.InfoLevel, AuthID: "other.json"}).Match(entry) {
+		t.Fatal("expected unrelated auth to be filtered")
+	}
+}
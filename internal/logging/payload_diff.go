@@ -0,0 +1,248 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// payloadDiffMaxSessions bounds the sessions whose previous request body is kept.
+	payloadDiffMaxSessions = 256
+	// payloadDiffTTL is how long a previous request body is kept for diffing.
+	payloadDiffTTL = time.Hour
+)
+
+// payloadDiffSessionHeaders and payloadDiffSessionFields identify the session of a request.
+var (
+	payloadDiffSessionHeaders = []string{"Session_id", "X-Session-Id", "Conversation_id"}
+	payloadDiffSessionFields  = []string{"metadata.session_id", "metadata.user_id", "prompt_cache_key", "conversation_id"}
+)
+
+// payloadDiffer remembers the last logged request body of each session so the next request of
+// the session can be logged as a structural diff against it.
+type payloadDiffer struct {
+	mu       sync.Mutex
+	previous map[string]previousPayload
+}
+
+type previousPayload struct {
+	body      []byte
+	requestID string
+	seen      time.Time
+}
+
+// payloadDiff is the document logged in place of a request body.
+type payloadDiff struct {
+	DiffAgainst  string          `json:"diff_against"`
+	PreviousSize int             `json:"previous_size"`
+	Size         int             `json:"size"`
+	Changes      []payloadChange `json:"changes"`
+}
+
+// payloadChange is one structural change. Path is a JSON pointer (RFC 6901). Ops are "add",
+// "remove", "replace" and "splice", which deletes Delete array items at Index and inserts Insert.
+type payloadChange struct {
+	Op     string            `json:"op"`
+	Path   string            `json:"path"`
+	Value  json.RawMessage   `json:"value,omitempty"`
+	Index  *int              `json:"index,omitempty"`
+	Delete *int              `json:"delete,omitempty"`
+	Insert []json.RawMessage `json:"insert,omitempty"`
+}
+
+func newPayloadDiffer() *payloadDiffer {
+	return &payloadDiffer{previous: make(map[string]previousPayload)}
+}
+
+// render returns the body to log for a request: a diff against the previous logged request of
+// the same session when that is smaller, otherwise body itself. Requests without a session, or
+// whose body is not a JSON object, are logged in full. The returned key is passed to remember
+// once the log has been written; it is empty when the request has no session.
+func (d *payloadDiffer) render(url string, headers map[string][]string, body []byte) ([]byte, string) {
+	if d == nil || len(body) == 0 || !gjson.ValidBytes(body) || !gjson.ParseBytes(body).IsObject() {
+		return body, ""
+	}
+	session := payloadDiffSession(headers, body)
+	if session == "" {
+		return body, ""
+	}
+	path, _, _ := strings.Cut(url, "?")
+	key := path + "\x00" + session
+
+	d.mu.Lock()
+	prev, ok := d.previous[key]
+	d.mu.Unlock()
+	if !ok || time.Since(prev.seen) > payloadDiffTTL {
+		return body, key
+	}
+
+	changes, errDiff := diffPayloads(prev.body, body)
+	if errDiff != nil {
+		return body, key
+	}
+	out, errMarshal := json.MarshalIndent(payloadDiff{
+		DiffAgainst:  prev.requestID,
+		PreviousSize: len(prev.body),
+		Size:         len(body),
+		Changes:      changes,
+	}, "", "  ")
+	if errMarshal != nil || len(out) >= len(body) {
+		return body, key
+	}
+	return out, key
+}
+
+// remember makes body the request the next request of the session is diffed against.
+func (d *payloadDiffer) remember(key string, body []byte, requestID string) {
+	if d == nil || key == "" {
+		return
+	}
+	if requestID == "" {
+		requestID = time.Now().Format(time.RFC3339Nano)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.previous[key]; !exists && len(d.previous) >= payloadDiffMaxSessions {
+		d.evictOldestLocked()
+	}
+	d.previous[key] = previousPayload{body: bytes.Clone(body), requestID: requestID, seen: time.Now()}
+}
+
+func (d *payloadDiffer) evictOldestLocked() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, prev := range d.previous {
+		if oldestKey == "" || prev.seen.Before(oldest) {
+			oldestKey, oldest = key, prev.seen
+		}
+	}
+	delete(d.previous, oldestKey)
+}
+
+// payloadDiffSession returns the session identifier of a request from its session headers or
+// the session fields of its body.
+func payloadDiffSession(headers map[string][]string, body []byte) string {
+	h := http.Header(headers)
+	for _, key := range payloadDiffSessionHeaders {
+		if value := strings.TrimSpace(h.Get(key)); value != "" {
+			return value
+		}
+	}
+	for _, field := range payloadDiffSessionFields {
+		if value := strings.TrimSpace(gjson.GetBytes(body, field).String()); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// diffPayloads returns the structural changes turning the JSON document prev into next.
+func diffPayloads(prev, next []byte) ([]payloadChange, error) {
+	prevValue, errPrev := decodePayload(prev)
+	if errPrev != nil {
+		return nil, errPrev
+	}
+	nextValue, errNext := decodePayload(next)
+	if errNext != nil {
+		return nil, errNext
+	}
+	changes := make([]payloadChange, 0)
+	diffValues("", prevValue, nextValue, &changes)
+	return changes, nil
+}
+
+func decodePayload(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func diffValues(path string, prev, next any, changes *[]payloadChange) {
+	switch prevTyped := prev.(type) {
+	case map[string]any:
+		if nextTyped, ok := next.(map[string]any); ok {
+			diffObjects(path, prevTyped, nextTyped, changes)
+			return
+		}
+	case []any:
+		if nextTyped, ok := next.([]any); ok {
+			diffArrays(path, prevTyped, nextTyped, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(prev, next) {
+		*changes = append(*changes, payloadChange{Op: "replace", Path: path, Value: rawPayloadValue(next)})
+	}
+}
+
+func diffObjects(path string, prev, next map[string]any, changes *[]payloadChange) {
+	keys := make([]string, 0, len(prev)+len(next))
+	for key := range prev {
+		keys = append(keys, key)
+	}
+	for key := range next {
+		if _, ok := prev[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "/" + escapePointerToken(key)
+		prevValue, inPrev := prev[key]
+		nextValue, inNext := next[key]
+		switch {
+		case !inNext:
+			*changes = append(*changes, payloadChange{Op: "remove", Path: childPath})
+		case !inPrev:
+			*changes = append(*changes, payloadChange{Op: "add", Path: childPath, Value: rawPayloadValue(nextValue)})
+		default:
+			diffValues(childPath, prevValue, nextValue, changes)
+		}
+	}
+}
+
+// diffArrays compares arrays item by item when their lengths match. Otherwise the items after
+// the common prefix are spliced, which covers conversation turns appended to a history.
+func diffArrays(path string, prev, next []any, changes *[]payloadChange) {
+	common := 0
+	for common < len(prev) && common < len(next) && reflect.DeepEqual(prev[common], next[common]) {
+		common++
+	}
+	if len(prev) == len(next) {
+		for i := common; i < len(next); i++ {
+			diffValues(path+"/"+strconv.Itoa(i), prev[i], next[i], changes)
+		}
+		return
+	}
+	index, deleted := common, len(prev)-common
+	change := payloadChange{Op: "splice", Path: path, Index: &index, Delete: &deleted}
+	for _, item := range next[common:] {
+		change.Insert = append(change.Insert, rawPayloadValue(item))
+	}
+	*changes = append(*changes, change)
+}
+
+func rawPayloadValue(value any) json.RawMessage {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return raw
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
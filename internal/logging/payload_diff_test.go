@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPayloadDifferLogsAppendedTurnsAsSplice(t *testing.T) {
+	differ := newPayloadDiffer()
+	history := strings.Repeat("long unchanged history ", 200)
+	headers := map[string][]string{"Session_id": {"session-1"}}
+	first := []byte(`{"model":"gpt-5","temperature":1,"messages":[{"role":"system","content":"` + history + `"},{"role":"user","content":"hi"}]}`)
+	second := []byte(`{"model":"gpt-5","temperature":0.5,"messages":[{"role":"system","content":"` + history + `"},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"more"}]}`)
+
+	got, key := differ.render("/v1/chat/completions", headers, first)
+	if string(got) != string(first) || key == "" {
+		t.Fatalf("first request should be logged in full, got %s (key %q)", got, key)
+	}
+	if got, _ = differ.render("/v1/chat/completions", headers, second); string(got) != string(second) {
+		t.Fatalf("request should be logged in full until the previous one is remembered, got %s", got)
+	}
+	differ.remember(key, first, "req-1")
+	got, _ = differ.render("/v1/chat/completions", headers, second)
+	var diff payloadDiff
+	if err := json.Unmarshal(got, &diff); err != nil {
+		t.Fatalf("second request should be logged as a diff: %v\n%s", err, got)
+	}
+	if diff.DiffAgainst != "req-1" || diff.Size != len(second) || len(diff.Changes) != 2 {
+		t.Fatalf("diff = %+v", diff)
+	}
+	splice := diff.Changes[0]
+	if splice.Op != "splice" || splice.Path != "/messages" || *splice.Index != 2 || *splice.Delete != 0 || len(splice.Insert) != 2 {
+		t.Fatalf("messages change = %+v", splice)
+	}
+	if replace := diff.Changes[1]; replace.Op != "replace" || replace.Path != "/temperature" || string(replace.Value) != "0.5" {
+		t.Fatalf("temperature change = %+v", replace)
+	}
+
+	// Other endpoints and requests without a session are logged in full.
+	if got, _ = differ.render("/v1/responses", headers, second); string(got) != string(second) {
+		t.Fatalf("request on another endpoint should be logged in full, got %s", got)
+	}
+	if got, key = differ.render("/v1/chat/completions", nil, second); string(got) != string(second) || key != "" {
+		t.Fatalf("request without a session should be logged in full, got %s", got)
+	}
+}
+
+func TestDiffPayloadsEscapesPointerTokens(t *testing.T) {
+	changes, err := diffPayloads([]byte(`{"a/b":{"c~d":1},"gone":true,"list":[1,2,3]}`), []byte(`{"a/b":{"c~d":2},"new":null,"list":[1,9,3]}`))
+	if err != nil {
+		t.Fatalf("diffPayloads: %v", err)
+	}
+	got := make([]string, 0, len(changes))
+	for _, change := range changes {
+		got = append(got, change.Op+" "+change.Path+" "+string(change.Value))
+	}
+	want := []string{"replace /a~1b/c~0d 2", "remove /gone ", "replace /list/1 9", "add /new null"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+}
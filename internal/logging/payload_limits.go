@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+// sampleRequestLog reports whether a request log is written. Failed requests are always
+// logged; other requests are kept with probability rate. A rate outside (0, 1) keeps every log.
+func sampleRequestLog(rate float64, failed bool) bool {
+	if failed || rate <= 0 || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+// truncationMarker describes the bytes removed from the middle of a payload of size bytes.
+func truncationMarker(removed, size int64, sum []byte) string {
+	return fmt.Sprintf("\n\n[... truncated %d of %d bytes, sha256 of full body: %s ...]\n\n", removed, size, hex.EncodeToString(sum))
+}
+
+// capPayload keeps the head and tail of payload within maxBytes, replacing the middle with a
+// marker that carries the hash of the full payload. maxBytes <= 0 disables the cap.
+func capPayload(payload []byte, maxBytes int) []byte {
+	if maxBytes <= 0 || len(payload) <= maxBytes {
+		return payload
+	}
+	sum := sha256.Sum256(payload)
+	head := maxBytes / 2
+	tail := maxBytes - head
+	var out bytes.Buffer
+	out.Grow(maxBytes + 128)
+	out.Write(payload[:head])
+	out.WriteString(truncationMarker(int64(len(payload)-maxBytes), int64(len(payload)), sum[:]))
+	out.Write(payload[len(payload)-tail:])
+	return out.Bytes()
+}
+
+// capPayloadFile returns a reader over file with the same head+tail truncation as capPayload.
+func capPayloadFile(file *os.File, maxBytes int) (io.Reader, error) {
+	info, errStat := file.Stat()
+	if errStat != nil {
+		return nil, errStat
+	}
+	size := info.Size()
+	if maxBytes <= 0 || size <= int64(maxBytes) {
+		return file, nil
+	}
+	hash := sha256.New()
+	if _, errCopy := io.Copy(hash, io.NewSectionReader(file, 0, size)); errCopy != nil {
+		return nil, errCopy
+	}
+	head := int64(maxBytes / 2)
+	tail := int64(maxBytes) - head
+	return io.MultiReader(
+		io.NewSectionReader(file, 0, head),
+		bytes.NewReader([]byte(truncationMarker(size-int64(maxBytes), size, hash.Sum(nil)))),
+		io.NewSectionReader(file, size-tail, tail),
+	), nil
+}
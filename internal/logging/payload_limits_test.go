@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapPayloadKeepsHeadAndTail(t *testing.T) {
+	payload := []byte(strings.Repeat("a", 50) + strings.Repeat("m", 900) + strings.Repeat("z", 50))
+	sum := sha256.Sum256(payload)
+	got := capPayload(payload, 100)
+	if !bytes.HasPrefix(got, []byte(strings.Repeat("a", 50))) || !bytes.HasSuffix(got, []byte(strings.Repeat("z", 50))) {
+		t.Fatalf("capped payload lost its head or tail: %q", got)
+	}
+	if bytes.Contains(got, []byte("m")) || !bytes.Contains(got, []byte("truncated 900 of 1000 bytes")) || !bytes.Contains(got, []byte(hex.EncodeToString(sum[:]))) {
+		t.Fatalf("capped payload = %q", got)
+	}
+	if small := []byte("short"); string(capPayload(small, 100)) != "short" || string(capPayload(payload, 0)) != string(payload) {
+		t.Fatal("payloads within the cap, or without a cap, must be unchanged")
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "body-*.tmp")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err = file.Write(payload); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	reader, err := capPayloadFile(file, 100)
+	if err != nil {
+		t.Fatalf("capPayloadFile: %v", err)
+	}
+	fromFile, _ := io.ReadAll(reader)
+	if !bytes.Equal(fromFile, got) {
+		t.Fatalf("file truncation = %q, want %q", fromFile, got)
+	}
+}
+
+func TestRequestLogSamplingKeepsFailures(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewFileRequestLogger(true, dir, "", 0)
+	logger.SetRequestLogLimits(0, 1e-12)
+	now := time.Now()
+	if err := logger.LogRequest("/v1/chat/completions", "POST", nil, []byte(`{}`), 200, nil, []byte(`ok`), nil, nil, nil, "sampled-out", now, now); err != nil {
+		t.Fatalf("LogRequest: %v", err)
+	}
+	if err := logger.LogRequest("/v1/chat/completions", "POST", nil, []byte(`{}`), 500, nil, []byte(`boom`), nil, nil, nil, "failed", now, now); err != nil {
+		t.Fatalf("LogRequest: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 || !strings.Contains(files[0], "failed") {
+		t.Fatalf("log files = %v, want only the failed request", files)
+	}
+}
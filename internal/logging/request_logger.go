@@ -9,6 +9,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -135,6 +136,15 @@ type FileRequestLogger struct {
 
 	// errorLogsMaxFiles limits the number of error log files retained.
 	errorLogsMaxFiles int
+
+	// differ, when set, logs request bodies as diffs against the previous request of the session.
+	differ *payloadDiffer
+
+	// maxBodyBytes caps each logged body with head+tail truncation (0 = unlimited).
+	maxBodyBytes int
+
+	// sampleRate is the fraction of successful requests that are logged (0 = all).
+	sampleRate float64
 }
 
 // NewFileRequestLogger creates a new file-based request logger.
@@ -185,6 +195,25 @@ func (l *FileRequestLogger) SetErrorLogsMaxFiles(maxFiles int) {
 	l.errorLogsMaxFiles = maxFiles
 }
 
+// SetRequestLogDiff enables or disables logging request bodies as structural diffs against the
+// previous request of the same session. Disabling it forgets the remembered bodies.
+func (l *FileRequestLogger) SetRequestLogDiff(enabled bool) {
+	if !enabled {
+		l.differ = nil
+		return
+	}
+	if l.differ == nil {
+		l.differ = newPayloadDiffer()
+	}
+}
+
+// SetRequestLogLimits updates the cap applied to each logged body and the fraction of
+// successful requests that are logged. Failed requests are always logged.
+func (l *FileRequestLogger) SetRequestLogLimits(maxBodyBytes int, sampleRate float64) {
+	l.maxBodyBytes = maxBodyBytes
+	l.sampleRate = sampleRate
+}
+
 // LogRequest logs a complete non-streaming request/response cycle to a file.
 //
 // Parameters:
@@ -217,6 +246,9 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 	if !l.enabled && !force {
 		return nil
 	}
+	if !force && !sampleRequestLog(l.sampleRate, statusCode >= http.StatusBadRequest || len(apiResponseErrors) > 0) {
+		return nil
+	}
 
 	// Ensure logs directory exists
 	if errEnsure := l.ensureLogsDir(); errEnsure != nil {
@@ -230,6 +262,11 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 	}
 	filePath := filepath.Join(l.logsDir, filename)
 
+	rawBody, diffKey := body, ""
+	if l.enabled {
+		body, diffKey = l.differ.render(url, requestHeaders, body)
+	}
+	body = capPayload(body, l.maxBodyBytes)
 	requestBodyPath, errTemp := l.writeRequestBodyTempFile(body)
 	if errTemp != nil {
 		log.WithError(errTemp).Warn("failed to create request body temp file, falling back to direct write")
@@ -247,6 +284,7 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 		// If decompression fails, continue with original response and annotate the log output.
 		responseToWrite = response
 	}
+	responseToWrite = capPayload(responseToWrite, l.maxBodyBytes)
 
 	logFile, errOpen := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if errOpen != nil {
@@ -260,8 +298,8 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 		requestHeaders,
 		body,
 		requestBodyPath,
-		apiRequest,
-		apiResponse,
+		capPayload(apiRequest, l.maxBodyBytes),
+		capPayload(apiResponse, l.maxBodyBytes),
 		apiResponseErrors,
 		statusCode,
 		responseHeaders,
@@ -279,6 +317,7 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 	if writeErr != nil {
 		return fmt.Errorf("failed to write log file: %w", writeErr)
 	}
+	l.differ.remember(diffKey, rawBody, requestID)
 
 	if force && !l.enabled {
 		if errCleanup := l.cleanupOldErrorLogs(); errCleanup != nil {
@@ -322,7 +361,8 @@ func (l *FileRequestLogger) LogStreamingRequest(url, method string, headers map[
 		requestHeaders[key] = headerValues
 	}
 
-	requestBodyPath, errTemp := l.writeRequestBodyTempFile(body)
+	loggedBody, diffKey := l.differ.render(url, requestHeaders, body)
+	requestBodyPath, errTemp := l.writeRequestBodyTempFile(capPayload(loggedBody, l.maxBodyBytes))
 	if errTemp != nil {
 		return nil, fmt.Errorf("failed to create request body temp file: %w", errTemp)
 	}
@@ -347,6 +387,12 @@ func (l *FileRequestLogger) LogStreamingRequest(url, method string, headers map[
 		chunkChan:        make(chan []byte, 100), // Buffered channel for async writes
 		closeChan:        make(chan struct{}),
 		errorChan:        make(chan error, 1),
+		maxBodyBytes:     l.maxBodyBytes,
+		sampleRate:       l.sampleRate,
+		differ:           l.differ,
+		diffKey:          diffKey,
+		requestBody:      body,
+		requestID:        requestID,
 	}
 
 	// Start async writer goroutine
@@ -1013,6 +1059,22 @@ type FileStreamingLogWriter struct {
 
 	// apiResponseTimestamp captures when the API response was received.
 	apiResponseTimestamp time.Time
+
+	// maxBodyBytes caps each logged body (0 = unlimited).
+	maxBodyBytes int
+
+	// sampleRate is the fraction of successful requests that are logged (0 = all).
+	sampleRate float64
+
+	// differ receives the request body once the log is written, keyed by diffKey.
+	differ  *payloadDiffer
+	diffKey string
+
+	// requestBody is the original request body, remembered by differ for the next diff.
+	requestBody []byte
+
+	// requestID identifies this log in the diff of the next request of the session.
+	requestID string
 }
 
 // WriteChunkAsync writes a response chunk asynchronously (non-blocking).
@@ -1122,7 +1184,7 @@ func (w *FileStreamingLogWriter) Close() error {
 	default:
 	}
 
-	if w.logFilePath == "" {
+	if w.logFilePath == "" || !sampleRequestLog(w.sampleRate, w.responseStatus >= http.StatusBadRequest) {
 		w.cleanupTempFiles()
 		return nil
 	}
@@ -1142,6 +1204,9 @@ func (w *FileStreamingLogWriter) Close() error {
 	}
 
 	w.cleanupTempFiles()
+	if writeErr == nil {
+		w.differ.remember(w.diffKey, w.requestBody, w.requestID)
+	}
 	return writeErr
 }
 
@@ -1185,10 +1250,10 @@ func (w *FileStreamingLogWriter) writeFinalLog(logFile *os.File) error {
 	if errWrite := writeRequestInfoWithBody(logFile, w.url, w.method, w.requestHeaders, nil, w.requestBodyPath, w.timestamp); errWrite != nil {
 		return errWrite
 	}
-	if errWrite := writeAPISection(logFile, "=== API REQUEST ===\n", "=== API REQUEST", w.apiRequest, time.Time{}); errWrite != nil {
+	if errWrite := writeAPISection(logFile, "=== API REQUEST ===\n", "=== API REQUEST", capPayload(w.apiRequest, w.maxBodyBytes), time.Time{}); errWrite != nil {
 		return errWrite
 	}
-	if errWrite := writeAPISection(logFile, "=== API RESPONSE ===\n", "=== API RESPONSE", w.apiResponse, w.apiResponseTimestamp); errWrite != nil {
+	if errWrite := writeAPISection(logFile, "=== API RESPONSE ===\n", "=== API RESPONSE", capPayload(w.apiResponse, w.maxBodyBytes), w.apiResponseTimestamp); errWrite != nil {
 		return errWrite
 	}
 
@@ -1202,7 +1267,11 @@ func (w *FileStreamingLogWriter) writeFinalLog(logFile *os.File) error {
 		}
 	}()
 
-	return writeResponseSection(logFile, w.responseStatus, w.statusWritten, w.responseHeaders, responseBodyFile, nil, false)
+	responseReader, errCap := capPayloadFile(responseBodyFile, w.maxBodyBytes)
+	if errCap != nil {
+		return errCap
+	}
+	return writeResponseSection(logFile, w.responseStatus, w.statusWritten, w.responseHeaders, responseReader, nil, false)
 }
 
 func (w *FileStreamingLogWriter) cleanupTempFiles() {
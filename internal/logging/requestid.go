@@ -4,10 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RequestIDHeader is the HTTP header used to accept and echo request IDs.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds adopted client-supplied request IDs.
+const maxRequestIDLength = 128
+
 // requestIDKey is the context key for storing/retrieving request IDs.
 type requestIDKey struct{}
 
@@ -23,6 +31,36 @@ func GenerateRequestID() string {
 	return hex.EncodeToString(b)
 }
 
+// SanitizeRequestID validates a client-supplied request ID.
+// It returns an empty string when the value is empty, too long, or contains
+// characters outside [A-Za-z0-9._:-], so untrusted input never reaches logs or headers verbatim.
+func SanitizeRequestID(raw string) string {
+	id := strings.TrimSpace(raw)
+	if id == "" || len(id) > maxRequestIDLength {
+		return ""
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return ""
+		}
+	}
+	return id
+}
+
+// RequestIDFromRequest adopts a valid incoming X-Request-Id or generates a new one.
+func RequestIDFromRequest(r *http.Request) string {
+	if r != nil {
+		if id := SanitizeRequestID(r.Header.Get(RequestIDHeader)); id != "" {
+			return id
+		}
+	}
+	return GenerateRequestID()
+}
+
 // WithRequestID returns a new context with the request ID attached.
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, requestIDKey{}, requestID)
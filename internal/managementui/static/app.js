@@ -0,0 +1,233 @@
+(function () {
+  "use strict";
+
+  const API = "/v0/management";
+  const KEY_STORAGE = "cliproxy-management-key";
+  let managementKey = sessionStorage.getItem(KEY_STORAGE) || "";
+  let activeTab = "auth-files";
+
+  const $ = (selector, root) => (root || document).querySelector(selector);
+
+  async function api(method, path, body) {
+    const init = { method, headers: { Authorization: "Bearer " + managementKey } };
+    if (body !== undefined) {
+      init.headers["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    const resp = await fetch(API + path, init);
+    const data = await resp.json().catch(() => ({}));
+    if (!resp.ok) {
+      const err = new Error(data.error || resp.status + " " + resp.statusText);
+      err.status = resp.status;
+      throw err;
+    }
+    return data;
+  }
+
+  function cell(text, className) {
+    const td = document.createElement("td");
+    td.textContent = text === undefined || text === null || text === "" ? "-" : String(text);
+    if (className) td.className = className;
+    return td;
+  }
+
+  function toggleCell(checked, onChange) {
+    const td = document.createElement("td");
+    const input = document.createElement("input");
+    input.type = "checkbox";
+    input.checked = checked;
+    input.addEventListener("change", async () => {
+      input.disabled = true;
+      try {
+        await onChange(input.checked);
+      } catch (err) {
+        input.checked = !input.checked;
+        showError(err);
+      } finally {
+        input.disabled = false;
+      }
+    });
+    td.appendChild(input);
+    return td;
+  }
+
+  function fillTable(tbody, rows) {
+    tbody.replaceChildren(...rows);
+  }
+
+  function showError(err) {
+    $("#error").textContent = err ? err.message : "";
+    if (err && err.status === 401) signOut();
+  }
+
+  const loaders = {
+    "auth-files": async () => {
+      const data = await api("GET", "/auth-files");
+      const rows = (data.files || []).map((file) => {
+        const tr = document.createElement("tr");
+        tr.append(
+          cell(file.name),
+          cell(file.provider),
+          cell(file.email || file.account || file.label, "muted"),
+          cell(file.status_message || file.status),
+          toggleCell(!file.disabled, (enabled) =>
+            api("PATCH", "/auth-files/status", { name: file.name, disabled: !enabled })
+          )
+        );
+        return tr;
+      });
+      fillTable($("#auth-files tbody"), rows);
+    },
+
+    "reverse-proxies": async () => {
+      const data = await api("GET", "/reverse-proxies");
+      const rows = (data["reverse-proxies"] || []).map((proxy) => {
+        const tr = document.createElement("tr");
+        tr.append(
+          cell(proxy.id, "muted"),
+          cell(proxy.name),
+          cell(proxy["base-url"]),
+          cell(proxy.description, "muted"),
+          toggleCell(proxy.enabled, (enabled) =>
+            api("PUT", "/reverse-proxies/" + encodeURIComponent(proxy.id), Object.assign({}, proxy, { enabled }))
+          )
+        );
+        return tr;
+      });
+      fillTable($("#reverse-proxies tbody"), rows);
+    },
+
+    routing: async () => {
+      const [strategy, proxies, routing, routingAuth] = await Promise.all([
+        api("GET", "/routing/strategy"),
+        api("GET", "/reverse-proxies"),
+        api("GET", "/proxy-routing"),
+        api("GET", "/proxy-routing-auth"),
+      ]);
+      $("#routing-strategy").value = strategy.strategy || "round-robin";
+
+      const names = {};
+      (proxies["reverse-proxies"] || []).forEach((proxy) => {
+        names[proxy.id] = proxy.name || proxy.id;
+      });
+      const providerRouting = routing["proxy-routing"] || {};
+      fillTable(
+        $("#proxy-routing tbody"),
+        Object.keys(providerRouting).sort().map((provider) => {
+          const tr = document.createElement("tr");
+          tr.append(cell(provider), cell(names[providerRouting[provider]] || providerRouting[provider]));
+          return tr;
+        })
+      );
+      const authRouting = routingAuth["proxy-routing-auth"] || {};
+      fillTable(
+        $("#proxy-routing-auth tbody"),
+        Object.keys(authRouting).sort().map((auth) => {
+          const tr = document.createElement("tr");
+          tr.append(cell(auth), cell(names[authRouting[auth]] || authRouting[auth]));
+          return tr;
+        })
+      );
+    },
+
+    usage: async () => {
+      const data = await api("GET", "/usage");
+      const usage = data.usage || {};
+      $("#usage-total").textContent = usage.total_requests || 0;
+      $("#usage-success").textContent = usage.success_count || 0;
+      $("#usage-failure").textContent = usage.failure_count || 0;
+      $("#usage-tokens").textContent = usage.total_tokens || 0;
+      const rows = [];
+      Object.keys(usage.apis || {}).sort().forEach((apiKey) => {
+        const models = usage.apis[apiKey].models || {};
+        Object.keys(models).sort().forEach((model) => {
+          const tr = document.createElement("tr");
+          tr.append(cell(apiKey, "muted"), cell(model), cell(models[model].total_requests), cell(models[model].total_tokens));
+          rows.push(tr);
+        });
+      });
+      fillTable($("#usage tbody"), rows);
+    },
+  };
+
+  async function load(tab) {
+    showError(null);
+    try {
+      await loaders[tab]();
+    } catch (err) {
+      showError(err);
+    }
+  }
+
+  function selectTab(tab) {
+    activeTab = tab;
+    document.querySelectorAll("#tabs button").forEach((button) => {
+      button.classList.toggle("active", button.dataset.tab === tab);
+    });
+    document.querySelectorAll(".panel").forEach((panel) => {
+      panel.hidden = panel.id !== tab;
+    });
+    load(tab);
+  }
+
+  function signedIn(yes) {
+    $("#login").hidden = yes;
+    $("#tabs").hidden = !yes;
+    $("#logout").hidden = !yes;
+    if (!yes) {
+      document.querySelectorAll(".panel").forEach((panel) => {
+        panel.hidden = true;
+      });
+    }
+  }
+
+  function signOut() {
+    managementKey = "";
+    sessionStorage.removeItem(KEY_STORAGE);
+    signedIn(false);
+  }
+
+  $("#login-form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    managementKey = $("#management-key").value.trim();
+    $("#login-error").textContent = "";
+    try {
+      await api("GET", "/routing/strategy");
+    } catch (err) {
+      managementKey = "";
+      $("#login-error").textContent = err.message;
+      return;
+    }
+    sessionStorage.setItem(KEY_STORAGE, managementKey);
+    $("#management-key").value = "";
+    signedIn(true);
+    selectTab(activeTab);
+  });
+
+  $("#logout").addEventListener("click", signOut);
+
+  document.querySelectorAll("#tabs button").forEach((button) => {
+    button.addEventListener("click", () => selectTab(button.dataset.tab));
+  });
+
+  document.querySelectorAll("[data-refresh]").forEach((button) => {
+    button.addEventListener("click", () => load(button.dataset.refresh));
+  });
+
+  $("#strategy-form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    try {
+      await api("PUT", "/routing/strategy", { value: $("#routing-strategy").value });
+      showError(null);
+    } catch (err) {
+      showError(err);
+    }
+  });
+
+  if (managementKey) {
+    signedIn(true);
+    selectTab(activeTab);
+  } else {
+    signedIn(false);
+  }
+})();
@@ -0,0 +1,55 @@
+// Package managementui embeds the built-in management dashboard served under /ui.
+// The dashboard is a static single-page application that talks to the existing
+// /v0/management endpoints using the management key entered by the operator.
+package managementui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BasePath is the URL prefix the dashboard is served from.
+const BasePath = "/ui"
+
+//go:embed static
+var staticFiles embed.FS
+
+// Assets returns the embedded dashboard files rooted at the static directory.
+func Assets() fs.FS {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		// The embed directive guarantees the directory exists.
+		panic(err)
+	}
+	return sub
+}
+
+// Handler serves the embedded dashboard. Unknown paths fall back to index.html so
+// client-side routes can be reloaded directly.
+func Handler() gin.HandlerFunc {
+	assets := Assets()
+	fileServer := http.FileServer(http.FS(assets))
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+		if name == "" {
+			name = "index.html"
+		}
+		if _, err := fs.Stat(assets, name); err != nil {
+			name = "index.html"
+		}
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Content-Type-Options", "nosniff")
+		req := c.Request.Clone(c.Request.Context())
+		req.URL.Path = "/" + name
+		if name == "index.html" {
+			// http.FileServer redirects explicit index.html requests to the directory.
+			req.URL.Path = "/"
+		}
+		fileServer.ServeHTTP(c.Writer, req)
+	}
+}
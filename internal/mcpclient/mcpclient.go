@@ -0,0 +1,340 @@
+// Package mcpclient connects to external Model Context Protocol servers so the proxy can
+// offer their tools to upstream models and execute the resulting tool calls itself.
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// protocolVersion is the MCP revision requested during initialization.
+	protocolVersion = "2025-03-26"
+	// toolsTTL is how long a server's tool list is reused before it is fetched again.
+	toolsTTL = 5 * time.Minute
+	// toolNamePrefix marks tools offered to models on behalf of an MCP server.
+	toolNamePrefix = "mcp__"
+	// maxToolNameLength is the longest function name upstream APIs accept.
+	maxToolNameLength = 64
+	// defaultTimeout bounds calls to servers without timeout-seconds.
+	defaultTimeout = 60 * time.Second
+)
+
+var unsafeToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Tool is a tool of an MCP server as offered to models.
+type Tool struct {
+	// Name is the function name offered to the model.
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the tool arguments.
+	InputSchema json.RawMessage
+
+	server string
+	tool   string
+}
+
+// transport exchanges JSON-RPC messages with one MCP server.
+type transport interface {
+	// call sends a request and returns the result of its response.
+	call(ctx context.Context, method string, params any) (gjson.Result, error)
+	// notify sends a notification.
+	notify(ctx context.Context, method string, params any) error
+	close() error
+}
+
+// server is a lazily connected MCP server.
+type server struct {
+	cfg config.MCPServer
+
+	mu        sync.Mutex
+	conn      transport
+	newTransp func(config.MCPServer) (transport, error)
+
+	toolsMu sync.Mutex
+	tools   []Tool
+	toolsAt time.Time
+}
+
+// Pool holds the configured MCP servers.
+type Pool struct {
+	mu      sync.Mutex
+	servers map[string]*server
+}
+
+// NewPool returns an empty pool; call Update to configure servers.
+func NewPool() *Pool {
+	return &Pool{servers: make(map[string]*server)}
+}
+
+// Update reconciles the pool with the configured servers. Servers that were removed or whose
+// settings changed are disconnected.
+func (p *Pool) Update(servers []config.MCPServer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	next := make(map[string]*server, len(servers))
+	for _, cfg := range servers {
+		if existing, ok := p.servers[cfg.Name]; ok && reflect.DeepEqual(existing.cfg, cfg) {
+			next[cfg.Name] = existing
+			continue
+		}
+		next[cfg.Name] = &server{cfg: cfg, newTransp: newTransport}
+	}
+	for name, existing := range p.servers {
+		if next[name] != existing {
+			existing.disconnect()
+		}
+	}
+	p.servers = next
+}
+
+// Tools returns the tools of every configured server. Servers that cannot be reached are
+// logged and skipped.
+func (p *Pool) Tools(ctx context.Context) []Tool {
+	p.mu.Lock()
+	servers := make([]*server, 0, len(p.servers))
+	for _, s := range p.servers {
+		servers = append(servers, s)
+	}
+	p.mu.Unlock()
+	slices.SortFunc(servers, func(a, b *server) int { return strings.Compare(a.cfg.Name, b.cfg.Name) })
+
+	var tools []Tool
+	for _, s := range servers {
+		serverTools, err := s.listTools(ctx)
+		if err != nil {
+			log.Warnf("mcp-tools: listing tools of %s failed: %v", s.cfg.Name, err)
+			continue
+		}
+		tools = append(tools, serverTools...)
+	}
+	return tools
+}
+
+// Call runs tool with the JSON arguments and returns the text of its result. A result the
+// server flags as an error is returned as an error carrying that text.
+func (p *Pool) Call(ctx context.Context, tool Tool, arguments string) (string, error) {
+	p.mu.Lock()
+	s, ok := p.servers[tool.server]
+	p.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("mcp server %s is no longer configured", tool.server)
+	}
+	return s.callTool(ctx, tool.tool, arguments)
+}
+
+// Close disconnects every server.
+func (p *Pool) Close() {
+	p.Update(nil)
+}
+
+// IsToolName reports whether name is a function name used for MCP tools.
+func IsToolName(name string) bool {
+	return strings.HasPrefix(name, toolNamePrefix)
+}
+
+// toolName builds the function name offered to models for tool of server.
+func toolName(server, tool string) string {
+	name := toolNamePrefix + unsafeToolNameChars.ReplaceAllString(server, "_") + "__" + unsafeToolNameChars.ReplaceAllString(tool, "_")
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+func (s *server) listTools(ctx context.Context) ([]Tool, error) {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	if s.tools != nil && time.Since(s.toolsAt) < toolsTTL {
+		return s.tools, nil
+	}
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		result, err := s.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Get("tools").Array() {
+			name := item.Get("name").String()
+			if name == "" || (len(s.cfg.Tools) > 0 && !slices.Contains(s.cfg.Tools, name)) {
+				continue
+			}
+			schema := json.RawMessage(item.Get("inputSchema").Raw)
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			tools = append(tools, Tool{
+				Name:        toolName(s.cfg.Name, name),
+				Description: item.Get("description").String(),
+				InputSchema: schema,
+				server:      s.cfg.Name,
+				tool:        name,
+			})
+		}
+		if cursor = result.Get("nextCursor").String(); cursor == "" {
+			break
+		}
+	}
+	if tools == nil {
+		tools = []Tool{}
+	}
+	s.tools, s.toolsAt = tools, time.Now()
+	return tools, nil
+}
+
+func (s *server) callTool(ctx context.Context, name, arguments string) (string, error) {
+	args := json.RawMessage(`{}`)
+	if strings.TrimSpace(arguments) != "" {
+		if !json.Valid([]byte(arguments)) {
+			return "", fmt.Errorf("tool arguments are not valid JSON")
+		}
+		args = json.RawMessage(arguments)
+	}
+	result, err := s.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for _, item := range result.Get("content").Array() {
+		switch item.Get("type").String() {
+		case "text":
+			parts = append(parts, item.Get("text").String())
+		case "resource":
+			if text := item.Get("resource.text"); text.Exists() {
+				parts = append(parts, text.String())
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content omitted]", item.Get("type").String()))
+		}
+	}
+	if len(parts) == 0 {
+		if structured := result.Get("structuredContent"); structured.Exists() {
+			parts = append(parts, structured.Raw)
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if result.Get("isError").Bool() {
+		if text == "" {
+			text = "tool failed"
+		}
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// call connects and initializes the server when needed and sends a request. A connection
+// that fails is dropped so the next call reconnects.
+func (s *server) call(ctx context.Context, method string, params any) (gjson.Result, error) {
+	timeout := defaultTimeout
+	if s.cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(s.cfg.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	result, err := conn.call(ctx, method, params)
+	var rpcErr *rpcError
+	if err != nil && !errors.As(err, &rpcErr) {
+		s.mu.Lock()
+		if s.conn == conn {
+			_ = conn.close()
+			s.conn = nil
+		}
+		s.mu.Unlock()
+	}
+	return result, err
+}
+
+func (s *server) connect(ctx context.Context) (transport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	conn, err := s.newTransp(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.call(ctx, "initialize", map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "cli-proxy-api", "version": buildinfo.Version},
+	})
+	if err == nil {
+		err = conn.notify(ctx, "notifications/initialized", map[string]any{})
+	}
+	if err != nil {
+		_ = conn.close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+func (s *server) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		_ = s.conn.close()
+		s.conn = nil
+	}
+}
+
+func newTransport(cfg config.MCPServer) (transport, error) {
+	if cfg.URL != "" {
+		return newHTTPTransport(cfg), nil
+	}
+	return newStdioTransport(cfg)
+}
+
+// rpcError is a JSON-RPC error returned by a server.
+type rpcError struct {
+	Code    int64
+	Message string
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// requestBody encodes a JSON-RPC request; a zero id encodes a notification.
+func requestBody(id int64, method string, params any) ([]byte, error) {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	if id != 0 {
+		msg["id"] = id
+	}
+	return json.Marshal(msg)
+}
+
+// responseResult extracts the result of a JSON-RPC response.
+func responseResult(resp gjson.Result) (gjson.Result, error) {
+	if errObj := resp.Get("error"); errObj.Exists() {
+		return gjson.Result{}, &rpcError{Code: errObj.Get("code").Int(), Message: errObj.Get("message").String()}
+	}
+	return resp.Get("result"), nil
+}
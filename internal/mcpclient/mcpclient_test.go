@@ -0,0 +1,96 @@
+package mcpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// newTestServer serves an MCP server over HTTP with an echo and a failing tool.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		msg := gjson.ParseBytes(body)
+		if !msg.Get("id").Exists() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		result := `{}`
+		switch msg.Get("method").String() {
+		case "initialize":
+			w.Header().Set("Mcp-Session-Id", "session-1")
+			result = `{"protocolVersion":"2025-03-26","capabilities":{"tools":{}},"serverInfo":{"name":"test"}}`
+		case "tools/list":
+			result = `{"tools":[{"name":"echo","description":"Echo text","inputSchema":{"type":"object","properties":{"text":{"type":"string"}}}},{"name":"fail.now"},{"name":"hidden"}]}`
+		case "tools/call":
+			if r.Header.Get("Mcp-Session-Id") != "session-1" {
+				http.Error(w, "missing session", http.StatusBadRequest)
+				return
+			}
+			if msg.Get("params.name").String() == "echo" {
+				result = `{"content":[{"type":"text","text":"echo: ` + msg.Get("params.arguments.text").String() + `"}]}`
+			} else {
+				result = `{"content":[{"type":"text","text":"boom"}],"isError":true}`
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":"+msg.Get("id").Raw+",\"result\":"+result+"}\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPoolToolsAndCall(t *testing.T) {
+	srv := newTestServer(t)
+	pool := NewPool()
+	defer pool.Close()
+	pool.Update([]config.MCPServer{{Name: "test server", URL: srv.URL, Tools: []string{"echo", "fail.now"}}})
+
+	tools := pool.Tools(context.Background())
+	if len(tools) != 2 {
+		t.Fatalf("tools = %d, want 2", len(tools))
+	}
+	if tools[0].Name != "mcp__test_server__echo" || tools[1].Name != "mcp__test_server__fail_now" {
+		t.Fatalf("tool names = %q, %q", tools[0].Name, tools[1].Name)
+	}
+	if !IsToolName(tools[0].Name) {
+		t.Fatalf("IsToolName(%q) = false", tools[0].Name)
+	}
+	if got := gjson.GetBytes(tools[1].InputSchema, "type").String(); got != "object" {
+		t.Fatalf("default schema type = %q, want object", got)
+	}
+
+	out, err := pool.Call(context.Background(), tools[0], `{"text":"hi"}`)
+	if err != nil || out != "echo: hi" {
+		t.Fatalf("Call echo = %q, %v", out, err)
+	}
+	if _, err = pool.Call(context.Background(), tools[1], ``); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Call fail.now error = %v, want boom", err)
+	}
+	if _, err = pool.Call(context.Background(), tools[0], `{not json`); err == nil {
+		t.Fatal("Call with invalid arguments succeeded")
+	}
+
+	pool.Update(nil)
+	if _, err = pool.Call(context.Background(), tools[0], `{}`); err == nil {
+		t.Fatal("Call after removing the server succeeded")
+	}
+}
+
+func TestToolNameIsCapped(t *testing.T) {
+	name := toolName(strings.Repeat("s", 40), strings.Repeat("t", 40))
+	if len(name) != maxToolNameLength || !IsToolName(name) {
+		t.Fatalf("toolName = %q", name)
+	}
+}
@@ -0,0 +1,305 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// maxMessageBytes bounds one JSON-RPC message read from a server.
+const maxMessageBytes = 16 << 20
+
+// httpTransport speaks the streamable HTTP transport. Responses may arrive as JSON or as an
+// SSE stream carrying the response message.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	nextID  atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPTransport(cfg config.MCPServer) *httpTransport {
+	return &httpTransport{url: cfg.URL, headers: cfg.Headers, client: &http.Client{}}
+}
+
+func (t *httpTransport) call(ctx context.Context, method string, params any) (gjson.Result, error) {
+	id := t.nextID.Add(1)
+	body, err := requestBody(id, method, params)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	resp, err := t.post(ctx, body)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return gjson.Result{}, fmt.Errorf("%s returned %d: %s", method, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		data, errRead := io.ReadAll(io.LimitReader(resp.Body, maxMessageBytes))
+		if errRead != nil {
+			return gjson.Result{}, errRead
+		}
+		return responseResult(gjson.ParseBytes(data))
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		msg := gjson.Parse(strings.TrimSpace(data))
+		if msg.Get("id").Int() == id && (msg.Get("result").Exists() || msg.Get("error").Exists()) {
+			return responseResult(msg)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.Result{}, fmt.Errorf("%s: stream ended without a response", method)
+}
+
+func (t *httpTransport) notify(ctx context.Context, method string, params any) error {
+	body, err := requestBody(0, method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := t.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", method, resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *httpTransport) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+	return t.client.Do(req)
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	// Ending the session is best effort; servers may not support it.
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// stdioTransport runs a server as a subprocess and exchanges newline-delimited JSON-RPC
+// messages over its stdin and stdout.
+type stdioTransport struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	nextID atomic.Int64
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan gjson.Result
+	done    chan struct{}
+	err     error
+}
+
+func newStdioTransport(cfg config.MCPServer) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+	}
+	t := &stdioTransport{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan gjson.Result),
+		done:    make(chan struct{}),
+	}
+	go t.readStderr(stderr)
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, method string, params any) (gjson.Result, error) {
+	id := t.nextID.Add(1)
+	body, err := requestBody(id, method, params)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	ch := make(chan gjson.Result, 1)
+	t.mu.Lock()
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err = t.write(body); err != nil {
+		return gjson.Result{}, err
+	}
+	select {
+	case msg := <-ch:
+		return responseResult(msg)
+	case <-t.done:
+		return gjson.Result{}, t.exitErr()
+	case <-ctx.Done():
+		return gjson.Result{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, method string, params any) error {
+	body, err := requestBody(0, method, params)
+	if err != nil {
+		return err
+	}
+	return t.write(body)
+}
+
+func (t *stdioTransport) write(body []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	select {
+	case <-t.done:
+		return t.exitErr()
+	default:
+	}
+	_, err := t.stdin.Write(append(body, '\n'))
+	return err
+}
+
+// readLoop delivers responses to their callers. Requests from the server are answered with
+// method not found, except ping.
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+	for scanner.Scan() {
+		msg := gjson.ParseBytes(scanner.Bytes())
+		id := msg.Get("id")
+		if !id.Exists() {
+			continue
+		}
+		if method := msg.Get("method").String(); method != "" {
+			reply := `{"jsonrpc":"2.0","id":` + id.Raw + `,"error":{"code":-32601,"message":"method not found"}}`
+			if method == "ping" {
+				reply = `{"jsonrpc":"2.0","id":` + id.Raw + `,"result":{}}`
+			}
+			_ = t.write([]byte(reply))
+			continue
+		}
+		t.mu.Lock()
+		ch, ok := t.pending[id.Int()]
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+	errScan := scanner.Err()
+	if errScan != nil && t.cmd.Process != nil {
+		// The server is unusable once its output cannot be parsed.
+		_ = t.cmd.Process.Kill()
+	}
+	err := t.cmd.Wait()
+	t.mu.Lock()
+	if errScan != nil {
+		err = errScan
+	}
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Debugf("mcp-tools: %s: %s", t.name, scanner.Text())
+	}
+}
+
+func (t *stdioTransport) exitErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return fmt.Errorf("mcp server %s exited: %w", t.name, t.err)
+	}
+	return fmt.Errorf("mcp server %s exited", t.name)
+}
+
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+	select {
+	case <-t.done:
+		return nil
+	default:
+	}
+	if t.cmd.Process != nil {
+		return t.cmd.Process.Kill()
+	}
+	return nil
+}
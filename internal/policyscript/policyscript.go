@@ -0,0 +1,320 @@
+// Package policyscript runs operator-written Starlark request policy scripts under time, step,
+// input size and allocation limits. Starlark cannot bound memory itself, since a single step
+// such as "x" * n can allocate a large value, so scripts with an allocation limit run in worker
+// processes that are killed once a call allocates more than allowed; see worker.go.
+//
+// A script defines on_request, on_response or both at the top level:
+//
+//	def on_request(req):
+//	    # req: model, format, client_key_hash, headers (lower-cased names), body (decoded JSON)
+//	    if req["model"].startswith("gpt-4") and req["client_key_hash"] == "79891e980747ffbd":
+//	        return {"reject": "trial keys cannot use gpt-4", "status": 403}
+//	    return {"model": "gpt-4o-mini"}
+//
+//	def on_response(resp):
+//	    # resp: model, format, client_key_hash, body (decoded JSON)
+//	    return None
+//
+// Returning None leaves the request or response unchanged. The json module of the Starlark
+// standard library is predeclared.
+package policyscript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	requestHook  = "on_request"
+	responseHook = "on_response"
+)
+
+// Limits bound a single script call. Zero values disable the corresponding limit.
+type Limits struct {
+	// Timeout is the wall-clock time the call may take.
+	Timeout time.Duration
+	// MaxSteps is the number of Starlark execution steps the call may take.
+	MaxSteps uint64
+	// MaxInputBytes is the largest body passed to the call; larger bodies fail it before it
+	// runs, since decoding them for the script costs memory of its own.
+	MaxInputBytes int
+	// MaxAllocBytes is the heap memory the call may allocate. Programs compiled with it set
+	// run in worker processes, including their top level.
+	MaxAllocBytes int64
+}
+
+// Request is the view of a client request passed to on_request.
+type Request struct {
+	Model  string
+	Format string
+	// ClientKeyHash identifies the client API key without revealing it; see util.ClientKeyHash.
+	ClientKeyHash string
+	Headers       map[string]string
+	Body          []byte
+}
+
+// Decision is what on_request asked for. Zero fields leave the request unchanged.
+type Decision struct {
+	// Model replaces the requested model, routing the request elsewhere.
+	Model string
+	// Body replaces the request body.
+	Body []byte
+	// Reject, when set, fails the request with this message.
+	Reject string
+	// Status is the HTTP status used with Reject.
+	Status int
+}
+
+// Response is the view of a non-streaming response passed to on_response.
+type Response struct {
+	Model         string
+	Format        string
+	ClientKeyHash string
+	Body          []byte
+}
+
+// Program is a compiled policy script. It is safe for concurrent use.
+type Program struct {
+	name    string
+	globals starlark.StringDict
+	// source and hooks are set instead of globals for programs that run in workers.
+	source string
+	hooks  map[string]bool
+}
+
+// Compile executes the top level of a script within limits and freezes its globals. With an
+// allocation limit the script is compiled, and later called, in a worker process.
+func Compile(name, source string, limits Limits) (*Program, error) {
+	if limits.MaxAllocBytes <= 0 {
+		return compile(name, source, limits)
+	}
+	reply, err := workers.call(context.Background(), limits, workerRequest{Name: name, Source: source})
+	if err != nil {
+		return nil, err
+	}
+	program := &Program{name: name, source: source, hooks: make(map[string]bool, len(reply.Hooks))}
+	for _, hook := range reply.Hooks {
+		program.hooks[hook] = true
+	}
+	return program, nil
+}
+
+func compile(name, source string, limits Limits) (*Program, error) {
+	program := &Program{name: name}
+	thread, stop := program.newThread(context.Background(), limits)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, source, starlark.StringDict{"json": starlarkjson.Module})
+	stop()
+	if err != nil {
+		return nil, fmt.Errorf("policy script %s: %w", name, err)
+	}
+	for _, hook := range []string{requestHook, responseHook} {
+		if fn, ok := globals[hook]; ok {
+			if _, callable := fn.(starlark.Callable); !callable {
+				return nil, fmt.Errorf("policy script %s: %s is a %s, not a function", name, hook, fn.Type())
+			}
+		}
+	}
+	globals.Freeze()
+	program.globals = globals
+	return program, nil
+}
+
+// HasRequestHook reports whether the script defines on_request.
+func (p *Program) HasRequestHook() bool {
+	return p != nil && (p.globals[requestHook] != nil || p.hooks[requestHook])
+}
+
+// HasResponseHook reports whether the script defines on_response.
+func (p *Program) HasResponseHook() bool {
+	return p != nil && (p.globals[responseHook] != nil || p.hooks[responseHook])
+}
+
+// OnRequest calls on_request. Scripts without the function return an empty decision.
+func (p *Program) OnRequest(ctx context.Context, limits Limits, req Request) (Decision, error) {
+	if !p.HasRequestHook() {
+		return Decision{}, nil
+	}
+	if p.globals == nil {
+		reply, err := workers.call(ctx, limits, workerRequest{Name: p.name, Source: p.source, Hook: requestHook, Request: &req})
+		if err != nil || reply.Decision == nil {
+			return Decision{}, err
+		}
+		return *reply.Decision, nil
+	}
+	headers := starlark.NewDict(len(req.Headers))
+	for name, value := range req.Headers {
+		_ = headers.SetKey(starlark.String(name), starlark.String(value))
+	}
+	out, err := p.call(ctx, limits, requestHook, req.Body, func(arg *starlark.Dict) {
+		_ = arg.SetKey(starlark.String("model"), starlark.String(req.Model))
+		_ = arg.SetKey(starlark.String("format"), starlark.String(req.Format))
+		_ = arg.SetKey(starlark.String("client_key_hash"), starlark.String(req.ClientKeyHash))
+		_ = arg.SetKey(starlark.String("headers"), headers)
+	})
+	if err != nil || out == nil {
+		return Decision{}, err
+	}
+	var decision Decision
+	if decision.Model, err = stringField(out, "model"); err != nil {
+		return Decision{}, p.errorf(requestHook, err)
+	}
+	if decision.Reject, err = stringField(out, "reject"); err != nil {
+		return Decision{}, p.errorf(requestHook, err)
+	}
+	if value, found, _ := out.Get(starlark.String("status")); found && value != starlark.None {
+		status, ok := value.(starlark.Int)
+		if !ok {
+			return Decision{}, p.errorf(requestHook, fmt.Errorf("status must be an int, got %s", value.Type()))
+		}
+		if code, exact := status.Int64(); exact {
+			decision.Status = int(code)
+		}
+	}
+	if decision.Body, err = p.bodyField(ctx, limits, out); err != nil {
+		return Decision{}, p.errorf(requestHook, err)
+	}
+	return decision, nil
+}
+
+// OnResponse calls on_response and returns the replacement body, or nil to keep the response.
+func (p *Program) OnResponse(ctx context.Context, limits Limits, resp Response) ([]byte, error) {
+	if !p.HasResponseHook() {
+		return nil, nil
+	}
+	if p.globals == nil {
+		reply, err := workers.call(ctx, limits, workerRequest{Name: p.name, Source: p.source, Hook: responseHook, Response: &resp})
+		return reply.Body, err
+	}
+	out, err := p.call(ctx, limits, responseHook, resp.Body, func(arg *starlark.Dict) {
+		_ = arg.SetKey(starlark.String("model"), starlark.String(resp.Model))
+		_ = arg.SetKey(starlark.String("format"), starlark.String(resp.Format))
+		_ = arg.SetKey(starlark.String("client_key_hash"), starlark.String(resp.ClientKeyHash))
+	})
+	if err != nil || out == nil {
+		return nil, err
+	}
+	body, err := p.bodyField(ctx, limits, out)
+	if err != nil {
+		return nil, p.errorf(responseHook, err)
+	}
+	return body, nil
+}
+
+// call runs hook with a dict holding the decoded body plus the fields set by fill, and returns
+// the dict the hook returned, or nil for None.
+func (p *Program) call(ctx context.Context, limits Limits, hook string, body []byte, fill func(arg *starlark.Dict)) (*starlark.Dict, error) {
+	if limits.MaxInputBytes > 0 && len(body) > limits.MaxInputBytes {
+		return nil, p.errorf(hook, fmt.Errorf("body of %d bytes exceeds the input limit of %d bytes", len(body), limits.MaxInputBytes))
+	}
+	thread, stop := p.newThread(ctx, limits)
+	defer stop()
+
+	arg := starlark.NewDict(5)
+	fill(arg)
+	decoded := starlark.Value(starlark.None)
+	if len(body) > 0 {
+		var err error
+		decoded, err = starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(body)}, nil)
+		if err != nil {
+			return nil, p.errorf(hook, fmt.Errorf("decode body: %w", err))
+		}
+	}
+	_ = arg.SetKey(starlark.String("body"), decoded)
+
+	result, err := starlark.Call(thread, p.globals[hook], starlark.Tuple{arg}, nil)
+	if err != nil {
+		return nil, p.errorf(hook, err)
+	}
+	if result == starlark.None {
+		return nil, nil
+	}
+	out, ok := result.(*starlark.Dict)
+	if !ok {
+		return nil, p.errorf(hook, fmt.Errorf("must return a dict or None, got %s", result.Type()))
+	}
+	return out, nil
+}
+
+// bodyField encodes the "body" entry of a hook result: a string is taken as raw JSON, any
+// other value is encoded as JSON. A missing or None body yields nil.
+func (p *Program) bodyField(ctx context.Context, limits Limits, out *starlark.Dict) ([]byte, error) {
+	value, found, _ := out.Get(starlark.String("body"))
+	if !found || value == starlark.None {
+		return nil, nil
+	}
+	if raw, ok := value.(starlark.String); ok {
+		return []byte(string(raw)), nil
+	}
+	thread, stop := p.newThread(ctx, limits)
+	defer stop()
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{value}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encode body: %w", err)
+	}
+	return []byte(string(encoded.(starlark.String))), nil
+}
+
+// newThread returns a thread enforcing limits and ctx cancellation. stop must be called when
+// the thread is done.
+func (p *Program) newThread(ctx context.Context, limits Limits) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name:  p.name,
+		Print: func(_ *starlark.Thread, msg string) { log.Debugf("policy script %s: %s", p.name, msg) },
+	}
+	if limits.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(limits.MaxSteps)
+	}
+	done := make(chan struct{})
+	var timeout <-chan time.Time
+	var timer *time.Timer
+	if limits.Timeout > 0 {
+		timer = time.NewTimer(limits.Timeout)
+		timeout = timer.C
+	}
+	go func() {
+		if timer != nil {
+			defer timer.Stop()
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				thread.Cancel("request canceled")
+				return
+			case <-timeout:
+				thread.Cancel(fmt.Sprintf("time limit of %s exceeded", limits.Timeout))
+				return
+			}
+		}
+	}()
+	return thread, func() { close(done) }
+}
+
+func stringField(out *starlark.Dict, key string) (string, error) {
+	value, found, _ := out.Get(starlark.String(key))
+	if !found || value == starlark.None {
+		return "", nil
+	}
+	str, ok := starlark.AsString(value)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %s", key, value.Type())
+	}
+	return strings.TrimSpace(str), nil
+}
+
+func (p *Program) errorf(hook string, err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return fmt.Errorf("policy script %s: %s: %s", p.name, hook, evalErr.Backtrace())
+	}
+	return fmt.Errorf("policy script %s: %s: %w", p.name, hook, err)
+}
@@ -0,0 +1,145 @@
+package policyscript
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLimits = Limits{Timeout: time.Second, MaxSteps: 100000, MaxInputBytes: 1 << 20}
+
+func TestOnRequestDecision(t *testing.T) {
+	program, err := Compile("policy.star", `
+def on_request(req):
+    if req["headers"].get("x-tier") == "free":
+        return {"reject": "upgrade required", "status": 402}
+    body = req["body"]
+    body["max_tokens"] = min(body.get("max_tokens", 4096), 1024)
+    return {"model": "cheap-" + req["model"], "body": body}
+`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if !program.HasRequestHook() || program.HasResponseHook() {
+		t.Fatalf("hooks = %v/%v, want request only", program.HasRequestHook(), program.HasResponseHook())
+	}
+
+	decision, err := program.OnRequest(context.Background(), testLimits, Request{Model: "m1", Body: []byte(`{"max_tokens":8000}`)})
+	if err != nil {
+		t.Fatalf("OnRequest() error = %v", err)
+	}
+	if decision.Model != "cheap-m1" || string(decision.Body) != `{"max_tokens":1024}` || decision.Reject != "" {
+		t.Fatalf("decision = %+v (body %s)", decision, decision.Body)
+	}
+
+	decision, err = program.OnRequest(context.Background(), testLimits, Request{Model: "m1", Headers: map[string]string{"x-tier": "free"}})
+	if err != nil {
+		t.Fatalf("OnRequest() error = %v", err)
+	}
+	if decision.Reject != "upgrade required" || decision.Status != 402 {
+		t.Fatalf("decision = %+v, want rejection with 402", decision)
+	}
+}
+
+func TestOnResponseReplacesBody(t *testing.T) {
+	program, err := Compile("policy.star", `
+def on_response(resp):
+    body = resp["body"]
+    body.pop("system_fingerprint", None)
+    return {"body": body}
+`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	body, err := program.OnResponse(context.Background(), testLimits, Response{Body: []byte(`{"id":"r1","system_fingerprint":"fp"}`)})
+	if err != nil || string(body) != `{"id":"r1"}` {
+		t.Fatalf("OnResponse() = %s, %v", body, err)
+	}
+}
+
+func TestLimitsStopRunawayScripts(t *testing.T) {
+	source := `
+def on_request(req):
+    n = 0
+    for i in range(100000000):
+        n += i
+    return None
+`
+	program, err := Compile("loop.star", source, testLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err = program.OnRequest(context.Background(), Limits{MaxSteps: 1000}, Request{}); err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Fatalf("OnRequest() error = %v, want step limit", err)
+	}
+	if _, err = program.OnRequest(context.Background(), Limits{Timeout: 20 * time.Millisecond}, Request{}); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Fatalf("OnRequest() error = %v, want time limit", err)
+	}
+
+	program, err = Compile("input.star", `
+def on_request(req):
+    return None
+`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	body := []byte(`{"input":"` + strings.Repeat("x", 2048) + `"}`)
+	if _, err = program.OnRequest(context.Background(), Limits{Timeout: time.Second, MaxInputBytes: 1024}, Request{Body: body}); err == nil || !strings.Contains(err.Error(), "input limit") {
+		t.Fatalf("OnRequest() error = %v, want input limit", err)
+	}
+}
+
+func TestAllocationLimitKillsScripts(t *testing.T) {
+	limits := Limits{Timeout: 5 * time.Second, MaxSteps: 100000, MaxInputBytes: 1 << 20, MaxAllocBytes: 16 << 20}
+	program, err := Compile("alloc.star", `
+def on_request(req):
+    if req["model"] == "big":
+        blob = "x" * (256 << 20)
+        return {"model": "big-" + str(len(blob))}
+    return {"model": "small-" + req["model"]}
+`, limits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if !program.HasRequestHook() || program.HasResponseHook() {
+		t.Fatalf("hooks = %v/%v, want request only", program.HasRequestHook(), program.HasResponseHook())
+	}
+
+	decision, err := program.OnRequest(context.Background(), limits, Request{Model: "m1", Body: []byte(`{"input":"hi"}`)})
+	if err != nil || decision.Model != "small-m1" {
+		t.Fatalf("OnRequest() = %+v, %v, want small-m1", decision, err)
+	}
+	if _, err = program.OnRequest(context.Background(), limits, Request{Model: "big"}); err == nil || !strings.Contains(err.Error(), "allocation limit") {
+		t.Fatalf("OnRequest() error = %v, want allocation limit", err)
+	}
+	// The killed worker is replaced for later calls.
+	if decision, err = program.OnRequest(context.Background(), limits, Request{Model: "m2"}); err != nil || decision.Model != "small-m2" {
+		t.Fatalf("OnRequest() = %+v, %v, want small-m2", decision, err)
+	}
+
+	if _, err = Compile("alloc-top.star", "blob = \"x\" * (256 << 20)\n", limits); err == nil || !strings.Contains(err.Error(), "allocation limit") {
+		t.Fatalf("Compile() error = %v, want allocation limit", err)
+	}
+}
+
+func TestCompileRejectsInvalidScripts(t *testing.T) {
+	for _, source := range []string{"def on_request(req)\n", "on_request = 1\n", "x = 1 // 0\n"} {
+		if _, err := Compile("bad.star", source, testLimits); err == nil {
+			t.Fatalf("Compile(%q) succeeded, want error", source)
+		}
+	}
+}
+
+func TestOnRequestRejectsBadResults(t *testing.T) {
+	program, err := Compile("bad.star", `
+def on_request(req):
+    return {"model": 7}
+`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err = program.OnRequest(context.Background(), testLimits, Request{}); err == nil {
+		t.Fatalf("OnRequest() succeeded, want error for a non-string model")
+	}
+}
@@ -0,0 +1,265 @@
+package policyscript
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Scripts with an allocation limit run in worker processes: copies of the running binary
+// started with workerEnv set, which this package's init turns into a loop serving calls over
+// stdin and stdout. A worker measures the heap allocations of each call and exits as soon as
+// they pass the limit, so a runaway script costs a worker rather than the proxy.
+
+const (
+	workerEnv = "CLIPROXY_POLICY_SCRIPT_WORKER"
+	// workerGrace is how much longer than the call's timeout the parent waits for a worker
+	// before killing it; the worker enforces the timeout itself.
+	workerGrace = time.Second
+	// workerPoll is how often a worker checks the allocations of the running call.
+	workerPoll = time.Millisecond
+	// maxWorkerPrograms bounds the compiled programs a worker keeps.
+	maxWorkerPrograms = 64
+)
+
+func init() {
+	if os.Getenv(workerEnv) == "1" {
+		serveWorker(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+}
+
+type workerRequest struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	// Hook is the function to call; empty only compiles the script.
+	Hook     string    `json:"hook,omitempty"`
+	Limits   Limits    `json:"limits"`
+	Request  *Request  `json:"request,omitempty"`
+	Response *Response `json:"response,omitempty"`
+}
+
+type workerReply struct {
+	Error    string    `json:"error,omitempty"`
+	Hooks    []string  `json:"hooks,omitempty"`
+	Decision *Decision `json:"decision,omitempty"`
+	Body     []byte    `json:"body,omitempty"`
+	// Exited is set when the worker exits after this reply.
+	Exited bool `json:"exited,omitempty"`
+}
+
+// serveWorker answers calls read from r until r is closed or a call exceeds its allocation limit.
+func serveWorker(r io.Reader, w io.Writer) {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	programs := make(map[[sha256.Size]byte]*Program)
+	for {
+		var req workerRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		start := heapAllocs()
+		done := make(chan workerReply, 1)
+		go func() { done <- handleWorkerRequest(programs, req) }()
+		reply, exceeded := awaitWithinAllocs(done, start, req.Limits.MaxAllocBytes)
+		if exceeded {
+			reply = workerReply{Error: fmt.Sprintf("policy script %s: allocation limit of %d bytes exceeded", req.Name, req.Limits.MaxAllocBytes), Exited: true}
+		}
+		if err := enc.Encode(reply); err != nil || exceeded {
+			return
+		}
+	}
+}
+
+// awaitWithinAllocs waits for the call to finish, reporting whether it allocated more than limit
+// bytes since start, in which case it stops waiting as soon as that happens.
+func awaitWithinAllocs(done <-chan workerReply, start uint64, limit int64) (workerReply, bool) {
+	over := func() bool { return limit > 0 && heapAllocs()-start > uint64(limit) }
+	ticker := time.NewTicker(workerPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case reply := <-done:
+			return reply, over()
+		case <-ticker.C:
+			if over() {
+				return workerReply{}, true
+			}
+		}
+	}
+}
+
+func handleWorkerRequest(programs map[[sha256.Size]byte]*Program, req workerRequest) workerReply {
+	limits := req.Limits
+	limits.MaxAllocBytes = 0
+	key := sha256.Sum256([]byte(req.Name + "\x00" + req.Source))
+	program := programs[key]
+	if program == nil {
+		var err error
+		if program, err = compile(req.Name, req.Source, limits); err != nil {
+			return workerReply{Error: err.Error()}
+		}
+		if len(programs) >= maxWorkerPrograms {
+			clear(programs)
+		}
+		programs[key] = program
+	}
+	switch {
+	case req.Hook == "":
+		var hooks []string
+		for _, hook := range []string{requestHook, responseHook} {
+			if program.globals[hook] != nil {
+				hooks = append(hooks, hook)
+			}
+		}
+		return workerReply{Hooks: hooks}
+	case req.Hook == requestHook && req.Request != nil:
+		decision, err := program.OnRequest(context.Background(), limits, *req.Request)
+		if err != nil {
+			return workerReply{Error: err.Error()}
+		}
+		return workerReply{Decision: &decision}
+	case req.Hook == responseHook && req.Response != nil:
+		body, err := program.OnResponse(context.Background(), limits, *req.Response)
+		if err != nil {
+			return workerReply{Error: err.Error()}
+		}
+		return workerReply{Body: body}
+	default:
+		return workerReply{Error: fmt.Sprintf("policy script %s: unknown hook %q", req.Name, req.Hook)}
+	}
+}
+
+var heapAllocsSample = []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+
+// heapAllocs returns the bytes allocated on the heap since the process started. Workers run one
+// call at a time, so the growth during a call is what the call allocated.
+func heapAllocs() uint64 {
+	metrics.Read(heapAllocsSample)
+	return heapAllocsSample[0].Value.Uint64()
+}
+
+type worker struct {
+	cmd   *exec.Cmd
+	stdin io.Closer
+	enc   *json.Encoder
+	dec   *json.Decoder
+}
+
+func startWorker() (*worker, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("policy script worker: %w", err)
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = []string{workerEnv + "=1"}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("policy script worker: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("policy script worker: %w", err)
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("policy script worker: %w", err)
+	}
+	return &worker{cmd: cmd, stdin: stdin, enc: json.NewEncoder(stdin), dec: json.NewDecoder(stdout)}, nil
+}
+
+func (w *worker) kill() {
+	_ = w.stdin.Close()
+	_ = w.cmd.Process.Kill()
+	go func() { _ = w.cmd.Wait() }()
+}
+
+// workerPool keeps idle workers for reuse; a worker serves one call at a time.
+type workerPool struct {
+	mu   sync.Mutex
+	idle []*worker
+}
+
+var workers workerPool
+
+func (p *workerPool) get() (*worker, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		w := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return w, nil
+	}
+	p.mu.Unlock()
+	return startWorker()
+}
+
+func (p *workerPool) put(w *worker) {
+	p.mu.Lock()
+	if len(p.idle) < runtime.GOMAXPROCS(0) {
+		p.idle = append(p.idle, w)
+		w = nil
+	}
+	p.mu.Unlock()
+	if w != nil {
+		w.kill()
+	}
+}
+
+// call runs req in a worker. A worker that exceeded a limit, timed out or was abandoned on
+// cancellation is killed instead of being reused.
+func (p *workerPool) call(ctx context.Context, limits Limits, req workerRequest) (workerReply, error) {
+	w, err := p.get()
+	if err != nil {
+		return workerReply{}, err
+	}
+	req.Limits = limits
+	type result struct {
+		reply workerReply
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		if res.err = w.enc.Encode(req); res.err == nil {
+			res.err = w.dec.Decode(&res.reply)
+		}
+		done <- res
+	}()
+	var timeout <-chan time.Time
+	if limits.Timeout > 0 {
+		timer := time.NewTimer(limits.Timeout + workerGrace)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case res := <-done:
+		if res.err != nil {
+			w.kill()
+			return workerReply{}, fmt.Errorf("policy script %s: worker: %w", req.Name, res.err)
+		}
+		if res.reply.Exited {
+			w.kill()
+		} else {
+			p.put(w)
+		}
+		if res.reply.Error != "" {
+			return res.reply, errors.New(res.reply.Error)
+		}
+		return res.reply, nil
+	case <-ctx.Done():
+		w.kill()
+		return workerReply{}, fmt.Errorf("policy script %s: %w", req.Name, ctx.Err())
+	case <-timeout:
+		w.kill()
+		return workerReply{}, fmt.Errorf("policy script %s: time limit of %s exceeded", req.Name, limits.Timeout)
+	}
+}
@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	"sort"
+	"strings"
+)
+
+// ModelMetadata summarizes the capabilities of a model as seen by the router.
+// It merges provider-reported ModelInfo with operator overrides from configuration.
+type ModelMetadata struct {
+	// ID is the client-visible model identifier.
+	ID string `json:"id"`
+	// OwnedBy is the organization that owns the model.
+	OwnedBy string `json:"owned_by,omitempty"`
+	// Providers lists the provider identifiers currently serving the model.
+	Providers []string `json:"providers,omitempty"`
+	// ContextWindow is the input context size in tokens (0 when unknown).
+	ContextWindow int `json:"context_window,omitempty"`
+	// MaxOutputTokens is the maximum completion size in tokens (0 when unknown).
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+	// SupportsTools reports whether the model accepts tool/function definitions.
+	SupportsTools bool `json:"supports_tools"`
+	// SupportsVision reports whether the model accepts image input.
+	SupportsVision bool `json:"supports_vision"`
+	// SupportsReasoning reports whether the model exposes a reasoning/thinking budget.
+	SupportsReasoning bool `json:"supports_reasoning"`
+}
+
+// ModelMetadataOverride replaces selected capabilities for models matching Pattern.
+// Zero values and nil pointers leave the discovered value unchanged.
+type ModelMetadataOverride struct {
+	// Pattern matches model IDs case-insensitively; '*' matches any substring.
+	Pattern           string
+	ContextWindow     int
+	MaxOutputTokens   int
+	SupportsTools     *bool
+	SupportsVision    *bool
+	SupportsReasoning *bool
+}
+
+// SetModelMetadataOverrides installs configuration overrides, replacing any previous set.
+// Later entries take precedence over earlier ones when several patterns match.
+func (r *ModelRegistry) SetModelMetadataOverrides(overrides []ModelMetadataOverride) {
+	r.metadataMu.Lock()
+	defer r.metadataMu.Unlock()
+	r.metadataOverrides = append([]ModelMetadataOverride(nil), overrides...)
+}
+
+// GetModelMetadata returns the merged capabilities of a model. The second result is false
+// when the model is unknown to both the registry and the overrides.
+func (r *ModelRegistry) GetModelMetadata(modelID string) (ModelMetadata, bool) {
+	modelID = strings.TrimSpace(modelID)
+	if modelID == "" {
+		return ModelMetadata{}, false
+	}
+	r.mutex.RLock()
+	reg := r.models[modelID]
+	var meta ModelMetadata
+	found := false
+	if reg != nil && reg.Info != nil {
+		meta = metadataFromRegistration(modelID, reg)
+		found = true
+	}
+	r.mutex.RUnlock()
+	if !found {
+		if info := LookupStaticModelInfo(modelID); info != nil {
+			meta = metadataFromInfo(modelID, info)
+			found = true
+		} else {
+			meta = ModelMetadata{ID: modelID}
+		}
+	}
+	if r.applyMetadataOverrides(&meta) {
+		found = true
+	}
+	return meta, found
+}
+
+// ListModelMetadata returns merged capabilities for every registered model, sorted by ID.
+func (r *ModelRegistry) ListModelMetadata() []ModelMetadata {
+	r.mutex.RLock()
+	out := make([]ModelMetadata, 0, len(r.models))
+	for modelID, reg := range r.models {
+		if reg == nil || reg.Info == nil || reg.Count <= 0 {
+			continue
+		}
+		out = append(out, metadataFromRegistration(modelID, reg))
+	}
+	r.mutex.RUnlock()
+	for i := range out {
+		r.applyMetadataOverrides(&out[i])
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// capabilitiesMap renders the capability fields embedded in enriched model listings.
+func (m ModelMetadata) capabilitiesMap() map[string]any {
+	result := map[string]any{
+		"supports_tools":     m.SupportsTools,
+		"supports_vision":    m.SupportsVision,
+		"supports_reasoning": m.SupportsReasoning,
+	}
+	if m.ContextWindow > 0 {
+		result["context_window"] = m.ContextWindow
+	}
+	if m.MaxOutputTokens > 0 {
+		result["max_output_tokens"] = m.MaxOutputTokens
+	}
+	if len(m.Providers) > 0 {
+		result["providers"] = m.Providers
+	}
+	return result
+}
+
+// metadataForListingLocked builds capabilities for a registration; callers hold r.mutex.
+func (r *ModelRegistry) metadataForListingLocked(modelID string, reg *ModelRegistration) map[string]any {
+	meta := metadataFromRegistration(modelID, reg)
+	r.applyMetadataOverrides(&meta)
+	return meta.capabilitiesMap()
+}
+
+func (r *ModelRegistry) applyMetadataOverrides(meta *ModelMetadata) bool {
+	r.metadataMu.RLock()
+	defer r.metadataMu.RUnlock()
+	matched := false
+	for _, override := range r.metadataOverrides {
+		if !wildcard.Match(override.Pattern, meta.ID) {
+			continue
+		}
+		matched = true
+		if override.ContextWindow > 0 {
+			meta.ContextWindow = override.ContextWindow
+		}
+		if override.MaxOutputTokens > 0 {
+			meta.MaxOutputTokens = override.MaxOutputTokens
+		}
+		if override.SupportsTools != nil {
+			meta.SupportsTools = *override.SupportsTools
+		}
+		if override.SupportsVision != nil {
+			meta.SupportsVision = *override.SupportsVision
+		}
+		if override.SupportsReasoning != nil {
+			meta.SupportsReasoning = *override.SupportsReasoning
+		}
+	}
+	return matched
+}
+
+func metadataFromRegistration(modelID string, reg *ModelRegistration) ModelMetadata {
+	meta := metadataFromInfo(modelID, reg.Info)
+	providers := make([]string, 0, len(reg.Providers))
+	for provider, count := range reg.Providers {
+		if count > 0 {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+	meta.Providers = providers
+	return meta
+}
+
+func metadataFromInfo(modelID string, info *ModelInfo) ModelMetadata {
+	meta := ModelMetadata{
+		ID:                modelID,
+		OwnedBy:           info.OwnedBy,
+		ContextWindow:     info.ContextWindow(),
+		MaxOutputTokens:   info.MaxCompletionTokens,
+		SupportsTools:     true,
+		SupportsReasoning: info.Thinking != nil,
+		SupportsVision:    inferVisionSupport(modelID, info),
+	}
+	if meta.MaxOutputTokens <= 0 {
+		meta.MaxOutputTokens = info.OutputTokenLimit
+	}
+	if len(info.SupportedParameters) > 0 {
+		meta.SupportsTools = containsFold(info.SupportedParameters, "tools")
+		if containsFold(info.SupportedParameters, "reasoning") || containsFold(info.SupportedParameters, "reasoning_effort") {
+			meta.SupportsReasoning = true
+		}
+	}
+	return meta
+}
+
+// inferVisionSupport guesses image input support from the model family when providers do not report it.
+func inferVisionSupport(modelID string, info *ModelInfo) bool {
+	id := strings.ToLower(modelID)
+	switch strings.ToLower(info.Type) {
+	case "claude", "gemini", "gemini-cli", "vertex", "aistudio", "antigravity":
+		return !strings.Contains(id, "embedding") && !strings.Contains(id, "-tts")
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-5", "o3", "o4"} {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(id, "vision") || strings.Contains(id, "-vl")
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package registry
+
+import "testing"
+
+func TestModelMetadataMergesProviderInfoAndOverrides(t *testing.T) {
+	r := newTestModelRegistry()
+	r.RegisterClient("client-a", "openai", []*ModelInfo{{
+		ID:                  "local-llama",
+		OwnedBy:             "local",
+		Type:                "openai",
+		ContextLength:       8192,
+		SupportedParameters: []string{"temperature"},
+	}})
+
+	meta, ok := r.GetModelMetadata("local-llama")
+	if !ok {
+		t.Fatal("expected registered model to have metadata")
+	}
+	if meta.ContextWindow != 8192 || meta.SupportsTools {
+		t.Fatalf("unexpected discovered metadata: %+v", meta)
+	}
+	if len(meta.Providers) != 1 || meta.Providers[0] != "openai" {
+		t.Fatalf("providers = %v", meta.Providers)
+	}
+
+	tools := true
+	r.SetModelMetadataOverrides([]ModelMetadataOverride{{Pattern: "local-*", ContextWindow: 32768, SupportsTools: &tools}})
+	meta, _ = r.GetModelMetadata("local-llama")
+	if meta.ContextWindow != 32768 || !meta.SupportsTools {
+		t.Fatalf("override not applied: %+v", meta)
+	}
+
+	models := r.GetAvailableModels("openai")
+	if len(models) != 1 {
+		t.Fatalf("expected one model, got %d", len(models))
+	}
+	capabilities, ok := models[0]["capabilities"].(map[string]any)
+	if !ok || capabilities["context_window"] != 32768 {
+		t.Fatalf("expected enriched capabilities, got %v", models[0]["capabilities"])
+	}
+}
+
+func TestModelMetadataUnknownModel(t *testing.T) {
+	r := newTestModelRegistry()
+	if _, ok := r.GetModelMetadata("does-not-exist"); ok {
+		t.Fatal("expected unknown model to report not found")
+	}
+}
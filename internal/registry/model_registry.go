@@ -114,6 +114,11 @@ type ModelRegistry struct {
 	mutex *sync.RWMutex
 	// hook is an optional callback sink for model registration changes
 	hook ModelRegistryHook
+	// metadataOverrides holds configured capability overrides, guarded by metadataMu
+	metadataOverrides []ModelMetadataOverride
+	metadataMu        sync.RWMutex
+	// hideExhausted omits models whose every client is quota-exhausted or suspended from listings
+	hideExhausted bool
 }
 
 // Global model registry instance
@@ -152,6 +157,46 @@ func LookupModelInfo(modelID string, provider ...string) *ModelInfo {
 	return LookupStaticModelInfo(modelID)
 }
 
+// ContextWindow returns the model's input context size in tokens, or 0 when unknown.
+func (m *ModelInfo) ContextWindow() int {
+	if m == nil {
+		return 0
+	}
+	if m.ContextLength > 0 {
+		return m.ContextLength
+	}
+	return m.InputTokenLimit
+}
+
+// LookupContextWindow returns the context size of modelID, including configured overrides,
+// or 0 when the model is unknown or does not advertise one.
+func LookupContextWindow(modelID string) int {
+	meta, ok := GetGlobalRegistry().GetModelMetadata(modelID)
+	if !ok {
+		return 0
+	}
+	return meta.ContextWindow
+}
+
+// SetHideExhaustedModels controls whether models served only by quota-exhausted or
+// suspended clients are omitted from available model listings.
+func (r *ModelRegistry) SetHideExhaustedModels(hide bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.hideExhausted = hide
+}
+
+// ClientIDs returns the IDs of all clients with registered models.
+func (r *ModelRegistry) ClientIDs() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ids := make([]string, 0, len(r.clientModels))
+	for clientID := range r.clientModels {
+		ids = append(ids, clientID)
+	}
+	return ids
+}
+
 // SetHook sets an optional hook for observing model registration changes.
 func (r *ModelRegistry) SetHook(hook ModelRegistryHook) {
 	if r == nil {
@@ -734,14 +779,20 @@ func (r *ModelRegistry) GetAvailableModels(handlerType string) []map[string]any
 		}
 
 		effectiveClients := availableClients - expiredClients - otherSuspended
+		if r.hideExhausted {
+			effectiveClients -= cooldownSuspended
+		}
 		if effectiveClients < 0 {
 			effectiveClients = 0
 		}
 
 		// Include models that have available clients, or those solely cooling down.
-		if effectiveClients > 0 || (availableClients > 0 && (expiredClients > 0 || cooldownSuspended > 0) && otherSuspended == 0) {
+		if effectiveClients > 0 || (!r.hideExhausted && availableClients > 0 && (expiredClients > 0 || cooldownSuspended > 0) && otherSuspended == 0) {
 			model := r.convertModelToMap(registration.Info, handlerType)
 			if model != nil {
+				if handlerType == "openai" {
+					model["capabilities"] = r.metadataForListingLocked(registration.Info.ID, registration)
+				}
 				models = append(models, model)
 			}
 		}
@@ -823,16 +874,22 @@ func (r *ModelRegistry) GetAvailableModelsForClients(handlerType string, clients
 			continue
 		}
 		effective := c.total - c.expired - c.otherPaused
+		if r.hideExhausted {
+			effective -= c.cooldown
+		}
 		if effective < 0 {
 			effective = 0
 		}
 		if effective == 0 {
-			if !(c.total > 0 && (c.expired > 0 || c.cooldown > 0) && c.otherPaused == 0) {
+			if r.hideExhausted || !(c.total > 0 && (c.expired > 0 || c.cooldown > 0) && c.otherPaused == 0) {
 				continue
 			}
 		}
 		model := r.convertModelToMap(reg.Info, handlerType)
 		if model != nil {
+			if handlerType == "openai" {
+				model["capabilities"] = r.metadataForListingLocked(modelID, reg)
+			}
 			models = append(models, model)
 		}
 	}
@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestHideExhaustedModelsOmitsQuotaExceededModels(t *testing.T) {
+	r := newTestModelRegistry()
+	r.RegisterClient("client-a", "openai", []*ModelInfo{{ID: "model-a"}, {ID: "model-b"}})
+	r.SetModelQuotaExceeded("client-a", "model-a")
+
+	if got := len(r.GetAvailableModels("openai")); got != 2 {
+		t.Fatalf("expected cooling-down model to be listed by default, got %d models", got)
+	}
+
+	r.SetHideExhaustedModels(true)
+	models := r.GetAvailableModels("openai")
+	if len(models) != 1 || models[0]["id"] != "model-b" {
+		t.Fatalf("expected only model-b, got %v", models)
+	}
+	if got := r.GetAvailableModelsForClients("openai", map[string]struct{}{"client-a": {}}); len(got) != 1 {
+		t.Fatalf("expected only one model for client-a, got %d", len(got))
+	}
+
+	r.ClearModelQuotaExceeded("client-a", "model-a")
+	if got := len(r.GetAvailableModels("openai")); got != 2 {
+		t.Fatalf("expected model-a to reappear after quota reset, got %d models", got)
+	}
+}
+
+func TestClientIDsListsRegisteredClients(t *testing.T) {
+	r := newTestModelRegistry()
+	r.RegisterClient("client-a", "openai", []*ModelInfo{{ID: "model-a"}})
+	r.RegisterClient("client-b", "claude", []*ModelInfo{{ID: "model-b"}})
+	r.UnregisterClient("client-a")
+
+	ids := r.ClientIDs()
+	sort.Strings(ids)
+	if len(ids) != 1 || ids[0] != "client-b" {
+		t.Fatalf("ClientIDs() = %v, want [client-b]", ids)
+	}
+}
@@ -0,0 +1,336 @@
+// Package routingrules implements the declarative routing rules file: an ordered list of
+// conditions on the requested model, client API key, prompt size and time of day, each
+// routing matching requests to a provider, to auths carrying a tag or through a proxy.
+//
+//	rules:
+//	  - name: night-batch
+//	    when:
+//	      models: ["gpt-5*"]
+//	      client-keys: ["batch-key"]
+//	      min-tokens: 20000
+//	      hours: "22:00-06:00"
+//	      days: ["mon", "tue", "wed", "thu", "fri"]
+//	      timezone: "Europe/Berlin"
+//	    route:
+//	      provider: codex
+//	      auth-tag: batch
+//	      proxy: "socks5://127.0.0.1:1080"
+//
+// The first matching rule wins. Empty conditions match every request.
+package routingrules
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the content of a routing rules file.
+type File struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule routes the requests matching When as described by Route.
+type Rule struct {
+	Name  string    `yaml:"name,omitempty" json:"name,omitempty"`
+	When  Condition `yaml:"when,omitempty" json:"when,omitempty"`
+	Route Route     `yaml:"route" json:"route"`
+}
+
+// Condition lists what a request must satisfy for a rule to apply. Empty fields match anything.
+type Condition struct {
+	// Models are requested model patterns; '*' matches any substring.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+	// ClientKeys are client API keys.
+	ClientKeys []string `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+	// MinTokens and MaxTokens bound the estimated prompt tokens; zero leaves a side open.
+	MinTokens int `yaml:"min-tokens,omitempty" json:"min-tokens,omitempty"`
+	MaxTokens int `yaml:"max-tokens,omitempty" json:"max-tokens,omitempty"`
+	// Hours is a "HH:MM-HH:MM" window; a window ending before it starts wraps past midnight.
+	Hours string `yaml:"hours,omitempty" json:"hours,omitempty"`
+	// Days lists weekdays as three-letter names, e.g. "mon".
+	Days []string `yaml:"days,omitempty" json:"days,omitempty"`
+	// Timezone is the IANA zone Hours and Days are read in. Defaults to the server's zone.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+// Route is where matching requests go. Empty fields leave that part of routing unchanged.
+type Route struct {
+	// Provider limits the request to one provider serving the model.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// AuthTag limits the request to auths carrying this tag.
+	AuthTag string `yaml:"auth-tag,omitempty" json:"auth-tag,omitempty"`
+	// Proxy is the proxy URL upstream calls go through, overriding the auth's proxy.
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+// Input describes the request rules are evaluated against.
+type Input struct {
+	Model     string
+	ClientKey string
+	// Tokens is the estimated prompt size.
+	Tokens int
+	Time   time.Time
+}
+
+// Result is the outcome of evaluating a request.
+type Result struct {
+	Matched bool   `json:"matched"`
+	Rule    string `json:"rule,omitempty"`
+	Index   int    `json:"index"`
+	Route   Route  `json:"route"`
+	// Explanations lists, in order, why each rule up to the matching one did or did not apply.
+	Explanations []Explanation `json:"explanations"`
+}
+
+// Explanation tells why one rule did or did not match.
+type Explanation struct {
+	Rule    string `json:"rule"`
+	Index   int    `json:"index"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// RuleSet is a validated rules file ready for evaluation.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	location    *time.Location
+	startMinute int
+	endMinute   int
+	hasHours    bool
+	days        map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse validates a rules file.
+func Parse(data []byte) (*RuleSet, error) {
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("routing rules: %w", err)
+	}
+	set := &RuleSet{rules: make([]compiledRule, 0, len(file.Rules))}
+	for i, rule := range file.Rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("routing rules: rule %d (%s): %w", i, compiled.Name, err)
+		}
+		set.rules = append(set.rules, compiled)
+	}
+	return set, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Route.Provider = strings.ToLower(strings.TrimSpace(rule.Route.Provider))
+	rule.Route.AuthTag = strings.TrimSpace(rule.Route.AuthTag)
+	rule.Route.Proxy = strings.TrimSpace(rule.Route.Proxy)
+	compiled := compiledRule{Rule: rule, location: time.Local}
+	if rule.Route == (Route{}) {
+		return compiled, fmt.Errorf("route sets neither provider, auth-tag nor proxy")
+	}
+	if rule.When.MinTokens < 0 || rule.When.MaxTokens < 0 || (rule.When.MaxTokens > 0 && rule.When.MaxTokens < rule.When.MinTokens) {
+		return compiled, fmt.Errorf("invalid token range %d-%d", rule.When.MinTokens, rule.When.MaxTokens)
+	}
+	if tz := strings.TrimSpace(rule.When.Timezone); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return compiled, fmt.Errorf("timezone: %w", err)
+		}
+		compiled.location = location
+	}
+	if hours := strings.TrimSpace(rule.When.Hours); hours != "" {
+		from, to, ok := strings.Cut(hours, "-")
+		start, errStart := parseClock(from)
+		end, errEnd := parseClock(to)
+		if !ok || errStart != nil || errEnd != nil || start == end {
+			return compiled, fmt.Errorf("hours %q is not a HH:MM-HH:MM window", hours)
+		}
+		compiled.startMinute, compiled.endMinute, compiled.hasHours = start, end, true
+	}
+	if len(rule.When.Days) > 0 {
+		compiled.days = make(map[time.Weekday]bool, len(rule.When.Days))
+		for _, day := range rule.When.Days {
+			weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+			if !ok {
+				return compiled, fmt.Errorf("unknown day %q", day)
+			}
+			compiled.days[weekday] = true
+		}
+	}
+	return compiled, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(value string) (int, error) {
+	hour, minute, ok := strings.Cut(strings.TrimSpace(value), ":")
+	h, errHour := strconv.Atoi(hour)
+	m, errMinute := strconv.Atoi(minute)
+	if !ok || errHour != nil || errMinute != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// Len returns the number of rules.
+func (s *RuleSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// UsesTokens reports whether any rule conditions on the prompt size, so callers can skip
+// estimating it otherwise.
+func (s *RuleSet) UsesTokens() bool {
+	if s == nil {
+		return false
+	}
+	for _, rule := range s.rules {
+		if rule.When.MinTokens > 0 || rule.When.MaxTokens > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the first rule matching in, explaining every rule checked on the way.
+func (s *RuleSet) Evaluate(in Input) Result {
+	result := Result{Index: -1, Explanations: []Explanation{}}
+	if s == nil {
+		return result
+	}
+	if in.Time.IsZero() {
+		in.Time = time.Now()
+	}
+	for i, rule := range s.rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		reason := rule.mismatch(in)
+		matched := reason == ""
+		if matched {
+			reason = "all conditions met"
+		}
+		result.Explanations = append(result.Explanations, Explanation{Rule: name, Index: i, Matched: matched, Reason: reason})
+		if matched {
+			result.Matched, result.Rule, result.Index, result.Route = true, name, i, rule.Route
+			break
+		}
+	}
+	return result
+}
+
+// mismatch returns why in does not satisfy the rule's conditions, or "" when it does.
+func (r compiledRule) mismatch(in Input) string {
+	when := r.When
+	if len(when.Models) > 0 && !matchAny(when.Models, in.Model) {
+		return fmt.Sprintf("model %q matches none of %v", in.Model, when.Models)
+	}
+	if len(when.ClientKeys) > 0 && !containsString(when.ClientKeys, in.ClientKey) {
+		return "client key is not listed"
+	}
+	if when.MinTokens > 0 && in.Tokens < when.MinTokens {
+		return fmt.Sprintf("%d tokens is below min-tokens %d", in.Tokens, when.MinTokens)
+	}
+	if when.MaxTokens > 0 && in.Tokens > when.MaxTokens {
+		return fmt.Sprintf("%d tokens is above max-tokens %d", in.Tokens, when.MaxTokens)
+	}
+	local := in.Time.In(r.location)
+	if r.days != nil && !r.days[local.Weekday()] {
+		return fmt.Sprintf("%s is not one of %v", strings.ToLower(local.Weekday().String()[:3]), when.Days)
+	}
+	if r.hasHours {
+		minute := local.Hour()*60 + local.Minute()
+		inside := minute >= r.startMinute && minute < r.endMinute
+		if r.startMinute > r.endMinute {
+			inside = minute >= r.startMinute || minute < r.endMinute
+		}
+		if !inside {
+			return fmt.Sprintf("%s %s is outside %s", local.Format("15:04"), r.location, when.Hours)
+		}
+	}
+	return ""
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if wildcard.Match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.TrimSpace(candidate) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fileCache keeps the rule sets of loaded files, reparsing a file when it changes.
+var fileCache = struct {
+	sync.Mutex
+	entries map[string]*cachedFile
+}{entries: make(map[string]*cachedFile)}
+
+type cachedFile struct {
+	modTime time.Time
+	size    int64
+	set     *RuleSet
+	err     error
+}
+
+// Load returns the rule set of the file at path, reparsing it only when it changed since the
+// last call. An empty path yields an empty rule set. Errors are logged once per distinct error.
+func Load(path string) (*RuleSet, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+	fileCache.Lock()
+	defer fileCache.Unlock()
+	cached := fileCache.entries[path]
+	info, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("routing rules: %w", err)
+		if cached == nil || cached.err == nil || cached.err.Error() != err.Error() {
+			log.Errorf("%v", err)
+		}
+		fileCache.entries[path] = &cachedFile{err: err}
+		return nil, err
+	}
+	if cached != nil && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.set, cached.err
+	}
+	data, err := os.ReadFile(path)
+	var set *RuleSet
+	if err == nil {
+		set, err = Parse(data)
+	}
+	if err != nil {
+		log.Errorf("failed to load %s: %v", path, err)
+	} else {
+		log.Infof("routing rules loaded from %s (%d rules)", path, set.Len())
+	}
+	fileCache.entries[path] = &cachedFile{modTime: info.ModTime(), size: info.Size(), set: set, err: err}
+	return set, err
+}
@@ -0,0 +1,89 @@
+package routingrules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `
+rules:
+  - name: big-prompts
+    when:
+      models: ["gpt-5*"]
+      min-tokens: 20000
+    route:
+      provider: Codex
+      auth-tag: long-context
+  - name: night
+    when:
+      client-keys: ["batch-key"]
+      hours: "22:00-06:00"
+      days: ["mon", "tue"]
+      timezone: "UTC"
+    route:
+      proxy: "socks5://127.0.0.1:1080"
+`
+
+func TestEvaluateExplainsFirstMatch(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	monday := time.Date(2026, 10, 12, 23, 30, 0, 0, time.UTC)
+
+	result := rules.Evaluate(Input{Model: "gpt-5-codex", Tokens: 30000, Time: monday})
+	if !result.Matched || result.Rule != "big-prompts" || result.Route.Provider != "codex" || result.Route.AuthTag != "long-context" {
+		t.Fatalf("result = %+v", result)
+	}
+
+	result = rules.Evaluate(Input{Model: "gpt-5", ClientKey: "batch-key", Tokens: 100, Time: monday})
+	if !result.Matched || result.Index != 1 || result.Route.Proxy == "" || len(result.Explanations) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := result.Explanations[0].Reason; !strings.Contains(got, "below min-tokens") {
+		t.Fatalf("first explanation = %q", got)
+	}
+
+	result = rules.Evaluate(Input{Model: "gpt-5", ClientKey: "batch-key", Time: monday.Add(8 * time.Hour)})
+	if result.Matched || result.Index != -1 || !strings.Contains(result.Explanations[1].Reason, "outside 22:00-06:00") {
+		t.Fatalf("result = %+v", result)
+	}
+	result = rules.Evaluate(Input{Model: "gpt-5", ClientKey: "batch-key", Time: monday.AddDate(0, 0, 2)})
+	if result.Matched || !strings.Contains(result.Explanations[1].Reason, "wed is not one of") {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	for _, body := range []string{
+		"rules:\n  - name: empty\n",
+		"rules:\n  - when: {hours: \"25:00-02:00\"}\n    route: {provider: codex}\n",
+		"rules:\n  - when: {days: [\"someday\"]}\n    route: {provider: codex}\n",
+		"rules:\n  - when: {min-tokens: 10, max-tokens: 5}\n    route: {provider: codex}\n",
+		"rules:\n  - when: {timezone: \"Mars/Olympus\"}\n    route: {provider: codex}\n",
+	} {
+		if _, err := Parse([]byte(body)); err == nil {
+			t.Fatalf("Parse(%q) succeeded, want error", body)
+		}
+	}
+}
+
+func TestLoadReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	rules, err := Load(path)
+	if err != nil || rules.Len() != 2 || !rules.UsesTokens() {
+		t.Fatalf("Load() = %d rules, %v", rules.Len(), err)
+	}
+	if err = os.WriteFile(path, []byte("rules:\n  - route: {auth-tag: any}\n"), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	if rules, err = Load(path); err != nil || rules.Len() != 1 || rules.UsesTokens() {
+		t.Fatalf("Load() after change = %d rules, %v", rules.Len(), err)
+	}
+}
@@ -1,8 +1,13 @@
 package executor
 
 import (
+	"container/list"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 )
 
 type codexCache struct {
@@ -10,11 +15,34 @@ type codexCache struct {
 	Expire time.Time
 }
 
-// codexCacheMap stores prompt cache IDs keyed by model+user_id.
-// Protected by codexCacheMu. Entries expire after 1 hour.
-var (
-	codexCacheMap = make(map[string]codexCache)
-	codexCacheMu  sync.RWMutex
+// codexCacheEntry is a cached prompt cache ID with its bookkeeping.
+type codexCacheEntry struct {
+	key     string
+	cache   codexCache
+	created time.Time
+	lastHit time.Time
+	hits    int64
+}
+
+// codexCacheState stores prompt cache IDs keyed by model+user_id in least recently used order
+// (front is most recent), along with hit/miss counters.
+var codexCacheState = struct {
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	order       *list.List
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+}{
+	entries: make(map[string]*list.Element),
+	order:   list.New(),
+}
+
+// Defaults used when the executor runs without a sanitized config.
+const (
+	defaultCodexCacheTTL        = time.Hour
+	defaultCodexCacheMaxEntries = 10000
 )
 
 // codexCacheCleanupInterval controls how often expired entries are purged.
@@ -23,8 +51,44 @@ const codexCacheCleanupInterval = 15 * time.Minute
 // codexCacheCleanupOnce ensures the background cleanup goroutine starts only once.
 var codexCacheCleanupOnce sync.Once
 
+// CodexPromptCacheStats reports the hit rate of the Codex prompt cache.
+type CodexPromptCacheStats struct {
+	Entries     int     `json:"entries"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRate     float64 `json:"hit_rate"`
+	Evictions   int64   `json:"evictions"`
+	Expirations int64   `json:"expirations"`
+}
+
+// CodexPromptCacheEntry is one live prompt cache key.
+type CodexPromptCacheEntry struct {
+	Key       string     `json:"key"`
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+	Hits      int64      `json:"hits"`
+}
+
+// codexCacheTTL returns the configured lifetime of new prompt cache IDs.
+func codexCacheTTL(cfg *config.Config) time.Duration {
+	if cfg == nil || cfg.CodexPromptCache.TTLSeconds <= 0 {
+		return defaultCodexCacheTTL
+	}
+	return time.Duration(cfg.CodexPromptCache.TTLSeconds) * time.Second
+}
+
+// codexCacheMaxEntries returns the configured bound of the prompt cache.
+func codexCacheMaxEntries(cfg *config.Config) int {
+	if cfg == nil || cfg.CodexPromptCache.MaxEntries <= 0 {
+		return defaultCodexCacheMaxEntries
+	}
+	return cfg.CodexPromptCache.MaxEntries
+}
+
 // startCodexCacheCleanup launches a background goroutine that periodically
-// removes expired entries from codexCacheMap to prevent memory leaks.
+// removes expired entries from the cache to prevent memory leaks.
 func startCodexCacheCleanup() {
 	go func() {
 		ticker := time.NewTicker(codexCacheCleanupInterval)
@@ -38,31 +102,107 @@ func startCodexCacheCleanup() {
 // purgeExpiredCodexCache removes entries that have expired.
 func purgeExpiredCodexCache() {
 	now := time.Now()
-	codexCacheMu.Lock()
-	defer codexCacheMu.Unlock()
-	for key, cache := range codexCacheMap {
-		if cache.Expire.Before(now) {
-			delete(codexCacheMap, key)
+	codexCacheState.mu.Lock()
+	defer codexCacheState.mu.Unlock()
+	for key, elem := range codexCacheState.entries {
+		if elem.Value.(*codexCacheEntry).cache.Expire.Before(now) {
+			removeCodexCacheLocked(key, elem)
+			codexCacheState.expirations++
 		}
 	}
 }
 
+func removeCodexCacheLocked(key string, elem *list.Element) {
+	codexCacheState.order.Remove(elem)
+	delete(codexCacheState.entries, key)
+}
+
 // getCodexCache retrieves a cached entry, returning ok=false if not found or expired.
+// Lookups count towards the hit/miss metrics.
 func getCodexCache(key string) (codexCache, bool) {
 	codexCacheCleanupOnce.Do(startCodexCacheCleanup)
-	codexCacheMu.RLock()
-	cache, ok := codexCacheMap[key]
-	codexCacheMu.RUnlock()
-	if !ok || cache.Expire.Before(time.Now()) {
+	now := time.Now()
+	codexCacheState.mu.Lock()
+	defer codexCacheState.mu.Unlock()
+	elem, ok := codexCacheState.entries[key]
+	if !ok {
+		codexCacheState.misses++
+		return codexCache{}, false
+	}
+	entry := elem.Value.(*codexCacheEntry)
+	if entry.cache.Expire.Before(now) {
+		removeCodexCacheLocked(key, elem)
+		codexCacheState.expirations++
+		codexCacheState.misses++
 		return codexCache{}, false
 	}
-	return cache, true
+	codexCacheState.order.MoveToFront(elem)
+	codexCacheState.hits++
+	entry.hits++
+	entry.lastHit = now
+	return entry.cache, true
 }
 
-// setCodexCache stores a cache entry.
-func setCodexCache(key string, cache codexCache) {
+// setCodexCache stores a cache entry, evicting the least recently used entries beyond maxEntries.
+func setCodexCache(key string, cache codexCache, maxEntries int) {
 	codexCacheCleanupOnce.Do(startCodexCacheCleanup)
-	codexCacheMu.Lock()
-	codexCacheMap[key] = cache
-	codexCacheMu.Unlock()
+	codexCacheState.mu.Lock()
+	defer codexCacheState.mu.Unlock()
+	if elem, ok := codexCacheState.entries[key]; ok {
+		removeCodexCacheLocked(key, elem)
+	}
+	codexCacheState.entries[key] = codexCacheState.order.PushFront(&codexCacheEntry{key: key, cache: cache, created: time.Now()})
+	for maxEntries > 0 && codexCacheState.order.Len() > maxEntries {
+		oldest := codexCacheState.order.Back()
+		removeCodexCacheLocked(oldest.Value.(*codexCacheEntry).key, oldest)
+		codexCacheState.evictions++
+	}
+}
+
+// CodexPromptCacheSnapshot returns the prompt cache metrics and its live entries, most recently
+// used first. Entries whose key contains filter are listed; an empty filter lists all.
+func CodexPromptCacheSnapshot(filter string) (CodexPromptCacheStats, []CodexPromptCacheEntry) {
+	now := time.Now()
+	codexCacheState.mu.Lock()
+	defer codexCacheState.mu.Unlock()
+	stats := CodexPromptCacheStats{
+		Entries:     codexCacheState.order.Len(),
+		Hits:        codexCacheState.hits,
+		Misses:      codexCacheState.misses,
+		Evictions:   codexCacheState.evictions,
+		Expirations: codexCacheState.expirations,
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	entries := make([]CodexPromptCacheEntry, 0, stats.Entries)
+	for elem := codexCacheState.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*codexCacheEntry)
+		if entry.cache.Expire.Before(now) || !strings.Contains(entry.key, filter) {
+			continue
+		}
+		out := CodexPromptCacheEntry{Key: entry.key, ID: entry.cache.ID, CreatedAt: entry.created, ExpiresAt: entry.cache.Expire, Hits: entry.hits}
+		if !entry.lastHit.IsZero() {
+			lastHit := entry.lastHit
+			out.LastHitAt = &lastHit
+		}
+		entries = append(entries, out)
+	}
+	return stats, entries
+}
+
+// PurgeCodexPromptCache removes the prompt cache entries whose key contains filter, or every
+// entry when filter is empty, and returns the removed keys in sorted order.
+func PurgeCodexPromptCache(filter string) []string {
+	codexCacheState.mu.Lock()
+	defer codexCacheState.mu.Unlock()
+	removed := make([]string, 0)
+	for key, elem := range codexCacheState.entries {
+		if strings.Contains(key, filter) {
+			removeCodexCacheLocked(key, elem)
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	return removed
 }
@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCodexCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	PurgeCodexPromptCache("")
+	expire := time.Now().Add(time.Hour)
+	setCodexCache("lru-a", codexCache{ID: "a", Expire: expire}, 2)
+	setCodexCache("lru-b", codexCache{ID: "b", Expire: expire}, 2)
+	if _, ok := getCodexCache("lru-a"); !ok {
+		t.Fatal("expected lru-a to be cached")
+	}
+	before, _ := CodexPromptCacheSnapshot("lru-")
+	setCodexCache("lru-c", codexCache{ID: "c", Expire: expire}, 2)
+
+	if _, ok := getCodexCache("lru-b"); ok {
+		t.Fatal("expected lru-b to be evicted as least recently used")
+	}
+	if cache, ok := getCodexCache("lru-a"); !ok || cache.ID != "a" {
+		t.Fatalf("lru-a = %+v, %v, want cached", cache, ok)
+	}
+
+	stats, entries := CodexPromptCacheSnapshot("lru-")
+	if stats.Evictions != before.Evictions+1 {
+		t.Errorf("evictions = %d, want %d", stats.Evictions, before.Evictions+1)
+	}
+	if stats.Hits != before.Hits+1 || stats.Misses != before.Misses+1 {
+		t.Errorf("hits/misses = %d/%d, want %d/%d", stats.Hits, stats.Misses, before.Hits+1, before.Misses+1)
+	}
+	if len(entries) != 2 || entries[0].Key != "lru-a" || entries[0].Hits != 2 || entries[0].LastHitAt == nil {
+		t.Fatalf("entries = %+v, want lru-a first with 2 hits", entries)
+	}
+	if got := PurgeCodexPromptCache("lru-c"); len(got) != 1 || got[0] != "lru-c" {
+		t.Fatalf("purged = %v, want [lru-c]", got)
+	}
+}
+
+func TestCodexCacheExpiredEntryCountsAsMiss(t *testing.T) {
+	PurgeCodexPromptCache("")
+	setCodexCache("expired", codexCache{ID: "x", Expire: time.Now().Add(-time.Second)}, 10)
+	before, _ := CodexPromptCacheSnapshot("")
+	if _, ok := getCodexCache("expired"); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+	stats, entries := CodexPromptCacheSnapshot("")
+	if stats.Expirations != before.Expirations+1 || stats.Misses != before.Misses+1 || len(entries) != 0 {
+		t.Fatalf("stats = %+v, entries = %+v", stats, entries)
+	}
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const (
+	// chaosTruncateMinBytes and chaosTruncateSpanBytes bound where a truncated stream is cut.
+	chaosTruncateMinBytes  = 64
+	chaosTruncateSpanBytes = 4096
+)
+
+// chaosTransport injects the faults configured under chaos into upstream requests: synthetic
+// 429, 500 and 502 responses, and truncated or slowed streaming responses.
+type chaosTransport struct {
+	base     http.RoundTripper
+	cfg      config.ChaosConfig
+	provider string
+}
+
+// applyChaos wraps the transport of client with fault injection when chaos is enabled for
+// provider.
+func applyChaos(cfg *config.Config, provider string, client *http.Client) *http.Client {
+	if cfg == nil || !cfg.Chaos.Enabled || client == nil {
+		return client
+	}
+	if len(cfg.Chaos.Providers) > 0 && !slices.Contains(cfg.Chaos.Providers, provider) {
+		return client
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &chaosTransport{base: base, cfg: cfg.Chaos, provider: provider}
+	return client
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	roll := rand.Float64()
+	for _, fault := range []struct {
+		rate   float64
+		status int
+	}{
+		{t.cfg.RateLimitRate, http.StatusTooManyRequests},
+		{t.cfg.ServerErrorRate, http.StatusInternalServerError},
+		{t.cfg.ProxyErrorRate, http.StatusBadGateway},
+	} {
+		if roll < fault.rate {
+			logWithRequestID(req.Context()).Warnf("chaos: injecting %d for provider %s: %s", fault.status, t.provider, req.URL.Redacted())
+			return t.faultResponse(req, fault.status), nil
+		}
+		roll -= fault.rate
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "event-stream") {
+		return resp, err
+	}
+	body := &chaosBody{ReadCloser: resp.Body, ctx: req.Context(), remaining: -1}
+	if t.cfg.TruncateRate > 0 && rand.Float64() < t.cfg.TruncateRate {
+		body.remaining = int64(chaosTruncateMinBytes + rand.Intn(chaosTruncateSpanBytes))
+		logWithRequestID(req.Context()).Warnf("chaos: truncating stream for provider %s after %d bytes", t.provider, body.remaining)
+	}
+	if t.cfg.SlowChunkDelayMs > 0 && t.cfg.SlowChunkRate > 0 && rand.Float64() < t.cfg.SlowChunkRate {
+		body.delay = time.Duration(t.cfg.SlowChunkDelayMs) * time.Millisecond
+		logWithRequestID(req.Context()).Warnf("chaos: slowing stream for provider %s by %s per read", t.provider, body.delay)
+	}
+	if body.remaining >= 0 || body.delay > 0 {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+func (t *chaosTransport) faultResponse(req *http.Request, status int) *http.Response {
+	payload := fmt.Sprintf(`{"error":{"message":"chaos: injected %d %s","type":"chaos_fault"}}`, status, http.StatusText(status))
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if status == http.StatusTooManyRequests && t.cfg.RetryAfterSeconds > 0 {
+		header.Set("Retry-After", strconv.Itoa(t.cfg.RetryAfterSeconds))
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Request:       req,
+	}
+}
+
+// chaosBody delays each read by delay and fails with io.ErrUnexpectedEOF once remaining bytes
+// have been read. A negative remaining never truncates.
+type chaosBody struct {
+	io.ReadCloser
+	ctx       context.Context
+	remaining int64
+	delay     time.Duration
+}
+
+func (b *chaosBody) Read(p []byte) (int, error) {
+	if b.delay > 0 {
+		timer := time.NewTimer(b.delay)
+		select {
+		case <-b.ctx.Done():
+			timer.Stop()
+			return 0, b.ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if b.remaining == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if b.remaining > 0 && int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.remaining > 0 {
+		b.remaining -= int64(n)
+	}
+	return n, err
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestChaosInjectsStatusFaults(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Chaos: config.ChaosConfig{Enabled: true, RateLimitRate: 1, RetryAfterSeconds: 7}}
+	client := newProxyAwareHTTPClient(context.Background(), cfg, &cliproxyauth.Auth{Provider: "claude"}, 0)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "7" || hits != 0 {
+		t.Fatalf("status = %d, Retry-After = %q, upstream hits = %d", resp.StatusCode, resp.Header.Get("Retry-After"), hits)
+	}
+
+	cfg.Chaos = config.ChaosConfig{Enabled: true, ProxyErrorRate: 1, Providers: []string{"codex"}}
+	client = newProxyAwareHTTPClient(context.Background(), cfg, &cliproxyauth.Auth{Provider: "claude"}, 0)
+	if resp, err = client.Get(server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits != 1 {
+		t.Fatalf("expected providers filter to skip claude, status = %d", resp.StatusCode)
+	}
+	client = newProxyAwareHTTPClient(context.Background(), cfg, &cliproxyauth.Auth{Provider: "codex"}, 0)
+	if resp, err = client.Get(server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || !shouldBanReverseProxyOnError(resp.StatusCode, "") {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+}
+
+func TestChaosTruncatesStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, strings.Repeat("data: {\"x\":1}\n\n", 1000))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Chaos: config.ChaosConfig{Enabled: true, TruncateRate: 1}}
+	client := newProxyAwareHTTPClient(context.Background(), cfg, nil, 0)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadAll() error = %v after %d bytes", err, len(body))
+	}
+	if len(body) < chaosTruncateMinBytes || len(body) >= chaosTruncateMinBytes+chaosTruncateSpanBytes {
+		t.Fatalf("truncated after %d bytes", len(body))
+	}
+}
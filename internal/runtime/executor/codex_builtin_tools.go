@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// applyCodexBuiltinTools removes the Responses built-in tools that codex-builtin-tools strips
+// for the client key of ctx, together with a tool_choice forcing one of them and include
+// entries asking for their output.
+func applyCodexBuiltinTools(ctx context.Context, cfg *config.Config, body []byte) []byte {
+	if cfg == nil || (len(cfg.CodexBuiltinTools.Tools) == 0 && len(cfg.CodexBuiltinTools.KeyTools) == 0) {
+		return body
+	}
+	tools := gjson.GetBytes(body, "tools")
+	if !tools.IsArray() {
+		return body
+	}
+	policy := cfg.CodexBuiltinTools
+	keyTools, _ := config.LookupClientKey(&cfg.SDKConfig, policy.KeyTools, apiKeyFromContext(ctx))
+	stripped := func(toolType string) bool {
+		if toolType == "" || toolType == "function" || toolType == "custom" {
+			return false
+		}
+		if action := codexBuiltinToolAction(keyTools, toolType); action != "" {
+			return action == config.CodexBuiltinToolStrip
+		}
+		return codexBuiltinToolAction(policy.Tools, toolType) == config.CodexBuiltinToolStrip
+	}
+
+	kept := []byte(`[]`)
+	removed := map[string]struct{}{}
+	for _, tool := range tools.Array() {
+		toolType := tool.Get("type").String()
+		if stripped(toolType) {
+			removed[toolType] = struct{}{}
+			continue
+		}
+		kept, _ = sjson.SetRawBytes(kept, "-1", []byte(tool.Raw))
+	}
+	if len(removed) == 0 {
+		return body
+	}
+	if string(kept) == "[]" {
+		body, _ = sjson.DeleteBytes(body, "tools")
+	} else {
+		body, _ = sjson.SetRawBytes(body, "tools", kept)
+	}
+	if choice := gjson.GetBytes(body, "tool_choice"); choice.IsObject() && stripped(choice.Get("type").String()) {
+		body, _ = sjson.SetBytes(body, "tool_choice", "auto")
+	}
+	if include := gjson.GetBytes(body, "include"); include.IsArray() {
+		entries := make([]string, 0, len(include.Array()))
+		for _, entry := range include.Array() {
+			prefix, _, _ := strings.Cut(entry.String(), ".")
+			toolType, isToolOutput := strings.CutSuffix(prefix, "_call")
+			if !isToolOutput || !stripped(toolType) {
+				entries = append(entries, entry.String())
+			}
+		}
+		body, _ = sjson.SetBytes(body, "include", entries)
+	}
+	return body
+}
+
+// codexBuiltinToolAction returns the action of the most specific entry covering toolType:
+// the type itself or a prefix of it ending before "_" ("web_search" covers "web_search_preview").
+func codexBuiltinToolAction(actions map[string]string, toolType string) string {
+	for name := toolType; name != ""; {
+		if action, ok := actions[name]; ok {
+			return action
+		}
+		i := strings.LastIndexByte(name, '_')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyCodexBuiltinTools(t *testing.T) {
+	cfg := &config.Config{CodexBuiltinTools: config.CodexBuiltinToolsConfig{
+		Tools:    map[string]string{"web_search": "strip", "file_search": "strip"},
+		KeyTools: map[string]map[string]string{"trusted": {"web_search": "allow"}},
+	}}
+	body := []byte(`{"tools":[{"type":"web_search_preview"},{"type":"file_search","vector_store_ids":["vs_1"]},{"type":"function","name":"f"}],` +
+		`"tool_choice":{"type":"web_search_preview"},"include":["web_search_call.action.sources","file_search_call.results","reasoning.encrypted_content"]}`)
+
+	out := applyCodexBuiltinTools(context.Background(), cfg, body)
+	if got := gjson.GetBytes(out, "tools.#.type").Raw; got != `["function"]` {
+		t.Fatalf("tools = %s", got)
+	}
+	if got := gjson.GetBytes(out, "tool_choice").String(); got != "auto" {
+		t.Fatalf("tool_choice = %s", got)
+	}
+	if got := gjson.GetBytes(out, "include").Raw; got != `["reasoning.encrypted_content"]` {
+		t.Fatalf("include = %s", got)
+	}
+
+	ctx := cliproxyexecutor.WithRequestMetadata(context.Background(), &cliproxyexecutor.RequestMetadata{ClientKey: "trusted"})
+	out = applyCodexBuiltinTools(ctx, cfg, body)
+	if got := gjson.GetBytes(out, "tools.#.type").Raw; got != `["web_search_preview","function"]` {
+		t.Fatalf("trusted key tools = %s", got)
+	}
+	if got := gjson.GetBytes(out, "tool_choice.type").String(); got != "web_search_preview" {
+		t.Fatalf("trusted key tool_choice = %s", got)
+	}
+
+	if out = applyCodexBuiltinTools(context.Background(), &config.Config{}, body); string(out) != string(body) {
+		t.Fatalf("body changed without a policy: %s", out)
+	}
+}
@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCodexVersionRefresh = 6 * time.Hour
+	codexVersionFetchTimeout   = 10 * time.Second
+	// codexMaxMinorLead bounds how far ahead of the configured version a detected version may
+	// be, so a single odd User-Agent cannot drag the advertised version somewhere implausible.
+	codexMaxMinorLead = 50
+)
+
+var (
+	// codexUserAgentVersion matches the client/version token of a Codex client User-Agent, such
+	// as codex_cli_rs/0.98.0 or codex_vscode/0.98.0.
+	codexUserAgentVersion = regexp.MustCompile(`^(codex_[a-z_]+)/(\d+\.\d+\.\d+)`)
+	codexVersionInText    = regexp.MustCompile(`\d+\.\d+\.\d+`)
+)
+
+// codexClient holds the Codex CLI identity presented upstream.
+var codexClient = struct {
+	mu        sync.Mutex
+	cfg       config.CodexClientConfig
+	proxy     config.SDKConfig
+	detected  string
+	lastFetch time.Time
+	fetching  bool
+}{}
+
+// SetCodexClientConfig applies the codex-client settings. Versions detected under previous
+// settings are kept when auto-detection stays on.
+func SetCodexClientConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	codexClient.mu.Lock()
+	defer codexClient.mu.Unlock()
+	if codexClient.cfg.VersionURL != cfg.CodexClient.VersionURL {
+		codexClient.lastFetch = time.Time{}
+	}
+	if !cfg.CodexClient.AutoDetect {
+		codexClient.detected = ""
+	}
+	codexClient.cfg = cfg.CodexClient
+	codexClient.proxy = cfg.SDKConfig
+}
+
+// codexClientIdentity returns the Codex CLI version and default User-Agent sent upstream.
+func codexClientIdentity() (version string, userAgent string) {
+	codexClient.mu.Lock()
+	cfg := codexClient.cfg
+	version = codexBaseVersion(cfg)
+	if cfg.AutoDetect && newerCodexVersion(codexClient.detected, version) {
+		version = codexClient.detected
+	}
+	if cfg.AutoDetect && cfg.VersionURL != "" {
+		maybeRefreshCodexVersionLocked()
+	}
+	codexClient.mu.Unlock()
+
+	userAgent = defaultCodexUserAgent
+	if cfg.UserAgent != "" {
+		userAgent = cfg.UserAgent
+	}
+	if m := codexUserAgentVersion.FindStringSubmatchIndex(userAgent); m != nil {
+		userAgent = userAgent[:m[4]] + version + userAgent[m[5]:]
+	}
+	return version, userAgent
+}
+
+func codexBaseVersion(cfg config.CodexClientConfig) string {
+	if cfg.Version != "" {
+		return cfg.Version
+	}
+	return codexClientVersion
+}
+
+// observeCodexUserAgent records the version of a real Codex client User-Agent when
+// auto-detection is on.
+func observeCodexUserAgent(userAgent string) {
+	m := codexUserAgentVersion.FindStringSubmatch(strings.TrimSpace(userAgent))
+	if m == nil {
+		return
+	}
+	recordDetectedCodexVersion(m[2], "client User-Agent")
+}
+
+func recordDetectedCodexVersion(version string, source string) {
+	codexClient.mu.Lock()
+	defer codexClient.mu.Unlock()
+	if !codexClient.cfg.AutoDetect || !plausibleCodexVersion(version, codexBaseVersion(codexClient.cfg)) {
+		return
+	}
+	current := codexClient.detected
+	if current == "" {
+		current = codexBaseVersion(codexClient.cfg)
+	}
+	if !newerCodexVersion(version, current) {
+		return
+	}
+	codexClient.detected = version
+	log.Infof("codex client version updated to %s from %s", version, source)
+}
+
+// plausibleCodexVersion accepts versions with the same major as base and at most
+// codexMaxMinorLead minor releases ahead of it.
+func plausibleCodexVersion(version, base string) bool {
+	v, ok := parseCodexVersion(version)
+	if !ok {
+		return false
+	}
+	b, ok := parseCodexVersion(base)
+	if !ok {
+		return false
+	}
+	return v[0] == b[0] && v[1] <= b[1]+codexMaxMinorLead
+}
+
+// newerCodexVersion reports whether a is a higher version than b.
+func newerCodexVersion(a, b string) bool {
+	va, okA := parseCodexVersion(a)
+	vb, okB := parseCodexVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+func parseCodexVersion(version string) ([3]int, bool) {
+	var out [3]int
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// maybeRefreshCodexVersionLocked starts a background fetch of version-url when the last one
+// is older than the refresh interval. Callers hold codexClient.mu.
+func maybeRefreshCodexVersionLocked() {
+	interval := time.Duration(codexClient.cfg.VersionRefreshHours) * time.Hour
+	if interval <= 0 {
+		interval = defaultCodexVersionRefresh
+	}
+	if codexClient.fetching || time.Since(codexClient.lastFetch) < interval {
+		return
+	}
+	codexClient.fetching = true
+	codexClient.lastFetch = time.Now()
+	versionURL := codexClient.cfg.VersionURL
+	proxyCfg := codexClient.proxy
+	go func() {
+		version, err := fetchCodexVersion(versionURL, &proxyCfg)
+		codexClient.mu.Lock()
+		codexClient.fetching = false
+		codexClient.mu.Unlock()
+		if err != nil {
+			log.Debugf("codex client version fetch from %s failed: %v", versionURL, err)
+			return
+		}
+		recordDetectedCodexVersion(version, versionURL)
+	}()
+}
+
+// fetchCodexVersion reads the latest Codex CLI version from versionURL. GitHub release bodies
+// are read from tag_name; anything else is searched for the first version number.
+func fetchCodexVersion(versionURL string, proxyCfg *config.SDKConfig) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), codexVersionFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, versionURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := util.SetProxy(proxyCfg, &http.Client{})
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	text := string(body)
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if json.Unmarshal(body, &release) == nil && release.TagName != "" {
+		text = release.TagName
+	}
+	version := codexVersionInText.FindString(text)
+	if version == "" {
+		return "", fmt.Errorf("no version in response")
+	}
+	return version, nil
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestCodexClientIdentity_ConfiguredVersion(t *testing.T) {
+	t.Cleanup(func() { SetCodexClientConfig(&config.Config{}) })
+	SetCodexClientConfig(&config.Config{CodexClient: config.CodexClientConfig{Version: "0.99.1"}})
+
+	version, userAgent := codexClientIdentity()
+	if version != "0.99.1" {
+		t.Fatalf("version = %q", version)
+	}
+	if userAgent != "codex_cli_rs/0.99.1 (Mac OS 26.0.1; arm64) Apple_Terminal/464" {
+		t.Fatalf("user agent = %q", userAgent)
+	}
+}
+
+func TestCodexClientIdentity_AutoDetectsClientVersion(t *testing.T) {
+	t.Cleanup(func() { SetCodexClientConfig(&config.Config{}) })
+	SetCodexClientConfig(&config.Config{CodexClient: config.CodexClientConfig{
+		Version:    "0.98.0",
+		UserAgent:  "codex_cli_rs/0.98.0 (Ubuntu 24.4.0; x86_64) xterm",
+		AutoDetect: true,
+	}})
+
+	observeCodexUserAgent("codex_cli_rs/0.101.0 (Mac OS 26.1.0; arm64) iTerm.app/3.6")
+	observeCodexUserAgent("codex_cli_rs/0.99.0 (Mac OS 26.1.0; arm64) iTerm.app/3.6")
+	observeCodexUserAgent("codex_cli_rs/9.0.0 (spoofed)")
+	observeCodexUserAgent("curl/8.0.0")
+
+	version, userAgent := codexClientIdentity()
+	if version != "0.101.0" || userAgent != "codex_cli_rs/0.101.0 (Ubuntu 24.4.0; x86_64) xterm" {
+		t.Fatalf("identity = %q, %q", version, userAgent)
+	}
+
+	SetCodexClientConfig(&config.Config{CodexClient: config.CodexClientConfig{Version: "0.98.0"}})
+	if version, _ = codexClientIdentity(); version != "0.98.0" {
+		t.Fatalf("version after disabling auto-detect = %q", version)
+	}
+}
+
+func TestFetchCodexVersion_ReadsReleaseTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"tag_name":"rust-v0.102.3","name":"0.102.3"}`)
+	}))
+	defer server.Close()
+
+	version, err := fetchCodexVersion(server.URL, &config.SDKConfig{})
+	if err != nil {
+		t.Fatalf("fetchCodexVersion() error = %v", err)
+	}
+	if version != "0.102.3" {
+		t.Fatalf("version = %q", version)
+	}
+}
@@ -1,7 +1,6 @@
 package executor
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -15,7 +14,6 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
-	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
@@ -24,10 +22,11 @@ import (
 	"github.com/tidwall/sjson"
 	"github.com/tiktoken-go/tokenizer"
 
-	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// codexClientVersion and defaultCodexUserAgent are the built-in Codex CLI identity; the
+// codex-client settings override them.
 const (
 	codexClientVersion     = "0.98.0"
 	defaultCodexUserAgent  = "codex_cli_rs/0.98.0 (Mac OS 26.0.1; arm64) Apple_Terminal/464"
@@ -53,29 +52,27 @@ func (e *CodexExecutor) PrepareRequest(req *http.Request, auth *cliproxyauth.Aut
 	if req == nil {
 		return nil
 	}
-	ginHeaders := codexInboundHeaders(req.Context())
+	inboundHeaders := codexInboundHeaders(req.Context())
 	token, _ := codexCreds(auth)
 	if strings.TrimSpace(token) != "" {
 		req.Header.Set("Authorization", "Bearer "+token)
 	}
 	misc.EnsureHeader(req.Header, nil, "Content-Type", "application/json")
-	misc.EnsureHeader(req.Header, ginHeaders, "Openai-Beta", codexResponsesBeta)
-	misc.EnsureHeader(req.Header, ginHeaders, "Session_id", uuid.NewString())
-	misc.EnsureHeader(req.Header, ginHeaders, "User-Agent", defaultCodexUserAgent)
-	misc.EnsureHeader(req.Header, ginHeaders, "X-Client-Request-Id", uuid.NewString())
-	applyCodexPassthroughHeaders(req.Header, ginHeaders)
+	misc.EnsureHeader(req.Header, inboundHeaders, "Openai-Beta", codexResponsesBeta)
+	misc.EnsureHeader(req.Header, inboundHeaders, "Session_id", uuid.NewString())
+	_, userAgent := codexClientIdentity()
+	observeCodexUserAgent(inboundHeaders.Get("User-Agent"))
+	misc.EnsureHeader(req.Header, inboundHeaders, "User-Agent", userAgent)
+	misc.EnsureHeader(req.Header, inboundHeaders, "X-Client-Request-Id", uuid.NewString())
+	applyCodexPassthroughHeaders(req.Header, inboundHeaders)
 	if !codexUsesAPIKey(auth) {
-		misc.EnsureHeader(req.Header, ginHeaders, "Originator", defaultCodexOriginator)
+		misc.EnsureHeader(req.Header, inboundHeaders, "Originator", defaultCodexOriginator)
 		if accountID := resolveCodexAccountID(auth); accountID != "" {
 			req.Header.Set("Chatgpt-Account-Id", accountID)
 		}
 	}
 	applyReverseProxyHeaders(req, e.cfg, auth, e.Identifier())
-	var attrs map[string]string
-	if auth != nil {
-		attrs = auth.Attributes
-	}
-	util.ApplyCustomHeadersFromAttrs(req, attrs)
+	applyAuthCustomHeaders(req, auth)
 	return nil
 }
 
@@ -128,7 +125,9 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
+	body = applyCodexBuiltinTools(ctx, e.cfg, body)
+	body = applySystemPromptCacheKey(to.String(), opts, body)
 	body, _ = sjson.SetBytes(body, "model", baseModel)
 	body, _ = sjson.SetBytes(body, "stream", true)
 	body, _ = sjson.DeleteBytes(body, "prompt_cache_retention")
@@ -138,90 +137,10 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	}
 
 	originalURL := strings.TrimSuffix(baseURL, "/") + "/responses"
-	proxyRoute := resolveReverseProxyRouteForAuth(e.cfg, auth, "codex", originalURL)
-	url := proxyRoute.URL
-	httpReq, err := e.cacheHelper(ctx, from, url, req, opts, body)
+	httpResp, err := e.newPipeline(ctx, auth, from, req, opts, apiKey, true, body).send(ctx, originalURL)
 	if err != nil {
 		return resp, err
 	}
-	applyCodexHeaders(httpReq, auth, apiKey, true)
-	applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
-	var authID, authLabel, authType, authValue string
-	if auth != nil {
-		authID = auth.ID
-		authLabel = auth.Label
-		authType, authValue = auth.AccountInfo()
-	}
-	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-		URL:       url,
-		Method:    http.MethodPost,
-		Headers:   httpReq.Header.Clone(),
-		Body:      body,
-		Provider:  e.Identifier(),
-		AuthID:    authID,
-		AuthLabel: authLabel,
-		AuthType:  authType,
-		AuthValue: authValue,
-	})
-	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
-	httpResp, err := httpClient.Do(httpReq)
-	if err != nil {
-		recordAPIResponseError(ctx, e.cfg, err)
-		return resp, err
-	}
-	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		b, _ := io.ReadAll(httpResp.Body)
-		appendAPIResponseChunk(ctx, e.cfg, b)
-		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		if proxyRoute.Proxied && shouldBanReverseProxyOnError(httpResp.StatusCode, string(b)) {
-			banReverseProxyTemporarily(proxyRoute.ProxyID, e.Identifier(), httpResp.StatusCode, string(b))
-			if errClose := httpResp.Body.Close(); errClose != nil {
-				log.Errorf("codex executor: close response body error: %v", errClose)
-			}
-			fallbackURL := originalURL
-			logWithRequestID(ctx).Warnf("codex executor: reverse proxy failed, retrying direct upstream: %s", fallbackURL)
-			httpReq, err = e.cacheHelper(ctx, from, fallbackURL, req, opts, body)
-			if err != nil {
-				return resp, err
-			}
-			applyCodexHeaders(httpReq, auth, apiKey, true)
-			applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
-			recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-				URL:       fallbackURL,
-				Method:    http.MethodPost,
-				Headers:   httpReq.Header.Clone(),
-				Body:      body,
-				Provider:  e.Identifier(),
-				AuthID:    authID,
-				AuthLabel: authLabel,
-				AuthType:  authType,
-				AuthValue: authValue,
-			})
-			httpResp, err = httpClient.Do(httpReq)
-			if err != nil {
-				recordAPIResponseError(ctx, e.cfg, err)
-				return resp, err
-			}
-			recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-			if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-				b, _ := io.ReadAll(httpResp.Body)
-				appendAPIResponseChunk(ctx, e.cfg, b)
-				logWithRequestID(ctx).Debugf("retry request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-				if errClose := httpResp.Body.Close(); errClose != nil {
-					log.Errorf("codex executor: close response body error: %v", errClose)
-				}
-				err = newCodexStatusErr(ctx, httpClient, auth, httpResp.StatusCode, b, httpResp.Header)
-				return resp, err
-			}
-		} else {
-			if errClose := httpResp.Body.Close(); errClose != nil {
-				log.Errorf("codex executor: close response body error: %v", errClose)
-			}
-			err = newCodexStatusErr(ctx, httpClient, auth, httpResp.StatusCode, b, httpResp.Header)
-			return resp, err
-		}
-	}
 	defer func() {
 		if errClose := httpResp.Body.Close(); errClose != nil {
 			log.Errorf("codex executor: close response body error: %v", errClose)
@@ -284,38 +203,13 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 	body, _ = sjson.SetBytes(body, "model", baseModel)
 	body, _ = sjson.DeleteBytes(body, "stream")
 
 	url := strings.TrimSuffix(baseURL, "/") + "/responses/compact"
-	httpReq, err := e.cacheHelper(ctx, from, url, req, opts, body)
-	if err != nil {
-		return resp, err
-	}
-	applyCodexHeaders(httpReq, auth, apiKey, false)
-	applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
-	var authID, authLabel, authType, authValue string
-	if auth != nil {
-		authID = auth.ID
-		authLabel = auth.Label
-		authType, authValue = auth.AccountInfo()
-	}
-	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-		URL:       url,
-		Method:    http.MethodPost,
-		Headers:   httpReq.Header.Clone(),
-		Body:      body,
-		Provider:  e.Identifier(),
-		AuthID:    authID,
-		AuthLabel: authLabel,
-		AuthType:  authType,
-		AuthValue: authValue,
-	})
-	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
-	httpResp, err := httpClient.Do(httpReq)
+	httpResp, err := e.newPipeline(ctx, auth, from, req, opts, apiKey, false, body).sendDirect(ctx, url)
 	if err != nil {
-		recordAPIResponseError(ctx, e.cfg, err)
 		return resp, err
 	}
 	defer func() {
@@ -323,14 +217,6 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 			log.Errorf("codex executor: close response body error: %v", errClose)
 		}
 	}()
-	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		b, _ := io.ReadAll(httpResp.Body)
-		appendAPIResponseChunk(ctx, e.cfg, b)
-		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		err = newCodexStatusErr(ctx, httpClient, auth, httpResp.StatusCode, b, httpResp.Header)
-		return resp, err
-	}
 	data, err := io.ReadAll(httpResp.Body)
 	if err != nil {
 		recordAPIResponseError(ctx, e.cfg, err)
@@ -378,7 +264,9 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
+	body = applyCodexBuiltinTools(ctx, e.cfg, body)
+	body = applySystemPromptCacheKey(to.String(), opts, body)
 	body, _ = sjson.DeleteBytes(body, "prompt_cache_retention")
 	body, _ = sjson.DeleteBytes(body, "safety_identifier")
 	body, _ = sjson.SetBytes(body, "model", baseModel)
@@ -387,96 +275,10 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 	}
 
 	originalURL := strings.TrimSuffix(baseURL, "/") + "/responses"
-	proxyRoute := resolveReverseProxyRouteForAuth(e.cfg, auth, "codex", originalURL)
-	url := proxyRoute.URL
-	httpReq, err := e.cacheHelper(ctx, from, url, req, opts, body)
-	if err != nil {
-		return nil, err
-	}
-	applyCodexHeaders(httpReq, auth, apiKey, true)
-	applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
-	var authID, authLabel, authType, authValue string
-	if auth != nil {
-		authID = auth.ID
-		authLabel = auth.Label
-		authType, authValue = auth.AccountInfo()
-	}
-	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-		URL:       url,
-		Method:    http.MethodPost,
-		Headers:   httpReq.Header.Clone(),
-		Body:      body,
-		Provider:  e.Identifier(),
-		AuthID:    authID,
-		AuthLabel: authLabel,
-		AuthType:  authType,
-		AuthValue: authValue,
-	})
-
-	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
-	httpResp, err := httpClient.Do(httpReq)
+	httpResp, err := e.newPipeline(ctx, auth, from, req, opts, apiKey, true, body).send(ctx, originalURL)
 	if err != nil {
-		recordAPIResponseError(ctx, e.cfg, err)
 		return nil, err
 	}
-	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		data, readErr := io.ReadAll(httpResp.Body)
-		if errClose := httpResp.Body.Close(); errClose != nil {
-			log.Errorf("codex executor: close response body error: %v", errClose)
-		}
-		if readErr != nil {
-			recordAPIResponseError(ctx, e.cfg, readErr)
-			return nil, readErr
-		}
-		appendAPIResponseChunk(ctx, e.cfg, data)
-		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
-		if proxyRoute.Proxied && shouldBanReverseProxyOnError(httpResp.StatusCode, string(data)) {
-			banReverseProxyTemporarily(proxyRoute.ProxyID, e.Identifier(), httpResp.StatusCode, string(data))
-			fallbackURL := originalURL
-			logWithRequestID(ctx).Warnf("codex executor: reverse proxy failed, retrying direct upstream: %s", fallbackURL)
-			httpReq, err = e.cacheHelper(ctx, from, fallbackURL, req, opts, body)
-			if err != nil {
-				return nil, err
-			}
-			applyCodexHeaders(httpReq, auth, apiKey, true)
-			applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
-			recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-				URL:       fallbackURL,
-				Method:    http.MethodPost,
-				Headers:   httpReq.Header.Clone(),
-				Body:      body,
-				Provider:  e.Identifier(),
-				AuthID:    authID,
-				AuthLabel: authLabel,
-				AuthType:  authType,
-				AuthValue: authValue,
-			})
-			httpResp, err = httpClient.Do(httpReq)
-			if err != nil {
-				recordAPIResponseError(ctx, e.cfg, err)
-				return nil, err
-			}
-			recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-			if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-				data, readErr = io.ReadAll(httpResp.Body)
-				if errClose := httpResp.Body.Close(); errClose != nil {
-					log.Errorf("codex executor: close response body error: %v", errClose)
-				}
-				if readErr != nil {
-					recordAPIResponseError(ctx, e.cfg, readErr)
-					return nil, readErr
-				}
-				appendAPIResponseChunk(ctx, e.cfg, data)
-				logWithRequestID(ctx).Debugf("retry request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
-				err = newCodexStatusErr(ctx, httpClient, auth, httpResp.StatusCode, data, httpResp.Header)
-				return nil, err
-			}
-		} else {
-			err = newCodexStatusErr(ctx, httpClient, auth, httpResp.StatusCode, data, httpResp.Header)
-			return nil, err
-		}
-	}
 	out := make(chan cliproxyexecutor.StreamChunk)
 	stream = out
 	go func() {
@@ -486,13 +288,8 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 				log.Errorf("codex executor: close response body error: %v", errClose)
 			}
 		}()
-		scanner := bufio.NewScanner(httpResp.Body)
-		scanner.Buffer(nil, 52_428_800) // 50MB
 		var param any
-		for scanner.Scan() {
-			line := scanner.Bytes()
-			appendAPIResponseChunk(ctx, e.cfg, line)
-
+		scanStreamLines(ctx, e.cfg, httpResp.Body, reporter, out, func(line []byte) {
 			if bytes.HasPrefix(line, dataTag) {
 				data := bytes.TrimSpace(line[5:])
 				if gjson.GetBytes(data, "type").String() == "response.completed" {
@@ -506,16 +303,34 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 			for i := range chunks {
 				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
 			}
-		}
-		if errScan := scanner.Err(); errScan != nil {
-			recordAPIResponseError(ctx, e.cfg, errScan)
-			reporter.publishFailure(ctx)
-			out <- cliproxyexecutor.StreamChunk{Err: errScan}
-		}
+		})
 	}()
 	return stream, nil
 }
 
+// newPipeline returns the request pipeline for a Codex upstream call. Error responses are
+// converted with newCodexStatusErr so quota hints are preserved.
+func (e *CodexExecutor) newPipeline(ctx context.Context, auth *cliproxyauth.Auth, from sdktranslator.Format, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, apiKey string, stream bool, body []byte) *requestPipeline {
+	pipeline := newRequestPipeline(ctx, e.cfg, auth, e.Identifier(), body, func(ctx context.Context, url string) (*http.Request, error) {
+		httpReq, err := e.cacheHelper(ctx, from, url, req, opts, body)
+		if err != nil {
+			return nil, err
+		}
+		sessionID := httpReq.Header.Get("Session_id")
+		applyCodexHeaders(httpReq, auth, apiKey, stream)
+		if sessionID != "" && codexSessionPinned(e.cfg) {
+			// Keep the configured session ID over one supplied by the client.
+			httpReq.Header.Set("Session_id", sessionID)
+		}
+		applyReverseProxyHeaders(httpReq, e.cfg, auth, e.Identifier())
+		return httpReq, nil
+	})
+	pipeline.statusError = func(ctx context.Context, statusCode int, body []byte, headers http.Header) error {
+		return newCodexStatusErr(ctx, pipeline.client, auth, statusCode, body, headers)
+	}
+	return pipeline
+}
+
 func (e *CodexExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
 
@@ -604,6 +419,39 @@ func parseRetryAfterHeader(headers http.Header) *time.Duration {
 	return &retryAfter
 }
 
+// parseRateLimitResetHeaders reads the OpenAI-style x-ratelimit-reset-requests and
+// x-ratelimit-reset-tokens headers ("1s", "6m0s") and returns the later reset.
+func parseRateLimitResetHeaders(headers http.Header) *time.Duration {
+	var longest time.Duration
+	for _, key := range []string{"X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens"} {
+		raw := strings.TrimSpace(headers.Get(key))
+		if raw == "" {
+			continue
+		}
+		reset, err := time.ParseDuration(raw)
+		if err != nil {
+			seconds, errFloat := strconv.ParseFloat(raw, 64)
+			if errFloat != nil {
+				continue
+			}
+			reset = time.Duration(seconds * float64(time.Second))
+		}
+		longest = max(longest, reset)
+	}
+	if longest <= 0 {
+		return nil
+	}
+	return &longest
+}
+
+// rateLimitRetryAfter returns the cooldown a rate-limited response advertises in its headers.
+func rateLimitRetryAfter(headers http.Header) *time.Duration {
+	if retryAfter := parseRetryAfterHeader(headers); retryAfter != nil {
+		return retryAfter
+	}
+	return parseRateLimitResetHeaders(headers)
+}
+
 func fetchCodexQuotaCooldownHint(ctx context.Context, client *http.Client, auth *cliproxyauth.Auth) (codexQuotaCooldownHint, bool) {
 	var hint codexQuotaCooldownHint
 	if client == nil || auth == nil {
@@ -634,7 +482,8 @@ func fetchCodexQuotaCooldownHint(ctx context.Context, client *http.Client, auth
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+token)
 	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("User-Agent", defaultCodexUserAgent)
+	_, userAgent := codexClientIdentity()
+	httpReq.Header.Set("User-Agent", userAgent)
 	if accountID != "" {
 		httpReq.Header.Set("Chatgpt-Account-Id", accountID)
 	}
@@ -979,35 +828,17 @@ func (e *CodexExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*
 }
 
 func (e *CodexExecutor) cacheHelper(ctx context.Context, from sdktranslator.Format, url string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, rawJSON []byte) (*http.Request, error) {
-	var cache codexCache
-	if from == "claude" {
-		userIDResult := gjson.GetBytes(req.Payload, "metadata.user_id")
-		if userIDResult.Exists() {
-			key := fmt.Sprintf("%s-%s", req.Model, userIDResult.String())
-			var ok bool
-			if cache, ok = getCodexCache(key); !ok {
-				cache = codexCache{
-					ID:     uuid.New().String(),
-					Expire: time.Now().Add(1 * time.Hour),
-				}
-				setCodexCache(key, cache)
-			}
-		}
-	}
-	if cache.ID == "" {
-		cache.ID = extractCodexConversationIDForRequest(req, opts, rawJSON)
-	}
-
-	if cache.ID != "" {
-		rawJSON, _ = sjson.SetBytes(rawJSON, "prompt_cache_key", cache.ID)
+	id, cacheKey := e.codexSessionID(from, req, opts, rawJSON)
+	if id != "" && cacheKey {
+		rawJSON, _ = sjson.SetBytes(rawJSON, "prompt_cache_key", id)
 	}
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(rawJSON))
 	if err != nil {
 		return nil, err
 	}
-	if cache.ID != "" {
-		httpReq.Header.Set("Conversation_id", cache.ID)
-		httpReq.Header.Set("Session_id", cache.ID)
+	if id != "" {
+		httpReq.Header.Set("Conversation_id", id)
+		httpReq.Header.Set("Session_id", id)
 	}
 	return httpReq, nil
 }
@@ -1076,14 +907,16 @@ func applyCodexHeaders(r *http.Request, auth *cliproxyauth.Auth, token string, s
 	r.Header.Set("Content-Type", "application/json")
 	r.Header.Set("Authorization", "Bearer "+token)
 
-	ginHeaders := codexInboundHeaders(r.Context())
+	inboundHeaders := codexInboundHeaders(r.Context())
 
-	misc.EnsureHeader(r.Header, ginHeaders, "Version", codexClientVersion)
-	misc.EnsureHeader(r.Header, ginHeaders, "Openai-Beta", codexResponsesBeta)
-	misc.EnsureHeader(r.Header, ginHeaders, "Session_id", uuid.NewString())
-	misc.EnsureHeader(r.Header, ginHeaders, "User-Agent", defaultCodexUserAgent)
-	misc.EnsureHeader(r.Header, ginHeaders, "X-Client-Request-Id", uuid.NewString())
-	applyCodexPassthroughHeaders(r.Header, ginHeaders)
+	version, userAgent := codexClientIdentity()
+	observeCodexUserAgent(inboundHeaders.Get("User-Agent"))
+	misc.EnsureHeader(r.Header, inboundHeaders, "Version", version)
+	misc.EnsureHeader(r.Header, inboundHeaders, "Openai-Beta", codexResponsesBeta)
+	misc.EnsureHeader(r.Header, inboundHeaders, "Session_id", uuid.NewString())
+	misc.EnsureHeader(r.Header, inboundHeaders, "User-Agent", userAgent)
+	misc.EnsureHeader(r.Header, inboundHeaders, "X-Client-Request-Id", uuid.NewString())
+	applyCodexPassthroughHeaders(r.Header, inboundHeaders)
 
 	if stream {
 		r.Header.Set("Accept", "text/event-stream")
@@ -1098,21 +931,11 @@ func applyCodexHeaders(r *http.Request, auth *cliproxyauth.Auth, token string, s
 			r.Header.Set("Chatgpt-Account-Id", accountID)
 		}
 	}
-	var attrs map[string]string
-	if auth != nil {
-		attrs = auth.Attributes
-	}
-	util.ApplyCustomHeadersFromAttrs(r, attrs)
+	applyAuthCustomHeaders(r, auth)
 }
 
 func codexInboundHeaders(ctx context.Context) http.Header {
-	if ctx == nil {
-		return nil
-	}
-	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
-		return ginCtx.Request.Header
-	}
-	return nil
+	return cliproxyexecutor.RequestMetadataFromContext(ctx).Headers
 }
 
 func applyCodexPassthroughHeaders(target http.Header, source http.Header) {
@@ -1133,13 +956,7 @@ func applyCodexPassthroughHeaders(target http.Header, source http.Header) {
 }
 
 func codexUserAgent(ctx context.Context) string {
-	if ctx == nil {
-		return ""
-	}
-	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
-		return strings.TrimSpace(ginCtx.Request.UserAgent())
-	}
-	return ""
+	return strings.TrimSpace(cliproxyexecutor.RequestMetadataFromContext(ctx).UserAgent)
 }
 
 func parseCodexRetryAfter(statusCode int, errorBody []byte, now time.Time) *time.Duration {
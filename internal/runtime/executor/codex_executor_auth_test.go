@@ -9,7 +9,6 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
@@ -82,29 +81,22 @@ func TestApplyCodexHeadersDoesNotInjectWebHeadersForAPIKey(t *testing.T) {
 }
 
 func TestApplyCodexHeadersPassesThroughCodexTelemetryHeaders(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	recorder := httptest.NewRecorder()
-	ginCtx, _ := gin.CreateTestContext(recorder)
-	inboundReq, err := http.NewRequest(http.MethodPost, "https://example.com/inbound", nil)
-	if err != nil {
-		t.Fatalf("new inbound request: %v", err)
-	}
-	inboundReq.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
-	inboundReq.Header.Set("Tracestate", "vendor=value")
-	inboundReq.Header.Set("X-Codex-Turn-State", "turn-state")
-	inboundReq.Header.Set("X-Codex-Turn-Metadata", "{\"turn_id\":\"t-1\"}")
-	inboundReq.Header.Set("X-Codex-Beta-Features", "beta-a,beta-b")
-	inboundReq.Header.Set("X-Openai-Subagent", "planner")
-	inboundReq.Header.Set("X-Openai-Internal-Codex-Residency", "us")
-	inboundReq.Header.Set("X-Client-Request-Id", "client-123")
-	ginCtx.Request = inboundReq
+	inbound := http.Header{}
+	inbound.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	inbound.Set("Tracestate", "vendor=value")
+	inbound.Set("X-Codex-Turn-State", "turn-state")
+	inbound.Set("X-Codex-Turn-Metadata", "{\"turn_id\":\"t-1\"}")
+	inbound.Set("X-Codex-Beta-Features", "beta-a,beta-b")
+	inbound.Set("X-Openai-Subagent", "planner")
+	inbound.Set("X-Openai-Internal-Codex-Residency", "us")
+	inbound.Set("X-Client-Request-Id", "client-123")
 
 	token := fakeCodexJWT(t, "acct-123")
 	req, err := http.NewRequest(http.MethodPost, "https://example.com/responses", nil)
 	if err != nil {
 		t.Fatalf("new request: %v", err)
 	}
-	req = req.WithContext(context.WithValue(req.Context(), "gin", ginCtx))
+	req = req.WithContext(cliproxyexecutor.WithRequestMetadata(req.Context(), &cliproxyexecutor.RequestMetadata{Headers: inbound}))
 
 	auth := &cliproxyauth.Auth{
 		Provider: "codex",
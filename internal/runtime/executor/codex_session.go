@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// codexSessionNamespace scopes the name-based UUIDs derived by the per-client and
+// system-prompt session strategies.
+var codexSessionNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/router-for-me/CLIProxyAPI/codex-session"))
+
+// codexSessionHeaders are the inbound headers forwarded by the passthrough strategy.
+var codexSessionHeaders = []string{"Session_id", "Conversation_id", "X-Session-Id"}
+
+func codexSessionStrategy(cfg *config.Config) string {
+	if cfg == nil || cfg.CodexSession.Strategy == "" {
+		return config.CodexSessionAuto
+	}
+	return cfg.CodexSession.Strategy
+}
+
+// codexSessionPinned reports whether the configured strategy overrides a client-supplied Session_id.
+func codexSessionPinned(cfg *config.Config) bool {
+	switch codexSessionStrategy(cfg) {
+	case config.CodexSessionPerRequest, config.CodexSessionPerClient, config.CodexSessionSystemPrompt:
+		return true
+	}
+	return false
+}
+
+// codexSessionID resolves the Session_id and Conversation_id of a Codex request according to
+// the codex-session strategy. cacheKey reports whether the ID also becomes the prompt_cache_key.
+// The per-client and system-prompt strategies fall back to auto when their input is missing.
+func (e *CodexExecutor) codexSessionID(from sdktranslator.Format, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, rawJSON []byte) (id string, cacheKey bool) {
+	switch codexSessionStrategy(e.cfg) {
+	case config.CodexSessionPerRequest:
+		return uuid.NewString(), false
+	case config.CodexSessionPassthrough:
+		for _, key := range codexSessionHeaders {
+			if value := sanitizeCodexConversationID(opts.Headers.Get(key)); value != "" {
+				return value, false
+			}
+		}
+		return "", false
+	case config.CodexSessionPerClient:
+		if clientKey, _ := opts.Metadata[cliproxyexecutor.ClientAPIKeyMetadataKey].(string); clientKey != "" {
+			return uuid.NewSHA1(codexSessionNamespace, []byte("client\x00"+clientKey+"\x00"+req.Model)).String(), true
+		}
+	case config.CodexSessionSystemPrompt:
+		if instructions := strings.TrimSpace(gjson.GetBytes(rawJSON, "instructions").String()); instructions != "" {
+			return uuid.NewSHA1(codexSessionNamespace, []byte("system\x00"+req.Model+"\x00"+instructions)).String(), true
+		}
+	}
+	return e.autoCodexSessionID(from, req, opts, rawJSON), true
+}
+
+// autoCodexSessionID keys Claude requests by model and metadata.user_id through the prompt
+// cache, and otherwise reuses a session or prompt cache key supplied by the client.
+func (e *CodexExecutor) autoCodexSessionID(from sdktranslator.Format, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, rawJSON []byte) string {
+	if from == "claude" {
+		userIDResult := gjson.GetBytes(req.Payload, "metadata.user_id")
+		if userIDResult.Exists() {
+			key := fmt.Sprintf("%s-%s", req.Model, userIDResult.String())
+			cache, ok := getCodexCache(key)
+			if !ok {
+				cache = codexCache{
+					ID:     uuid.New().String(),
+					Expire: time.Now().Add(codexCacheTTL(e.cfg)),
+				}
+				setCodexCache(key, cache, codexCacheMaxEntries(e.cfg))
+			}
+			return cache.ID
+		}
+	}
+	return extractCodexConversationIDForRequest(req, opts, rawJSON)
+}
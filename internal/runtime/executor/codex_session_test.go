@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func codexSessionRequest(t *testing.T, strategy string, opts cliproxyexecutor.Options, body string) (*http.Request, string) {
+	t.Helper()
+	cfg := &config.Config{CodexSession: config.CodexSessionConfig{Strategy: strategy}}
+	req := cliproxyexecutor.Request{Model: "gpt-5-codex", Payload: []byte(body)}
+	httpReq, err := NewCodexExecutor(cfg).cacheHelper(context.Background(), sdktranslator.FormatOpenAIResponse, "https://example.com/responses", req, opts, []byte(body))
+	if err != nil {
+		t.Fatalf("cacheHelper: %v", err)
+	}
+	out, _ := io.ReadAll(httpReq.Body)
+	return httpReq, gjson.GetBytes(out, "prompt_cache_key").String()
+}
+
+func TestCodexSessionStrategies(t *testing.T) {
+	body := `{"model":"gpt-5-codex","instructions":"You are a helpful assistant.","input":"hi"}`
+	clientOpts := func(key string) cliproxyexecutor.Options {
+		return cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ClientAPIKeyMetadataKey: key}}
+	}
+
+	first, _ := codexSessionRequest(t, config.CodexSessionPerRequest, cliproxyexecutor.Options{}, body)
+	second, cacheKey := codexSessionRequest(t, config.CodexSessionPerRequest, cliproxyexecutor.Options{}, body)
+	if first.Header.Get("Session_id") == second.Header.Get("Session_id") || cacheKey != "" {
+		t.Fatalf("per-request: session IDs %q/%q, prompt_cache_key %q", first.Header.Get("Session_id"), second.Header.Get("Session_id"), cacheKey)
+	}
+
+	a, keyA := codexSessionRequest(t, config.CodexSessionPerClient, clientOpts("client-a"), body)
+	a2, _ := codexSessionRequest(t, config.CodexSessionPerClient, clientOpts("client-a"), body)
+	b, _ := codexSessionRequest(t, config.CodexSessionPerClient, clientOpts("client-b"), body)
+	if a.Header.Get("Session_id") != a2.Header.Get("Session_id") || a.Header.Get("Session_id") == b.Header.Get("Session_id") || keyA != a.Header.Get("Session_id") {
+		t.Fatalf("per-client: %q %q %q (prompt_cache_key %q)", a.Header.Get("Session_id"), a2.Header.Get("Session_id"), b.Header.Get("Session_id"), keyA)
+	}
+
+	s1, _ := codexSessionRequest(t, config.CodexSessionSystemPrompt, clientOpts("client-a"), body)
+	s2, _ := codexSessionRequest(t, config.CodexSessionSystemPrompt, clientOpts("client-b"), body)
+	s3, _ := codexSessionRequest(t, config.CodexSessionSystemPrompt, clientOpts("client-a"), `{"model":"gpt-5-codex","instructions":"Be terse.","input":"hi"}`)
+	if s1.Header.Get("Conversation_id") != s2.Header.Get("Conversation_id") || s1.Header.Get("Conversation_id") == s3.Header.Get("Conversation_id") {
+		t.Fatalf("system-prompt: %q %q %q", s1.Header.Get("Conversation_id"), s2.Header.Get("Conversation_id"), s3.Header.Get("Conversation_id"))
+	}
+
+	headers := http.Header{}
+	headers.Set("Conversation_id", "client-conversation-0123456789")
+	p, cacheKey := codexSessionRequest(t, config.CodexSessionPassthrough, cliproxyexecutor.Options{Headers: headers}, `{"model":"gpt-5-codex","input":"hi","prompt_cache_key":"client-cache-key-0123456789"}`)
+	if p.Header.Get("Session_id") != "client-conversation-0123456789" || cacheKey != "client-cache-key-0123456789" {
+		t.Fatalf("passthrough: Session_id %q, prompt_cache_key %q", p.Header.Get("Session_id"), cacheKey)
+	}
+	none, _ := codexSessionRequest(t, config.CodexSessionPassthrough, cliproxyexecutor.Options{}, body)
+	if none.Header.Get("Session_id") != "" {
+		t.Fatalf("passthrough without client header set Session_id %q", none.Header.Get("Session_id"))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,7 +17,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
@@ -133,7 +133,7 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 
 	basePayload = fixGeminiCLIImageAspectRatio(baseModel, basePayload)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
+	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	action := "generateContent"
 	if req.Metadata != nil {
@@ -148,16 +148,9 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 		models = append([]string{baseModel}, models...)
 	}
 
-	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
 
-	var authID, authLabel, authType, authValue string
-	authID = auth.ID
-	authLabel = auth.Label
-	authType, authValue = auth.AccountInfo()
-
-	var lastStatus int
-	var lastBody []byte
+	var lastErr error
 
 	for idx, attemptModel := range models {
 		payload := append([]byte(nil), basePayload...)
@@ -181,76 +174,39 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 			url = url + fmt.Sprintf("?$alt=%s", opts.Alt)
 		}
 
-		reqHTTP, errReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
-		if errReq != nil {
-			err = errReq
-			return resp, err
-		}
-		reqHTTP.Header.Set("Content-Type", "application/json")
-		reqHTTP.Header.Set("Authorization", "Bearer "+tok.AccessToken)
-		applyGeminiCLIHeaders(reqHTTP)
-		reqHTTP.Header.Set("Accept", "application/json")
-		recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-			URL:       url,
-			Method:    http.MethodPost,
-			Headers:   reqHTTP.Header.Clone(),
-			Body:      payload,
-			Provider:  e.Identifier(),
-			AuthID:    authID,
-			AuthLabel: authLabel,
-			AuthType:  authType,
-			AuthValue: authValue,
-		})
-
-		httpResp, errDo := httpClient.Do(reqHTTP)
-		if errDo != nil {
-			recordAPIResponseError(ctx, e.cfg, errDo)
-			err = errDo
-			return resp, err
+		httpResp, errSend := e.newPipeline(ctx, auth, tok.AccessToken, "application/json", payload).send(ctx, url)
+		if errSend != nil {
+			if !isRateLimited(errSend) {
+				err = errSend
+				return resp, err
+			}
+			lastErr = errSend
+			if idx+1 < len(models) {
+				log.Debugf("gemini cli executor: rate limited, retrying with next model: %s", models[idx+1])
+			} else {
+				log.Debug("gemini cli executor: rate limited, no additional fallback model")
+			}
+			continue
 		}
 
 		data, errRead := io.ReadAll(httpResp.Body)
 		if errClose := httpResp.Body.Close(); errClose != nil {
 			log.Errorf("gemini cli executor: close response body error: %v", errClose)
 		}
-		recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
 		if errRead != nil {
 			recordAPIResponseError(ctx, e.cfg, errRead)
 			err = errRead
 			return resp, err
 		}
 		appendAPIResponseChunk(ctx, e.cfg, data)
-		if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
-			reporter.publish(ctx, parseGeminiCLIUsage(data))
-			var param any
-			out := sdktranslator.TranslateNonStream(respCtx, to, from, attemptModel, opts.OriginalRequest, payload, data, &param)
-			resp = cliproxyexecutor.Response{Payload: []byte(out)}
-			return resp, nil
-		}
-
-		lastStatus = httpResp.StatusCode
-		lastBody = append([]byte(nil), data...)
-		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
-		if httpResp.StatusCode == 429 {
-			if idx+1 < len(models) {
-				log.Debugf("gemini cli executor: rate limited, retrying with next model: %s", models[idx+1])
-			} else {
-				log.Debug("gemini cli executor: rate limited, no additional fallback model")
-			}
-			continue
-		}
-
-		err = newGeminiStatusErr(httpResp.StatusCode, data)
-		return resp, err
+		reporter.publish(ctx, parseGeminiCLIUsage(data))
+		var param any
+		out := sdktranslator.TranslateNonStream(respCtx, to, from, attemptModel, opts.OriginalRequest, payload, data, &param)
+		resp = cliproxyexecutor.Response{Payload: []byte(out)}
+		return resp, nil
 	}
 
-	if len(lastBody) > 0 {
-		appendAPIResponseChunk(ctx, e.cfg, lastBody)
-	}
-	if lastStatus == 0 {
-		lastStatus = 429
-	}
-	err = newGeminiStatusErr(lastStatus, lastBody)
+	err = rateLimitedFallbackError(lastErr)
 	return resp, err
 }
 
@@ -286,7 +242,7 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 
 	basePayload = fixGeminiCLIImageAspectRatio(baseModel, basePayload)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
+	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	projectID := resolveGeminiProjectID(auth)
 
@@ -295,16 +251,9 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 		models = append([]string{baseModel}, models...)
 	}
 
-	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
 
-	var authID, authLabel, authType, authValue string
-	authID = auth.ID
-	authLabel = auth.Label
-	authType, authValue = auth.AccountInfo()
-
-	var lastStatus int
-	var lastBody []byte
+	var lastErr error
 
 	for idx, attemptModel := range models {
 		payload := append([]byte(nil), basePayload...)
@@ -325,58 +274,19 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 			url = url + fmt.Sprintf("?$alt=%s", opts.Alt)
 		}
 
-		reqHTTP, errReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
-		if errReq != nil {
-			err = errReq
-			return nil, err
-		}
-		reqHTTP.Header.Set("Content-Type", "application/json")
-		reqHTTP.Header.Set("Authorization", "Bearer "+tok.AccessToken)
-		applyGeminiCLIHeaders(reqHTTP)
-		reqHTTP.Header.Set("Accept", "text/event-stream")
-		recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-			URL:       url,
-			Method:    http.MethodPost,
-			Headers:   reqHTTP.Header.Clone(),
-			Body:      payload,
-			Provider:  e.Identifier(),
-			AuthID:    authID,
-			AuthLabel: authLabel,
-			AuthType:  authType,
-			AuthValue: authValue,
-		})
-
-		httpResp, errDo := httpClient.Do(reqHTTP)
-		if errDo != nil {
-			recordAPIResponseError(ctx, e.cfg, errDo)
-			err = errDo
-			return nil, err
-		}
-		recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-		if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-			data, errRead := io.ReadAll(httpResp.Body)
-			if errClose := httpResp.Body.Close(); errClose != nil {
-				log.Errorf("gemini cli executor: close response body error: %v", errClose)
-			}
-			if errRead != nil {
-				recordAPIResponseError(ctx, e.cfg, errRead)
-				err = errRead
+		httpResp, errSend := e.newPipeline(ctx, auth, tok.AccessToken, "text/event-stream", payload).send(ctx, url)
+		if errSend != nil {
+			if !isRateLimited(errSend) {
+				err = errSend
 				return nil, err
 			}
-			appendAPIResponseChunk(ctx, e.cfg, data)
-			lastStatus = httpResp.StatusCode
-			lastBody = append([]byte(nil), data...)
-			logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
-			if httpResp.StatusCode == 429 {
-				if idx+1 < len(models) {
-					log.Debugf("gemini cli executor: rate limited, retrying with next model: %s", models[idx+1])
-				} else {
-					log.Debug("gemini cli executor: rate limited, no additional fallback model")
-				}
-				continue
+			lastErr = errSend
+			if idx+1 < len(models) {
+				log.Debugf("gemini cli executor: rate limited, retrying with next model: %s", models[idx+1])
+			} else {
+				log.Debug("gemini cli executor: rate limited, no additional fallback model")
 			}
-			err = newGeminiStatusErr(httpResp.StatusCode, data)
-			return nil, err
+			continue
 		}
 
 		out := make(chan cliproxyexecutor.StreamChunk)
@@ -442,13 +352,7 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 		return stream, nil
 	}
 
-	if len(lastBody) > 0 {
-		appendAPIResponseChunk(ctx, e.cfg, lastBody)
-	}
-	if lastStatus == 0 {
-		lastStatus = 429
-	}
-	err = newGeminiStatusErr(lastStatus, lastBody)
+	err = rateLimitedFallbackError(lastErr)
 	return nil, err
 }
 
@@ -469,18 +373,9 @@ func (e *GeminiCLIExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.
 		models = append([]string{baseModel}, models...)
 	}
 
-	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
 
-	var authID, authLabel, authType, authValue string
-	if auth != nil {
-		authID = auth.ID
-		authLabel = auth.Label
-		authType, authValue = auth.AccountInfo()
-	}
-
-	var lastStatus int
-	var lastBody []byte
+	var lastErr error
 
 	// The loop variable attemptModel is only used as the concrete model id sent to the upstream
 	// Gemini CLI endpoint when iterating fallback variants.
@@ -508,57 +403,64 @@ func (e *GeminiCLIExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.
 			url = url + fmt.Sprintf("?$alt=%s", opts.Alt)
 		}
 
-		reqHTTP, errReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
-		if errReq != nil {
-			return cliproxyexecutor.Response{}, errReq
-		}
-		reqHTTP.Header.Set("Content-Type", "application/json")
-		reqHTTP.Header.Set("Authorization", "Bearer "+tok.AccessToken)
-		applyGeminiCLIHeaders(reqHTTP)
-		reqHTTP.Header.Set("Accept", "application/json")
-		recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
-			URL:       url,
-			Method:    http.MethodPost,
-			Headers:   reqHTTP.Header.Clone(),
-			Body:      payload,
-			Provider:  e.Identifier(),
-			AuthID:    authID,
-			AuthLabel: authLabel,
-			AuthType:  authType,
-			AuthValue: authValue,
-		})
-
-		resp, errDo := httpClient.Do(reqHTTP)
-		if errDo != nil {
-			recordAPIResponseError(ctx, e.cfg, errDo)
-			return cliproxyexecutor.Response{}, errDo
+		httpResp, errSend := e.newPipeline(ctx, auth, tok.AccessToken, "application/json", payload).sendDirect(ctx, url)
+		if errSend != nil {
+			if !isRateLimited(errSend) {
+				return cliproxyexecutor.Response{}, errSend
+			}
+			lastErr = errSend
+			log.Debugf("gemini cli executor: rate limited, retrying with next model")
+			continue
 		}
-		data, errRead := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		recordAPIResponseMetadata(ctx, e.cfg, resp.StatusCode, resp.Header.Clone())
+		data, errRead := io.ReadAll(httpResp.Body)
+		_ = httpResp.Body.Close()
 		if errRead != nil {
 			recordAPIResponseError(ctx, e.cfg, errRead)
 			return cliproxyexecutor.Response{}, errRead
 		}
 		appendAPIResponseChunk(ctx, e.cfg, data)
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			count := gjson.GetBytes(data, "totalTokens").Int()
-			translated := sdktranslator.TranslateTokenCount(respCtx, to, from, count, data)
-			return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
-		}
-		lastStatus = resp.StatusCode
-		lastBody = append([]byte(nil), data...)
-		if resp.StatusCode == 429 {
-			log.Debugf("gemini cli executor: rate limited, retrying with next model")
-			continue
+		count := gjson.GetBytes(data, "totalTokens").Int()
+		translated := sdktranslator.TranslateTokenCount(respCtx, to, from, count, data)
+		return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
+	}
+
+	return cliproxyexecutor.Response{}, rateLimitedFallbackError(lastErr)
+}
+
+// newPipeline returns the request pipeline for a Code Assist call authorized with accessToken.
+// Error responses become Gemini status errors carrying the upstream retry delay.
+func (e *GeminiCLIExecutor) newPipeline(ctx context.Context, auth *cliproxyauth.Auth, accessToken, accept string, body []byte) *requestPipeline {
+	pipeline := newRequestPipeline(ctx, e.cfg, auth, e.Identifier(), body, func(ctx context.Context, url string) (*http.Request, error) {
+		reqHTTP, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
 		}
-		break
+		reqHTTP.Header.Set("Content-Type", "application/json")
+		reqHTTP.Header.Set("Authorization", "Bearer "+accessToken)
+		applyGeminiCLIHeaders(reqHTTP)
+		reqHTTP.Header.Set("Accept", accept)
+		return reqHTTP, nil
+	})
+	pipeline.statusError = func(_ context.Context, statusCode int, body []byte, _ http.Header) error {
+		return newGeminiStatusErr(statusCode, body)
 	}
+	return pipeline
+}
+
+// isRateLimited reports whether err is a 429 from upstream, which moves on to the next
+// fallback model.
+func isRateLimited(err error) bool {
+	var status statusErr
+	return errors.As(err, &status) && status.code == http.StatusTooManyRequests
+}
 
-	if lastStatus == 0 {
-		lastStatus = 429
+// rateLimitedFallbackError returns the error of the last fallback model, all of which were
+// rate limited.
+func rateLimitedFallbackError(lastErr error) error {
+	if lastErr != nil {
+		return lastErr
 	}
-	return cliproxyexecutor.Response{}, newGeminiStatusErr(lastStatus, lastBody)
+	return newGeminiStatusErr(http.StatusTooManyRequests, nil)
 }
 
 // Refresh refreshes the authentication credentials (no-op for Gemini CLI).
@@ -738,14 +640,11 @@ func stringValue(m map[string]any, key string) string {
 
 // applyGeminiCLIHeaders sets required headers for the Gemini CLI upstream.
 func applyGeminiCLIHeaders(r *http.Request) {
-	var ginHeaders http.Header
-	if ginCtx, ok := r.Context().Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
-		ginHeaders = ginCtx.Request.Header
-	}
+	inboundHeaders := cliproxyexecutor.RequestMetadataFromContext(r.Context()).Headers
 
-	misc.EnsureHeader(r.Header, ginHeaders, "User-Agent", "google-api-nodejs-client/9.15.1")
-	misc.EnsureHeader(r.Header, ginHeaders, "X-Goog-Api-Client", "gl-node/22.17.0")
-	misc.EnsureHeader(r.Header, ginHeaders, "Client-Metadata", geminiCLIClientMetadata())
+	misc.EnsureHeader(r.Header, inboundHeaders, "User-Agent", "google-api-nodejs-client/9.15.1")
+	misc.EnsureHeader(r.Header, inboundHeaders, "X-Goog-Api-Client", "gl-node/22.17.0")
+	misc.EnsureHeader(r.Header, inboundHeaders, "Client-Metadata", geminiCLIClientMetadata())
 }
 
 // geminiCLIClientMetadata returns a compact metadata string required by upstream.
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// applyAuthCustomHeaders applies the custom headers configured for auth, expanding header
+// templates with the auth's identity variables: auth_id, auth_index, provider, label, email
+// and account_id.
+func applyAuthCustomHeaders(r *http.Request, auth *cliproxyauth.Auth) {
+	if auth == nil {
+		return
+	}
+	util.ApplyCustomHeadersWithVars(r, auth.Attributes, authHeaderVars(auth))
+}
+
+func authHeaderVars(auth *cliproxyauth.Auth) map[string]string {
+	vars := map[string]string{
+		"auth_id":    auth.ID,
+		"auth_index": auth.EnsureIndex(),
+		"provider":   auth.Provider,
+		"label":      auth.Label,
+	}
+	for _, key := range []string{"email", "account_id"} {
+		if value, ok := auth.Metadata[key].(string); ok && strings.TrimSpace(value) != "" {
+			vars[key] = strings.TrimSpace(value)
+		}
+	}
+	if vars["account_id"] == "" {
+		if value, ok := auth.Metadata["chatgpt_account_id"].(string); ok && strings.TrimSpace(value) != "" {
+			vars["account_id"] = strings.TrimSpace(value)
+		} else if value := strings.TrimSpace(auth.Attributes["account_id"]); value != "" {
+			vars["account_id"] = value
+		}
+	}
+	return vars
+}
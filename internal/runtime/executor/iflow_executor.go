@@ -1,12 +1,12 @@
 package executor
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -102,7 +102,7 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 
 	body = preserveReasoningContentInMessages(body)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	endpoint := strings.TrimSuffix(baseURL, "/") + iflowDefaultEndpoint
 
@@ -146,7 +146,7 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		b, _ := io.ReadAll(httpResp.Body)
 		appendAPIResponseChunk(ctx, e.cfg, b)
 		logWithRequestID(ctx).Debugf("request error, error status: %d error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = newIFlowStatusErr(httpResp.StatusCode, b, httpResp.Header)
 		return resp, err
 	}
 
@@ -156,6 +156,11 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		return resp, err
 	}
 	appendAPIResponseChunk(ctx, e.cfg, data)
+	if bodyErr, ok := iflowBodyError(data, httpResp.Header); ok {
+		err = bodyErr
+		return resp, err
+	}
+	reporter.observeSystemFingerprint(data)
 	reporter.publish(ctx, parseOpenAIUsage(data))
 	// Ensure usage is recorded even if upstream omits usage metadata.
 	reporter.ensurePublished(ctx)
@@ -209,7 +214,7 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 		body = ensureToolsArray(body)
 	}
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	endpoint := strings.TrimSuffix(baseURL, "/") + iflowDefaultEndpoint
 
@@ -251,7 +256,7 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 		}
 		appendAPIResponseChunk(ctx, e.cfg, data)
 		logWithRequestID(ctx).Debugf("request error, error status: %d error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
-		err = statusErr{code: httpResp.StatusCode, msg: string(data)}
+		err = newIFlowStatusErr(httpResp.StatusCode, data, httpResp.Header)
 		return nil, err
 	}
 
@@ -265,27 +270,28 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 			}
 		}()
 
-		scanner := bufio.NewScanner(httpResp.Body)
-		scanner.Buffer(nil, 52_428_800) // 50MB
 		var param any
-		for scanner.Scan() {
-			line := scanner.Bytes()
-			appendAPIResponseChunk(ctx, e.cfg, line)
-			if detail, ok := parseOpenAIStreamUsage(line); ok {
-				reporter.publish(ctx, detail)
+		var streamUsage openAIStreamUsage
+		failed := false
+		scanStreamLines(ctx, e.cfg, httpResp.Body, reporter, out, func(line []byte) {
+			if failed {
+				return
 			}
+			if bodyErr, ok := iflowBodyError(jsonPayload(line), httpResp.Header); ok {
+				// iFlow reports errors such as rate limits as a JSON body on a 200 stream.
+				failed = true
+				reporter.publishFailure(ctx)
+				out <- cliproxyexecutor.StreamChunk{Err: bodyErr}
+				return
+			}
+			reporter.observeSystemFingerprint(line)
+			streamUsage.observe(line)
 			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, opts.OriginalRequest, body, line, &param)
 			for i := range chunks {
 				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
 			}
-		}
-		if errScan := scanner.Err(); errScan != nil {
-			recordAPIResponseError(ctx, e.cfg, errScan)
-			reporter.publishFailure(ctx)
-			out <- cliproxyexecutor.StreamChunk{Err: errScan}
-		}
-		// Guarantee a usage record exists even if the stream never emitted usage data.
-		reporter.ensurePublished(ctx)
+		})
+		streamUsage.publish(ctx, reporter)
 	}()
 
 	return stream, nil
@@ -536,3 +542,36 @@ func preserveReasoningContentInMessages(body []byte) []byte {
 
 	return body
 }
+
+// newIFlowStatusErr converts an iFlow error response; rate limits use the reset advertised in
+// the response headers.
+func newIFlowStatusErr(statusCode int, body []byte, headers http.Header) statusErr {
+	sErr := statusErr{code: statusCode, msg: string(body)}
+	if statusCode == http.StatusTooManyRequests {
+		sErr.retryAfter = rateLimitRetryAfter(headers)
+	}
+	return sErr
+}
+
+// iflowBodyError detects the {"status":"449","msg":"..."} errors iFlow returns with HTTP 200.
+// Status 449 is iFlow's rate limit and maps to 429.
+func iflowBodyError(body []byte, headers http.Header) (statusErr, bool) {
+	if len(body) == 0 {
+		return statusErr{}, false
+	}
+	root := gjson.ParseBytes(body)
+	if root.Get("choices").Exists() || !root.Get("msg").Exists() {
+		return statusErr{}, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(root.Get("status").String()))
+	if err != nil || code < 400 {
+		return statusErr{}, false
+	}
+	switch {
+	case code == 449:
+		code = http.StatusTooManyRequests
+	case code > 599:
+		code = http.StatusBadGateway
+	}
+	return newIFlowStatusErr(code, body, headers), true
+}
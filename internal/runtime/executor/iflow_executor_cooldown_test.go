@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIFlowBodyError_RateLimit(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Retry-After", "30")
+	sErr, ok := iflowBodyError([]byte(`{"status":"449","msg":"You exceed the rate limit, please slow down and try again later."}`), headers)
+	if !ok {
+		t.Fatalf("expected an error body to be detected")
+	}
+	if sErr.code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", sErr.code)
+	}
+	if sErr.retryAfter == nil || *sErr.retryAfter != 30*time.Second {
+		t.Fatalf("expected 30s cooldown, got %v", sErr.retryAfter)
+	}
+}
+
+func TestIFlowBodyError_IgnoresCompletions(t *testing.T) {
+	for _, body := range []string{
+		`{"id":"chatcmpl-1","choices":[{"message":{"content":"hi"}}],"status":"500","msg":"ignored"}`,
+		`{"status":"200","msg":"ok"}`,
+		``,
+	} {
+		if _, ok := iflowBodyError([]byte(body), nil); ok {
+			t.Fatalf("unexpected error for %s", body)
+		}
+	}
+}
+
+func TestNewIFlowStatusErr_RateLimitHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-Ratelimit-Reset-Tokens", "2m")
+	sErr := newIFlowStatusErr(http.StatusTooManyRequests, nil, headers)
+	if sErr.retryAfter == nil || *sErr.retryAfter != 2*time.Minute {
+		t.Fatalf("expected 2m cooldown, got %v", sErr.retryAfter)
+	}
+	if sErr = newIFlowStatusErr(http.StatusInternalServerError, nil, headers); sErr.retryAfter != nil {
+		t.Fatalf("expected no cooldown for 500, got %v", sErr.retryAfter)
+	}
+}
+
+func TestOpenAIStreamUsage_KeepsFinalTotals(t *testing.T) {
+	var u openAIStreamUsage
+	for _, line := range []string{
+		`data: {"choices":[{"delta":{"content":"a"}}],"usage":{"prompt_tokens":10,"completion_tokens":1,"total_tokens":11}}`,
+		`data: {"choices":[{"delta":{"content":"b"}}],"usage":null}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":7,"total_tokens":17}}`,
+		`data: [DONE]`,
+	} {
+		u.observe([]byte(line))
+	}
+	if !u.seen || u.detail.OutputTokens != 7 || u.detail.TotalTokens != 17 {
+		t.Fatalf("expected final usage, got %+v", u.detail)
+	}
+}
@@ -3,6 +3,8 @@ package executor
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"html"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
@@ -51,8 +54,20 @@ type upstreamAttempt struct {
 	errorWritten         bool
 }
 
-// recordAPIRequest stores the upstream request metadata in Gin context for request logging.
+// recordAPIRequest stores the upstream request metadata in Gin context for request logging
+// and appends it to the audit chain.
 func recordAPIRequest(ctx context.Context, cfg *config.Config, info upstreamRequestLog) {
+	if cfg != nil && cfg.AuditChain.File != "" {
+		recordAuditChain(ctx, cfg.AuditChain.File, info)
+	}
+	if debugtrace.Enabled(ctx) {
+		debugtrace.Record(ctx, "upstream_request", info.Method+" "+info.URL, map[string]any{
+			"provider": info.Provider,
+			"auth":     formatAuthInfo(info),
+			"headers":  maskedHeaders(info.Headers),
+			"body":     info.Body,
+		})
+	}
 	if cfg == nil || !cfg.RequestLog {
 		return
 	}
@@ -98,8 +113,32 @@ func recordAPIRequest(ctx context.Context, cfg *config.Config, info upstreamRequ
 	updateAggregatedRequest(ginCtx, attempts)
 }
 
+// recordAuditChain appends info to the audit chain in path. Bodies are recorded by digest and
+// credentials are left out.
+func recordAuditChain(ctx context.Context, path string, info upstreamRequestLog) {
+	digest := sha256.Sum256(info.Body)
+	_, err := logging.AppendAuditRecord(path, logging.AuditRecord{
+		Time:       time.Now(),
+		RequestID:  logging.GetRequestID(ctx),
+		Provider:   info.Provider,
+		AuthID:     info.AuthID,
+		Method:     info.Method,
+		URL:        info.URL,
+		BodySHA256: hex.EncodeToString(digest[:]),
+	})
+	if err != nil {
+		logWithRequestID(ctx).Errorf("audit chain: %v", err)
+	}
+}
+
 // recordAPIResponseMetadata captures upstream response status/header information for the latest attempt.
 func recordAPIResponseMetadata(ctx context.Context, cfg *config.Config, status int, headers http.Header) {
+	if debugtrace.Enabled(ctx) {
+		debugtrace.Record(ctx, "upstream_response", fmt.Sprintf("status %d", status), map[string]any{
+			"status":  status,
+			"headers": maskedHeaders(headers),
+		})
+	}
 	if cfg == nil || !cfg.RequestLog {
 		return
 	}
@@ -130,6 +169,7 @@ func recordAPIResponseError(ctx context.Context, cfg *config.Config, err error)
 	if err == nil {
 		return
 	}
+	debugtrace.Record(ctx, "upstream_response", "upstream error", map[string]any{"error": err.Error()})
 	ginCtx := ginContextFrom(ctx)
 	if ginCtx == nil {
 		return
@@ -168,6 +208,11 @@ func RecordUpstreamErrorResponse(ctx context.Context, statusCode int, contentTyp
 	if statusCode < 400 || len(body) == 0 {
 		return
 	}
+	debugtrace.Record(ctx, "upstream_response", fmt.Sprintf("error body (status %d)", statusCode), map[string]any{
+		"status":       statusCode,
+		"content_type": contentType,
+		"body":         body,
+	})
 	ginCtx := ginContextFrom(ctx)
 	if ginCtx == nil {
 		return
@@ -292,6 +337,19 @@ func updateAggregatedResponse(ginCtx *gin.Context, attempts []*upstreamAttempt)
 	ginCtx.Set(apiResponseKey, []byte(builder.String()))
 }
 
+// maskedHeaders flattens headers for a debug trace, masking credentials like writeHeaders.
+func maskedHeaders(headers http.Header) map[string]string {
+	out := make(map[string]string, len(headers))
+	for key, values := range headers {
+		masked := make([]string, 0, len(values))
+		for _, value := range values {
+			masked = append(masked, util.MaskSensitiveHeaderValue(key, value))
+		}
+		out[key] = strings.Join(masked, ", ")
+	}
+	return out
+}
+
 func writeHeaders(builder *strings.Builder, headers http.Header) {
 	if builder == nil {
 		return
@@ -422,3 +480,17 @@ func logWithRequestID(ctx context.Context) *log.Entry {
 	}
 	return log.WithField("request_id", requestID)
 }
+
+// applyRequestIDHeader forwards the client request ID upstream unless a header is already set.
+// Only used for generic OpenAI-compatible providers; executors that mimic official clients
+// must not add headers those clients would never send.
+func applyRequestIDHeader(ctx context.Context, req *http.Request) {
+	if req == nil {
+		return
+	}
+	requestID := logging.GetRequestID(ctx)
+	if requestID == "" || req.Header.Get(logging.RequestIDHeader) != "" {
+		return
+	}
+	req.Header.Set(logging.RequestIDHeader, requestID)
+}
@@ -0,0 +1,379 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const defaultMockErrorBody = `{"error":{"message":"mock upstream error","type":"server_error"}}`
+
+// MockExecutor serves the mock-provider models from canned fixtures instead of a real upstream.
+// Requests and fixtures still pass through the translators, so client formats, routing and
+// usage accounting behave as they do for real providers.
+type MockExecutor struct {
+	cfg *config.Config
+}
+
+// NewMockExecutor creates an executor for the providers configured under mock-provider.
+func NewMockExecutor(cfg *config.Config) *MockExecutor {
+	return &MockExecutor{cfg: cfg}
+}
+
+// Identifier implements cliproxyauth.ProviderExecutor.
+func (e *MockExecutor) Identifier() string { return "mock" }
+
+// PrepareRequest is a no-op; mock providers have no credentials.
+func (e *MockExecutor) PrepareRequest(_ *http.Request, _ *cliproxyauth.Auth) error { return nil }
+
+// HttpRequest is not supported by mock providers.
+func (e *MockExecutor) HttpRequest(_ context.Context, _ *cliproxyauth.Auth, _ *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("mock executor: http requests are not supported")
+}
+
+func (e *MockExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	model, err := e.resolveModel(auth, baseModel)
+	if err != nil {
+		return resp, err
+	}
+	from := opts.SourceFormat
+	to := sdktranslator.FromString(model.Format)
+	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, false)
+	if err = mockInjectFailure(ctx, model); err != nil {
+		return resp, err
+	}
+
+	var body []byte
+	if model.Format == "claude" && from != to {
+		// Claude responses for other client formats are translated from the event stream, as
+		// ClaudeExecutor does.
+		lines, errLines := mockStreamLines(model, baseModel, translated)
+		if errLines != nil {
+			return resp, errLines
+		}
+		for _, line := range lines {
+			if detail, ok := parseClaudeStreamUsage(line); ok {
+				reporter.publish(ctx, detail)
+			}
+		}
+		body = bytes.Join(lines, []byte("\n"))
+	} else {
+		if body, err = mockResponseBody(model, baseModel, translated); err != nil {
+			return resp, err
+		}
+		reporter.publish(ctx, mockUsage(model.Format, body))
+	}
+	appendAPIResponseChunk(ctx, e.cfg, body)
+	reporter.ensurePublished(ctx)
+
+	var param any
+	out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, opts.OriginalRequest, translated, body, &param)
+	return cliproxyexecutor.Response{Payload: []byte(out)}, nil
+}
+
+func (e *MockExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	model, err := e.resolveModel(auth, baseModel)
+	if err != nil {
+		return nil, err
+	}
+	from := opts.SourceFormat
+	to := sdktranslator.FromString(model.Format)
+	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, true)
+	if err = mockInjectFailure(ctx, model); err != nil {
+		return nil, err
+	}
+	lines, err := mockStreamLines(model, baseModel, translated)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cliproxyexecutor.StreamChunk)
+	stream = out
+	go func() {
+		defer close(out)
+		var param any
+		emit := func(line []byte) {
+			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, opts.OriginalRequest, translated, line, &param)
+			for i := range chunks {
+				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
+			}
+		}
+		for i, line := range lines {
+			if i > 0 && !mockSleep(ctx, model.ChunkDelayMS) {
+				reporter.publishFailure(ctx)
+				out <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+				return
+			}
+			if model.MalformedRate > 0 && rand.Float64() < model.MalformedRate {
+				line = line[:len(line)/2]
+			}
+			appendAPIResponseChunk(ctx, e.cfg, line)
+			if detail, ok := mockStreamUsage(model.Format, line); ok {
+				reporter.publish(ctx, detail)
+			}
+			switch model.Format {
+			case "gemini":
+				if payload := jsonPayload(line); len(payload) > 0 {
+					emit(payload)
+				}
+			case "openai":
+				if bytes.HasPrefix(line, []byte("data:")) {
+					emit(line)
+				}
+			default:
+				emit(line)
+			}
+		}
+		if model.Format == "gemini" {
+			emit([]byte("[DONE]"))
+		}
+		reporter.ensurePublished(ctx)
+	}()
+	return stream, nil
+}
+
+// CountTokens estimates the prompt size from the translated payload, since mock models have
+// no tokenizer of their own.
+func (e *MockExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+	model, err := e.resolveModel(auth, baseModel)
+	if err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	to := sdktranslator.FromString(model.Format)
+	translated := sdktranslator.TranslateRequest(opts.SourceFormat, to, baseModel, req.Payload, false)
+	count := mockPromptTokens(translated)
+	usageJSON := buildOpenAIUsageJSON(count)
+	translatedUsage := sdktranslator.TranslateTokenCount(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, count, usageJSON)
+	return cliproxyexecutor.Response{Payload: []byte(translatedUsage)}, nil
+}
+
+// Refresh is a no-op for mock providers.
+func (e *MockExecutor) Refresh(_ context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *MockExecutor) resolveModel(auth *cliproxyauth.Auth, model string) (*config.MockModel, error) {
+	if auth == nil || e.cfg == nil {
+		return nil, statusErr{code: http.StatusUnauthorized, msg: "mock executor: missing provider"}
+	}
+	name := ""
+	if auth.Attributes != nil {
+		name = auth.Attributes["mock_name"]
+	}
+	for i := range e.cfg.MockProviders {
+		provider := &e.cfg.MockProviders[i]
+		if provider.Name != name {
+			continue
+		}
+		for j := range provider.Models {
+			if strings.EqualFold(provider.Models[j].Name, model) {
+				return &provider.Models[j], nil
+			}
+		}
+	}
+	return nil, statusErr{code: http.StatusNotFound, msg: fmt.Sprintf("mock executor: unknown model %q", model)}
+}
+
+// mockInjectFailure waits out the configured latency and fails the request at the configured
+// error rate.
+func mockInjectFailure(ctx context.Context, model *config.MockModel) error {
+	if !mockSleep(ctx, model.LatencyMS) {
+		return ctx.Err()
+	}
+	if model.ErrorRate <= 0 || rand.Float64() >= model.ErrorRate {
+		return nil
+	}
+	body := model.ErrorBody
+	if body == "" {
+		body = defaultMockErrorBody
+	}
+	return statusErr{code: model.ErrorStatus, msg: body}
+}
+
+func mockSleep(ctx context.Context, ms int) bool {
+	if ms <= 0 {
+		return true
+	}
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func mockResponseBody(model *config.MockModel, name string, request []byte) ([]byte, error) {
+	if model.ResponseFile != "" {
+		return os.ReadFile(model.ResponseFile)
+	}
+	if model.Response != "" {
+		return []byte(model.Response), nil
+	}
+	return mockDefaultResponse(model.Format, name, request), nil
+}
+
+func mockStreamLines(model *config.MockModel, name string, request []byte) ([][]byte, error) {
+	text := model.Stream
+	if model.StreamFile != "" {
+		data, err := os.ReadFile(model.StreamFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return mockDefaultStream(model.Format, name, request), nil
+	}
+	var lines [][]byte
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, []byte(line))
+		}
+	}
+	return lines, nil
+}
+
+func mockUsage(format string, body []byte) usage.Detail {
+	switch format {
+	case "claude":
+		return parseClaudeUsage(body)
+	case "gemini":
+		return parseGeminiUsage(body)
+	case "codex":
+		detail, _ := parseCodexUsage(body)
+		return detail
+	default:
+		return parseOpenAIUsage(body)
+	}
+}
+
+func mockStreamUsage(format string, line []byte) (usage.Detail, bool) {
+	switch format {
+	case "claude":
+		return parseClaudeStreamUsage(line)
+	case "gemini":
+		return parseGeminiStreamUsage(line)
+	case "codex":
+		payload := jsonPayload(line)
+		if gjson.GetBytes(payload, "type").String() != "response.completed" {
+			return usage.Detail{}, false
+		}
+		return parseCodexUsage(payload)
+	default:
+		return parseOpenAIStreamUsage(line)
+	}
+}
+
+// mockPromptTokens approximates the prompt size at four bytes per token.
+func mockPromptTokens(request []byte) int64 {
+	return int64(len(request)+3) / 4
+}
+
+// mockReplyWords is the generated reply used when a mock model has no fixture.
+func mockReplyWords(name string) []string {
+	return strings.Fields("This is a mock response from " + name + ".")
+}
+
+func mockDefaultResponse(format, name string, request []byte) []byte {
+	words := mockReplyWords(name)
+	text := strings.Join(words, " ")
+	input, output := mockPromptTokens(request), int64(len(words))
+	created := time.Now().Unix()
+	switch format {
+	case "claude":
+		return fmt.Appendf(nil, `{"id":"msg_mock","type":"message","role":"assistant","model":%q,"content":[{"type":"text","text":%q}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":%d,"output_tokens":%d}}`,
+			name, text, input, output)
+	case "gemini":
+		return fmt.Appendf(nil, `{"candidates":[{"content":{"role":"model","parts":[{"text":%q}]},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":%d,"candidatesTokenCount":%d,"totalTokenCount":%d},"modelVersion":%q,"responseId":"mock"}`,
+			text, input, output, input+output, name)
+	case "codex":
+		return []byte(mockCodexCompleted(name, text, created, input, output))
+	default:
+		return fmt.Appendf(nil, `{"id":"chatcmpl-mock","object":"chat.completion","created":%d,"model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":%d,"completion_tokens":%d,"total_tokens":%d}}`,
+			created, name, text, input, output, input+output)
+	}
+}
+
+func mockDefaultStream(format, name string, request []byte) [][]byte {
+	words := mockReplyWords(name)
+	input, output := mockPromptTokens(request), int64(len(words))
+	created := time.Now().Unix()
+	var lines [][]byte
+	add := func(format string, args ...any) { lines = append(lines, fmt.Appendf(nil, format, args...)) }
+	switch format {
+	case "claude":
+		add("event: message_start")
+		add(`data: {"type":"message_start","message":{"id":"msg_mock","type":"message","role":"assistant","model":%q,"content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":%d,"output_tokens":1}}}`, name, input)
+		add("event: content_block_start")
+		add(`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`)
+		for i, word := range words {
+			add("event: content_block_delta")
+			add(`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, mockWordDelta(i, word))
+		}
+		add("event: content_block_stop")
+		add(`data: {"type":"content_block_stop","index":0}`)
+		add("event: message_delta")
+		add(`data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"input_tokens":%d,"output_tokens":%d}}`, input, output)
+		add("event: message_stop")
+		add(`data: {"type":"message_stop"}`)
+	case "gemini":
+		for i, word := range words {
+			add(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":%q}]},"index":0}],"modelVersion":%q,"responseId":"mock"}`, mockWordDelta(i, word), name)
+		}
+		add(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":%d,"candidatesTokenCount":%d,"totalTokenCount":%d},"modelVersion":%q,"responseId":"mock"}`, input, output, input+output, name)
+	case "codex":
+		add(`data: {"type":"response.created","response":{"id":"resp_mock","object":"response","created_at":%d,"status":"in_progress","model":%q,"output":[]}}`, created, name)
+		add(`data: {"type":"response.output_item.added","output_index":0,"item":{"id":"msg_mock","type":"message","status":"in_progress","role":"assistant","content":[]}}`)
+		for i, word := range words {
+			add(`data: {"type":"response.output_text.delta","item_id":"msg_mock","output_index":0,"content_index":0,"delta":%q}`, mockWordDelta(i, word))
+		}
+		add(`data: {"type":"response.output_item.done","output_index":0,"item":{"id":"msg_mock","type":"message","status":"completed","role":"assistant","content":[{"type":"output_text","text":%q,"annotations":[]}]}}`, strings.Join(words, " "))
+		add("data: %s", mockCodexCompleted(name, strings.Join(words, " "), created, input, output))
+	default:
+		for i, word := range words {
+			add(`data: {"id":"chatcmpl-mock","object":"chat.completion.chunk","created":%d,"model":%q,"choices":[{"index":0,"delta":{"content":%q},"finish_reason":null}]}`, created, name, mockWordDelta(i, word))
+		}
+		add(`data: {"id":"chatcmpl-mock","object":"chat.completion.chunk","created":%d,"model":%q,"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`, created, name)
+		add(`data: {"id":"chatcmpl-mock","object":"chat.completion.chunk","created":%d,"model":%q,"choices":[],"usage":{"prompt_tokens":%d,"completion_tokens":%d,"total_tokens":%d}}`, created, name, input, output, input+output)
+		add("data: [DONE]")
+	}
+	return lines
+}
+
+func mockWordDelta(i int, word string) string {
+	if i == 0 {
+		return word
+	}
+	return " " + word
+}
+
+func mockCodexCompleted(name, text string, created, input, output int64) string {
+	return fmt.Sprintf(`{"type":"response.completed","response":{"id":"resp_mock","object":"response","created_at":%d,"status":"completed","model":%q,"output":[{"id":"msg_mock","type":"message","status":"completed","role":"assistant","content":[{"type":"output_text","text":%q,"annotations":[]}]}],"usage":{"input_tokens":%d,"output_tokens":%d,"total_tokens":%d}}}`,
+		created, name, text, input, output, input+output)
+}
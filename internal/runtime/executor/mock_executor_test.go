@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func newMockTestExecutor(models ...config.MockModel) (*MockExecutor, *cliproxyauth.Auth) {
+	cfg := &config.Config{MockProviders: []config.MockProvider{{Name: "fixtures", Models: models}}}
+	cfg.SanitizeMockProviders()
+	auth := &cliproxyauth.Auth{ID: "mock-1", Provider: "mock", Attributes: map[string]string{"mock_name": "fixtures"}}
+	return NewMockExecutor(cfg), auth
+}
+
+func mockTestRequest(model string, stream bool) (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	payload := []byte(`{"model":"` + model + `","messages":[{"role":"user","content":"hi"}]}`)
+	return cliproxyexecutor.Request{Model: model, Payload: payload},
+		cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai"), OriginalRequest: payload, Stream: stream}
+}
+
+func TestMockExecutor_TranslatesGeneratedReplies(t *testing.T) {
+	for _, format := range []string{"openai", "claude", "gemini", "codex"} {
+		executor, auth := newMockTestExecutor(config.MockModel{Name: "mock-" + format, Format: format})
+		req, opts := mockTestRequest("mock-"+format, false)
+
+		resp, err := executor.Execute(context.Background(), auth, req, opts)
+		if err != nil {
+			t.Fatalf("%s: Execute error: %v", format, err)
+		}
+		if got := gjson.GetBytes(resp.Payload, "choices.0.message.content").String(); got != "This is a mock response from mock-"+format+"." {
+			t.Fatalf("%s: content = %q in %s", format, got, resp.Payload)
+		}
+
+		req, opts = mockTestRequest("mock-"+format, true)
+		stream, err := executor.ExecuteStream(context.Background(), auth, req, opts)
+		if err != nil {
+			t.Fatalf("%s: ExecuteStream error: %v", format, err)
+		}
+		var text strings.Builder
+		for chunk := range stream {
+			if chunk.Err != nil {
+				t.Fatalf("%s: stream error: %v", format, chunk.Err)
+			}
+			text.WriteString(gjson.GetBytes(jsonPayload(chunk.Payload), "choices.0.delta.content").String())
+		}
+		if text.String() != "This is a mock response from mock-"+format+"." {
+			t.Fatalf("%s: streamed content = %q", format, text.String())
+		}
+	}
+}
+
+func TestMockExecutor_ReplaysFixtures(t *testing.T) {
+	executor, auth := newMockTestExecutor(config.MockModel{
+		Name:   "mock-fixture",
+		Stream: "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"one\"}}]}\n\ndata: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\" two\"}}]}\n\ndata: [DONE]\n",
+	})
+	req, opts := mockTestRequest("mock-fixture", true)
+	stream, err := executor.ExecuteStream(context.Background(), auth, req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream error: %v", err)
+	}
+	var text strings.Builder
+	for chunk := range stream {
+		text.WriteString(gjson.GetBytes(jsonPayload(chunk.Payload), "choices.0.delta.content").String())
+	}
+	if text.String() != "one two" {
+		t.Fatalf("streamed content = %q", text.String())
+	}
+}
+
+func TestMockExecutor_InjectsErrors(t *testing.T) {
+	executor, auth := newMockTestExecutor(config.MockModel{Name: "mock-flaky", ErrorRate: 1, ErrorStatus: http.StatusTooManyRequests})
+	req, opts := mockTestRequest("mock-flaky", false)
+	_, err := executor.Execute(context.Background(), auth, req, opts)
+	status, ok := err.(statusErr)
+	if !ok || status.StatusCode() != http.StatusTooManyRequests || !strings.Contains(status.Error(), "mock upstream error") {
+		t.Fatalf("error = %v", err)
+	}
+
+	req, opts = mockTestRequest("mock-unknown", false)
+	if _, err = executor.Execute(context.Background(), auth, req, opts); err == nil {
+		t.Fatalf("expected unknown model to fail")
+	}
+}
+
+func TestMockExecutor_MalformedChunks(t *testing.T) {
+	executor, auth := newMockTestExecutor(config.MockModel{Name: "mock-broken", MalformedRate: 1})
+	req, opts := mockTestRequest("mock-broken", true)
+	stream, err := executor.ExecuteStream(context.Background(), auth, req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream error: %v", err)
+	}
+	for chunk := range stream {
+		if payload := jsonPayload(chunk.Payload); len(payload) > 0 && gjson.ValidBytes(payload) && gjson.GetBytes(payload, "choices.0.delta.content").String() != "" {
+			t.Fatalf("expected malformed chunks to carry no content, got %s", chunk.Payload)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// FetchOpenAICompatModels lists the models an OpenAI-compatible provider exposes to the supplied auth
+// by querying its /models endpoint. It returns nil when the endpoint is unavailable or fails.
+func FetchOpenAICompatModels(ctx context.Context, auth *cliproxyauth.Auth, cfg *config.Config, ownedBy string) []*registry.ModelInfo {
+	if auth == nil || auth.Attributes == nil {
+		return nil
+	}
+	baseURL := strings.TrimSuffix(strings.TrimSpace(auth.Attributes["base_url"]), "/")
+	if baseURL == "" {
+		return nil
+	}
+	apiKey := strings.TrimSpace(auth.Attributes["api_key"])
+
+	httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models", nil)
+	if errReq != nil {
+		return nil
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+	applyAuthCustomHeaders(httpReq, auth)
+
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	httpResp, errDo := httpClient.Do(httpReq)
+	if errDo != nil {
+		log.Debugf("openai compat executor: models request failed for %s: %v", auth.ID, errDo)
+		return nil
+	}
+	bodyBytes, errRead := io.ReadAll(httpResp.Body)
+	if errClose := httpResp.Body.Close(); errClose != nil {
+		log.Errorf("openai compat executor: close response body error: %v", errClose)
+	}
+	if errRead != nil || httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
+		log.Debugf("openai compat executor: models request for %s returned status %d", auth.ID, httpResp.StatusCode)
+		return nil
+	}
+
+	data := gjson.GetBytes(bodyBytes, "data")
+	if !data.IsArray() {
+		return nil
+	}
+	now := time.Now().Unix()
+	models := make([]*registry.ModelInfo, 0, len(data.Array()))
+	for _, item := range data.Array() {
+		modelID := strings.TrimSpace(item.Get("id").String())
+		if modelID == "" {
+			continue
+		}
+		created := item.Get("created").Int()
+		if created <= 0 {
+			created = now
+		}
+		models = append(models, &registry.ModelInfo{
+			ID:            modelID,
+			Object:        "model",
+			Created:       created,
+			OwnedBy:       ownedBy,
+			Type:          "openai-compatibility",
+			DisplayName:   modelID,
+			ContextLength: int(item.Get("context_length").Int()),
+			UserDefined:   true,
+		})
+	}
+	return models
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// payloadStopPaths lists the stop sequence fields used by each payload dialect.
+var payloadStopPaths = []string{"stop", "stop_sequences", "generationConfig.stopSequences"}
+
+// applyPayloadCompatRules runs the compatibility shims of every matching rule in order.
+func applyPayloadCompatRules(rules []config.PayloadCompatRule, protocol, root string, candidates []string, payload []byte) []byte {
+	out := payload
+	for i := range rules {
+		rule := &rules[i]
+		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+			continue
+		}
+		out = applyPayloadCompatRule(rule, root, candidates[0], out)
+	}
+	return out
+}
+
+func applyPayloadCompatRule(rule *config.PayloadCompatRule, root, model string, payload []byte) []byte {
+	out := payload
+	warn := func(param, action string) {
+		log.WithFields(log.Fields{
+			"model": model,
+			"param": param,
+		}).Warnf("payload compat: %s", action)
+	}
+
+	if rule.MaxStopSequences > 0 {
+		for _, path := range payloadStopPaths {
+			fullPath := buildPayloadPath(root, path)
+			stops := gjson.GetBytes(out, fullPath)
+			if !stops.IsArray() {
+				continue
+			}
+			items := stops.Array()
+			if len(items) <= rule.MaxStopSequences {
+				continue
+			}
+			kept := make([]string, 0, rule.MaxStopSequences)
+			for _, item := range items[:rule.MaxStopSequences] {
+				kept = append(kept, item.String())
+			}
+			if updated, errSet := sjson.SetBytes(out, fullPath, kept); errSet == nil {
+				out = updated
+				warn(path, "truncated stop sequences to backend limit")
+			}
+		}
+	}
+
+	if rule.StripParallelToolCalls {
+		for _, path := range []string{"parallel_tool_calls", "tool_choice.disable_parallel_tool_use"} {
+			out = deletePayloadPath(out, buildPayloadPath(root, path), func() { warn(path, "removed unsupported parameter") })
+		}
+	}
+
+	switch rule.ToolChoice {
+	case config.PayloadCompatToolChoiceStrip:
+		for _, path := range []string{"tool_choice", "toolConfig.functionCallingConfig"} {
+			out = deletePayloadPath(out, buildPayloadPath(root, path), func() { warn(path, "removed unsupported parameter") })
+		}
+	case config.PayloadCompatToolChoiceAuto:
+		out = downgradePayloadToolChoice(out, root, warn)
+	}
+
+	for _, path := range rule.Strip {
+		out = deletePayloadPath(out, buildPayloadPath(root, path), func() { warn(path, "removed unsupported parameter") })
+	}
+	return out
+}
+
+// downgradePayloadToolChoice replaces forced tool selection with automatic selection. When a
+// specific tool was forced, the tool list is narrowed to that tool so the model can only call it.
+func downgradePayloadToolChoice(payload []byte, root string, warn func(param, action string)) []byte {
+	out := payload
+	choicePath := buildPayloadPath(root, "tool_choice")
+	choice := gjson.GetBytes(out, choicePath)
+	if choice.Exists() {
+		forcedName := ""
+		forced := false
+		switch {
+		case choice.Type == gjson.String:
+			forced = choice.String() == "required"
+		case choice.IsObject():
+			switch choice.Get("type").String() {
+			case "function":
+				forcedName = choice.Get("function.name").String()
+				if forcedName == "" {
+					forcedName = choice.Get("name").String()
+				}
+			case "tool":
+				forcedName = choice.Get("name").String()
+			case "any":
+				forced = true
+			}
+			forced = forced || forcedName != ""
+		}
+		if forced {
+			var auto any = "auto"
+			if choice.IsObject() {
+				auto = map[string]any{"type": "auto"}
+			}
+			if updated, errSet := sjson.SetBytes(out, choicePath, auto); errSet == nil {
+				out = updated
+				warn("tool_choice", "downgraded forced tool choice to auto")
+			}
+			if forcedName != "" {
+				out = narrowPayloadTools(out, buildPayloadPath(root, "tools"), forcedName, warn)
+			}
+		}
+	}
+
+	modePath := buildPayloadPath(root, "toolConfig.functionCallingConfig.mode")
+	if strings.EqualFold(gjson.GetBytes(out, modePath).String(), "ANY") {
+		if updated, errSet := sjson.SetBytes(out, modePath, "AUTO"); errSet == nil {
+			out = updated
+			warn("toolConfig.functionCallingConfig.mode", "downgraded forced tool choice to auto")
+		}
+	}
+	return out
+}
+
+// narrowPayloadTools keeps only the named tool in an OpenAI or Claude style tool list.
+func narrowPayloadTools(payload []byte, toolsPath, name string, warn func(param, action string)) []byte {
+	tools := gjson.GetBytes(payload, toolsPath)
+	if !tools.IsArray() {
+		return payload
+	}
+	kept := make([]string, 0, 1)
+	for _, tool := range tools.Array() {
+		toolName := tool.Get("function.name").String()
+		if toolName == "" {
+			toolName = tool.Get("name").String()
+		}
+		if toolName == name {
+			kept = append(kept, tool.Raw)
+		}
+	}
+	if len(kept) == 0 || len(kept) == len(tools.Array()) {
+		return payload
+	}
+	updated, errSet := sjson.SetRawBytes(payload, toolsPath, []byte("["+strings.Join(kept, ",")+"]"))
+	if errSet != nil {
+		return payload
+	}
+	warn("tools", "narrowed tool list to emulate forced tool choice")
+	return updated
+}
+
+func deletePayloadPath(payload []byte, path string, onDelete func()) []byte {
+	if path == "" || !gjson.GetBytes(payload, path).Exists() {
+		return payload
+	}
+	updated, errDel := sjson.DeleteBytes(payload, path)
+	if errDel != nil {
+		return payload
+	}
+	if onDelete != nil {
+		onDelete()
+	}
+	return updated
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyPayloadConfigCompatShims(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{Compat: []config.PayloadCompatRule{{
+		Models:                 []config.PayloadModelRule{{Name: "local-*", Protocol: "openai"}},
+		MaxStopSequences:       4,
+		StripParallelToolCalls: true,
+		ToolChoice:             config.PayloadCompatToolChoiceAuto,
+		Strip:                  []string{"logit_bias"},
+	}}}}
+	payload := []byte(`{"model":"local-llama","stop":["a","b","c","d","e","f"],"parallel_tool_calls":true,"logit_bias":{"1":2},` +
+		`"tool_choice":{"type":"function","function":{"name":"lookup"}},` +
+		`"tools":[{"type":"function","function":{"name":"lookup"}},{"type":"function","function":{"name":"other"}}]}`)
+
+	out := applyPayloadConfigWithRoot(cfg, "local-llama", "openai", "", payload, nil, "", payloadTarget{})
+
+	if n := len(gjson.GetBytes(out, "stop").Array()); n != 4 {
+		t.Fatalf("stop length = %d, want 4", n)
+	}
+	if gjson.GetBytes(out, "parallel_tool_calls").Exists() || gjson.GetBytes(out, "logit_bias").Exists() {
+		t.Fatalf("expected unsupported parameters to be stripped: %s", out)
+	}
+	if got := gjson.GetBytes(out, "tool_choice.type").String(); got != "auto" {
+		t.Fatalf("tool_choice.type = %q, want auto", got)
+	}
+	tools := gjson.GetBytes(out, "tools").Array()
+	if len(tools) != 1 || tools[0].Get("function.name").String() != "lookup" {
+		t.Fatalf("expected tool list narrowed to forced tool, got %s", gjson.GetBytes(out, "tools").Raw)
+	}
+
+	untouched := applyPayloadConfigWithRoot(cfg, "gpt-5", "openai", "", payload, nil, "", payloadTarget{})
+	if string(untouched) != string(payload) {
+		t.Fatalf("expected non-matching model to be unchanged")
+	}
+}
+
+func TestApplyPayloadCompatGeminiRoot(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{Compat: []config.PayloadCompatRule{{
+		Models:           []config.PayloadModelRule{{Name: "gemini-*"}},
+		MaxStopSequences: 1,
+		ToolChoice:       config.PayloadCompatToolChoiceAuto,
+	}}}}
+	payload := []byte(`{"request":{"generationConfig":{"stopSequences":["x","y"]},"toolConfig":{"functionCallingConfig":{"mode":"ANY"}}}}`)
+
+	out := applyPayloadConfigWithRoot(cfg, "gemini-2.5-pro", "gemini", "request", payload, nil, "", payloadTarget{})
+
+	if n := len(gjson.GetBytes(out, "request.generationConfig.stopSequences").Array()); n != 1 {
+		t.Fatalf("stopSequences length = %d, want 1", n)
+	}
+	if got := gjson.GetBytes(out, "request.toolConfig.functionCallingConfig.mode").String(); got != "AUTO" {
+		t.Fatalf("mode = %q, want AUTO", got)
+	}
+}
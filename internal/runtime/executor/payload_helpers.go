@@ -6,6 +6,7 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -16,12 +17,13 @@ import (
 // and restricts matches to the given protocol when supplied. Defaults are checked
 // against the original payload when provided. requestedModel carries the client-visible
 // model name before alias resolution so payload rules can target aliases precisely.
-func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+// target selects the payload profiles layered in before the rules.
+func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string, target payloadTarget) []byte {
 	if cfg == nil || len(payload) == 0 {
 		return payload
 	}
 	rules := cfg.Payload
-	if len(rules.Default) == 0 && len(rules.DefaultRaw) == 0 && len(rules.Override) == 0 && len(rules.OverrideRaw) == 0 && len(rules.Filter) == 0 {
+	if len(rules.Default) == 0 && len(rules.DefaultRaw) == 0 && len(rules.Override) == 0 && len(rules.OverrideRaw) == 0 && len(rules.Filter) == 0 && len(rules.Compat) == 0 && len(rules.Transform) == 0 && len(cfg.PayloadProfiles.Profiles) == 0 {
 		return payload
 	}
 	model = strings.TrimSpace(model)
@@ -35,6 +37,8 @@ func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 	if len(source) == 0 {
 		source = payload
 	}
+	// Apply payload profiles first so the rules below can still override them.
+	out = applyPayloadProfiles(cfg, target, root, candidates, source, out)
 	appliedDefaults := make(map[string]struct{})
 	// Apply default rules: first write wins per field across all matching rules.
 	for i := range rules.Default {
@@ -148,6 +152,98 @@ func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			out = updated
 		}
 	}
+	// Apply conditional transforms after the unconditional rules.
+	out = applyPayloadTransformRules(rules.Transform, protocol, root, candidates, out)
+	// Apply compatibility shims last so they see the final parameter set.
+	out = applyPayloadCompatRules(rules.Compat, protocol, root, candidates, out)
+	return out
+}
+
+// payloadTarget identifies the provider and client key a payload is sent for, which select
+// the payload profiles applied to it.
+type payloadTarget struct {
+	provider  string
+	clientKey string
+}
+
+// newPayloadTarget builds the payload target of a request served by provider.
+func newPayloadTarget(provider string, opts cliproxyexecutor.Options) payloadTarget {
+	target := payloadTarget{provider: strings.ToLower(strings.TrimSpace(provider))}
+	if opts.Metadata != nil {
+		target.clientKey, _ = opts.Metadata[cliproxyexecutor.ClientAPIKeyMetadataKey].(string)
+	}
+	return target
+}
+
+// applyPayloadProfiles layers the base, provider, model and client key profiles selected for
+// target, each expanded with the profiles it inherits from, and applies the result to out.
+// Later layers win for the same path; filters accumulate. A client key without a profile
+// uses the profile of its group.
+func applyPayloadProfiles(cfg *config.Config, target payloadTarget, root string, models []string, source, out []byte) []byte {
+	profiles := &cfg.PayloadProfiles
+	if len(profiles.Profiles) == 0 {
+		return out
+	}
+	names := []string{profiles.Base, profiles.Providers[target.provider]}
+	model := ""
+	for _, binding := range profiles.Models {
+		for _, candidate := range models {
+			if wildcard.Match(binding.Model, candidate) {
+				model = binding.Profile
+				break
+			}
+		}
+		if model != "" {
+			break
+		}
+	}
+	names = append(names, model)
+	if target.clientKey != "" {
+		keyProfile, _ := config.LookupClientKey(&cfg.SDKConfig, profiles.ClientKeys, target.clientKey)
+		names = append(names, keyProfile)
+	}
+
+	defaults := make(map[string]any)
+	overrides := make(map[string]any)
+	var filters []string
+	for _, name := range names {
+		for _, profile := range profiles.Chain(name) {
+			for path, value := range profile.Default {
+				defaults[path] = value
+			}
+			for path, value := range profile.Override {
+				overrides[path] = value
+			}
+			filters = append(filters, profile.Filter...)
+		}
+	}
+	for path, value := range defaults {
+		fullPath := buildPayloadPath(root, path)
+		if fullPath == "" || gjson.GetBytes(source, fullPath).Exists() {
+			continue
+		}
+		if updated, errSet := sjson.SetBytes(out, fullPath, value); errSet == nil {
+			out = updated
+		}
+	}
+	for path, value := range overrides {
+		fullPath := buildPayloadPath(root, path)
+		if fullPath == "" {
+			continue
+		}
+		if updated, errSet := sjson.SetBytes(out, fullPath, value); errSet == nil {
+			out = updated
+		}
+	}
+	for _, path := range filters {
+		fullPath := buildPayloadPath(root, path)
+		if fullPath == "" {
+			continue
+		}
+		if updated, errDel := sjson.DeleteBytes(out, fullPath); errDel == nil {
+			out = updated
+		}
+	}
 	return out
 }
 
@@ -164,7 +260,7 @@ func payloadModelRulesMatch(rules []config.PayloadModelRule, protocol string, mo
 			if ep := strings.TrimSpace(entry.Protocol); ep != "" && protocol != "" && !strings.EqualFold(ep, protocol) {
 				continue
 			}
-			if matchModelPattern(name, model) {
+			if wildcard.Match(name, model) {
 				return true
 			}
 		}
@@ -272,48 +368,3 @@ func payloadRequestedModel(opts cliproxyexecutor.Options, fallback string) strin
 		return fallback
 	}
 }
-
-// matchModelPattern performs simple wildcard matching where '*' matches zero or more characters.
-// Examples:
-//
-//	"*-5" matches "gpt-5"
-//	"gpt-*" matches "gpt-5" and "gpt-4"
-//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
-func matchModelPattern(pattern, model string) bool {
-	pattern = strings.TrimSpace(pattern)
-	model = strings.TrimSpace(model)
-	if pattern == "" {
-		return false
-	}
-	if pattern == "*" {
-		return true
-	}
-	// Iterative glob-style matcher supporting only '*' wildcard.
-	pi, si := 0, 0
-	starIdx := -1
-	matchIdx := 0
-	for si < len(model) {
-		if pi < len(pattern) && (pattern[pi] == model[si]) {
-			pi++
-			si++
-			continue
-		}
-		if pi < len(pattern) && pattern[pi] == '*' {
-			starIdx = pi
-			matchIdx = si
-			pi++
-			continue
-		}
-		if starIdx != -1 {
-			pi = starIdx + 1
-			matchIdx++
-			si = matchIdx
-			continue
-		}
-		return false
-	}
-	for pi < len(pattern) && pattern[pi] == '*' {
-		pi++
-	}
-	return pi == len(pattern)
-}
@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyPayloadConfigProfiles(t *testing.T) {
+	cfg := &config.Config{
+		PayloadProfiles: config.PayloadProfilesConfig{
+			Profiles: []config.PayloadProfile{
+				{Name: "base", Default: map[string]any{"temperature": 0.2, "store": false}},
+				{Name: "codex", Inherits: "base", Override: map[string]any{"store": true}, Filter: []string{"user"}},
+				{Name: "long", Default: map[string]any{"max_output_tokens": 8192}},
+				{Name: "tenant", Inherits: "long", Override: map[string]any{"temperature": 0.9}},
+			},
+			Providers:  map[string]string{"codex": "codex"},
+			Models:     []config.PayloadProfileBinding{{Model: "gpt-5*", Profile: "long"}},
+			ClientKeys: map[string]string{"sk-tenant": "tenant"},
+		},
+		Payload: config.PayloadConfig{Override: []config.PayloadRule{{
+			Models: []config.PayloadModelRule{{Name: "gpt-5"}},
+			Params: map[string]any{"max_output_tokens": 1024},
+		}}},
+	}
+	payload := []byte(`{"model":"gpt-5","temperature":0.5,"user":"u1"}`)
+
+	out := applyPayloadConfigWithRoot(cfg, "gpt-5", "codex", "", payload, nil, "", payloadTarget{provider: "codex"})
+	if got := gjson.GetBytes(out, "temperature").Float(); got != 0.5 {
+		t.Fatalf("temperature = %v, want client value 0.5", got)
+	}
+	if !gjson.GetBytes(out, "store").Bool() {
+		t.Fatalf("expected provider profile override of inherited default: %s", out)
+	}
+	if gjson.GetBytes(out, "user").Exists() {
+		t.Fatalf("expected user filtered: %s", out)
+	}
+	if got := gjson.GetBytes(out, "max_output_tokens").Int(); got != 1024 {
+		t.Fatalf("max_output_tokens = %d, want payload rule value 1024", got)
+	}
+
+	out = applyPayloadConfigWithRoot(cfg, "gpt-5", "codex", "", payload, nil, "", payloadTarget{provider: "codex", clientKey: "sk-tenant"})
+	if got := gjson.GetBytes(out, "temperature").Float(); got != 0.9 {
+		t.Fatalf("temperature = %v, want client key profile 0.9", got)
+	}
+
+	other := []byte(`{"model":"claude-sonnet-4"}`)
+	out = applyPayloadConfigWithRoot(cfg, "claude-sonnet-4", "claude", "", other, nil, "", payloadTarget{provider: "claude"})
+	if gjson.GetBytes(out, "temperature").Exists() || gjson.GetBytes(out, "max_output_tokens").Exists() {
+		t.Fatalf("expected no profile for unbound provider and model: %s", out)
+	}
+}
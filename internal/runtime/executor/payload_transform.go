@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// payloadConditionPatterns caches compiled transform condition expressions by source.
+var payloadConditionPatterns sync.Map
+
+// applyPayloadTransformRules runs every matching transform rule whose conditions hold, in order.
+// Each rule sees the payload left by the rules before it.
+func applyPayloadTransformRules(rules []config.PayloadTransformRule, protocol, root string, candidates []string, payload []byte) []byte {
+	out := payload
+	for i := range rules {
+		rule := &rules[i]
+		if !payloadModelRulesMatch(rule.Models, protocol, candidates) || !payloadConditionsHold(rule.When, root, out) {
+			continue
+		}
+		out = applyPayloadTransformRule(rule, root, out)
+	}
+	return out
+}
+
+func applyPayloadTransformRule(rule *config.PayloadTransformRule, root string, payload []byte) []byte {
+	out := payload
+	for _, from := range slices.Sorted(maps.Keys(rule.Rename)) {
+		fromPath := buildPayloadPath(root, from)
+		toPath := buildPayloadPath(root, rule.Rename[from])
+		value := gjson.GetBytes(out, fromPath)
+		if fromPath == "" || toPath == "" || !value.Exists() {
+			continue
+		}
+		updated, errSet := sjson.SetRawBytes(out, toPath, []byte(value.Raw))
+		if errSet != nil {
+			continue
+		}
+		out = deletePayloadPath(updated, fromPath, nil)
+	}
+	for _, path := range slices.Sorted(maps.Keys(rule.Set)) {
+		fullPath := buildPayloadPath(root, path)
+		if fullPath == "" {
+			continue
+		}
+		if updated, errSet := sjson.SetBytes(out, fullPath, rule.Set[path]); errSet == nil {
+			out = updated
+		}
+	}
+	for _, path := range rule.Delete {
+		if fullPath := buildPayloadPath(root, path); fullPath != "" {
+			out = deletePayloadPath(out, fullPath, nil)
+		}
+	}
+	return out
+}
+
+func payloadConditionsHold(conditions []config.PayloadCondition, root string, payload []byte) bool {
+	for i := range conditions {
+		if !payloadConditionHolds(&conditions[i], root, payload) {
+			return false
+		}
+	}
+	return true
+}
+
+func payloadConditionHolds(cond *config.PayloadCondition, root string, payload []byte) bool {
+	value := gjson.GetBytes(payload, buildPayloadPath(root, cond.Path))
+	if cond.Exists != nil {
+		if value.Exists() != *cond.Exists {
+			return false
+		}
+		if !*cond.Exists {
+			return true
+		}
+	} else if !value.Exists() {
+		return false
+	}
+	if cond.Equals != nil && !payloadValueEquals(value, cond.Equals) {
+		return false
+	}
+	if cond.Matches != "" {
+		pattern, ok := payloadConditionPattern(cond.Matches)
+		if !ok || !pattern.MatchString(value.String()) {
+			return false
+		}
+	}
+	return true
+}
+
+// payloadValueEquals compares a payload value with a configured one after normalizing both
+// through JSON, so YAML integers equal JSON numbers.
+func payloadValueEquals(value gjson.Result, expected any) bool {
+	raw, errMarshal := json.Marshal(expected)
+	if errMarshal != nil {
+		return false
+	}
+	var normalized any
+	if errUnmarshal := json.Unmarshal(raw, &normalized); errUnmarshal != nil {
+		return false
+	}
+	return reflect.DeepEqual(value.Value(), normalized)
+}
+
+func payloadConditionPattern(expr string) (*regexp.Regexp, bool) {
+	if cached, ok := payloadConditionPatterns.Load(expr); ok {
+		return cached.(*regexp.Regexp), true
+	}
+	pattern, errCompile := regexp.Compile(expr)
+	if errCompile != nil {
+		return nil, false
+	}
+	payloadConditionPatterns.Store(expr, pattern)
+	return pattern, true
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyPayloadConfigTransformRules(t *testing.T) {
+	absent := false
+	cfg := &config.Config{Payload: config.PayloadConfig{Transform: []config.PayloadTransformRule{
+		{
+			Models: []config.PayloadModelRule{{Name: "gpt-*", Protocol: "codex"}},
+			When:   []config.PayloadCondition{{Path: "reasoning.effort", Equals: "minimal"}},
+			Rename: map[string]string{"max_output_tokens": "max_tokens"},
+			Set:    map[string]any{"reasoning.effort": "low"},
+			Delete: []string{"prompt_cache_retention"},
+		},
+		{
+			Models: []config.PayloadModelRule{{Name: "gpt-*"}},
+			When:   []config.PayloadCondition{{Path: "user", Exists: &absent}, {Path: "model", Matches: "^gpt-5"}},
+			Set:    map[string]any{"store": false},
+		},
+	}}}
+	payload := []byte(`{"model":"gpt-5","reasoning":{"effort":"minimal"},"max_output_tokens":512,"prompt_cache_retention":"24h"}`)
+
+	out := applyPayloadConfigWithRoot(cfg, "gpt-5", "codex", "", payload, nil, "", payloadTarget{})
+
+	if got := gjson.GetBytes(out, "reasoning.effort").String(); got != "low" {
+		t.Fatalf("reasoning.effort = %q, want low", got)
+	}
+	if gjson.GetBytes(out, "max_output_tokens").Exists() || gjson.GetBytes(out, "max_tokens").Int() != 512 {
+		t.Fatalf("expected max_output_tokens renamed: %s", out)
+	}
+	if gjson.GetBytes(out, "prompt_cache_retention").Exists() {
+		t.Fatalf("expected prompt_cache_retention deleted: %s", out)
+	}
+	if store := gjson.GetBytes(out, "store"); !store.Exists() || store.Bool() {
+		t.Fatalf("expected store=false: %s", out)
+	}
+
+	other := []byte(`{"model":"gpt-5","reasoning":{"effort":"high"},"user":"u1"}`)
+	if untouched := applyPayloadConfigWithRoot(cfg, "gpt-5", "codex", "", other, nil, "", payloadTarget{}); string(untouched) != string(other) {
+		t.Fatalf("expected payload failing conditions to be unchanged, got %s", untouched)
+	}
+}
@@ -0,0 +1,176 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// maxModelListPages bounds how many pages of a paginated model list are fetched.
+const maxModelListPages = 10
+
+// FetchGeminiAPIKeyModels lists the generateContent models a Gemini API key can access through
+// models.list. It returns nil when the auth has no API key or the request fails.
+func FetchGeminiAPIKeyModels(ctx context.Context, auth *cliproxyauth.Auth, cfg *config.Config) []*registry.ModelInfo {
+	apiKey, _ := geminiCreds(auth)
+	if apiKey == "" {
+		return nil
+	}
+	baseURL := resolveGeminiBaseURL(auth)
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	now := time.Now().Unix()
+	var models []*registry.ModelInfo
+	pageToken := ""
+	for page := 0; page < maxModelListPages; page++ {
+		query := url.Values{"pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/"+glAPIVersion+"/models?"+query.Encode(), nil)
+		if errReq != nil {
+			return nil
+		}
+		httpReq.Header.Set("x-goog-api-key", apiKey)
+		applyGeminiHeaders(httpReq, auth)
+		body, ok := fetchModelList(httpClient, httpReq, "gemini", auth.ID)
+		if !ok {
+			return nil
+		}
+		for _, item := range gjson.GetBytes(body, "models").Array() {
+			if !modelSupportsMethod(item, "generateContent") {
+				continue
+			}
+			name := strings.TrimSpace(item.Get("name").String())
+			id := strings.TrimPrefix(name, "models/")
+			if id == "" {
+				continue
+			}
+			displayName := item.Get("displayName").String()
+			if displayName == "" {
+				displayName = id
+			}
+			models = append(models, &registry.ModelInfo{
+				ID:               id,
+				Object:           "model",
+				Created:          now,
+				OwnedBy:          "google",
+				Type:             "gemini",
+				Name:             name,
+				DisplayName:      displayName,
+				Description:      item.Get("description").String(),
+				InputTokenLimit:  int(item.Get("inputTokenLimit").Int()),
+				OutputTokenLimit: int(item.Get("outputTokenLimit").Int()),
+			})
+		}
+		pageToken = gjson.GetBytes(body, "nextPageToken").String()
+		if pageToken == "" {
+			break
+		}
+	}
+	return models
+}
+
+// FetchClaudeAPIKeyModels lists the models a Claude API key can access through /v1/models.
+// It returns nil when the auth has no API key or the request fails.
+func FetchClaudeAPIKeyModels(ctx context.Context, auth *cliproxyauth.Auth, cfg *config.Config) []*registry.ModelInfo {
+	if auth == nil || auth.Attributes == nil || strings.TrimSpace(auth.Attributes["api_key"]) == "" {
+		return nil
+	}
+	apiKey, baseURL := claudeCreds(auth)
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	now := time.Now().Unix()
+	var models []*registry.ModelInfo
+	afterID := ""
+	for page := 0; page < maxModelListPages; page++ {
+		query := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+		httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v1/models?"+query.Encode(), nil)
+		if errReq != nil {
+			return nil
+		}
+		if strings.EqualFold(httpReq.URL.Scheme, "https") && strings.EqualFold(httpReq.URL.Host, "api.anthropic.com") {
+			httpReq.Header.Set("x-api-key", apiKey)
+		} else {
+			httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+		applyAuthCustomHeaders(httpReq, auth)
+		body, ok := fetchModelList(httpClient, httpReq, "claude", auth.ID)
+		if !ok {
+			return nil
+		}
+		for _, item := range gjson.GetBytes(body, "data").Array() {
+			id := strings.TrimSpace(item.Get("id").String())
+			if id == "" {
+				continue
+			}
+			displayName := item.Get("display_name").String()
+			if displayName == "" {
+				displayName = id
+			}
+			created := now
+			if createdAt, errParse := time.Parse(time.RFC3339, item.Get("created_at").String()); errParse == nil {
+				created = createdAt.Unix()
+			}
+			models = append(models, &registry.ModelInfo{
+				ID:          id,
+				Object:      "model",
+				Created:     created,
+				OwnedBy:     "anthropic",
+				Type:        "claude",
+				DisplayName: displayName,
+			})
+		}
+		afterID = gjson.GetBytes(body, "last_id").String()
+		if !gjson.GetBytes(body, "has_more").Bool() || afterID == "" {
+			break
+		}
+	}
+	return models
+}
+
+// fetchModelList performs a model-list request and returns its body when it succeeded.
+func fetchModelList(httpClient *http.Client, httpReq *http.Request, provider, authID string) ([]byte, bool) {
+	httpResp, errDo := httpClient.Do(httpReq)
+	if errDo != nil {
+		log.Debugf("%s executor: models request failed for %s: %v", provider, authID, errDo)
+		return nil, false
+	}
+	body, errRead := io.ReadAll(httpResp.Body)
+	if errClose := httpResp.Body.Close(); errClose != nil {
+		log.Errorf("%s executor: close response body error: %v", provider, errClose)
+	}
+	if errRead != nil || httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
+		log.Debugf("%s executor: models request for %s returned status %d", provider, authID, httpResp.StatusCode)
+		return nil, false
+	}
+	return body, true
+}
+
+func modelSupportsMethod(item gjson.Result, method string) bool {
+	methods := item.Get("supportedGenerationMethods")
+	if !methods.Exists() {
+		return true
+	}
+	for _, m := range methods.Array() {
+		if m.String() == method {
+			return true
+		}
+	}
+	return false
+}
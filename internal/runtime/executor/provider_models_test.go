@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestFetchGeminiAPIKeyModelsPaginatesAndSkipsNonGenerative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "AIza-test" {
+			t.Errorf("x-goog-api-key = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"models":[{"name":"models/gemini-2.5-pro","displayName":"Gemini 2.5 Pro","inputTokenLimit":1048576,"supportedGenerationMethods":["generateContent","countTokens"]},{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}],"nextPageToken":"next"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":[{"name":"models/gemini-upstream-new","supportedGenerationMethods":["generateContent"]}]}`))
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{ID: "gemini-test", Provider: "gemini", Attributes: map[string]string{"api_key": "AIza-test", "base_url": server.URL}}
+	models := FetchGeminiAPIKeyModels(context.Background(), auth, &config.Config{})
+	if len(models) != 2 {
+		t.Fatalf("expected two generative models, got %d", len(models))
+	}
+	if models[0].ID != "gemini-2.5-pro" || models[0].Name != "models/gemini-2.5-pro" || models[0].InputTokenLimit != 1048576 {
+		t.Fatalf("unexpected first model: %+v", models[0])
+	}
+	if models[1].ID != "gemini-upstream-new" || models[1].DisplayName != "gemini-upstream-new" {
+		t.Fatalf("unexpected second model: %+v", models[1])
+	}
+}
+
+func TestFetchGeminiAPIKeyModelsReturnsNilOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{ID: "gemini-test", Provider: "gemini", Attributes: map[string]string{"api_key": "AIza-test", "base_url": server.URL}}
+	if models := FetchGeminiAPIKeyModels(context.Background(), auth, &config.Config{}); models != nil {
+		t.Fatalf("expected nil on upstream error, got %v", models)
+	}
+}
@@ -0,0 +1,271 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// Proxy routing canary states.
+const (
+	CanaryStateRunning    = "running"
+	CanaryStatePassed     = "passed"
+	CanaryStateRolledBack = "rolled-back"
+)
+
+const (
+	defaultCanaryWindow       = 10 * time.Minute
+	defaultCanaryMaxErrorRate = 0.2
+	defaultCanaryMinRequests  = 10
+)
+
+// ProxyRoutingCanary is a candidate reverse proxy routing tried on a share of traffic before
+// it replaces the configured routing. Empty fields and missing auth entries keep the current
+// routing.
+type ProxyRoutingCanary struct {
+	ProxyRouting     config.ProxyRouting `json:"proxy-routing"`
+	ProxyRoutingAuth map[string]string   `json:"proxy-routing-auth,omitempty"`
+	// Percent is the share of affected requests, 1 to 100, routed by the candidate.
+	Percent int `json:"percent"`
+	// WindowSeconds is how long the canary is watched for errors. <= 0 uses 600.
+	WindowSeconds int `json:"window-seconds,omitempty"`
+	// MaxErrorRate is the canary error rate, 0 to 1, above which it is rolled back. <= 0 uses 0.2.
+	MaxErrorRate float64 `json:"max-error-rate,omitempty"`
+	// MinRequests is the number of canary requests needed before the error rate is judged.
+	// <= 0 uses 10.
+	MinRequests int `json:"min-requests,omitempty"`
+}
+
+// CanaryArmStats counts the requests of one canary arm.
+type CanaryArmStats struct {
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error-rate"`
+}
+
+// ProxyRoutingCanaryStatus reports a canary and how each arm is doing. Only requests whose
+// route the candidate changes take part; the baseline arm is those left on the current routing.
+type ProxyRoutingCanaryStatus struct {
+	ProxyRoutingCanary
+	State        string         `json:"state"`
+	StartedAt    time.Time      `json:"started-at"`
+	WindowEndsAt time.Time      `json:"window-ends-at"`
+	Reason       string         `json:"reason,omitempty"`
+	Canary       CanaryArmStats `json:"canary"`
+	Baseline     CanaryArmStats `json:"baseline"`
+}
+
+var proxyCanaryState struct {
+	mu         sync.Mutex
+	current    *ProxyRoutingCanaryStatus
+	generation uint64
+}
+
+type proxyCanaryArmKey struct{}
+
+// proxyCanaryArm is the canary arm a request was assigned to.
+type proxyCanaryArm struct {
+	generation uint64
+	canary     bool
+	routing    config.ProxyRouting
+	authRoute  map[string]string
+}
+
+// StartProxyRoutingCanary starts routing spec.Percent of the affected requests through the
+// candidate routing, replacing any canary already running.
+func StartProxyRoutingCanary(spec ProxyRoutingCanary) (ProxyRoutingCanaryStatus, error) {
+	if spec.Percent < 1 || spec.Percent > 100 {
+		return ProxyRoutingCanaryStatus{}, fmt.Errorf("percent must be between 1 and 100")
+	}
+	if spec.MaxErrorRate > 1 {
+		return ProxyRoutingCanaryStatus{}, fmt.Errorf("max-error-rate must be between 0 and 1")
+	}
+	if spec.WindowSeconds <= 0 {
+		spec.WindowSeconds = int(defaultCanaryWindow / time.Second)
+	}
+	if spec.MaxErrorRate <= 0 {
+		spec.MaxErrorRate = defaultCanaryMaxErrorRate
+	}
+	if spec.MinRequests <= 0 {
+		spec.MinRequests = defaultCanaryMinRequests
+	}
+	authRoute := make(map[string]string, len(spec.ProxyRoutingAuth))
+	for key, value := range spec.ProxyRoutingAuth {
+		if k, v := strings.TrimSpace(key), strings.TrimSpace(value); k != "" && v != "" {
+			authRoute[k] = v
+		}
+	}
+	spec.ProxyRoutingAuth = authRoute
+
+	now := time.Now()
+	status := &ProxyRoutingCanaryStatus{
+		ProxyRoutingCanary: spec,
+		State:              CanaryStateRunning,
+		StartedAt:          now,
+		WindowEndsAt:       now.Add(time.Duration(spec.WindowSeconds) * time.Second),
+	}
+	proxyCanaryState.mu.Lock()
+	proxyCanaryState.current = status
+	proxyCanaryState.generation++
+	out := *status
+	proxyCanaryState.mu.Unlock()
+	log.Infof("proxy routing canary started for %d%% of traffic until %s", spec.Percent, status.WindowEndsAt.Format(time.RFC3339))
+	return out, nil
+}
+
+// StopProxyRoutingCanary ends the canary and returns it. All traffic goes back to the
+// configured routing.
+func StopProxyRoutingCanary() (ProxyRoutingCanaryStatus, bool) {
+	proxyCanaryState.mu.Lock()
+	defer proxyCanaryState.mu.Unlock()
+	if proxyCanaryState.current == nil {
+		return ProxyRoutingCanaryStatus{}, false
+	}
+	out := *proxyCanaryState.current
+	proxyCanaryState.current = nil
+	proxyCanaryState.generation++
+	return out, true
+}
+
+// ProxyRoutingCanaryState returns the current canary, if any.
+func ProxyRoutingCanaryState() (ProxyRoutingCanaryStatus, bool) {
+	proxyCanaryState.mu.Lock()
+	defer proxyCanaryState.mu.Unlock()
+	current := proxyCanaryState.current
+	if current == nil {
+		return ProxyRoutingCanaryStatus{}, false
+	}
+	current.checkWindow(time.Now())
+	return *current, true
+}
+
+// checkWindow marks a running canary as passed once its window ends without a rollback.
+// A passed canary keeps serving its share of traffic until it is promoted or stopped.
+func (s *ProxyRoutingCanaryStatus) checkWindow(now time.Time) {
+	if s.State == CanaryStateRunning && !now.Before(s.WindowEndsAt) {
+		s.State = CanaryStatePassed
+		log.Infof("proxy routing canary passed: %d of %d canary requests failed", s.Canary.Errors, s.Canary.Requests)
+	}
+}
+
+// withProxyCanaryArm assigns a request to a canary arm when a canary is active and its
+// candidate routing would send the request through a different reverse proxy.
+func withProxyCanaryArm(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, provider string) context.Context {
+	proxyCanaryState.mu.Lock()
+	current := proxyCanaryState.current
+	if current == nil {
+		proxyCanaryState.mu.Unlock()
+		return ctx
+	}
+	current.checkWindow(time.Now())
+	if current.State == CanaryStateRolledBack {
+		proxyCanaryState.mu.Unlock()
+		return ctx
+	}
+	arm := proxyCanaryArm{
+		generation: proxyCanaryState.generation,
+		routing:    current.ProxyRouting,
+		authRoute:  current.ProxyRoutingAuth,
+	}
+	percent := current.Percent
+	proxyCanaryState.mu.Unlock()
+
+	baseline := baselineProxyID(cfg, auth, provider)
+	if arm.candidateProxyID(cfg, auth, provider) == baseline {
+		return ctx
+	}
+	arm.canary = rand.Intn(100) < percent
+	return context.WithValue(ctx, proxyCanaryArmKey{}, arm)
+}
+
+// proxyIDForRequest returns the reverse proxy ID for a request, honouring the canary arm
+// assigned by withProxyCanaryArm.
+func proxyIDForRequest(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, provider string) string {
+	if ctx != nil {
+		if arm, ok := ctx.Value(proxyCanaryArmKey{}).(proxyCanaryArm); ok && arm.canary {
+			return arm.candidateProxyID(cfg, auth, provider)
+		}
+	}
+	return baselineProxyID(cfg, auth, provider)
+}
+
+func baselineProxyID(cfg *config.Config, auth *cliproxyauth.Auth, provider string) string {
+	if proxyID := resolveProxyIDForAuth(cfg, auth); proxyID != "" {
+		return proxyID
+	}
+	return strings.TrimSpace(resolveProxyIDForProvider(cfg, provider))
+}
+
+// candidateProxyID resolves the proxy ID with the candidate routing laid over the current
+// one: auth routing still takes precedence over provider routing.
+func (a proxyCanaryArm) candidateProxyID(cfg *config.Config, auth *cliproxyauth.Auth, provider string) string {
+	if proxyID := proxyIDFromAuthRouting(a.authRoute, auth); proxyID != "" {
+		return proxyID
+	}
+	if proxyID := resolveProxyIDForAuth(cfg, auth); proxyID != "" {
+		return proxyID
+	}
+	if proxyID := strings.TrimSpace(proxyIDFromRouting(a.routing, provider)); proxyID != "" {
+		return proxyID
+	}
+	return strings.TrimSpace(resolveProxyIDForProvider(cfg, provider))
+}
+
+// recordProxyCanaryOutcome counts the first attempt of a request assigned to a canary arm
+// and rolls the canary back when its error rate crosses the threshold within the window.
+func recordProxyCanaryOutcome(ctx context.Context, failed bool) {
+	arm, ok := ctx.Value(proxyCanaryArmKey{}).(proxyCanaryArm)
+	if !ok {
+		return
+	}
+	proxyCanaryState.mu.Lock()
+	current := proxyCanaryState.current
+	if current == nil || proxyCanaryState.generation != arm.generation || current.State == CanaryStateRolledBack {
+		proxyCanaryState.mu.Unlock()
+		return
+	}
+	stats := &current.Baseline
+	if arm.canary {
+		stats = &current.Canary
+	}
+	stats.Requests++
+	if failed {
+		stats.Errors++
+	}
+	stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+
+	now := time.Now()
+	rollBack := arm.canary && current.State == CanaryStateRunning && now.Before(current.WindowEndsAt) &&
+		current.Canary.Requests >= current.MinRequests && current.Canary.ErrorRate > current.MaxErrorRate
+	if !rollBack {
+		current.checkWindow(now)
+		proxyCanaryState.mu.Unlock()
+		return
+	}
+	current.State = CanaryStateRolledBack
+	current.Reason = fmt.Sprintf("canary error rate %.0f%% (%d of %d requests) exceeded %.0f%%",
+		current.Canary.ErrorRate*100, current.Canary.Errors, current.Canary.Requests, current.MaxErrorRate*100)
+	status := *current
+	proxyCanaryState.mu.Unlock()
+
+	log.Warnf("proxy routing canary rolled back: %s", status.Reason)
+	webhook.Notify(webhook.EventCanaryRolledBack, map[string]any{
+		"reason":             status.Reason,
+		"percent":            status.Percent,
+		"canary_requests":    status.Canary.Requests,
+		"canary_errors":      status.Canary.Errors,
+		"baseline_requests":  status.Baseline.Requests,
+		"baseline_errors":    status.Baseline.Errors,
+		"started_at":         status.StartedAt.UTC(),
+		"proxy_routing":      status.ProxyRouting,
+		"proxy_routing_auth": status.ProxyRoutingAuth,
+	})
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestProxyRoutingCanary_RollsBackOnErrors(t *testing.T) {
+	resetReverseProxyBanState()
+	t.Cleanup(func() { StopProxyRoutingCanary() })
+	var proxyCalls int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyCalls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer direct.Close()
+
+	cfg := &config.Config{
+		ReverseProxies: []config.ReverseProxy{{ID: "new", Name: "new", BaseURL: proxy.URL, Enabled: true}},
+	}
+	if _, err := StartProxyRoutingCanary(ProxyRoutingCanary{ProxyRouting: config.ProxyRouting{Codex: "new"}, Percent: 100, MinRequests: 2}); err != nil {
+		t.Fatalf("StartProxyRoutingCanary() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := newTestPipeline(cfg).send(context.Background(), direct.URL+"/responses")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}
+	status, ok := ProxyRoutingCanaryState()
+	if !ok || status.State != CanaryStateRolledBack {
+		t.Fatalf("status = %+v, want rolled back", status)
+	}
+	if proxyCalls != 2 || status.Canary.Requests != 2 || status.Canary.Errors != 2 {
+		t.Fatalf("proxy calls = %d, canary = %+v", proxyCalls, status.Canary)
+	}
+}
+
+func TestProxyRoutingCanary_OnlyCountsChangedRoutes(t *testing.T) {
+	t.Cleanup(func() { StopProxyRoutingCanary() })
+	cfg := &config.Config{ProxyRouting: config.ProxyRouting{Codex: "old"}}
+	if _, err := StartProxyRoutingCanary(ProxyRoutingCanary{ProxyRouting: config.ProxyRouting{Claude: "new"}, Percent: 100}); err != nil {
+		t.Fatalf("StartProxyRoutingCanary() error = %v", err)
+	}
+
+	ctx := withProxyCanaryArm(context.Background(), cfg, nil, "codex")
+	if got := proxyIDForRequest(ctx, cfg, nil, "codex"); got != "old" {
+		t.Fatalf("codex proxy = %q, want old", got)
+	}
+	recordProxyCanaryOutcome(ctx, true)
+
+	ctx = withProxyCanaryArm(context.Background(), cfg, nil, "claude")
+	if got := proxyIDForRequest(ctx, cfg, nil, "claude"); got != "new" {
+		t.Fatalf("claude proxy = %q, want new", got)
+	}
+	recordProxyCanaryOutcome(ctx, false)
+
+	status, _ := ProxyRoutingCanaryState()
+	if status.Canary.Requests != 1 || status.Canary.Errors != 0 || status.Baseline.Requests != 0 {
+		t.Fatalf("canary = %+v, baseline = %+v", status.Canary, status.Baseline)
+	}
+}
+
+func TestStartProxyRoutingCanary_RejectsInvalidPercent(t *testing.T) {
+	if _, err := StartProxyRoutingCanary(ProxyRoutingCanary{Percent: 0}); err == nil {
+		t.Fatalf("expected error for zero percent")
+	}
+}
@@ -12,6 +12,8 @@ import (
 func resetReverseProxyBanState() {
 	reverseProxyBanState.mu.Lock()
 	reverseProxyBanState.bannedTill = make(map[string]time.Time)
+	reverseProxyBanState.nextProbe = make(map[string]time.Time)
+	reverseProxyBanState.probing = make(map[string]bool)
 	reverseProxyBanState.mu.Unlock()
 }
 
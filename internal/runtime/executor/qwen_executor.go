@@ -1,7 +1,6 @@
 package executor
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -95,7 +94,7 @@ func (e *QwenExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	url := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
@@ -137,7 +136,7 @@ func (e *QwenExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req
 		b, _ := io.ReadAll(httpResp.Body)
 		appendAPIResponseChunk(ctx, e.cfg, b)
 		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = newQwenStatusErr(httpResp.StatusCode, b, httpResp.Header, time.Now())
 		return resp, err
 	}
 	data, err := io.ReadAll(httpResp.Body)
@@ -146,7 +145,9 @@ func (e *QwenExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req
 		return resp, err
 	}
 	appendAPIResponseChunk(ctx, e.cfg, data)
+	reporter.observeSystemFingerprint(data)
 	reporter.publish(ctx, parseOpenAIUsage(data))
+	reporter.ensurePublished(ctx)
 	var param any
 	// Note: TranslateNonStream uses req.Model (original with suffix) to preserve
 	// the original model name in the response for client compatibility.
@@ -192,7 +193,7 @@ func (e *QwenExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Aut
 	}
 	body, _ = sjson.SetBytes(body, "stream_options.include_usage", true)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel, newPayloadTarget(e.Identifier(), opts))
 
 	url := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
@@ -232,7 +233,7 @@ func (e *QwenExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Aut
 		if errClose := httpResp.Body.Close(); errClose != nil {
 			log.Errorf("qwen executor: close response body error: %v", errClose)
 		}
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = newQwenStatusErr(httpResp.StatusCode, b, httpResp.Header, time.Now())
 		return nil, err
 	}
 	out := make(chan cliproxyexecutor.StreamChunk)
@@ -244,29 +245,21 @@ func (e *QwenExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Aut
 				log.Errorf("qwen executor: close response body error: %v", errClose)
 			}
 		}()
-		scanner := bufio.NewScanner(httpResp.Body)
-		scanner.Buffer(nil, 52_428_800) // 50MB
 		var param any
-		for scanner.Scan() {
-			line := scanner.Bytes()
-			appendAPIResponseChunk(ctx, e.cfg, line)
-			if detail, ok := parseOpenAIStreamUsage(line); ok {
-				reporter.publish(ctx, detail)
-			}
+		var streamUsage openAIStreamUsage
+		scanStreamLines(ctx, e.cfg, httpResp.Body, reporter, out, func(line []byte) {
+			reporter.observeSystemFingerprint(line)
+			streamUsage.observe(line)
 			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, opts.OriginalRequest, body, line, &param)
 			for i := range chunks {
 				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
 			}
-		}
+		})
 		doneChunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, opts.OriginalRequest, body, []byte("[DONE]"), &param)
 		for i := range doneChunks {
 			out <- cliproxyexecutor.StreamChunk{Payload: []byte(doneChunks[i])}
 		}
-		if errScan := scanner.Err(); errScan != nil {
-			recordAPIResponseError(ctx, e.cfg, errScan)
-			reporter.publishFailure(ctx)
-			out <- cliproxyexecutor.StreamChunk{Err: errScan}
-		}
+		streamUsage.publish(ctx, reporter)
 	}()
 	return stream, nil
 }
@@ -373,3 +366,39 @@ func qwenCreds(a *cliproxyauth.Auth) (token, baseURL string) {
 	}
 	return
 }
+
+// qwenQuotaResetZone is the time zone whose midnight resets the Qwen free tier daily quota.
+var qwenQuotaResetZone = time.FixedZone("UTC+8", 8*60*60)
+
+// newQwenStatusErr converts a Qwen error response. An exhausted daily free tier quota (403 or
+// 429 with insufficient_quota) becomes a 429 cooling the auth down until the quota resets;
+// other rate limits use the reset advertised in the response headers.
+func newQwenStatusErr(statusCode int, body []byte, headers http.Header, now time.Time) statusErr {
+	sErr := statusErr{code: statusCode, msg: string(body)}
+	if (statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests) && isQwenQuotaError(body) {
+		sErr.code = http.StatusTooManyRequests
+		retryAfter := nextQwenQuotaReset(now).Sub(now)
+		sErr.retryAfter = &retryAfter
+		sErr.quotaReason = "qwen_daily_quota"
+		return sErr
+	}
+	if statusCode == http.StatusTooManyRequests {
+		sErr.retryAfter = rateLimitRetryAfter(headers)
+	}
+	return sErr
+}
+
+func isQwenQuotaError(body []byte) bool {
+	for _, path := range []string{"error.code", "error.type", "code"} {
+		if strings.EqualFold(gjson.GetBytes(body, path).String(), "insufficient_quota") {
+			return true
+		}
+	}
+	return false
+}
+
+// nextQwenQuotaReset returns the next midnight in qwenQuotaResetZone after now.
+func nextQwenQuotaReset(now time.Time) time.Time {
+	local := now.In(qwenQuotaResetZone)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, qwenQuotaResetZone)
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewQwenStatusErr_DailyQuotaCoolsDownUntilReset(t *testing.T) {
+	// 2026-03-01 15:30 UTC is 23:30 in UTC+8, half an hour before the quota resets.
+	now := time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC)
+	body := []byte(`{"error":{"code":"insufficient_quota","message":"Free allocated quota exceeded.","type":"insufficient_quota"}}`)
+	sErr := newQwenStatusErr(http.StatusForbidden, body, nil, now)
+	if sErr.code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", sErr.code)
+	}
+	if sErr.quotaReason != "qwen_daily_quota" {
+		t.Fatalf("expected qwen_daily_quota, got %q", sErr.quotaReason)
+	}
+	if sErr.retryAfter == nil || *sErr.retryAfter != 30*time.Minute {
+		t.Fatalf("expected 30m cooldown, got %v", sErr.retryAfter)
+	}
+}
+
+func TestNewQwenStatusErr_RateLimitHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-Ratelimit-Reset-Requests", "1m30s")
+	headers.Set("X-Ratelimit-Reset-Tokens", "20s")
+	sErr := newQwenStatusErr(http.StatusTooManyRequests, []byte(`{"error":{"code":"rate_limit_exceeded"}}`), headers, time.Now())
+	if sErr.retryAfter == nil || *sErr.retryAfter != 90*time.Second {
+		t.Fatalf("expected 90s cooldown, got %v", sErr.retryAfter)
+	}
+	if sErr.quotaReason != "" {
+		t.Fatalf("expected no quota reason, got %q", sErr.quotaReason)
+	}
+
+	headers.Set("Retry-After", "5")
+	sErr = newQwenStatusErr(http.StatusTooManyRequests, nil, headers, time.Now())
+	if sErr.retryAfter == nil || *sErr.retryAfter != 5*time.Second {
+		t.Fatalf("expected Retry-After to win, got %v", sErr.retryAfter)
+	}
+}
+
+func TestNewQwenStatusErr_ForbiddenWithoutQuota(t *testing.T) {
+	sErr := newQwenStatusErr(http.StatusForbidden, []byte(`{"error":{"code":"invalid_api_key"}}`), nil, time.Now())
+	if sErr.code != http.StatusForbidden || sErr.retryAfter != nil {
+		t.Fatalf("expected plain 403, got %d retryAfter=%v", sErr.code, sErr.retryAfter)
+	}
+}
@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestPipeline sends an executor's upstream HTTP request. It owns the steps every
+// provider repeats: reverse proxy routing with a single direct retry when the proxy fails,
+// upstream request/response logging and conversion of error responses.
+// Providers plug in through newRequest and statusError.
+type requestPipeline struct {
+	cfg      *config.Config
+	auth     *cliproxyauth.Auth
+	provider string
+	client   *http.Client
+	// body is the payload recorded in the request log.
+	body []byte
+	// newRequest builds the upstream request for url, including provider headers.
+	newRequest func(ctx context.Context, url string) (*http.Request, error)
+	// statusError converts a non-2xx response into the executor's error. Defaults to statusErr.
+	statusError func(ctx context.Context, statusCode int, body []byte, headers http.Header) error
+}
+
+// upstreamFailure is a non-2xx upstream response whose body has been read and closed.
+type upstreamFailure struct {
+	statusCode int
+	body       []byte
+	headers    http.Header
+}
+
+func newRequestPipeline(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, provider string, body []byte, newRequest func(ctx context.Context, url string) (*http.Request, error)) *requestPipeline {
+	return &requestPipeline{
+		cfg:        cfg,
+		auth:       auth,
+		provider:   provider,
+		client:     newProxyAwareHTTPClient(ctx, cfg, auth, 0),
+		body:       body,
+		newRequest: newRequest,
+	}
+}
+
+// send issues the request for originalURL through the reverse proxy configured for the auth
+// or provider, or by a running proxy routing canary. When the proxy fails with an error that warrants a ban, the proxy is banned
+// temporarily and the request is retried once against originalURL. While a proxy is banned,
+// reverse-proxy-probe-interval-seconds lets one request per interval probe it; a successful
+// probe lifts the ban.
+// On success the caller owns the response body.
+func (p *requestPipeline) send(ctx context.Context, originalURL string) (*http.Response, error) {
+	ctx = withProxyCanaryArm(ctx, p.cfg, p.auth, p.provider)
+	proxyID := proxyIDForRequest(ctx, p.cfg, p.auth, p.provider)
+	route := resolveReverseProxyRouteWithID(p.cfg, proxyID, p.provider, originalURL)
+	route = resolveReverseProxyProbe(p.cfg, route, p.provider, originalURL)
+	if route.ProxyID != "" {
+		debugtrace.Record(ctx, "proxy", "reverse proxy route", map[string]any{
+			"provider": p.provider,
+			"proxy_id": route.ProxyID,
+			"proxied":  route.Proxied,
+			"probe":    route.Probe,
+			"url":      route.URL,
+		})
+	}
+	httpResp, failure, err := p.attempt(ctx, route.URL, "request error")
+	recordProxyCanaryOutcome(ctx, err != nil || failure.routeFailed())
+	if route.Probe {
+		finishReverseProxyProbe(route.ProxyID, p.provider, err == nil && !failure.routeFailed())
+		if err != nil && ctx.Err() == nil {
+			// The probe carries a real request, so a proxy that is still down must not fail it.
+			logWithRequestID(ctx).Warnf("%s executor: reverse proxy probe failed, retrying direct upstream: %v", p.provider, err)
+			route.Proxied = false
+			httpResp, failure, err = p.attempt(ctx, originalURL, "retry request error")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if failure == nil {
+		return httpResp, nil
+	}
+	if route.Proxied && shouldBanReverseProxyOnError(failure.statusCode, string(failure.body)) {
+		banReverseProxyTemporarily(route.ProxyID, p.provider, failure.statusCode, string(failure.body))
+		debugtrace.Record(ctx, "proxy", "reverse proxy banned, retrying direct", map[string]any{
+			"proxy_id": route.ProxyID,
+			"status":   failure.statusCode,
+		})
+		logWithRequestID(ctx).Warnf("%s executor: reverse proxy failed, retrying direct upstream: %s", p.provider, originalURL)
+		httpResp, failure, err = p.attempt(ctx, originalURL, "retry request error")
+		if err != nil {
+			return nil, err
+		}
+		if failure == nil {
+			return httpResp, nil
+		}
+	}
+	return nil, p.failureError(ctx, failure)
+}
+
+// sendDirect issues the request for url without reverse proxy routing.
+func (p *requestPipeline) sendDirect(ctx context.Context, url string) (*http.Response, error) {
+	httpResp, failure, err := p.attempt(ctx, url, "request error")
+	if err != nil {
+		return nil, err
+	}
+	if failure != nil {
+		return nil, p.failureError(ctx, failure)
+	}
+	return httpResp, nil
+}
+
+// attempt performs a single request. A 2xx response is returned with its body open;
+// any other status is read, logged and returned as an upstreamFailure.
+func (p *requestPipeline) attempt(ctx context.Context, url string, label string) (*http.Response, *upstreamFailure, error) {
+	httpReq, err := p.newRequest(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	var authID, authLabel, authType, authValue string
+	if p.auth != nil {
+		authID = p.auth.ID
+		authLabel = p.auth.Label
+		authType, authValue = p.auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, p.cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    httpReq.Method,
+		Headers:   httpReq.Header.Clone(),
+		Body:      p.body,
+		Provider:  p.provider,
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, p.cfg, err)
+		return nil, nil, err
+	}
+	recordAPIResponseMetadata(ctx, p.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
+		return httpResp, nil, nil
+	}
+	b, errRead := io.ReadAll(httpResp.Body)
+	if errClose := httpResp.Body.Close(); errClose != nil {
+		log.Errorf("%s executor: close response body error: %v", p.provider, errClose)
+	}
+	if errRead != nil {
+		recordAPIResponseError(ctx, p.cfg, errRead)
+		return nil, nil, errRead
+	}
+	appendAPIResponseChunk(ctx, p.cfg, b)
+	logWithRequestID(ctx).Debugf("%s, error status: %d, error message: %s", label, httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+	return nil, &upstreamFailure{statusCode: httpResp.StatusCode, body: b, headers: httpResp.Header}, nil
+}
+
+// routeFailed reports whether the failure points at the route rather than the request: a
+// server error or a reverse proxy error. A nil failure is a success.
+func (f *upstreamFailure) routeFailed() bool {
+	if f == nil {
+		return false
+	}
+	return f.statusCode >= http.StatusInternalServerError || shouldBanReverseProxyOnError(f.statusCode, string(f.body))
+}
+
+func (p *requestPipeline) failureError(ctx context.Context, failure *upstreamFailure) error {
+	if p.statusError != nil {
+		return p.statusError(ctx, failure.statusCode, failure.body, failure.headers)
+	}
+	return statusErr{code: failure.statusCode, msg: string(failure.body)}
+}
+
+// scanStreamLines reads a streaming response body line by line, records every line in the
+// request log and passes it to handle. A read failure is recorded, reported as a failed
+// request through reporter and forwarded to out.
+func scanStreamLines(ctx context.Context, cfg *config.Config, body io.Reader, reporter *usageReporter, out chan<- cliproxyexecutor.StreamChunk, handle func(line []byte)) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, streamScannerBuffer)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		appendAPIResponseChunk(ctx, cfg, line)
+		handle(line)
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		recordAPIResponseError(ctx, cfg, errScan)
+		reporter.publishFailure(ctx)
+		out <- cliproxyexecutor.StreamChunk{Err: errScan}
+	}
+}
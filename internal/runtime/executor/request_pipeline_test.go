@@ -0,0 +1,155 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func newTestPipeline(cfg *config.Config) *requestPipeline {
+	return newRequestPipeline(context.Background(), cfg, nil, "codex", []byte(`{}`), func(ctx context.Context, url string) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(`{}`))
+	})
+}
+
+func TestRequestPipelineSend_FallsBackToDirectWhenProxyFails(t *testing.T) {
+	resetReverseProxyBanState()
+	var proxyCalls int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyCalls++
+		if r.URL.Path != "/codex/responses" {
+			t.Errorf("unexpected proxy path %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer direct.Close()
+
+	cfg := &config.Config{
+		ProxyRouting:   config.ProxyRouting{Codex: "deno-1"},
+		ReverseProxies: []config.ReverseProxy{{ID: "deno-1", Name: "deno-1", BaseURL: proxy.URL, Enabled: true}},
+	}
+	resp, err := newTestPipeline(cfg).send(context.Background(), direct.URL+"/responses")
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" || proxyCalls != 1 {
+		t.Fatalf("body = %q, proxy calls = %d", body, proxyCalls)
+	}
+	if !isReverseProxyTemporarilyBanned("deno-1") {
+		t.Fatalf("expected failing reverse proxy to be banned")
+	}
+}
+
+func TestRequestPipelineSend_ProbesBannedProxyAndRestoresIt(t *testing.T) {
+	resetReverseProxyBanState()
+	proxyStatus := http.StatusNotFound
+	var proxyCalls, directCalls int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyCalls++
+		w.WriteHeader(proxyStatus)
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		directCalls++
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer direct.Close()
+
+	cfg := &config.Config{
+		ReverseProxyProbeIntervalSeconds: 60,
+		ProxyRouting:                     config.ProxyRouting{Codex: "deno-1"},
+		ReverseProxies:                   []config.ReverseProxy{{ID: "deno-1", Name: "deno-1", BaseURL: proxy.URL, Enabled: true}},
+	}
+	send := func() {
+		t.Helper()
+		resp, err := newTestPipeline(cfg).send(context.Background(), direct.URL+"/responses")
+		if err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	banReverseProxyTemporarily("deno-1", "codex", http.StatusNotFound, "not found")
+
+	// The first request probes the proxy, which still fails, and falls back to direct.
+	send()
+	if proxyCalls != 1 || directCalls != 1 || !isReverseProxyTemporarilyBanned("deno-1") {
+		t.Fatalf("failed probe: proxy calls = %d, direct calls = %d, banned = %t", proxyCalls, directCalls, isReverseProxyTemporarilyBanned("deno-1"))
+	}
+	// Within the probe interval traffic stays direct.
+	send()
+	if proxyCalls != 1 || directCalls != 2 {
+		t.Fatalf("within interval: proxy calls = %d, direct calls = %d", proxyCalls, directCalls)
+	}
+
+	reverseProxyBanState.mu.Lock()
+	reverseProxyBanState.nextProbe["deno-1"] = time.Now().Add(-time.Second)
+	reverseProxyBanState.mu.Unlock()
+	proxyStatus = http.StatusOK
+	send()
+	if proxyCalls != 2 || directCalls != 2 || isReverseProxyTemporarilyBanned("deno-1") {
+		t.Fatalf("successful probe: proxy calls = %d, direct calls = %d, banned = %t", proxyCalls, directCalls, isReverseProxyTemporarilyBanned("deno-1"))
+	}
+}
+
+func TestRequestPipelineSend_UsesStatusErrorHook(t *testing.T) {
+	resetReverseProxyBanState()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, "slow down")
+	}))
+	defer server.Close()
+
+	pipeline := newTestPipeline(&config.Config{})
+	if _, err := pipeline.send(context.Background(), server.URL); err == nil {
+		t.Fatalf("expected status error")
+	} else if se, ok := err.(statusErr); !ok || se.StatusCode() != http.StatusTooManyRequests || se.msg != "slow down" {
+		t.Fatalf("unexpected default error %#v", err)
+	}
+
+	errHook := errors.New("converted")
+	pipeline.statusError = func(_ context.Context, statusCode int, body []byte, _ http.Header) error {
+		if statusCode != http.StatusTooManyRequests || string(body) != "slow down" {
+			t.Errorf("hook got %d %q", statusCode, body)
+		}
+		return errHook
+	}
+	if _, err := pipeline.sendDirect(context.Background(), server.URL); !errors.Is(err, errHook) {
+		t.Fatalf("sendDirect() error = %v, want hook error", err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestScanStreamLines_ForwardsReadError(t *testing.T) {
+	reporter := newUsageReporter(context.Background(), "codex", "gpt-5", nil)
+	out := make(chan cliproxyexecutor.StreamChunk, 1)
+	var lines int
+	scanStreamLines(context.Background(), &config.Config{}, io.MultiReader(strings.NewReader("a\nb\n"), failingReader{}), reporter, out, func([]byte) { lines++ })
+	if lines != 2 {
+		t.Fatalf("handled %d lines, want 2", lines)
+	}
+	select {
+	case chunk := <-out:
+		if chunk.Err == nil || chunk.Err.Error() != "connection reset" {
+			t.Fatalf("unexpected chunk %+v", chunk)
+		}
+	default:
+		t.Fatalf("expected read error to be forwarded")
+	}
+}
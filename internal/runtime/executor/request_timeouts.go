@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// Built-in upstream timeouts used when request-timeouts leaves a value unset.
+// Response header and non-streaming totals stay unbounded by default because
+// reasoning models can legitimately take minutes before responding.
+const (
+	defaultConnectTimeoutSeconds      = 30
+	defaultTLSHandshakeTimeoutSeconds = 15
+	upstreamDialKeepAlive             = 30 * time.Second
+)
+
+// upstreamTimeouts holds resolved upstream timeouts; zero disables a timeout.
+type upstreamTimeouts struct {
+	connect        time.Duration
+	tlsHandshake   time.Duration
+	responseHeader time.Duration
+	nonStream      time.Duration
+}
+
+// resolveUpstreamTimeouts merges built-in defaults with the global, provider, and endpoint
+// levels of request-timeouts. Later levels override earlier ones when set.
+func resolveUpstreamTimeouts(cfg *config.Config, provider string, endpoint cliproxyexecutor.Endpoint) upstreamTimeouts {
+	merged := config.RequestTimeouts{
+		ConnectSeconds:      defaultConnectTimeoutSeconds,
+		TLSHandshakeSeconds: defaultTLSHandshakeTimeoutSeconds,
+	}
+	if cfg != nil {
+		mergeRequestTimeouts(&merged, cfg.RequestTimeouts.RequestTimeouts)
+		if providerTimeouts, ok := cfg.RequestTimeouts.Providers[strings.ToLower(provider)]; ok && provider != "" {
+			mergeRequestTimeouts(&merged, providerTimeouts.RequestTimeouts)
+			if endpointTimeouts, okEndpoint := providerTimeouts.Endpoints[string(endpoint)]; okEndpoint && endpoint != "" {
+				mergeRequestTimeouts(&merged, endpointTimeouts)
+			}
+		}
+	}
+	resolved := upstreamTimeouts{
+		connect:        secondsToTimeout(merged.ConnectSeconds),
+		tlsHandshake:   secondsToTimeout(merged.TLSHandshakeSeconds),
+		responseHeader: secondsToTimeout(merged.ResponseHeaderSeconds),
+	}
+	if endpoint == cliproxyexecutor.EndpointExecute || endpoint == cliproxyexecutor.EndpointCountTokens {
+		resolved.nonStream = secondsToTimeout(merged.NonStreamSeconds)
+	}
+	return resolved
+}
+
+func mergeRequestTimeouts(dst *config.RequestTimeouts, src config.RequestTimeouts) {
+	if src.ConnectSeconds != 0 {
+		dst.ConnectSeconds = src.ConnectSeconds
+	}
+	if src.TLSHandshakeSeconds != 0 {
+		dst.TLSHandshakeSeconds = src.TLSHandshakeSeconds
+	}
+	if src.ResponseHeaderSeconds != 0 {
+		dst.ResponseHeaderSeconds = src.ResponseHeaderSeconds
+	}
+	if src.NonStreamSeconds != 0 {
+		dst.NonStreamSeconds = src.NonStreamSeconds
+	}
+}
+
+func secondsToTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// timeoutProviderKey returns the provider key used to look up per-provider timeouts.
+func timeoutProviderKey(auth *cliproxyauth.Auth) string {
+	if auth == nil {
+		return ""
+	}
+	if auth.Attributes != nil {
+		if key := strings.TrimSpace(auth.Attributes["provider_key"]); key != "" {
+			return strings.ToLower(key)
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(auth.Provider))
+}
+
+// applyTransportTimeouts installs connection-level timeouts on a transport owned by the executor.
+func applyTransportTimeouts(transport *http.Transport, timeouts upstreamTimeouts) {
+	if transport == nil {
+		return
+	}
+	if timeouts.connect > 0 {
+		if dial := transport.DialContext; dial != nil {
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialCtx, cancel := context.WithTimeout(ctx, timeouts.connect)
+				defer cancel()
+				return dial(dialCtx, network, addr)
+			}
+		} else {
+			// The standard library only negotiates HTTP/2 by itself while the dial and TLS
+			// settings are untouched; keep it for transports that had it before the dialer.
+			if transport.TLSClientConfig == nil && transport.DialTLSContext == nil && transport.DialTLS == nil && transport.Dial == nil {
+				transport.ForceAttemptHTTP2 = true
+			}
+			transport.DialContext = (&net.Dialer{Timeout: timeouts.connect, KeepAlive: upstreamDialKeepAlive}).DialContext
+		}
+	}
+	transport.TLSHandshakeTimeout = timeouts.tlsHandshake
+	transport.ResponseHeaderTimeout = timeouts.responseHeader
+}
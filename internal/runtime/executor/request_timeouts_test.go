@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestResolveUpstreamTimeoutsLayering(t *testing.T) {
+	cfg := &config.Config{RequestTimeouts: config.RequestTimeoutConfig{
+		RequestTimeouts: config.RequestTimeouts{ResponseHeaderSeconds: 60, NonStreamSeconds: 300},
+		Providers: map[string]config.ProviderRequestTimeouts{
+			"codex": {
+				RequestTimeouts: config.RequestTimeouts{ConnectSeconds: 5, NonStreamSeconds: 900},
+				Endpoints: map[string]config.RequestTimeouts{
+					"count-tokens": {NonStreamSeconds: 20, TLSHandshakeSeconds: -1},
+				},
+			},
+		},
+	}}
+
+	defaults := resolveUpstreamTimeouts(nil, "", "")
+	if defaults.connect != 30*time.Second || defaults.tlsHandshake != 15*time.Second || defaults.responseHeader != 0 || defaults.nonStream != 0 {
+		t.Fatalf("unexpected defaults: %+v", defaults)
+	}
+
+	global := resolveUpstreamTimeouts(cfg, "claude", cliproxyexecutor.EndpointExecute)
+	if global.connect != 30*time.Second || global.responseHeader != time.Minute || global.nonStream != 5*time.Minute {
+		t.Fatalf("unexpected global timeouts: %+v", global)
+	}
+
+	provider := resolveUpstreamTimeouts(cfg, "codex", cliproxyexecutor.EndpointExecute)
+	if provider.connect != 5*time.Second || provider.nonStream != 15*time.Minute {
+		t.Fatalf("unexpected provider timeouts: %+v", provider)
+	}
+
+	endpoint := resolveUpstreamTimeouts(cfg, "codex", cliproxyexecutor.EndpointCountTokens)
+	if endpoint.nonStream != 20*time.Second || endpoint.tlsHandshake != 0 || endpoint.connect != 5*time.Second {
+		t.Fatalf("unexpected endpoint timeouts: %+v", endpoint)
+	}
+
+	stream := resolveUpstreamTimeouts(cfg, "codex", cliproxyexecutor.EndpointStream)
+	if stream.nonStream != 0 || stream.responseHeader != time.Minute {
+		t.Fatalf("streaming requests must not get a total timeout: %+v", stream)
+	}
+}
+
+func TestNewProxyAwareHTTPClientAppliesTimeouts(t *testing.T) {
+	cfg := &config.Config{RequestTimeouts: config.RequestTimeoutConfig{
+		RequestTimeouts: config.RequestTimeouts{ResponseHeaderSeconds: 45, NonStreamSeconds: 120},
+	}}
+	auth := &cliproxyauth.Auth{Provider: "claude"}
+
+	ctx := cliproxyexecutor.WithEndpoint(context.Background(), cliproxyexecutor.EndpointExecute)
+	client := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	if client.Timeout != 2*time.Minute {
+		t.Fatalf("client timeout = %s, want 2m", client.Timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", client.Transport)
+	}
+	if transport.ResponseHeaderTimeout != 45*time.Second || transport.TLSHandshakeTimeout != 15*time.Second || transport.DialContext == nil {
+		t.Fatalf("transport timeouts not applied: header=%s tls=%s", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout)
+	}
+	if !transport.ForceAttemptHTTP2 {
+		t.Fatal("the connect timeout dialer must not turn off HTTP/2")
+	}
+
+	streamCtx := cliproxyexecutor.WithEndpoint(context.Background(), cliproxyexecutor.EndpointStream)
+	if client := newProxyAwareHTTPClient(streamCtx, cfg, auth, 0); client.Timeout != 0 {
+		t.Fatalf("stream client timeout = %s, want none", client.Timeout)
+	}
+	if client := newProxyAwareHTTPClient(ctx, cfg, auth, 10*time.Second); client.Timeout != 10*time.Second {
+		t.Fatalf("explicit timeout must win, got %s", client.Timeout)
+	}
+}
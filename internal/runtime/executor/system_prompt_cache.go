@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"strings"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// claudeMaxCacheBreakpoints is the number of cache_control breakpoints Anthropic accepts per request.
+const claudeMaxCacheBreakpoints = 4
+
+// applySystemPromptCacheKey turns the caching key system-prompt-dedup assigned to a repeated
+// system prompt into the provider's prompt caching hint. Keys set by the client win.
+func applySystemPromptCacheKey(protocol string, opts cliproxyexecutor.Options, body []byte) []byte {
+	key, _ := opts.Metadata[cliproxyexecutor.SystemPromptCacheKeyMetadataKey].(string)
+	if strings.TrimSpace(key) == "" {
+		return body
+	}
+	switch protocol {
+	case "claude":
+		if countCacheControls(body) < claudeMaxCacheBreakpoints {
+			return injectSystemCacheControl(body)
+		}
+	case "codex", "openai":
+		if !gjson.GetBytes(body, "prompt_cache_key").Exists() {
+			if updated, err := sjson.SetBytes(body, "prompt_cache_key", key); err == nil {
+				return updated
+			}
+		}
+	}
+	return body
+}
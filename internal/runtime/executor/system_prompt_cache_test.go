@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplySystemPromptCacheKey(t *testing.T) {
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.SystemPromptCacheKeyMetadataKey: "cliproxy-sys-abc"}}
+
+	out := applySystemPromptCacheKey("codex", opts, []byte(`{"model":"gpt-5"}`))
+	if got := gjson.GetBytes(out, "prompt_cache_key").String(); got != "cliproxy-sys-abc" {
+		t.Fatalf("codex prompt_cache_key = %q", got)
+	}
+	out = applySystemPromptCacheKey("openai", opts, []byte(`{"prompt_cache_key":"client"}`))
+	if got := gjson.GetBytes(out, "prompt_cache_key").String(); got != "client" {
+		t.Fatalf("client prompt_cache_key overwritten: %q", got)
+	}
+
+	claude := []byte(`{"system":"long prompt","messages":[{"role":"user","content":[{"type":"text","text":"hi","cache_control":{"type":"ephemeral"}}]}]}`)
+	out = applySystemPromptCacheKey("claude", opts, claude)
+	if !gjson.GetBytes(out, "system.0.cache_control").Exists() {
+		t.Fatalf("claude system breakpoint missing: %s", out)
+	}
+
+	out = applySystemPromptCacheKey("codex", cliproxyexecutor.Options{}, []byte(`{"model":"gpt-5"}`))
+	if gjson.GetBytes(out, "prompt_cache_key").Exists() {
+		t.Fatalf("prompt_cache_key set without a dedup key: %s", out)
+	}
+}
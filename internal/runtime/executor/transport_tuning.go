@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// defaultIdleConnTimeout matches http.DefaultTransport so executor-owned transports
+// keep the standard library's connection reuse unless upstream-transport overrides it.
+const defaultIdleConnTimeout = 90 * time.Second
+
+// upstreamTransportTuning holds resolved transport settings; zero durations disable a feature.
+// With neither forceHTTP2 nor disableHTTP2 set, the transport keeps the standard library's
+// HTTP/2 behaviour.
+type upstreamTransportTuning struct {
+	forceHTTP2         bool
+	disableHTTP2       bool
+	h2ReadIdle         time.Duration
+	h2PingTimeout      time.Duration
+	idleConnTimeout    time.Duration
+	disableCompression bool
+}
+
+// resolveUpstreamTransportTuning merges built-in defaults with the global and provider levels
+// of upstream-transport. Compression is only disabled for streaming requests.
+func resolveUpstreamTransportTuning(cfg *config.Config, provider string, endpoint cliproxyexecutor.Endpoint) upstreamTransportTuning {
+	var merged config.TransportTuning
+	if cfg != nil {
+		mergeTransportTuning(&merged, cfg.UpstreamTransport.TransportTuning)
+		if providerTuning, ok := cfg.UpstreamTransport.Providers[strings.ToLower(provider)]; ok && provider != "" {
+			mergeTransportTuning(&merged, providerTuning)
+		}
+	}
+	resolved := upstreamTransportTuning{
+		h2ReadIdle:      secondsToTimeout(merged.H2ReadIdleSeconds),
+		h2PingTimeout:   secondsToTimeout(merged.H2PingTimeoutSeconds),
+		idleConnTimeout: defaultIdleConnTimeout,
+	}
+	if merged.ForceHTTP2 != nil {
+		resolved.forceHTTP2 = *merged.ForceHTTP2
+		resolved.disableHTTP2 = !*merged.ForceHTTP2
+	}
+	if merged.IdleConnTimeoutSeconds > 0 {
+		resolved.idleConnTimeout = time.Duration(merged.IdleConnTimeoutSeconds) * time.Second
+	}
+	if endpoint == cliproxyexecutor.EndpointStream && merged.DisableStreamCompression != nil {
+		resolved.disableCompression = *merged.DisableStreamCompression
+	}
+	return resolved
+}
+
+func mergeTransportTuning(dst *config.TransportTuning, src config.TransportTuning) {
+	if src.ForceHTTP2 != nil {
+		dst.ForceHTTP2 = src.ForceHTTP2
+	}
+	if src.H2ReadIdleSeconds != 0 {
+		dst.H2ReadIdleSeconds = src.H2ReadIdleSeconds
+	}
+	if src.H2PingTimeoutSeconds != 0 {
+		dst.H2PingTimeoutSeconds = src.H2PingTimeoutSeconds
+	}
+	if src.IdleConnTimeoutSeconds != 0 {
+		dst.IdleConnTimeoutSeconds = src.IdleConnTimeoutSeconds
+	}
+	if src.DisableStreamCompression != nil {
+		dst.DisableStreamCompression = src.DisableStreamCompression
+	}
+}
+
+// applyTransportTuning installs HTTP/2 and keep-alive settings on a transport owned by the executor.
+func applyTransportTuning(transport *http.Transport, tuning upstreamTransportTuning) {
+	if transport == nil {
+		return
+	}
+	transport.IdleConnTimeout = tuning.idleConnTimeout
+	transport.DisableCompression = tuning.disableCompression
+	if tuning.disableHTTP2 {
+		// A non-nil empty map disables the automatic HTTP/2 upgrade.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return
+	}
+	if tuning.forceHTTP2 {
+		// A custom dialer disables HTTP/2 unless it is requested explicitly.
+		transport.ForceAttemptHTTP2 = true
+	}
+	if tuning.h2ReadIdle > 0 || tuning.h2PingTimeout > 0 {
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout: tuning.h2ReadIdle,
+			PingTimeout:     tuning.h2PingTimeout,
+		}
+	}
+}
+
+// upstreamTransportKey identifies transports that can be shared: same proxy and same
+// connection-level settings.
+type upstreamTransportKey struct {
+	proxyURL string
+	timeouts upstreamTimeouts
+	tuning   upstreamTransportTuning
+}
+
+// upstreamTransports caches executor-owned transports so their connection pools and HTTP/2
+// sessions are reused across requests. The cache is dropped when the configuration changes.
+var upstreamTransports = struct {
+	mu         sync.Mutex
+	cfg        *config.Config
+	transports map[upstreamTransportKey]*http.Transport
+}{transports: make(map[upstreamTransportKey]*http.Transport)}
+
+// cachedUpstreamTransport returns the transport cached for key, creating it with build when
+// missing. build may return nil, in which case nothing is cached.
+func cachedUpstreamTransport(cfg *config.Config, key upstreamTransportKey, build func() *http.Transport) *http.Transport {
+	// The whole-request timeout is set on the client, so it must not split the cache.
+	key.timeouts.nonStream = 0
+	upstreamTransports.mu.Lock()
+	defer upstreamTransports.mu.Unlock()
+	if upstreamTransports.cfg != cfg {
+		for _, transport := range upstreamTransports.transports {
+			transport.CloseIdleConnections()
+		}
+		upstreamTransports.cfg = cfg
+		upstreamTransports.transports = make(map[upstreamTransportKey]*http.Transport)
+	}
+	if transport, ok := upstreamTransports.transports[key]; ok {
+		return transport
+	}
+	transport := build()
+	if transport != nil {
+		upstreamTransports.transports[key] = transport
+	}
+	return transport
+}
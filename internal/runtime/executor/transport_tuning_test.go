@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestResolveUpstreamTransportTuning(t *testing.T) {
+	disabled := false
+	enabled := true
+	cfg := &config.Config{UpstreamTransport: config.UpstreamTransportConfig{
+		TransportTuning: config.TransportTuning{
+			H2ReadIdleSeconds:        30,
+			H2PingTimeoutSeconds:     10,
+			DisableStreamCompression: &enabled,
+		},
+		Providers: map[string]config.TransportTuning{
+			"codex": {ForceHTTP2: &disabled, IdleConnTimeoutSeconds: -1},
+		},
+	}}
+
+	defaults := resolveUpstreamTransportTuning(nil, "", cliproxyexecutor.EndpointStream)
+	if defaults.forceHTTP2 || defaults.disableHTTP2 || defaults.idleConnTimeout != defaultIdleConnTimeout || defaults.h2ReadIdle != 0 || defaults.disableCompression {
+		t.Fatalf("unexpected defaults: %+v", defaults)
+	}
+
+	global := resolveUpstreamTransportTuning(cfg, "claude", cliproxyexecutor.EndpointStream)
+	if global.forceHTTP2 || global.h2ReadIdle != 30*time.Second || global.h2PingTimeout != 10*time.Second || !global.disableCompression {
+		t.Fatalf("unexpected global tuning: %+v", global)
+	}
+	if execute := resolveUpstreamTransportTuning(cfg, "claude", cliproxyexecutor.EndpointExecute); execute.disableCompression {
+		t.Fatalf("compression must only be disabled for streaming requests")
+	}
+
+	provider := resolveUpstreamTransportTuning(cfg, "codex", cliproxyexecutor.EndpointStream)
+	if !provider.disableHTTP2 || provider.idleConnTimeout != defaultIdleConnTimeout || provider.h2ReadIdle != 30*time.Second {
+		t.Fatalf("unexpected provider tuning: %+v", provider)
+	}
+}
+
+func TestNewProxyAwareHTTPClientAppliesTransportTuning(t *testing.T) {
+	disabled := false
+	enabled := true
+	cfg := &config.Config{UpstreamTransport: config.UpstreamTransportConfig{
+		TransportTuning: config.TransportTuning{H2ReadIdleSeconds: 20, H2PingTimeoutSeconds: 5, DisableStreamCompression: &enabled},
+		Providers: map[string]config.TransportTuning{
+			"codex": {ForceHTTP2: &disabled},
+		},
+	}}
+	ctx := cliproxyexecutor.WithEndpoint(context.Background(), cliproxyexecutor.EndpointStream)
+
+	client := newProxyAwareHTTPClient(ctx, cfg, &cliproxyauth.Auth{Provider: "claude"}, 0)
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", client.Transport)
+	}
+	if !transport.ForceAttemptHTTP2 || transport.HTTP2 == nil || transport.HTTP2.SendPingTimeout != 20*time.Second || transport.HTTP2.PingTimeout != 5*time.Second {
+		t.Fatalf("HTTP/2 tuning not applied: force=%t http2=%+v", transport.ForceAttemptHTTP2, transport.HTTP2)
+	}
+	if !transport.DisableCompression {
+		t.Fatal("expected compression to be disabled for streaming requests")
+	}
+
+	client = newProxyAwareHTTPClient(ctx, cfg, &cliproxyauth.Auth{Provider: "codex"}, 0)
+	transport = client.Transport.(*http.Transport)
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil || len(transport.TLSNextProto) != 0 {
+		t.Fatal("expected HTTP/2 to be disabled for codex")
+	}
+}
+
+func TestNewProxyAwareHTTPClientReusesTransports(t *testing.T) {
+	enabled := true
+	cfg := &config.Config{UpstreamTransport: config.UpstreamTransportConfig{
+		Providers: map[string]config.TransportTuning{"codex": {ForceHTTP2: &enabled}},
+	}}
+	cfg.ProxyURL = "socks5://proxy.example.com:1080"
+	ctx := cliproxyexecutor.WithEndpoint(context.Background(), cliproxyexecutor.EndpointStream)
+
+	first := newProxyAwareHTTPClient(ctx, cfg, &cliproxyauth.Auth{Provider: "claude"}, 0).Transport
+	if second := newProxyAwareHTTPClient(ctx, cfg, &cliproxyauth.Auth{Provider: "claude"}, 0).Transport; second != first {
+		t.Fatal("expected requests with the same settings to share a transport")
+	}
+	codex := newProxyAwareHTTPClient(ctx, cfg, &cliproxyauth.Auth{Provider: "codex"}, 0).Transport
+	if codex == first {
+		t.Fatal("expected a separate transport for the codex tuning")
+	}
+
+	reloaded := *cfg
+	if next := newProxyAwareHTTPClient(ctx, &reloaded, &cliproxyauth.Auth{Provider: "claude"}, 0).Transport; next == first {
+		t.Fatal("expected a new transport after the configuration changed")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -12,24 +11,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
 type usageReporter struct {
-	provider    string
-	model       string
-	authID      string
-	authIndex   string
-	apiKey      string
-	source      string
-	sessionID   string
-	requestID   string
-	requestedAt time.Time
-	statusCode  int
-	durationMs  int64
-	once        sync.Once
+	provider  string
+	model     string
+	authID    string
+	authIndex string
+	apiKey    string
+	source    string
+	sessionID string
+	requestID string
+	// systemFingerprint identifies the upstream backend configuration that served the request.
+	systemFingerprint string
+	requestedAt       time.Time
+	statusCode        int
+	durationMs        int64
+	once              sync.Once
 }
 
 func newUsageReporter(ctx context.Context, provider, model string, auth *cliproxyauth.Auth) *usageReporter {
@@ -58,6 +60,19 @@ func (r *usageReporter) setPerformance(statusCode int, durationMs int64) {
 	r.durationMs = durationMs
 }
 
+// observeSystemFingerprint records the system_fingerprint of an OpenAI-compatible response body
+// or stream line, keeping the first one seen.
+func (r *usageReporter) observeSystemFingerprint(data []byte) {
+	if r == nil || r.systemFingerprint != "" {
+		return
+	}
+	payload := jsonPayload(data)
+	if len(payload) == 0 {
+		return
+	}
+	r.systemFingerprint = strings.TrimSpace(gjson.GetBytes(payload, "system_fingerprint").String())
+}
+
 func (r *usageReporter) publish(ctx context.Context, detail usage.Detail) {
 	r.publishWithOutcome(ctx, detail, false)
 }
@@ -170,6 +185,9 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
 		return
 	}
+	if !failed {
+		usage.ObserveDetail(ctx, detail)
+	}
 	r.once.Do(func() {
 		// Auto-compute duration if not explicitly set via setPerformance
 		durationMs := r.durationMs
@@ -188,19 +206,20 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 		}
 
 		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			RequestID:   r.requestID,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			SessionID:   r.sessionID,
-			RequestedAt: r.requestedAt,
-			Failed:      failed,
-			StatusCode:  statusCode,
-			DurationMs:  durationMs,
-			Detail:      detail,
+			Provider:          r.provider,
+			Model:             r.model,
+			Source:            r.source,
+			APIKey:            r.apiKey,
+			RequestID:         r.requestID,
+			AuthID:            r.authID,
+			AuthIndex:         r.authIndex,
+			SessionID:         r.sessionID,
+			RequestedAt:       r.requestedAt,
+			SystemFingerprint: r.systemFingerprint,
+			Failed:            failed,
+			StatusCode:        statusCode,
+			DurationMs:        durationMs,
+			Detail:            detail,
 		})
 	})
 }
@@ -227,42 +246,26 @@ func (r *usageReporter) ensurePublished(ctx context.Context) {
 		}
 
 		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			RequestID:   r.requestID,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			SessionID:   r.sessionID,
-			RequestedAt: r.requestedAt,
-			Failed:      false,
-			StatusCode:  statusCode,
-			DurationMs:  durationMs,
-			Detail:      usage.Detail{},
+			Provider:          r.provider,
+			Model:             r.model,
+			Source:            r.source,
+			APIKey:            r.apiKey,
+			RequestID:         r.requestID,
+			AuthID:            r.authID,
+			AuthIndex:         r.authIndex,
+			SessionID:         r.sessionID,
+			RequestedAt:       r.requestedAt,
+			SystemFingerprint: r.systemFingerprint,
+			Failed:            false,
+			StatusCode:        statusCode,
+			DurationMs:        durationMs,
+			Detail:            usage.Detail{},
 		})
 	})
 }
 
 func apiKeyFromContext(ctx context.Context) string {
-	if ctx == nil {
-		return ""
-	}
-	ginCtx, ok := ctx.Value("gin").(*gin.Context)
-	if !ok || ginCtx == nil {
-		return ""
-	}
-	if v, exists := ginCtx.Get("apiKey"); exists {
-		switch value := v.(type) {
-		case string:
-			return value
-		case fmt.Stringer:
-			return value.String()
-		default:
-			return fmt.Sprintf("%v", value)
-		}
-	}
-	return ""
+	return cliproxyexecutor.RequestMetadataFromContext(ctx).ClientKey
 }
 
 func resolveUsageSource(auth *cliproxyauth.Auth, ctxAPIKey string) string {
@@ -386,22 +389,35 @@ func parseOpenAIStreamUsage(line []byte) (usage.Detail, bool) {
 	return detail, true
 }
 
+// openAIStreamUsage keeps the last usage reported on an OpenAI-compatible stream. Providers
+// that repeat cumulative usage on every chunk are recorded once, with the final totals.
+type openAIStreamUsage struct {
+	detail usage.Detail
+	seen   bool
+}
+
+func (u *openAIStreamUsage) observe(line []byte) {
+	// Chunks carrying "usage":null must not overwrite the totals seen so far.
+	if detail, ok := parseOpenAIStreamUsage(line); ok && (detail.InputTokens > 0 || detail.OutputTokens > 0 || detail.TotalTokens > 0) {
+		u.detail, u.seen = detail, true
+	}
+}
+
+// publish records the final usage when the stream completes, or an empty record when the
+// stream carried none.
+func (u *openAIStreamUsage) publish(ctx context.Context, reporter *usageReporter) {
+	if u.seen {
+		reporter.publish(ctx, u.detail)
+	}
+	reporter.ensurePublished(ctx)
+}
+
 func parseClaudeUsage(data []byte) usage.Detail {
 	usageNode := gjson.ParseBytes(data).Get("usage")
 	if !usageNode.Exists() {
 		return usage.Detail{}
 	}
-	detail := usage.Detail{
-		InputTokens:  usageNode.Get("input_tokens").Int(),
-		OutputTokens: usageNode.Get("output_tokens").Int(),
-		CachedTokens: usageNode.Get("cache_read_input_tokens").Int(),
-	}
-	if detail.CachedTokens == 0 {
-		// fall back to creation tokens when read tokens are absent
-		detail.CachedTokens = usageNode.Get("cache_creation_input_tokens").Int()
-	}
-	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
-	return detail
+	return claudeUsageDetail(usageNode)
 }
 
 func parseClaudeStreamUsage(line []byte) (usage.Detail, bool) {
@@ -413,16 +429,22 @@ func parseClaudeStreamUsage(line []byte) (usage.Detail, bool) {
 	if !usageNode.Exists() {
 		return usage.Detail{}, false
 	}
+	return claudeUsageDetail(usageNode), true
+}
+
+// claudeUsageDetail converts a Claude usage object. Claude reports cache reads and cache writes
+// separately from input_tokens; they are folded into InputTokens so it means the same as for
+// other providers, with the cache reads kept apart in CachedTokens.
+func claudeUsageDetail(usageNode gjson.Result) usage.Detail {
+	cacheRead := usageNode.Get("cache_read_input_tokens").Int()
+	cacheWrite := usageNode.Get("cache_creation_input_tokens").Int()
 	detail := usage.Detail{
-		InputTokens:  usageNode.Get("input_tokens").Int(),
+		InputTokens:  usageNode.Get("input_tokens").Int() + cacheRead + cacheWrite,
 		OutputTokens: usageNode.Get("output_tokens").Int(),
-		CachedTokens: usageNode.Get("cache_read_input_tokens").Int(),
-	}
-	if detail.CachedTokens == 0 {
-		detail.CachedTokens = usageNode.Get("cache_creation_input_tokens").Int()
+		CachedTokens: cacheRead,
 	}
 	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
-	return detail, true
+	return detail
 }
 
 func parseGeminiFamilyUsageDetail(node gjson.Result) usage.Detail {
@@ -41,3 +41,31 @@ func TestParseOpenAIUsageResponses(t *testing.T) {
 		t.Fatalf("reasoning tokens = %d, want %d", detail.ReasoningTokens, 9)
 	}
 }
+
+func TestParseClaudeUsageSeparatesCacheReads(t *testing.T) {
+	data := []byte(`{"usage":{"input_tokens":10,"cache_read_input_tokens":300,"cache_creation_input_tokens":50,"output_tokens":20}}`)
+	detail := parseClaudeUsage(data)
+	if detail.InputTokens != 360 {
+		t.Fatalf("input tokens = %d, want %d", detail.InputTokens, 360)
+	}
+	if detail.CachedTokens != 300 {
+		t.Fatalf("cached tokens = %d, want %d", detail.CachedTokens, 300)
+	}
+	if detail.TotalTokens != 380 {
+		t.Fatalf("total tokens = %d, want %d", detail.TotalTokens, 380)
+	}
+}
+
+func TestUsageReporterKeepsFirstSystemFingerprint(t *testing.T) {
+	reporter := &usageReporter{}
+	reporter.observeSystemFingerprint([]byte(`data: {"choices":[{"delta":{"content":"hi"}}]}`))
+	if reporter.systemFingerprint != "" {
+		t.Fatalf("system fingerprint = %q, want empty", reporter.systemFingerprint)
+	}
+	reporter.observeSystemFingerprint([]byte(`data: {"system_fingerprint":"fp_44709d6fcb","choices":[]}`))
+	reporter.observeSystemFingerprint([]byte(`{"system_fingerprint":"fp_other"}`))
+	reporter.observeSystemFingerprint([]byte(`data: [DONE]`))
+	if reporter.systemFingerprint != "fp_44709d6fcb" {
+		t.Fatalf("system fingerprint = %q, want %q", reporter.systemFingerprint, "fp_44709d6fcb")
+	}
+}
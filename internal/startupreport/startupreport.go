@@ -0,0 +1,198 @@
+// Package startupreport summarizes the state of the proxy after startup: the providers and
+// auths it can serve with, and configuration that silently does nothing or denies everything,
+// such as routing to reverse proxies that do not exist or client keys allowed no auths.
+package startupreport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// Warning codes.
+const (
+	WarnNoAuths                = "no-auths"
+	WarnNoClientKeys           = "no-client-keys"
+	WarnProviderNoEnabledAuths = "provider-no-enabled-auths"
+	WarnMissingReverseProxy    = "missing-reverse-proxy"
+	WarnUnknownAuthRef         = "unknown-auth-ref"
+	WarnClientKeyNoAuths       = "client-key-no-auths"
+)
+
+// Report is the startup state of the proxy.
+type Report struct {
+	GeneratedAt time.Time         `json:"generated-at"`
+	Providers   []ProviderSummary `json:"providers"`
+	ClientKeys  int               `json:"client-keys"`
+	// ReverseProxies is the number of configured reverse proxies.
+	ReverseProxies int       `json:"reverse-proxies"`
+	Warnings       []Warning `json:"warnings"`
+}
+
+// ProviderSummary counts the auths of one provider.
+type ProviderSummary struct {
+	Provider string `json:"provider"`
+	Auths    int    `json:"auths"`
+	Enabled  int    `json:"enabled"`
+	Disabled int    `json:"disabled"`
+	// FromConfig counts auths defined by API keys in the configuration; the rest are auth files.
+	FromConfig int `json:"from-config"`
+}
+
+// Warning is a likely misconfiguration.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var latest atomic.Pointer[Report]
+
+// Build inspects the configuration and the auths of the manager.
+func Build(cfg *config.Config, manager *coreauth.Manager) Report {
+	report := Report{GeneratedAt: time.Now().UTC(), Providers: []ProviderSummary{}, Warnings: []Warning{}}
+	warn := func(code, format string, args ...any) {
+		report.Warnings = append(report.Warnings, Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	var auths []*coreauth.Auth
+	if manager != nil {
+		auths = manager.List()
+	}
+
+	byProvider := make(map[string]*ProviderSummary)
+	for _, auth := range auths {
+		if auth == nil {
+			continue
+		}
+		provider := strings.ToLower(strings.TrimSpace(auth.Provider))
+		summary := byProvider[provider]
+		if summary == nil {
+			summary = &ProviderSummary{Provider: provider}
+			byProvider[provider] = summary
+		}
+		summary.Auths++
+		if auth.Disabled {
+			summary.Disabled++
+		} else {
+			summary.Enabled++
+		}
+		if auth.FileName == "" {
+			summary.FromConfig++
+		}
+	}
+	for _, summary := range byProvider {
+		report.Providers = append(report.Providers, *summary)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool { return report.Providers[i].Provider < report.Providers[j].Provider })
+	if len(report.Providers) == 0 {
+		warn(WarnNoAuths, "no provider auths are loaded; every request will fail until API keys are configured or auth files are added")
+	}
+	for _, summary := range report.Providers {
+		if summary.Enabled == 0 {
+			warn(WarnProviderNoEnabledAuths, "provider %s has %d auths but all are disabled", summary.Provider, summary.Auths)
+		}
+	}
+	if cfg == nil {
+		return report
+	}
+
+	report.ClientKeys = len(cfg.APIKeys)
+	report.ReverseProxies = len(cfg.ReverseProxies)
+	if len(cfg.APIKeys) == 0 {
+		warn(WarnNoClientKeys, "no client api-keys are configured")
+	}
+
+	proxies := make(map[string]bool, len(cfg.ReverseProxies))
+	for _, proxy := range cfg.ReverseProxies {
+		proxies[proxy.ID] = true
+	}
+	routing := cfg.ProxyRouting
+	for _, route := range []struct{ provider, id string }{
+		{"codex", routing.Codex}, {"antigravity", routing.Antigravity}, {"claude", routing.Claude},
+		{"gemini", routing.Gemini}, {"gemini-cli", routing.GeminiCLI}, {"vertex", routing.Vertex},
+		{"aistudio", routing.AIStudio}, {"qwen", routing.Qwen}, {"iflow", routing.IFlow},
+	} {
+		if id := strings.TrimSpace(route.id); id != "" && !proxies[id] {
+			warn(WarnMissingReverseProxy, "proxy-routing sends %s through reverse proxy %q, which does not exist; requests go direct", route.provider, id)
+		}
+	}
+	for _, ref := range sortedKeys(cfg.ProxyRoutingAuth) {
+		if id := strings.TrimSpace(cfg.ProxyRoutingAuth[ref]); id != "" && !proxies[id] {
+			warn(WarnMissingReverseProxy, "proxy-routing-auth sends auth %q through reverse proxy %q, which does not exist; requests go direct", ref, id)
+		}
+		if !anyAuthMatches(auths, ref) {
+			warn(WarnUnknownAuthRef, "proxy-routing-auth references auth %q, which is not loaded", ref)
+		}
+	}
+
+	for _, key := range sortedKeys(cfg.APIKeyAuth) {
+		refs := cfg.APIKeyAuth[key]
+		matched := 0
+		for _, auth := range auths {
+			if coreauth.AuthAllowedByRefs(auth, refs) {
+				matched++
+			}
+		}
+		for _, ref := range refs {
+			if !anyAuthMatches(auths, ref) {
+				warn(WarnUnknownAuthRef, "api-key-auth for client key %s references auth %q, which is not loaded", util.HideAPIKey(key), ref)
+			}
+		}
+		if matched == 0 {
+			if len(refs) == 0 {
+				warn(WarnClientKeyNoAuths, "api-key-auth gives client key %s an empty auth list, which denies it every auth", util.HideAPIKey(key))
+			} else {
+				warn(WarnClientKeyNoAuths, "api-key-auth allows client key %s only auths that are not loaded, so every request it sends fails", util.HideAPIKey(key))
+			}
+		}
+	}
+	return report
+}
+
+// Record builds the report, logs it and keeps it as the latest report.
+func Record(cfg *config.Config, manager *coreauth.Manager) Report {
+	report := Build(cfg, manager)
+	latest.Store(&report)
+	parts := make([]string, 0, len(report.Providers))
+	for _, summary := range report.Providers {
+		parts = append(parts, fmt.Sprintf("%s=%d/%d", summary.Provider, summary.Enabled, summary.Auths))
+	}
+	log.Infof("startup report: %d client keys, %d reverse proxies, enabled/total auths: %s", report.ClientKeys, report.ReverseProxies, strings.Join(parts, " "))
+	for _, warning := range report.Warnings {
+		log.Warnf("startup report: %s", warning.Message)
+	}
+	return report
+}
+
+// Latest returns the report recorded at startup, if any.
+func Latest() (Report, bool) {
+	report := latest.Load()
+	if report == nil {
+		return Report{}, false
+	}
+	return *report, true
+}
+
+func anyAuthMatches(auths []*coreauth.Auth, ref string) bool {
+	for _, auth := range auths {
+		if coreauth.AuthMatchesRef(auth, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
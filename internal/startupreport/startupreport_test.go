@@ -0,0 +1,74 @@
+package startupreport
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestBuildReportsMisconfigurations(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	for _, auth := range []*coreauth.Auth{
+		{ID: "codex-a", Provider: "codex", FileName: "codex-a.json"},
+		{ID: "codex-b", Provider: "codex", FileName: "codex-b.json", Disabled: true},
+		{ID: "claude-a", Provider: "claude", Disabled: true},
+	} {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+	cfg := &config.Config{
+		SDKConfig:        config.SDKConfig{APIKeys: []string{"sk-allowed-key", "sk-denied-key", "sk-stale-key"}},
+		ReverseProxies:   []config.ReverseProxy{{ID: "rp-1"}},
+		ProxyRouting:     config.ProxyRouting{Codex: "rp-1", Claude: "rp-missing"},
+		ProxyRoutingAuth: map[string]string{"codex-a.json": "rp-gone"},
+		APIKeyAuth: map[string][]string{
+			"sk-allowed-key": {"codex-a.json"},
+			"sk-denied-key":  {},
+			"sk-stale-key":   {"deleted.json"},
+		},
+	}
+
+	report := Build(cfg, manager)
+	if len(report.Providers) != 2 || report.Providers[1] != (ProviderSummary{Provider: "codex", Auths: 2, Enabled: 1, Disabled: 1}) {
+		t.Fatalf("providers = %+v", report.Providers)
+	}
+	if report.ClientKeys != 3 || report.ReverseProxies != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[string]int{
+		WarnProviderNoEnabledAuths: 1, // claude
+		WarnMissingReverseProxy:    2, // claude route and codex-a.json route
+		WarnUnknownAuthRef:         1, // deleted.json
+		WarnClientKeyNoAuths:       2, // denied and stale keys
+	}
+	got := make(map[string]int)
+	for _, warning := range report.Warnings {
+		got[warning.Code]++
+		if strings.Contains(warning.Message, "sk-denied-key") {
+			t.Fatalf("client key not masked: %s", warning.Message)
+		}
+	}
+	for code, count := range want {
+		if got[code] != count {
+			t.Fatalf("warnings %s = %d, want %d: %+v", code, got[code], count, report.Warnings)
+		}
+	}
+	if len(report.Warnings) != 6 {
+		t.Fatalf("warnings = %+v", report.Warnings)
+	}
+}
+
+func TestBuildWithoutAuths(t *testing.T) {
+	report := Build(&config.Config{}, coreauth.NewManager(nil, nil, nil))
+	codes := make([]string, 0, len(report.Warnings))
+	for _, warning := range report.Warnings {
+		codes = append(codes, warning.Code)
+	}
+	if strings.Join(codes, ",") != WarnNoAuths+","+WarnNoClientKeys {
+		t.Fatalf("warnings = %v", codes)
+	}
+}
@@ -56,7 +56,7 @@ func IsUserDefinedModel(modelInfo *registry.ModelInfo) bool {
 // Suffix Priority: When the model name includes a thinking suffix (e.g., "gemini-2.5-pro(8192)"),
 // the suffix configuration takes priority over any thinking parameters in the request body.
 // This enables users to override thinking settings via the model name without modifying their
-// request payload.
+// request payload. Custom suffixes registered with SetSuffixAliases are expanded first.
 //
 // Parameters:
 //   - body: Original request body JSON
@@ -110,6 +110,14 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 	// Use provider-specific lookup to handle capability differences across providers.
 	modelInfo := registry.LookupModelInfo(baseModel, providerKey)
 
+	// Operator-defined suffix aliases expand to a built-in suffix and an optional output limit.
+	if suffixResult.HasSuffix {
+		if alias, ok := LookupSuffixAlias(suffixResult.RawSuffix, providerFormat); ok {
+			suffixResult.RawSuffix = alias.Thinking
+			body = applySuffixAliasOutputLimit(body, alias, modelInfo, providerFormat)
+		}
+	}
+
 	// 3. Model capability check
 	// Unknown models are treated as user-defined so thinking config can still be applied.
 	// The upstream service is responsible for validating the configuration.
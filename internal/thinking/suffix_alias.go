@@ -0,0 +1,147 @@
+package thinking
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// SuffixAlias is an operator-defined model suffix that expands to a built-in thinking suffix
+// and, optionally, an output token limit. For example the alias "max" lets clients request
+// "claude-sonnet-4-5(max)" instead of spelling out the effort and limit.
+type SuffixAlias struct {
+	// Name is the suffix written inside the parentheses.
+	Name string
+	// Providers restricts the alias to these provider formats; empty applies to all.
+	Providers []string
+	// Thinking is the built-in suffix the alias expands to: a level, a budget, "none" or "auto".
+	Thinking string
+	// MaxOutputTokens sets the request's output token limit when positive, capped at the
+	// model's own limit.
+	MaxOutputTokens int
+}
+
+var suffixAliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var suffixAliases struct {
+	mu      sync.RWMutex
+	aliases []SuffixAlias
+}
+
+// ValidateSuffixAlias checks that alias has a usable name, expands to a built-in suffix and
+// only names providers that support thinking configuration.
+func ValidateSuffixAlias(alias SuffixAlias) error {
+	name := strings.ToLower(strings.TrimSpace(alias.Name))
+	if !suffixAliasNamePattern.MatchString(name) {
+		return fmt.Errorf("suffix name %q must use lowercase letters, digits, '-' or '_'", alias.Name)
+	}
+	if isBuiltInSuffix(name) {
+		return fmt.Errorf("suffix name %q is a built-in thinking suffix", alias.Name)
+	}
+	if !isBuiltInSuffix(strings.TrimSpace(alias.Thinking)) {
+		return fmt.Errorf("thinking %q must be a level (minimal, low, medium, high, xhigh), a budget, none or auto", alias.Thinking)
+	}
+	if alias.MaxOutputTokens < 0 {
+		return fmt.Errorf("max-output-tokens must not be negative")
+	}
+	for _, provider := range alias.Providers {
+		key := strings.ToLower(strings.TrimSpace(provider))
+		if _, ok := providerAppliers[key]; !ok {
+			return fmt.Errorf("provider %q does not support thinking configuration", provider)
+		}
+	}
+	return nil
+}
+
+// SetSuffixAliases replaces the registered suffix aliases. Invalid aliases are skipped and
+// reported in the returned errors.
+func SetSuffixAliases(aliases []SuffixAlias) []error {
+	var errs []error
+	valid := make([]SuffixAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		if err := ValidateSuffixAlias(alias); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		alias.Name = strings.ToLower(strings.TrimSpace(alias.Name))
+		alias.Thinking = strings.TrimSpace(alias.Thinking)
+		valid = append(valid, alias)
+	}
+	suffixAliases.mu.Lock()
+	suffixAliases.aliases = valid
+	suffixAliases.mu.Unlock()
+	return errs
+}
+
+// LookupSuffixAlias returns the first alias named name that applies to provider.
+func LookupSuffixAlias(name, provider string) (SuffixAlias, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	suffixAliases.mu.RLock()
+	defer suffixAliases.mu.RUnlock()
+	for _, alias := range suffixAliases.aliases {
+		if alias.Name != name {
+			continue
+		}
+		if len(alias.Providers) == 0 {
+			return alias, true
+		}
+		for _, p := range alias.Providers {
+			if strings.EqualFold(strings.TrimSpace(p), provider) {
+				return alias, true
+			}
+		}
+	}
+	return SuffixAlias{}, false
+}
+
+func isBuiltInSuffix(raw string) bool {
+	if _, ok := ParseSpecialSuffix(raw); ok {
+		return true
+	}
+	if _, ok := ParseLevelSuffix(raw); ok {
+		return true
+	}
+	_, ok := ParseNumericSuffix(raw)
+	return ok
+}
+
+// applySuffixAliasOutputLimit sets the output token limit of an alias in the request body,
+// capped at the model's limit. Codex requests carry no output limit and are left unchanged.
+func applySuffixAliasOutputLimit(body []byte, alias SuffixAlias, modelInfo *registry.ModelInfo, provider string) []byte {
+	limit := alias.MaxOutputTokens
+	if limit <= 0 {
+		return body
+	}
+	if modelInfo != nil {
+		if modelMax := max(modelInfo.MaxCompletionTokens, modelInfo.OutputTokenLimit); modelMax > 0 && limit > modelMax {
+			limit = modelMax
+		}
+	}
+	var path string
+	switch provider {
+	case "codex":
+		return body
+	case "gemini":
+		path = "generationConfig.maxOutputTokens"
+	case "gemini-cli", "antigravity":
+		path = "request.generationConfig.maxOutputTokens"
+	case "openai":
+		path = "max_tokens"
+		if gjson.GetBytes(body, "max_completion_tokens").Exists() {
+			path = "max_completion_tokens"
+		}
+	default:
+		path = "max_tokens"
+	}
+	out, err := sjson.SetBytes(body, path, limit)
+	if err != nil {
+		return body
+	}
+	return out
+}
@@ -70,6 +70,9 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 
 	messagesResult := gjson.GetBytes(rawJSON, "messages")
 	if messagesResult.IsArray() {
+		// Tool results name the function they answer; IDs minted by other providers do not
+		// encode it, so it is looked up from the tool calls in the history first.
+		toolNames := util.ClaudeToolUseNames(messagesResult)
 		messageResults := messagesResult.Array()
 		numMessages := len(messageResults)
 		for i := 0; i < numMessages; i++ {
@@ -206,10 +209,13 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 					} else if contentTypeResult.Type == gjson.String && contentTypeResult.String() == "tool_result" {
 						toolCallID := contentResult.Get("tool_use_id").String()
 						if toolCallID != "" {
-							funcName := toolCallID
-							toolCallIDs := strings.Split(toolCallID, "-")
-							if len(toolCallIDs) > 1 {
-								funcName = strings.Join(toolCallIDs[0:len(toolCallIDs)-2], "-")
+							funcName, ok := toolNames[toolCallID]
+							if !ok {
+								funcName = toolCallID
+								toolCallIDs := strings.Split(toolCallID, "-")
+								if len(toolCallIDs) > 1 {
+									funcName = strings.Join(toolCallIDs[0:len(toolCallIDs)-2], "-")
+								}
 							}
 							functionResponseResult := contentResult.Get("content")
 
@@ -0,0 +1,53 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// Parallel tool calls replayed from a Codex conversation must reach Claude as one assistant
+// turn with every tool_use followed by one user turn with every tool_result.
+func TestConvertOpenAIResponsesRequestToClaudeGroupsParallelToolCalls(t *testing.T) {
+	input := []byte(`{
+		"model": "claude-sonnet-4",
+		"input": [
+			{"type": "message", "role": "user", "content": [{"type": "input_text", "text": "Read both files."}]},
+			{"type": "function_call", "call_id": "call_a", "name": "read_file", "arguments": "{\"path\":\"a.go\"}"},
+			{"type": "function_call", "call_id": "call_b", "name": "read_file", "arguments": "{\"path\":\"b.go\"}"},
+			{"type": "function_call_output", "call_id": "call_a", "output": "package a"},
+			{"type": "function_call_output", "call_id": "call_b", "output": "package b"}
+		]
+	}`)
+
+	out := ConvertOpenAIResponsesRequestToClaude("claude-sonnet-4", input, false)
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %s, want user, assistant and user turns", gjson.GetBytes(out, "messages").Raw)
+	}
+	if got := messages[1].Get("content.#.id").Raw; messages[1].Get("role").String() != "assistant" || got != `["call_a","call_b"]` {
+		t.Fatalf("assistant turn = %s, want tool_use call_a and call_b", messages[1].Raw)
+	}
+	if got := messages[2].Get("content.#.tool_use_id").Raw; messages[2].Get("role").String() != "user" || got != `["call_a","call_b"]` {
+		t.Fatalf("user turn = %s, want tool_result call_a and call_b", messages[2].Raw)
+	}
+}
+
+func TestConvertOpenAIResponsesRequestToClaudeSanitizesCallIDs(t *testing.T) {
+	input := []byte(`{
+		"model": "claude-sonnet-4",
+		"input": [
+			{"type": "function_call", "call_id": "default_api:read.file-1", "name": "read_file", "arguments": "{}"},
+			{"type": "function_call_output", "call_id": "default_api:read.file-1", "output": "ok"}
+		]
+	}`)
+
+	out := ConvertOpenAIResponsesRequestToClaude("claude-sonnet-4", input, false)
+	toolUseID := gjson.GetBytes(out, "messages.0.content.0.id").String()
+	if toolUseID != "default_api_read_file-1" {
+		t.Fatalf("tool_use id = %q, want %q", toolUseID, "default_api_read_file-1")
+	}
+	if got := gjson.GetBytes(out, "messages.1.content.0.tool_use_id").String(); got != toolUseID {
+		t.Fatalf("tool_result tool_use_id = %q, want %q", got, toolUseID)
+	}
+}
@@ -46,19 +46,25 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 	rootResult := gjson.ParseBytes(rawJSON)
 	template, _ = sjson.Set(template, "model", modelName)
 
-	// Process system messages and convert them to input content format.
+	// Process system messages and convert them to input content format. The system prompt may
+	// be a plain string or an array of text blocks.
 	systemsResult := rootResult.Get("system")
+	var systemTexts []string
 	if systemsResult.IsArray() {
-		systemResults := systemsResult.Array()
-		message := `{"type":"message","role":"developer","content":[]}`
-		for i := 0; i < len(systemResults); i++ {
-			systemResult := systemResults[i]
-			systemTypeResult := systemResult.Get("type")
-			if systemTypeResult.String() == "text" {
-				message, _ = sjson.Set(message, fmt.Sprintf("content.%d.type", i), "input_text")
-				message, _ = sjson.Set(message, fmt.Sprintf("content.%d.text", i), systemResult.Get("text").String())
+		for _, systemResult := range systemsResult.Array() {
+			if systemResult.Get("type").String() == "text" && systemResult.Get("text").String() != "" {
+				systemTexts = append(systemTexts, systemResult.Get("text").String())
 			}
 		}
+	} else if systemsResult.Type == gjson.String && systemsResult.String() != "" {
+		systemTexts = append(systemTexts, systemsResult.String())
+	}
+	if len(systemTexts) > 0 {
+		message := `{"type":"message","role":"developer","content":[]}`
+		for i, text := range systemTexts {
+			message, _ = sjson.Set(message, fmt.Sprintf("content.%d.type", i), "input_text")
+			message, _ = sjson.Set(message, fmt.Sprintf("content.%d.text", i), text)
+		}
 		template, _ = sjson.SetRaw(template, "input.-1", message)
 	}
 
@@ -157,7 +163,7 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 						flushMessage()
 						functionCallOutputMessage := `{"type":"function_call_output"}`
 						functionCallOutputMessage, _ = sjson.Set(functionCallOutputMessage, "call_id", messageContentResult.Get("tool_use_id").String())
-						functionCallOutputMessage, _ = sjson.Set(functionCallOutputMessage, "output", messageContentResult.Get("content").String())
+						functionCallOutputMessage, _ = sjson.Set(functionCallOutputMessage, "output", toolResultOutput(messageContentResult))
 						template, _ = sjson.SetRaw(template, "input.-1", functionCallOutputMessage)
 					}
 				}
@@ -174,7 +180,7 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 	toolsResult := rootResult.Get("tools")
 	if toolsResult.IsArray() {
 		template, _ = sjson.SetRaw(template, "tools", `[]`)
-		template, _ = sjson.Set(template, "tool_choice", `auto`)
+		template, _ = sjson.SetRaw(template, "tool_choice", codexToolChoice(rootResult.Get("tool_choice"), buildReverseMapFromClaudeOriginalToShort(rawJSON)))
 		toolResults := toolsResult.Array()
 		// Build short name map from declared tools
 		var names []string
@@ -214,7 +220,7 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 	}
 
 	// Add additional configuration parameters for the Codex API.
-	template, _ = sjson.Set(template, "parallel_tool_calls", true)
+	template, _ = sjson.Set(template, "parallel_tool_calls", !rootResult.Get("tool_choice.disable_parallel_tool_use").Bool())
 
 	// Convert thinking.budget_tokens to reasoning.effort.
 	reasoningEffort := "medium"
@@ -262,6 +268,46 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 	return []byte(template)
 }
 
+// toolResultOutput flattens a Claude tool_result content, which may be a string or an array
+// of content blocks, into the text Codex expects as function_call_output.
+func toolResultOutput(toolResult gjson.Result) string {
+	content := toolResult.Get("content")
+	output := content.String()
+	if content.IsArray() {
+		var texts []string
+		for _, block := range content.Array() {
+			if block.Get("type").String() == "text" {
+				texts = append(texts, block.Get("text").String())
+			}
+		}
+		output = strings.Join(texts, "\n")
+	}
+	if toolResult.Get("is_error").Bool() && !strings.HasPrefix(output, "Error") {
+		output = "Error: " + output
+	}
+	return output
+}
+
+// codexToolChoice maps a Claude tool_choice to the Codex equivalent: "any" requires a tool
+// call, "tool" forces the named (possibly shortened) function and "none" disables tools.
+func codexToolChoice(toolChoice gjson.Result, shortNames map[string]string) string {
+	switch toolChoice.Get("type").String() {
+	case "any":
+		return `"required"`
+	case "none":
+		return `"none"`
+	case "tool":
+		name := toolChoice.Get("name").String()
+		if short, ok := shortNames[name]; ok {
+			name = short
+		}
+		choice, _ := sjson.Set(`{"type":"function","name":""}`, "name", name)
+		return choice
+	default:
+		return `"auto"`
+	}
+}
+
 // shortenNameIfNeeded applies a simple shortening rule for a single name.
 func shortenNameIfNeeded(name string) string {
 	const limit = 64
@@ -0,0 +1,67 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertClaudeRequestToCodexSystemPrompt(t *testing.T) {
+	for name, system := range map[string]string{
+		"string": `"Be terse."`,
+		"blocks": `[{"type":"text","text":"Be terse.","cache_control":{"type":"ephemeral"}},{"type":"image"},{"type":"text","text":"Use Go."}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			raw := `{"system":` + system + `,"messages":[{"role":"user","content":"hi"}]}`
+			input := gjson.GetBytes(ConvertClaudeRequestToCodex("gpt-5", []byte(raw), true), "input")
+			var developer gjson.Result
+			for _, item := range input.Array() {
+				if item.Get("role").String() == "developer" {
+					developer = item
+				}
+			}
+			if got := developer.Get("content.0.text").String(); got != "Be terse." {
+				t.Fatalf("developer message = %s", developer.Raw)
+			}
+			for _, part := range developer.Get("content").Array() {
+				if part.Get("type").String() != "input_text" {
+					t.Fatalf("unexpected system part: %s", developer.Raw)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertClaudeRequestToCodexToolRoundTrip(t *testing.T) {
+	raw := `{
+		"tools":[{"name":"Read","input_schema":{"type":"object"}}],
+		"tool_choice":{"type":"tool","name":"Read","disable_parallel_tool_use":true},
+		"messages":[
+			{"role":"user","content":"read a"},
+			{"role":"assistant","content":[{"type":"tool_use","id":"toolu_01","name":"Read","input":{"path":"a"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_01","is_error":true,"content":[{"type":"text","text":"not found"},{"type":"text","text":"twice"}]}]}
+		]}`
+	out := gjson.ParseBytes(ConvertClaudeRequestToCodex("gpt-5", []byte(raw), true))
+
+	var call, result gjson.Result
+	for _, item := range out.Get("input").Array() {
+		switch item.Get("type").String() {
+		case "function_call":
+			call = item
+		case "function_call_output":
+			result = item
+		}
+	}
+	if call.Get("call_id").String() != "toolu_01" || result.Get("call_id").String() != "toolu_01" {
+		t.Fatalf("tool call IDs not preserved: %s / %s", call.Raw, result.Raw)
+	}
+	if got := result.Get("output").String(); got != "Error: not found\ntwice" {
+		t.Fatalf("tool result output = %q", got)
+	}
+	if out.Get("tool_choice.type").String() != "function" || out.Get("tool_choice.name").String() != "Read" {
+		t.Fatalf("tool_choice = %s", out.Get("tool_choice").Raw)
+	}
+	if out.Get("parallel_tool_calls").Bool() {
+		t.Fatal("disable_parallel_tool_use not applied")
+	}
+}
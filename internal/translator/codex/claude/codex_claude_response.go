@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -25,6 +27,52 @@ type ConvertCodexResponseToClaudeParams struct {
 	HasToolCall               bool
 	BlockIndex                int
 	HasReceivedArgumentsDelta bool
+	// BlockOpen reports whether a content block was started and not yet stopped at BlockIndex.
+	BlockOpen bool
+	// BlockText accumulates the text of the open text block to resolve citation offsets.
+	BlockText string
+	// WebSearchRequests counts the built-in web searches the model ran.
+	WebSearchRequests int
+}
+
+// startBlock opens a content block at the current index, closing a block left open first so
+// every content_block_start is matched by a content_block_stop in order.
+func (p *ConvertCodexResponseToClaudeParams) startBlock(contentBlock string) string {
+	output := p.stopBlock()
+	template := `{"type":"content_block_start","index":0,"content_block":{}}`
+	template, _ = sjson.Set(template, "index", p.BlockIndex)
+	template, _ = sjson.SetRaw(template, "content_block", contentBlock)
+	p.BlockOpen = true
+	output += "event: content_block_start\n"
+	output += fmt.Sprintf("data: %s\n\n", template)
+	return output
+}
+
+// stopBlock closes the open content block, if any, and advances the block index.
+func (p *ConvertCodexResponseToClaudeParams) stopBlock() string {
+	if !p.BlockOpen {
+		return ""
+	}
+	template := `{"type":"content_block_stop","index":0}`
+	template, _ = sjson.Set(template, "index", p.BlockIndex)
+	p.BlockIndex++
+	p.BlockOpen = false
+	return "event: content_block_stop\n" + fmt.Sprintf("data: %s\n\n", template)
+}
+
+// delta emits a content_block_delta for the open block, opening a block of contentBlock type
+// first when the upstream skipped the part-added event.
+func (p *ConvertCodexResponseToClaudeParams) delta(contentBlock, delta string) string {
+	output := ""
+	if !p.BlockOpen {
+		output = p.startBlock(contentBlock)
+	}
+	template := `{"type":"content_block_delta","index":0,"delta":{}}`
+	template, _ = sjson.Set(template, "index", p.BlockIndex)
+	template, _ = sjson.SetRaw(template, "delta", delta)
+	output += "event: content_block_delta\n"
+	output += fmt.Sprintf("data: %s\n\n", template)
+	return output
 }
 
 // ConvertCodexResponseToClaude performs sophisticated streaming response format conversion.
@@ -32,8 +80,9 @@ type ConvertCodexResponseToClaudeParams struct {
 // into Claude Code-compatible Server-Sent Events (SSE) format. It manages different response types
 // and handles state transitions between content blocks, thinking processes, and function calls.
 //
-// Response type states: 0=none, 1=content, 2=thinking, 3=function
-// The function maintains state across multiple calls to ensure proper SSE event sequencing.
+// Events are emitted in Claude order: message_start, then start/delta/stop per content block
+// with increasing indexes, then message_delta and message_stop. A block the upstream left open
+// is closed before the next one starts or the message ends.
 //
 // Parameters:
 //   - ctx: The context for the request, used for cancellation and timeout handling
@@ -50,6 +99,7 @@ func ConvertCodexResponseToClaude(_ context.Context, _ string, originalRequestRa
 			BlockIndex:  0,
 		}
 	}
+	params := (*param).(*ConvertCodexResponseToClaudeParams)
 
 	// log.Debugf("rawJSON: %s", string(rawJSON))
 	if !bytes.HasPrefix(rawJSON, dataTag) {
@@ -70,65 +120,41 @@ func ConvertCodexResponseToClaude(_ context.Context, _ string, originalRequestRa
 		output = "event: message_start\n"
 		output += fmt.Sprintf("data: %s\n\n", template)
 	} else if typeStr == "response.reasoning_summary_part.added" {
-		template = `{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-
-		output = "event: content_block_start\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
+		output = params.startBlock(`{"type":"thinking","thinking":""}`)
 	} else if typeStr == "response.reasoning_summary_text.delta" {
-		template = `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":""}}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-		template, _ = sjson.Set(template, "delta.thinking", rootResult.Get("delta").String())
-
-		output = "event: content_block_delta\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
+		delta, _ := sjson.Set(`{"type":"thinking_delta","thinking":""}`, "thinking", rootResult.Get("delta").String())
+		output = params.delta(`{"type":"thinking","thinking":""}`, delta)
 	} else if typeStr == "response.reasoning_summary_part.done" {
-		template = `{"type":"content_block_stop","index":0}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-		(*param).(*ConvertCodexResponseToClaudeParams).BlockIndex++
-
-		output = "event: content_block_stop\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
-
+		output = params.stopBlock()
 	} else if typeStr == "response.content_part.added" {
-		template = `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-
-		output = "event: content_block_start\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
+		params.BlockText = ""
+		output = params.startBlock(`{"type":"text","text":""}`)
 	} else if typeStr == "response.output_text.delta" {
-		template = `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":""}}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-		template, _ = sjson.Set(template, "delta.text", rootResult.Get("delta").String())
-
-		output = "event: content_block_delta\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
-	} else if typeStr == "response.content_part.done" {
-		template = `{"type":"content_block_stop","index":0}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-		(*param).(*ConvertCodexResponseToClaudeParams).BlockIndex++
-
-		output = "event: content_block_stop\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
-	} else if typeStr == "response.completed" {
-		template = `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`
-		p := (*param).(*ConvertCodexResponseToClaudeParams).HasToolCall
-		stopReason := rootResult.Get("response.stop_reason").String()
-		if p {
-			template, _ = sjson.Set(template, "delta.stop_reason", "tool_use")
-		} else if stopReason == "max_tokens" || stopReason == "stop" {
-			template, _ = sjson.Set(template, "delta.stop_reason", stopReason)
-		} else {
-			template, _ = sjson.Set(template, "delta.stop_reason", "end_turn")
+		params.BlockText += rootResult.Get("delta").String()
+		delta, _ := sjson.Set(`{"type":"text_delta","text":""}`, "text", rootResult.Get("delta").String())
+		output = params.delta(`{"type":"text","text":""}`, delta)
+	} else if typeStr == "response.output_text.annotation.added" {
+		if citation, ok := claudeCitation(rootResult.Get("annotation"), params.BlockText); ok {
+			delta, _ := sjson.SetRaw(`{"type":"citations_delta","citation":{}}`, "citation", citation)
+			output = params.delta(`{"type":"text","text":""}`, delta)
 		}
+	} else if typeStr == "response.content_part.done" {
+		output = params.stopBlock()
+	} else if typeStr == "response.completed" || typeStr == "response.incomplete" {
+		output = params.stopBlock()
+		template = `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`
+		template, _ = sjson.Set(template, "delta.stop_reason", claudeStopReason(rootResult.Get("response"), params.HasToolCall))
 		inputTokens, outputTokens, cachedTokens := extractResponsesUsage(rootResult.Get("response.usage"))
 		template, _ = sjson.Set(template, "usage.input_tokens", inputTokens)
 		template, _ = sjson.Set(template, "usage.output_tokens", outputTokens)
 		if cachedTokens > 0 {
 			template, _ = sjson.Set(template, "usage.cache_read_input_tokens", cachedTokens)
 		}
+		if params.WebSearchRequests > 0 {
+			template, _ = sjson.Set(template, "usage.server_tool_use.web_search_requests", params.WebSearchRequests)
+		}
 
-		output = "event: message_delta\n"
+		output += "event: message_delta\n"
 		output += fmt.Sprintf("data: %s\n\n", template)
 		output += "event: message_stop\n"
 		output += `data: {"type":"message_stop"}`
@@ -137,11 +163,10 @@ func ConvertCodexResponseToClaude(_ context.Context, _ string, originalRequestRa
 		itemResult := rootResult.Get("item")
 		itemType := itemResult.Get("type").String()
 		if itemType == "function_call" {
-			(*param).(*ConvertCodexResponseToClaudeParams).HasToolCall = true
-			(*param).(*ConvertCodexResponseToClaudeParams).HasReceivedArgumentsDelta = false
-			template = `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"","name":"","input":{}}}`
-			template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-			template, _ = sjson.Set(template, "content_block.id", itemResult.Get("call_id").String())
+			params.HasToolCall = true
+			params.HasReceivedArgumentsDelta = false
+			block := `{"type":"tool_use","id":"","name":"","input":{}}`
+			block, _ = sjson.Set(block, "id", claudeToolUseID(itemResult))
 			{
 				// Restore original tool name if shortened
 				name := itemResult.Get("name").String()
@@ -149,51 +174,41 @@ func ConvertCodexResponseToClaude(_ context.Context, _ string, originalRequestRa
 				if orig, ok := rev[name]; ok {
 					name = orig
 				}
-				template, _ = sjson.Set(template, "content_block.name", name)
+				block, _ = sjson.Set(block, "name", name)
 			}
-
-			output = "event: content_block_start\n"
-			output += fmt.Sprintf("data: %s\n\n", template)
-
-			template = `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}`
-			template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-
-			output += "event: content_block_delta\n"
-			output += fmt.Sprintf("data: %s\n\n", template)
+			output = params.startBlock(block)
+			output += params.delta(block, `{"type":"input_json_delta","partial_json":""}`)
 		}
 	} else if typeStr == "response.output_item.done" {
 		itemResult := rootResult.Get("item")
 		itemType := itemResult.Get("type").String()
 		if itemType == "function_call" {
-			template = `{"type":"content_block_stop","index":0}`
-			template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-			(*param).(*ConvertCodexResponseToClaudeParams).BlockIndex++
-
-			output = "event: content_block_stop\n"
-			output += fmt.Sprintf("data: %s\n\n", template)
+			output = params.stopBlock()
+		} else if itemType == "web_search_call" {
+			params.WebSearchRequests++
+			toolUse, result := claudeWebSearchBlocks(itemResult)
+			input := gjson.Get(toolUse, "input").Raw
+			toolUse, _ = sjson.SetRaw(toolUse, "input", `{}`)
+			output = params.startBlock(toolUse)
+			delta, _ := sjson.Set(`{"type":"input_json_delta","partial_json":""}`, "partial_json", input)
+			output += params.delta(toolUse, delta)
+			output += params.startBlock(result)
+			output += params.stopBlock()
 		}
 	} else if typeStr == "response.function_call_arguments.delta" {
-		(*param).(*ConvertCodexResponseToClaudeParams).HasReceivedArgumentsDelta = true
-		template = `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}`
-		template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-		template, _ = sjson.Set(template, "delta.partial_json", rootResult.Get("delta").String())
-
-		output += "event: content_block_delta\n"
-		output += fmt.Sprintf("data: %s\n\n", template)
+		params.HasReceivedArgumentsDelta = true
+		delta, _ := sjson.Set(`{"type":"input_json_delta","partial_json":""}`, "partial_json", rootResult.Get("delta").String())
+		output = params.delta(`{"type":"tool_use","id":"","name":"","input":{}}`, delta)
 	} else if typeStr == "response.function_call_arguments.done" {
 		// Some models (e.g. gpt-5.3-codex-spark) send function call arguments
 		// in a single "done" event without preceding "delta" events.
 		// Emit the full arguments as a single input_json_delta so the
 		// downstream Claude client receives the complete tool input.
 		// When delta events were already received, skip to avoid duplicating arguments.
-		if !(*param).(*ConvertCodexResponseToClaudeParams).HasReceivedArgumentsDelta {
+		if !params.HasReceivedArgumentsDelta {
 			if args := rootResult.Get("arguments").String(); args != "" {
-				template = `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}`
-				template, _ = sjson.Set(template, "index", (*param).(*ConvertCodexResponseToClaudeParams).BlockIndex)
-				template, _ = sjson.Set(template, "delta.partial_json", args)
-
-				output += "event: content_block_delta\n"
-				output += fmt.Sprintf("data: %s\n\n", template)
+				delta, _ := sjson.Set(`{"type":"input_json_delta","partial_json":""}`, "partial_json", args)
+				output = params.delta(`{"type":"tool_use","id":"","name":"","input":{}}`, delta)
 			}
 		}
 	}
@@ -238,6 +253,7 @@ func ConvertCodexResponseToClaudeNonStream(_ context.Context, _ string, original
 	}
 
 	hasToolCall := false
+	webSearchRequests := 0
 
 	if output := responseData.Get("output"); output.Exists() && output.IsArray() {
 		output.ForEach(func(_, item gjson.Result) bool {
@@ -288,6 +304,11 @@ func ConvertCodexResponseToClaudeNonStream(_ context.Context, _ string, original
 								if text != "" {
 									block := `{"type":"text","text":""}`
 									block, _ = sjson.Set(block, "text", text)
+									for _, annotation := range part.Get("annotations").Array() {
+										if citation, ok := claudeCitation(annotation, text); ok {
+											block, _ = sjson.SetRaw(block, "citations.-1", citation)
+										}
+									}
 									out, _ = sjson.SetRaw(out, "content.-1", block)
 								}
 							}
@@ -310,7 +331,7 @@ func ConvertCodexResponseToClaudeNonStream(_ context.Context, _ string, original
 				}
 
 				toolBlock := `{"type":"tool_use","id":"","name":"","input":{}}`
-				toolBlock, _ = sjson.Set(toolBlock, "id", item.Get("call_id").String())
+				toolBlock, _ = sjson.Set(toolBlock, "id", claudeToolUseID(item))
 				toolBlock, _ = sjson.Set(toolBlock, "name", name)
 				inputRaw := "{}"
 				if argsStr := item.Get("arguments").String(); argsStr != "" && gjson.Valid(argsStr) {
@@ -321,18 +342,20 @@ func ConvertCodexResponseToClaudeNonStream(_ context.Context, _ string, original
 				}
 				toolBlock, _ = sjson.SetRaw(toolBlock, "input", inputRaw)
 				out, _ = sjson.SetRaw(out, "content.-1", toolBlock)
+			case "web_search_call":
+				webSearchRequests++
+				toolUse, result := claudeWebSearchBlocks(item)
+				out, _ = sjson.SetRaw(out, "content.-1", toolUse)
+				out, _ = sjson.SetRaw(out, "content.-1", result)
 			}
 			return true
 		})
 	}
 
-	if stopReason := responseData.Get("stop_reason"); stopReason.Exists() && stopReason.String() != "" {
-		out, _ = sjson.Set(out, "stop_reason", stopReason.String())
-	} else if hasToolCall {
-		out, _ = sjson.Set(out, "stop_reason", "tool_use")
-	} else {
-		out, _ = sjson.Set(out, "stop_reason", "end_turn")
+	if webSearchRequests > 0 {
+		out, _ = sjson.Set(out, "usage.server_tool_use.web_search_requests", webSearchRequests)
 	}
+	out, _ = sjson.Set(out, "stop_reason", claudeStopReason(responseData, hasToolCall))
 
 	if stopSequence := responseData.Get("stop_sequence"); stopSequence.Exists() && stopSequence.String() != "" {
 		out, _ = sjson.SetRaw(out, "stop_sequence", stopSequence.Raw)
@@ -341,6 +364,81 @@ func ConvertCodexResponseToClaudeNonStream(_ context.Context, _ string, original
 	return out
 }
 
+// claudeWebSearchBlocks converts a Codex web_search_call item into the server_tool_use and
+// web_search_tool_result blocks Claude reports for its own web search tool.
+func claudeWebSearchBlocks(item gjson.Result) (string, string) {
+	id := "srvtoolu_" + strings.TrimPrefix(item.Get("id").String(), "ws_")
+	toolUse := `{"type":"server_tool_use","id":"","name":"web_search","input":{"query":""}}`
+	toolUse, _ = sjson.Set(toolUse, "id", id)
+	toolUse, _ = sjson.Set(toolUse, "input.query", item.Get("action.query").String())
+
+	result := `{"type":"web_search_tool_result","tool_use_id":"","content":[]}`
+	result, _ = sjson.Set(result, "tool_use_id", id)
+	for _, source := range item.Get("action.sources").Array() {
+		if source.Get("url").String() == "" {
+			continue
+		}
+		entry := `{"type":"web_search_result","url":"","title":"","encrypted_content":"","page_age":null}`
+		entry, _ = sjson.Set(entry, "url", source.Get("url").String())
+		entry, _ = sjson.Set(entry, "title", source.Get("title").String())
+		result, _ = sjson.SetRaw(result, "content.-1", entry)
+	}
+	return toolUse, result
+}
+
+// claudeCitation converts a Responses url_citation annotation on text into a Claude web
+// search citation. Offsets count characters of text.
+func claudeCitation(annotation gjson.Result, text string) (string, bool) {
+	if annotation.Get("type").String() != "url_citation" {
+		return "", false
+	}
+	citation := `{"type":"web_search_result_location","url":"","title":"","encrypted_index":"","cited_text":""}`
+	citation, _ = sjson.Set(citation, "url", annotation.Get("url").String())
+	citation, _ = sjson.Set(citation, "title", annotation.Get("title").String())
+	runes := []rune(text)
+	start, end := int(annotation.Get("start_index").Int()), int(annotation.Get("end_index").Int())
+	if start >= 0 && start < end && end <= len(runes) {
+		citation, _ = sjson.Set(citation, "cited_text", string(runes[start:end]))
+	}
+	return citation, true
+}
+
+// claudeStopReason maps a Codex response to a Claude stop_reason. Truncated responses report
+// max_tokens (or refusal when filtered) even mid tool call; otherwise tool calls report
+// tool_use and everything else end_turn.
+func claudeStopReason(response gjson.Result, hasToolCall bool) string {
+	if response.Get("status").String() == "incomplete" {
+		switch response.Get("incomplete_details.reason").String() {
+		case "max_output_tokens":
+			return "max_tokens"
+		case "content_filter":
+			return "refusal"
+		}
+	}
+	if hasToolCall {
+		return "tool_use"
+	}
+	switch reason := response.Get("stop_reason").String(); reason {
+	case "end_turn", "max_tokens", "stop_sequence", "tool_use", "pause_turn", "refusal":
+		return reason
+	}
+	return "end_turn"
+}
+
+// claudeToolUseID returns the tool_use ID for a Codex function call item. The call_id is used
+// unchanged so the tool_result the client sends back maps to the same Codex call; the item ID
+// is the fallback. Characters Claude rejects in IDs are replaced.
+func claudeToolUseID(item gjson.Result) string {
+	id := item.Get("call_id").String()
+	if id == "" {
+		id = item.Get("id").String()
+	}
+	if id == "" {
+		return "toolu_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	return util.SanitizeToolCallID(id)
+}
+
 func extractResponsesUsage(usage gjson.Result) (int64, int64, int64) {
 	if !usage.Exists() || usage.Type == gjson.Null {
 		return 0, 0, 0
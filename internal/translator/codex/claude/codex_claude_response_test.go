@@ -0,0 +1,200 @@
+package claude
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// claudeStreamEvents runs Codex SSE lines through the stream translator and returns the
+// emitted Claude event payloads in order.
+func claudeStreamEvents(t *testing.T, request string, lines ...string) []gjson.Result {
+	t.Helper()
+	var param any
+	var events []gjson.Result
+	for _, line := range lines {
+		for _, out := range ConvertCodexResponseToClaude(context.Background(), "", []byte(request), nil, []byte("data: "+line), &param) {
+			for _, part := range strings.Split(out, "\n") {
+				if data, ok := strings.CutPrefix(part, "data: "); ok {
+					events = append(events, gjson.Parse(data))
+				}
+			}
+		}
+	}
+	return events
+}
+
+// assertClaudeStreamOrder checks the event ordering the Claude SDKs rely on: message_start
+// first, blocks started at consecutive indexes, deltas and stops only for the open block, and
+// message_delta/message_stop last with no block left open.
+func assertClaudeStreamOrder(t *testing.T, events []gjson.Result) {
+	t.Helper()
+	if len(events) < 3 || events[0].Get("type").String() != "message_start" {
+		t.Fatalf("stream must begin with message_start: %v", events)
+	}
+	open, next := -1, 0
+	for i, event := range events[1:] {
+		index := int(event.Get("index").Int())
+		switch event.Get("type").String() {
+		case "content_block_start":
+			if open != -1 || index != next {
+				t.Fatalf("event %d: block %d started while %d open (next %d)", i+1, index, open, next)
+			}
+			open = index
+		case "content_block_delta":
+			if index != open {
+				t.Fatalf("event %d: delta for block %d while %d open", i+1, index, open)
+			}
+		case "content_block_stop":
+			if index != open {
+				t.Fatalf("event %d: stop for block %d while %d open", i+1, index, open)
+			}
+			open, next = -1, next+1
+		case "message_delta":
+			if open != -1 {
+				t.Fatalf("event %d: message_delta with block %d open", i+1, open)
+			}
+		case "message_stop":
+			if i+1 != len(events)-1 {
+				t.Fatalf("message_stop is not the last event")
+			}
+		default:
+			t.Fatalf("event %d: unexpected type %s", i+1, event.Get("type").String())
+		}
+	}
+	if events[len(events)-1].Get("type").String() != "message_stop" {
+		t.Fatal("stream must end with message_stop")
+	}
+}
+
+func TestConvertCodexResponseToClaudeStreamOrder(t *testing.T) {
+	request := `{"tools":[{"name":"Read","input_schema":{"type":"object"}}]}`
+	events := claudeStreamEvents(t, request,
+		`{"type":"response.created","response":{"id":"resp_1","model":"gpt-5"}}`,
+		`{"type":"response.reasoning_summary_part.added"}`,
+		`{"type":"response.reasoning_summary_text.delta","delta":"thinking"}`,
+		`{"type":"response.reasoning_summary_part.done"}`,
+		`{"type":"response.content_part.added"}`,
+		`{"type":"response.output_text.delta","delta":"Reading."}`,
+		// The text part is never closed before the tool call starts.
+		`{"type":"response.output_item.added","item":{"type":"function_call","call_id":"call_abc","name":"Read"}}`,
+		`{"type":"response.function_call_arguments.delta","delta":"{\"path\":\"a\"}"}`,
+		`{"type":"response.output_item.done","item":{"type":"function_call"}}`,
+		`{"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":5,"output_tokens":3}}}`,
+	)
+	assertClaudeStreamOrder(t, events)
+
+	var toolStart, messageDelta gjson.Result
+	for _, event := range events {
+		switch {
+		case event.Get("content_block.type").String() == "tool_use":
+			toolStart = event
+		case event.Get("type").String() == "message_delta":
+			messageDelta = event
+		}
+	}
+	if toolStart.Get("content_block.id").String() != "call_abc" || toolStart.Get("index").Int() != 2 {
+		t.Fatalf("unexpected tool_use start: %s", toolStart.Raw)
+	}
+	if got := messageDelta.Get("delta.stop_reason").String(); got != "tool_use" {
+		t.Fatalf("stop_reason = %q, want tool_use", got)
+	}
+}
+
+func TestConvertCodexResponseToClaudeIncompleteStream(t *testing.T) {
+	events := claudeStreamEvents(t, `{}`,
+		`{"type":"response.created","response":{"id":"resp_1","model":"gpt-5"}}`,
+		`{"type":"response.output_text.delta","delta":"partial"}`,
+		`{"type":"response.incomplete","response":{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}}`,
+	)
+	assertClaudeStreamOrder(t, events)
+	if got := events[len(events)-2].Get("delta.stop_reason").String(); got != "max_tokens" {
+		t.Fatalf("stop_reason = %q, want max_tokens", got)
+	}
+}
+
+func TestClaudeStopReason(t *testing.T) {
+	cases := []struct {
+		response string
+		toolCall bool
+		want     string
+	}{
+		{`{"status":"completed"}`, false, "end_turn"},
+		{`{"status":"completed","stop_reason":"stop"}`, false, "end_turn"},
+		{`{"status":"completed"}`, true, "tool_use"},
+		{`{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}`, true, "max_tokens"},
+		{`{"status":"incomplete","incomplete_details":{"reason":"content_filter"}}`, false, "refusal"},
+	}
+	for _, tc := range cases {
+		if got := claudeStopReason(gjson.Parse(tc.response), tc.toolCall); got != tc.want {
+			t.Errorf("claudeStopReason(%s, %t) = %q, want %q", tc.response, tc.toolCall, got, tc.want)
+		}
+	}
+}
+
+func TestConvertCodexResponseToClaudeNonStreamToolUseID(t *testing.T) {
+	raw := `{"type":"response.completed","response":{"id":"resp_1","model":"gpt-5","status":"completed","output":[` +
+		`{"type":"function_call","call_id":"call_abc","name":"Read","arguments":"{\"path\":\"a\"}"},` +
+		`{"type":"function_call","id":"fc.1","name":"Read","arguments":"{}"}]}}`
+	out := gjson.Parse(ConvertCodexResponseToClaudeNonStream(context.Background(), "", []byte(`{}`), nil, []byte(raw), nil))
+	if got := out.Get("content.0.id").String(); got != "call_abc" {
+		t.Fatalf("first tool_use id = %q, want call_abc", got)
+	}
+	if got := out.Get("content.1.id").String(); got != "fc_1" {
+		t.Fatalf("fallback tool_use id = %q, want fc_1", got)
+	}
+	if got := out.Get("stop_reason").String(); got != "tool_use" {
+		t.Fatalf("stop_reason = %q, want tool_use", got)
+	}
+}
+
+func TestConvertCodexResponseToClaudeWebSearch(t *testing.T) {
+	item := `{"id":"ws_1","type":"web_search_call","status":"completed","action":{"type":"search","query":"go 1.24","sources":[{"type":"url","url":"https://go.dev/doc/go1.24"}]}}`
+	events := claudeStreamEvents(t, `{}`,
+		`{"type":"response.created","response":{"id":"resp_1","model":"gpt-5"}}`,
+		`{"type":"response.output_item.done","item":`+item+`}`,
+		`{"type":"response.content_part.added"}`,
+		`{"type":"response.output_text.delta","delta":"Go 1.24 is out."}`,
+		`{"type":"response.output_text.annotation.added","annotation":{"type":"url_citation","url":"https://go.dev/doc/go1.24","title":"Go 1.24","start_index":0,"end_index":7}}`,
+		`{"type":"response.content_part.done"}`,
+		`{"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":3,"output_tokens":4}}}`,
+	)
+	assertClaudeStreamOrder(t, events)
+	if got := events[1].Get("content_block.type").String(); got != "server_tool_use" {
+		t.Fatalf("first block = %s, want server_tool_use", events[1].Raw)
+	}
+	if got := events[2].Get("delta.partial_json").String(); got != `{"query":"go 1.24"}` {
+		t.Fatalf("server_tool_use input = %q", got)
+	}
+	result := events[4].Get("content_block")
+	if result.Get("type").String() != "web_search_tool_result" || result.Get("tool_use_id").String() != "srvtoolu_1" || result.Get("content.0.url").String() != "https://go.dev/doc/go1.24" {
+		t.Fatalf("web_search_tool_result = %s", result.Raw)
+	}
+	var citation, usage gjson.Result
+	for _, event := range events {
+		if event.Get("delta.type").String() == "citations_delta" {
+			citation = event.Get("delta.citation")
+		}
+		if event.Get("type").String() == "message_delta" {
+			usage = event.Get("usage")
+		}
+	}
+	if citation.Get("cited_text").String() != "Go 1.24" || citation.Get("type").String() != "web_search_result_location" {
+		t.Fatalf("citation = %s", citation.Raw)
+	}
+	if usage.Get("server_tool_use.web_search_requests").Int() != 1 {
+		t.Fatalf("usage = %s", usage.Raw)
+	}
+
+	out := ConvertCodexResponseToClaudeNonStream(context.Background(), "", []byte(`{}`), nil, []byte(`{"type":"response.completed","response":{"id":"resp_1","status":"completed","output":[`+item+`,
+		{"type":"message","content":[{"type":"output_text","text":"Go 1.24 is out.","annotations":[{"type":"url_citation","url":"https://go.dev/doc/go1.24","title":"Go 1.24","start_index":0,"end_index":7}]}]}]}}`), nil)
+	content := gjson.Get(out, "content")
+	if got := content.Get("#.type").Raw; got != `["server_tool_use","web_search_tool_result","text"]` {
+		t.Fatalf("content types = %s", got)
+	}
+	if content.Get("2.citations.0.cited_text").String() != "Go 1.24" || gjson.Get(out, "stop_reason").String() != "end_turn" {
+		t.Fatalf("non-stream response = %s", out)
+	}
+}
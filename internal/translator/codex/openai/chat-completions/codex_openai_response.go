@@ -112,6 +112,14 @@ func ConvertCodexResponseToOpenAI(_ context.Context, modelName string, originalR
 			template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
 			template, _ = sjson.Set(template, "choices.0.delta.content", deltaResult.String())
 		}
+	} else if dataType == "response.output_text.annotation.added" {
+		citation, ok := chatURLCitation(rootResult.Get("annotation"))
+		if !ok {
+			return []string{}
+		}
+		template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
+		template, _ = sjson.SetRaw(template, "choices.0.delta.annotations", `[]`)
+		template, _ = sjson.SetRaw(template, "choices.0.delta.annotations.-1", citation)
 	} else if dataType == "response.completed" {
 		finishReason := "stop"
 		if (*param).(*ConvertCliToOpenAIParams).FunctionCallIndex != -1 {
@@ -279,6 +287,7 @@ func ConvertCodexResponseToOpenAINonStream(_ context.Context, _ string, original
 		var contentText string
 		var reasoningText string
 		var toolCalls []string
+		var annotations []string
 
 		for _, outputItem := range outputArray {
 			outputType := outputItem.Get("type").String()
@@ -302,6 +311,11 @@ func ConvertCodexResponseToOpenAINonStream(_ context.Context, _ string, original
 					for _, contentItem := range contentArray {
 						if contentItem.Get("type").String() == "output_text" {
 							contentText = contentItem.Get("text").String()
+							for _, annotation := range contentItem.Get("annotations").Array() {
+								if citation, ok := chatURLCitation(annotation); ok {
+									annotations = append(annotations, citation)
+								}
+							}
 							break
 						}
 					}
@@ -342,6 +356,14 @@ func ConvertCodexResponseToOpenAINonStream(_ context.Context, _ string, original
 			template, _ = sjson.Set(template, "choices.0.message.role", "assistant")
 		}
 
+		// Web search citations of the built-in web_search tool.
+		if len(annotations) > 0 {
+			template, _ = sjson.SetRaw(template, "choices.0.message.annotations", `[]`)
+			for _, citation := range annotations {
+				template, _ = sjson.SetRaw(template, "choices.0.message.annotations.-1", citation)
+			}
+		}
+
 		// Add tool calls if any
 		if len(toolCalls) > 0 {
 			template, _ = sjson.SetRaw(template, "choices.0.message.tool_calls", `[]`)
@@ -394,3 +416,17 @@ func buildReverseMapFromOriginalOpenAI(original []byte) map[string]string {
 	}
 	return rev
 }
+
+// chatURLCitation converts a Responses url_citation annotation into the Chat Completions
+// annotation format. Other annotation types have no Chat Completions counterpart.
+func chatURLCitation(annotation gjson.Result) (string, bool) {
+	if annotation.Get("type").String() != "url_citation" {
+		return "", false
+	}
+	citation := `{"type":"url_citation","url_citation":{"url":"","title":"","start_index":0,"end_index":0}}`
+	citation, _ = sjson.Set(citation, "url_citation.url", annotation.Get("url").String())
+	citation, _ = sjson.Set(citation, "url_citation.title", annotation.Get("title").String())
+	citation, _ = sjson.Set(citation, "url_citation.start_index", annotation.Get("start_index").Int())
+	citation, _ = sjson.Set(citation, "url_citation.end_index", annotation.Get("end_index").Int())
+	return citation, true
+}
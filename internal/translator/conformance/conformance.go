@@ -0,0 +1,258 @@
+// Package conformance runs a standard battery of request payloads through the
+// registered translators and reports, per source/target pair, which features are
+// carried over faithfully, which survive only in a degraded form and which are dropped.
+package conformance
+
+import (
+	"fmt"
+	"strings"
+
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// Status classifies how a feature survives translation.
+type Status string
+
+const (
+	// StatusSupported means the feature is mapped to its native target field.
+	StatusSupported Status = "supported"
+	// StatusLossy means the content survives but not in the native target field.
+	StatusLossy Status = "lossy"
+	// StatusUnsupported means the feature is dropped by the translator.
+	StatusUnsupported Status = "unsupported"
+)
+
+// Case names of the standard battery.
+const (
+	CaseTools          = "tools"
+	CaseMultimodal     = "multimodal"
+	CaseSystemPrompt   = "system_prompt"
+	CaseStopSequences  = "stop_sequences"
+	CaseStreaming      = "streaming"
+	conformanceModel   = "conformance-probe"
+	toolMarker         = "get_weather_probe"
+	toolParamMarker    = "city_probe"
+	imageMarker        = "iVBORw0KGgoCONFORMANCE"
+	systemMarker       = "You are the conformance probe."
+	stopSequenceMarker = "CONFORMANCE_END"
+)
+
+// Cases lists the battery in report order.
+var Cases = []string{CaseTools, CaseMultimodal, CaseSystemPrompt, CaseStopSequences, CaseStreaming}
+
+// Sources lists the client formats the battery has payloads for.
+var Sources = []sdktranslator.Format{
+	sdktranslator.FormatOpenAI,
+	sdktranslator.FormatOpenAIResponse,
+	sdktranslator.FormatClaude,
+	sdktranslator.FormatGemini,
+	sdktranslator.FormatGeminiCLI,
+}
+
+// Targets lists the provider formats the battery knows how to probe.
+var Targets = []sdktranslator.Format{
+	sdktranslator.FormatOpenAI,
+	sdktranslator.FormatClaude,
+	sdktranslator.FormatCodex,
+	sdktranslator.FormatGemini,
+	sdktranslator.FormatGeminiCLI,
+	sdktranslator.FormatAntigravity,
+}
+
+// CaseResult is the outcome of one case for a source/target pair.
+type CaseResult struct {
+	Case   string `json:"case"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the conformance matrix row of one source/target pair.
+type Report struct {
+	From  string       `json:"from"`
+	To    string       `json:"to"`
+	Cases []CaseResult `json:"cases"`
+}
+
+// probe locates a feature in a translated payload through any of its native paths. No
+// paths means the target carries the feature outside the payload, such as Gemini selecting
+// streaming by endpoint.
+type probe struct {
+	field string
+	paths []string
+}
+
+func native(field string, paths ...string) probe {
+	return probe{field: field, paths: paths}
+}
+
+// targetProbes maps each target format and case to the native fields holding the feature.
+var targetProbes = map[sdktranslator.Format]map[string][]probe{
+	sdktranslator.FormatOpenAI: {
+		CaseTools:         {native("name", "tools.#.function.name"), native("parameters", "tools.#.function.parameters")},
+		CaseMultimodal:    {native("image", "messages.#.content.#.image_url.url")},
+		CaseSystemPrompt:  {native("system", `messages.#(role=="system")#.content`)},
+		CaseStopSequences: {native("stop", "stop")},
+		CaseStreaming:     {native("stream", "stream")},
+	},
+	sdktranslator.FormatClaude: {
+		CaseTools:         {native("name", "tools.#.name"), native("parameters", "tools.#.input_schema")},
+		CaseMultimodal:    {native("image", "messages.#.content.#.source.data")},
+		CaseSystemPrompt:  {native("system", "system")},
+		CaseStopSequences: {native("stop", "stop_sequences")},
+		CaseStreaming:     {native("stream", "stream")},
+	},
+	sdktranslator.FormatCodex: {
+		CaseTools:         {native("name", "tools.#.name"), native("parameters", "tools.#.parameters")},
+		CaseMultimodal:    {native("image", "input.#.content.#.image_url")},
+		CaseSystemPrompt:  {native("system", "instructions", `input.#(role=="developer")#.content`)},
+		CaseStopSequences: {native("stop", "stop")},
+		CaseStreaming:     {native("stream", "stream")},
+	},
+	sdktranslator.FormatGemini:      geminiProbes(""),
+	sdktranslator.FormatGeminiCLI:   geminiProbes("request."),
+	sdktranslator.FormatAntigravity: geminiProbes("request."),
+}
+
+// geminiProbes builds the probes of the Gemini family, whose API accepts both camelCase
+// and snake_case field names.
+func geminiProbes(prefix string) map[string][]probe {
+	declarations := []string{prefix + "tools.#.functionDeclarations", prefix + "tools.#.function_declarations"}
+	return map[string][]probe{
+		CaseTools: {
+			native("name", declarations[0]+".#.name", declarations[1]+".#.name"),
+			native("parameters", declarations[0]+".#.parametersJsonSchema", declarations[0]+".#.parameters",
+				declarations[1]+".#.parametersJsonSchema", declarations[1]+".#.parameters"),
+		},
+		CaseMultimodal:    {native("image", prefix+"contents.#.parts.#.inlineData.data", prefix+"contents.#.parts.#.inline_data.data")},
+		CaseSystemPrompt:  {native("system", prefix+"systemInstruction.parts.#.text", prefix+"system_instruction.parts.#.text")},
+		CaseStopSequences: {native("stop", prefix+"generationConfig.stopSequences")},
+		CaseStreaming:     {native("stream")},
+	}
+}
+
+// caseMarkers are the strings each case plants in the payload; finding them anywhere in
+// the translated payload, but not in the native fields, marks the mapping as lossy.
+var caseMarkers = map[string]string{
+	CaseTools:         toolParamMarker,
+	CaseMultimodal:    imageMarker,
+	CaseSystemPrompt:  systemMarker,
+	CaseStopSequences: stopSequenceMarker,
+}
+
+// Run executes the battery for one pair against the default registry.
+func Run(from, to sdktranslator.Format) (Report, error) {
+	return RunWith(sdktranslator.Default(), from, to)
+}
+
+// RunWith executes the battery for one pair against registry.
+func RunWith(registry *sdktranslator.Registry, from, to sdktranslator.Format) (Report, error) {
+	probes, ok := targetProbes[to]
+	if !ok {
+		return Report{}, fmt.Errorf("unknown target format %q", to)
+	}
+	build, ok := sourcePayloads[from]
+	if !ok {
+		return Report{}, fmt.Errorf("unknown source format %q", from)
+	}
+	report := Report{From: from.String(), To: to.String(), Cases: make([]CaseResult, 0, len(Cases))}
+	if !registry.HasRequestTransformer(from, to) {
+		for _, name := range Cases {
+			report.Cases = append(report.Cases, CaseResult{Case: name, Status: StatusUnsupported, Detail: "no request translator registered"})
+		}
+		return report, nil
+	}
+	for _, name := range Cases {
+		payload := build(name)
+		if payload == nil {
+			continue
+		}
+		stream := name == CaseStreaming
+		out := registry.TranslateRequest(from, to, conformanceModel, payload, stream)
+		report.Cases = append(report.Cases, evaluate(registry, from, to, name, out, probes[name]))
+	}
+	return report, nil
+}
+
+// RunAll executes the battery for every source/target pair with a registered request translator.
+func RunAll() []Report {
+	var reports []Report
+	for _, from := range Sources {
+		for _, to := range Targets {
+			if !sdktranslator.HasRequestTransformer(from, to) {
+				continue
+			}
+			if report, err := Run(from, to); err == nil {
+				reports = append(reports, report)
+			}
+		}
+	}
+	return reports
+}
+
+func evaluate(registry *sdktranslator.Registry, from, to sdktranslator.Format, name string, out []byte, probes []probe) CaseResult {
+	result := CaseResult{Case: name}
+	if !gjson.ValidBytes(out) {
+		result.Status, result.Detail = StatusUnsupported, "translator produced invalid JSON"
+		return result
+	}
+	if name == CaseStreaming {
+		return evaluateStreaming(registry, from, to, out, probes)
+	}
+
+	marker := caseMarkers[name]
+	var missing []string
+	for _, p := range probes {
+		if !p.found(out, probeValue(name, p.field, marker)) {
+			missing = append(missing, p.field)
+		}
+	}
+	switch {
+	case len(missing) == 0:
+		result.Status = StatusSupported
+	case len(missing) < len(probes) || strings.Contains(string(out), marker):
+		result.Status = StatusLossy
+		result.Detail = "not mapped to native field: " + strings.Join(missing, ", ")
+	default:
+		result.Status = StatusUnsupported
+		result.Detail = "dropped: " + strings.Join(missing, ", ")
+	}
+	return result
+}
+
+// found reports whether value appears in the probed field of out.
+func (p probe) found(out []byte, value string) bool {
+	for _, path := range p.paths {
+		if strings.Contains(gjson.GetBytes(out, path).Raw, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeValue returns the string a probe expects in its field.
+func probeValue(name, field, marker string) string {
+	if name == CaseTools && field == "name" {
+		return toolMarker
+	}
+	return marker
+}
+
+func evaluateStreaming(registry *sdktranslator.Registry, from, to sdktranslator.Format, out []byte, probes []probe) CaseResult {
+	result := CaseResult{Case: CaseStreaming, Status: StatusSupported}
+	if from != to && !registry.HasResponseTransformer(from, to) {
+		result.Status, result.Detail = StatusUnsupported, "no response translator registered"
+		return result
+	}
+	for _, p := range probes {
+		if len(p.paths) == 0 {
+			result.Detail = "streaming is selected by the upstream endpoint"
+			continue
+		}
+		if !gjson.GetBytes(out, p.paths[0]).Bool() {
+			result.Status, result.Detail = StatusLossy, "stream flag not set in translated payload"
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package conformance
+
+import (
+	"testing"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+func caseStatuses(report Report) map[string]CaseResult {
+	out := make(map[string]CaseResult, len(report.Cases))
+	for _, c := range report.Cases {
+		out[c.Case] = c
+	}
+	return out
+}
+
+func TestRunWithClassifiesMappings(t *testing.T) {
+	registry := sdktranslator.NewRegistry()
+	// A deliberately poor Claude -> OpenAI translator: it keeps tools, folds the system
+	// prompt into a user message, drops stop sequences and forgets the stream flag.
+	registry.Register(sdktranslator.FormatClaude, sdktranslator.FormatOpenAI, func(model string, raw []byte, stream bool) []byte {
+		out := []byte(`{"model":"` + model + `","messages":[]}`)
+		if system := gjson.GetBytes(raw, "system").String(); system != "" {
+			out, _ = sjson.SetBytes(out, "messages.-1", map[string]string{"role": "user", "content": system})
+		}
+		if tools := gjson.GetBytes(raw, "tools.0").Raw; tools != "" {
+			out, _ = sjson.SetRawBytes(out, "tools.0", []byte(`{"type":"function","function":{"name":`+gjson.GetBytes(raw, "tools.0.name").Raw+`,"parameters":`+gjson.GetBytes(raw, "tools.0.input_schema").Raw+`}}`))
+		}
+		return out
+	}, sdktranslator.ResponseTransform{})
+
+	report, err := RunWith(registry, sdktranslator.FormatClaude, sdktranslator.FormatOpenAI)
+	if err != nil {
+		t.Fatalf("RunWith: %v", err)
+	}
+	got := caseStatuses(report)
+	want := map[string]Status{
+		CaseTools:         StatusSupported,
+		CaseMultimodal:    StatusUnsupported,
+		CaseSystemPrompt:  StatusLossy,
+		CaseStopSequences: StatusUnsupported,
+		CaseStreaming:     StatusLossy,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("cases = %+v, want %d cases", report.Cases, len(want))
+	}
+	for name, status := range want {
+		if got[name].Status != status {
+			t.Errorf("%s = %s (%s), want %s", name, got[name].Status, got[name].Detail, status)
+		}
+	}
+}
+
+func TestRunWithUnregisteredPair(t *testing.T) {
+	report, err := RunWith(sdktranslator.NewRegistry(), sdktranslator.FormatGemini, sdktranslator.FormatCodex)
+	if err != nil {
+		t.Fatalf("RunWith: %v", err)
+	}
+	for _, c := range report.Cases {
+		if c.Status != StatusUnsupported {
+			t.Errorf("%s = %s, want unsupported", c.Case, c.Status)
+		}
+	}
+	if _, err = RunWith(sdktranslator.NewRegistry(), "unknown", sdktranslator.FormatCodex); err == nil {
+		t.Fatal("expected error for unknown source format")
+	}
+}
+
+func TestRunAllOmitsInapplicableCases(t *testing.T) {
+	reports := RunAll()
+	if len(reports) == 0 {
+		t.Fatal("expected reports for the built-in translators")
+	}
+	for _, report := range reports {
+		statuses := caseStatuses(report)
+		if report.From == sdktranslator.FormatOpenAIResponse.String() {
+			if _, ok := statuses[CaseStopSequences]; ok {
+				t.Errorf("%s->%s reports stop sequences, which the Responses API cannot express", report.From, report.To)
+			}
+		}
+		if report.From == sdktranslator.FormatClaude.String() && report.To == sdktranslator.FormatOpenAI.String() {
+			if statuses[CaseTools].Status != StatusSupported {
+				t.Errorf("claude->openai tools = %+v, want supported", statuses[CaseTools])
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package conformance
+
+import (
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/sjson"
+)
+
+// sourcePayloads builds the request of a case in each source format. A nil payload means
+// the source format cannot express the case, and it is left out of the report.
+var sourcePayloads = map[sdktranslator.Format]func(name string) []byte{
+	sdktranslator.FormatOpenAI:         openAIPayload,
+	sdktranslator.FormatOpenAIResponse: openAIResponsesPayload,
+	sdktranslator.FormatClaude:         claudePayload,
+	sdktranslator.FormatGemini:         geminiPayload,
+	sdktranslator.FormatGeminiCLI:      geminiCLIPayload,
+}
+
+const (
+	probePrompt     = "What is the weather in Paris?"
+	probeImageType  = "image/png"
+	probeToolDesc   = "Look up the current weather."
+	probeToolSchema = `{"type":"object","properties":{"` + toolParamMarker + `":{"type":"string"}},"required":["` + toolParamMarker + `"]}`
+)
+
+func openAIPayload(name string) []byte {
+	out := []byte(`{"model":"` + conformanceModel + `","messages":[{"role":"user","content":"` + probePrompt + `"}]}`)
+	switch name {
+	case CaseTools:
+		out, _ = sjson.SetRawBytes(out, "tools", []byte(`[{"type":"function","function":{"name":"`+toolMarker+`","description":"`+probeToolDesc+`","parameters":`+probeToolSchema+`}}]`))
+	case CaseMultimodal:
+		out, _ = sjson.SetRawBytes(out, "messages.0.content", []byte(`[{"type":"text","text":"`+probePrompt+`"},{"type":"image_url","image_url":{"url":"data:`+probeImageType+`;base64,`+imageMarker+`"}}]`))
+	case CaseSystemPrompt:
+		out, _ = sjson.SetRawBytes(out, "messages", []byte(`[{"role":"system","content":"`+systemMarker+`"},{"role":"user","content":"`+probePrompt+`"}]`))
+	case CaseStopSequences:
+		out, _ = sjson.SetBytes(out, "stop", []string{stopSequenceMarker})
+	case CaseStreaming:
+		out, _ = sjson.SetBytes(out, "stream", true)
+	}
+	return out
+}
+
+func openAIResponsesPayload(name string) []byte {
+	out := []byte(`{"model":"` + conformanceModel + `","input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"` + probePrompt + `"}]}]}`)
+	switch name {
+	case CaseTools:
+		out, _ = sjson.SetRawBytes(out, "tools", []byte(`[{"type":"function","name":"`+toolMarker+`","description":"`+probeToolDesc+`","parameters":`+probeToolSchema+`}]`))
+	case CaseMultimodal:
+		out, _ = sjson.SetRawBytes(out, "input.0.content.-1", []byte(`{"type":"input_image","image_url":"data:`+probeImageType+`;base64,`+imageMarker+`"}`))
+	case CaseSystemPrompt:
+		out, _ = sjson.SetBytes(out, "instructions", systemMarker)
+	case CaseStopSequences:
+		// The Responses API has no stop sequences.
+		return nil
+	case CaseStreaming:
+		out, _ = sjson.SetBytes(out, "stream", true)
+	}
+	return out
+}
+
+func claudePayload(name string) []byte {
+	out := []byte(`{"model":"` + conformanceModel + `","max_tokens":1024,"messages":[{"role":"user","content":"` + probePrompt + `"}]}`)
+	switch name {
+	case CaseTools:
+		out, _ = sjson.SetRawBytes(out, "tools", []byte(`[{"name":"`+toolMarker+`","description":"`+probeToolDesc+`","input_schema":`+probeToolSchema+`}]`))
+	case CaseMultimodal:
+		out, _ = sjson.SetRawBytes(out, "messages.0.content", []byte(`[{"type":"text","text":"`+probePrompt+`"},{"type":"image","source":{"type":"base64","media_type":"`+probeImageType+`","data":"`+imageMarker+`"}}]`))
+	case CaseSystemPrompt:
+		out, _ = sjson.SetBytes(out, "system", systemMarker)
+	case CaseStopSequences:
+		out, _ = sjson.SetBytes(out, "stop_sequences", []string{stopSequenceMarker})
+	case CaseStreaming:
+		out, _ = sjson.SetBytes(out, "stream", true)
+	}
+	return out
+}
+
+func geminiPayload(name string) []byte {
+	out := []byte(`{"contents":[{"role":"user","parts":[{"text":"` + probePrompt + `"}]}]}`)
+	switch name {
+	case CaseTools:
+		out, _ = sjson.SetRawBytes(out, "tools", []byte(`[{"functionDeclarations":[{"name":"`+toolMarker+`","description":"`+probeToolDesc+`","parameters":`+probeToolSchema+`}]}]`))
+	case CaseMultimodal:
+		out, _ = sjson.SetRawBytes(out, "contents.0.parts.-1", []byte(`{"inlineData":{"mimeType":"`+probeImageType+`","data":"`+imageMarker+`"}}`))
+	case CaseSystemPrompt:
+		out, _ = sjson.SetBytes(out, "systemInstruction.parts.0.text", systemMarker)
+	case CaseStopSequences:
+		out, _ = sjson.SetBytes(out, "generationConfig.stopSequences", []string{stopSequenceMarker})
+	}
+	// Gemini selects streaming by endpoint, so the streaming case reuses the plain request.
+	return out
+}
+
+func geminiCLIPayload(name string) []byte {
+	out, _ := sjson.SetRawBytes([]byte(`{"model":"`+conformanceModel+`"}`), "request", geminiPayload(name))
+	return out
+}
@@ -0,0 +1,39 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// Tool results of calls made by another provider carry IDs such as "toolu_..." or "call_..."
+// that do not encode the function name, which Gemini needs to link the response.
+func TestConvertClaudeRequestToGeminiNamesToolResultsFromHistory(t *testing.T) {
+	input := []byte(`{
+		"model": "gemini-2.5-pro",
+		"messages": [
+			{"role": "user", "content": "What is in main.go?"},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_01AbC", "name": "read_file", "input": {"path": "main.go"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_01AbC", "content": "package main"}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToGemini("gemini-2.5-pro", input, false)
+	if got := gjson.GetBytes(out, "contents.2.parts.0.functionResponse.name").String(); got != "read_file" {
+		t.Fatalf("functionResponse name = %q, want %q", got, "read_file")
+	}
+}
+
+func TestConvertClaudeRequestToGeminiFallsBackToIDName(t *testing.T) {
+	input := []byte(`{
+		"model": "gemini-2.5-pro",
+		"messages": [
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "read_file-1", "content": "package main"}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToGemini("gemini-2.5-pro", input, false)
+	if got := gjson.GetBytes(out, "contents.0.parts.0.functionResponse.name").String(); got != "read_file" {
+		t.Fatalf("functionResponse name = %q, want %q", got, "read_file")
+	}
+}
@@ -0,0 +1,19 @@
+package chat_completions
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIRequestToGeminiMapsSeed(t *testing.T) {
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","seed":42,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.seed"); got.Int() != 42 {
+		t.Fatalf("generationConfig.seed = %s, want 42", got.Raw)
+	}
+
+	out = ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.seed"); got.Exists() {
+		t.Fatalf("generationConfig.seed = %s, want it unset", got.Raw)
+	}
+}
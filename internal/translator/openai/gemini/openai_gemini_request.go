@@ -64,6 +64,11 @@ func ConvertGeminiRequestToOpenAI(modelName string, inputRawJSON []byte, stream
 			out, _ = sjson.Set(out, "top_k", topK.Int())
 		}
 
+		// Seed
+		if seed := genConfig.Get("seed"); seed.Exists() {
+			out, _ = sjson.Set(out, "seed", seed.Int())
+		}
+
 		// Stop sequences
 		if stopSequences := genConfig.Get("stopSequences"); stopSequences.Exists() && stopSequences.IsArray() {
 			var stops []string
@@ -0,0 +1,179 @@
+package usage
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the samples kept per auth, provider and model.
+const maxLatencySamples = 200
+
+// Supported LatencySummaries groupings.
+const (
+	LatencyGroupAuth     = "auth"
+	LatencyGroupProvider = "provider"
+	LatencyGroupModel    = "model"
+)
+
+// StreamLatencySample is the timing of one successful streamed upstream response.
+type StreamLatencySample struct {
+	AuthIndex string
+	Provider  string
+	Model     string
+	// TTFT is the time from sending the upstream request to receiving the first payload.
+	TTFT time.Duration
+	// TokensPerSecond is the output rate after the first payload; zero when the output token
+	// count is unknown.
+	TokensPerSecond float64
+}
+
+// Percentiles summarizes a set of values.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// LatencySummary holds the streaming latency percentiles of one group.
+type LatencySummary struct {
+	AuthIndex string `json:"auth_index,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Samples   int    `json:"samples"`
+	// TTFTMs summarizes the time to first token in milliseconds.
+	TTFTMs Percentiles `json:"ttft_ms"`
+	// TokensPerSecond summarizes output rates over the samples that reported output tokens.
+	TokensPerSecond *Percentiles `json:"tokens_per_second,omitempty"`
+}
+
+type latencyKey struct {
+	authIndex string
+	provider  string
+	model     string
+}
+
+// latencyStore keeps the most recent streaming samples per auth, provider and model.
+type latencyStore struct {
+	mu      sync.Mutex
+	samples map[latencyKey][]StreamLatencySample
+}
+
+var defaultLatencyStore = &latencyStore{samples: make(map[latencyKey][]StreamLatencySample)}
+
+// RecordStreamLatency adds a streaming latency sample when usage statistics are enabled.
+func RecordStreamLatency(sample StreamLatencySample) {
+	if !statisticsEnabled.Load() || sample.TTFT < 0 {
+		return
+	}
+	defaultLatencyStore.record(sample)
+}
+
+// LatencySummaries returns streaming latency percentiles grouped by groupBy, one of
+// LatencyGroupAuth, LatencyGroupProvider or LatencyGroupModel. Any other value groups by
+// all three. Summaries are ordered by descending median time to first token.
+func LatencySummaries(groupBy string) []LatencySummary {
+	return defaultLatencyStore.summaries(groupBy)
+}
+
+// FastestStreamTTFT returns the lowest recorded time to first token of an auth, provider and
+// model, reporting false when there are no samples.
+func FastestStreamTTFT(authIndex, provider, model string) (time.Duration, bool) {
+	return defaultLatencyStore.fastest(latencyKey{authIndex: authIndex, provider: provider, model: model})
+}
+
+func (s *latencyStore) fastest(key latencyKey) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples[key]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	fastest := samples[0].TTFT
+	for _, sample := range samples[1:] {
+		fastest = min(fastest, sample.TTFT)
+	}
+	return fastest, true
+}
+
+func (s *latencyStore) record(sample StreamLatencySample) {
+	key := latencyKey{authIndex: sample.AuthIndex, provider: sample.Provider, model: sample.Model}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := append(s.samples[key], sample)
+	if len(samples) > maxLatencySamples {
+		samples = samples[len(samples)-maxLatencySamples:]
+	}
+	s.samples[key] = samples
+}
+
+func (s *latencyStore) summaries(groupBy string) []LatencySummary {
+	groupBy = strings.ToLower(strings.TrimSpace(groupBy))
+	groups := make(map[latencyKey][]StreamLatencySample)
+	s.mu.Lock()
+	for key, samples := range s.samples {
+		switch groupBy {
+		case LatencyGroupAuth:
+			key = latencyKey{authIndex: key.authIndex}
+		case LatencyGroupProvider:
+			key = latencyKey{provider: key.provider}
+		case LatencyGroupModel:
+			key = latencyKey{model: key.model}
+		}
+		groups[key] = append(groups[key], samples...)
+	}
+	s.mu.Unlock()
+
+	out := make([]LatencySummary, 0, len(groups))
+	for key, samples := range groups {
+		ttft := make([]float64, 0, len(samples))
+		rates := make([]float64, 0, len(samples))
+		for _, sample := range samples {
+			ttft = append(ttft, float64(sample.TTFT.Milliseconds()))
+			if sample.TokensPerSecond > 0 {
+				rates = append(rates, sample.TokensPerSecond)
+			}
+		}
+		summary := LatencySummary{
+			AuthIndex: key.authIndex,
+			Provider:  key.provider,
+			Model:     key.model,
+			Samples:   len(samples),
+			TTFTMs:    percentiles(ttft),
+		}
+		if len(rates) > 0 {
+			rate := percentiles(rates)
+			summary.TokensPerSecond = &rate
+		}
+		out = append(out, summary)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TTFTMs.P50 != out[j].TTFTMs.P50 {
+			return out[i].TTFTMs.P50 > out[j].TTFTMs.P50
+		}
+		if out[i].AuthIndex != out[j].AuthIndex {
+			return out[i].AuthIndex < out[j].AuthIndex
+		}
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// percentiles computes nearest-rank percentiles of values, which it sorts in place.
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Float64s(values)
+	rank := func(p float64) float64 {
+		idx := int(math.Ceil(p/100*float64(len(values)))) - 1
+		return values[max(idx, 0)]
+	}
+	return Percentiles{P50: rank(50), P90: rank(90), P99: rank(99), Max: values[len(values)-1]}
+}
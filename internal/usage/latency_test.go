@@ -0,0 +1,45 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyStoreSummaries(t *testing.T) {
+	store := &latencyStore{samples: make(map[latencyKey][]StreamLatencySample)}
+	for i := 1; i <= 10; i++ {
+		store.record(StreamLatencySample{AuthIndex: "a1", Provider: "codex", Model: "gpt-5", TTFT: time.Duration(i*100) * time.Millisecond, TokensPerSecond: float64(i * 10)})
+	}
+	store.record(StreamLatencySample{AuthIndex: "a2", Provider: "codex", Model: "gpt-5", TTFT: 50 * time.Millisecond})
+
+	byAuth := store.summaries(LatencyGroupAuth)
+	if len(byAuth) != 2 || byAuth[0].AuthIndex != "a1" || byAuth[0].Provider != "" {
+		t.Fatalf("by auth = %+v", byAuth)
+	}
+	slow := byAuth[0]
+	if slow.Samples != 10 || slow.TTFTMs.P50 != 500 || slow.TTFTMs.P90 != 900 || slow.TTFTMs.Max != 1000 {
+		t.Fatalf("ttft = %+v", slow.TTFTMs)
+	}
+	if slow.TokensPerSecond == nil || slow.TokensPerSecond.P50 != 50 {
+		t.Fatalf("tokens per second = %+v", slow.TokensPerSecond)
+	}
+	if byAuth[1].TokensPerSecond != nil {
+		t.Fatalf("expected no rate without output tokens, got %+v", byAuth[1].TokensPerSecond)
+	}
+
+	byProvider := store.summaries(LatencyGroupProvider)
+	if len(byProvider) != 1 || byProvider[0].Provider != "codex" || byProvider[0].Samples != 11 {
+		t.Fatalf("by provider = %+v", byProvider)
+	}
+}
+
+func TestLatencyStoreKeepsRecentSamples(t *testing.T) {
+	store := &latencyStore{samples: make(map[latencyKey][]StreamLatencySample)}
+	for i := 0; i < maxLatencySamples+5; i++ {
+		store.record(StreamLatencySample{AuthIndex: "a1", TTFT: time.Duration(i) * time.Millisecond})
+	}
+	summaries := store.summaries("")
+	if len(summaries) != 1 || summaries[0].Samples != maxLatencySamples || summaries[0].TTFTMs.Max != float64(maxLatencySamples+4) {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 )
 
@@ -89,15 +90,17 @@ type modelStats struct {
 
 // RequestDetail stores the timestamp and token usage for a single request.
 type RequestDetail struct {
-	Timestamp  time.Time  `json:"timestamp"`
-	Source     string     `json:"source"`
-	AuthIndex  string     `json:"auth_index"`
-	RequestID  string     `json:"request_id"`
-	SessionID  string     `json:"session_id"`
-	StatusCode int        `json:"status_code"`
-	DurationMs int64      `json:"duration_ms"`
-	Tokens     TokenStats `json:"tokens"`
-	Failed     bool       `json:"failed"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	AuthIndex string    `json:"auth_index"`
+	RequestID string    `json:"request_id"`
+	SessionID string    `json:"session_id"`
+	// SystemFingerprint is the upstream backend configuration, for tracking reproducibility.
+	SystemFingerprint string     `json:"system_fingerprint,omitempty"`
+	StatusCode        int        `json:"status_code"`
+	DurationMs        int64      `json:"duration_ms"`
+	Tokens            TokenStats `json:"tokens"`
+	Failed            bool       `json:"failed"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -201,15 +204,16 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		s.apis[statsKey] = stats
 	}
 	s.updateAPIStats(stats, modelName, RequestDetail{
-		Timestamp:  timestamp,
-		Source:     record.Source,
-		AuthIndex:  record.AuthIndex,
-		RequestID:  record.RequestID,
-		SessionID:  record.SessionID,
-		StatusCode: record.StatusCode,
-		DurationMs: record.DurationMs,
-		Tokens:     detail,
-		Failed:     failed,
+		Timestamp:         timestamp,
+		Source:            record.Source,
+		AuthIndex:         record.AuthIndex,
+		RequestID:         record.RequestID,
+		SessionID:         record.SessionID,
+		SystemFingerprint: record.SystemFingerprint,
+		StatusCode:        record.StatusCode,
+		DurationMs:        record.DurationMs,
+		Tokens:            detail,
+		Failed:            failed,
 	})
 
 	s.requestsByDay[dayKey]++
@@ -405,23 +409,12 @@ func dedupKey(apiName, modelName string, detail RequestDetail) string {
 }
 
 func resolveAPIIdentifier(ctx context.Context, record coreusage.Record) string {
-	if ctx != nil {
-		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
-			path := ginCtx.FullPath()
-			if path == "" && ginCtx.Request != nil {
-				path = ginCtx.Request.URL.Path
-			}
-			method := ""
-			if ginCtx.Request != nil {
-				method = ginCtx.Request.Method
-			}
-			if path != "" {
-				if method != "" {
-					return method + " " + path
-				}
-				return path
-			}
+	meta := coreexecutor.RequestMetadataFromContext(ctx)
+	if meta.Path != "" {
+		if meta.Method != "" {
+			return meta.Method + " " + meta.Path
 		}
+		return meta.Path
 	}
 	if record.Provider != "" {
 		return record.Provider
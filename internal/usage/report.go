@@ -0,0 +1,148 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+)
+
+// Report summarizes the requests recorded in a time range.
+type Report struct {
+	Name   string       `json:"name"`
+	Start  time.Time    `json:"start"`
+	End    time.Time    `json:"end"`
+	Totals ReportTotals `json:"totals"`
+	// ByKey groups usage by client API key (masked).
+	ByKey []ReportRow `json:"by_key"`
+	// ByAuth groups usage by the upstream auth that served the request.
+	ByAuth []ReportRow `json:"by_auth"`
+}
+
+// ReportTotals holds aggregated counters of a report or one of its rows.
+type ReportTotals struct {
+	Requests    int64 `json:"requests"`
+	Failures    int64 `json:"failures"`
+	InputTokens int64 `json:"input_tokens"`
+	// CachedTokens is the part of InputTokens read from the prompt cache.
+	CachedTokens int64 `json:"cached_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+	// EstimatedCost is computed from the configured model pricing; models without pricing add nothing.
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// ReportRow is the usage of one client key or auth.
+type ReportRow struct {
+	Name string `json:"name"`
+	ReportTotals
+}
+
+// BuildReport summarizes the requests of snapshot made in [start, end). Costs are estimated
+// from pricing.
+func BuildReport(name string, snapshot StatisticsSnapshot, start, end time.Time, pricing []config.ModelPricing) Report {
+	report := Report{Name: name, Start: start, End: end}
+	byKey := make(map[string]*ReportTotals)
+	byAuth := make(map[string]*ReportTotals)
+	for apiKey, api := range snapshot.APIs {
+		for model, stats := range api.Models {
+			price, priced := reportPricing(pricing, model)
+			for _, detail := range stats.Details {
+				if detail.Timestamp.Before(start) || !detail.Timestamp.Before(end) {
+					continue
+				}
+				cost := 0.0
+				if priced {
+					cost = price.Cost(detail.Tokens.InputTokens, detail.Tokens.CachedTokens, detail.Tokens.OutputTokens)
+				}
+				auth := detail.AuthIndex
+				if auth == "" {
+					auth = "unknown"
+				}
+				for _, totals := range []*ReportTotals{&report.Totals, reportRow(byKey, util.HideAPIKey(apiKey)), reportRow(byAuth, auth)} {
+					totals.add(detail, cost)
+				}
+			}
+		}
+	}
+	report.ByKey = sortedReportRows(byKey)
+	report.ByAuth = sortedReportRows(byAuth)
+	return report
+}
+
+func (t *ReportTotals) add(detail RequestDetail, cost float64) {
+	t.Requests++
+	if detail.Failed {
+		t.Failures++
+	}
+	t.InputTokens += detail.Tokens.InputTokens
+	t.CachedTokens += detail.Tokens.CachedTokens
+	t.OutputTokens += detail.Tokens.OutputTokens
+	t.TotalTokens += detail.Tokens.TotalTokens
+	t.EstimatedCost += cost
+}
+
+// Text renders the report as plain text for email bodies and chat messages.
+func (r Report) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s to %s\n\n", r.Name, r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Total: %s\n", r.Totals.summary())
+	for _, section := range []struct {
+		title string
+		rows  []ReportRow
+	}{{"By client key", r.ByKey}, {"By auth", r.ByAuth}} {
+		if len(section.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", section.title)
+		for _, row := range section.rows {
+			fmt.Fprintf(&sb, "  %s: %s\n", row.Name, row.summary())
+		}
+	}
+	return sb.String()
+}
+
+func (t ReportTotals) summary() string {
+	return fmt.Sprintf("%d requests (%d failed), %d tokens (%d in, %d cached / %d out), est. cost %.4f",
+		t.Requests, t.Failures, t.TotalTokens, t.InputTokens, t.CachedTokens, t.OutputTokens, t.EstimatedCost)
+}
+
+func reportRow(rows map[string]*ReportTotals, name string) *ReportTotals {
+	row, ok := rows[name]
+	if !ok {
+		row = &ReportTotals{}
+		rows[name] = row
+	}
+	return row
+}
+
+// sortedReportRows orders rows by estimated cost, then tokens, then name.
+func sortedReportRows(rows map[string]*ReportTotals) []ReportRow {
+	out := make([]ReportRow, 0, len(rows))
+	for name, totals := range rows {
+		out = append(out, ReportRow{Name: name, ReportTotals: *totals})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].EstimatedCost != out[j].EstimatedCost {
+			return out[i].EstimatedCost > out[j].EstimatedCost
+		}
+		if out[i].TotalTokens != out[j].TotalTokens {
+			return out[i].TotalTokens > out[j].TotalTokens
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func reportPricing(pricing []config.ModelPricing, model string) (config.ModelPricing, bool) {
+	for _, entry := range pricing {
+		if wildcard.Match(entry.Model, model) {
+			return entry, true
+		}
+	}
+	return config.ModelPricing{}, false
+}
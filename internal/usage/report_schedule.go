@@ -0,0 +1,133 @@
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportSchedule is a parsed five-field cron expression: minute, hour, day of month, month
+// and day of week (0 or 7 is Sunday). Fields accept "*", numbers, ranges "a-b", steps "*/n"
+// or "a-b/n", and comma-separated lists of those.
+type ReportSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday record unrestricted day fields; cron matches either restricted one.
+	anyDay, anyWeekday bool
+}
+
+// ParseReportSchedule parses a cron expression.
+func ParseReportSchedule(spec string) (ReportSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return ReportSchedule{}, fmt.Errorf("schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	var s ReportSchedule
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return s, fmt.Errorf("schedule %q: minute: %w", spec, err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return s, fmt.Errorf("schedule %q: hour: %w", spec, err)
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return s, fmt.Errorf("schedule %q: day of month: %w", spec, err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return s, fmt.Errorf("schedule %q: month: %w", spec, err)
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return s, fmt.Errorf("schedule %q: day of week: %w", spec, err)
+	}
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+	return s, nil
+}
+
+// Next returns the first time after t matching the schedule, in t's location.
+// It returns the zero time when no match exists within four years.
+func (s ReportSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(4, 0, 0)
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s ReportSchedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var errA, errB error
+			start, errA = strconv.Atoi(a)
+			end, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			start, end = n, n
+			if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestBuildReportAggregatesByKeyAndAuth(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	detail := func(at time.Time, auth string, in, out int64, failed bool) RequestDetail {
+		return RequestDetail{Timestamp: at, AuthIndex: auth, Failed: failed, Tokens: TokenStats{InputTokens: in, OutputTokens: out, TotalTokens: in + out}}
+	}
+	snapshot := StatisticsSnapshot{APIs: map[string]APISnapshot{
+		"sk-client-one-1234": {Models: map[string]ModelSnapshot{
+			"gpt-5": {Details: []RequestDetail{
+				detail(start.Add(time.Hour), "auth-a", 1_000_000, 0, false),
+				detail(start.Add(-time.Hour), "auth-a", 5, 5, false), // before the period
+			}},
+		}},
+		"k2": {Models: map[string]ModelSnapshot{
+			"unpriced": {Details: []RequestDetail{
+				detail(start.Add(2*time.Hour), "auth-b", 10, 20, true),
+				detail(end, "auth-b", 10, 20, false), // end is exclusive
+			}},
+		}},
+	}}
+	pricing := []config.ModelPricing{{Model: "gpt-*", InputPerMillion: 2, OutputPerMillion: 8}}
+
+	report := BuildReport("daily", snapshot, start, end, pricing)
+	if report.Totals.Requests != 2 || report.Totals.Failures != 1 || report.Totals.TotalTokens != 1_000_030 {
+		t.Fatalf("totals = %+v", report.Totals)
+	}
+	if report.Totals.EstimatedCost != 2 {
+		t.Fatalf("estimated cost = %v, want 2", report.Totals.EstimatedCost)
+	}
+	if len(report.ByKey) != 2 || report.ByKey[0].Name != "sk-c...1234" {
+		t.Fatalf("by key = %+v", report.ByKey)
+	}
+	if len(report.ByAuth) != 2 || report.ByAuth[0].Name != "auth-a" || report.ByAuth[1].Failures != 1 {
+		t.Fatalf("by auth = %+v", report.ByAuth)
+	}
+	text := report.Text()
+	for _, want := range []string{"daily:", "By client key:", "auth-b: 1 requests (1 failed)"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestBuildReportPricesCachedTokens(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := StatisticsSnapshot{APIs: map[string]APISnapshot{
+		"k1": {Models: map[string]ModelSnapshot{
+			"claude-sonnet-4": {Details: []RequestDetail{{
+				Timestamp: start.Add(time.Hour),
+				Tokens:    TokenStats{InputTokens: 1_000_000, CachedTokens: 800_000, OutputTokens: 100_000, TotalTokens: 1_100_000},
+			}}},
+		}},
+	}}
+	pricing := []config.ModelPricing{{Model: "claude-*", InputPerMillion: 3, CachedInputPerMillion: 0.3, OutputPerMillion: 15}}
+
+	report := BuildReport("daily", snapshot, start, start.Add(24*time.Hour), pricing)
+	if report.Totals.CachedTokens != 800_000 {
+		t.Fatalf("cached tokens = %d", report.Totals.CachedTokens)
+	}
+	// 0.2M uncached at 3 + 0.8M cached at 0.3 + 0.1M output at 15.
+	if got := report.Totals.EstimatedCost; got < 2.339 || got > 2.341 {
+		t.Fatalf("estimated cost = %v, want 2.34", got)
+	}
+	if !strings.Contains(report.Text(), "1000000 in, 800000 cached / 100000 out") {
+		t.Fatalf("text = %s", report.Text())
+	}
+}
+
+func TestReportScheduleNext(t *testing.T) {
+	cases := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"0 0 * * *", time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1", time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 8, 30, 0, 0, time.UTC)},
+		{"*/15 9-10 * * *", time.Date(2026, 3, 1, 9, 50, 0, 0, time.UTC), time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		schedule, err := ParseReportSchedule(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+		if got := schedule.Next(tc.from); !got.Equal(tc.want) {
+			t.Errorf("%q next after %s = %s, want %s", tc.spec, tc.from, got, tc.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseReportSchedule(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
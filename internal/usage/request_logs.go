@@ -11,27 +11,33 @@ const maxRequestLogEntries = 500
 
 // RequestLogEntry represents a live request entry for monitor logging.
 type RequestLogEntry struct {
-	ID           string    `json:"id"`
-	Method       string    `json:"method"`
-	Path         string    `json:"path"`
-	APIKey       string    `json:"api_key,omitempty"`
-	RequestType  string    `json:"request_type,omitempty"`
-	Model        string    `json:"model,omitempty"`
-	SessionID    string    `json:"session_id,omitempty"`
-	StatusCode   int       `json:"status_code"`
-	ErrorMessage string    `json:"error_message,omitempty"`
-	StartedAt    time.Time `json:"started_at"`
-	CompletedAt  time.Time `json:"completed_at,omitempty"`
-	DurationMs   int64     `json:"duration_ms"`
-	Pending      bool      `json:"pending"`
+	ID              string    `json:"id"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	APIKey          string    `json:"api_key,omitempty"`
+	RequestType     string    `json:"request_type,omitempty"`
+	Model           string    `json:"model,omitempty"`
+	SessionID       string    `json:"session_id,omitempty"`
+	Moderation      string    `json:"moderation,omitempty"`
+	TTFTMs          int64     `json:"ttft_ms,omitempty"`
+	TokensPerSecond float64   `json:"tokens_per_second,omitempty"`
+	StatusCode      int       `json:"status_code"`
+	ErrorMessage    string    `json:"error_message,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	CompletedAt     time.Time `json:"completed_at,omitempty"`
+	DurationMs      int64     `json:"duration_ms"`
+	Pending         bool      `json:"pending"`
 }
 
 // RequestLogUpdate carries optional fields to update a request entry.
 type RequestLogUpdate struct {
-	APIKey      string
-	RequestType string
-	Model       string
-	SessionID   string
+	APIKey          string
+	RequestType     string
+	Model           string
+	SessionID       string
+	Moderation      string
+	TTFTMs          int64
+	TokensPerSecond float64
 }
 
 type requestLogStore struct {
@@ -142,6 +148,15 @@ func (s *requestLogStore) update(id string, update RequestLogUpdate) {
 	if update.SessionID != "" {
 		entry.SessionID = update.SessionID
 	}
+	if update.Moderation != "" {
+		entry.Moderation = update.Moderation
+	}
+	if update.TTFTMs > 0 {
+		entry.TTFTMs = update.TTFTMs
+	}
+	if update.TokensPerSecond > 0 {
+		entry.TokensPerSecond = update.TokensPerSecond
+	}
 }
 
 func (s *requestLogStore) finish(id string, status int, errorMessage string, completedAt time.Time) {
@@ -0,0 +1,149 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(tokenBudgetPlugin{ledger: defaultTokenBudgetLedger})
+}
+
+// tokenBudgetLedger tracks, per client API key, the tokens consumed in the current budget
+// window and the tokens reserved by requests still in flight.
+type tokenBudgetLedger struct {
+	mu          sync.Mutex
+	window      time.Duration
+	windowStart time.Time
+	used        map[string]int64
+	reserved    map[string]int64
+	// alerted holds the highest budget share, in percent, already alerted in the window.
+	alerted map[string]int
+}
+
+var defaultTokenBudgetLedger = newTokenBudgetLedger()
+
+func newTokenBudgetLedger() *tokenBudgetLedger {
+	return &tokenBudgetLedger{
+		used:     make(map[string]int64),
+		reserved: make(map[string]int64),
+		alerted:  make(map[string]int),
+	}
+}
+
+// ReserveTokens reserves tokens against the budget of key for the window containing now.
+// It fails when the tokens used and reserved in the window plus tokens would exceed limit.
+// The returned release function drops the reservation; calling it more than once is safe.
+func ReserveTokens(key string, tokens, limit int64, window time.Duration, now time.Time) (release func(), ok bool) {
+	return defaultTokenBudgetLedger.reserve(key, tokens, limit, window, now)
+}
+
+// TokenBudgetUsage reports the tokens used and reserved by key in the current window.
+func TokenBudgetUsage(key string) (used, reserved int64) {
+	return defaultTokenBudgetLedger.usage(key)
+}
+
+// TokenBudgetStanding reports the tokens used and reserved by key in the window of the given
+// length containing now, and when that window ends.
+func TokenBudgetStanding(key string, window time.Duration, now time.Time) (used, reserved int64, resetsAt time.Time) {
+	return defaultTokenBudgetLedger.standing(key, window, now)
+}
+
+// ClaimTokenBudgetAlert reports whether an alert for key reaching percent of its budget is
+// still due in the current window, and marks it as sent. Each share is alerted once per window.
+func ClaimTokenBudgetAlert(key string, percent int) bool {
+	return defaultTokenBudgetLedger.claimAlert(key, percent)
+}
+
+func (l *tokenBudgetLedger) reserve(key string, tokens, limit int64, window time.Duration, now time.Time) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window = window
+	l.rollLocked(now)
+	if l.used[key]+l.reserved[key]+tokens > limit {
+		return nil, false
+	}
+	l.reserved[key] += tokens
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.reserved[key] -= tokens; l.reserved[key] <= 0 {
+				delete(l.reserved, key)
+			}
+		})
+	}, true
+}
+
+func (l *tokenBudgetLedger) record(key string, tokens int64, at time.Time) {
+	if key == "" || tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.window <= 0 {
+		// No budget has been checked yet.
+		return
+	}
+	l.rollLocked(at)
+	if at.Before(l.windowStart) {
+		return
+	}
+	l.used[key] += tokens
+}
+
+func (l *tokenBudgetLedger) claimAlert(key string, percent int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.alerted[key] >= percent {
+		return false
+	}
+	l.alerted[key] = percent
+	return true
+}
+
+func (l *tokenBudgetLedger) usage(key string) (int64, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.used[key], l.reserved[key]
+}
+
+func (l *tokenBudgetLedger) standing(key string, window time.Duration, now time.Time) (int64, int64, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window = window
+	l.rollLocked(now)
+	return l.used[key], l.reserved[key], now.Truncate(window).Add(window)
+}
+
+// rollLocked starts a new window, forgetting consumed tokens, once now has left the current one.
+// Reservations carry over because their requests are still running.
+func (l *tokenBudgetLedger) rollLocked(now time.Time) {
+	if l.window <= 0 {
+		return
+	}
+	start := now.Truncate(l.window)
+	if start.After(l.windowStart) {
+		l.windowStart = start
+		l.used = make(map[string]int64)
+		l.alerted = make(map[string]int)
+	}
+}
+
+// tokenBudgetPlugin counts the actual token usage of each request against its client key.
+type tokenBudgetPlugin struct {
+	ledger *tokenBudgetLedger
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (p tokenBudgetPlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	at := record.RequestedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.ledger.record(record.APIKey, normaliseDetail(record.Detail).TotalTokens, at)
+}
@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestTokenBudgetLedgerReservesAndReconciles(t *testing.T) {
+	ledger := newTokenBudgetLedger()
+	plugin := tokenBudgetPlugin{ledger: ledger}
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	first, ok := ledger.reserve("k", 600, 1000, time.Hour, now)
+	if !ok {
+		t.Fatal("first reservation should fit")
+	}
+	if _, ok = ledger.reserve("k", 600, 1000, time.Hour, now); ok {
+		t.Fatal("concurrent reservation should exceed the budget")
+	}
+	if _, ok = ledger.reserve("other", 600, 1000, time.Hour, now); !ok {
+		t.Fatal("budgets are per key")
+	}
+
+	plugin.HandleUsage(context.Background(), coreusage.Record{APIKey: "k", RequestedAt: now, Detail: coreusage.Detail{InputTokens: 100, OutputTokens: 200}})
+	first()
+	first()
+	if used, reserved := ledger.usage("k"); used != 300 || reserved != 0 {
+		t.Fatalf("used = %d, reserved = %d, want 300/0", used, reserved)
+	}
+	if _, ok = ledger.reserve("k", 600, 1000, time.Hour, now); !ok {
+		t.Fatal("reservation should fit after reconciling actual usage")
+	}
+
+	if _, ok = ledger.reserve("k", 900, 1000, time.Hour, now.Add(time.Hour)); ok {
+		t.Fatal("in-flight reservations carry over into the next window")
+	}
+	if used, _ := ledger.usage("k"); used != 0 {
+		t.Fatalf("used = %d after window roll, want 0", used)
+	}
+}
+
+func TestTokenBudgetLedgerClaimsAlertsOncePerWindow(t *testing.T) {
+	ledger := newTokenBudgetLedger()
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	if _, ok := ledger.reserve("k", 10, 1000, time.Hour, now); !ok {
+		t.Fatal("reservation should fit")
+	}
+
+	if !ledger.claimAlert("k", 80) {
+		t.Fatal("first 80% alert should be due")
+	}
+	if ledger.claimAlert("k", 80) {
+		t.Fatal("80% alert should fire once per window")
+	}
+	if !ledger.claimAlert("k", 100) {
+		t.Fatal("exhaustion alert should still be due")
+	}
+
+	ledger.reserve("k", 10, 1000, time.Hour, now.Add(time.Hour))
+	if !ledger.claimAlert("k", 80) {
+		t.Fatal("alerts should reset with the window")
+	}
+}
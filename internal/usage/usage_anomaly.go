@@ -0,0 +1,173 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// MaxAnomalyTrailingHours bounds the hourly history kept per client key.
+const MaxAnomalyTrailingHours = 7 * 24
+
+func init() {
+	coreusage.RegisterPlugin(anomalyPlugin{ledger: defaultAnomalyLedger})
+}
+
+// UsageAnomaly describes a client key whose token usage in the current hour is well above its
+// trailing hourly average.
+type UsageAnomaly struct {
+	HourTokens      int64   `json:"hour-tokens"`
+	TrailingAverage float64 `json:"trailing-average"`
+	TrailingHours   int     `json:"trailing-hours"`
+}
+
+// anomalyKey is the hourly token history and throttle state of one client key.
+type anomalyKey struct {
+	firstHour   int64
+	hours       map[int64]int64
+	flaggedHour int64
+	// throttledUntil ends the stricter rate limit applied after an anomaly.
+	throttledUntil time.Time
+	// requests are the start times of requests in the last minute while throttled.
+	requests []time.Time
+}
+
+// anomalyLedger tracks hourly token usage per client API key.
+type anomalyLedger struct {
+	mu   sync.Mutex
+	keys map[string]*anomalyKey
+}
+
+var defaultAnomalyLedger = &anomalyLedger{keys: make(map[string]*anomalyKey)}
+
+// CheckUsageAnomaly reports whether key used more than multiplier times its average hourly
+// tokens over the previous trailingHours hours in the hour containing now, and at least
+// minTokens. Hours before the key was first seen do not count, so a key needs one full hour
+// of history. Each key is reported at most once per hour.
+func CheckUsageAnomaly(key string, multiplier float64, trailingHours int, minTokens int64, now time.Time) (UsageAnomaly, bool) {
+	return defaultAnomalyLedger.check(key, multiplier, trailingHours, minTokens, now)
+}
+
+// ThrottleClientKey applies the stricter anomaly rate limit to key until the given time.
+func ThrottleClientKey(key string, until time.Time) {
+	defaultAnomalyLedger.throttle(key, until)
+}
+
+// ThrottledUntil returns when the anomaly rate limit of key ends. The zero time means key is
+// not throttled.
+func ThrottledUntil(key string, now time.Time) time.Time {
+	defaultAnomalyLedger.mu.Lock()
+	defer defaultAnomalyLedger.mu.Unlock()
+	if entry := defaultAnomalyLedger.keys[key]; entry != nil && now.Before(entry.throttledUntil) {
+		return entry.throttledUntil
+	}
+	return time.Time{}
+}
+
+// AllowThrottledRequest counts a request of key against its anomaly rate limit of perMinute
+// requests. throttled is false when key is not throttled; otherwise ok reports whether the
+// request fits and retryAfter when the next one will.
+func AllowThrottledRequest(key string, perMinute int, now time.Time) (throttled, ok bool, retryAfter time.Duration) {
+	return defaultAnomalyLedger.allow(key, perMinute, now)
+}
+
+func (l *anomalyLedger) record(key string, tokens int64, at time.Time) {
+	if key == "" || tokens <= 0 {
+		return
+	}
+	hour := at.Unix() / 3600
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.keys[key]
+	if entry == nil {
+		entry = &anomalyKey{firstHour: hour, hours: make(map[int64]int64)}
+		l.keys[key] = entry
+	}
+	entry.hours[hour] += tokens
+	for h := range entry.hours {
+		if h <= hour-MaxAnomalyTrailingHours {
+			delete(entry.hours, h)
+		}
+	}
+}
+
+func (l *anomalyLedger) check(key string, multiplier float64, trailingHours int, minTokens int64, now time.Time) (UsageAnomaly, bool) {
+	hour := now.Unix() / 3600
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.keys[key]
+	if entry == nil || entry.flaggedHour == hour {
+		return UsageAnomaly{}, false
+	}
+	current := entry.hours[hour]
+	if current < minTokens {
+		return UsageAnomaly{}, false
+	}
+	n := int64(min(trailingHours, MaxAnomalyTrailingHours))
+	if history := hour - entry.firstHour; history < n {
+		n = history
+	}
+	if n < 1 {
+		return UsageAnomaly{}, false
+	}
+	var total int64
+	for h := hour - n; h < hour; h++ {
+		total += entry.hours[h]
+	}
+	average := float64(total) / float64(n)
+	if float64(current) <= multiplier*average {
+		return UsageAnomaly{}, false
+	}
+	entry.flaggedHour = hour
+	return UsageAnomaly{HourTokens: current, TrailingAverage: average, TrailingHours: int(n)}, true
+}
+
+func (l *anomalyLedger) throttle(key string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.keys[key]
+	if entry == nil {
+		entry = &anomalyKey{firstHour: until.Unix() / 3600, hours: make(map[int64]int64)}
+		l.keys[key] = entry
+	}
+	entry.throttledUntil = until
+	entry.requests = nil
+}
+
+func (l *anomalyLedger) allow(key string, perMinute int, now time.Time) (bool, bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.keys[key]
+	if entry == nil || !now.Before(entry.throttledUntil) {
+		return false, true, 0
+	}
+	cutoff := now.Add(-time.Minute)
+	kept := entry.requests[:0]
+	for _, at := range entry.requests {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	entry.requests = kept
+	if len(entry.requests) >= perMinute {
+		return true, false, entry.requests[0].Sub(cutoff)
+	}
+	entry.requests = append(entry.requests, now)
+	return true, true, 0
+}
+
+// anomalyPlugin counts the token usage of each request in the hourly history of its client key.
+type anomalyPlugin struct {
+	ledger *anomalyLedger
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (p anomalyPlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	at := record.RequestedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.ledger.record(record.APIKey, normaliseDetail(record.Detail).TotalTokens, at)
+}
@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnomalyLedgerFlagsSpikeOncePerHour(t *testing.T) {
+	l := &anomalyLedger{keys: make(map[string]*anomalyKey)}
+	start := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	for h := 0; h < 4; h++ {
+		l.record("k", 1000, start.Add(time.Duration(h)*time.Hour))
+	}
+	now := start.Add(4 * time.Hour)
+	l.record("k", 4000, now)
+	if _, flagged := l.check("k", 5, 24, 100, now); flagged {
+		t.Fatal("4x the average must not be flagged at multiplier 5")
+	}
+	l.record("k", 2000, now)
+	anomaly, flagged := l.check("k", 5, 24, 100, now)
+	if !flagged || anomaly.HourTokens != 6000 || anomaly.TrailingAverage != 1000 || anomaly.TrailingHours != 4 {
+		t.Fatalf("anomaly = %+v, flagged = %v", anomaly, flagged)
+	}
+	if _, flagged = l.check("k", 5, 24, 100, now.Add(time.Minute)); flagged {
+		t.Fatal("expected one flag per hour")
+	}
+	if _, flagged = l.check("k", 5, 24, 10000, now.Add(time.Hour)); flagged {
+		t.Fatal("expected usage below min-tokens to be ignored")
+	}
+
+	l.record("new", 50000, now)
+	if _, flagged = l.check("new", 5, 24, 100, now); flagged {
+		t.Fatal("a key without a full hour of history must not be flagged")
+	}
+}
+
+func TestAnomalyLedgerThrottle(t *testing.T) {
+	l := &anomalyLedger{keys: make(map[string]*anomalyKey)}
+	now := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	if throttled, ok, _ := l.allow("k", 2, now); throttled || !ok {
+		t.Fatal("unthrottled key must be allowed")
+	}
+	l.throttle("k", now.Add(10*time.Minute))
+	for i := 0; i < 2; i++ {
+		if throttled, ok, _ := l.allow("k", 2, now.Add(time.Duration(i)*time.Second)); !throttled || !ok {
+			t.Fatalf("request %d should fit the throttled limit", i)
+		}
+	}
+	throttled, ok, retryAfter := l.allow("k", 2, now.Add(10*time.Second))
+	if !throttled || ok || retryAfter != 50*time.Second {
+		t.Fatalf("throttled=%v ok=%v retryAfter=%v", throttled, ok, retryAfter)
+	}
+	if _, ok, _ = l.allow("k", 2, now.Add(time.Minute+time.Second)); !ok {
+		t.Fatal("expected a slot once the minute has passed")
+	}
+	if throttled, _, _ = l.allow("k", 2, now.Add(10*time.Minute)); throttled {
+		t.Fatal("expected the throttle to expire")
+	}
+}
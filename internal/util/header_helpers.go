@@ -2,16 +2,88 @@ package util
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // ApplyCustomHeadersFromAttrs applies user-defined headers stored in the provided attributes map.
 // Custom headers override built-in defaults when conflicts occur.
 func ApplyCustomHeadersFromAttrs(r *http.Request, attrs map[string]string) {
+	ApplyCustomHeadersWithVars(r, attrs, nil)
+}
+
+// ApplyCustomHeadersWithVars applies the custom headers in attrs like ApplyCustomHeadersFromAttrs,
+// expanding {{name}} placeholders in their values for each request. Built-in variables are:
+//   - uuid: a random UUID, shared by all headers of the request
+//   - timestamp, timestamp_ms: the Unix time in seconds or milliseconds
+//   - rfc3339: the UTC time in RFC 3339 format
+//   - random: 16 random hex characters
+//   - attr:<name>: the auth attribute <name>
+//
+// vars supplies further variables, such as account_id. Unknown placeholders are left as they
+// are; headers that expand to an empty value are skipped.
+func ApplyCustomHeadersWithVars(r *http.Request, attrs map[string]string, vars map[string]string) {
 	if r == nil {
 		return
 	}
-	applyCustomHeaders(r, extractCustomHeaders(attrs))
+	headers := extractCustomHeaders(attrs)
+	if len(headers) == 0 {
+		return
+	}
+	expand := headerTemplateExpander(attrs, vars)
+	for name, value := range headers {
+		headers[name] = strings.TrimSpace(expand(value))
+	}
+	applyCustomHeaders(r, headers)
+}
+
+// headerTemplateVar matches a {{name}} placeholder in a custom header value.
+var headerTemplateVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.:-]+)\s*\}\}`)
+
+// headerTemplateExpander returns a function expanding header placeholders. Values are
+// computed once per expander so every header of a request sees the same uuid and time.
+func headerTemplateExpander(attrs map[string]string, vars map[string]string) func(string) string {
+	now := time.Now()
+	var requestUUID string
+	lookup := func(name string) (string, bool) {
+		if attr, ok := strings.CutPrefix(name, "attr:"); ok {
+			value, found := attrs[attr]
+			return value, found
+		}
+		switch name {
+		case "uuid":
+			if requestUUID == "" {
+				requestUUID = uuid.NewString()
+			}
+			return requestUUID, true
+		case "timestamp":
+			return strconv.FormatInt(now.Unix(), 10), true
+		case "timestamp_ms":
+			return strconv.FormatInt(now.UnixMilli(), 10), true
+		case "rfc3339":
+			return now.UTC().Format(time.RFC3339), true
+		case "random":
+			return randomSessionID(), true
+		}
+		value, ok := vars[name]
+		return value, ok
+	}
+	return func(value string) string {
+		if !strings.Contains(value, "{{") {
+			return value
+		}
+		return headerTemplateVar.ReplaceAllStringFunc(value, func(match string) string {
+			name := headerTemplateVar.FindStringSubmatch(match)[1]
+			if expanded, ok := lookup(name); ok {
+				return expanded
+			}
+			return match
+		})
+	}
 }
 
 func extractCustomHeaders(attrs map[string]string) map[string]string {
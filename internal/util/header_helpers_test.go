@@ -0,0 +1,42 @@
+package util
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestApplyCustomHeadersWithVars_ExpandsTemplates(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	attrs := map[string]string{
+		"api_key":         "sk-test",
+		"header:X-Static": "plain",
+		"header:X-Trace":  "{{uuid}}",
+		"header:X-Echo":   "{{ uuid }}",
+		"header:X-Acct":   "acct-{{account_id}}/{{attr:api_key}}",
+		"header:X-Time":   "{{timestamp}}",
+		"header:X-Keep":   "{{unknown}}",
+		"header:X-Empty":  "{{email}}",
+	}
+	ApplyCustomHeadersWithVars(req, attrs, map[string]string{"account_id": "42", "email": ""})
+
+	if got := req.Header.Get("X-Static"); got != "plain" {
+		t.Fatalf("X-Static = %q", got)
+	}
+	trace := req.Header.Get("X-Trace")
+	if !regexp.MustCompile(`^[0-9a-f-]{36}$`).MatchString(trace) || req.Header.Get("X-Echo") != trace {
+		t.Fatalf("X-Trace = %q, X-Echo = %q", trace, req.Header.Get("X-Echo"))
+	}
+	if got := req.Header.Get("X-Acct"); got != "acct-42/sk-test" {
+		t.Fatalf("X-Acct = %q", got)
+	}
+	if got := req.Header.Get("X-Time"); !regexp.MustCompile(`^\d{10,}$`).MatchString(got) {
+		t.Fatalf("X-Time = %q", got)
+	}
+	if got := req.Header.Get("X-Keep"); got != "{{unknown}}" {
+		t.Fatalf("X-Keep = %q", got)
+	}
+	if _, ok := req.Header["X-Empty"]; ok {
+		t.Fatalf("expected header expanding to empty to be skipped")
+	}
+}
@@ -0,0 +1,243 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// maxSchemaViolations bounds the violations ValidateJSONSchema reports.
+const maxSchemaViolations = 10
+
+// ValidateJSONSchema checks value against a JSON schema and returns the violations found, each
+// prefixed with the JSON path of the offending value. It covers the keywords structured output
+// schemas use: type, enum, const, properties, required, additionalProperties, items,
+// minItems/maxItems, minLength/maxLength, minimum/maximum, anyOf, oneOf, allOf and local
+// $ref pointers. Unknown keywords are ignored.
+func ValidateJSONSchema(schema, value gjson.Result) []string {
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "$", 0)
+	return v.violations
+}
+
+type schemaValidator struct {
+	root       gjson.Result
+	violations []string
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	if len(v.violations) < maxSchemaViolations {
+		v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// resolve follows a local $ref such as "#/$defs/item".
+func (v *schemaValidator) resolve(schema gjson.Result) gjson.Result {
+	for i := 0; i < 16; i++ {
+		ref := schema.Get(gjson.Escape("$ref")).String()
+		if !strings.HasPrefix(ref, "#/") {
+			return schema
+		}
+		parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+		for j, part := range parts {
+			parts[j] = gjson.Escape(strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~"))
+		}
+		schema = v.root.Get(strings.Join(parts, "."))
+	}
+	return schema
+}
+
+func (v *schemaValidator) validate(schema, value gjson.Result, path string, depth int) {
+	if depth > 64 || !schema.IsObject() {
+		return
+	}
+	schema = v.resolve(schema)
+
+	if types := schema.Get("type"); types.Exists() {
+		matched := false
+		for _, t := range typeList(types) {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "expected %s, got %s", strings.Join(typeList(types), " or "), jsonTypeName(value))
+			return
+		}
+	}
+	if enum := schema.Get("enum"); enum.IsArray() {
+		found := false
+		for _, option := range enum.Array() {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "value %s is not one of %s", value.Raw, enum.Raw)
+		}
+	}
+	if constant := schema.Get("const"); constant.Exists() && !jsonEqual(constant, value) {
+		v.fail(path, "value %s is not %s", value.Raw, constant.Raw)
+	}
+
+	switch {
+	case value.IsObject():
+		v.validateObject(schema, value, path, depth)
+	case value.IsArray():
+		items := value.Array()
+		if limit := schema.Get("minItems"); limit.Exists() && int64(len(items)) < limit.Int() {
+			v.fail(path, "expected at least %d items, got %d", limit.Int(), len(items))
+		}
+		if limit := schema.Get("maxItems"); limit.Exists() && int64(len(items)) > limit.Int() {
+			v.fail(path, "expected at most %d items, got %d", limit.Int(), len(items))
+		}
+		if itemSchema := schema.Get("items"); itemSchema.IsObject() {
+			for i, item := range items {
+				v.validate(itemSchema, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			}
+		}
+	case value.Type == gjson.String:
+		length := int64(utf8.RuneCountInString(value.String()))
+		if limit := schema.Get("minLength"); limit.Exists() && length < limit.Int() {
+			v.fail(path, "expected at least %d characters", limit.Int())
+		}
+		if limit := schema.Get("maxLength"); limit.Exists() && length > limit.Int() {
+			v.fail(path, "expected at most %d characters", limit.Int())
+		}
+	case value.Type == gjson.Number:
+		if limit := schema.Get("minimum"); limit.Exists() && value.Float() < limit.Float() {
+			v.fail(path, "%s is below the minimum %s", value.Raw, limit.Raw)
+		}
+		if limit := schema.Get("maximum"); limit.Exists() && value.Float() > limit.Float() {
+			v.fail(path, "%s is above the maximum %s", value.Raw, limit.Raw)
+		}
+	}
+
+	for _, sub := range schema.Get("allOf").Array() {
+		v.validate(sub, value, path, depth+1)
+	}
+	if anyOf := schema.Get("anyOf"); anyOf.IsArray() && v.countMatches(anyOf, value, depth) == 0 {
+		v.fail(path, "value matches none of the anyOf schemas")
+	}
+	if oneOf := schema.Get("oneOf"); oneOf.IsArray() {
+		if matches := v.countMatches(oneOf, value, depth); matches != 1 {
+			v.fail(path, "value matches %d of the oneOf schemas, expected exactly one", matches)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(schema, value gjson.Result, path string, depth int) {
+	properties := schema.Get("properties")
+	for _, name := range schema.Get("required").Array() {
+		if !value.Get(gjson.Escape(name.String())).Exists() {
+			v.fail(path, "missing required property %q", name.String())
+		}
+	}
+	additional := schema.Get("additionalProperties")
+	value.ForEach(func(key, item gjson.Result) bool {
+		propertyPath := path + "." + key.String()
+		if propertySchema := properties.Get(gjson.Escape(key.String())); propertySchema.Exists() {
+			v.validate(propertySchema, item, propertyPath, depth+1)
+			return true
+		}
+		switch {
+		case additional.Type == gjson.False:
+			v.fail(path, "unexpected property %q", key.String())
+		case additional.IsObject():
+			v.validate(additional, item, propertyPath, depth+1)
+		}
+		return true
+	})
+}
+
+// countMatches counts the schemas of alternatives that value satisfies.
+func (v *schemaValidator) countMatches(alternatives, value gjson.Result, depth int) int {
+	matches := 0
+	for _, sub := range alternatives.Array() {
+		probe := &schemaValidator{root: v.root}
+		probe.validate(sub, value, "$", depth+1)
+		if len(probe.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func typeList(types gjson.Result) []string {
+	if !types.IsArray() {
+		return []string{types.String()}
+	}
+	var out []string
+	for _, t := range types.Array() {
+		out = append(out, t.String())
+	}
+	return out
+}
+
+func jsonTypeMatches(name string, value gjson.Result) bool {
+	switch name {
+	case "integer":
+		return value.Type == gjson.Number && value.Float() == math.Trunc(value.Float())
+	case "number":
+		return value.Type == gjson.Number
+	}
+	return name == jsonTypeName(value)
+}
+
+func jsonTypeName(value gjson.Result) string {
+	switch {
+	case value.IsObject():
+		return "object"
+	case value.IsArray():
+		return "array"
+	}
+	switch value.Type {
+	case gjson.String:
+		return "string"
+	case gjson.Number:
+		return "number"
+	case gjson.True, gjson.False:
+		return "boolean"
+	}
+	return "null"
+}
+
+// jsonEqual compares two JSON values, ignoring formatting and object key order.
+func jsonEqual(a, b gjson.Result) bool {
+	if jsonTypeName(a) != jsonTypeName(b) {
+		return false
+	}
+	switch {
+	case a.IsObject():
+		am, bm := a.Map(), b.Map()
+		if len(am) != len(bm) {
+			return false
+		}
+		for key, item := range am {
+			other, ok := bm[key]
+			if !ok || !jsonEqual(item, other) {
+				return false
+			}
+		}
+		return true
+	case a.IsArray():
+		aa, ba := a.Array(), b.Array()
+		if len(aa) != len(ba) {
+			return false
+		}
+		for i := range aa {
+			if !jsonEqual(aa[i], ba[i]) {
+				return false
+			}
+		}
+		return true
+	case a.Type == gjson.Number:
+		return a.Float() == b.Float()
+	}
+	return a.String() == b.String()
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := gjson.Parse(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+			"kind": {"enum": ["a", "b"]},
+			"contact": {"anyOf": [{"type": "string"}, {"type": "null"}]}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"$defs": {"tag": {"type": "string"}}
+	}`)
+
+	if problems := ValidateJSONSchema(schema, gjson.Parse(`{"name":"Ada","age":36,"tags":["x"],"kind":"a","contact":null}`)); len(problems) != 0 {
+		t.Fatalf("valid value reported problems: %v", problems)
+	}
+
+	problems := ValidateJSONSchema(schema, gjson.Parse(`{"name":"","age":1.5,"tags":["x",2,"z"],"kind":"c","contact":3,"extra":true}`))
+	want := []string{
+		"$.name: expected at least 1 characters",
+		"$.age: expected integer, got number",
+		"$.tags: expected at most 2 items, got 3",
+		"$.tags[1]: expected string, got number",
+		`$.kind: value "c" is not one of ["a", "b"]`,
+		"$.contact: value matches none of the anyOf schemas",
+		`$: unexpected property "extra"`,
+	}
+	joined := strings.Join(problems, "\n")
+	for _, w := range want {
+		if !strings.Contains(joined, w) {
+			t.Errorf("missing %q in:\n%s", w, joined)
+		}
+	}
+
+	if problems := ValidateJSONSchema(schema, gjson.Parse(`{"name":"Ada"}`)); len(problems) != 1 || !strings.Contains(problems[0], `missing required property "age"`) {
+		t.Fatalf("problems = %v, want missing age", problems)
+	}
+}
@@ -0,0 +1,172 @@
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// logModulePaths maps source path fragments to the module their log entries belong to.
+var logModulePaths = []struct {
+	fragment string
+	module   string
+}{
+	{"/internal/runtime/executor/", "executor"},
+	{"/internal/translator/", "translator"},
+	{"/sdk/translator/", "translator"},
+	{"/internal/access/", "access"},
+	{"/sdk/access/", "access"},
+	{"/internal/logging/gin_logger.go", "access"},
+	{"/internal/api/handlers/management/", "management"},
+	{"/internal/managementasset/", "management"},
+}
+
+// LogLevelStatus describes the configured and runtime log levels.
+type LogLevelStatus struct {
+	// Level is the effective level of entries outside the modules.
+	Level string `json:"level"`
+	// Modules are the effective levels of the modules.
+	Modules map[string]string `json:"modules"`
+	// Overrides are the levels set at runtime, keyed by module; "*" is the global override.
+	// They last until cleared or the process exits, surviving config reloads.
+	Overrides map[string]string `json:"overrides,omitempty"`
+}
+
+// logLevels holds the levels from the config and those set at runtime.
+var logLevels = struct {
+	sync.RWMutex
+	base      log.Level
+	modules   map[string]log.Level
+	overrides map[string]log.Level
+	// fileModules caches the module of each source file seen.
+	fileModules sync.Map
+}{base: log.InfoLevel, overrides: make(map[string]log.Level)}
+
+// moduleLevelLocked returns the effective level of module. Runtime overrides beat the config,
+// and module levels beat the global one.
+func moduleLevelLocked(module string) log.Level {
+	if level, ok := logLevels.overrides[module]; ok && module != "" {
+		return level
+	}
+	if level, ok := logLevels.overrides["*"]; ok {
+		return level
+	}
+	if level, ok := logLevels.modules[module]; ok {
+		return level
+	}
+	return logLevels.base
+}
+
+// updateLogLevels runs change under the level lock, then sets the logrus level to the most
+// verbose effective level, so entries of verbose modules are created; LogEntryEnabled filters
+// the rest. The change is logged after the lock is released, since logging takes it.
+func updateLogLevels(change func()) {
+	logLevels.Lock()
+	change()
+	level := moduleLevelLocked("")
+	for _, module := range config.LogModules {
+		level = max(level, moduleLevelLocked(module))
+	}
+	current := log.GetLevel()
+	log.SetLevel(level)
+	logLevels.Unlock()
+	if current != level {
+		log.Infof("log level changed from %s to %s", current, level)
+	}
+}
+
+// SetLogLevelOverride sets the level of module, or of everything when module is empty, until
+// ClearLogLevelOverride is called.
+func SetLogLevelOverride(module string, level log.Level) {
+	if module == "" {
+		module = "*"
+	}
+	updateLogLevels(func() { logLevels.overrides[module] = level })
+}
+
+// ClearLogLevelOverride removes the runtime level of module, or all runtime levels when module
+// is empty, returning to the configured levels.
+func ClearLogLevelOverride(module string) {
+	updateLogLevels(func() {
+		if module == "" {
+			clear(logLevels.overrides)
+		} else {
+			delete(logLevels.overrides, module)
+		}
+	})
+}
+
+// ToggleDebugLogging switches the global runtime override between debug and none, reporting
+// whether debug logging is now forced on.
+func ToggleDebugLogging() bool {
+	var on bool
+	updateLogLevels(func() {
+		if _, on = logLevels.overrides["*"]; on {
+			delete(logLevels.overrides, "*")
+		} else {
+			logLevels.overrides["*"] = log.DebugLevel
+		}
+	})
+	return !on
+}
+
+// CurrentLogLevels reports the effective and runtime log levels.
+func CurrentLogLevels() LogLevelStatus {
+	logLevels.RLock()
+	defer logLevels.RUnlock()
+	status := LogLevelStatus{Level: moduleLevelLocked("").String(), Modules: make(map[string]string, len(config.LogModules))}
+	for _, module := range config.LogModules {
+		status.Modules[module] = moduleLevelLocked(module).String()
+	}
+	if len(logLevels.overrides) > 0 {
+		status.Overrides = make(map[string]string, len(logLevels.overrides))
+		for module, level := range logLevels.overrides {
+			status.Overrides[module] = level.String()
+		}
+	}
+	return status
+}
+
+// LogEntryEnabled reports whether entry passes the level of the module that logged it. The
+// module is found from the caller, so entries without one follow the global level.
+func LogEntryEnabled(entry *log.Entry) bool {
+	module := ""
+	if entry.Caller != nil {
+		module = logModuleOf(entry.Caller.File)
+	}
+	logLevels.RLock()
+	defer logLevels.RUnlock()
+	return entry.Level <= moduleLevelLocked(module)
+}
+
+func logModuleOf(file string) string {
+	if module, ok := logLevels.fileModules.Load(file); ok {
+		return module.(string)
+	}
+	module := ""
+	slashed := strings.ReplaceAll(file, "\\", "/")
+	for _, path := range logModulePaths {
+		if strings.Contains(slashed, path.fragment) {
+			module = path.module
+			break
+		}
+	}
+	logLevels.fileModules.Store(file, module)
+	return module
+}
+
+// setConfiguredLogLevels replaces the levels taken from the config.
+func setConfiguredLogLevels(base log.Level, modules map[string]string) {
+	parsed := make(map[string]log.Level, len(modules))
+	for module, name := range modules {
+		if level, err := log.ParseLevel(name); err == nil {
+			parsed[module] = level
+		}
+	}
+	updateLogLevels(func() {
+		logLevels.base = base
+		logLevels.modules = parsed
+	})
+}
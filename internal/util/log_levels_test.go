@@ -0,0 +1,57 @@
+package util
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLogLevels(t *testing.T) {
+	defer func() {
+		ClearLogLevelOverride("")
+		SetLogLevel(&config.Config{})
+	}()
+	entry := func(level log.Level, file string) *log.Entry {
+		e := log.NewEntry(log.StandardLogger())
+		e.Level = level
+		e.Caller = &runtime.Frame{File: file}
+		return e
+	}
+	executorFile := "/src/internal/runtime/executor/codex_executor.go"
+	otherFile := "/src/internal/api/server.go"
+
+	SetLogLevel(&config.Config{LogLevels: map[string]string{"executor": "debug", "access": "warn"}})
+	if log.GetLevel() != log.DebugLevel {
+		t.Fatalf("logrus level = %s, want debug so executor entries are created", log.GetLevel())
+	}
+	if !LogEntryEnabled(entry(log.DebugLevel, executorFile)) {
+		t.Fatal("executor debug entry filtered")
+	}
+	if LogEntryEnabled(entry(log.DebugLevel, otherFile)) {
+		t.Fatal("debug entry outside the executor passed")
+	}
+	if LogEntryEnabled(entry(log.InfoLevel, "/src/internal/logging/gin_logger.go")) {
+		t.Fatal("access info entry passed a warn level")
+	}
+
+	SetLogLevelOverride("executor", log.ErrorLevel)
+	if LogEntryEnabled(entry(log.WarnLevel, executorFile)) {
+		t.Fatal("runtime module level did not beat the config")
+	}
+	if !ToggleDebugLogging() || !LogEntryEnabled(entry(log.DebugLevel, otherFile)) {
+		t.Fatal("SIGUSR1 toggle did not force debug logging")
+	}
+	if status := CurrentLogLevels(); status.Level != "debug" || status.Overrides["*"] != "debug" || status.Modules["executor"] != "error" {
+		t.Fatalf("status = %+v", status)
+	}
+	if ToggleDebugLogging() || LogEntryEnabled(entry(log.DebugLevel, otherFile)) {
+		t.Fatal("second toggle did not restore the configured levels")
+	}
+
+	ClearLogLevelOverride("")
+	if status := CurrentLogLevels(); status.Modules["executor"] != "debug" || len(status.Overrides) != 0 {
+		t.Fatalf("status after clear = %+v", status)
+	}
+}
@@ -4,52 +4,313 @@
 package util
 
 import (
-	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/proxy"
 )
 
 // SetProxy configures the provided HTTP client with proxy settings from the configuration.
 // It supports SOCKS5, HTTP, and HTTPS proxies. The function modifies the client's transport
 // to route requests through the configured proxy server.
 func SetProxy(cfg *config.SDKConfig, httpClient *http.Client) *http.Client {
-	var transport *http.Transport
-	// Attempt to parse the proxy URL from the configuration.
-	proxyURL, errParse := url.Parse(cfg.ProxyURL)
-	if errParse == nil {
-		// Handle different proxy schemes.
-		if proxyURL.Scheme == "socks5" {
-			// Configure SOCKS5 proxy with optional authentication.
-			var proxyAuth *proxy.Auth
-			if proxyURL.User != nil {
-				username := proxyURL.User.Username()
-				password, _ := proxyURL.User.Password()
-				proxyAuth = &proxy.Auth{User: username, Password: password}
-			}
-			dialer, errSOCKS5 := proxy.SOCKS5("tcp", proxyURL.Host, proxyAuth, proxy.Direct)
-			if errSOCKS5 != nil {
-				log.Errorf("create SOCKS5 dialer failed: %v", errSOCKS5)
-				return httpClient
-			}
-			// Set up a custom transport using the SOCKS5 dialer.
-			transport = &http.Transport{
-				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return dialer.Dial(network, addr)
-				},
-			}
-		} else if proxyURL.Scheme == "http" || proxyURL.Scheme == "https" {
-			// Configure HTTP or HTTPS proxy.
-			transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		}
-	}
-	// If a new transport was created, apply it to the HTTP client.
-	if transport != nil {
-		httpClient.Transport = transport
+	if strings.TrimSpace(cfg.ProxyURL) == "" && len(cfg.ProxyHostOverrides) == 0 {
+		return httpClient
 	}
+	transport, err := NewProxyTransport(cfg, cfg.ProxyURL)
+	if err != nil {
+		log.Errorf("configure proxy failed: %v", err)
+		return httpClient
+	}
+	httpClient.Transport = transport
 	return httpClient
 }
+
+// proxyRandPlaceholder in a proxy username is replaced with a random session ID on every
+// request, for residential proxies that pick the exit IP from the username.
+const proxyRandPlaceholder = "{rand}"
+
+// ParseProxyURL parses a proxy URL and checks its scheme. Supported schemes are http, https,
+// socks5 and socks5h. Host names are always resolved by SOCKS proxies, so socks5 and socks5h
+// behave the same. A {rand} placeholder may appear in the username.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	// Braces are not valid in URL user info; escape the placeholder so it survives parsing.
+	raw = strings.ReplaceAll(strings.TrimSpace(raw), proxyRandPlaceholder, url.PathEscape(proxyRandPlaceholder))
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q: use http, https, socks5 or socks5h", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy url is missing a host")
+	}
+	return proxyURL, nil
+}
+
+// NewProxyTransport returns a transport that sends requests through proxyURL, honouring the
+// proxy-bypass, proxy-host-overrides, proxy credential and tls-pins settings of cfg. An empty
+// proxyURL connects directly unless an override matches. cfg may be nil.
+func NewProxyTransport(cfg *config.SDKConfig, proxyURL string) (*http.Transport, error) {
+	proxyFunc, err := ProxyFunc(cfg, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{Proxy: proxyFunc}
+	ApplyTLSPins(cfg, transport)
+	return transport, nil
+}
+
+// ProxyFunc returns an http.Transport Proxy function choosing, per request host, between the
+// first matching proxy-host-overrides entry, a direct connection for proxy-bypass hosts, and
+// proxyURL. Overrides with invalid proxy URLs are skipped.
+//
+// When proxyURL is the configured proxy-url, credentials from proxy-credentials-file or
+// proxy-credentials-env replace its user info; the file is re-read whenever it changes.
+func ProxyFunc(cfg *config.SDKConfig, proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	var defaultProxy *url.URL
+	if strings.TrimSpace(proxyURL) != "" {
+		parsed, err := ParseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		defaultProxy = parsed
+	}
+
+	type override struct {
+		host  hostPattern
+		proxy *url.URL
+	}
+	var overrides []override
+	var bypass []hostPattern
+	var credentials func() (*url.Userinfo, error)
+	if cfg != nil {
+		for _, entry := range cfg.ProxyHostOverrides {
+			pattern, ok := parseHostPattern(entry.Host)
+			if !ok {
+				continue
+			}
+			var target *url.URL
+			if !strings.EqualFold(strings.TrimSpace(entry.ProxyURL), config.ProxyDirect) {
+				parsed, err := ParseProxyURL(entry.ProxyURL)
+				if err != nil {
+					log.Debugf("skipping proxy override for %s: %v", entry.Host, err)
+					continue
+				}
+				target = parsed
+			}
+			overrides = append(overrides, override{host: pattern, proxy: target})
+		}
+		for _, entry := range cfg.ProxyBypass {
+			if pattern, ok := parseHostPattern(entry); ok {
+				bypass = append(bypass, pattern)
+			}
+		}
+		if defaultProxy != nil && strings.TrimSpace(proxyURL) == strings.TrimSpace(cfg.ProxyURL) {
+			credentials = proxyCredentialsSource(cfg)
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		host, port := req.URL.Hostname(), req.URL.Port()
+		if port == "" {
+			port = "80"
+			if req.URL.Scheme == "https" {
+				port = "443"
+			}
+		}
+		for _, o := range overrides {
+			if o.host.match(host, port) {
+				return expandProxyUser(o.proxy, nil), nil
+			}
+		}
+		for _, p := range bypass {
+			if p.match(host, port) {
+				return nil, nil
+			}
+		}
+		if defaultProxy == nil {
+			return nil, nil
+		}
+		var user *url.Userinfo
+		if credentials != nil {
+			var err error
+			if user, err = credentials(); err != nil {
+				return nil, err
+			}
+		}
+		return expandProxyUser(defaultProxy, user), nil
+	}, nil
+}
+
+// expandProxyUser returns proxyURL with user (when set) as its user info and any {rand}
+// placeholder in the username replaced by a fresh session ID.
+func expandProxyUser(proxyURL *url.URL, user *url.Userinfo) *url.URL {
+	if proxyURL == nil {
+		return nil
+	}
+	if user == nil {
+		user = proxyURL.User
+	}
+	if user == nil || (user == proxyURL.User && !strings.Contains(user.Username(), proxyRandPlaceholder)) {
+		return proxyURL
+	}
+	out := *proxyURL
+	username := user.Username()
+	for strings.Contains(username, proxyRandPlaceholder) {
+		username = strings.Replace(username, proxyRandPlaceholder, randomSessionID(), 1)
+	}
+	if password, ok := user.Password(); ok {
+		out.User = url.UserPassword(username, password)
+	} else {
+		out.User = url.User(username)
+	}
+	return &out
+}
+
+func randomSessionID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// proxyCredentialsSource returns a loader for the proxy-url credentials configured in cfg,
+// or nil when none are configured.
+func proxyCredentialsSource(cfg *config.SDKConfig) func() (*url.Userinfo, error) {
+	if path := strings.TrimSpace(cfg.ProxyCredentialsFile); path != "" {
+		return func() (*url.Userinfo, error) { return proxyCredentialFiles.load(path) }
+	}
+	if name := strings.TrimSpace(cfg.ProxyCredentialsEnv); name != "" {
+		return func() (*url.Userinfo, error) {
+			value := strings.TrimSpace(os.Getenv(name))
+			if value == "" {
+				return nil, fmt.Errorf("proxy credentials env %s is empty", name)
+			}
+			return parseProxyCredentials(value), nil
+		}
+	}
+	return nil
+}
+
+// parseProxyCredentials parses "username:password" or a bare username.
+func parseProxyCredentials(value string) *url.Userinfo {
+	if username, password, ok := strings.Cut(value, ":"); ok {
+		return url.UserPassword(username, password)
+	}
+	return url.User(value)
+}
+
+// proxyCredentialFiles caches proxy credential files by path, re-reading a file when its
+// modification time or size changes.
+var proxyCredentialFiles = &credentialFileCache{entries: make(map[string]credentialFileEntry)}
+
+type credentialFileEntry struct {
+	modTime time.Time
+	size    int64
+	user    *url.Userinfo
+}
+
+type credentialFileCache struct {
+	mu      sync.Mutex
+	entries map[string]credentialFileEntry
+}
+
+func (c *credentialFileCache) load(path string) (*url.Userinfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read proxy credentials: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[path]; ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.user, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read proxy credentials: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return nil, fmt.Errorf("proxy credentials file %s is empty", path)
+	}
+	user := parseProxyCredentials(value)
+	c.entries[path] = credentialFileEntry{modTime: info.ModTime(), size: info.Size(), user: user}
+	return user, nil
+}
+
+// hostPattern is one NO_PROXY-style entry.
+type hostPattern struct {
+	any        bool
+	domain     string
+	subdomains bool // match subdomains only
+	network    *net.IPNet
+	ip         net.IP
+	port       string
+}
+
+func parseHostPattern(raw string) (hostPattern, bool) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return hostPattern{}, false
+	}
+	if raw == "*" {
+		return hostPattern{any: true}, true
+	}
+	if _, network, err := net.ParseCIDR(raw); err == nil {
+		return hostPattern{network: network}, true
+	}
+	var p hostPattern
+	if host, port, err := net.SplitHostPort(raw); err == nil {
+		raw, p.port = host, port
+	}
+	raw = strings.Trim(raw, "[]")
+	if ip := net.ParseIP(raw); ip != nil {
+		p.ip = ip
+		return p, true
+	}
+	if strings.HasPrefix(raw, "*.") {
+		raw = raw[1:]
+	}
+	if strings.HasPrefix(raw, ".") {
+		p.subdomains = true
+		raw = raw[1:]
+	}
+	if raw == "" {
+		return hostPattern{}, false
+	}
+	p.domain = raw
+	return p, true
+}
+
+func (p hostPattern) match(host, port string) bool {
+	if p.any {
+		return true
+	}
+	if p.port != "" && p.port != port {
+		return false
+	}
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	switch {
+	case p.network != nil:
+		return ip != nil && p.network.Contains(ip)
+	case p.ip != nil:
+		return ip != nil && p.ip.Equal(ip)
+	case p.subdomains:
+		return strings.HasSuffix(host, "."+p.domain)
+	default:
+		return host == p.domain || strings.HasSuffix(host, "."+p.domain)
+	}
+}
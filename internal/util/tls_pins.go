@@ -0,0 +1,111 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// TLSPinError reports a TLS connection to a pinned host whose certificates match none of its
+// pins, which usually means a proxy on the path is intercepting TLS.
+type TLSPinError struct {
+	Host string
+	// Presented are the SPKI pins of the certificates the host presented, leaf first.
+	Presented []string
+}
+
+func (e *TLSPinError) Error() string {
+	return fmt.Sprintf("tls pin mismatch for %s: presented keys %s match no configured pin; the connection may be intercepted by a proxy", e.Host, strings.Join(e.Presented, ", "))
+}
+
+type tlsPinSet struct {
+	host hostPattern
+	spki map[string]bool
+	cert map[string]bool
+}
+
+// ApplyTLSPins makes transport verify the tls-pins of cfg on every TLS handshake, including
+// handshakes with HTTPS proxies. It does nothing when cfg has no pins.
+func ApplyTLSPins(cfg *config.SDKConfig, transport *http.Transport) {
+	if transport == nil {
+		return
+	}
+	verify := TLSPinVerifier(cfg)
+	if verify == nil {
+		return
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	next := transport.TLSClientConfig.VerifyConnection
+	transport.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if next != nil {
+			if err := next(state); err != nil {
+				return err
+			}
+		}
+		return verify(state.ServerName, state.PeerCertificates)
+	}
+}
+
+// TLSPinVerifier returns a check of the certificates a server presented against the tls-pins
+// of cfg, for TLS stacks other than crypto/tls. It returns nil when cfg has no pins.
+func TLSPinVerifier(cfg *config.SDKConfig) func(serverName string, certs []*x509.Certificate) error {
+	if cfg == nil || len(cfg.TLSPins) == 0 {
+		return nil
+	}
+	sets := make([]tlsPinSet, 0, len(cfg.TLSPins))
+	for _, pin := range cfg.TLSPins {
+		pattern, ok := parseHostPattern(pin.Host)
+		if !ok {
+			continue
+		}
+		pattern.port = ""
+		set := tlsPinSet{host: pattern, spki: make(map[string]bool), cert: make(map[string]bool)}
+		for _, value := range pin.SPKISHA256 {
+			set.spki[strings.TrimPrefix(strings.TrimSpace(value), "sha256/")] = true
+		}
+		for _, value := range pin.CertSHA256 {
+			set.cert[strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), ":", ""))] = true
+		}
+		sets = append(sets, set)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	return func(serverName string, certs []*x509.Certificate) error {
+		return verifyTLSPins(sets, serverName, certs)
+	}
+}
+
+// verifyTLSPins checks certs against the first pin set matching the server name.
+func verifyTLSPins(sets []tlsPinSet, serverName string, certs []*x509.Certificate) error {
+	host := strings.ToLower(serverName)
+	for _, set := range sets {
+		if !set.host.match(host, "") {
+			continue
+		}
+		presented := make([]string, 0, len(certs))
+		for _, cert := range certs {
+			spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			spkiPin := base64.StdEncoding.EncodeToString(spki[:])
+			fingerprint := sha256.Sum256(cert.Raw)
+			if set.spki[spkiPin] || set.cert[hex.EncodeToString(fingerprint[:])] {
+				return nil
+			}
+			presented = append(presented, "sha256/"+spkiPin)
+		}
+		err := &TLSPinError{Host: host, Presented: presented}
+		log.Warn(err.Error())
+		return err
+	}
+	return nil
+}
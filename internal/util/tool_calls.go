@@ -0,0 +1,87 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// SanitizeToolCallID makes a tool call ID acceptable to Claude, which only allows letters,
+// digits, underscores and dashes. IDs minted by other providers are mapped the same way for
+// the tool call and its result, so the two stay linked when a conversation moves to Claude.
+func SanitizeToolCallID(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+}
+
+// ClaudeToolUseNames maps the ID of every tool_use block in Claude messages to its tool name,
+// so tool results can name the function they answer even when the ID does not encode it.
+func ClaudeToolUseNames(messages gjson.Result) map[string]string {
+	names := make(map[string]string)
+	for _, message := range messages.Array() {
+		for _, block := range message.Get("content").Array() {
+			if block.Get("type").String() != "tool_use" {
+				continue
+			}
+			if id := block.Get("id").String(); id != "" {
+				names[id] = block.Get("name").String()
+			}
+		}
+	}
+	return names
+}
+
+// AppendClaudeMessageBlock adds a content block with role to the messages of a Claude request.
+// Claude expects every tool_use of a turn to be answered by tool_result blocks in the next
+// message, so blocks extend the last message when it has the same role: tool calls join the
+// assistant turn before them and tool results join a user message holding only tool results.
+// Other blocks start a new message.
+func AppendClaudeMessageBlock(out, role, block string) string {
+	messages := gjson.Get(out, "messages").Array()
+	if n := len(messages); n > 0 && messages[n-1].Get("role").String() == role && canExtendClaudeMessage(messages[n-1], role, block) {
+		path := fmt.Sprintf("messages.%d.content", n-1)
+		if content := messages[n-1].Get("content"); content.Type == gjson.String {
+			out, _ = sjson.SetRaw(out, path, "[]")
+			if text := content.String(); text != "" {
+				out, _ = sjson.Set(out, path+".-1", map[string]string{"type": "text", "text": text})
+			}
+		}
+		out, _ = sjson.SetRaw(out, path+".-1", block)
+		return out
+	}
+	msg := `{"role":"","content":[]}`
+	msg, _ = sjson.Set(msg, "role", role)
+	msg, _ = sjson.SetRaw(msg, "content.-1", block)
+	out, _ = sjson.SetRaw(out, "messages.-1", msg)
+	return out
+}
+
+func canExtendClaudeMessage(message gjson.Result, role, block string) bool {
+	switch role {
+	case "assistant":
+		return gjson.Get(block, "type").String() == "tool_use"
+	case "user":
+		if gjson.Get(block, "type").String() != "tool_result" {
+			return false
+		}
+		content := message.Get("content")
+		if !content.IsArray() {
+			return false
+		}
+		for _, existing := range content.Array() {
+			if existing.Get("type").String() != "tool_result" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestSanitizeToolCallID(t *testing.T) {
+	tests := map[string]string{
+		"toolu_01ABCdef":        "toolu_01ABCdef",
+		"call_abc-123":          "call_abc-123",
+		"default_api:read.file": "default_api_read_file",
+		"fc 1/2":                "fc_1_2",
+	}
+	for input, want := range tests {
+		if got := SanitizeToolCallID(input); got != want {
+			t.Errorf("SanitizeToolCallID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestClaudeToolUseNames(t *testing.T) {
+	messages := gjson.Parse(`[
+		{"role":"assistant","content":[{"type":"text","text":"checking"},{"type":"tool_use","id":"call_1","name":"read_file","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":"ok"}]},
+		{"role":"user","content":"thanks"}
+	]`)
+	names := ClaudeToolUseNames(messages)
+	if len(names) != 1 || names["call_1"] != "read_file" {
+		t.Fatalf("names = %v, want call_1 -> read_file", names)
+	}
+}
+
+func TestAppendClaudeMessageBlockGroupsToolTurns(t *testing.T) {
+	out := `{"messages":[{"role":"assistant","content":"Let me look."}]}`
+	out = AppendClaudeMessageBlock(out, "assistant", `{"type":"tool_use","id":"a","name":"f","input":{}}`)
+	out = AppendClaudeMessageBlock(out, "assistant", `{"type":"tool_use","id":"b","name":"g","input":{}}`)
+	out = AppendClaudeMessageBlock(out, "user", `{"type":"tool_result","tool_use_id":"a","content":"1"}`)
+	out = AppendClaudeMessageBlock(out, "user", `{"type":"tool_result","tool_use_id":"b","content":"2"}`)
+
+	messages := gjson.Get(out, "messages").Array()
+	if len(messages) != 2 {
+		t.Fatalf("messages = %s, want one assistant and one user turn", gjson.Get(out, "messages").Raw)
+	}
+	if got := messages[0].Get("content.#(type==\"text\").text").String(); got != "Let me look." {
+		t.Fatalf("assistant text = %q, want it kept", got)
+	}
+	if got := messages[0].Get("content.#(type==\"tool_use\")#.id").Raw; got != `["a","b"]` {
+		t.Fatalf("tool_use ids = %s, want [\"a\",\"b\"]", got)
+	}
+	if got := messages[1].Get("content.#.tool_use_id").Raw; got != `["a","b"]` {
+		t.Fatalf("tool_result ids = %s, want [\"a\",\"b\"]", got)
+	}
+}
+
+func TestAppendClaudeMessageBlockKeepsToolResultsFirst(t *testing.T) {
+	out := `{"messages":[{"role":"user","content":"hello"}]}`
+	out = AppendClaudeMessageBlock(out, "user", `{"type":"tool_result","tool_use_id":"a","content":"1"}`)
+	if got := gjson.Get(out, "messages.#").Int(); got != 2 {
+		t.Fatalf("messages = %d, want the tool result in its own message", got)
+	}
+}
@@ -55,20 +55,14 @@ func SanitizeFunctionName(name string) string {
 }
 
 // SetLogLevel configures the logrus log level based on the configuration.
-// It sets the log level to DebugLevel if debug mode is enabled, otherwise to InfoLevel.
+// The global level is DebugLevel if debug mode is enabled, otherwise InfoLevel; log-levels sets
+// the level of individual modules. Levels set at runtime take precedence over both.
 func SetLogLevel(cfg *config.Config) {
-	currentLevel := log.GetLevel()
-	var newLevel log.Level
+	base := log.InfoLevel
 	if cfg.Debug {
-		newLevel = log.DebugLevel
-	} else {
-		newLevel = log.InfoLevel
-	}
-
-	if currentLevel != newLevel {
-		log.SetLevel(newLevel)
-		log.Infof("log level changed from %s to %s (debug=%t)", currentLevel, newLevel, cfg.Debug)
+		base = log.DebugLevel
 	}
+	setConfiguredLogLevels(base, cfg.LogLevels)
 }
 
 // ResolveAuthDir normalizes the auth directory path for consistent reuse throughout the app.
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// watermarkPrefix starts every response watermark, versioning its format.
+const watermarkPrefix = "wm1."
+
+// ClientKeyHash returns a short, stable hash of a client API key that identifies the key
+// without revealing it.
+func ClientKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Watermark builds the response watermark for a request: the hash of its client key followed
+// by its request ID.
+func Watermark(clientKey, requestID string) string {
+	return watermarkPrefix + ClientKeyHash(clientKey) + "." + requestID
+}
+
+// ParseWatermark splits a response watermark into the client key hash and the request ID.
+func ParseWatermark(value string) (keyHash, requestID string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(value), watermarkPrefix)
+	if !ok {
+		return "", "", false
+	}
+	keyHash, requestID, _ = strings.Cut(rest, ".")
+	if len(keyHash) != 16 {
+		return "", "", false
+	}
+	return keyHash, requestID, true
+}
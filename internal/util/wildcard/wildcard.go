@@ -0,0 +1,35 @@
+// Package wildcard matches model names, auth references and similar identifiers against
+// configured patterns. It has no dependencies so every package can share one matcher.
+package wildcard
+
+import "strings"
+
+// Match reports whether value matches pattern, ignoring case. '*' matches any run of
+// characters, including none; no other character is special. An empty pattern matches nothing.
+//
+//	"gpt-*" matches "gpt-5" and "GPT-4o"
+//	"gemini-*-pro" matches "gemini-2.5-pro"
+func Match(pattern, value string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	value = strings.ToLower(value)
+	if pattern == "" {
+		return false
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	first, last := parts[0], parts[len(parts)-1]
+	if len(value) < len(first)+len(last) || !strings.HasPrefix(value, first) || !strings.HasSuffix(value, last) {
+		return false
+	}
+	value = value[len(first) : len(value)-len(last)]
+	for _, segment := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, segment)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(segment):]
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package wildcard
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"gpt-5", "gpt-5", true},
+		{"gpt-5", "gpt-5-mini", false},
+		{"GPT-*", "gpt-4o", true},
+		{"*-mini", "gpt-5-mini", true},
+		{"gemini-*-pro", "gemini-2.5-pro", true},
+		{"gemini-*-pro", "gemini-pro", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "acb", false},
+		{"ab*ba", "aba", false},
+		{"ab*ba", "abba", true},
+		{"*", "", true},
+		{" claude-* ", "claude-sonnet-4", true},
+		{"", "gpt-5", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("Match(%q, %q) = %t, want %t", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
 	"gopkg.in/yaml.v3"
 
 	log "github.com/sirupsen/logrus"
@@ -103,6 +104,7 @@ func (w *Watcher) reloadConfig() bool {
 	w.oldConfigYaml, _ = yaml.Marshal(newConfig)
 	w.config = newConfig
 	w.clientsMutex.Unlock()
+	w.watchSecretFiles(newConfig)
 
 	var affectedOAuthProviders []string
 	if oldConfig != nil {
@@ -121,6 +123,7 @@ func (w *Watcher) reloadConfig() bool {
 			for _, d := range details {
 				log.Debugf("  %s", d)
 			}
+			webhook.Notify(webhook.EventConfigChanged, map[string]any{"changes": details})
 		} else {
 			log.Debugf("no material config field changes detected")
 		}
@@ -27,12 +27,24 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Debug != newCfg.Debug {
 		changes = append(changes, fmt.Sprintf("debug: %t -> %t", oldCfg.Debug, newCfg.Debug))
 	}
+	if !reflect.DeepEqual(oldCfg.LogLevels, newCfg.LogLevels) {
+		changes = append(changes, fmt.Sprintf("log-levels: %v -> %v", oldCfg.LogLevels, newCfg.LogLevels))
+	}
 	if oldCfg.Pprof.Enable != newCfg.Pprof.Enable {
 		changes = append(changes, fmt.Sprintf("pprof.enable: %t -> %t", oldCfg.Pprof.Enable, newCfg.Pprof.Enable))
 	}
 	if strings.TrimSpace(oldCfg.Pprof.Addr) != strings.TrimSpace(newCfg.Pprof.Addr) {
 		changes = append(changes, fmt.Sprintf("pprof.addr: %s -> %s", strings.TrimSpace(oldCfg.Pprof.Addr), strings.TrimSpace(newCfg.Pprof.Addr)))
 	}
+	if oldCfg.LeaderElection.Enable != newCfg.LeaderElection.Enable {
+		changes = append(changes, fmt.Sprintf("leader-election.enable: %t -> %t", oldCfg.LeaderElection.Enable, newCfg.LeaderElection.Enable))
+	}
+	if oldCfg.LeaderElection.LockFile != newCfg.LeaderElection.LockFile {
+		changes = append(changes, fmt.Sprintf("leader-election.lock-file: %s -> %s", oldCfg.LeaderElection.LockFile, newCfg.LeaderElection.LockFile))
+	}
+	if oldCfg.LeaderElection.LeaseSeconds != newCfg.LeaderElection.LeaseSeconds {
+		changes = append(changes, fmt.Sprintf("leader-election.lease-seconds: %d -> %d", oldCfg.LeaderElection.LeaseSeconds, newCfg.LeaderElection.LeaseSeconds))
+	}
 	if oldCfg.LoggingToFile != newCfg.LoggingToFile {
 		changes = append(changes, fmt.Sprintf("logging-to-file: %t -> %t", oldCfg.LoggingToFile, newCfg.LoggingToFile))
 	}
@@ -51,6 +63,15 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.ErrorLogsMaxFiles != newCfg.ErrorLogsMaxFiles {
 		changes = append(changes, fmt.Sprintf("error-logs-max-files: %d -> %d", oldCfg.ErrorLogsMaxFiles, newCfg.ErrorLogsMaxFiles))
 	}
+	if oldCfg.RequestLogDiff != newCfg.RequestLogDiff {
+		changes = append(changes, fmt.Sprintf("request-log-diff: %t -> %t", oldCfg.RequestLogDiff, newCfg.RequestLogDiff))
+	}
+	if oldCfg.RequestLogMaxBodyBytes != newCfg.RequestLogMaxBodyBytes {
+		changes = append(changes, fmt.Sprintf("request-log-max-body-bytes: %d -> %d", oldCfg.RequestLogMaxBodyBytes, newCfg.RequestLogMaxBodyBytes))
+	}
+	if oldCfg.RequestLogSampleRate != newCfg.RequestLogSampleRate {
+		changes = append(changes, fmt.Sprintf("request-log-sample-rate: %g -> %g", oldCfg.RequestLogSampleRate, newCfg.RequestLogSampleRate))
+	}
 	if oldCfg.RequestRetry != newCfg.RequestRetry {
 		changes = append(changes, fmt.Sprintf("request-retry: %d -> %d", oldCfg.RequestRetry, newCfg.RequestRetry))
 	}
@@ -60,6 +81,24 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.ProxyURL != newCfg.ProxyURL {
 		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", formatProxyURL(oldCfg.ProxyURL), formatProxyURL(newCfg.ProxyURL)))
 	}
+	if oldCfg.ProxyCredentialsFile != newCfg.ProxyCredentialsFile {
+		changes = append(changes, fmt.Sprintf("proxy-credentials-file: %s -> %s", oldCfg.ProxyCredentialsFile, newCfg.ProxyCredentialsFile))
+	}
+	if oldCfg.ProxyCredentialsEnv != newCfg.ProxyCredentialsEnv {
+		changes = append(changes, fmt.Sprintf("proxy-credentials-env: %s -> %s", oldCfg.ProxyCredentialsEnv, newCfg.ProxyCredentialsEnv))
+	}
+	if !reflect.DeepEqual(oldCfg.ProxyBypass, newCfg.ProxyBypass) {
+		changes = append(changes, fmt.Sprintf("proxy-bypass: updated (%d -> %d hosts)", len(oldCfg.ProxyBypass), len(newCfg.ProxyBypass)))
+	}
+	if !reflect.DeepEqual(oldCfg.ProxyHostOverrides, newCfg.ProxyHostOverrides) {
+		changes = append(changes, fmt.Sprintf("proxy-host-overrides: updated (%d -> %d entries)", len(oldCfg.ProxyHostOverrides), len(newCfg.ProxyHostOverrides)))
+	}
+	if !reflect.DeepEqual(oldCfg.TLSPins, newCfg.TLSPins) {
+		changes = append(changes, fmt.Sprintf("tls-pins: updated (%d -> %d hosts)", len(oldCfg.TLSPins), len(newCfg.TLSPins)))
+	}
+	if oldCfg.ReverseProxyProbeIntervalSeconds != newCfg.ReverseProxyProbeIntervalSeconds {
+		changes = append(changes, fmt.Sprintf("reverse-proxy-probe-interval-seconds: %d -> %d", oldCfg.ReverseProxyProbeIntervalSeconds, newCfg.ReverseProxyProbeIntervalSeconds))
+	}
 	if oldCfg.WebsocketAuth != newCfg.WebsocketAuth {
 		changes = append(changes, fmt.Sprintf("ws-auth: %t -> %t", oldCfg.WebsocketAuth, newCfg.WebsocketAuth))
 	}
@@ -69,9 +108,189 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.NonStreamKeepAliveInterval != newCfg.NonStreamKeepAliveInterval {
 		changes = append(changes, fmt.Sprintf("nonstream-keepalive-interval: %d -> %d", oldCfg.NonStreamKeepAliveInterval, newCfg.NonStreamKeepAliveInterval))
 	}
+	if oldCfg.Streaming.MaxStreams != newCfg.Streaming.MaxStreams || oldCfg.Streaming.MaxStreamsPerKey != newCfg.Streaming.MaxStreamsPerKey || !reflect.DeepEqual(oldCfg.Streaming.KeyMaxStreams, newCfg.Streaming.KeyMaxStreams) {
+		changes = append(changes, fmt.Sprintf("streaming.max-streams: %d -> %d (per key %d -> %d, %d -> %d key overrides)", oldCfg.Streaming.MaxStreams, newCfg.Streaming.MaxStreams, oldCfg.Streaming.MaxStreamsPerKey, newCfg.Streaming.MaxStreamsPerKey, len(oldCfg.Streaming.KeyMaxStreams), len(newCfg.Streaming.KeyMaxStreams)))
+	}
+	if oldCfg.Streaming.SalvageMinBytes != newCfg.Streaming.SalvageMinBytes {
+		changes = append(changes, fmt.Sprintf("streaming.salvage-min-bytes: %d -> %d", oldCfg.Streaming.SalvageMinBytes, newCfg.Streaming.SalvageMinBytes))
+	}
+	if oldCfg.ErrorResponses.Normalize != newCfg.ErrorResponses.Normalize {
+		changes = append(changes, fmt.Sprintf("error-responses.normalize: %t -> %t", oldCfg.ErrorResponses.Normalize, newCfg.ErrorResponses.Normalize))
+	}
+	if oldCfg.ErrorResponses.HideUpstreamDetail != newCfg.ErrorResponses.HideUpstreamDetail {
+		changes = append(changes, fmt.Sprintf("error-responses.hide-upstream-detail: %t -> %t", oldCfg.ErrorResponses.HideUpstreamDetail, newCfg.ErrorResponses.HideUpstreamDetail))
+	}
+	if !reflect.DeepEqual(oldCfg.ThinkingSuffixes, newCfg.ThinkingSuffixes) {
+		changes = append(changes, fmt.Sprintf("thinking-suffixes: updated (%d -> %d suffixes)", len(oldCfg.ThinkingSuffixes), len(newCfg.ThinkingSuffixes)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelCatalogs, newCfg.ModelCatalogs) {
+		changes = append(changes, fmt.Sprintf("model-catalogs: updated (%d -> %d catalogs)", len(oldCfg.ModelCatalogs), len(newCfg.ModelCatalogs)))
+	}
+	if oldCfg.DisableDuplicateAuths != newCfg.DisableDuplicateAuths {
+		changes = append(changes, fmt.Sprintf("disable-duplicate-auths: %t -> %t", oldCfg.DisableDuplicateAuths, newCfg.DisableDuplicateAuths))
+	}
+	if oldCfg.AuthArchive != newCfg.AuthArchive {
+		changes = append(changes, fmt.Sprintf("auth-archive: enabled=%t retention-days=%d -> enabled=%t retention-days=%d", oldCfg.AuthArchive.Enabled, oldCfg.AuthArchive.RetentionDays, newCfg.AuthArchive.Enabled, newCfg.AuthArchive.RetentionDays))
+	}
+	if !reflect.DeepEqual(oldCfg.ShareLinks, newCfg.ShareLinks) {
+		changes = append(changes, fmt.Sprintf("share-links: updated (%d -> %d links)", len(oldCfg.ShareLinks), len(newCfg.ShareLinks)))
+	}
+	if !reflect.DeepEqual(oldCfg.APIKeyCatalogs, newCfg.APIKeyCatalogs) {
+		changes = append(changes, fmt.Sprintf("api-key-catalogs: updated (%d -> %d keys)", len(oldCfg.APIKeyCatalogs), len(newCfg.APIKeyCatalogs)))
+	}
+	if oldCfg.EchoRequestedModel != newCfg.EchoRequestedModel {
+		changes = append(changes, fmt.Sprintf("echo-requested-model: %t -> %t", oldCfg.EchoRequestedModel, newCfg.EchoRequestedModel))
+	}
+	if oldCfg.CassetteRecordDir != newCfg.CassetteRecordDir {
+		changes = append(changes, fmt.Sprintf("cassette-record-dir: %s -> %s", oldCfg.CassetteRecordDir, newCfg.CassetteRecordDir))
+	}
+	if !reflect.DeepEqual(oldCfg.DebugTraceKeys, newCfg.DebugTraceKeys) {
+		changes = append(changes, fmt.Sprintf("debug-trace-keys: updated (%d -> %d keys)", len(oldCfg.DebugTraceKeys), len(newCfg.DebugTraceKeys)))
+	}
+	if oldCfg.MCP.Enabled != newCfg.MCP.Enabled {
+		changes = append(changes, fmt.Sprintf("mcp.enabled: %t -> %t", oldCfg.MCP.Enabled, newCfg.MCP.Enabled))
+	}
+	if oldCfg.MCP.ManagementTools != newCfg.MCP.ManagementTools {
+		changes = append(changes, fmt.Sprintf("mcp.management-tools: %t -> %t", oldCfg.MCP.ManagementTools, newCfg.MCP.ManagementTools))
+	}
+	if oldCfg.MCP.DefaultModel != newCfg.MCP.DefaultModel {
+		changes = append(changes, fmt.Sprintf("mcp.default-model: %s -> %s", oldCfg.MCP.DefaultModel, newCfg.MCP.DefaultModel))
+	}
+	if !reflect.DeepEqual(oldCfg.MCPTools, newCfg.MCPTools) {
+		changes = append(changes, fmt.Sprintf("mcp-tools: updated (%d -> %d servers)", len(oldCfg.MCPTools.Servers), len(newCfg.MCPTools.Servers)))
+	}
+	if !reflect.DeepEqual(oldCfg.WebSearch, newCfg.WebSearch) {
+		changes = append(changes, fmt.Sprintf("web-search: updated (provider %q -> %q)", oldCfg.WebSearch.Provider, newCfg.WebSearch.Provider))
+	}
+	if !reflect.DeepEqual(oldCfg.CodeExecution, newCfg.CodeExecution) {
+		changes = append(changes, fmt.Sprintf("code-execution: updated (sandbox %q -> %q)", oldCfg.CodeExecution.Sandbox, newCfg.CodeExecution.Sandbox))
+	}
+	if !reflect.DeepEqual(oldCfg.JSONValidation, newCfg.JSONValidation) {
+		changes = append(changes, fmt.Sprintf("json-validation: enable %t -> %t, repair %t -> %t", oldCfg.JSONValidation.Enable, newCfg.JSONValidation.Enable, oldCfg.JSONValidation.Repair, newCfg.JSONValidation.Repair))
+	}
+	if oldCfg.ReloginNotice != newCfg.ReloginNotice {
+		changes = append(changes, fmt.Sprintf("relogin-notice.enable: %t -> %t", oldCfg.ReloginNotice.Enable, newCfg.ReloginNotice.Enable))
+	}
+	if !reflect.DeepEqual(oldCfg.ResponseRules, newCfg.ResponseRules) {
+		changes = append(changes, fmt.Sprintf("response-rules: updated (%d -> %d entries)", len(oldCfg.ResponseRules), len(newCfg.ResponseRules)))
+	}
+	if !reflect.DeepEqual(oldCfg.ClientKeyGroups, newCfg.ClientKeyGroups) {
+		changes = append(changes, fmt.Sprintf("client-key-groups: updated (%d -> %d groups)", len(oldCfg.ClientKeyGroups), len(newCfg.ClientKeyGroups)))
+	}
+	if !reflect.DeepEqual(oldCfg.PayloadProfiles, newCfg.PayloadProfiles) {
+		changes = append(changes, fmt.Sprintf("payload-profiles: updated (%d -> %d profiles)", len(oldCfg.PayloadProfiles.Profiles), len(newCfg.PayloadProfiles.Profiles)))
+	}
+	if oldCfg.RoutingRulesFile != newCfg.RoutingRulesFile {
+		changes = append(changes, fmt.Sprintf("routing-rules-file: %s -> %s", oldCfg.RoutingRulesFile, newCfg.RoutingRulesFile))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestScripts, newCfg.RequestScripts) {
+		changes = append(changes, fmt.Sprintf("request-scripts: updated (%d -> %d entries)", len(oldCfg.RequestScripts), len(newCfg.RequestScripts)))
+	}
+	if oldCfg.RefusalFallback.Enable != newCfg.RefusalFallback.Enable {
+		changes = append(changes, fmt.Sprintf("refusal-fallback.enable: %t -> %t", oldCfg.RefusalFallback.Enable, newCfg.RefusalFallback.Enable))
+	}
+	if !reflect.DeepEqual(oldCfg.RefusalFallback.Rules, newCfg.RefusalFallback.Rules) {
+		changes = append(changes, fmt.Sprintf("refusal-fallback.rules: updated (%d -> %d entries)", len(oldCfg.RefusalFallback.Rules), len(newCfg.RefusalFallback.Rules)))
+	}
+	if oldCfg.ContextOverflow.Strategy != newCfg.ContextOverflow.Strategy {
+		changes = append(changes, fmt.Sprintf("context-overflow.strategy: %s -> %s", oldCfg.ContextOverflow.Strategy, newCfg.ContextOverflow.Strategy))
+	}
+	if !reflect.DeepEqual(oldCfg.ContextOverflow.Siblings, newCfg.ContextOverflow.Siblings) {
+		changes = append(changes, fmt.Sprintf("context-overflow.siblings: updated (%d -> %d entries)", len(oldCfg.ContextOverflow.Siblings), len(newCfg.ContextOverflow.Siblings)))
+	}
+	if !reflect.DeepEqual(oldCfg.PromptCompression, newCfg.PromptCompression) {
+		changes = append(changes, fmt.Sprintf("prompt-compression: updated (strategy %q -> %q, threshold %d -> %d)", oldCfg.PromptCompression.Strategy, newCfg.PromptCompression.Strategy, oldCfg.PromptCompression.ThresholdTokens, newCfg.PromptCompression.ThresholdTokens))
+	}
+	if !reflect.DeepEqual(oldCfg.SystemPromptDedup, newCfg.SystemPromptDedup) {
+		changes = append(changes, fmt.Sprintf("system-prompt-dedup: updated (strategy %q -> %q)", oldCfg.SystemPromptDedup.Strategy, newCfg.SystemPromptDedup.Strategy))
+	}
+	if oldCfg.Moderation.BaseURL != newCfg.Moderation.BaseURL {
+		changes = append(changes, fmt.Sprintf("moderation.base-url: %s -> %s", oldCfg.Moderation.BaseURL, newCfg.Moderation.BaseURL))
+	}
+	if oldCfg.Moderation.APIKey != newCfg.Moderation.APIKey {
+		changes = append(changes, "moderation.api-key: updated")
+	}
+	if oldCfg.Moderation.Action != newCfg.Moderation.Action {
+		changes = append(changes, fmt.Sprintf("moderation.action: %s -> %s", oldCfg.Moderation.Action, newCfg.Moderation.Action))
+	}
+	if !reflect.DeepEqual(oldCfg.Moderation.KeyActions, newCfg.Moderation.KeyActions) {
+		changes = append(changes, fmt.Sprintf("moderation.key-actions: updated (%d -> %d keys)", len(oldCfg.Moderation.KeyActions), len(newCfg.Moderation.KeyActions)))
+	}
+	if !reflect.DeepEqual(oldCfg.CostCeiling, newCfg.CostCeiling) {
+		changes = append(changes, fmt.Sprintf("cost-ceiling: updated (action %q -> %q, max %g -> %g, %d -> %d priced models)", oldCfg.CostCeiling.Action, newCfg.CostCeiling.Action, oldCfg.CostCeiling.MaxCostPerRequest, newCfg.CostCeiling.MaxCostPerRequest, len(oldCfg.CostCeiling.Pricing), len(newCfg.CostCeiling.Pricing)))
+	}
+	if !reflect.DeepEqual(oldCfg.TokenBudgets, newCfg.TokenBudgets) {
+		changes = append(changes, fmt.Sprintf("token-budgets: updated (window %ds -> %ds, %d -> %d keys)", oldCfg.TokenBudgets.WindowSeconds, newCfg.TokenBudgets.WindowSeconds, len(oldCfg.TokenBudgets.Keys), len(newCfg.TokenBudgets.Keys)))
+	}
+	if !reflect.DeepEqual(oldCfg.UsageAnomaly, newCfg.UsageAnomaly) {
+		changes = append(changes, fmt.Sprintf("usage-anomaly: updated (enabled %t -> %t, throttle %dm -> %dm)", oldCfg.UsageAnomaly.Enabled, newCfg.UsageAnomaly.Enabled, oldCfg.UsageAnomaly.ThrottleMinutes, newCfg.UsageAnomaly.ThrottleMinutes))
+	}
+	if !reflect.DeepEqual(oldCfg.RepeatedPrompts, newCfg.RepeatedPrompts) {
+		changes = append(changes, fmt.Sprintf("repeated-prompts: updated (enabled %t -> %t, action %s -> %s, %d -> %d key overrides)", oldCfg.RepeatedPrompts.Enabled, newCfg.RepeatedPrompts.Enabled, oldCfg.RepeatedPrompts.Action, newCfg.RepeatedPrompts.Action, len(oldCfg.RepeatedPrompts.Keys), len(newCfg.RepeatedPrompts.Keys)))
+	}
+	if !reflect.DeepEqual(oldCfg.MemoryGuard, newCfg.MemoryGuard) {
+		changes = append(changes, fmt.Sprintf("memory-guard: updated (enabled %t -> %t, soft %d -> %d MB, hard %d -> %d MB)", oldCfg.MemoryGuard.Enabled, newCfg.MemoryGuard.Enabled, oldCfg.MemoryGuard.SoftLimitMB, newCfg.MemoryGuard.SoftLimitMB, oldCfg.MemoryGuard.HardLimitMB, newCfg.MemoryGuard.HardLimitMB))
+	}
+	if !reflect.DeepEqual(oldCfg.Webhooks, newCfg.Webhooks) {
+		changes = append(changes, fmt.Sprintf("webhooks: updated (%d -> %d endpoints)", len(oldCfg.Webhooks), len(newCfg.Webhooks)))
+	}
+	if !reflect.DeepEqual(oldCfg.UsageReports, newCfg.UsageReports) {
+		changes = append(changes, fmt.Sprintf("usage-reports: updated (%d -> %d reports)", len(oldCfg.UsageReports.Reports), len(newCfg.UsageReports.Reports)))
+	}
+	if !reflect.DeepEqual(oldCfg.AuditChain, newCfg.AuditChain) {
+		changes = append(changes, "audit-chain: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.ExecutorPlugins, newCfg.ExecutorPlugins) {
+		changes = append(changes, fmt.Sprintf("executor-plugins: updated (%d -> %d entries)", len(oldCfg.ExecutorPlugins), len(newCfg.ExecutorPlugins)))
+	}
+	if !reflect.DeepEqual(oldCfg.Notifiers, newCfg.Notifiers) {
+		changes = append(changes, fmt.Sprintf("notifiers: updated (telegram %d -> %d, discord %d -> %d)", len(oldCfg.Notifiers.Telegram), len(newCfg.Notifiers.Telegram), len(oldCfg.Notifiers.Discord), len(newCfg.Notifiers.Discord)))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestTimeouts, newCfg.RequestTimeouts) {
+		changes = append(changes, fmt.Sprintf("request-timeouts: updated (%d -> %d providers)", len(oldCfg.RequestTimeouts.Providers), len(newCfg.RequestTimeouts.Providers)))
+	}
+	if !reflect.DeepEqual(oldCfg.UpstreamTransport, newCfg.UpstreamTransport) {
+		changes = append(changes, fmt.Sprintf("upstream-transport: updated (%d -> %d providers)", len(oldCfg.UpstreamTransport.Providers), len(newCfg.UpstreamTransport.Providers)))
+	}
+	if oldCfg.Hedging.Enabled != newCfg.Hedging.Enabled {
+		changes = append(changes, fmt.Sprintf("hedging.enabled: %t -> %t", oldCfg.Hedging.Enabled, newCfg.Hedging.Enabled))
+	}
+	if oldCfg.Hedging.DelayMs != newCfg.Hedging.DelayMs {
+		changes = append(changes, fmt.Sprintf("hedging.delay-ms: %d -> %d", oldCfg.Hedging.DelayMs, newCfg.Hedging.DelayMs))
+	}
+	if !reflect.DeepEqual(oldCfg.Hedging.Providers, newCfg.Hedging.Providers) {
+		changes = append(changes, fmt.Sprintf("hedging.providers: %v -> %v", oldCfg.Hedging.Providers, newCfg.Hedging.Providers))
+	}
+	if oldCfg.Chaos.Enabled != newCfg.Chaos.Enabled {
+		changes = append(changes, fmt.Sprintf("chaos.enabled: %t -> %t", oldCfg.Chaos.Enabled, newCfg.Chaos.Enabled))
+	}
+	if !reflect.DeepEqual(oldCfg.Chaos, newCfg.Chaos) && oldCfg.Chaos.Enabled == newCfg.Chaos.Enabled {
+		changes = append(changes, "chaos: updated")
+	}
+	if oldCfg.ModelDiscovery.RefreshIntervalSeconds != newCfg.ModelDiscovery.RefreshIntervalSeconds {
+		changes = append(changes, fmt.Sprintf("model-discovery.refresh-interval-seconds: %d -> %d", oldCfg.ModelDiscovery.RefreshIntervalSeconds, newCfg.ModelDiscovery.RefreshIntervalSeconds))
+	}
+	if oldCfg.ModelDiscovery.HideExhausted != newCfg.ModelDiscovery.HideExhausted {
+		changes = append(changes, fmt.Sprintf("model-discovery.hide-exhausted: %t -> %t", oldCfg.ModelDiscovery.HideExhausted, newCfg.ModelDiscovery.HideExhausted))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelMetadata, newCfg.ModelMetadata) {
+		changes = append(changes, fmt.Sprintf("model-metadata: updated (%d -> %d entries)", len(oldCfg.ModelMetadata), len(newCfg.ModelMetadata)))
+	}
 	if oldCfg.CodexInstructionsEnabled != newCfg.CodexInstructionsEnabled {
 		changes = append(changes, fmt.Sprintf("codex-instructions-enabled: %t -> %t", oldCfg.CodexInstructionsEnabled, newCfg.CodexInstructionsEnabled))
 	}
+	if oldCfg.CodexClient != newCfg.CodexClient {
+		changes = append(changes, "codex-client: updated")
+	}
+	if oldCfg.CodexPromptCache != newCfg.CodexPromptCache {
+		changes = append(changes, fmt.Sprintf("codex-prompt-cache: updated (ttl %ds -> %ds, max entries %d -> %d)", oldCfg.CodexPromptCache.TTLSeconds, newCfg.CodexPromptCache.TTLSeconds, oldCfg.CodexPromptCache.MaxEntries, newCfg.CodexPromptCache.MaxEntries))
+	}
+	if oldCfg.CodexSession.Strategy != newCfg.CodexSession.Strategy {
+		changes = append(changes, fmt.Sprintf("codex-session.strategy: %s -> %s", oldCfg.CodexSession.Strategy, newCfg.CodexSession.Strategy))
+	}
+	if !reflect.DeepEqual(oldCfg.CodexBuiltinTools, newCfg.CodexBuiltinTools) {
+		changes = append(changes, "codex-builtin-tools: updated")
+	}
 
 	// Quota-exceeded behavior
 	if oldCfg.QuotaExceeded.SwitchProject != newCfg.QuotaExceeded.SwitchProject {
@@ -91,6 +310,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.APIKeys), trimStrings(newCfg.APIKeys)) {
 		changes = append(changes, "api-keys: values updated (count unchanged, redacted)")
 	}
+	if !reflect.DeepEqual(oldCfg.APIKeyMetadata, newCfg.APIKeyMetadata) {
+		changes = append(changes, fmt.Sprintf("api-key-metadata: updated (%d -> %d keys)", len(oldCfg.APIKeyMetadata), len(newCfg.APIKeyMetadata)))
+	}
 	if len(oldCfg.GeminiKey) != len(newCfg.GeminiKey) {
 		changes = append(changes, fmt.Sprintf("gemini-api-key count: %d -> %d", len(oldCfg.GeminiKey), len(newCfg.GeminiKey)))
 	} else {
@@ -247,6 +469,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	}
 
 	// Remote management (never print the key)
+	if oldCfg.ReadOnly != newCfg.ReadOnly {
+		changes = append(changes, fmt.Sprintf("read-only: %t -> %t", oldCfg.ReadOnly, newCfg.ReadOnly))
+	}
 	if oldCfg.RemoteManagement.AllowRemote != newCfg.RemoteManagement.AllowRemote {
 		changes = append(changes, fmt.Sprintf("remote-management.allow-remote: %t -> %t", oldCfg.RemoteManagement.AllowRemote, newCfg.RemoteManagement.AllowRemote))
 	}
@@ -258,7 +483,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldPanelRepo != newPanelRepo {
 		changes = append(changes, fmt.Sprintf("remote-management.panel-github-repository: %s -> %s", oldPanelRepo, newPanelRepo))
 	}
-	if oldCfg.RemoteManagement.SecretKey != newCfg.RemoteManagement.SecretKey {
+	if oldCfg.RemoteManagement.SecretKeyFile != newCfg.RemoteManagement.SecretKeyFile {
+		changes = append(changes, fmt.Sprintf("remote-management.secret-key-file: %s -> %s", oldCfg.RemoteManagement.SecretKeyFile, newCfg.RemoteManagement.SecretKeyFile))
+	}
+	// A key read from a file is hashed afresh on every load, so its hash says nothing.
+	secretKeyFromFile := oldCfg.RemoteManagement.SecretKeyFile != "" && oldCfg.RemoteManagement.SecretKeyFile == newCfg.RemoteManagement.SecretKeyFile
+	if !secretKeyFromFile && oldCfg.RemoteManagement.SecretKey != newCfg.RemoteManagement.SecretKey {
 		switch {
 		case oldCfg.RemoteManagement.SecretKey == "" && newCfg.RemoteManagement.SecretKey != "":
 			changes = append(changes, "remote-management.secret-key: created")
@@ -268,6 +498,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			changes = append(changes, "remote-management.secret-key: updated")
 		}
 	}
+	if !reflect.DeepEqual(oldCfg.RemoteManagement.ViewerKeys, newCfg.RemoteManagement.ViewerKeys) {
+		changes = append(changes, fmt.Sprintf("remote-management.viewer-keys: updated (%d -> %d keys)", len(oldCfg.RemoteManagement.ViewerKeys), len(newCfg.RemoteManagement.ViewerKeys)))
+	}
+	if oldCfg.RemoteManagement.RedactSecrets != newCfg.RemoteManagement.RedactSecrets {
+		changes = append(changes, fmt.Sprintf("remote-management.redact-secrets: %t -> %t", oldCfg.RemoteManagement.RedactSecrets, newCfg.RemoteManagement.RedactSecrets))
+	}
 
 	// OpenAI compatibility providers (summarized)
 	if compat := DiffOpenAICompatibility(oldCfg.OpenAICompatibility, newCfg.OpenAICompatibility); len(compat) > 0 {
@@ -307,6 +543,11 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 		}
 	}
 
+	// Mock providers
+	if !reflect.DeepEqual(oldCfg.MockProviders, newCfg.MockProviders) {
+		changes = append(changes, fmt.Sprintf("mock-provider: updated (%d -> %d entries)", len(oldCfg.MockProviders), len(newCfg.MockProviders)))
+	}
+
 	return changes
 }
 
@@ -39,6 +39,11 @@ func (w *Watcher) start(ctx context.Context) error {
 	}
 	log.Debugf("watching auth directory: %s", w.authDir)
 
+	w.clientsMutex.RLock()
+	cfg := w.config
+	w.clientsMutex.RUnlock()
+	w.watchSecretFiles(cfg)
+
 	go w.processEvents(ctx)
 
 	w.reloadClients(true, nil, false)
@@ -65,8 +70,12 @@ func (w *Watcher) processEvents(ctx context.Context) {
 }
 
 func (w *Watcher) handleEvent(event fsnotify.Event) {
+	if w.isSecretFileEvent(event) {
+		w.reloadForSecretFile(event)
+		return
+	}
 	// Filter only relevant events: config file or auth-dir JSON files.
-	configOps := fsnotify.Write | fsnotify.Create | fsnotify.Rename
+	configOps := fsnotify.Write | fsnotify.Create | fsnotify.Rename | fsnotify.Remove
 	normalizedName := w.normalizeAuthPath(event.Name)
 	normalizedConfigPath := w.normalizeAuthPath(w.configPath)
 	normalizedAuthDir := w.normalizeAuthPath(w.authDir)
@@ -84,6 +93,9 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	// Handle config file changes
 	if isConfigEvent {
 		log.Debugf("config file change details - operation: %s, timestamp: %s", event.Op.String(), now.Format("2006-01-02 15:04:05.000"))
+		if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+			w.rewatchConfig()
+		}
 		w.scheduleConfigReload()
 		return
 	}
@@ -124,6 +136,19 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	}
 }
 
+// rewatchConfig watches the config file again after it was replaced by a rename, as atomic
+// writers such as config sync and many editors do; the watch on the old file ends with it.
+func (w *Watcher) rewatchConfig() {
+	time.Sleep(replaceCheckDelay)
+	if _, errStat := os.Stat(w.configPath); errStat != nil {
+		return
+	}
+	_ = w.watcher.Remove(w.configPath)
+	if errAdd := w.watcher.Add(w.configPath); errAdd != nil {
+		log.Errorf("failed to watch replaced config file %s: %v", w.configPath, errAdd)
+	}
+}
+
 func (w *Watcher) authFileUnchanged(path string) (bool, error) {
 	data, errRead := os.ReadFile(path)
 	if errRead != nil {
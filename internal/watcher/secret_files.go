@@ -0,0 +1,72 @@
+// secret_files.go watches the files that config *-file settings read credentials from and
+// reloads the config when one of them changes.
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchSecretFiles watches the directories of the secret files cfg references. Directories
+// are watched rather than the files, since mounted secrets are usually replaced by swapping a
+// symlink (Kubernetes' ..data) instead of being written in place.
+func (w *Watcher) watchSecretFiles(cfg *config.Config) {
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range cfg.SecretFiles() {
+		if abs, errAbs := filepath.Abs(path); errAbs == nil {
+			path = abs
+		}
+		files[w.normalizeAuthPath(path)] = true
+		dirs[w.normalizeAuthPath(filepath.Dir(path))] = true
+	}
+
+	w.clientsMutex.Lock()
+	previous := w.secretDirs
+	w.secretFiles = files
+	w.secretDirs = dirs
+	w.clientsMutex.Unlock()
+
+	for dir := range dirs {
+		if previous[dir] {
+			continue
+		}
+		if errAdd := w.watcher.Add(dir); errAdd != nil {
+			log.Errorf("failed to watch secret file directory %s: %v", dir, errAdd)
+			continue
+		}
+		log.Debugf("watching secret file directory: %s", dir)
+	}
+	for dir := range previous {
+		// The auth directory keeps its own watch.
+		if dirs[dir] || dir == w.normalizeAuthPath(w.authDir) {
+			continue
+		}
+		_ = w.watcher.Remove(dir)
+	}
+}
+
+// isSecretFileEvent reports whether event changes a watched secret file, directly or through
+// a symlink swap in its directory.
+func (w *Watcher) isSecretFileEvent(event fsnotify.Event) bool {
+	name := w.normalizeAuthPath(event.Name)
+	w.clientsMutex.RLock()
+	defer w.clientsMutex.RUnlock()
+	if w.secretFiles[name] {
+		return true
+	}
+	return w.secretDirs[filepath.Dir(name)] && strings.HasPrefix(filepath.Base(name), "..")
+}
+
+// reloadForSecretFile reloads the config even though config.yaml itself did not change.
+func (w *Watcher) reloadForSecretFile(event fsnotify.Event) {
+	log.Infof("secret file changed (%s): %s, reloading config", event.Op.String(), filepath.Base(event.Name))
+	w.clientsMutex.Lock()
+	w.lastConfigHash = ""
+	w.clientsMutex.Unlock()
+	w.scheduleConfigReload()
+}
@@ -10,7 +10,7 @@ import (
 )
 
 // ConfigSynthesizer generates Auth entries from configuration API keys.
-// It handles Gemini, Claude, Codex, OpenAI-compat, and Vertex-compat providers.
+// It handles Gemini, Claude, Codex, OpenAI-compat, Vertex-compat, and mock providers.
 type ConfigSynthesizer struct{}
 
 // NewConfigSynthesizer creates a new ConfigSynthesizer instance.
@@ -35,6 +35,8 @@ func (s *ConfigSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth,
 	out = append(out, s.synthesizeOpenAICompat(ctx)...)
 	// Vertex-compat
 	out = append(out, s.synthesizeVertexCompat(ctx)...)
+	// Mock providers
+	out = append(out, s.synthesizeMockProviders(ctx)...)
 
 	return out, nil
 }
@@ -317,3 +319,37 @@ func (s *ConfigSynthesizer) synthesizeVertexCompat(ctx *SynthesisContext) []*cor
 	}
 	return out
 }
+
+// synthesizeMockProviders creates Auth entries for in-process mock providers.
+func (s *ConfigSynthesizer) synthesizeMockProviders(ctx *SynthesisContext) []*coreauth.Auth {
+	cfg := ctx.Config
+	now := ctx.Now
+	idGen := ctx.IDGenerator
+
+	out := make([]*coreauth.Auth, 0, len(cfg.MockProviders))
+	for i := range cfg.MockProviders {
+		provider := &cfg.MockProviders[i]
+		id, token := idGen.Next("mock", provider.Name)
+		attrs := map[string]string{
+			"source":    fmt.Sprintf("config:mock[%s]", token),
+			"mock_name": provider.Name,
+		}
+		if provider.Priority != 0 {
+			attrs["priority"] = strconv.Itoa(provider.Priority)
+		}
+		if hash := diff.ComputeMockModelsHash(provider.Models); hash != "" {
+			attrs["models_hash"] = hash
+		}
+		out = append(out, &coreauth.Auth{
+			ID:         id,
+			Provider:   "mock",
+			Label:      provider.Name,
+			Prefix:     strings.TrimSpace(provider.Prefix),
+			Status:     coreauth.StatusActive,
+			Attributes: attrs,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+	return out
+}
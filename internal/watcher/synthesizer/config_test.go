@@ -400,6 +400,33 @@ func TestConfigSynthesizer_VertexCompat(t *testing.T) {
 	}
 }
 
+func TestConfigSynthesizer_MockProviders(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			MockProviders: []config.MockProvider{
+				{Name: "fixtures", Prefix: "mock", Models: []config.MockModel{{Name: "mock-gpt", Format: "openai"}}},
+			},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 1 {
+		t.Fatalf("expected 1 auth, got %d", len(auths))
+	}
+	if auths[0].Provider != "mock" || auths[0].Label != "fixtures" || auths[0].Prefix != "mock" {
+		t.Errorf("unexpected auth: provider=%s label=%s prefix=%s", auths[0].Provider, auths[0].Label, auths[0].Prefix)
+	}
+	if auths[0].Attributes["mock_name"] != "fixtures" || auths[0].Attributes["models_hash"] == "" {
+		t.Errorf("unexpected attributes: %v", auths[0].Attributes)
+	}
+}
+
 func TestConfigSynthesizer_VertexCompat_SkipsEmptyAndHeaders(t *testing.T) {
 	synth := NewConfigSynthesizer()
 	ctx := &SynthesisContext{
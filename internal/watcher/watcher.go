@@ -52,6 +52,8 @@ type Watcher struct {
 	storePersister    storePersister
 	mirroredAuthDir   string
 	oldConfigYaml     []byte
+	secretFiles       map[string]bool
+	secretDirs        map[string]bool
 }
 
 // AuthUpdateAction represents the type of change detected in auth sources.
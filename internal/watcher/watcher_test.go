@@ -3,10 +3,12 @@ package watcher
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -828,6 +830,63 @@ func TestHandleEventConfigChangeSchedulesReload(t *testing.T) {
 	}
 }
 
+func TestHandleEventSecretFileChangeReloadsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	authDir := filepath.Join(tmpDir, "auth")
+	secretDir := filepath.Join(tmpDir, "secrets")
+	for _, dir := range []string{authDir, secretDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	secretPath := filepath.Join(secretDir, "codex_key")
+	if err := os.WriteFile(secretPath, []byte("sk-old"), 0o600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	configData := "auth-dir: " + authDir + "\ncodex-api-key:\n  - api-key-file: " + secretPath + "\n    base-url: https://example.com\n"
+	if err := os.WriteFile(configPath, []byte(configData), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create fsnotify watcher: %v", err)
+	}
+	defer func() { _ = fsWatcher.Close() }()
+	reloaded := make(chan *config.Config, 1)
+	w := &Watcher{
+		authDir:        authDir,
+		configPath:     configPath,
+		watcher:        fsWatcher,
+		lastAuthHashes: make(map[string]string),
+		reloadCallback: func(cfg *config.Config) { reloaded <- cfg },
+	}
+	w.SetConfig(cfg)
+	w.watchSecretFiles(cfg)
+	sum := sha256.Sum256([]byte(configData))
+	w.lastConfigHash = hex.EncodeToString(sum[:])
+
+	w.handleEvent(fsnotify.Event{Name: filepath.Join(secretDir, "unrelated"), Op: fsnotify.Write})
+	if err = os.WriteFile(secretPath, []byte("sk-new"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite secret file: %v", err)
+	}
+	w.handleEvent(fsnotify.Event{Name: secretPath, Op: fsnotify.Write})
+
+	select {
+	case got := <-reloaded:
+		if len(got.CodexKey) != 1 || got.CodexKey[0].APIKey != "sk-new" {
+			t.Fatalf("reloaded codex keys = %+v, want the new secret", got.CodexKey)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("secret file change did not reload the config")
+	}
+}
+
 func TestHandleEventAuthWriteTriggersUpdate(t *testing.T) {
 	tmpDir := t.TempDir()
 	authDir := filepath.Join(tmpDir, "auth")
@@ -1488,3 +1547,33 @@ func TestScheduleProcessEventsStopsOnContextDone(t *testing.T) {
 func hexString(data []byte) string {
 	return strings.ToLower(fmt.Sprintf("%x", data))
 }
+
+func TestRewatchConfigFollowsAtomicReplace(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create fsnotify watcher: %v", err)
+	}
+	defer func() { _ = fsWatcher.Close() }()
+	if err = fsWatcher.Add(configPath); err != nil {
+		t.Fatalf("failed to watch config: %v", err)
+	}
+	w := &Watcher{configPath: configPath, watcher: fsWatcher}
+
+	tmp := filepath.Join(dir, ".config.yaml.tmp")
+	if err = os.WriteFile(tmp, []byte("port: 2\n"), 0o600); err != nil {
+		t.Fatalf("failed to write replacement: %v", err)
+	}
+	if err = os.Rename(tmp, configPath); err != nil {
+		t.Fatalf("failed to replace config: %v", err)
+	}
+	w.rewatchConfig()
+
+	if !slices.Contains(fsWatcher.WatchList(), configPath) {
+		t.Fatalf("watch list = %v, want %s", fsWatcher.WatchList(), configPath)
+	}
+}
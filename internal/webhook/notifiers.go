@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// telegramAPIBase is the Telegram Bot API endpoint; tests point it at a local server.
+var telegramAPIBase = "https://api.telegram.org"
+
+// Message length limits of the chat services.
+const (
+	telegramMaxMessage = 4096
+	discordMaxMessage  = 2000
+)
+
+// defaultTemplates render the built-in chat messages. Templates see the event data fields plus
+// .event (the event type) and .time.
+var defaultTemplates = map[string]string{
+	EventAuthReloginRequired: `🔑 {{.provider}} account {{with .label}}{{.}}{{else}}{{.auth_id}}{{end}} needs to log in again.{{with .error}}
+Error: {{.}}{{end}}`,
+	EventAuthRefreshFailed: `⚠️ Token refresh failed for {{.provider}} account {{.auth_id}}.{{with .error}}
+Error: {{.}}{{end}}`,
+	EventQuotaExhausted: `{{if .weekly}}📅 Weekly quota reached{{else}}⏳ Quota exhausted{{end}} for {{.provider}} account {{.auth_id}}{{with .model}} (model {{.}}){{end}}.{{with .recover_at}}
+Resets at {{.}}.{{end}}`,
+	EventReverseProxyBanned:   `🚫 Reverse proxy {{.proxy_id}} banned for {{.provider}} until {{.until}} (status {{.status_code}}).`,
+	EventReverseProxyRestored: `✅ Reverse proxy {{.proxy_id}} restored for {{.provider}} after a successful probe.`,
+	EventBudgetThreshold:      `{{if .exhausted}}🛑 Token budget exhausted{{else}}📈 Token budget at {{.percent}}%{{end}} for key {{.api_key}} ({{.used}} of {{.limit}} tokens used).`,
+	EventConfigChanged: `🛠️ Configuration changed:{{range .changes}}
+• {{.}}{{end}}`,
+	EventCanaryRolledBack: `↩️ Proxy routing canary rolled back: {{.reason}}.`,
+	EventUsageAnomaly: `📊 Token usage spike for key {{.api_key}}: {{.hour_tokens}} tokens this hour{{with .ratio}}, {{.}}× the trailing average{{end}}.{{with .throttled_until}}
+Throttled until {{.}}.{{end}}`,
+}
+
+// messageRenderer turns events into chat message text.
+type messageRenderer struct {
+	templates map[string]*template.Template
+}
+
+func newMessageRenderer(overrides map[string]string) *messageRenderer {
+	r := &messageRenderer{templates: make(map[string]*template.Template, len(defaultTemplates))}
+	for event, text := range defaultTemplates {
+		r.templates[event] = template.Must(template.New(event).Parse(text))
+	}
+	for event, text := range overrides {
+		tmpl, err := template.New(event).Parse(text)
+		if err != nil {
+			log.Warnf("notifiers: invalid template for %s, using the built-in one: %v", event, err)
+			continue
+		}
+		r.templates[event] = tmpl
+	}
+	return r
+}
+
+// render returns the message for event. Events without a template are summarized by type.
+func (r *messageRenderer) render(event Event) (string, error) {
+	data := make(map[string]any, len(event.Data)+2)
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	data["event"] = event.Type
+	data["time"] = event.Time
+	tmpl, ok := r.templates[event.Type]
+	if !ok {
+		return "Event " + event.Type, nil
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(sb.String(), "<no value>", ""), nil
+}
+
+// notifierTargets builds the delivery targets of the configured chat notifiers.
+func notifierTargets(cfg config.NotifiersConfig) []target {
+	if len(cfg.Telegram) == 0 && len(cfg.Discord) == 0 {
+		return nil
+	}
+	renderer := newMessageRenderer(cfg.Templates)
+	targets := make([]target, 0, len(cfg.Telegram)+len(cfg.Discord))
+	for _, notifier := range cfg.Telegram {
+		chatID := notifier.ChatID
+		targets = append(targets, target{
+			name:   "telegram chat " + chatID,
+			url:    telegramAPIBase + "/bot" + notifier.BotToken + "/sendMessage",
+			events: notifier.Events,
+			render: func(event Event) ([]byte, error) {
+				text, err := renderer.render(event)
+				if err != nil {
+					return nil, err
+				}
+				return json.Marshal(map[string]any{
+					"chat_id":                  chatID,
+					"text":                     truncateMessage(text, telegramMaxMessage),
+					"disable_web_page_preview": true,
+				})
+			},
+		})
+	}
+	for _, notifier := range cfg.Discord {
+		targets = append(targets, target{
+			name:   "discord webhook",
+			url:    notifier.WebhookURL,
+			events: notifier.Events,
+			render: func(event Event) ([]byte, error) {
+				text, err := renderer.render(event)
+				if err != nil {
+					return nil, err
+				}
+				return json.Marshal(map[string]any{
+					"content":          truncateMessage(text, discordMaxMessage),
+					"allowed_mentions": map[string]any{"parse": []string{}},
+				})
+			},
+		})
+	}
+	return targets
+}
+
+// truncateMessage shortens text to at most limit characters, marking the cut with an ellipsis.
+func truncateMessage(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestMessageRendererBuiltInTemplates(t *testing.T) {
+	r := newMessageRenderer(nil)
+
+	msg, err := r.render(Event{Type: EventQuotaExhausted, Data: map[string]any{"provider": "codex", "auth_id": "a.json", "weekly": true}})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(msg, "Weekly quota reached") || !strings.Contains(msg, "codex account a.json") {
+		t.Fatalf("quota message = %q", msg)
+	}
+
+	msg, err = r.render(Event{Type: EventAuthReloginRequired, Data: map[string]any{"provider": "claude", "auth_id": "b.json", "label": "me@example.com"}})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(msg, "claude account me@example.com needs to log in again") || strings.Contains(msg, "Error") {
+		t.Fatalf("relogin message = %q", msg)
+	}
+}
+
+func TestMessageRendererOverrides(t *testing.T) {
+	r := newMessageRenderer(map[string]string{
+		EventQuotaExhausted:    "{{.provider}} out of quota ({{.event}})",
+		EventConfigChanged:     "{{.broken",
+		"custom.event.unknown": "ignored",
+	})
+	msg, _ := r.render(Event{Type: EventQuotaExhausted, Data: map[string]any{"provider": "gemini"}})
+	if msg != "gemini out of quota (quota.exhausted)" {
+		t.Fatalf("override message = %q", msg)
+	}
+	msg, _ = r.render(Event{Type: EventConfigChanged, Data: map[string]any{"changes": []string{"debug: false -> true"}}})
+	if !strings.Contains(msg, "debug: false -> true") {
+		t.Fatalf("invalid override should fall back to the built-in template, got %q", msg)
+	}
+}
+
+func TestNotifierTargetsPostChatMessages(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	previousBase := telegramAPIBase
+	telegramAPIBase = server.URL
+	defer func() { telegramAPIBase = previousBase }()
+
+	d := &dispatcher{sleep: func(time.Duration) {}}
+	d.configure(buildTargets(&config.Config{Notifiers: config.NotifiersConfig{
+		Telegram: []config.TelegramNotifier{{BotToken: "123:abc", ChatID: "42", Events: []string{EventAuthReloginRequired}}},
+		Discord:  []config.DiscordNotifier{{WebhookURL: server.URL + "/discord"}},
+	}}), &http.Client{})
+
+	d.notify(EventAuthReloginRequired, map[string]any{"provider": "codex", "auth_id": "c.json"})
+	d.deliverWG.Wait()
+
+	telegram := bodies["/bot123:abc/sendMessage"]
+	if telegram["chat_id"] != "42" || !strings.Contains(telegram["text"].(string), "needs to log in again") {
+		t.Fatalf("telegram body = %v", telegram)
+	}
+	if !strings.Contains(bodies["/discord"]["content"].(string), "needs to log in again") {
+		t.Fatalf("discord body = %v", bodies["/discord"])
+	}
+
+	delete(bodies, "/discord")
+	delete(bodies, "/bot123:abc/sendMessage")
+	d.notify(EventConfigChanged, map[string]any{"changes": []string{"x"}})
+	d.deliverWG.Wait()
+	if _, ok := bodies["/bot123:abc/sendMessage"]; ok {
+		t.Fatal("telegram notifier should only receive subscribed events")
+	}
+	if _, ok := bodies["/discord"]; !ok {
+		t.Fatal("discord notifier without a filter should receive every event")
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	if got := truncateMessage("héllo", 10); got != "héllo" {
+		t.Fatalf("got %q", got)
+	}
+	if got := truncateMessage("héllo world", 5); got != "héll…" {
+		t.Fatalf("got %q", got)
+	}
+}
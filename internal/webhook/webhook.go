@@ -0,0 +1,218 @@
+// Package webhook delivers notifications about notable proxy events to operator-configured
+// HTTP endpoints and built-in chat notifiers. Deliveries are asynchronous, signed with
+// HMAC-SHA256 when a secret is set, and retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Event types.
+const (
+	EventAuthRefreshFailed    = "auth.refresh_failed"
+	EventAuthReloginRequired  = "auth.relogin_required"
+	EventQuotaExhausted       = "quota.exhausted"
+	EventReverseProxyBanned   = "reverse_proxy.banned"
+	EventReverseProxyRestored = "reverse_proxy.restored"
+	EventBudgetThreshold      = "budget.threshold"
+	EventConfigChanged        = "config.changed"
+	EventCanaryRolledBack     = "canary.rolled_back"
+	EventUsageAnomaly         = "usage.anomaly"
+)
+
+const (
+	defaultMaxRetries = 3
+	deliveryTimeout   = 10 * time.Second
+	retryBaseDelay    = time.Second
+)
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID   string         `json:"id"`
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// target is one destination of events: a raw webhook or a chat notifier.
+type target struct {
+	// name identifies the target in logs without exposing credentials.
+	name    string
+	url     string
+	secret  string
+	events  []string
+	retries int
+	// render builds the request body; nil sends the JSON event itself.
+	render func(Event) ([]byte, error)
+}
+
+// dispatcher holds the active targets.
+type dispatcher struct {
+	mu        sync.RWMutex
+	targets   []target
+	client    *http.Client
+	sleep     func(time.Duration)
+	deliverWG sync.WaitGroup
+}
+
+var defaultDispatcher = &dispatcher{client: &http.Client{}, sleep: time.Sleep}
+
+// Configure replaces the webhooks and notifiers told about events and the proxy used to reach them.
+func Configure(cfg *config.Config) {
+	if cfg == nil {
+		defaultDispatcher.configure(nil, &http.Client{})
+		return
+	}
+	defaultDispatcher.configure(buildTargets(cfg), util.SetProxy(&cfg.SDKConfig, &http.Client{}))
+}
+
+func buildTargets(cfg *config.Config) []target {
+	targets := make([]target, 0, len(cfg.Webhooks))
+	for _, hook := range cfg.Webhooks {
+		targets = append(targets, target{
+			name:    hook.URL,
+			url:     hook.URL,
+			secret:  hook.Secret,
+			events:  hook.Events,
+			retries: hook.MaxRetries,
+		})
+	}
+	return append(targets, notifierTargets(cfg.Notifiers)...)
+}
+
+// Notify sends an event of the given type to every webhook subscribed to it.
+// It returns immediately; delivery happens in the background.
+func Notify(eventType string, data map[string]any) {
+	defaultDispatcher.notify(eventType, data)
+}
+
+func (d *dispatcher) configure(targets []target, client *http.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.targets = targets
+	d.client = client
+}
+
+func (d *dispatcher) notify(eventType string, data map[string]any) {
+	d.mu.RLock()
+	targets := d.targets
+	client := d.client
+	d.mu.RUnlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	event := Event{ID: newEventID(), Type: eventType, Time: time.Now().UTC(), Data: data}
+	eventBody, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("webhook: failed to encode %s event: %v", eventType, err)
+		return
+	}
+	for _, t := range targets {
+		if len(t.events) > 0 && !slices.Contains(t.events, eventType) {
+			continue
+		}
+		body := eventBody
+		if t.render != nil {
+			if body, err = t.render(event); err != nil {
+				log.Warnf("webhook: failed to render %s event for %s: %v", eventType, t.name, err)
+				continue
+			}
+		}
+		d.deliverWG.Add(1)
+		go func(t target, body []byte) {
+			defer d.deliverWG.Done()
+			d.deliver(client, t, event, body)
+		}(t, body)
+	}
+}
+
+// deliver posts body to the target, retrying network errors, 429 and 5xx responses.
+func (d *dispatcher) deliver(client *http.Client, t target, event Event, body []byte) {
+	retries := t.retries
+	if retries == 0 {
+		retries = defaultMaxRetries
+	}
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		retryable, err := d.post(client, t, event, body)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= retries {
+			log.Warnf("webhook: delivery of %s event %s to %s failed: %v", event.Type, event.ID, t.name, err)
+			return
+		}
+		d.sleep(delay)
+		delay *= 2
+	}
+}
+
+func (d *dispatcher) post(client *http.Client, t target, event Event, body []byte) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(event.Time.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CLIProxyAPI-Webhook")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-ID", event.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if t.secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+Sign(t.secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("webhook: close response body error: %v", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("status %d", resp.StatusCode)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with secret, as sent
+// in the X-Webhook-Signature header. Receivers recompute it to authenticate deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newEventID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "evt_" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func newTestDispatcher(hooks ...config.WebhookConfig) *dispatcher {
+	d := &dispatcher{sleep: func(time.Duration) {}}
+	d.configure(buildTargets(&config.Config{Webhooks: hooks}), &http.Client{})
+	return d
+}
+
+func TestNotifySignsAndFiltersDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(
+		config.WebhookConfig{URL: server.URL, Secret: "s3cret", Events: []string{EventQuotaExhausted}},
+		config.WebhookConfig{URL: server.URL + "/other", Events: []string{EventConfigChanged}},
+	)
+	d.notify(EventQuotaExhausted, map[string]any{"auth_id": "a1"})
+	d.deliverWG.Wait()
+
+	if len(received) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(received))
+	}
+	req, body := received[0], bodies[0]
+	if req.Header.Get("X-Webhook-Event") != EventQuotaExhausted {
+		t.Fatalf("event header = %q", req.Header.Get("X-Webhook-Event"))
+	}
+	want := "sha256=" + Sign("s3cret", req.Header.Get("X-Webhook-Timestamp"), body)
+	if got := req.Header.Get("X-Webhook-Signature"); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if event.Type != EventQuotaExhausted || event.Data["auth_id"] != "a1" || event.ID == "" {
+		t.Fatalf("event = %+v", event)
+	}
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(config.WebhookConfig{URL: server.URL})
+	d.notify(EventConfigChanged, nil)
+	d.deliverWG.Wait()
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+}
+
+func TestDeliverDoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(config.WebhookConfig{URL: server.URL, MaxRetries: 5})
+	d.notify(EventConfigChanged, nil)
+	d.deliverWG.Wait()
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("attempts = %d, want 1", got)
+	}
+}
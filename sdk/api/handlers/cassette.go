@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cassette"
+)
+
+// newCassetteRecorder attaches a cassette recorder to ctx when cassette-record-dir is set.
+// The returned recorder is nil otherwise; Flush on it is a no-op.
+func (h *BaseAPIHandler) newCassetteRecorder(ctx context.Context) (*cassette.Recorder, context.Context) {
+	if h == nil || h.Cfg == nil || ctx == nil || h.Cfg.CassetteRecordDir == "" {
+		return nil, ctx
+	}
+	recorder := cassette.NewRecorder(h.Cfg.CassetteRecordDir)
+	return recorder, recorder.Attach(ctx)
+}
@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
@@ -122,6 +124,39 @@ func (h *ClaudeCodeAPIHandler) ClaudeCountTokens(c *gin.Context) {
 	cliCancel()
 }
 
+// ClaudeCompact handles the /v1/messages/compact endpoint.
+// It accepts a Messages API request body and returns the conversation compacted by the
+// backend's native compaction, as Claude-format messages. The stream flag is ignored.
+//
+// Parameters:
+//   - c: The Gin context for the request.
+func (h *ClaudeCodeAPIHandler) ClaudeCompact(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	stopKeepAlive := h.StartNonStreamingKeepAlive(c, cliCtx)
+	resp, errMsg := h.CompactConversation(cliCtx, h.HandlerType(), modelName, rawJSON)
+	stopKeepAlive()
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
 // ClaudeModels handles the Claude models listing endpoint.
 // It returns a JSON response containing available Claude models and their specifications.
 //
@@ -129,6 +164,7 @@ func (h *ClaudeCodeAPIHandler) ClaudeCountTokens(c *gin.Context) {
 //   - c: The Gin context for the request.
 func (h *ClaudeCodeAPIHandler) ClaudeModels(c *gin.Context) {
 	models := h.AvailableModelsForRequest(c, "claude")
+	h.MarkDegradedModels(models)
 	firstID := ""
 	lastID := ""
 	if len(models) > 0 {
@@ -280,6 +316,7 @@ func (h *ClaudeCodeAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON [
 
 func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
+		SalvageFormat: h.HandlerType(),
 		WriteChunk: func(chunk []byte) {
 			if len(chunk) == 0 {
 				return
@@ -297,6 +334,13 @@ func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.
 			c.Status(status)
 
 			errorBytes, _ := json.Marshal(h.toClaudeError(errMsg))
+			if h.Cfg != nil && h.Cfg.ErrorResponses.Normalize {
+				errorBytes = h.ErrorMessageBody(c, errMsg)
+			} else if retryAfter := errMsg.Addon.Get("Retry-After"); retryAfter != "" {
+				if seconds, errParse := strconv.Atoi(retryAfter); errParse == nil {
+					errorBytes = handlers.ApplyRetryAfterToErrorBody(h.HandlerType(), errorBytes, time.Duration(seconds)*time.Second)
+				}
+			}
 			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errorBytes)
 		},
 	})
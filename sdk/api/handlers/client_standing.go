@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// ClientStanding is what a client API key may learn about itself: its usage, its remaining
+// token budget, its rate limits and the models it may use.
+type ClientStanding struct {
+	// Key is the masked client API key.
+	Key       string   `json:"key"`
+	ExpiresAt string   `json:"expires-at,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+
+	Usage ClientUsage `json:"usage"`
+	// Budget is the token budget of the key, absent when the key has none.
+	Budget *ClientBudget `json:"budget,omitempty"`
+	// MaxCostPerRequest is the operator cost ceiling for one request; 0 means none.
+	MaxCostPerRequest float64          `json:"max-cost-per-request,omitempty"`
+	RateLimits        ClientRateLimits `json:"rate-limits"`
+	// Models are the IDs of the models the key may use.
+	Models []string `json:"models"`
+}
+
+// ClientUsage counts the requests and tokens of a client key since the server started.
+type ClientUsage struct {
+	Requests int64                       `json:"requests"`
+	Tokens   int64                       `json:"tokens"`
+	Models   map[string]ClientModelUsage `json:"models,omitempty"`
+}
+
+// ClientModelUsage counts the requests and tokens of a client key for one model.
+type ClientModelUsage struct {
+	Requests int64 `json:"requests"`
+	Tokens   int64 `json:"tokens"`
+}
+
+// ClientBudget is the state of a client key's token budget in the current window.
+type ClientBudget struct {
+	Limit         int64     `json:"limit"`
+	Used          int64     `json:"used"`
+	Reserved      int64     `json:"reserved"`
+	Remaining     int64     `json:"remaining"`
+	WindowSeconds int       `json:"window-seconds"`
+	ResetsAt      time.Time `json:"resets-at"`
+}
+
+// ClientRateLimits reports the concurrency limits of a client key and whether it is throttled
+// after a usage spike.
+type ClientRateLimits struct {
+	OpenStreams int `json:"open-streams"`
+	// MaxStreams is the number of streams the key may open at once; 0 means unlimited.
+	MaxStreams int  `json:"max-streams,omitempty"`
+	Throttled  bool `json:"throttled"`
+	// ThrottledUntil and RequestsPerMinute describe the throttle while it lasts.
+	ThrottledUntil    *time.Time `json:"throttled-until,omitempty"`
+	RequestsPerMinute int        `json:"requests-per-minute,omitempty"`
+}
+
+// ClientStanding reports the standing of the client API key that authenticated c.
+func (h *BaseAPIHandler) ClientStanding(c *gin.Context) ClientStanding {
+	key := clientAPIKeyFromGin(c)
+	now := time.Now()
+	standing := ClientStanding{Key: util.HideAPIKey(key), Models: []string{}}
+	if h == nil || h.Cfg == nil {
+		return standing
+	}
+	cfg := h.Cfg
+	standing.Groups = cfg.ClientKeyGroupsOf(key)
+
+	if api, ok := usage.GetRequestStatistics().Snapshot().APIs[key]; ok && key != "" {
+		standing.Usage.Requests = api.TotalRequests
+		standing.Usage.Tokens = api.TotalTokens
+		standing.Usage.Models = make(map[string]ClientModelUsage, len(api.Models))
+		for model, stats := range api.Models {
+			standing.Usage.Models[model] = ClientModelUsage{Requests: stats.TotalRequests, Tokens: stats.TotalTokens}
+		}
+	}
+
+	if limit, ok := config.LookupClientKey(cfg, cfg.TokenBudgets.Keys, key); ok && key != "" {
+		window := time.Duration(cfg.TokenBudgets.WindowSeconds) * time.Second
+		if window <= 0 {
+			window = defaultTokenBudgetWindow
+		}
+		used, reserved, resetsAt := usage.TokenBudgetStanding(key, window, now)
+		standing.Budget = &ClientBudget{
+			Limit:         limit,
+			Used:          used,
+			Reserved:      reserved,
+			Remaining:     max(limit-used-reserved, 0),
+			WindowSeconds: int(window / time.Second),
+			ResetsAt:      resetsAt,
+		}
+	}
+
+	standing.MaxCostPerRequest = max(cfg.CostCeiling.MaxCostPerRequest, 0)
+	if limit, ok := config.LookupClientKey(cfg, cfg.CostCeiling.KeyLimits, key); ok {
+		standing.MaxCostPerRequest = max(limit, 0)
+	}
+
+	standing.RateLimits.OpenStreams = openStreams.open(key)
+	standing.RateLimits.MaxStreams = max(cfg.Streaming.MaxStreamsPerKey, 0)
+	if override, ok := config.LookupClientKey(cfg, cfg.Streaming.KeyMaxStreams, key); ok && key != "" {
+		standing.RateLimits.MaxStreams = max(override, 0)
+	}
+	if until := usage.ThrottledUntil(key, now); !until.IsZero() {
+		standing.RateLimits.Throttled = true
+		standing.RateLimits.ThrottledUntil = &until
+		standing.RateLimits.RequestsPerMinute = cfg.UsageAnomaly.ThrottleRequestsPerMinute
+		if standing.RateLimits.RequestsPerMinute <= 0 {
+			standing.RateLimits.RequestsPerMinute = defaultUsageAnomalyRequestsPerMin
+		}
+	}
+
+	for _, model := range h.AvailableModelsForRequest(c, "openai") {
+		if id, _ := model["id"].(string); id != "" {
+			standing.Models = append(standing.Models, id)
+		}
+	}
+	sort.Strings(standing.Models)
+	return standing
+}
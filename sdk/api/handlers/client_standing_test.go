@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestClientStanding(t *testing.T) {
+	const key = "standing-test-key"
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+		ClientKeyGroups: []sdkconfig.ClientKeyGroup{{Name: "team", Keys: []string{key}}},
+		TokenBudgets:    sdkconfig.TokenBudgetConfig{Keys: map[string]int64{"group:team": 1000}},
+		Streaming:       sdkconfig.StreamingConfig{KeyMaxStreams: map[string]int{"group:team": 2}},
+	}, coreauth.NewManager(nil, nil, nil))
+	ctx := catalogTestContext(key)
+	ginCtx := ctx.Value("gin").(*gin.Context)
+
+	release, errMsg := h.reserveTokenBudget(ctx, []byte(`{"messages":[{"role":"user","content":"hi"}],"max_tokens":100}`))
+	if errMsg != nil {
+		t.Fatalf("reserve: %v", errMsg.Error)
+	}
+	defer release()
+	releaseStream, errMsg := h.acquireStreamSlot(ctx)
+	if errMsg != nil {
+		t.Fatalf("acquire stream: %v", errMsg.Error)
+	}
+	defer releaseStream()
+	usage.ThrottleClientKey(key, time.Now().Add(time.Minute))
+
+	standing := h.ClientStanding(ginCtx)
+	if standing.Key == key || len(standing.Groups) != 1 || standing.Groups[0] != "team" {
+		t.Fatalf("key = %q, groups = %v", standing.Key, standing.Groups)
+	}
+	budget := standing.Budget
+	if budget == nil || budget.Limit != 1000 || budget.Reserved <= 0 || budget.Remaining != budget.Limit-budget.Used-budget.Reserved || !budget.ResetsAt.After(time.Now()) {
+		t.Fatalf("budget = %+v", budget)
+	}
+	limits := standing.RateLimits
+	if limits.OpenStreams != 1 || limits.MaxStreams != 2 || !limits.Throttled || limits.ThrottledUntil == nil || limits.RequestsPerMinute != defaultUsageAnomalyRequestsPerMin {
+		t.Fatalf("rate limits = %+v", limits)
+	}
+	if standing.Models == nil {
+		t.Fatalf("models must be an empty list, not null")
+	}
+
+	if other := h.ClientStanding(catalogTestContext("standing-other-key").Value("gin").(*gin.Context)); other.Budget != nil || other.RateLimits.Throttled {
+		t.Fatalf("unrelated key standing = %+v", other)
+	}
+}
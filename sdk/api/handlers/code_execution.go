@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// codeExecutionToolName is the function name of the sandboxed code interpreter.
+	codeExecutionToolName = "code_interpreter"
+	// codeInterpreterToolType is the request tool type clients send to opt in.
+	codeInterpreterToolType = "code_interpreter"
+	// codeExecutionStartupGrace covers sandbox startup on top of the execution timeout.
+	codeExecutionStartupGrace = 10 * time.Second
+)
+
+// codeInterpreters maps the supported languages to the interpreter reading code from stdin.
+var codeInterpreters = map[string][]string{
+	"python": {"python3", "-"},
+	"shell":  {"sh", "-s"},
+}
+
+// auditFileMu serializes writes to audit files.
+var auditFileMu sync.Mutex
+
+// codeExecutionAudit is the audit record of one execution.
+type codeExecutionAudit struct {
+	Time        time.Time `json:"time"`
+	RequestID   string    `json:"request_id,omitempty"`
+	ClientKey   string    `json:"client_key,omitempty"`
+	Sandbox     string    `json:"sandbox"`
+	Language    string    `json:"language"`
+	Code        string    `json:"code"`
+	ExitCode    int       `json:"exit_code"`
+	TimedOut    bool      `json:"timed_out,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+	OutputBytes int       `json:"output_bytes"`
+}
+
+// codeExecutionApply reports whether the code interpreter is offered to clientKey on a request
+// that includes a code_interpreter tool.
+func (h *BaseAPIHandler) codeExecutionApply(clientKey string, rawJSON []byte) bool {
+	if h == nil || h.Cfg == nil || h.Cfg.CodeExecution.Sandbox == "" {
+		return false
+	}
+	if !gjson.GetBytes(rawJSON, `tools.#(type=="`+codeInterpreterToolType+`")`).Exists() {
+		return false
+	}
+	keys := h.Cfg.CodeExecution.ClientKeys
+	return len(keys) == 0 || slices.Contains(keys, clientKey)
+}
+
+func (h *BaseAPIHandler) codeExecutionTool() serverTool {
+	ce := h.Cfg.CodeExecution
+	return serverTool{
+		name: codeExecutionToolName,
+		description: fmt.Sprintf("Run Python or shell code in an isolated sandbox without network access and return its exit code and combined output. "+
+			"Print the values you need. Executions are limited to %d seconds and %d MB of memory; files do not persist between calls.", ce.TimeoutSeconds, ce.MemoryMB),
+		schema: []byte(`{"type":"object","properties":{"language":{"type":"string","enum":["python","shell"],"description":"Defaults to python."},` +
+			`"code":{"type":"string","description":"The code to run."}},"required":["code"]}`),
+		run: h.runCode,
+	}
+}
+
+// runCode executes a code_interpreter call in the sandbox and audits it.
+func (h *BaseAPIHandler) runCode(ctx context.Context, arguments string) (string, error) {
+	ce := h.Cfg.CodeExecution
+	code := gjson.Get(arguments, "code").String()
+	if strings.TrimSpace(code) == "" {
+		return "", fmt.Errorf("code is required")
+	}
+	language := gjson.Get(arguments, "language").String()
+	if language == "" {
+		language = "python"
+	}
+	if _, ok := codeInterpreters[language]; !ok {
+		return "", fmt.Errorf("unsupported language %q", language)
+	}
+
+	timeout := time.Duration(ce.TimeoutSeconds) * time.Second
+	runCtx, cancel := context.WithTimeout(ctx, timeout+codeExecutionStartupGrace)
+	defer cancel()
+	name, args := codeSandboxCommand(ce, language)
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Stdin = strings.NewReader(code)
+	output := &limitedBuffer{max: ce.MaxOutputBytes}
+	cmd.Stdout, cmd.Stderr = output, output
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+	entry := codeExecutionAudit{
+		Time:        start.UTC(),
+		RequestID:   logging.GetRequestID(ctx),
+		Sandbox:     ce.Sandbox,
+		Language:    language,
+		Code:        code,
+		DurationMs:  duration.Milliseconds(),
+		OutputBytes: output.total,
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+		entry.ClientKey = util.HideAPIKey(clientAPIKeyFromGin(ginCtx))
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		entry.ExitCode = exitErr.ExitCode()
+		// The sandboxes kill the interpreter with SIGKILL (137) once the timeout elapses.
+		entry.TimedOut = runCtx.Err() != nil || (entry.ExitCode == 137 && duration >= timeout)
+	default:
+		entry.ExitCode = -1
+		entry.TimedOut = runCtx.Err() != nil
+		entry.Error = err.Error()
+	}
+	writeAuditLine(ce.AuditFile, "code-execution", entry)
+	if entry.Error != "" && !entry.TimedOut {
+		return "", fmt.Errorf("sandbox failed: %s", entry.Error)
+	}
+
+	var b strings.Builder
+	if entry.TimedOut {
+		fmt.Fprintf(&b, "Execution timed out after %s.\n", timeout)
+	} else {
+		fmt.Fprintf(&b, "exit_code: %d\n", entry.ExitCode)
+	}
+	b.Write(output.buf.Bytes())
+	if output.total > output.buf.Len() {
+		fmt.Fprintf(&b, "\n[output truncated, %d of %d bytes shown]", output.buf.Len(), output.total)
+	}
+	return b.String(), nil
+}
+
+// codeSandboxCommand builds the command running language in the configured sandbox. Code is
+// read from stdin; the sandbox has no network, runs unprivileged and enforces the limits.
+func codeSandboxCommand(ce config.CodeExecutionConfig, language string) (string, []string) {
+	interpreter := codeInterpreters[language]
+	switch ce.Sandbox {
+	case config.CodeSandboxFirejail:
+		name := ce.Binary
+		if name == "" {
+			name = "firejail"
+		}
+		t := ce.TimeoutSeconds
+		args := []string{
+			"--quiet", "--noprofile", "--private", "--net=none", "--nonewprivs", "--noroot",
+			"--caps.drop=all", "--seccomp",
+			"--rlimit-as=" + strconv.Itoa(ce.MemoryMB<<20),
+			"--rlimit-nproc=" + strconv.Itoa(ce.MaxProcesses),
+			fmt.Sprintf("--timeout=%02d:%02d:%02d", t/3600, t/60%60, t%60),
+			"--",
+		}
+		return name, append(args, interpreter...)
+	default:
+		name := ce.Binary
+		if name == "" {
+			name = "docker"
+		}
+		memory := strconv.Itoa(ce.MemoryMB) + "m"
+		args := []string{
+			"run", "--rm", "-i", "--network", "none",
+			"--memory", memory, "--memory-swap", memory, "--cpus", "1",
+			"--pids-limit", strconv.Itoa(ce.MaxProcesses),
+			"--read-only", "--tmpfs", "/tmp:rw,size=64m", "--workdir", "/tmp",
+			"--user", "65534:65534", "--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+			ce.Image, "timeout", "-s", "KILL", strconv.Itoa(ce.TimeoutSeconds),
+		}
+		return name, append(args, interpreter...)
+	}
+}
+
+// writeAuditLine appends entry as a JSON line to path, or logs it under label when path is
+// empty or unwritable.
+func writeAuditLine(path, label string, entry any) {
+	line, _ := json.Marshal(entry)
+	if path != "" {
+		auditFileMu.Lock()
+		defer auditFileMu.Unlock()
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err == nil {
+			_, err = f.Write(append(line, '\n'))
+			if errClose := f.Close(); err == nil {
+				err = errClose
+			}
+		}
+		if err == nil {
+			return
+		}
+		log.Errorf("%s: write audit file: %v", label, err)
+	}
+	log.Infof("%s audit: %s", label, line)
+}
+
+// limitedBuffer keeps the first max bytes written to it and counts the rest.
+type limitedBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	max   int
+	total int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += len(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
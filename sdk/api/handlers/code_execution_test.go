@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// fakeSandbox writes a sandbox binary that ignores its arguments and runs stdin with sh.
+func fakeSandbox(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sandbox")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sh -s\n"), 0o755); err != nil {
+		t.Fatalf("write fake sandbox: %v", err)
+	}
+	return path
+}
+
+func TestRunCodeAuditsExecution(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{CodeExecution: sdkconfig.CodeExecutionConfig{
+		Sandbox:        "docker",
+		Binary:         fakeSandbox(t),
+		TimeoutSeconds: 5,
+		MemoryMB:       64,
+		MaxProcesses:   8,
+		MaxOutputBytes: 10,
+		AuditFile:      auditFile,
+	}}, nil)
+
+	out, err := h.runCode(context.Background(), `{"language":"shell","code":"echo 0123456789abcdef; exit 3"}`)
+	if err != nil {
+		t.Fatalf("runCode: %v", err)
+	}
+	if !strings.HasPrefix(out, "exit_code: 3\n0123456789") || !strings.Contains(out, "[output truncated, 10 of 17 bytes shown]") {
+		t.Fatalf("output = %q", out)
+	}
+
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatalf("read audit file: %v", err)
+	}
+	entry := gjson.ParseBytes(data)
+	if entry.Get("language").String() != "shell" || entry.Get("exit_code").Int() != 3 || !strings.Contains(entry.Get("code").String(), "exit 3") {
+		t.Fatalf("audit entry = %s", data)
+	}
+
+	if _, err = h.runCode(context.Background(), `{"language":"ruby","code":"puts 1"}`); err == nil {
+		t.Fatal("runCode with an unsupported language succeeded")
+	}
+}
+
+func TestCodeSandboxCommand(t *testing.T) {
+	ce := sdkconfig.CodeExecutionConfig{Sandbox: "docker", Image: "python:3.12-alpine", TimeoutSeconds: 75, MemoryMB: 128, MaxProcesses: 16}
+	name, args := codeSandboxCommand(ce, "python")
+	if name != "docker" || !slices.Contains(args, "none") || !slices.Contains(args, "128m") || !slices.Equal(args[len(args)-7:], []string{"python:3.12-alpine", "timeout", "-s", "KILL", "75", "python3", "-"}) {
+		t.Fatalf("docker command = %s %v", name, args)
+	}
+
+	ce.Sandbox = "firejail"
+	name, args = codeSandboxCommand(ce, "shell")
+	if name != "firejail" || !slices.Contains(args, "--net=none") || !slices.Contains(args, "--timeout=00:01:15") || !slices.Equal(args[len(args)-3:], []string{"--", "sh", "-s"}) {
+		t.Fatalf("firejail command = %s %v", name, args)
+	}
+}
+
+func TestCodeExecutionRequiresOptIn(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{CodeExecution: sdkconfig.CodeExecutionConfig{Sandbox: "docker", ClientKeys: []string{"k1"}}}, nil)
+	optIn := []byte(`{"tools":[{"type":"code_interpreter"},{"type":"function","function":{"name":"f"}}]}`)
+	if !h.codeExecutionApply("k1", optIn) || h.codeExecutionApply("k2", optIn) || h.codeExecutionApply("k1", []byte(`{}`)) {
+		t.Fatal("code execution opt-in or client key filter not applied")
+	}
+	if got := gjson.GetBytes(withoutToolType(optIn, "code_interpreter"), "tools.#.type").Raw; got != `["function"]` {
+		t.Fatalf("tools after removal = %s", got)
+	}
+}
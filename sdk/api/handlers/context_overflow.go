@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// perMessageTokenOverhead approximates the role and framing tokens each conversation turn costs.
+const perMessageTokenOverhead = 4
+
+var (
+	overflowCodecOnce sync.Once
+	overflowCodec     tokenizer.Codec
+)
+
+// contextOverflowError reports a request that does not fit the target model's context window.
+// Its message follows the OpenAI context_length_exceeded error so clients can recognize it.
+type contextOverflowError struct {
+	model  string
+	limit  int
+	tokens int
+}
+
+func (e *contextOverflowError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens (model %s). Please reduce the length of the messages.", e.limit, e.tokens, e.model),
+			"type":    "invalid_request_error",
+			"param":   "messages",
+			"code":    "context_length_exceeded",
+		},
+	})
+	return string(body)
+}
+
+func (e *contextOverflowError) StatusCode() int { return http.StatusBadRequest }
+
+func (e *contextOverflowError) ProxyGenerated() bool { return true }
+
+// resolveContextOverflow applies the configured context-overflow strategy before execution.
+// It returns the providers, model, and payload to execute, which differ from the inputs when
+// the request was truncated or routed to a larger-context sibling.
+func (h *BaseAPIHandler) resolveContextOverflow(ctx context.Context, handlerType string, providers []string, model string, rawJSON []byte) ([]string, string, []byte, *interfaces.ErrorMessage) {
+	if h == nil || h.Cfg == nil || h.Cfg.ContextOverflow.Strategy == "" {
+		return providers, model, rawJSON, nil
+	}
+	window := contextWindowFor(model)
+	if window <= 0 {
+		return providers, model, rawJSON, nil
+	}
+	outputTokens := requestedOutputTokens(rawJSON)
+	tokens := requestInputTokens(ctx, rawJSON)
+	if tokens <= contextBudget(window, outputTokens) {
+		return providers, model, rawJSON, nil
+	}
+
+	overflow := &contextOverflowError{model: model, limit: window, tokens: tokens}
+	switch h.Cfg.ContextOverflow.Strategy {
+	case config.ContextOverflowTruncate:
+		truncated, ok := truncateOldestTurns(handlerType, rawJSON, contextBudget(window, outputTokens))
+		if !ok {
+			return nil, "", nil, errorMessageFromError(overflow)
+		}
+		log.Infof("context overflow: truncated oldest turns for model %s (%d tokens, limit %d)", model, tokens, window)
+		return providers, model, truncated, nil
+	case config.ContextOverflowRoute:
+		for _, entry := range h.Cfg.ContextOverflow.Siblings {
+			if !wildcard.Match(entry.Model, model) && !wildcard.Match(entry.Model, thinking.ParseSuffix(model).ModelName) {
+				continue
+			}
+			if tokens > contextBudget(contextWindowFor(entry.Sibling), outputTokens) || !h.catalogPermits(ctx, entry.Sibling) {
+				continue
+			}
+			siblingProviders, siblingModel, errMsg := h.getRequestDetails(entry.Sibling)
+			if errMsg != nil {
+				continue
+			}
+			log.Infof("context overflow: routing model %s to %s (%d tokens, limit %d)", model, siblingModel, tokens, window)
+			annotateServedModel(ctx, siblingModel, fallbackReasonContextOverflow)
+			return siblingProviders, siblingModel, rawJSON, nil
+		}
+	}
+	return nil, "", nil, errorMessageFromError(overflow)
+}
+
+func contextWindowFor(model string) int {
+	if window := registry.LookupContextWindow(model); window > 0 {
+		return window
+	}
+	return registry.LookupContextWindow(thinking.ParseSuffix(model).ModelName)
+}
+
+// contextBudget is the input token budget left after reserving the requested output tokens.
+func contextBudget(window, outputTokens int) int {
+	if window <= 0 {
+		return 0
+	}
+	if outputTokens > 0 && outputTokens < window {
+		return window - outputTokens
+	}
+	return window
+}
+
+// outputTokenPaths lists where the client formats carry the requested output token limit.
+var outputTokenPaths = []string{"max_tokens", "max_completion_tokens", "max_output_tokens", "generationConfig.maxOutputTokens", "request.generationConfig.maxOutputTokens"}
+
+func requestedOutputTokens(rawJSON []byte) int {
+	for _, path := range outputTokenPaths {
+		if value := gjson.GetBytes(rawJSON, path); value.Exists() && value.Int() > 0 {
+			return int(value.Int())
+		}
+	}
+	return 0
+}
+
+// inputTokensContextKey stores the *inputTokenCount of a request in its context.
+type inputTokensContextKey struct{}
+
+// inputTokenCount holds the estimated input tokens of the latest body counted for a request,
+// so that the checks run before execution tokenize each body once.
+type inputTokenCount struct {
+	mu      sync.Mutex
+	counted bool
+	body    []byte
+	tokens  int
+}
+
+// withInputTokenCount returns a context in which requestInputTokens reuses its counts.
+func withInputTokenCount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inputTokensContextKey{}, &inputTokenCount{})
+}
+
+// requestInputTokens returns the estimated input tokens of rawJSON, reusing the count stored in
+// ctx while the body is unchanged. Bodies rewritten by compression or truncation are recounted.
+func requestInputTokens(ctx context.Context, rawJSON []byte) int {
+	var count *inputTokenCount
+	if ctx != nil {
+		count, _ = ctx.Value(inputTokensContextKey{}).(*inputTokenCount)
+	}
+	if count == nil {
+		return estimateInputTokens(rawJSON)
+	}
+	count.mu.Lock()
+	defer count.mu.Unlock()
+	if !count.counted || !bytes.Equal(count.body, rawJSON) {
+		count.counted, count.body, count.tokens = true, rawJSON, estimateInputTokens(rawJSON)
+	}
+	return count.tokens
+}
+
+// estimateInputTokens approximates the prompt size of a request in any client format.
+func estimateInputTokens(rawJSON []byte) int {
+	if !gjson.ValidBytes(rawJSON) {
+		return 0
+	}
+	return estimateTokens(gjson.ParseBytes(rawJSON))
+}
+
+func estimateTokens(value gjson.Result) int {
+	segments := make([]string, 0, 16)
+	collectTextSegments(value, &segments)
+	if len(segments) == 0 {
+		return 0
+	}
+	joined := strings.Join(segments, "\n")
+	overflowCodecOnce.Do(func() {
+		codec, errCodec := tokenizer.Get(tokenizer.O200kBase)
+		if errCodec != nil {
+			log.Warnf("context overflow: tokenizer unavailable, using length estimate: %v", errCodec)
+			return
+		}
+		overflowCodec = codec
+	})
+	if overflowCodec != nil {
+		if count, errCount := overflowCodec.Count(joined); errCount == nil {
+			return count
+		}
+	}
+	return len(joined) / 4
+}
+
+// collectTextSegments gathers the string leaves of a payload, skipping inline binary data.
+func collectTextSegments(value gjson.Result, segments *[]string) {
+	switch {
+	case value.IsObject():
+		value.ForEach(func(key, item gjson.Result) bool {
+			if key.String() == "data" && item.Type == gjson.String {
+				return true
+			}
+			collectTextSegments(item, segments)
+			return true
+		})
+	case value.IsArray():
+		value.ForEach(func(_, item gjson.Result) bool {
+			collectTextSegments(item, segments)
+			return true
+		})
+	case value.Type == gjson.String:
+		if text := value.String(); text != "" && !strings.HasPrefix(text, "data:") {
+			*segments = append(*segments, text)
+		}
+	}
+}
+
+// conversationPath returns the JSON path of the conversation turns for a client format.
+func conversationPath(handlerType string) string {
+	switch handlerType {
+	case constant.Gemini:
+		return "contents"
+	case constant.GeminiCLI:
+		return "request.contents"
+	case constant.OpenaiResponse:
+		return "input"
+	default:
+		return "messages"
+	}
+}
+
+// truncateOldestTurns drops the oldest conversation turns until the payload fits budget.
+// System turns and the latest turn are always kept; the result must not start with an
+// assistant turn or an orphaned tool result. ok is false when no truncation fits.
+func truncateOldestTurns(handlerType string, rawJSON []byte, budget int) ([]byte, bool) {
+	path := conversationPath(handlerType)
+	turns := gjson.GetBytes(rawJSON, path)
+	if !turns.IsArray() {
+		return nil, false
+	}
+	items := turns.Array()
+	if len(items) < 2 {
+		return nil, false
+	}
+	total := estimateInputTokens(rawJSON)
+	costs := make([]int, len(items))
+	base := total
+	for i, item := range items {
+		costs[i] = estimateTokens(item) + perMessageTokenOverhead
+		base -= costs[i] - perMessageTokenOverhead
+	}
+
+	dropped := make([]bool, len(items))
+	used := base
+	for i := range items {
+		used += costs[i]
+	}
+	for i := 0; i < len(items)-1 && used > budget; i++ {
+		if isSystemTurn(items[i]) {
+			continue
+		}
+		dropped[i] = true
+		used -= costs[i]
+	}
+	// The first kept conversational turn must be a user turn that does not answer a dropped tool call.
+	for i := 0; i < len(items)-1; i++ {
+		if dropped[i] || isSystemTurn(items[i]) {
+			continue
+		}
+		if isUserTurn(items[i]) && !isToolResultTurn(items[i]) {
+			break
+		}
+		dropped[i] = true
+		used -= costs[i]
+	}
+	if used > budget {
+		return nil, false
+	}
+
+	kept := make([]string, 0, len(items))
+	for i, item := range items {
+		if !dropped[i] {
+			kept = append(kept, item.Raw)
+		}
+	}
+	updated, errSet := sjson.SetRawBytes(rawJSON, path, []byte("["+strings.Join(kept, ",")+"]"))
+	if errSet != nil {
+		return nil, false
+	}
+	return updated, true
+}
+
+func isSystemTurn(turn gjson.Result) bool {
+	switch turn.Get("role").String() {
+	case "system", "developer":
+		return true
+	}
+	return false
+}
+
+func isUserTurn(turn gjson.Result) bool {
+	role := turn.Get("role").String()
+	if role == "" {
+		// Responses API items without a role are typed items such as function_call.
+		return turn.Get("type").String() == "message"
+	}
+	return role == "user"
+}
+
+func isToolResultTurn(turn gjson.Result) bool {
+	if turn.Get("role").String() == "tool" || turn.Get("type").String() == "function_call_output" {
+		return true
+	}
+	for _, block := range turn.Get("content").Array() {
+		if block.Get("type").String() == "tool_result" {
+			return true
+		}
+	}
+	for _, part := range turn.Get("parts").Array() {
+		if part.Get("functionResponse").Exists() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func registerContextOverflowModels(t *testing.T) {
+	t.Helper()
+	modelRegistry := registry.GetGlobalRegistry()
+	now := time.Now().Unix()
+	modelRegistry.RegisterClient("test-context-overflow", "openai", []*registry.ModelInfo{
+		{ID: "tiny-ctx", Created: now, ContextLength: 200},
+		{ID: "huge-ctx", Created: now, ContextLength: 100000},
+	})
+	t.Cleanup(func() { modelRegistry.UnregisterClient("test-context-overflow") })
+}
+
+func overflowPayload() []byte {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	return []byte(`{"model":"tiny-ctx","messages":[` +
+		`{"role":"system","content":"be brief"},` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","content":"` + filler + `"},` +
+		`{"role":"user","content":"latest question"}]}`)
+}
+
+func TestResolveContextOverflowReject(t *testing.T) {
+	registerContextOverflowModels(t)
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{ContextOverflow: sdkconfig.ContextOverflowConfig{Strategy: "reject"}}, coreauth.NewManager(nil, nil, nil))
+
+	_, _, _, errMsg := h.resolveContextOverflow(context.Background(), "openai", []string{"openai"}, "tiny-ctx", overflowPayload())
+	if errMsg == nil {
+		t.Fatal("expected overflow error")
+	}
+	if errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d", errMsg.StatusCode)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "context_length_exceeded" {
+		t.Fatalf("code = %q", code)
+	}
+}
+
+func TestResolveContextOverflowTruncate(t *testing.T) {
+	registerContextOverflowModels(t)
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{ContextOverflow: sdkconfig.ContextOverflowConfig{Strategy: "truncate"}}, coreauth.NewManager(nil, nil, nil))
+
+	_, model, payload, errMsg := h.resolveContextOverflow(context.Background(), "openai", []string{"openai"}, "tiny-ctx", overflowPayload())
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if model != "tiny-ctx" {
+		t.Fatalf("model = %q", model)
+	}
+	messages := gjson.GetBytes(payload, "messages").Array()
+	if len(messages) != 2 {
+		t.Fatalf("expected system + latest turn, got %s", gjson.GetBytes(payload, "messages").Raw)
+	}
+	if messages[0].Get("role").String() != "system" || messages[1].Get("content").String() != "latest question" {
+		t.Fatalf("unexpected kept turns: %s", gjson.GetBytes(payload, "messages").Raw)
+	}
+}
+
+func TestResolveContextOverflowRoute(t *testing.T) {
+	registerContextOverflowModels(t)
+	cfg := &sdkconfig.SDKConfig{ContextOverflow: sdkconfig.ContextOverflowConfig{
+		Strategy: "route",
+		Siblings: []sdkconfig.ContextOverflowSibling{{Model: "tiny-*", Sibling: "huge-ctx"}},
+	}}
+	h := NewBaseAPIHandlers(cfg, coreauth.NewManager(nil, nil, nil))
+
+	providers, model, _, errMsg := h.resolveContextOverflow(context.Background(), "openai", []string{"openai"}, "tiny-ctx", overflowPayload())
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if model != "huge-ctx" || len(providers) == 0 {
+		t.Fatalf("expected routing to huge-ctx, got model=%q providers=%v", model, providers)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CompactConversation shrinks the conversation in a Claude or OpenAI chat request with the
+// backend's native /responses/compact support and returns the compacted history in the
+// client's own message format.
+//
+// The request is translated to the Responses format, executed with the "responses/compact"
+// alt and the returned output items are converted back. Items without a client-side
+// equivalent, such as encrypted compaction state, are dropped.
+func (h *BaseAPIHandler) CompactConversation(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	body := sdktranslator.TranslateRequest(sdktranslator.FromString(handlerType), sdktranslator.FromString(constant.Codex), modelName, rawJSON, false)
+	body, _ = sjson.DeleteBytes(body, "stream")
+	body, _ = sjson.SetBytes(body, "model", modelName)
+
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, constant.OpenaiResponse, modelName, body, "responses/compact")
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	return compactedConversation(handlerType, modelName, resp), nil
+}
+
+// compactedConversation converts a /responses/compact response into a handlerType payload
+// carrying the compacted messages and token usage.
+func compactedConversation(handlerType, modelName string, resp []byte) []byte {
+	out := []byte(`{"messages":[]}`)
+	dropped := 0
+	for _, item := range gjson.GetBytes(resp, "output").Array() {
+		role := item.Get("role").String()
+		if itemType := item.Get("type").String(); (itemType != "" && itemType != "message") || role == "" {
+			dropped++
+			continue
+		}
+		text := compactItemText(item)
+		if text == "" {
+			continue
+		}
+		if handlerType == constant.Claude {
+			if role != "user" && role != "assistant" {
+				dropped++
+				continue
+			}
+			msg, _ := sjson.SetBytes([]byte(`{"role":"","content":[{"type":"text","text":""}]}`), "role", role)
+			msg, _ = sjson.SetBytes(msg, "content.0.text", text)
+			out, _ = sjson.SetRawBytes(out, "messages.-1", msg)
+			continue
+		}
+		msg, _ := sjson.SetBytes([]byte(`{"role":"","content":""}`), "role", role)
+		msg, _ = sjson.SetBytes(msg, "content", text)
+		out, _ = sjson.SetRawBytes(out, "messages.-1", msg)
+	}
+	if dropped > 0 {
+		log.Debugf("compact conversation: dropped %d output items without a %s equivalent", dropped, handlerType)
+	}
+
+	usage := gjson.GetBytes(resp, "usage")
+	if handlerType == constant.Claude {
+		out, _ = sjson.SetBytes(out, "type", "compaction")
+		out, _ = sjson.SetBytes(out, "model", modelName)
+		if usage.Exists() {
+			out, _ = sjson.SetBytes(out, "usage.input_tokens", usage.Get("input_tokens").Int())
+			out, _ = sjson.SetBytes(out, "usage.output_tokens", usage.Get("output_tokens").Int())
+		}
+		return out
+	}
+	out, _ = sjson.SetBytes(out, "object", "chat.compaction")
+	out, _ = sjson.SetBytes(out, "model", modelName)
+	if usage.Exists() {
+		out, _ = sjson.SetBytes(out, "usage.prompt_tokens", usage.Get("input_tokens").Int())
+		out, _ = sjson.SetBytes(out, "usage.completion_tokens", usage.Get("output_tokens").Int())
+		out, _ = sjson.SetBytes(out, "usage.total_tokens", usage.Get("total_tokens").Int())
+	}
+	return out
+}
+
+// compactItemText joins the text parts of a Responses message item.
+func compactItemText(item gjson.Result) string {
+	content := item.Get("content")
+	if content.Type == gjson.String {
+		return content.String()
+	}
+	parts := make([]string, 0, 1)
+	content.ForEach(func(_, part gjson.Result) bool {
+		switch part.Get("type").String() {
+		case "input_text", "output_text", "text", "summary_text":
+			if text := part.Get("text").String(); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
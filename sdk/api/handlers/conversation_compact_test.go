@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const compactTestResponse = `{"object":"response.compaction","output":[` +
+	`{"type":"message","role":"user","content":[{"type":"input_text","text":"fix the build"}]},` +
+	`{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Summary: build fixed"}]},` +
+	`{"type":"compaction","encrypted_content":"opaque"}],` +
+	`"usage":{"input_tokens":120,"output_tokens":30,"total_tokens":150}}`
+
+type compactTestExecutor struct {
+	alt          string
+	sourceFormat string
+}
+
+func (e *compactTestExecutor) Identifier() string { return "compact-test" }
+
+func (e *compactTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, _ coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.alt = opts.Alt
+	e.sourceFormat = opts.SourceFormat.String()
+	return coreexecutor.Response{Payload: []byte(compactTestResponse)}, nil
+}
+
+func (e *compactTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *compactTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *compactTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *compactTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestCompactConversationClaude(t *testing.T) {
+	executor := &compactTestExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "compact-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "compact-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	out, errMsg := h.CompactConversation(context.Background(), "claude", "compact-model", []byte(`{"model":"compact-model","stream":true,"messages":[{"role":"user","content":"fix the build"}]}`))
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if executor.alt != "responses/compact" || executor.sourceFormat != "openai-response" {
+		t.Fatalf("alt = %q, source format = %q", executor.alt, executor.sourceFormat)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 2 {
+		t.Fatalf("messages = %s", out)
+	}
+	if messages[1].Get("role").String() != "assistant" || messages[1].Get("content.0.text").String() != "Summary: build fixed" {
+		t.Fatalf("unexpected assistant message: %s", messages[1].Raw)
+	}
+	if gjson.GetBytes(out, "usage.input_tokens").Int() != 120 {
+		t.Fatalf("usage not translated: %s", out)
+	}
+}
+
+func TestCompactedConversationOpenAI(t *testing.T) {
+	out := compactedConversation("openai", "m", []byte(compactTestResponse))
+	if got := gjson.GetBytes(out, "messages.0.content").String(); got != "fix the build" {
+		t.Fatalf("first message = %q", got)
+	}
+	if gjson.GetBytes(out, "messages.#").Int() != 2 {
+		t.Fatalf("compaction item should be dropped: %s", out)
+	}
+	if gjson.GetBytes(out, "usage.total_tokens").Int() != 150 || gjson.GetBytes(out, "object").String() != "chat.compaction" {
+		t.Fatalf("unexpected envelope: %s", out)
+	}
+}
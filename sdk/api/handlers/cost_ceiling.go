@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxCostHeader lets a client lower the cost ceiling of a single request.
+const maxCostHeader = "X-Max-Cost"
+
+// budgetExceededError reports a request whose estimated cost exceeds its ceiling.
+type budgetExceededError struct {
+	model     string
+	estimated float64
+	ceiling   float64
+	// estimatedInputTokens is the proxy's estimate, not the provider's count.
+	estimatedInputTokens int
+	maxOutputTokens      int
+}
+
+func (e *budgetExceededError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message":                fmt.Sprintf("The estimated cost of this request (%.6f) exceeds the per-request ceiling of %.6f for model %s. Reduce the input or the requested output tokens.", e.estimated, e.ceiling, e.model),
+			"type":                   "invalid_request_error",
+			"code":                   "budget_exceeded",
+			"estimated_cost":         e.estimated,
+			"max_cost":               e.ceiling,
+			"estimated_input_tokens": e.estimatedInputTokens,
+			"max_output_tokens":      e.maxOutputTokens,
+		},
+	})
+	return string(body)
+}
+
+func (e *budgetExceededError) StatusCode() int { return http.StatusBadRequest }
+
+func (e *budgetExceededError) ProxyGenerated() bool { return true }
+
+// enforceCostCeiling checks the estimated cost of the request against the ceiling that applies
+// to it. With the clamp action the requested output tokens are lowered to fit the ceiling.
+func (h *BaseAPIHandler) enforceCostCeiling(ctx context.Context, handlerType, model string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	if h == nil || h.Cfg == nil {
+		return rawJSON, nil
+	}
+	cc := h.Cfg.CostCeiling
+	ceiling := h.costCeiling(ctx)
+	if ceiling <= 0 {
+		return rawJSON, nil
+	}
+	pricing, ok := pricingFor(cc.Pricing, model)
+	if !ok {
+		return rawJSON, nil
+	}
+
+	inputTokens := requestInputTokens(ctx, rawJSON)
+	outputTokens := requestedOutputTokens(rawJSON)
+	inputCost := float64(inputTokens) * pricing.InputPerMillion / 1e6
+	estimated := inputCost + float64(outputTokens)*pricing.OutputPerMillion/1e6
+	overBudget := &budgetExceededError{model: model, estimated: estimated, ceiling: ceiling, estimatedInputTokens: inputTokens, maxOutputTokens: outputTokens}
+	if cc.Action != config.CostCeilingClamp || pricing.OutputPerMillion <= 0 {
+		if estimated <= ceiling {
+			return rawJSON, nil
+		}
+		return nil, errorMessageFromError(overBudget)
+	}
+
+	// Clamping also caps requests without an explicit output limit, which could otherwise
+	// run up to the model's maximum.
+	allowed := int(math.Floor((ceiling - inputCost) * 1e6 / pricing.OutputPerMillion))
+	if allowed < 1 {
+		return nil, errorMessageFromError(overBudget)
+	}
+	if outputTokens > 0 && outputTokens <= allowed {
+		return rawJSON, nil
+	}
+	updated, errSet := sjson.SetBytes(rawJSON, outputTokensPath(handlerType, rawJSON), allowed)
+	if errSet != nil {
+		return nil, errorMessageFromError(overBudget)
+	}
+	log.Infof("cost ceiling: clamped max output tokens for model %s to %d (ceiling %.6f)", model, allowed, ceiling)
+	return updated, nil
+}
+
+// costCeiling returns the lowest positive ceiling among the operator limit for the client key
+// and the client's X-Max-Cost header, or 0 when neither applies.
+func (h *BaseAPIHandler) costCeiling(ctx context.Context) float64 {
+	cc := h.Cfg.CostCeiling
+	ceiling := cc.MaxCostPerRequest
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	if ginCtx != nil {
+		if limit, ok := config.LookupClientKey(h.Cfg, cc.KeyLimits, clientAPIKeyFromGin(ginCtx)); ok {
+			ceiling = limit
+		}
+	}
+	if ceiling < 0 {
+		ceiling = 0
+	}
+	if ginCtx != nil && ginCtx.Request != nil {
+		if raw := strings.TrimSpace(ginCtx.Request.Header.Get(maxCostHeader)); raw != "" {
+			if requested, err := strconv.ParseFloat(raw, 64); err == nil && requested > 0 && (ceiling <= 0 || requested < ceiling) {
+				ceiling = requested
+			}
+		}
+	}
+	return ceiling
+}
+
+func pricingFor(pricing []config.ModelPricing, model string) (config.ModelPricing, bool) {
+	baseModel := thinking.ParseSuffix(model).ModelName
+	for _, entry := range pricing {
+		if wildcard.Match(entry.Model, model) || wildcard.Match(entry.Model, baseModel) {
+			return entry, true
+		}
+	}
+	return config.ModelPricing{}, false
+}
+
+// outputTokensPath returns the path of the output token limit already present in the payload,
+// or the conventional one for the client format.
+func outputTokensPath(handlerType string, rawJSON []byte) string {
+	for _, path := range outputTokenPaths {
+		if gjson.GetBytes(rawJSON, path).Exists() {
+			return path
+		}
+	}
+	switch handlerType {
+	case constant.Gemini:
+		return "generationConfig.maxOutputTokens"
+	case constant.GeminiCLI:
+		return "request.generationConfig.maxOutputTokens"
+	case constant.OpenaiResponse:
+		return "max_output_tokens"
+	default:
+		return "max_tokens"
+	}
+}
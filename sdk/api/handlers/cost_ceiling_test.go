@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func newCostCeilingTestHandler(action string) *BaseAPIHandler {
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{CostCeiling: sdkconfig.CostCeilingConfig{
+		Action:            action,
+		MaxCostPerRequest: 0.01,
+		KeyLimits:         map[string]float64{"rich-key": 10},
+		Pricing:           []sdkconfig.ModelPricing{{Model: "priced-*", InputPerMillion: 1000, OutputPerMillion: 1000}},
+	}}, coreauth.NewManager(nil, nil, nil))
+}
+
+func TestEnforceCostCeilingReject(t *testing.T) {
+	h := newCostCeilingTestHandler("reject")
+	payload := []byte(`{"messages":[{"role":"user","content":"hello"}],"max_tokens":100}`)
+
+	_, errMsg := h.enforceCostCeiling(catalogTestContext("plain-key"), "openai", "priced-model", payload)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected budget error, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "budget_exceeded" {
+		t.Fatalf("code = %q", code)
+	}
+	if _, errMsg = h.enforceCostCeiling(catalogTestContext("rich-key"), "openai", "priced-model", payload); errMsg != nil {
+		t.Fatalf("key limit should allow request: %v", errMsg.Error)
+	}
+	if _, errMsg = h.enforceCostCeiling(catalogTestContext("plain-key"), "openai", "unpriced-model", payload); errMsg != nil {
+		t.Fatalf("unpriced model should not be checked: %v", errMsg.Error)
+	}
+}
+
+func TestEnforceCostCeilingClamp(t *testing.T) {
+	h := newCostCeilingTestHandler("clamp")
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`)
+
+	out, errMsg := h.enforceCostCeiling(catalogTestContext("plain-key"), "gemini", "priced-model", payload)
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	clamped := gjson.GetBytes(out, "generationConfig.maxOutputTokens").Int()
+	if clamped < 1 || clamped >= 10 {
+		t.Fatalf("maxOutputTokens = %d, want clamped below 10", clamped)
+	}
+
+	huge := []byte(`{"contents":[{"role":"user","parts":[{"text":"` + strings.Repeat("word ", 200) + `"}]}]}`)
+	if _, errMsg = h.enforceCostCeiling(catalogTestContext("plain-key"), "gemini", "priced-model", huge); errMsg == nil {
+		t.Fatalf("input over the ceiling should be rejected")
+	}
+}
+
+func TestCostCeilingReusesTheRequestTokenCount(t *testing.T) {
+	h := newCostCeilingTestHandler("reject")
+	payload := []byte(`{"messages":[{"role":"user","content":"hello"}],"max_tokens":1}`)
+	ctx := withInputTokenCount(catalogTestContext("plain-key"))
+
+	counted := requestInputTokens(ctx, payload)
+	if counted <= 0 {
+		t.Fatalf("counted %d tokens", counted)
+	}
+	// Planting a count shows the ceiling reads the stored count instead of tokenizing again.
+	count := ctx.Value(inputTokensContextKey{}).(*inputTokenCount)
+	count.tokens = 1_000_000
+	_, errMsg := h.enforceCostCeiling(ctx, "openai", "priced-model", payload)
+	if errMsg == nil {
+		t.Fatalf("expected the stored count to exceed the ceiling")
+	}
+	if got := gjson.Get(errMsg.Error.Error(), "error.estimated_input_tokens").Int(); got != 1_000_000 {
+		t.Fatalf("estimated_input_tokens = %d, want the stored count", got)
+	}
+
+	changed := []byte(`{"messages":[{"role":"user","content":"hi"}],"max_tokens":1}`)
+	if _, errMsg = h.enforceCostCeiling(ctx, "openai", "priced-model", changed); errMsg != nil {
+		t.Fatalf("a rewritten body should be recounted: %v", errMsg.Error)
+	}
+}
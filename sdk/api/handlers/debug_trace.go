@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// startDebugTrace starts a debug trace for the request when the client sent the debug header
+// and its API key is listed in debug-trace-keys. The returned trace is nil otherwise.
+func (h *BaseAPIHandler) startDebugTrace(ctx context.Context, c *gin.Context) (context.Context, *debugtrace.Trace) {
+	if h == nil || h.Cfg == nil || len(h.Cfg.DebugTraceKeys) == 0 || c == nil || c.Request == nil {
+		return ctx, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader(debugtrace.Header))) {
+	case "", "0", "false", "off":
+		return ctx, nil
+	}
+	key := clientAPIKeyFromGin(c)
+	if key == "" || (!slices.Contains(h.Cfg.DebugTraceKeys, key) && !slices.Contains(h.Cfg.DebugTraceKeys, "*")) {
+		return ctx, nil
+	}
+	path := c.FullPath()
+	if path == "" && c.Request.URL != nil {
+		path = c.Request.URL.Path
+	}
+	ctx, trace := debugtrace.Start(ctx, logging.GetRequestID(ctx), c.Request.Method, path)
+	if trace == nil {
+		return ctx, nil
+	}
+	ctx = sdktranslator.WithResponseRecorder(ctx, func(rec sdktranslator.ResponseRecord) {
+		trace.Record("translate", rec.From.String()+" -> "+rec.To.String(), map[string]any{
+			"model":    rec.Model,
+			"stream":   rec.Stream,
+			"response": rec.Response,
+			"output":   strings.Join(rec.Output, ""),
+		})
+	})
+	return ctx, trace
+}
+
+// traceClientRequest records the client request as received in the debug trace.
+func traceClientRequest(ctx context.Context, handlerType, modelName string, rawJSON []byte, stream bool) {
+	if !debugtrace.Enabled(ctx) {
+		return
+	}
+	debugtrace.Record(ctx, "request", "client request", map[string]any{
+		"format": handlerType,
+		"model":  modelName,
+		"stream": stream,
+		"body":   rawJSON,
+	})
+}
+
+// traceDispatch records the resolved model, the candidate providers and the payload after
+// the handler-side rewrites, just before it is handed to the auth manager.
+func traceDispatch(ctx context.Context, providers []string, model string, payload []byte) {
+	if !debugtrace.Enabled(ctx) {
+		return
+	}
+	debugtrace.Record(ctx, "request", "dispatch", map[string]any{
+		"model":     model,
+		"providers": strings.Join(providers, ","),
+		"body":      payload,
+	})
+}
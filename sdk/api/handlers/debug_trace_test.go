@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+func TestDebugTraceRequiresPermittedKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{DebugTraceKeys: []string{"trusted"}}, nil)
+
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("Authorization"))
+		ctx := logging.WithRequestID(c.Request.Context(), c.GetHeader(logging.RequestIDHeader))
+		ctx, cancel := handler.GetContextWithCancel(nil, c, ctx)
+		traceClientRequest(ctx, "openai", "gpt-test", []byte(`{"model":"gpt-test"}`), false)
+		sdktranslator.TranslateNonStream(ctx, sdktranslator.FromString("openai"), sdktranslator.FromString("openai"), "gpt-test", nil, nil, []byte(`{}`), nil)
+		c.Status(http.StatusTeapot)
+		cancel()
+	})
+
+	send := func(requestID, key string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set(logging.RequestIDHeader, requestID)
+		req.Header.Set("Authorization", key)
+		req.Header.Set(debugtrace.Header, "1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("trace-denied", "other")
+	if _, ok := debugtrace.Get("trace-denied"); ok {
+		t.Fatal("trace collected for a key not listed in debug-trace-keys")
+	}
+
+	send("trace-allowed", "trusted")
+	trace, ok := debugtrace.Get("trace-allowed")
+	if !ok {
+		t.Fatal("expected a trace for a permitted key")
+	}
+	if trace.Pending || trace.StatusCode != http.StatusTeapot {
+		t.Fatalf("trace not finished: pending=%t status=%d", trace.Pending, trace.StatusCode)
+	}
+	kinds := make(map[string]bool)
+	for _, event := range trace.Events {
+		kinds[event.Kind] = true
+	}
+	if !kinds["request"] || !kinds["translate"] {
+		t.Fatalf("missing events: %+v", trace.Events)
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxNormalizedErrorMessageLength bounds upstream messages copied into normalized errors.
+const maxNormalizedErrorMessageLength = 1024
+
+// proxyGeneratedError is implemented by errors the proxy raises itself with a complete
+// client-facing body, such as budget and rate limit rejections. Their bodies carry nothing
+// from upstream, so they are sent unchanged even when error normalization is enabled.
+type proxyGeneratedError interface {
+	ProxyGenerated() bool
+}
+
+// ErrorResponseBody builds the client-facing error body for the current request.
+// When error normalization is disabled it falls back to BuildErrorResponseBody, which
+// passes upstream JSON through unchanged.
+func (h *BaseAPIHandler) ErrorResponseBody(c *gin.Context, status int, errText string) []byte {
+	if h == nil || h.Cfg == nil || !h.Cfg.ErrorResponses.Normalize {
+		return BuildErrorResponseBody(status, errText)
+	}
+	return BuildNormalizedErrorBody(ErrorFormatFromContext(c), status, errText, h.Cfg.ErrorResponses.HideUpstreamDetail)
+}
+
+// ErrorMessageBody builds the client-facing body for an execution error message,
+// including the retry hint when the upstream or the cooldown scheduler provided one.
+// It is used for terminal stream errors where headers have already been sent.
+func (h *BaseAPIHandler) ErrorMessageBody(c *gin.Context, msg *interfaces.ErrorMessage) []byte {
+	status := http.StatusInternalServerError
+	errText := ""
+	if msg != nil {
+		if msg.StatusCode > 0 {
+			status = msg.StatusCode
+		}
+		if msg.Error != nil {
+			errText = msg.Error.Error()
+		}
+	}
+	if errText == "" {
+		errText = http.StatusText(status)
+	}
+	body := h.executionErrorBody(c, msg, status, errText)
+	if retryAfter, ok := retryAfterFromHeaders(msg); ok {
+		body = ApplyRetryAfterToErrorBody(ErrorFormatFromContext(c), body, retryAfter)
+	}
+	return body
+}
+
+// executionErrorBody builds the client-facing body for an execution error. Only upstream errors
+// are normalized; bodies of errors the proxy generated are kept as they are.
+func (h *BaseAPIHandler) executionErrorBody(c *gin.Context, msg *interfaces.ErrorMessage, status int, errText string) []byte {
+	var generated proxyGeneratedError
+	if msg != nil && errors.As(msg.Error, &generated) && generated.ProxyGenerated() {
+		return BuildErrorResponseBody(status, errText)
+	}
+	return h.ErrorResponseBody(c, status, errText)
+}
+
+// ApplyRetryAfterToErrorBody records a retry hint inside an error body using the client's format:
+// Gemini clients receive a google.rpc.RetryInfo detail, others an error.retry_after field in seconds.
+// Bodies that are not JSON objects with an "error" object are returned unchanged.
+func ApplyRetryAfterToErrorBody(format string, body []byte, retryAfter time.Duration) []byte {
+	if retryAfter <= 0 || !gjson.ValidBytes(body) {
+		return body
+	}
+	errNode := gjson.GetBytes(body, "error")
+	if !errNode.IsObject() {
+		return body
+	}
+	seconds := retryAfterSeconds(retryAfter)
+	switch format {
+	case constant.Gemini, constant.GeminiCLI:
+		for _, detail := range errNode.Get("details").Array() {
+			if strings.HasSuffix(detail.Get("@type").String(), "google.rpc.RetryInfo") {
+				return body
+			}
+		}
+		updated, errSet := sjson.SetBytes(body, "error.details.-1", map[string]any{
+			"@type":      "type.googleapis.com/google.rpc.RetryInfo",
+			"retryDelay": fmt.Sprintf("%ds", seconds),
+		})
+		if errSet != nil {
+			return body
+		}
+		return updated
+	default:
+		if errNode.Get("retry_after").Exists() {
+			return body
+		}
+		updated, errSet := sjson.SetBytes(body, "error.retry_after", seconds)
+		if errSet != nil {
+			return body
+		}
+		return updated
+	}
+}
+
+// retryAfterFromError extracts a provider retry hint from an execution error.
+func retryAfterFromError(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var provider interface{ RetryAfter() *time.Duration }
+	if !errors.As(err, &provider) || provider == nil {
+		return 0, false
+	}
+	retryAfter := provider.RetryAfter()
+	if retryAfter == nil || *retryAfter <= 0 {
+		return 0, false
+	}
+	return *retryAfter, true
+}
+
+// retryAfterFromHeaders reads the Retry-After hint carried by an error message.
+func retryAfterFromHeaders(msg *interfaces.ErrorMessage) (time.Duration, bool) {
+	if msg == nil || msg.Addon == nil {
+		return 0, false
+	}
+	raw := strings.TrimSpace(msg.Addon.Get("Retry-After"))
+	if raw == "" {
+		return 0, false
+	}
+	if seconds, errParse := strconv.Atoi(raw); errParse == nil {
+		if seconds <= 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, errParse := http.ParseTime(raw); errParse == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+	}
+	return 0, false
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// ErrorFormatFromContext resolves the client API format of the current request.
+// It prefers the handler type recorded during execution and falls back to the request path.
+func ErrorFormatFromContext(c *gin.Context) string {
+	if c == nil {
+		return constant.OpenAI
+	}
+	if v, exists := c.Get(monitorRequestTypeKey); exists {
+		if format, ok := v.(string); ok && format != "" {
+			return format
+		}
+	}
+	if c.Request == nil || c.Request.URL == nil {
+		return constant.OpenAI
+	}
+	path := c.Request.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/v1/messages"):
+		return constant.Claude
+	case strings.HasPrefix(path, "/v1internal"):
+		return constant.GeminiCLI
+	case strings.HasPrefix(path, "/v1beta"):
+		return constant.Gemini
+	case strings.HasPrefix(path, "/v1/responses"):
+		return constant.OpenaiResponse
+	default:
+		return constant.OpenAI
+	}
+}
+
+// BuildNormalizedErrorBody maps an upstream error into the error schema of the client format.
+// The upstream message is extracted from provider JSON when possible; when hideDetail is true
+// it is replaced with a generic status description so provider internals never reach clients.
+func BuildNormalizedErrorBody(format string, status int, errText string, hideDetail bool) []byte {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	message := upstreamErrorMessage(errText)
+	if hideDetail || message == "" {
+		message = genericErrorMessage(status)
+	}
+
+	var payload any
+	switch format {
+	case constant.Claude:
+		payload = map[string]any{
+			"type": "error",
+			"error": map[string]any{
+				"type":    claudeErrorType(status),
+				"message": message,
+			},
+		}
+	case constant.Gemini, constant.GeminiCLI:
+		payload = map[string]any{
+			"error": map[string]any{
+				"code":    status,
+				"message": message,
+				"status":  geminiErrorStatus(status),
+			},
+		}
+	default:
+		errType, code := openAIErrorTypeAndCode(status)
+		payload = ErrorResponse{Error: ErrorDetail{Message: message, Type: errType, Code: code}}
+	}
+	body, errMarshal := json.Marshal(payload)
+	if errMarshal != nil {
+		return BuildErrorResponseBody(status, message)
+	}
+	return body
+}
+
+// upstreamErrorMessage extracts a human-readable message from a raw upstream error body.
+func upstreamErrorMessage(errText string) string {
+	trimmed := strings.TrimSpace(errText)
+	if trimmed == "" {
+		return ""
+	}
+	if gjson.Valid(trimmed) {
+		parsed := gjson.Parse(trimmed)
+		if parsed.IsArray() {
+			parsed = parsed.Get("0")
+		}
+		for _, path := range []string{"error.message", "message", "error.error.message", "detail", "error_description", "error"} {
+			if value := parsed.Get(path); value.Exists() && value.Type == gjson.String {
+				if msg := strings.TrimSpace(value.String()); msg != "" {
+					return truncateErrorMessage(msg)
+				}
+			}
+		}
+		return ""
+	}
+	return truncateErrorMessage(trimmed)
+}
+
+func truncateErrorMessage(msg string) string {
+	if len(msg) <= maxNormalizedErrorMessageLength {
+		return msg
+	}
+	return msg[:maxNormalizedErrorMessageLength] + "..."
+}
+
+func genericErrorMessage(status int) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return "Rate limit exceeded, please retry later"
+	case http.StatusUnauthorized:
+		return "Authentication with the upstream provider failed"
+	case http.StatusForbidden, http.StatusPaymentRequired:
+		return "The upstream provider denied the request"
+	case http.StatusNotFound:
+		return "The requested model or resource was not found"
+	}
+	if status >= http.StatusInternalServerError {
+		return "The upstream provider returned an error"
+	}
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return "Request failed"
+}
+
+func openAIErrorTypeAndCode(status int) (string, string) {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error", "invalid_api_key"
+	case http.StatusForbidden, http.StatusPaymentRequired:
+		return "permission_error", "insufficient_quota"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error", "rate_limit_exceeded"
+	case http.StatusNotFound:
+		return "invalid_request_error", "model_not_found"
+	}
+	if status >= http.StatusInternalServerError {
+		return "server_error", "internal_server_error"
+	}
+	return "invalid_request_error", ""
+}
+
+func claudeErrorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden, http.StatusPaymentRequired:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	}
+	if status >= http.StatusInternalServerError {
+		return "api_error"
+	}
+	return "invalid_request_error"
+}
+
+func geminiErrorStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden, http.StatusPaymentRequired:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "ABORTED"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return "DEADLINE_EXCEEDED"
+	case http.StatusNotImplemented:
+		return "UNIMPLEMENTED"
+	}
+	if status >= http.StatusInternalServerError {
+		return "INTERNAL"
+	}
+	return "FAILED_PRECONDITION"
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestBuildNormalizedErrorBodyFormats(t *testing.T) {
+	upstream := `{"error":{"message":"quota exhausted for project 123","type":"insufficient_quota"}}`
+
+	openAI := BuildNormalizedErrorBody("openai", http.StatusTooManyRequests, upstream, false)
+	if got := gjson.GetBytes(openAI, "error.message").String(); got != "quota exhausted for project 123" {
+		t.Fatalf("openai message = %q", got)
+	}
+	if got := gjson.GetBytes(openAI, "error.type").String(); got != "rate_limit_error" {
+		t.Fatalf("openai type = %q", got)
+	}
+
+	claude := BuildNormalizedErrorBody("claude", http.StatusTooManyRequests, upstream, false)
+	if got := gjson.GetBytes(claude, "type").String(); got != "error" {
+		t.Fatalf("claude envelope type = %q", got)
+	}
+	if got := gjson.GetBytes(claude, "error.type").String(); got != "rate_limit_error" {
+		t.Fatalf("claude error type = %q", got)
+	}
+
+	gemini := BuildNormalizedErrorBody("gemini", http.StatusTooManyRequests, upstream, false)
+	if got := gjson.GetBytes(gemini, "error.status").String(); got != "RESOURCE_EXHAUSTED" {
+		t.Fatalf("gemini status = %q", got)
+	}
+	if got := gjson.GetBytes(gemini, "error.code").Int(); got != http.StatusTooManyRequests {
+		t.Fatalf("gemini code = %d", got)
+	}
+}
+
+func TestBuildNormalizedErrorBodyHidesDetail(t *testing.T) {
+	body := BuildNormalizedErrorBody("openai", http.StatusBadGateway, `{"error":{"message":"internal host 10.0.0.5 refused"}}`, true)
+	msg := gjson.GetBytes(body, "error.message").String()
+	if msg != "The upstream provider returned an error" {
+		t.Fatalf("expected generic message, got %q", msg)
+	}
+}
+
+func TestErrorFormatFromContextFallsBackToPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := map[string]string{
+		"/v1/messages":                          "claude",
+		"/v1beta/models/gemini:generateContent": "gemini",
+		"/v1/responses":                         "openai-response",
+		"/v1/chat/completions":                  "openai",
+	}
+	for path, want := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, path, nil)
+		if got := ErrorFormatFromContext(c); got != want {
+			t.Fatalf("path %s: got %q want %q", path, got, want)
+		}
+	}
+}
+
+func TestNormalizationKeepsProxyGeneratedErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{ErrorResponses: sdkconfig.ErrorResponseConfig{Normalize: true, HideUpstreamDetail: true}}}
+
+	write := func(err error) []byte {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+		h.WriteErrorResponse(c, errorMessageFromError(err))
+		return recorder.Body.Bytes()
+	}
+
+	budget := write(&budgetExceededError{model: "claude-opus", estimated: 0.5, ceiling: 0.1, estimatedInputTokens: 1000, maxOutputTokens: 2000})
+	if code := gjson.GetBytes(budget, "error.code").String(); code != "budget_exceeded" {
+		t.Fatalf("budget error code = %q, body %s", code, budget)
+	}
+	if got := gjson.GetBytes(budget, "error.estimated_cost").Float(); got != 0.5 {
+		t.Fatalf("budget error estimated_cost = %v, body %s", got, budget)
+	}
+	if got := gjson.GetBytes(budget, "error.max_cost").Float(); got != 0.1 {
+		t.Fatalf("budget error max_cost = %v, body %s", got, budget)
+	}
+	if got := gjson.GetBytes(budget, "error.estimated_input_tokens").Int(); got != 1000 {
+		t.Fatalf("budget error estimated_input_tokens = %v, body %s", got, budget)
+	}
+
+	upstream := write(errors.New(`{"error":{"message":"internal host 10.0.0.5 refused"}}`))
+	if got := gjson.GetBytes(upstream, "type").String(); got != "error" {
+		t.Fatalf("upstream error not normalized to the claude schema: %s", upstream)
+	}
+	if got := gjson.GetBytes(upstream, "error.message").String(); got == "internal host 10.0.0.5 refused" {
+		t.Fatalf("upstream detail not hidden: %s", upstream)
+	}
+}
+
+type retryAfterTestError struct {
+	retryAfter time.Duration
+}
+
+func (e retryAfterTestError) Error() string              { return "rate limited" }
+func (e retryAfterTestError) StatusCode() int            { return http.StatusTooManyRequests }
+func (e retryAfterTestError) RetryAfter() *time.Duration { return &e.retryAfter }
+
+func TestErrorMessageFromErrorSetsRetryAfter(t *testing.T) {
+	msg := errorMessageFromError(retryAfterTestError{retryAfter: 1500 * time.Millisecond})
+	if msg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d", msg.StatusCode)
+	}
+	if got := msg.Addon.Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After = %q, want 2", got)
+	}
+
+	plain := errorMessageFromError(errors.New("boom"))
+	if plain.Addon != nil && plain.Addon.Get("Retry-After") != "" {
+		t.Fatalf("unexpected Retry-After on plain error")
+	}
+}
+
+func TestApplyRetryAfterToErrorBody(t *testing.T) {
+	openAI := ApplyRetryAfterToErrorBody("openai", []byte(`{"error":{"message":"slow down"}}`), 30*time.Second)
+	if got := gjson.GetBytes(openAI, "error.retry_after").Int(); got != 30 {
+		t.Fatalf("openai retry_after = %d", got)
+	}
+
+	gemini := ApplyRetryAfterToErrorBody("gemini", []byte(`{"error":{"code":429,"details":[]}}`), 30*time.Second)
+	if got := gjson.GetBytes(gemini, "error.details.0.retryDelay").String(); got != "30s" {
+		t.Fatalf("gemini retryDelay = %q", got)
+	}
+	again := ApplyRetryAfterToErrorBody("gemini", gemini, 10*time.Second)
+	if n := len(gjson.GetBytes(again, "error.details").Array()); n != 1 {
+		t.Fatalf("expected existing RetryInfo to be kept, got %d details", n)
+	}
+
+	raw := []byte("upstream says no")
+	if got := ApplyRetryAfterToErrorBody("openai", raw, time.Second); string(got) != string(raw) {
+		t.Fatalf("non-JSON body should be unchanged, got %s", got)
+	}
+}
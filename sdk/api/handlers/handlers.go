@@ -9,14 +9,17 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/mcpclient"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
@@ -76,6 +79,28 @@ const (
 
 var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:\-]+$`)
 
+// requestMetadataFromGin captures the inbound request details executors need, so they do
+// not have to depend on the gin context.
+func requestMetadataFromGin(c *gin.Context) *coreexecutor.RequestMetadata {
+	meta := &coreexecutor.RequestMetadata{ClientKey: clientAPIKeyFromGin(c)}
+	if c == nil || c.Request == nil {
+		return meta
+	}
+	meta.UserAgent = c.Request.UserAgent()
+	meta.Headers = c.Request.Header.Clone()
+	meta.Method = c.Request.Method
+	meta.Path = c.FullPath()
+	if meta.Path == "" && c.Request.URL != nil {
+		meta.Path = c.Request.URL.Path
+	}
+	return meta
+}
+
+// ClientAPIKey returns the client API key the request authenticated with, or "".
+func ClientAPIKey(c *gin.Context) string {
+	return clientAPIKeyFromGin(c)
+}
+
 func clientAPIKeyFromGin(c *gin.Context) string {
 	if c == nil {
 		return ""
@@ -411,6 +436,9 @@ type BaseAPIHandler struct {
 
 	// Cfg holds the current application configuration.
 	Cfg *config.SDKConfig
+
+	// mcpTools connects to the MCP servers whose tools are executed for chat completions clients.
+	mcpTools *mcpclient.Pool
 }
 
 // NewBaseAPIHandlers creates a new API handlers instance.
@@ -423,10 +451,15 @@ type BaseAPIHandler struct {
 // Returns:
 //   - *BaseAPIHandler: A new API handlers instance
 func NewBaseAPIHandlers(cfg *config.SDKConfig, authManager *coreauth.Manager) *BaseAPIHandler {
-	return &BaseAPIHandler{
+	h := &BaseAPIHandler{
 		Cfg:         cfg,
 		AuthManager: authManager,
+		mcpTools:    mcpclient.NewPool(),
+	}
+	if cfg != nil {
+		h.mcpTools.Update(cfg.MCPTools.Servers)
 	}
+	return h
 }
 
 // UpdateClients updates the handlers' client list and configuration.
@@ -435,7 +468,12 @@ func NewBaseAPIHandlers(cfg *config.SDKConfig, authManager *coreauth.Manager) *B
 // Parameters:
 //   - clients: The new slice of AI service clients
 //   - cfg: The new application configuration
-func (h *BaseAPIHandler) UpdateClients(cfg *config.SDKConfig) { h.Cfg = cfg }
+func (h *BaseAPIHandler) UpdateClients(cfg *config.SDKConfig) {
+	h.Cfg = cfg
+	if h.mcpTools != nil && cfg != nil {
+		h.mcpTools.Update(cfg.MCPTools.Servers)
+	}
+}
 
 // GetAlt extracts the 'alt' parameter from the request query string.
 // It checks both 'alt' and '$alt' parameters and returns the appropriate value.
@@ -465,6 +503,14 @@ func (h *BaseAPIHandler) AvailableModelsForRequest(c *gin.Context, handlerType s
 		return nil
 	}
 	clientKey := clientAPIKeyFromGin(c)
+	models := h.availableModelsForClientKey(modelRegistry, clientKey, handlerType)
+	if entries, restricted := h.catalogForClientKey(clientKey); restricted {
+		return applyModelCatalog(models, handlerType, entries)
+	}
+	return models
+}
+
+func (h *BaseAPIHandler) availableModelsForClientKey(modelRegistry *registry.ModelRegistry, clientKey, handlerType string) []map[string]any {
 	if clientKey == "" || h == nil || h.AuthManager == nil {
 		return modelRegistry.GetAvailableModels(handlerType)
 	}
@@ -508,7 +554,12 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
-	newCtx, cancel := context.WithCancel(parentCtx)
+	budgetCtx, stopBudget := withLatencyBudget(parentCtx, c)
+	newCtx, cancelCtx := context.WithCancel(budgetCtx)
+	cancel := func() {
+		cancelCtx()
+		stopBudget()
+	}
 	if requestCtx != nil && requestCtx != parentCtx {
 		go func() {
 			select {
@@ -520,7 +571,12 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 	}
 	newCtx = context.WithValue(newCtx, "gin", c)
 	newCtx = context.WithValue(newCtx, "handler", handler)
+	newCtx = coreexecutor.WithRequestMetadata(newCtx, requestMetadataFromGin(c))
+	newCtx, trace := h.startDebugTrace(newCtx, c)
 	return newCtx, func(params ...interface{}) {
+		if trace != nil {
+			defer func() { trace.Finish(c.Writer.Status()) }()
+		}
 		if h.Cfg.RequestLog && len(params) == 1 {
 			if existing, exists := c.Get("API_RESPONSE"); exists {
 				if existingBytes, ok := existing.([]byte); ok && len(bytes.TrimSpace(existingBytes)) > 0 {
@@ -638,10 +694,49 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	rewriter, ctx := h.newResponseRewriter(ctx, modelName)
+	recorder, ctx := h.newCassetteRecorder(ctx)
+	defer recorder.Flush()
+	ctx = withInputTokenCount(ctx)
+	traceClientRequest(ctx, handlerType, modelName, rawJSON, false)
+	modelName, errMsg := h.resolveCatalogModel(ctx, modelName)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	requestedModel := modelName
+	modelName, rawJSON, errMsg = h.applyRequestScripts(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	providers, ctx, errMsg = h.applyRoutingRules(ctx, providers, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	// Local admission checks run before moderation and compression, which call upstreams.
+	if errMsg = h.checkLocalAdmission(ctx, handlerType, normalizedModel, rawJSON); errMsg != nil {
+		return nil, errMsg
+	}
+	release, errMsg := h.reserveTokenBudget(ctx, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	defer release()
+	if errMsg = h.moderatePrompt(ctx, handlerType, rawJSON); errMsg != nil {
+		return nil, errMsg
+	}
+	rawJSON = h.compressPrompt(ctx, handlerType, normalizedModel, rawJSON)
+	providers, normalizedModel, rawJSON, errMsg = h.resolveContextOverflow(ctx, handlerType, providers, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	rawJSON, errMsg = h.enforceCostCeiling(ctx, handlerType, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
 	sessionID, normalizedRawJSON := resolveSession(ctx, handlerType, rawJSON)
@@ -649,6 +744,7 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		reqMeta[coreexecutor.SessionIDMetadataKey] = sessionID
 		ctx = coreauth.WithSessionID(ctx, sessionID)
 	}
+	normalizedRawJSON = h.dedupSystemPrompt(ctx, handlerType, normalizedModel, sessionID, reqMeta, normalizedRawJSON)
 	updateMonitorRequestContext(ctx, handlerType, normalizedModel, sessionID)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
@@ -661,32 +757,45 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
+	traceDispatch(ctx, providers, normalizedModel, req.Payload)
 	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
-	if err != nil {
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
+	if fallbackModel, ok := h.refusalFallbackModel(normalizedModel); ok {
+		if (err != nil && isContentPolicyError(err)) || (err == nil && isContentPolicyRefusalPayload(resp.Payload)) {
+			if fbResp, attempted, fbErr := h.executeRefusalFallback(ctx, fallbackModel, req, opts); attempted {
+				resp, err = fbResp, fbErr
 			}
 		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
-			}
-		}
-		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
-	return cloneBytes(resp.Payload), nil
+	if err != nil {
+		return nil, errorMessageFromError(err)
+	}
+	resp = h.validateJSONOutput(ctx, handlerType, providers, req, opts, resp)
+	payload, errMsg := h.applyResponseScripts(ctx, handlerType, requestedModel, cloneBytes(resp.Payload))
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	return rewriter.rewrite(payload), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	modelName, errMsg := h.resolveCatalogModel(ctx, modelName)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	modelName, rawJSON, errMsg = h.applyRequestScripts(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	providers, ctx, errMsg = h.applyRoutingRules(ctx, providers, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
 	sessionID, normalizedRawJSON := resolveSession(ctx, handlerType, rawJSON)
@@ -708,32 +817,86 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	opts.Metadata = reqMeta
 	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
-			}
-		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
-			}
-		}
-		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		return nil, errorMessageFromError(err)
 	}
 	return cloneBytes(resp.Payload), nil
 }
 
+// checkLocalAdmission runs the admission checks that need no upstream call, so requests they
+// reject never reach moderation or prompt compression.
+func (h *BaseAPIHandler) checkLocalAdmission(ctx context.Context, handlerType, modelName string, rawJSON []byte) *interfaces.ErrorMessage {
+	if errMsg := h.checkMemoryPressure(ctx, rawJSON); errMsg != nil {
+		return errMsg
+	}
+	if errMsg := h.checkUsageAnomaly(ctx); errMsg != nil {
+		return errMsg
+	}
+	return h.checkRepeatedPrompt(ctx, handlerType, modelName, rawJSON)
+}
+
+// streamError returns the channels of a stream that failed before it started.
+func streamError(errMsg *interfaces.ErrorMessage) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	errChan := make(chan *interfaces.ErrorMessage, 1)
+	errChan <- errMsg
+	close(errChan)
+	return nil, errChan
+}
+
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	rewriter, ctx := h.newResponseRewriter(ctx, modelName)
+	usageInjector, ctx := newStreamUsageInjector(ctx, handlerType, rawJSON)
+	recorder, ctx := h.newCassetteRecorder(ctx)
+	ctx = withInputTokenCount(ctx)
+	traceClientRequest(ctx, handlerType, modelName, rawJSON, true)
+	modelName, errMsg := h.resolveCatalogModel(ctx, modelName)
+	if errMsg != nil {
+		return streamError(errMsg)
+	}
+	modelName, rawJSON, errMsg = h.applyRequestScripts(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return streamError(errMsg)
+	}
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
-		errChan := make(chan *interfaces.ErrorMessage, 1)
-		errChan <- errMsg
-		close(errChan)
-		return nil, errChan
+		return streamError(errMsg)
+	}
+	providers, ctx, errMsg = h.applyRoutingRules(ctx, providers, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return streamError(errMsg)
+	}
+	// Local admission checks run before moderation and compression, which call upstreams.
+	if errMsg = h.checkLocalAdmission(ctx, handlerType, normalizedModel, rawJSON); errMsg != nil {
+		return streamError(errMsg)
+	}
+	releaseStream, errMsg := h.acquireStreamSlot(ctx)
+	if errMsg != nil {
+		return streamError(errMsg)
+	}
+	releaseBudget, errMsg := h.reserveTokenBudget(ctx, rawJSON)
+	if errMsg != nil {
+		releaseStream()
+		return streamError(errMsg)
+	}
+	release := func() {
+		releaseBudget()
+		releaseStream()
+	}
+	if errMsg = h.moderatePrompt(ctx, handlerType, rawJSON); errMsg != nil {
+		release()
+		return streamError(errMsg)
+	}
+	rawJSON = h.compressPrompt(ctx, handlerType, normalizedModel, rawJSON)
+	providers, normalizedModel, rawJSON, errMsg = h.resolveContextOverflow(ctx, handlerType, providers, normalizedModel, rawJSON)
+	if errMsg != nil {
+		release()
+		return streamError(errMsg)
+	}
+	rawJSON, errMsg = h.enforceCostCeiling(ctx, handlerType, normalizedModel, rawJSON)
+	if errMsg != nil {
+		release()
+		return streamError(errMsg)
 	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
@@ -742,6 +905,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		reqMeta[coreexecutor.SessionIDMetadataKey] = sessionID
 		ctx = coreauth.WithSessionID(ctx, sessionID)
 	}
+	normalizedRawJSON = h.dedupSystemPrompt(ctx, handlerType, normalizedModel, sessionID, reqMeta, normalizedRawJSON)
 	updateMonitorRequestContext(ctx, handlerType, normalizedModel, sessionID)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
@@ -754,28 +918,25 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
+	traceDispatch(ctx, providers, normalizedModel, req.Payload)
 	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
-	if err != nil {
-		errChan := make(chan *interfaces.ErrorMessage, 1)
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
-			}
-		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
+	if err != nil && isContentPolicyError(err) {
+		if fallbackModel, ok := h.refusalFallbackModel(normalizedModel); ok {
+			if fbChunks, attempted, fbErr := h.executeRefusalFallbackStream(ctx, fallbackModel, req, opts); attempted {
+				chunks, err = fbChunks, fbErr
 			}
 		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
-		close(errChan)
-		return nil, errChan
 	}
+	if err != nil {
+		release()
+		return streamError(errorMessageFromError(err))
+	}
+	rewriter.applyHeaders()
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 	go func() {
+		defer recorder.Flush()
+		defer release()
 		defer close(dataChan)
 		defer close(errChan)
 		sentPayload := false
@@ -837,6 +998,9 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					chunk, ok = <-chunks
 				}
 				if !ok {
+					if tail := usageInjector.finish(); tail != nil {
+						_ = sendData(rewriter.rewrite(tail))
+					}
 					return
 				}
 				if chunk.Err != nil {
@@ -846,6 +1010,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					if !sentPayload {
 						if bootstrapRetries < maxBootstrapRetries && bootstrapEligible(streamErr) {
 							bootstrapRetries++
+							debugtrace.Record(ctx, "retry", "stream bootstrap retry", map[string]any{"attempt": bootstrapRetries, "error": streamErr.Error()})
 							retryChunks, retryErr := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
 							if retryErr == nil {
 								chunks = retryChunks
@@ -855,24 +1020,12 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 						}
 					}
 
-					status := http.StatusInternalServerError
-					if se, ok := streamErr.(interface{ StatusCode() int }); ok && se != nil {
-						if code := se.StatusCode(); code > 0 {
-							status = code
-						}
-					}
-					var addon http.Header
-					if he, ok := streamErr.(interface{ Headers() http.Header }); ok && he != nil {
-						if hdr := he.Headers(); hdr != nil {
-							addon = hdr.Clone()
-						}
-					}
-					_ = sendErr(&interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon})
+					_ = sendErr(errorMessageFromError(streamErr))
 					return
 				}
 				if len(chunk.Payload) > 0 {
 					sentPayload = true
-					if okSendData := sendData(cloneBytes(chunk.Payload)); !okSendData {
+					if okSendData := sendData(rewriter.rewrite(usageInjector.process(cloneBytes(chunk.Payload)))); !okSendData {
 						return
 					}
 				}
@@ -882,6 +1035,30 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	return dataChan, errChan
 }
 
+// errorMessageFromError converts an execution error into an ErrorMessage carrying the
+// upstream status code, any provider headers, and a Retry-After hint when one is known.
+func errorMessageFromError(err error) *interfaces.ErrorMessage {
+	status := http.StatusInternalServerError
+	if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
+		if code := se.StatusCode(); code > 0 {
+			status = code
+		}
+	}
+	var addon http.Header
+	if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
+		if hdr := he.Headers(); hdr != nil {
+			addon = hdr.Clone()
+		}
+	}
+	if retryAfter, ok := retryAfterFromError(err); ok && (addon == nil || addon.Get("Retry-After") == "") {
+		if addon == nil {
+			addon = make(http.Header)
+		}
+		addon.Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+	}
+	return &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+}
+
 func statusFromError(err error) int {
 	if err == nil {
 		return 0
@@ -958,7 +1135,7 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 	}
 	if msg != nil && msg.Addon != nil {
 		for key, values := range msg.Addon {
-			if len(values) == 0 {
+			if len(values) == 0 || strings.EqualFold(key, logging.RequestIDHeader) {
 				continue
 			}
 			c.Writer.Header().Del(key)
@@ -975,7 +1152,10 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 		}
 	}
 
-	body := BuildErrorResponseBody(status, errText)
+	body := h.executionErrorBody(c, msg, status, errText)
+	if retryAfter, ok := retryAfterFromHeaders(msg); ok {
+		body = ApplyRetryAfterToErrorBody(ErrorFormatFromContext(c), body, retryAfter)
+	}
 	// Append first to preserve upstream response logs, then drop duplicate payloads if already recorded.
 	var previous []byte
 	if existing, exists := c.Get("API_RESPONSE"); exists {
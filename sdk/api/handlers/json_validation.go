@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// JSONValidationHeader reports the outcome of JSON-mode validation: valid, repaired or invalid.
+const JSONValidationHeader = "X-JSON-Validation"
+
+// jsonOutputRequest describes the JSON output a request asked for.
+type jsonOutputRequest struct {
+	// schema is the requested schema; it does not exist for plain JSON mode.
+	schema gjson.Result
+}
+
+// jsonOutputRequested returns the JSON output requested by rawJSON in the client's format.
+func jsonOutputRequested(handlerType string, rawJSON []byte) (jsonOutputRequest, bool) {
+	switch handlerType {
+	case constant.OpenAI:
+		switch gjson.GetBytes(rawJSON, "response_format.type").String() {
+		case "json_object":
+			return jsonOutputRequest{}, true
+		case "json_schema":
+			return jsonOutputRequest{schema: gjson.GetBytes(rawJSON, "response_format.json_schema.schema")}, true
+		}
+	case constant.OpenaiResponse:
+		switch gjson.GetBytes(rawJSON, "text.format.type").String() {
+		case "json_object":
+			return jsonOutputRequest{}, true
+		case "json_schema":
+			return jsonOutputRequest{schema: gjson.GetBytes(rawJSON, "text.format.schema")}, true
+		}
+	case constant.Gemini, constant.GeminiCLI:
+		prefix := ""
+		if handlerType == constant.GeminiCLI {
+			prefix = "request."
+		}
+		generation := gjson.GetBytes(rawJSON, prefix+"generationConfig")
+		if strings.EqualFold(generation.Get("responseMimeType").String(), "application/json") {
+			schema := generation.Get("responseJsonSchema")
+			if !schema.Exists() {
+				schema = generation.Get("responseSchema")
+			}
+			return jsonOutputRequest{schema: schema}, true
+		}
+	}
+	return jsonOutputRequest{}, false
+}
+
+// jsonOutputText returns the text of the first answer in a response in the client's format.
+func jsonOutputText(handlerType string, payload []byte) (string, bool) {
+	switch handlerType {
+	case constant.OpenAI:
+		content := gjson.GetBytes(payload, "choices.0.message.content")
+		return content.String(), content.Type == gjson.String
+	case constant.OpenaiResponse:
+		var parts []string
+		for _, item := range gjson.GetBytes(payload, "output").Array() {
+			for _, content := range item.Get("content").Array() {
+				if content.Get("type").String() == "output_text" {
+					parts = append(parts, content.Get("text").String())
+				}
+			}
+		}
+		return strings.Join(parts, ""), len(parts) > 0
+	case constant.Gemini, constant.GeminiCLI:
+		root := gjson.ParseBytes(payload)
+		if inner := root.Get("response"); inner.IsObject() {
+			root = inner
+		}
+		var parts []string
+		for _, part := range root.Get("candidates.0.content.parts").Array() {
+			if text := part.Get("text"); text.Exists() && !part.Get("thought").Bool() {
+				parts = append(parts, text.String())
+			}
+		}
+		return strings.Join(parts, ""), len(parts) > 0
+	}
+	return "", false
+}
+
+// checkJSONOutput returns the problems of text as output for want; none means it is valid.
+func checkJSONOutput(want jsonOutputRequest, text string) []string {
+	text = strings.TrimSpace(text)
+	if !gjson.Valid(text) {
+		return []string{"the output is not valid JSON"}
+	}
+	if !want.schema.IsObject() {
+		return nil
+	}
+	return util.ValidateJSONSchema(want.schema, gjson.Parse(text))
+}
+
+// jsonValidationApplies reports whether JSON-mode output of ctx's client is validated.
+func (h *BaseAPIHandler) jsonValidationApplies(ctx context.Context) bool {
+	if h == nil || h.Cfg == nil || !h.Cfg.JSONValidation.Enable {
+		return false
+	}
+	keys := h.Cfg.JSONValidation.ClientKeys
+	if len(keys) == 0 {
+		return true
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	return slices.Contains(keys, clientAPIKeyFromGin(ginCtx))
+}
+
+// validateJSONOutput checks a successful non-streaming response of a JSON-mode request against
+// the requested schema. With repair enabled, an invalid answer is sent back to the model once
+// with the problems found, and the repaired response is returned when it validates. The
+// outcome is reported in the JSONValidationHeader.
+func (h *BaseAPIHandler) validateJSONOutput(ctx context.Context, handlerType string, providers []string, req coreexecutor.Request, opts coreexecutor.Options, resp coreexecutor.Response) coreexecutor.Response {
+	if !h.jsonValidationApplies(ctx) {
+		return resp
+	}
+	want, ok := jsonOutputRequested(handlerType, req.Payload)
+	if !ok {
+		return resp
+	}
+	text, ok := jsonOutputText(handlerType, resp.Payload)
+	if !ok {
+		return resp
+	}
+	problems := checkJSONOutput(want, text)
+	if len(problems) == 0 {
+		setJSONValidationHeader(ctx, "valid")
+		return resp
+	}
+	log.Debugf("json validation: %s output of model %s is invalid: %s", handlerType, req.Model, strings.Join(problems, "; "))
+	if h.Cfg.JSONValidation.Repair {
+		if repaired, okRepair := h.repairJSONOutput(ctx, handlerType, providers, req, opts, want, text, problems); okRepair {
+			setJSONValidationHeader(ctx, "repaired")
+			return repaired
+		}
+	}
+	setJSONValidationHeader(ctx, "invalid")
+	return resp
+}
+
+// repairJSONOutput re-asks the model once, quoting its invalid answer and the problems found,
+// and reports whether the new answer validates.
+func (h *BaseAPIHandler) repairJSONOutput(ctx context.Context, handlerType string, providers []string, req coreexecutor.Request, opts coreexecutor.Options, want jsonOutputRequest, text string, problems []string) (coreexecutor.Response, bool) {
+	instruction := fmt.Sprintf("Your previous reply was rejected because it does not match the required JSON output format:\n- %s\nReply again with only the corrected JSON and no other text.", strings.Join(problems, "\n- "))
+	payload, ok := appendJSONRepairTurn(handlerType, req.Payload, text, instruction)
+	if !ok {
+		return coreexecutor.Response{}, false
+	}
+	req.Payload = payload
+	opts.OriginalRequest = cloneBytes(payload)
+	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	if err != nil {
+		log.Debugf("json validation: repair request for model %s failed: %v", req.Model, err)
+		return coreexecutor.Response{}, false
+	}
+	repairedText, ok := jsonOutputText(handlerType, resp.Payload)
+	if !ok {
+		return coreexecutor.Response{}, false
+	}
+	if remaining := checkJSONOutput(want, repairedText); len(remaining) > 0 {
+		log.Debugf("json validation: repaired output of model %s is still invalid: %s", req.Model, strings.Join(remaining, "; "))
+		return coreexecutor.Response{}, false
+	}
+	return resp, true
+}
+
+// appendJSONRepairTurn adds the model's invalid answer and the repair instruction to the
+// conversation of a request in the client's format.
+func appendJSONRepairTurn(handlerType string, rawJSON []byte, answer, instruction string) ([]byte, bool) {
+	var err error
+	out := cloneBytes(rawJSON)
+	switch handlerType {
+	case constant.OpenAI:
+		out, err = sjson.SetBytes(out, "messages.-1", map[string]any{"role": "assistant", "content": answer})
+		if err == nil {
+			out, err = sjson.SetBytes(out, "messages.-1", map[string]any{"role": "user", "content": instruction})
+		}
+	case constant.OpenaiResponse:
+		if input := gjson.GetBytes(out, "input"); input.Type == gjson.String {
+			out, err = sjson.SetBytes(out, "input", []map[string]any{{"role": "user", "content": input.String()}})
+		}
+		if err == nil {
+			out, err = sjson.SetBytes(out, "input.-1", map[string]any{"role": "assistant", "content": answer})
+		}
+		if err == nil {
+			out, err = sjson.SetBytes(out, "input.-1", map[string]any{"role": "user", "content": instruction})
+		}
+	case constant.Gemini, constant.GeminiCLI:
+		path := "contents.-1"
+		if handlerType == constant.GeminiCLI {
+			path = "request.contents.-1"
+		}
+		out, err = sjson.SetBytes(out, path, map[string]any{"role": "model", "parts": []map[string]any{{"text": answer}}})
+		if err == nil {
+			out, err = sjson.SetBytes(out, path, map[string]any{"role": "user", "parts": []map[string]any{{"text": instruction}}})
+		}
+	default:
+		return nil, false
+	}
+	return out, err == nil
+}
+
+func setJSONValidationHeader(ctx context.Context, outcome string) {
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	if ginCtx == nil || ginCtx.Writer == nil || ginCtx.Writer.Written() {
+		return
+	}
+	ginCtx.Header(JSONValidationHeader, outcome)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const jsonValidationTestRequest = `{"model":"gpt-5","messages":[{"role":"user","content":"name a city"}],` +
+	`"response_format":{"type":"json_schema","json_schema":{"name":"city","schema":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"],"additionalProperties":false}}}}`
+
+// sequenceExecutor answers with its contents in turn and records the payloads it received.
+type sequenceExecutor struct {
+	staticResponseExecutor
+	mu       sync.Mutex
+	contents []string
+	payloads [][]byte
+}
+
+func (e *sequenceExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	content, _ := json.Marshal(e.contents[min(len(e.payloads), len(e.contents)-1)])
+	e.payloads = append(e.payloads, req.Payload)
+	return coreexecutor.Response{Payload: []byte(`{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":` + string(content) + `}}]}`)}, nil
+}
+
+func newJSONValidationTestHandler(t *testing.T, repair bool, contents ...string) (*BaseAPIHandler, *sequenceExecutor) {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	exec := &sequenceExecutor{contents: contents}
+	manager.RegisterExecutor(exec)
+	auth := &coreauth.Auth{ID: "json-validation-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gpt-5"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	cfg := &sdkconfig.SDKConfig{JSONValidation: sdkconfig.JSONValidationConfig{Enable: true, Repair: repair}}
+	return NewBaseAPIHandlers(cfg, manager), exec
+}
+
+func TestExecuteWithAuthManager_RepairsInvalidJSONOutput(t *testing.T) {
+	h, exec := newJSONValidationTestHandler(t, true, `{"town":"Paris"}`, `{"city":"Paris"}`)
+
+	ctx, rec := responseRulesTestContext("key-a")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(jsonValidationTestRequest), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != `{"city":"Paris"}` {
+		t.Fatalf("content = %s, want the repaired answer", got)
+	}
+	if got := rec.Header().Get(JSONValidationHeader); got != "repaired" {
+		t.Fatalf("%s = %q, want repaired", JSONValidationHeader, got)
+	}
+	if len(exec.payloads) != 2 {
+		t.Fatalf("calls = %d, want 2", len(exec.payloads))
+	}
+	messages := gjson.GetBytes(exec.payloads[1], "messages").Array()
+	if len(messages) != 3 || messages[1].Get("content").String() != `{"town":"Paris"}` || messages[2].Get("role").String() != "user" {
+		t.Fatalf("repair messages = %s", gjson.GetBytes(exec.payloads[1], "messages").Raw)
+	}
+}
+
+func TestExecuteWithAuthManager_ReportsInvalidJSONOutput(t *testing.T) {
+	h, exec := newJSONValidationTestHandler(t, false, `not json`)
+
+	ctx, rec := responseRulesTestContext("key-a")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(jsonValidationTestRequest), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != "not json" {
+		t.Fatalf("content = %s, want the original answer", got)
+	}
+	if got := rec.Header().Get(JSONValidationHeader); got != "invalid" || len(exec.payloads) != 1 {
+		t.Fatalf("%s = %q after %d calls, want invalid without repair", JSONValidationHeader, got, len(exec.payloads))
+	}
+
+	h.Cfg.JSONValidation.ClientKeys = []string{"key-b"}
+	ctx, rec = responseRulesTestContext("key-a")
+	if _, errMsg = h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(jsonValidationTestRequest), ""); errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if got := rec.Header().Get(JSONValidationHeader); got != "" {
+		t.Fatalf("%s = %q for a key without validation", JSONValidationHeader, got)
+	}
+}
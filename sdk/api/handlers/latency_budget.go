@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// LatencyBudgetHeader lets a client bound the time the proxy spends on a request, including
+// failover retries. It takes a Go duration ("1500ms", "30s") or a number of seconds.
+const LatencyBudgetHeader = "X-CLIProxy-Timeout"
+
+// withLatencyBudget applies the client's latency budget to ctx. Requests without the header,
+// or with an invalid one, keep ctx as is.
+func withLatencyBudget(ctx context.Context, c *gin.Context) (context.Context, context.CancelFunc) {
+	if c == nil || c.Request == nil {
+		return ctx, func() {}
+	}
+	raw := c.GetHeader(LatencyBudgetHeader)
+	if raw == "" {
+		return ctx, func() {}
+	}
+	timeout, ok := parseLatencyBudget(raw)
+	if !ok {
+		log.Debugf("ignoring invalid %s header %q", LatencyBudgetHeader, raw)
+		return ctx, func() {}
+	}
+	return coreauth.WithLatencyBudget(ctx, timeout)
+}
+
+// parseLatencyBudget parses a latency budget header value, which must be positive.
+func parseLatencyBudget(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		timeout := time.Duration(seconds * float64(time.Second))
+		return timeout, timeout > 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false
+	}
+	return timeout, timeout > 0
+}
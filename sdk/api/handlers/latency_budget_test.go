@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLatencyBudget(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"30", 30 * time.Second, true},
+		{" 2.5 ", 2500 * time.Millisecond, true},
+		{"1500ms", 1500 * time.Millisecond, true},
+		{"1m", time.Minute, true},
+		{"0", 0, false},
+		{"-5s", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseLatencyBudget(tc.value)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("parseLatencyBudget(%q) = %v, %v; want %v, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
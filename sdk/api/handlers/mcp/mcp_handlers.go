@@ -0,0 +1,529 @@
+// Package mcp exposes the proxy as a Model Context Protocol server. MCP clients can request
+// completions from the model pool, either through the sampling/createMessage method or the
+// create_message tool, list the models available to their key and, when enabled, inspect the
+// auths and usage behind it. JSON-RPC messages are accepted over streamable HTTP (POST /mcp)
+// and the SSE transport (GET /mcp/sse with POST /mcp/message); stdio clients connect through
+// the "mcp stdio" subcommand, which relays to the HTTP endpoint.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// protocolVersion is the MCP revision answered when the client asks for an unknown one.
+const protocolVersion = "2025-03-26"
+
+// supportedProtocolVersions are the MCP revisions whose client request is echoed back.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+const (
+	// defaultSamplingMaxTokens is used when a sampling request has no maxTokens.
+	defaultSamplingMaxTokens = 1024
+	// sseKeepAliveInterval is how often an idle SSE stream receives a comment line.
+	sseKeepAliveInterval = 25 * time.Second
+)
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// Tool describes an MCP tool.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+
+	// Management tools are only offered when mcp.management-tools is enabled.
+	Management bool `json:"-"`
+}
+
+// ToolFunc runs a tool call for the request in c. A string result is returned as text, any
+// other value as JSON. Errors are reported to the client as a failed tool call.
+type ToolFunc func(c *gin.Context, args gjson.Result) (any, error)
+
+type registeredTool struct {
+	tool Tool
+	fn   ToolFunc
+}
+
+// sseSession is an open SSE stream; responses to the messages posted for it are written to ch.
+type sseSession struct {
+	owner string
+	ch    chan []byte
+}
+
+// MCPAPIHandler serves the Model Context Protocol endpoints.
+type MCPAPIHandler struct {
+	*handlers.BaseAPIHandler
+
+	mu       sync.RWMutex
+	tools    []registeredTool
+	sessions map[string]*sseSession
+}
+
+// NewMCPAPIHandler creates an MCP handler with the built-in tools registered.
+func NewMCPAPIHandler(apiHandlers *handlers.BaseAPIHandler) *MCPAPIHandler {
+	h := &MCPAPIHandler{
+		BaseAPIHandler: apiHandlers,
+		sessions:       make(map[string]*sseSession),
+	}
+	h.registerBuiltinTools()
+	return h
+}
+
+// HandlerType returns the identifier for this handler implementation. Sampling requests are
+// executed as Claude messages.
+func (h *MCPAPIHandler) HandlerType() string {
+	return Claude
+}
+
+// Models returns the models served to MCP clients.
+func (h *MCPAPIHandler) Models() []map[string]any {
+	return registry.GetGlobalRegistry().GetAvailableModels("openai")
+}
+
+// RegisterTool adds a tool, replacing any tool with the same name.
+func (h *MCPAPIHandler) RegisterTool(tool Tool, fn ToolFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tools = slices.DeleteFunc(h.tools, func(t registeredTool) bool { return t.tool.Name == tool.Name })
+	h.tools = append(h.tools, registeredTool{tool: tool, fn: fn})
+}
+
+// HandleMessage handles POST /mcp, the streamable HTTP transport. The body is a JSON-RPC
+// message or batch; responses are returned as JSON, and a body of notifications only is
+// answered with 202 Accepted.
+func (h *MCPAPIHandler) HandleMessage(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, rpcErrorResponse(nil, rpcParseError, err.Error()))
+		return
+	}
+	out, ok := h.dispatch(c, body)
+	if !ok {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// HandleSSE handles GET /mcp/sse, the SSE transport. It announces the message endpoint for
+// the session and streams the responses to the messages posted there.
+func (h *MCPAPIHandler) HandleSSE(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{Error: handlers.ErrorDetail{Message: "Streaming not supported", Type: "server_error"}})
+		return
+	}
+	id := uuid.NewString()
+	session := &sseSession{owner: handlers.ClientAPIKey(c), ch: make(chan []byte, 16)}
+	h.mu.Lock()
+	h.sessions[id] = session
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	_, _ = fmt.Fprintf(c.Writer, "event: endpoint\ndata: %s?session_id=%s\n\n", strings.TrimSuffix(c.Request.URL.Path, "/sse")+"/message", id)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+		case msg := <-session.ch:
+			_, _ = fmt.Fprintf(c.Writer, "event: message\ndata: %s\n\n", msg)
+		}
+		flusher.Flush()
+	}
+}
+
+// HandleSSEMessage handles POST /mcp/message?session_id=..., delivering the responses to the
+// session's SSE stream.
+func (h *MCPAPIHandler) HandleSSEMessage(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	h.mu.RLock()
+	session, ok := h.sessions[c.Query("session_id")]
+	h.mu.RUnlock()
+	if !ok || session.owner != handlers.ClientAPIKey(c) {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{Error: handlers.ErrorDetail{Message: "Unknown MCP session", Type: "invalid_request_error"}})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, rpcErrorResponse(nil, rpcParseError, err.Error()))
+		return
+	}
+	if out, hasResponse := h.dispatch(c, body); hasResponse {
+		select {
+		case session.ch <- out:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *MCPAPIHandler) enabled(c *gin.Context) bool {
+	if h.Cfg != nil && h.Cfg.MCP.Enabled {
+		return true
+	}
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{Error: handlers.ErrorDetail{Message: "MCP server is disabled", Type: "invalid_request_error"}})
+	return false
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) rpcResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// dispatch handles a JSON-RPC message or batch and returns the encoded responses. It reports
+// false when there is nothing to send back because every message was a notification.
+func (h *MCPAPIHandler) dispatch(c *gin.Context, body []byte) ([]byte, bool) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			out, _ := json.Marshal(rpcErrorResponse(nil, rpcParseError, err.Error()))
+			return out, true
+		}
+		responses := make([]rpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp, ok := h.handleRPC(c, raw); ok {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil, false
+		}
+		out, _ := json.Marshal(responses)
+		return out, true
+	}
+	resp, ok := h.handleRPC(c, body)
+	if !ok {
+		return nil, false
+	}
+	out, _ := json.Marshal(resp)
+	return out, true
+}
+
+func (h *MCPAPIHandler) handleRPC(c *gin.Context, raw []byte) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return rpcErrorResponse(nil, rpcParseError, err.Error()), true
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcInvalidRequest, "invalid JSON-RPC 2.0 request"), true
+	}
+	if len(req.ID) == 0 {
+		// Notifications, such as notifications/initialized, need no answer.
+		return rpcResponse{}, false
+	}
+	params := gjson.ParseBytes(req.Params)
+
+	var result any
+	var errRPC *rpcError
+	switch req.Method {
+	case "initialize":
+		result = h.initialize(params)
+	case "ping":
+		result = map[string]any{}
+	case "tools/list":
+		result = map[string]any{"tools": h.listTools()}
+	case "tools/call":
+		result, errRPC = h.callTool(c, params)
+	case "sampling/createMessage":
+		result, errRPC = h.createMessage(c, params)
+	default:
+		errRPC = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	if errRPC != nil {
+		return rpcErrorResponse(req.ID, errRPC.Code, errRPC.Message), true
+	}
+	return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}, true
+}
+
+func (h *MCPAPIHandler) initialize(params gjson.Result) map[string]any {
+	version := protocolVersion
+	if requested := params.Get("protocolVersion").String(); slices.Contains(supportedProtocolVersions, requested) {
+		version = requested
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools": map[string]any{},
+			// Sampling is normally a client capability; here the server offers its model pool.
+			"experimental": map[string]any{"sampling": map[string]any{}},
+		},
+		"serverInfo":   map[string]any{"name": "cli-proxy-api", "version": buildinfo.Version},
+		"instructions": "Use the create_message tool or sampling/createMessage to get completions from the proxy's model pool; list_models shows the models available to your key.",
+	}
+}
+
+func (h *MCPAPIHandler) listTools() []Tool {
+	management := h.Cfg != nil && h.Cfg.MCP.ManagementTools
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	tools := make([]Tool, 0, len(h.tools))
+	for _, registered := range h.tools {
+		if registered.tool.Management && !management {
+			continue
+		}
+		tools = append(tools, registered.tool)
+	}
+	return tools
+}
+
+func (h *MCPAPIHandler) callTool(c *gin.Context, params gjson.Result) (any, *rpcError) {
+	name := params.Get("name").String()
+	management := h.Cfg != nil && h.Cfg.MCP.ManagementTools
+	h.mu.RLock()
+	idx := slices.IndexFunc(h.tools, func(t registeredTool) bool { return t.tool.Name == name })
+	var registered registeredTool
+	if idx >= 0 {
+		registered = h.tools[idx]
+	}
+	h.mu.RUnlock()
+	if idx < 0 || (registered.tool.Management && !management) {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool: %s", name)}
+	}
+
+	value, err := registered.fn(c, params.Get("arguments"))
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	if text, ok := value.(string); ok {
+		return toolResult(text, false), nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+	}
+	return toolResult(string(data), false), nil
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// createMessage serves sampling/createMessage from the model pool. Besides the MCP fields it
+// accepts a "model" that takes precedence over the model hints.
+func (h *MCPAPIHandler) createMessage(c *gin.Context, params gjson.Result) (map[string]any, *rpcError) {
+	if !params.Get("messages").IsArray() {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "messages is required"}
+	}
+	var hints []string
+	for _, hint := range params.Get("modelPreferences.hints").Array() {
+		if name := strings.TrimSpace(hint.Get("name").String()); name != "" {
+			hints = append(hints, name)
+		}
+	}
+	model, err := h.resolveModel(c, strings.TrimSpace(params.Get("model").String()), hints)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	payload, err := samplingPayload(model, params)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), model, payload, "")
+	if errMsg != nil {
+		cliCancel(errMsg.Error)
+		message := http.StatusText(errMsg.StatusCode)
+		if errMsg.Error != nil {
+			message = errMsg.Error.Error()
+		}
+		return nil, &rpcError{Code: rpcInternalError, Message: message}
+	}
+	cliCancel()
+
+	var text strings.Builder
+	for _, block := range gjson.GetBytes(resp, "content").Array() {
+		if block.Get("type").String() == "text" {
+			text.WriteString(block.Get("text").String())
+		}
+	}
+	responseModel := gjson.GetBytes(resp, "model").String()
+	if responseModel == "" {
+		responseModel = model
+	}
+	return map[string]any{
+		"role":       "assistant",
+		"content":    map[string]any{"type": "text", "text": text.String()},
+		"model":      responseModel,
+		"stopReason": samplingStopReason(gjson.GetBytes(resp, "stop_reason").String()),
+	}, nil
+}
+
+// resolveModel picks the model for a sampling request: the explicit model, else the first
+// available model matching a hint (exactly, then as a substring), else mcp.default-model.
+func (h *MCPAPIHandler) resolveModel(c *gin.Context, explicit string, hints []string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	var ids []string
+	for _, model := range h.AvailableModelsForRequest(c, "openai") {
+		if id, ok := model["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	for _, hint := range hints {
+		if slices.Contains(ids, hint) {
+			return hint, nil
+		}
+	}
+	for _, hint := range hints {
+		for _, id := range ids {
+			if strings.Contains(id, hint) {
+				return id, nil
+			}
+		}
+	}
+	if h.Cfg != nil && h.Cfg.MCP.DefaultModel != "" {
+		return h.Cfg.MCP.DefaultModel, nil
+	}
+	return "", fmt.Errorf("no available model matches the model hints; pass a model or set mcp.default-model")
+}
+
+// samplingPayload converts MCP sampling parameters into a Claude messages request. Message
+// content may be a single content block, an array of blocks or a plain string.
+func samplingPayload(model string, params gjson.Result) ([]byte, error) {
+	payload := `{"model":"","max_tokens":0,"messages":[]}`
+	payload, _ = sjson.Set(payload, "model", model)
+	maxTokens := params.Get("maxTokens").Int()
+	if maxTokens <= 0 {
+		maxTokens = defaultSamplingMaxTokens
+	}
+	payload, _ = sjson.Set(payload, "max_tokens", maxTokens)
+	if system := params.Get("systemPrompt").String(); system != "" {
+		payload, _ = sjson.Set(payload, "system", system)
+	}
+	if temperature := params.Get("temperature"); temperature.Exists() {
+		payload, _ = sjson.Set(payload, "temperature", temperature.Float())
+	}
+	if stops := params.Get("stopSequences"); stops.IsArray() && len(stops.Array()) > 0 {
+		payload, _ = sjson.SetRaw(payload, "stop_sequences", stops.Raw)
+	}
+
+	for i, msg := range params.Get("messages").Array() {
+		role := msg.Get("role").String()
+		if role != "user" && role != "assistant" {
+			return nil, fmt.Errorf("messages[%d].role must be user or assistant", i)
+		}
+		content := msg.Get("content")
+		var blocks []gjson.Result
+		switch {
+		case content.Type == gjson.String:
+			blocks = []gjson.Result{gjson.Parse(textBlock(content.String()))}
+		case content.IsArray():
+			blocks = content.Array()
+		case content.IsObject():
+			blocks = []gjson.Result{content}
+		default:
+			return nil, fmt.Errorf("messages[%d].content is required", i)
+		}
+
+		item, _ := sjson.Set(`{"role":"","content":[]}`, "role", role)
+		for _, block := range blocks {
+			switch block.Get("type").String() {
+			case "text":
+				item, _ = sjson.SetRaw(item, "content.-1", textBlock(block.Get("text").String()))
+			case "image":
+				image := `{"type":"image","source":{"type":"base64","media_type":"","data":""}}`
+				image, _ = sjson.Set(image, "source.media_type", block.Get("mimeType").String())
+				image, _ = sjson.Set(image, "source.data", block.Get("data").String())
+				item, _ = sjson.SetRaw(item, "content.-1", image)
+			default:
+				return nil, fmt.Errorf("messages[%d]: unsupported content type %q", i, block.Get("type").String())
+			}
+		}
+		payload, _ = sjson.SetRaw(payload, "messages.-1", item)
+	}
+	return []byte(payload), nil
+}
+
+func textBlock(text string) string {
+	block, _ := sjson.Set(`{"type":"text","text":""}`, "text", text)
+	return block
+}
+
+// samplingStopReason maps a Claude stop_reason to the MCP stopReason.
+func samplingStopReason(reason string) string {
+	switch reason {
+	case "end_turn":
+		return "endTurn"
+	case "max_tokens":
+		return "maxTokens"
+	case "stop_sequence":
+		return "stopSequence"
+	case "tool_use":
+		return "toolUse"
+	}
+	return reason
+}
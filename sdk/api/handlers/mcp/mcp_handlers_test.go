@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type samplingExecutor struct {
+	payloads []string
+}
+
+func (e *samplingExecutor) Identifier() string { return "mcp-test-provider" }
+
+func (e *samplingExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.payloads = append(e.payloads, string(req.Payload))
+	return coreexecutor.Response{Payload: []byte(`{"model":"mcp-model","content":[{"type":"text","text":"hello from the pool"}],"stop_reason":"end_turn"}`)}, nil
+}
+
+func (e *samplingExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *samplingExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *samplingExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *samplingExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newMCPTestRouter(t *testing.T, cfg *sdkconfig.SDKConfig, executor *samplingExecutor) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "mcp-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "mcp-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	h := NewMCPAPIHandler(handlers.NewBaseAPIHandlers(cfg, manager))
+	router := gin.New()
+	router.POST("/mcp", h.HandleMessage)
+	return router
+}
+
+func postMCP(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestMCPDisabled(t *testing.T) {
+	router := newMCPTestRouter(t, &sdkconfig.SDKConfig{}, &samplingExecutor{})
+	if resp := postMCP(router, `{"jsonrpc":"2.0","id":1,"method":"ping"}`); resp.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusNotFound)
+	}
+}
+
+func TestMCPInitializeAndTools(t *testing.T) {
+	router := newMCPTestRouter(t, &sdkconfig.SDKConfig{MCP: sdkconfig.MCPConfig{Enabled: true}}, &samplingExecutor{})
+
+	resp := postMCP(router, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	if got := gjson.Get(resp.Body.String(), "result.protocolVersion").String(); got != "2024-11-05" {
+		t.Fatalf("protocolVersion = %q, body = %s", got, resp.Body.String())
+	}
+
+	if resp = postMCP(router, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.Code != http.StatusAccepted {
+		t.Fatalf("notification status = %d, want %d", resp.Code, http.StatusAccepted)
+	}
+
+	resp = postMCP(router, `[{"jsonrpc":"2.0","id":2,"method":"tools/list"},{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"list_auths"}}]`)
+	body := gjson.Parse(resp.Body.String())
+	names := body.Get(`#(id==2).result.tools.#.name`).String()
+	if !strings.Contains(names, "create_message") || strings.Contains(names, "list_auths") {
+		t.Fatalf("tools = %s, want create_message without management tools", names)
+	}
+	if got := body.Get(`#(id==3).error.code`).Int(); got != rpcInvalidParams {
+		t.Fatalf("list_auths error code = %d, want %d", got, rpcInvalidParams)
+	}
+}
+
+func TestMCPSampling(t *testing.T) {
+	executor := &samplingExecutor{}
+	router := newMCPTestRouter(t, &sdkconfig.SDKConfig{MCP: sdkconfig.MCPConfig{Enabled: true}}, executor)
+
+	resp := postMCP(router, `{"jsonrpc":"2.0","id":1,"method":"sampling/createMessage","params":{
+		"messages":[{"role":"user","content":{"type":"text","text":"hi"}}],
+		"systemPrompt":"be nice","maxTokens":50,"modelPreferences":{"hints":[{"name":"mcp"}]}}}`)
+	result := gjson.Get(resp.Body.String(), "result")
+	if got := result.Get("content.text").String(); got != "hello from the pool" {
+		t.Fatalf("text = %q, body = %s", got, resp.Body.String())
+	}
+	if got := result.Get("stopReason").String(); got != "endTurn" {
+		t.Fatalf("stopReason = %q, want endTurn", got)
+	}
+	if len(executor.payloads) != 1 {
+		t.Fatalf("executor calls = %d, want 1", len(executor.payloads))
+	}
+	payload := gjson.Parse(executor.payloads[0])
+	if payload.Get("model").String() != "mcp-model" || payload.Get("system").String() != "be nice" || payload.Get("max_tokens").Int() != 50 {
+		t.Fatalf("unexpected payload: %s", executor.payloads[0])
+	}
+
+	resp = postMCP(router, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_message","arguments":{"model":"mcp-model","messages":[{"role":"user","content":"hi"}]}}}`)
+	result = gjson.Get(resp.Body.String(), "result")
+	if result.Get("isError").Bool() || result.Get("content.0.text").String() != "hello from the pool" {
+		t.Fatalf("unexpected tool result: %s", resp.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+const createMessageSchema = `{
+  "type": "object",
+  "properties": {
+    "model": {"type": "string", "description": "Model to use; see list_models"},
+    "messages": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "role": {"type": "string", "enum": ["user", "assistant"]},
+          "content": {"type": "string"}
+        },
+        "required": ["role", "content"]
+      }
+    },
+    "systemPrompt": {"type": "string"},
+    "maxTokens": {"type": "integer"},
+    "temperature": {"type": "number"},
+    "stopSequences": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["messages"]
+}`
+
+// authSummary is the list_auths view of an auth.
+type authSummary struct {
+	ID             string `json:"id"`
+	Provider       string `json:"provider"`
+	Label          string `json:"label,omitempty"`
+	Status         string `json:"status"`
+	StatusMessage  string `json:"status_message,omitempty"`
+	Disabled       bool   `json:"disabled"`
+	Unavailable    bool   `json:"unavailable"`
+	QuotaExceeded  bool   `json:"quota_exceeded"`
+	NextRetryAfter string `json:"next_retry_after,omitempty"`
+}
+
+func (h *MCPAPIHandler) registerBuiltinTools() {
+	h.RegisterTool(Tool{
+		Name:        "create_message",
+		Description: "Generate a reply from a model in the proxy's pool. Returns the reply text.",
+		InputSchema: json.RawMessage(createMessageSchema),
+	}, h.createMessageTool)
+	h.RegisterTool(Tool{
+		Name:        "list_models",
+		Description: "List the models available to this API key.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+	}, h.listModelsTool)
+	h.RegisterTool(Tool{
+		Name:        "list_auths",
+		Description: "List the upstream accounts this API key may use, with their status.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		Management:  true,
+	}, h.listAuthsTool)
+}
+
+func (h *MCPAPIHandler) createMessageTool(c *gin.Context, args gjson.Result) (any, error) {
+	result, errRPC := h.createMessage(c, args)
+	if errRPC != nil {
+		return nil, errors.New(errRPC.Message)
+	}
+	content, _ := result["content"].(map[string]any)
+	text, _ := content["text"].(string)
+	return text, nil
+}
+
+func (h *MCPAPIHandler) listModelsTool(c *gin.Context, _ gjson.Result) (any, error) {
+	ids := make([]string, 0)
+	for _, model := range h.AvailableModelsForRequest(c, "openai") {
+		if id, ok := model["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return map[string]any{"models": ids}, nil
+}
+
+func (h *MCPAPIHandler) listAuthsTool(c *gin.Context, _ gjson.Result) (any, error) {
+	if h.AuthManager == nil {
+		return nil, errors.New("auth manager unavailable")
+	}
+	allowed, restricted := h.AuthManager.AllowedAuthIDsForClientKey(handlers.ClientAPIKey(c))
+	auths := make([]authSummary, 0)
+	for _, auth := range h.AuthManager.List() {
+		if restricted {
+			if _, ok := allowed[auth.ID]; !ok {
+				continue
+			}
+		}
+		summary := authSummary{
+			ID:            auth.ID,
+			Provider:      auth.Provider,
+			Label:         auth.Label,
+			Status:        string(auth.Status),
+			StatusMessage: auth.StatusMessage,
+			Disabled:      auth.Disabled,
+			Unavailable:   auth.Unavailable,
+			QuotaExceeded: auth.Quota.Exceeded,
+		}
+		if !auth.NextRetryAfter.IsZero() {
+			summary.NextRetryAfter = auth.NextRetryAfter.UTC().Format(time.RFC3339)
+		}
+		auths = append(auths, summary)
+	}
+	sort.Slice(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+	return map[string]any{"restricted": restricted, "auths": auths}, nil
+}
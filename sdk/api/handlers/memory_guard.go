@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime/metrics"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMemoryGuardLargeRequestKB = 256
+	defaultMemoryGuardRetryAfter     = 10 * time.Second
+	memorySampleInterval             = time.Second
+)
+
+// Memory pressure levels.
+const (
+	memoryPressureNone = iota
+	memoryPressureSoft
+	memoryPressureHard
+)
+
+// memorySampler caches the memory in use by the process, reading it at most once per interval.
+type memorySampler struct {
+	mu       sync.Mutex
+	read     func() uint64
+	at       time.Time
+	bytes    uint64
+	pressure int
+}
+
+var processMemory = &memorySampler{read: readProcessMemory}
+
+func (s *memorySampler) sample(now time.Time) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.at.IsZero() || now.Sub(s.at) >= memorySampleInterval {
+		s.bytes = s.read()
+		s.at = now
+	}
+	return s.bytes
+}
+
+// setPressure records the pressure level and reports whether it changed.
+func (s *memorySampler) setPressure(level int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.pressure != level
+	s.pressure = level
+	return changed
+}
+
+// readProcessMemory returns the resident set size of the process, or the memory obtained from
+// the OS by the Go runtime where the RSS cannot be read.
+func readProcessMemory() uint64 {
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 1 {
+			if pages, errParse := strconv.ParseUint(fields[1], 10, 64); errParse == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		return sample[0].Value.Uint64()
+	}
+	return 0
+}
+
+// memoryPressureError reports a request shed because the process is short of memory.
+type memoryPressureError struct {
+	retryAfter time.Duration
+}
+
+func (e *memoryPressureError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": "The server is under memory pressure and is not accepting this request right now. Retry shortly.",
+			"type":    "server_error",
+			"code":    "server_overloaded",
+		},
+	})
+	return string(body)
+}
+
+func (e *memoryPressureError) StatusCode() int { return http.StatusServiceUnavailable }
+
+func (e *memoryPressureError) ProxyGenerated() bool { return true }
+
+func (e *memoryPressureError) RetryAfter() *time.Duration { return &e.retryAfter }
+
+// checkMemoryPressure sheds new requests while the process uses a lot of memory, so in-flight
+// streams survive instead of the whole process being killed. Above the soft limit large
+// requests are rejected; above the hard limit every request is. Priority keys are never shed.
+func (h *BaseAPIHandler) checkMemoryPressure(ctx context.Context, rawJSON []byte) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || !h.Cfg.MemoryGuard.Enabled {
+		return nil
+	}
+	cfg := h.Cfg.MemoryGuard
+	if cfg.SoftLimitMB <= 0 && cfg.HardLimitMB <= 0 {
+		return nil
+	}
+	used := processMemory.sample(time.Now())
+	usedMB := int(used >> 20)
+	level := memoryPressureNone
+	switch {
+	case cfg.HardLimitMB > 0 && usedMB >= cfg.HardLimitMB:
+		level = memoryPressureHard
+	case cfg.SoftLimitMB > 0 && usedMB >= cfg.SoftLimitMB:
+		level = memoryPressureSoft
+	}
+	if processMemory.setPressure(level) {
+		switch level {
+		case memoryPressureHard:
+			log.Warnf("memory guard: %d MB in use, shedding all non-priority requests", usedMB)
+		case memoryPressureSoft:
+			log.Warnf("memory guard: %d MB in use, shedding large non-priority requests", usedMB)
+		default:
+			log.Infof("memory guard: %d MB in use, accepting all requests again", usedMB)
+		}
+	}
+	if level == memoryPressureNone {
+		return nil
+	}
+	if ginCtx, _ := ctx.Value("gin").(*gin.Context); ginCtx != nil {
+		if key := clientAPIKeyFromGin(ginCtx); key != "" && slices.Contains(cfg.PriorityKeys, key) {
+			return nil
+		}
+	}
+	if level == memoryPressureSoft {
+		largeKB := cfg.LargeRequestKB
+		if largeKB <= 0 {
+			largeKB = defaultMemoryGuardLargeRequestKB
+		}
+		if len(rawJSON) < largeKB<<10 {
+			return nil
+		}
+	}
+	retryAfter := time.Duration(cfg.RetryAfterSeconds) * time.Second
+	if retryAfter <= 0 {
+		retryAfter = defaultMemoryGuardRetryAfter
+	}
+	return errorMessageFromError(&memoryPressureError{retryAfter: retryAfter})
+}
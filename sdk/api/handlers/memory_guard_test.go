@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestCheckMemoryPressureShedsRequests(t *testing.T) {
+	var usedMB uint64
+	previous := processMemory
+	processMemory = &memorySampler{read: func() uint64 { return usedMB << 20 }}
+	defer func() { processMemory = previous }()
+
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{MemoryGuard: sdkconfig.MemoryGuardConfig{
+		Enabled:           true,
+		SoftLimitMB:       500,
+		HardLimitMB:       800,
+		LargeRequestKB:    1,
+		PriorityKeys:      []string{"memory-priority-key"},
+		RetryAfterSeconds: 7,
+	}}, coreauth.NewManager(nil, nil, nil))
+	small := []byte(`{"messages":[]}`)
+	large := append([]byte(`{"input":"`), append(bytes.Repeat([]byte("x"), 2048), []byte(`"}`)...)...)
+	check := func(key string, body []byte) int {
+		processMemory.at = time.Time{}
+		if errMsg := h.checkMemoryPressure(catalogTestContext(key), body); errMsg != nil {
+			if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "server_overloaded" {
+				t.Fatalf("code = %q", code)
+			}
+			if errMsg.Addon.Get("Retry-After") != "7" {
+				t.Fatalf("Retry-After = %q", errMsg.Addon.Get("Retry-After"))
+			}
+			return errMsg.StatusCode
+		}
+		return http.StatusOK
+	}
+
+	usedMB = 100
+	if got := check("memory-key", large); got != http.StatusOK {
+		t.Fatalf("below the soft limit: %d", got)
+	}
+	usedMB = 600
+	if got := check("memory-key", small); got != http.StatusOK {
+		t.Fatalf("small request above the soft limit: %d", got)
+	}
+	if got := check("memory-key", large); got != http.StatusServiceUnavailable {
+		t.Fatalf("large request above the soft limit: %d", got)
+	}
+	usedMB = 900
+	if got := check("memory-key", small); got != http.StatusServiceUnavailable {
+		t.Fatalf("small request above the hard limit: %d", got)
+	}
+	if got := check("memory-priority-key", large); got != http.StatusOK {
+		t.Fatalf("priority key above the hard limit: %d", got)
+	}
+}
+
+func TestMemoryPressureRejectsBeforeModeration(t *testing.T) {
+	previous := processMemory
+	processMemory = &memorySampler{read: func() uint64 { return 900 << 20 }}
+	defer func() { processMemory = previous }()
+
+	backend, inputs := newModerationBackend(t)
+	h := newRequestScriptsTestHandler(t, nil)
+	h.Cfg.MemoryGuard = sdkconfig.MemoryGuardConfig{Enabled: true, SoftLimitMB: 500, HardLimitMB: 800}
+	h.Cfg.Moderation = sdkconfig.ModerationConfig{BaseURL: backend.URL, APIKey: "mod-key", Action: "reject"}
+	payload := []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hello"}]}`)
+
+	ctx, _ := responseRulesTestContext("memory-key")
+	if _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", payload, ""); errMsg == nil || errMsg.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the memory guard to reject, got %v", errMsg)
+	}
+	ctx, _ = responseRulesTestContext("memory-key")
+	_, errChan := h.ExecuteStreamWithAuthManager(ctx, "openai", "gpt-5", payload, "")
+	if errMsg := <-errChan; errMsg == nil || errMsg.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the memory guard to reject the stream, got %v", errMsg)
+	}
+	if len(*inputs) != 0 {
+		t.Fatalf("moderation called for rejected requests: %q", *inputs)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+)
+
+// catalogForClientKey returns the catalog entries assigned to a client API key.
+// restricted is false when the key has no catalog assignment. A key assigned to an
+// unknown catalog is restricted with no entries, so it can neither list nor use models.
+// Share links limited to a model behave like a catalog holding only that model.
+func (h *BaseAPIHandler) catalogForClientKey(clientKey string) (entries []config.ModelCatalogEntry, restricted bool) {
+	if h == nil || h.Cfg == nil || clientKey == "" {
+		return nil, false
+	}
+	if link, ok := h.Cfg.ShareLink(clientKey); ok && link.Model != "" {
+		return []config.ModelCatalogEntry{{Name: link.Model}}, true
+	}
+	if len(h.Cfg.APIKeyCatalogs) == 0 {
+		return nil, false
+	}
+	name, ok := config.LookupClientKey(h.Cfg, h.Cfg.APIKeyCatalogs, clientKey)
+	if !ok {
+		return nil, false
+	}
+	for i := range h.Cfg.ModelCatalogs {
+		if strings.EqualFold(h.Cfg.ModelCatalogs[i].Name, name) {
+			return h.Cfg.ModelCatalogs[i].Models, true
+		}
+	}
+	return nil, true
+}
+
+// resolveCatalogModel maps a client-visible model name to the underlying model for keys
+// bound to a catalog, rejecting models outside the catalog. Thinking suffixes are preserved.
+func (h *BaseAPIHandler) resolveCatalogModel(ctx context.Context, modelName string) (string, *interfaces.ErrorMessage) {
+	if ctx == nil {
+		return modelName, nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	entries, restricted := h.catalogForClientKey(clientAPIKeyFromGin(ginCtx))
+	if !restricted {
+		return modelName, nil
+	}
+	if resolved, ok := catalogModelFor(entries, modelName); ok {
+		return resolved, nil
+	}
+	return "", &interfaces.ErrorMessage{StatusCode: http.StatusNotFound, Error: fmt.Errorf("model %s is not available for this API key", modelName)}
+}
+
+func catalogModelFor(entries []config.ModelCatalogEntry, modelName string) (string, bool) {
+	parsed := thinking.ParseSuffix(strings.TrimSpace(modelName))
+	base := parsed.ModelName
+	for _, entry := range entries {
+		if entry.Alias != "" {
+			if !strings.EqualFold(entry.Alias, base) {
+				continue
+			}
+			if parsed.HasSuffix {
+				return fmt.Sprintf("%s(%s)", entry.Name, parsed.RawSuffix), true
+			}
+			return entry.Name, true
+		}
+		if wildcard.Match(entry.Name, base) && !catalogAliased(entries, base) {
+			return modelName, true
+		}
+	}
+	return "", false
+}
+
+// catalogAliased reports whether the catalog exposes a model only under an alias, in
+// which case its original name must not be reachable through wildcard entries.
+func catalogAliased(entries []config.ModelCatalogEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.Alias != "" && strings.EqualFold(entry.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogPermits reports whether the caller's catalog allows a model the proxy picked on
+// its behalf, such as a refusal fallback, overflow sibling or script reroute. The model is
+// an upstream name, so aliased entries match on their underlying name.
+func (h *BaseAPIHandler) catalogPermits(ctx context.Context, model string) bool {
+	if ctx == nil {
+		return true
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	entries, restricted := h.catalogForClientKey(clientAPIKeyFromGin(ginCtx))
+	if !restricted {
+		return true
+	}
+	base := thinking.ParseSuffix(strings.TrimSpace(model)).ModelName
+	for _, entry := range entries {
+		if entry.Alias != "" {
+			if strings.EqualFold(entry.Name, base) {
+				return true
+			}
+			continue
+		}
+		if wildcard.Match(entry.Name, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyModelCatalog filters a model listing down to the catalog and applies its renames.
+func applyModelCatalog(models []map[string]any, handlerType string, entries []config.ModelCatalogEntry) []map[string]any {
+	out := make([]map[string]any, 0, len(models))
+	seen := make(map[string]struct{}, len(models))
+	add := func(id string, model map[string]any) {
+		key := strings.ToLower(id)
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+		out = append(out, model)
+	}
+	for _, model := range models {
+		id := catalogListingID(handlerType, model)
+		if id == "" {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Alias != "" {
+				if strings.EqualFold(entry.Name, id) {
+					add(entry.Alias, renameCatalogModel(handlerType, model, entry.Alias))
+				}
+				continue
+			}
+			if wildcard.Match(entry.Name, id) && !catalogAliased(entries, id) {
+				add(id, model)
+			}
+		}
+	}
+	return out
+}
+
+// catalogListingID returns the model ID of a registry listing entry for the handler format.
+func catalogListingID(handlerType string, model map[string]any) string {
+	if handlerType == "gemini" {
+		name, _ := model["name"].(string)
+		return strings.TrimPrefix(name, "models/")
+	}
+	id, _ := model["id"].(string)
+	return id
+}
+
+func renameCatalogModel(handlerType string, model map[string]any, alias string) map[string]any {
+	renamed := make(map[string]any, len(model))
+	for k, v := range model {
+		renamed[k] = v
+	}
+	for _, key := range []string{"display_name", "displayName"} {
+		if _, exists := renamed[key]; exists {
+			renamed[key] = alias
+		}
+	}
+	if handlerType == "gemini" {
+		if name, _ := model["name"].(string); strings.HasPrefix(name, "models/") {
+			alias = "models/" + alias
+		}
+		renamed["name"] = alias
+		return renamed
+	}
+	renamed["id"] = alias
+	return renamed
+}
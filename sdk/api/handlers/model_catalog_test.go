@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func newCatalogTestHandler() *BaseAPIHandler {
+	return &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{
+		ModelCatalogs: []sdkconfig.ModelCatalog{{
+			Name: "team-a",
+			Models: []sdkconfig.ModelCatalogEntry{
+				{Name: "gemini-2.5-pro", Alias: "team-large"},
+				{Name: "claude-*"},
+			},
+		}},
+		APIKeyCatalogs: map[string]string{"key-a": "team-a", "key-missing": "unknown"},
+	}}
+}
+
+func catalogTestContext(clientKey string) context.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if clientKey != "" {
+		c.Set("apiKey", clientKey)
+	}
+	return context.WithValue(context.Background(), "gin", c)
+}
+
+func TestResolveCatalogModel(t *testing.T) {
+	h := newCatalogTestHandler()
+
+	if got, errMsg := h.resolveCatalogModel(catalogTestContext("key-a"), "team-large(high)"); errMsg != nil || got != "gemini-2.5-pro(high)" {
+		t.Fatalf("expected alias to resolve with suffix, got %q err=%v", got, errMsg)
+	}
+	if got, errMsg := h.resolveCatalogModel(catalogTestContext("key-a"), "claude-sonnet-4"); errMsg != nil || got != "claude-sonnet-4" {
+		t.Fatalf("expected wildcard entry to pass through, got %q err=%v", got, errMsg)
+	}
+	for _, model := range []string{"gemini-2.5-pro", "gpt-5"} {
+		if _, errMsg := h.resolveCatalogModel(catalogTestContext("key-a"), model); errMsg == nil || errMsg.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected %s to be rejected for catalog key, got %v", model, errMsg)
+		}
+	}
+	if _, errMsg := h.resolveCatalogModel(catalogTestContext("key-missing"), "claude-sonnet-4"); errMsg == nil {
+		t.Fatal("expected key assigned to unknown catalog to be denied")
+	}
+	if got, errMsg := h.resolveCatalogModel(catalogTestContext("key-other"), "gpt-5"); errMsg != nil || got != "gpt-5" {
+		t.Fatalf("expected unassigned key to be unrestricted, got %q err=%v", got, errMsg)
+	}
+}
+
+func TestApplyModelCatalogFiltersAndRenames(t *testing.T) {
+	entries := newCatalogTestHandler().Cfg.ModelCatalogs[0].Models
+
+	openai := applyModelCatalog([]map[string]any{
+		{"id": "gemini-2.5-pro", "display_name": "Gemini 2.5 Pro"},
+		{"id": "claude-sonnet-4"},
+		{"id": "gpt-5"},
+	}, "openai", entries)
+	if len(openai) != 2 || openai[0]["id"] != "team-large" || openai[0]["display_name"] != "team-large" || openai[1]["id"] != "claude-sonnet-4" {
+		t.Fatalf("unexpected openai listing: %v", openai)
+	}
+
+	gemini := applyModelCatalog([]map[string]any{
+		{"name": "models/gemini-2.5-pro"},
+		{"name": "gpt-5"},
+	}, "gemini", entries)
+	if len(gemini) != 1 || gemini[0]["name"] != "models/team-large" {
+		t.Fatalf("unexpected gemini listing: %v", gemini)
+	}
+}
+
+func TestResolveCatalogModelShareLink(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{ShareLinks: []sdkconfig.ShareLink{
+		{Key: "share-gpt", Model: "gpt-5*"},
+		{Key: "share-any"},
+	}}}
+
+	if got, errMsg := h.resolveCatalogModel(catalogTestContext("share-gpt"), "gpt-5-codex"); errMsg != nil || got != "gpt-5-codex" {
+		t.Fatalf("expected share link model to pass, got %q err=%v", got, errMsg)
+	}
+	if _, errMsg := h.resolveCatalogModel(catalogTestContext("share-gpt"), "claude-sonnet-4"); errMsg == nil {
+		t.Fatal("expected other models to be rejected for a model-scoped share link")
+	}
+	if _, errMsg := h.resolveCatalogModel(catalogTestContext("share-any"), "claude-sonnet-4"); errMsg != nil {
+		t.Fatalf("expected share link without model to be unrestricted, got %v", errMsg)
+	}
+}
+
+func TestCatalogAliasHidesOriginalName(t *testing.T) {
+	entries := []sdkconfig.ModelCatalogEntry{
+		{Name: "claude-opus-4", Alias: "team-opus"},
+		{Name: "claude-*"},
+	}
+	if _, ok := catalogModelFor(entries, "claude-opus-4"); ok {
+		t.Fatal("expected aliased model to be unreachable under its original name")
+	}
+	if got, ok := catalogModelFor(entries, "claude-sonnet-4"); !ok || got != "claude-sonnet-4" {
+		t.Fatalf("expected other wildcard models to pass, got %q ok=%v", got, ok)
+	}
+	listing := applyModelCatalog([]map[string]any{{"id": "claude-opus-4"}, {"id": "claude-sonnet-4"}}, "openai", entries)
+	if len(listing) != 2 || listing[0]["id"] != "team-opus" || listing[1]["id"] != "claude-sonnet-4" {
+		t.Fatalf("unexpected listing: %v", listing)
+	}
+}
+
+func TestCatalogPermitsReroutedModels(t *testing.T) {
+	h := newCatalogTestHandler()
+	h.Cfg.ShareLinks = []sdkconfig.ShareLink{{Key: "share-gpt", Model: "gpt-5*"}}
+
+	if !h.catalogPermits(catalogTestContext("key-a"), "gemini-2.5-pro(high)") {
+		t.Fatal("expected the underlying name of an aliased entry to be permitted")
+	}
+	if !h.catalogPermits(catalogTestContext("key-a"), "claude-sonnet-4") {
+		t.Fatal("expected wildcard entry to permit rerouted model")
+	}
+	if h.catalogPermits(catalogTestContext("key-a"), "gpt-5") {
+		t.Fatal("expected model outside the catalog to be refused")
+	}
+	if h.catalogPermits(catalogTestContext("share-gpt"), "claude-sonnet-4") {
+		t.Fatal("expected share link model limit to apply to rerouted models")
+	}
+	if !h.catalogPermits(catalogTestContext("key-other"), "gpt-5") {
+		t.Fatal("expected unassigned key to be unrestricted")
+	}
+}
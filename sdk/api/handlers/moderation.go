@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// moderationTimeout bounds pre-flight moderation calls so a slow backend cannot stall requests.
+const moderationTimeout = 10 * time.Second
+
+// moderationRejectedError reports a prompt rejected by the pre-flight moderation check.
+type moderationRejectedError struct {
+	categories []string
+}
+
+func (e *moderationRejectedError) Error() string {
+	message := "The request was rejected by content moderation."
+	if len(e.categories) > 0 {
+		message = fmt.Sprintf("The request was rejected by content moderation (categories: %s).", strings.Join(e.categories, ", "))
+	}
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    "invalid_request_error",
+			"code":    "content_policy_violation",
+		},
+	})
+	return string(body)
+}
+
+func (e *moderationRejectedError) StatusCode() int { return http.StatusBadRequest }
+
+func (e *moderationRejectedError) ProxyGenerated() bool { return true }
+
+// ForwardModeration passes an OpenAI moderation request to the configured moderation backend
+// and returns the backend's status code and body unchanged.
+func (h *BaseAPIHandler) ForwardModeration(ctx context.Context, rawJSON []byte) (int, []byte, *interfaces.ErrorMessage) {
+	if h == nil || h.Cfg == nil || h.Cfg.Moderation.BaseURL == "" {
+		return 0, nil, &interfaces.ErrorMessage{StatusCode: http.StatusNotFound, Error: fmt.Errorf("moderation backend is not configured")}
+	}
+	status, body, err := h.callModeration(ctx, rawJSON, 0)
+	if err != nil {
+		return 0, nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: err}
+	}
+	return status, body, nil
+}
+
+// moderatePrompt runs the latest user prompt through the moderation backend according to the
+// client key's policy and records the verdict in the request monitor. Backend failures are
+// logged and let the request through.
+func (h *BaseAPIHandler) moderatePrompt(ctx context.Context, handlerType string, rawJSON []byte) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || h.Cfg.Moderation.BaseURL == "" {
+		return nil
+	}
+	action := h.moderationAction(ctx)
+	if action == "" || action == config.ModerationActionOff {
+		return nil
+	}
+	prompt := latestUserPrompt(handlerType, rawJSON)
+	if prompt == "" {
+		return nil
+	}
+
+	payload, _ := sjson.SetBytes([]byte(`{}`), "input", prompt)
+	if h.Cfg.Moderation.Model != "" {
+		payload, _ = sjson.SetBytes(payload, "model", h.Cfg.Moderation.Model)
+	}
+	status, body, err := h.callModeration(ctx, payload, moderationTimeout)
+	if err == nil && (status < 200 || status >= 300) {
+		err = fmt.Errorf("status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if err != nil {
+		log.Warnf("moderation: pre-flight check failed, allowing request: %v", err)
+		return nil
+	}
+
+	result := gjson.GetBytes(body, "results.0")
+	if !result.Get("flagged").Bool() {
+		usage.UpdateRequestLog(logging.GetRequestID(ctx), usage.RequestLogUpdate{Moderation: "passed"})
+		return nil
+	}
+	categories := make([]string, 0, 4)
+	result.Get("categories").ForEach(func(key, value gjson.Result) bool {
+		if value.Bool() {
+			categories = append(categories, key.String())
+		}
+		return true
+	})
+	sort.Strings(categories)
+	verdict := "flagged"
+	if action == config.ModerationActionReject {
+		verdict = "rejected"
+	}
+	if len(categories) > 0 {
+		verdict += " (" + strings.Join(categories, ", ") + ")"
+	}
+	usage.UpdateRequestLog(logging.GetRequestID(ctx), usage.RequestLogUpdate{Moderation: verdict})
+	log.Warnf("moderation: prompt %s", verdict)
+	if action == config.ModerationActionReject {
+		return errorMessageFromError(&moderationRejectedError{categories: categories})
+	}
+	return nil
+}
+
+// moderationAction resolves the pre-flight policy for the client key of the request.
+func (h *BaseAPIHandler) moderationAction(ctx context.Context) string {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		if action, found := config.LookupClientKey(h.Cfg, h.Cfg.Moderation.KeyActions, clientAPIKeyFromGin(ginCtx)); found {
+			return action
+		}
+	}
+	return h.Cfg.Moderation.Action
+}
+
+// callModeration posts body to the moderation backend. A zero timeout keeps only ctx's deadline.
+func (h *BaseAPIHandler) callModeration(ctx context.Context, body []byte, timeout time.Duration) (int, []byte, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Cfg.Moderation.BaseURL+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Cfg.Moderation.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Cfg.Moderation.APIKey)
+	}
+	resp, err := util.SetProxy(h.Cfg, &http.Client{}).Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("moderation: close response body error: %v", errClose)
+		}
+	}()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// latestUserPrompt returns the text of the last conversation turn when it is a user prompt.
+// Tool results are not moderated.
+func latestUserPrompt(handlerType string, rawJSON []byte) string {
+	conversation := gjson.GetBytes(rawJSON, conversationPath(handlerType))
+	if conversation.Type == gjson.String {
+		return conversation.String()
+	}
+	items := conversation.Array()
+	if len(items) == 0 {
+		return ""
+	}
+	last := items[len(items)-1]
+	if !isUserTurn(last) || isToolResultTurn(last) {
+		return ""
+	}
+	if content := last.Get("content"); content.Type == gjson.String {
+		return content.String()
+	}
+	segments := make([]string, 0, 2)
+	for _, key := range []string{"content", "parts"} {
+		for _, part := range last.Get(key).Array() {
+			if text := part.Get("text").String(); text != "" {
+				segments = append(segments, text)
+			}
+		}
+	}
+	return strings.Join(segments, "\n")
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func newModerationBackend(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		input := gjson.GetBytes(body, "input").String()
+		inputs = append(inputs, input)
+		if r.Header.Get("Authorization") != "Bearer mod-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		flagged := strconv.FormatBool(strings.Contains(input, "forbidden"))
+		_, _ = io.WriteString(w, `{"results":[{"flagged":`+flagged+`,"categories":{"violence":`+flagged+`,"hate":false}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &inputs
+}
+
+func TestModeratePromptPolicies(t *testing.T) {
+	backend, inputs := newModerationBackend(t)
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{Moderation: sdkconfig.ModerationConfig{
+		BaseURL:    backend.URL,
+		APIKey:     "mod-key",
+		Action:     "flag",
+		KeyActions: map[string]string{"strict-key": "reject", "trusted-key": "off"},
+	}}, coreauth.NewManager(nil, nil, nil))
+	payload := []byte(`{"messages":[{"role":"assistant","content":"earlier"},{"role":"user","content":[{"type":"text","text":"something forbidden"}]}]}`)
+
+	errMsg := h.moderatePrompt(catalogTestContext("strict-key"), "openai", payload)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected rejection, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "content_policy_violation" {
+		t.Fatalf("code = %q", code)
+	}
+	if errMsg = h.moderatePrompt(catalogTestContext("other-key"), "openai", payload); errMsg != nil {
+		t.Fatalf("flag action should not block: %v", errMsg.Error)
+	}
+	if errMsg = h.moderatePrompt(catalogTestContext("trusted-key"), "openai", payload); errMsg != nil {
+		t.Fatalf("off action should not block: %v", errMsg.Error)
+	}
+	if len(*inputs) != 2 || (*inputs)[0] != "something forbidden" {
+		t.Fatalf("backend inputs = %q, want latest prompt checked twice", *inputs)
+	}
+}
+
+func TestModeratePromptFailsOpen(t *testing.T) {
+	backend, _ := newModerationBackend(t)
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{Moderation: sdkconfig.ModerationConfig{
+		BaseURL: backend.URL,
+		APIKey:  "wrong-key",
+		Action:  "reject",
+	}}, coreauth.NewManager(nil, nil, nil))
+
+	if errMsg := h.moderatePrompt(context.Background(), "openai-response", []byte(`{"input":"something forbidden"}`)); errMsg != nil {
+		t.Fatalf("backend failure should not block: %v", errMsg.Error)
+	}
+}
@@ -0,0 +1,196 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/sjson"
+)
+
+// Transcript export formats of GET /v1/threads/:thread_id/export.
+const (
+	exportFormatOpenAI   = "openai"
+	exportFormatClaude   = "claude"
+	exportFormatMarkdown = "markdown"
+	exportFormatText     = "text"
+)
+
+// transcriptTurn is one exported turn: the system prompt or a thread message.
+type transcriptTurn struct {
+	role  string
+	texts []string
+	// images holds image URLs, including data: URLs of inline images.
+	images []string
+}
+
+// ExportThread handles GET /v1/threads/:thread_id/export. It renders the thread's conversation,
+// preceded by the instructions of its latest run, as OpenAI chat messages, Claude messages,
+// Markdown or plain text so it can be resumed in another tool.
+func (h *OpenAIAssistantsAPIHandler) ExportThread(c *gin.Context) {
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", exportFormatOpenAI)))
+	var turns []transcriptTurn
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		turns = threadTranscript(t)
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+
+	id := c.Param("thread_id")
+	switch format {
+	case exportFormatOpenAI:
+		c.Data(http.StatusOK, "application/json", exportOpenAITranscript(turns))
+	case exportFormatClaude:
+		c.Data(http.StatusOK, "application/json", exportClaudeTranscript(turns))
+	case exportFormatMarkdown:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".md"))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(exportMarkdownTranscript(id, turns)))
+	case exportFormatText:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".txt"))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(exportTextTranscript(turns)))
+	default:
+		writeAssistantsError(c, http.StatusBadRequest, fmt.Sprintf("Unsupported export format '%s'. Use openai, claude, markdown or text.", format))
+	}
+}
+
+// threadTranscript collects the turns of t. The caller holds the store lock.
+func threadTranscript(t *AssistantThread) []transcriptTurn {
+	turns := make([]transcriptTurn, 0, len(t.messages)+1)
+	if n := len(t.runs); n > 0 && strings.TrimSpace(t.runs[n-1].Instructions) != "" {
+		turns = append(turns, transcriptTurn{role: "system", texts: []string{t.runs[n-1].Instructions}})
+	}
+	for _, msg := range t.messages {
+		turn := transcriptTurn{role: msg.Role}
+		for _, part := range msg.Content {
+			switch {
+			case part.Text != nil:
+				turn.texts = append(turn.texts, part.Text.Value)
+			case part.ImageURL != nil:
+				turn.images = append(turn.images, part.ImageURL.URL)
+			}
+		}
+		if len(turn.texts) > 0 || len(turn.images) > 0 {
+			turns = append(turns, turn)
+		}
+	}
+	return turns
+}
+
+func exportOpenAITranscript(turns []transcriptTurn) []byte {
+	out := []byte(`{"messages":[]}`)
+	for _, turn := range turns {
+		msg, _ := sjson.SetBytes([]byte(`{"role":""}`), "role", turn.role)
+		if len(turn.images) == 0 {
+			msg, _ = sjson.SetBytes(msg, "content", strings.Join(turn.texts, "\n\n"))
+		} else {
+			msg, _ = sjson.SetRawBytes(msg, "content", []byte(`[]`))
+			for _, text := range turn.texts {
+				msg, _ = sjson.SetRawBytes(msg, "content.-1", []byte(textPart("text", text)))
+			}
+			for _, url := range turn.images {
+				image, _ := sjson.Set(`{"type":"image_url","image_url":{"url":""}}`, "image_url.url", url)
+				msg, _ = sjson.SetRawBytes(msg, "content.-1", []byte(image))
+			}
+		}
+		out, _ = sjson.SetRawBytes(out, "messages.-1", msg)
+	}
+	return out
+}
+
+// exportClaudeTranscript renders turns as a Claude Messages request body. The system prompt
+// moves to the top-level system field and consecutive turns of one role are merged, as the
+// Messages API requires alternating roles.
+func exportClaudeTranscript(turns []transcriptTurn) []byte {
+	out := []byte(`{"messages":[]}`)
+	lastRole := ""
+	index := -1
+	for _, turn := range turns {
+		if turn.role == "system" {
+			out, _ = sjson.SetBytes(out, "system", strings.Join(turn.texts, "\n\n"))
+			continue
+		}
+		role := "user"
+		if turn.role == "assistant" {
+			role = "assistant"
+		}
+		if role != lastRole {
+			msg, _ := sjson.SetBytes([]byte(`{"role":"","content":[]}`), "role", role)
+			out, _ = sjson.SetRawBytes(out, "messages.-1", msg)
+			lastRole = role
+			index++
+		}
+		path := fmt.Sprintf("messages.%d.content.-1", index)
+		for _, text := range turn.texts {
+			out, _ = sjson.SetRawBytes(out, path, []byte(textPart("text", text)))
+		}
+		for _, url := range turn.images {
+			out, _ = sjson.SetRawBytes(out, path, []byte(claudeImageBlock(url)))
+		}
+	}
+	return out
+}
+
+// claudeImageBlock converts an image URL into a Claude image block with a base64 or URL source.
+func claudeImageBlock(url string) string {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if mediaType, data, found := strings.Cut(rest, ";base64,"); found {
+			block, _ := sjson.Set(`{"type":"image","source":{"type":"base64","media_type":"","data":""}}`, "source.media_type", mediaType)
+			block, _ = sjson.Set(block, "source.data", data)
+			return block
+		}
+	}
+	block, _ := sjson.Set(`{"type":"image","source":{"type":"url","url":""}}`, "source.url", url)
+	return block
+}
+
+func exportMarkdownTranscript(threadID string, turns []transcriptTurn) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Thread %s\n", threadID)
+	for _, turn := range turns {
+		fmt.Fprintf(&b, "\n## %s\n", transcriptRoleTitle(turn.role))
+		for _, text := range turn.texts {
+			fmt.Fprintf(&b, "\n%s\n", text)
+		}
+		for _, url := range turn.images {
+			if strings.HasPrefix(url, "data:") {
+				b.WriteString("\n*[inline image]*\n")
+				continue
+			}
+			fmt.Fprintf(&b, "\n![image](%s)\n", url)
+		}
+	}
+	return b.String()
+}
+
+func exportTextTranscript(turns []transcriptTurn) string {
+	var b strings.Builder
+	for _, turn := range turns {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(turn.role)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(turn.texts, "\n"))
+		for _, url := range turn.images {
+			if strings.HasPrefix(url, "data:") {
+				url = "inline image"
+			}
+			fmt.Fprintf(&b, "\n[image: %s]", url)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func transcriptRoleTitle(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
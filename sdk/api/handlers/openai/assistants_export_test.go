@@ -0,0 +1,71 @@
+package openai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAssistantsExportThread(t *testing.T) {
+	executor := &scriptedResponsesExecutor{responses: []string{
+		`{"status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"a cat"}]}]}`,
+	}}
+	router := newAssistantsTestRouter(t, executor)
+	assistant := doAssistantsRequest(t, router, http.MethodPost, "/v1/assistants", "key-a", `{"model":"assistants-model","instructions":"be brief"}`)
+	thread := doAssistantsRequest(t, router, http.MethodPost, "/v1/threads", "key-a",
+		`{"messages":[{"role":"user","content":"hello"},{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}]}`)
+	threadID := thread.Get("id").String()
+	doAssistantsRequest(t, router, http.MethodPost, "/v1/threads/"+threadID+"/runs", "key-a", `{"assistant_id":"`+assistant.Get("id").String()+`"}`)
+
+	export := func(format string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/threads/"+threadID+"/export?format="+format, nil)
+		req.Header.Set("Authorization", "key-a")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code, resp.Body.String()
+	}
+
+	_, body := export("openai")
+	openai := gjson.Parse(body)
+	if got := openai.Get("messages.#").Int(); got != 4 {
+		t.Fatalf("openai messages = %d, want 4: %s", got, body)
+	}
+	if openai.Get("messages.0.role").String() != "system" || openai.Get("messages.0.content").String() != "be brief" {
+		t.Fatalf("openai system turn: %s", body)
+	}
+	if got := openai.Get("messages.2.content.1.image_url.url").String(); got != "data:image/png;base64,AAAA" {
+		t.Fatalf("openai image = %q", got)
+	}
+
+	_, body = export("claude")
+	claude := gjson.Parse(body)
+	if claude.Get("system").String() != "be brief" || claude.Get("messages.#").Int() != 2 {
+		t.Fatalf("claude export: %s", body)
+	}
+	if got := claude.Get("messages.0.content.#").Int(); got != 3 {
+		t.Fatalf("merged user blocks = %d, want 3: %s", got, body)
+	}
+	if claude.Get("messages.0.content.2.source.media_type").String() != "image/png" || claude.Get("messages.0.content.2.source.data").String() != "AAAA" {
+		t.Fatalf("claude image block: %s", body)
+	}
+	if claude.Get("messages.1.role").String() != "assistant" || claude.Get("messages.1.content.0.text").String() != "a cat" {
+		t.Fatalf("claude assistant turn: %s", body)
+	}
+
+	_, body = export("markdown")
+	if !strings.HasPrefix(body, "# Thread "+threadID) || !strings.Contains(body, "## Assistant\n\na cat\n") || !strings.Contains(body, "*[inline image]*") {
+		t.Fatalf("markdown export:\n%s", body)
+	}
+
+	_, body = export("text")
+	if want := "system: be brief\n\nuser: hello\n\nuser: what is this?\n[image: inline image]\n\nassistant: a cat\n"; body != want {
+		t.Fatalf("text export = %q, want %q", body, want)
+	}
+
+	if code, _ := export("yaml"); code != http.StatusBadRequest {
+		t.Fatalf("unknown format status = %d, want 400", code)
+	}
+}
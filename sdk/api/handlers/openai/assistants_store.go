@@ -0,0 +1,300 @@
+package openai
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxAssistantThreads bounds the threads kept by the Assistants emulation; the least recently
+// used thread is dropped first. maxAssistants bounds stored assistants the same way.
+const (
+	maxAssistantThreads = 1000
+	maxAssistants       = 1000
+)
+
+// Assistant is an OpenAI Assistants API assistant object.
+type Assistant struct {
+	ID           string            `json:"id"`
+	Object       string            `json:"object"`
+	CreatedAt    int64             `json:"created_at"`
+	Name         *string           `json:"name"`
+	Description  *string           `json:"description"`
+	Model        string            `json:"model"`
+	Instructions *string           `json:"instructions"`
+	Tools        []json.RawMessage `json:"tools"`
+	Metadata     map[string]string `json:"metadata"`
+	Temperature  *float64          `json:"temperature,omitempty"`
+	TopP         *float64          `json:"top_p,omitempty"`
+
+	owner string
+}
+
+// AssistantThread is an OpenAI Assistants API thread object.
+type AssistantThread struct {
+	ID        string            `json:"id"`
+	Object    string            `json:"object"`
+	CreatedAt int64             `json:"created_at"`
+	Metadata  map[string]string `json:"metadata"`
+
+	owner    string
+	lastUsed time.Time
+	messages []*AssistantMessage
+	runs     []*AssistantRun
+}
+
+// AssistantMessage is an OpenAI Assistants API message object.
+type AssistantMessage struct {
+	ID          string                    `json:"id"`
+	Object      string                    `json:"object"`
+	CreatedAt   int64                     `json:"created_at"`
+	ThreadID    string                    `json:"thread_id"`
+	Status      string                    `json:"status"`
+	CompletedAt *int64                    `json:"completed_at"`
+	Role        string                    `json:"role"`
+	Content     []AssistantMessageContent `json:"content"`
+	AssistantID *string                   `json:"assistant_id"`
+	RunID       *string                   `json:"run_id"`
+	Attachments []json.RawMessage         `json:"attachments"`
+	Metadata    map[string]string         `json:"metadata"`
+}
+
+// AssistantMessageContent is one text or image part of a message.
+type AssistantMessageContent struct {
+	Type     string                 `json:"type"`
+	Text     *AssistantMessageText  `json:"text,omitempty"`
+	ImageURL *AssistantMessageImage `json:"image_url,omitempty"`
+}
+
+// AssistantMessageText is the text of a message content part.
+type AssistantMessageText struct {
+	Value       string            `json:"value"`
+	Annotations []json.RawMessage `json:"annotations"`
+}
+
+// AssistantMessageImage is the image of a message content part.
+type AssistantMessageImage struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AssistantRun is an OpenAI Assistants API run object.
+type AssistantRun struct {
+	ID                  string               `json:"id"`
+	Object              string               `json:"object"`
+	CreatedAt           int64                `json:"created_at"`
+	ThreadID            string               `json:"thread_id"`
+	AssistantID         string               `json:"assistant_id"`
+	Status              string               `json:"status"`
+	RequiredAction      *AssistantRunAction  `json:"required_action"`
+	LastError           *AssistantRunError   `json:"last_error"`
+	StartedAt           *int64               `json:"started_at"`
+	CancelledAt         *int64               `json:"cancelled_at"`
+	FailedAt            *int64               `json:"failed_at"`
+	CompletedAt         *int64               `json:"completed_at"`
+	IncompleteDetails   *AssistantIncomplete `json:"incomplete_details"`
+	Model               string               `json:"model"`
+	Instructions        string               `json:"instructions"`
+	Tools               []json.RawMessage    `json:"tools"`
+	ToolChoice          json.RawMessage      `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool                `json:"parallel_tool_calls,omitempty"`
+	MaxCompletionTokens *int64               `json:"max_completion_tokens"`
+	Metadata            map[string]string    `json:"metadata"`
+	Usage               *AssistantRunUsage   `json:"usage"`
+	Temperature         *float64             `json:"temperature,omitempty"`
+	TopP                *float64             `json:"top_p,omitempty"`
+
+	// toolCalls are the function calls made during the run with the outputs submitted so far.
+	toolCalls []assistantToolCallIO
+}
+
+// AssistantRunAction is the required_action of a run waiting for tool outputs.
+type AssistantRunAction struct {
+	Type              string                     `json:"type"`
+	SubmitToolOutputs AssistantSubmitToolOutputs `json:"submit_tool_outputs"`
+}
+
+// AssistantSubmitToolOutputs lists the tool calls a run waits on.
+type AssistantSubmitToolOutputs struct {
+	ToolCalls []AssistantToolCall `json:"tool_calls"`
+}
+
+// AssistantToolCall is a function call requested by a run.
+type AssistantToolCall struct {
+	ID       string                `json:"id"`
+	Type     string                `json:"type"`
+	Function AssistantFunctionCall `json:"function"`
+}
+
+// AssistantFunctionCall is the function name and JSON arguments of a tool call.
+type AssistantFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// AssistantRunError is the last_error of a failed run.
+type AssistantRunError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// AssistantRunUsage reports the tokens a run consumed.
+type AssistantRunUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// AssistantIncomplete explains why a run ended incomplete.
+type AssistantIncomplete struct {
+	Reason string `json:"reason"`
+}
+
+// assistantToolCallIO is a Responses function_call item made during a run and, once the
+// client submitted it, its output. They are replayed to the model when the run continues.
+type assistantToolCallIO struct {
+	callID string
+	item   string
+	output *string
+}
+
+// assistantStore keeps assistants and threads in memory, scoped to the client API key that
+// created them.
+type assistantStore struct {
+	mu         sync.Mutex
+	assistants map[string]*Assistant
+	threads    map[string]*AssistantThread
+}
+
+func newAssistantStore() *assistantStore {
+	return &assistantStore{
+		assistants: make(map[string]*Assistant),
+		threads:    make(map[string]*AssistantThread),
+	}
+}
+
+// newAssistantObjectID returns an ID with the given prefix in the style of OpenAI object IDs.
+func newAssistantObjectID(prefix string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+func (s *assistantStore) putAssistant(a *Assistant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assistants[a.ID] = a
+	if len(s.assistants) > maxAssistants {
+		var oldest *Assistant
+		for _, candidate := range s.assistants {
+			if oldest == nil || candidate.CreatedAt < oldest.CreatedAt {
+				oldest = candidate
+			}
+		}
+		delete(s.assistants, oldest.ID)
+	}
+}
+
+// assistant returns a copy of the assistant id owned by owner.
+func (s *assistantStore) assistant(owner, id string) (Assistant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.assistants[id]
+	if !ok || a.owner != owner {
+		return Assistant{}, false
+	}
+	return *a, true
+}
+
+func (s *assistantStore) updateAssistant(owner, id string, update func(*Assistant)) (Assistant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.assistants[id]
+	if !ok || a.owner != owner {
+		return Assistant{}, false
+	}
+	update(a)
+	return *a, true
+}
+
+func (s *assistantStore) deleteAssistant(owner, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.assistants[id]
+	if !ok || a.owner != owner {
+		return false
+	}
+	delete(s.assistants, id)
+	return true
+}
+
+func (s *assistantStore) listAssistants(owner string) []Assistant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Assistant, 0)
+	for _, a := range s.assistants {
+		if a.owner == owner {
+			out = append(out, *a)
+		}
+	}
+	slices.SortFunc(out, func(a, b Assistant) int { return compareCreated(a.CreatedAt, b.CreatedAt, a.ID, b.ID) })
+	return out
+}
+
+func (s *assistantStore) putThread(t *AssistantThread) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.lastUsed = time.Now()
+	s.threads[t.ID] = t
+	if len(s.threads) > maxAssistantThreads {
+		var oldest *AssistantThread
+		for _, candidate := range s.threads {
+			if oldest == nil || candidate.lastUsed.Before(oldest.lastUsed) {
+				oldest = candidate
+			}
+		}
+		delete(s.threads, oldest.ID)
+	}
+}
+
+// withThread runs fn with the thread id owned by owner while holding the store lock.
+func (s *assistantStore) withThread(owner, id string, fn func(*AssistantThread)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[id]
+	if !ok || t.owner != owner {
+		return false
+	}
+	t.lastUsed = time.Now()
+	fn(t)
+	return true
+}
+
+func (s *assistantStore) deleteThread(owner, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[id]
+	if !ok || t.owner != owner {
+		return false
+	}
+	delete(s.threads, id)
+	return true
+}
+
+// compareCreated orders objects by creation time, then ID.
+func compareCreated(a, b int64, aID, bID string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case aID < bID:
+		return -1
+	case aID > bID:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,930 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Run statuses of the Assistants API.
+const (
+	runStatusInProgress     = "in_progress"
+	runStatusRequiresAction = "requires_action"
+	runStatusCancelled      = "cancelled"
+	runStatusFailed         = "failed"
+	runStatusCompleted      = "completed"
+	runStatusIncomplete     = "incomplete"
+)
+
+// OpenAIAssistantsAPIHandler emulates the OpenAI Assistants API (assistants, threads, messages
+// and runs) on top of the Responses pipeline, so tools built on Assistants can use any pooled
+// account. Objects live in memory and are private to the client API key that created them.
+// Runs execute synchronously: the create-run call returns once the run completed, failed or
+// requires tool outputs. A thread's ID is sent as the prompt cache key, so session-aware routing
+// keeps a thread on the same account.
+type OpenAIAssistantsAPIHandler struct {
+	*handlers.BaseAPIHandler
+	store *assistantStore
+}
+
+// NewOpenAIAssistantsAPIHandler creates a new Assistants API emulation handler.
+func NewOpenAIAssistantsAPIHandler(apiHandlers *handlers.BaseAPIHandler) *OpenAIAssistantsAPIHandler {
+	return &OpenAIAssistantsAPIHandler{
+		BaseAPIHandler: apiHandlers,
+		store:          newAssistantStore(),
+	}
+}
+
+// HandlerType returns the identifier for this handler implementation. Runs are executed as
+// OpenAI Responses requests.
+func (h *OpenAIAssistantsAPIHandler) HandlerType() string {
+	return OpenaiResponse
+}
+
+// Models returns the OpenAI-compatible model metadata supported by this handler.
+func (h *OpenAIAssistantsAPIHandler) Models() []map[string]any {
+	return registry.GetGlobalRegistry().GetAvailableModels("openai")
+}
+
+// assistantRequest is the body of assistant create and modify requests.
+type assistantRequest struct {
+	Model        *string            `json:"model"`
+	Name         *string            `json:"name"`
+	Description  *string            `json:"description"`
+	Instructions *string            `json:"instructions"`
+	Tools        *[]json.RawMessage `json:"tools"`
+	Metadata     map[string]string  `json:"metadata"`
+	Temperature  *float64           `json:"temperature"`
+	TopP         *float64           `json:"top_p"`
+}
+
+// messageRequest is the body of message create requests and of the messages of a new thread.
+type messageRequest struct {
+	Role        string            `json:"role"`
+	Content     json.RawMessage   `json:"content"`
+	Attachments []json.RawMessage `json:"attachments"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// threadRequest is the body of thread create requests.
+type threadRequest struct {
+	Messages []messageRequest  `json:"messages"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// runRequest is the body of run create requests.
+type runRequest struct {
+	AssistantID            string             `json:"assistant_id"`
+	Model                  *string            `json:"model"`
+	Instructions           *string            `json:"instructions"`
+	AdditionalInstructions string             `json:"additional_instructions"`
+	AdditionalMessages     []messageRequest   `json:"additional_messages"`
+	Tools                  *[]json.RawMessage `json:"tools"`
+	ToolChoice             json.RawMessage    `json:"tool_choice"`
+	ParallelToolCalls      *bool              `json:"parallel_tool_calls"`
+	MaxCompletionTokens    *int64             `json:"max_completion_tokens"`
+	Metadata               map[string]string  `json:"metadata"`
+	Temperature            *float64           `json:"temperature"`
+	TopP                   *float64           `json:"top_p"`
+	Stream                 bool               `json:"stream"`
+	Thread                 *threadRequest     `json:"thread"`
+}
+
+// toolOutputsRequest is the body of submit_tool_outputs requests.
+type toolOutputsRequest struct {
+	ToolOutputs []struct {
+		ToolCallID string `json:"tool_call_id"`
+		Output     string `json:"output"`
+	} `json:"tool_outputs"`
+	Stream bool `json:"stream"`
+}
+
+// CreateAssistant handles POST /v1/assistants.
+func (h *OpenAIAssistantsAPIHandler) CreateAssistant(c *gin.Context) {
+	var req assistantRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	if req.Model == nil || strings.TrimSpace(*req.Model) == "" {
+		writeAssistantsError(c, http.StatusBadRequest, "Missing required parameter: 'model'.")
+		return
+	}
+	a := &Assistant{
+		ID:        newAssistantObjectID("asst_"),
+		Object:    "assistant",
+		CreatedAt: time.Now().Unix(),
+		Tools:     []json.RawMessage{},
+		Metadata:  map[string]string{},
+		owner:     handlers.ClientAPIKey(c),
+	}
+	applyAssistantRequest(a, req)
+	h.store.putAssistant(a)
+	c.JSON(http.StatusOK, a)
+}
+
+// ListAssistants handles GET /v1/assistants.
+func (h *OpenAIAssistantsAPIHandler) ListAssistants(c *gin.Context) {
+	writeAssistantsList(c, h.store.listAssistants(handlers.ClientAPIKey(c)), func(a Assistant) string { return a.ID })
+}
+
+// GetAssistant handles GET /v1/assistants/:assistant_id.
+func (h *OpenAIAssistantsAPIHandler) GetAssistant(c *gin.Context) {
+	a, ok := h.store.assistant(handlers.ClientAPIKey(c), c.Param("assistant_id"))
+	if !ok {
+		writeAssistantsNotFound(c, "assistant", c.Param("assistant_id"))
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// ModifyAssistant handles POST /v1/assistants/:assistant_id.
+func (h *OpenAIAssistantsAPIHandler) ModifyAssistant(c *gin.Context) {
+	var req assistantRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	a, ok := h.store.updateAssistant(handlers.ClientAPIKey(c), c.Param("assistant_id"), func(a *Assistant) {
+		applyAssistantRequest(a, req)
+	})
+	if !ok {
+		writeAssistantsNotFound(c, "assistant", c.Param("assistant_id"))
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAssistant handles DELETE /v1/assistants/:assistant_id.
+func (h *OpenAIAssistantsAPIHandler) DeleteAssistant(c *gin.Context) {
+	id := c.Param("assistant_id")
+	if !h.store.deleteAssistant(handlers.ClientAPIKey(c), id) {
+		writeAssistantsNotFound(c, "assistant", id)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "assistant.deleted", "deleted": true})
+}
+
+// CreateThread handles POST /v1/threads.
+func (h *OpenAIAssistantsAPIHandler) CreateThread(c *gin.Context) {
+	var req threadRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	t, errText := newAssistantThread(handlers.ClientAPIKey(c), req)
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	h.store.putThread(t)
+	c.JSON(http.StatusOK, t)
+}
+
+// GetThread handles GET /v1/threads/:thread_id.
+func (h *OpenAIAssistantsAPIHandler) GetThread(c *gin.Context) {
+	var out AssistantThread
+	if !h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) { out = *t }) {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ModifyThread handles POST /v1/threads/:thread_id. Only metadata can be changed.
+func (h *OpenAIAssistantsAPIHandler) ModifyThread(c *gin.Context) {
+	var req threadRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	var out AssistantThread
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		if req.Metadata != nil {
+			t.Metadata = req.Metadata
+		}
+		out = *t
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteThread handles DELETE /v1/threads/:thread_id.
+func (h *OpenAIAssistantsAPIHandler) DeleteThread(c *gin.Context) {
+	id := c.Param("thread_id")
+	if !h.store.deleteThread(handlers.ClientAPIKey(c), id) {
+		writeAssistantsNotFound(c, "thread", id)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "thread.deleted", "deleted": true})
+}
+
+// CreateMessage handles POST /v1/threads/:thread_id/messages.
+func (h *OpenAIAssistantsAPIHandler) CreateMessage(c *gin.Context) {
+	var req messageRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	var out AssistantMessage
+	errText := ""
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		if run := activeRun(t); run != nil {
+			errText = fmt.Sprintf("Can't add messages to %s while a run %s is active.", t.ID, run.ID)
+			return
+		}
+		var msg *AssistantMessage
+		if msg, errText = newAssistantMessage(t.ID, req); errText == "" {
+			t.messages = append(t.messages, msg)
+			out = *msg
+		}
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ListMessages handles GET /v1/threads/:thread_id/messages.
+func (h *OpenAIAssistantsAPIHandler) ListMessages(c *gin.Context) {
+	var messages []AssistantMessage
+	runID := c.Query("run_id")
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		for _, msg := range t.messages {
+			if runID == "" || (msg.RunID != nil && *msg.RunID == runID) {
+				messages = append(messages, *msg)
+			}
+		}
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	writeAssistantsList(c, messages, func(m AssistantMessage) string { return m.ID })
+}
+
+// GetMessage handles GET /v1/threads/:thread_id/messages/:message_id.
+func (h *OpenAIAssistantsAPIHandler) GetMessage(c *gin.Context) {
+	var out *AssistantMessage
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		for _, msg := range t.messages {
+			if msg.ID == c.Param("message_id") {
+				copied := *msg
+				out = &copied
+			}
+		}
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	if out == nil {
+		writeAssistantsNotFound(c, "message", c.Param("message_id"))
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateRun handles POST /v1/threads/:thread_id/runs.
+func (h *OpenAIAssistantsAPIHandler) CreateRun(c *gin.Context) {
+	var req runRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	h.createRun(c, c.Param("thread_id"), req)
+}
+
+// CreateThreadAndRun handles POST /v1/threads/runs.
+func (h *OpenAIAssistantsAPIHandler) CreateThreadAndRun(c *gin.Context) {
+	var req runRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	threadReq := threadRequest{}
+	if req.Thread != nil {
+		threadReq = *req.Thread
+	}
+	t, errText := newAssistantThread(handlers.ClientAPIKey(c), threadReq)
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	h.store.putThread(t)
+	h.createRun(c, t.ID, req)
+}
+
+// ListRuns handles GET /v1/threads/:thread_id/runs.
+func (h *OpenAIAssistantsAPIHandler) ListRuns(c *gin.Context) {
+	var runs []AssistantRun
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		for _, run := range t.runs {
+			runs = append(runs, *run)
+		}
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	writeAssistantsList(c, runs, func(r AssistantRun) string { return r.ID })
+}
+
+// GetRun handles GET /v1/threads/:thread_id/runs/:run_id.
+func (h *OpenAIAssistantsAPIHandler) GetRun(c *gin.Context) {
+	h.updateRun(c, func(*AssistantThread, *AssistantRun) string { return "" })
+}
+
+// CancelRun handles POST /v1/threads/:thread_id/runs/:run_id/cancel.
+func (h *OpenAIAssistantsAPIHandler) CancelRun(c *gin.Context) {
+	h.updateRun(c, func(_ *AssistantThread, run *AssistantRun) string {
+		if run.Status != runStatusInProgress && run.Status != runStatusRequiresAction {
+			return fmt.Sprintf("Cannot cancel run with status '%s'.", run.Status)
+		}
+		now := time.Now().Unix()
+		run.Status = runStatusCancelled
+		run.CancelledAt = &now
+		run.RequiredAction = nil
+		return ""
+	})
+}
+
+// SubmitToolOutputs handles POST /v1/threads/:thread_id/runs/:run_id/submit_tool_outputs and
+// continues the run with the outputs.
+func (h *OpenAIAssistantsAPIHandler) SubmitToolOutputs(c *gin.Context) {
+	var req toolOutputsRequest
+	if !bindAssistantsJSON(c, &req) {
+		return
+	}
+	if req.Stream {
+		writeAssistantsError(c, http.StatusBadRequest, "Streaming runs are not supported; poll the run instead.")
+		return
+	}
+	var run *AssistantRun
+	errText := ""
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		run = findRun(t, c.Param("run_id"))
+		if run == nil {
+			return
+		}
+		if run.Status != runStatusRequiresAction {
+			errText = fmt.Sprintf("Runs in status \"%s\" do not accept tool outputs.", run.Status)
+			return
+		}
+		outputs := make(map[string]string, len(req.ToolOutputs))
+		for _, output := range req.ToolOutputs {
+			outputs[output.ToolCallID] = output.Output
+		}
+		for i := range run.toolCalls {
+			call := &run.toolCalls[i]
+			if call.output != nil {
+				continue
+			}
+			output, ok := outputs[call.callID]
+			if !ok {
+				errText = fmt.Sprintf("Expected tool outputs for call_ids %s, got %d outputs.", pendingCallIDs(run), len(req.ToolOutputs))
+				return
+			}
+			call.output = &output
+		}
+		run.Status = runStatusInProgress
+		run.RequiredAction = nil
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	if run == nil {
+		writeAssistantsNotFound(c, "run", c.Param("run_id"))
+		return
+	}
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	c.JSON(http.StatusOK, h.executeRun(c, run))
+}
+
+// createRun validates req, adds the run to the thread and executes it.
+func (h *OpenAIAssistantsAPIHandler) createRun(c *gin.Context, threadID string, req runRequest) {
+	if req.Stream {
+		writeAssistantsError(c, http.StatusBadRequest, "Streaming runs are not supported; poll the run instead.")
+		return
+	}
+	owner := handlers.ClientAPIKey(c)
+	assistant, ok := h.store.assistant(owner, req.AssistantID)
+	if !ok {
+		writeAssistantsNotFound(c, "assistant", req.AssistantID)
+		return
+	}
+	run := newAssistantRun(threadID, assistant, req)
+	errText := ""
+	found := h.store.withThread(owner, threadID, func(t *AssistantThread) {
+		if active := activeRun(t); active != nil {
+			errText = fmt.Sprintf("Thread %s already has an active run %s.", t.ID, active.ID)
+			return
+		}
+		var added []*AssistantMessage
+		for _, msgReq := range req.AdditionalMessages {
+			msg, errMsg := newAssistantMessage(t.ID, msgReq)
+			if errMsg != "" {
+				errText = errMsg
+				return
+			}
+			added = append(added, msg)
+		}
+		t.messages = append(t.messages, added...)
+		t.runs = append(t.runs, run)
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", threadID)
+		return
+	}
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	c.JSON(http.StatusOK, h.executeRun(c, run))
+}
+
+// updateRun applies update to the run addressed by the request and writes the run.
+func (h *OpenAIAssistantsAPIHandler) updateRun(c *gin.Context, update func(*AssistantThread, *AssistantRun) string) {
+	var out *AssistantRun
+	errText := ""
+	found := h.store.withThread(handlers.ClientAPIKey(c), c.Param("thread_id"), func(t *AssistantThread) {
+		if run := findRun(t, c.Param("run_id")); run != nil {
+			errText = update(t, run)
+			copied := *run
+			out = &copied
+		}
+	})
+	if !found {
+		writeAssistantsNotFound(c, "thread", c.Param("thread_id"))
+		return
+	}
+	if out == nil {
+		writeAssistantsNotFound(c, "run", c.Param("run_id"))
+		return
+	}
+	if errText != "" {
+		writeAssistantsError(c, http.StatusBadRequest, errText)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// executeRun sends the thread to the Responses pipeline and records the outcome on run: an
+// assistant message, a required tool call action or an error. It returns a copy of the run.
+func (h *OpenAIAssistantsAPIHandler) executeRun(c *gin.Context, run *AssistantRun) AssistantRun {
+	owner := handlers.ClientAPIKey(c)
+	var payload []byte
+	h.store.withThread(owner, run.ThreadID, func(t *AssistantThread) {
+		payload = buildAssistantRunPayload(t, run)
+	})
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), run.Model, payload, "")
+	if errMsg != nil {
+		cliCancel(errMsg.Error)
+	} else {
+		cliCancel()
+	}
+
+	var out AssistantRun
+	h.store.withThread(owner, run.ThreadID, func(t *AssistantThread) {
+		if run.Status == runStatusCancelled {
+			out = *run
+			return
+		}
+		now := time.Now().Unix()
+		if errMsg != nil {
+			code := "server_error"
+			if errMsg.StatusCode == http.StatusTooManyRequests {
+				code = "rate_limit_exceeded"
+			}
+			message := http.StatusText(errMsg.StatusCode)
+			if errMsg.Error != nil {
+				message = errMsg.Error.Error()
+			}
+			run.Status = runStatusFailed
+			run.FailedAt = &now
+			run.LastError = &AssistantRunError{Code: code, Message: message}
+			out = *run
+			return
+		}
+		applyAssistantRunResponse(t, run, gjson.ParseBytes(resp), now)
+		out = *run
+	})
+	return out
+}
+
+// buildAssistantRunPayload converts the thread and the run's tool calls into a Responses request.
+func buildAssistantRunPayload(t *AssistantThread, run *AssistantRun) []byte {
+	payload := `{"model":"","input":[],"stream":false}`
+	payload, _ = sjson.Set(payload, "model", run.Model)
+	if run.Instructions != "" {
+		payload, _ = sjson.Set(payload, "instructions", run.Instructions)
+	}
+	// The thread ID keys session-aware routing and prompt caching.
+	payload, _ = sjson.Set(payload, "prompt_cache_key", t.ID)
+	if run.Temperature != nil {
+		payload, _ = sjson.Set(payload, "temperature", *run.Temperature)
+	}
+	if run.TopP != nil {
+		payload, _ = sjson.Set(payload, "top_p", *run.TopP)
+	}
+	if run.MaxCompletionTokens != nil {
+		payload, _ = sjson.Set(payload, "max_output_tokens", *run.MaxCompletionTokens)
+	}
+	if run.ParallelToolCalls != nil {
+		payload, _ = sjson.Set(payload, "parallel_tool_calls", *run.ParallelToolCalls)
+	}
+	hasTools := false
+	for _, tool := range run.Tools {
+		fn := gjson.GetBytes(tool, "function")
+		if gjson.GetBytes(tool, "type").String() != "function" || !fn.Exists() {
+			// code_interpreter and file_search have no Responses counterpart here.
+			continue
+		}
+		converted := `{"type":"function"}`
+		for _, key := range []string{"name", "description", "parameters", "strict"} {
+			if value := fn.Get(key); value.Exists() {
+				converted, _ = sjson.SetRaw(converted, key, value.Raw)
+			}
+		}
+		payload, _ = sjson.SetRaw(payload, "tools.-1", converted)
+		hasTools = true
+	}
+	if hasTools && len(run.ToolChoice) > 0 {
+		choice := gjson.ParseBytes(run.ToolChoice)
+		if name := choice.Get("function.name"); name.Exists() {
+			converted, _ := sjson.Set(`{"type":"function"}`, "name", name.String())
+			payload, _ = sjson.SetRaw(payload, "tool_choice", converted)
+		} else if choice.Type == gjson.String {
+			payload, _ = sjson.Set(payload, "tool_choice", choice.String())
+		}
+	}
+
+	for _, msg := range t.messages {
+		if msg.RunID != nil && *msg.RunID == run.ID {
+			// Text the model produced alongside pending tool calls is replayed below.
+			continue
+		}
+		item := `{"type":"message","role":"","content":[]}`
+		item, _ = sjson.Set(item, "role", msg.Role)
+		for _, part := range msg.Content {
+			switch {
+			case part.Text != nil && msg.Role == "assistant":
+				item, _ = sjson.SetRaw(item, "content.-1", textPart("output_text", part.Text.Value))
+			case part.Text != nil:
+				item, _ = sjson.SetRaw(item, "content.-1", textPart("input_text", part.Text.Value))
+			case part.ImageURL != nil:
+				image, _ := sjson.Set(`{"type":"input_image","image_url":""}`, "image_url", part.ImageURL.URL)
+				item, _ = sjson.SetRaw(item, "content.-1", image)
+			}
+		}
+		payload, _ = sjson.SetRaw(payload, "input.-1", item)
+	}
+	for _, msg := range t.messages {
+		if msg.RunID == nil || *msg.RunID != run.ID {
+			continue
+		}
+		item := `{"type":"message","role":"assistant","content":[]}`
+		for _, part := range msg.Content {
+			if part.Text != nil {
+				item, _ = sjson.SetRaw(item, "content.-1", textPart("output_text", part.Text.Value))
+			}
+		}
+		payload, _ = sjson.SetRaw(payload, "input.-1", item)
+	}
+	for _, call := range run.toolCalls {
+		payload, _ = sjson.SetRaw(payload, "input.-1", call.item)
+		if call.output != nil {
+			output := `{"type":"function_call_output","call_id":"","output":""}`
+			output, _ = sjson.Set(output, "call_id", call.callID)
+			output, _ = sjson.Set(output, "output", *call.output)
+			payload, _ = sjson.SetRaw(payload, "input.-1", output)
+		}
+	}
+	return []byte(payload)
+}
+
+// applyAssistantRunResponse records a Responses result on run and adds the assistant's reply
+// to the thread.
+func applyAssistantRunResponse(t *AssistantThread, run *AssistantRun, resp gjson.Result, now int64) {
+	var text strings.Builder
+	var calls []AssistantToolCall
+	for _, item := range resp.Get("output").Array() {
+		switch item.Get("type").String() {
+		case "message":
+			for _, part := range item.Get("content").Array() {
+				if part.Get("type").String() == "output_text" {
+					text.WriteString(part.Get("text").String())
+				}
+			}
+		case "function_call":
+			callID := item.Get("call_id").String()
+			call := `{"type":"function_call","call_id":"","name":"","arguments":""}`
+			call, _ = sjson.Set(call, "call_id", callID)
+			call, _ = sjson.Set(call, "name", item.Get("name").String())
+			call, _ = sjson.Set(call, "arguments", item.Get("arguments").String())
+			run.toolCalls = append(run.toolCalls, assistantToolCallIO{callID: callID, item: call})
+			calls = append(calls, AssistantToolCall{
+				ID:       callID,
+				Type:     "function",
+				Function: AssistantFunctionCall{Name: item.Get("name").String(), Arguments: item.Get("arguments").String()},
+			})
+		}
+	}
+
+	if usage := resp.Get("usage"); usage.Exists() {
+		if run.Usage == nil {
+			run.Usage = &AssistantRunUsage{}
+		}
+		run.Usage.PromptTokens += usage.Get("input_tokens").Int()
+		run.Usage.CompletionTokens += usage.Get("output_tokens").Int()
+		run.Usage.TotalTokens = run.Usage.PromptTokens + run.Usage.CompletionTokens
+	}
+
+	if text.Len() > 0 {
+		assistantID, runID := run.AssistantID, run.ID
+		t.messages = append(t.messages, &AssistantMessage{
+			ID:          newAssistantObjectID("msg_"),
+			Object:      "thread.message",
+			CreatedAt:   now,
+			ThreadID:    t.ID,
+			Status:      "completed",
+			CompletedAt: &now,
+			Role:        "assistant",
+			Content:     []AssistantMessageContent{{Type: "text", Text: &AssistantMessageText{Value: text.String(), Annotations: []json.RawMessage{}}}},
+			AssistantID: &assistantID,
+			RunID:       &runID,
+			Attachments: []json.RawMessage{},
+			Metadata:    map[string]string{},
+		})
+	}
+
+	switch {
+	case len(calls) > 0:
+		run.Status = runStatusRequiresAction
+		run.RequiredAction = &AssistantRunAction{Type: "submit_tool_outputs", SubmitToolOutputs: AssistantSubmitToolOutputs{ToolCalls: calls}}
+	case resp.Get("status").String() == "incomplete":
+		run.Status = runStatusIncomplete
+		run.IncompleteDetails = &AssistantIncomplete{Reason: resp.Get("incomplete_details.reason").String()}
+		run.CompletedAt = &now
+	default:
+		run.Status = runStatusCompleted
+		run.CompletedAt = &now
+	}
+}
+
+func textPart(partType, text string) string {
+	part, _ := sjson.Set(`{"type":"","text":""}`, "type", partType)
+	part, _ = sjson.Set(part, "text", text)
+	return part
+}
+
+func applyAssistantRequest(a *Assistant, req assistantRequest) {
+	if req.Model != nil && strings.TrimSpace(*req.Model) != "" {
+		a.Model = strings.TrimSpace(*req.Model)
+	}
+	if req.Name != nil {
+		a.Name = req.Name
+	}
+	if req.Description != nil {
+		a.Description = req.Description
+	}
+	if req.Instructions != nil {
+		a.Instructions = req.Instructions
+	}
+	if req.Tools != nil {
+		a.Tools = *req.Tools
+	}
+	if req.Metadata != nil {
+		a.Metadata = req.Metadata
+	}
+	if req.Temperature != nil {
+		a.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		a.TopP = req.TopP
+	}
+}
+
+func newAssistantThread(owner string, req threadRequest) (*AssistantThread, string) {
+	t := &AssistantThread{
+		ID:        newAssistantObjectID("thread_"),
+		Object:    "thread",
+		CreatedAt: time.Now().Unix(),
+		Metadata:  req.Metadata,
+		owner:     owner,
+	}
+	if t.Metadata == nil {
+		t.Metadata = map[string]string{}
+	}
+	for _, msgReq := range req.Messages {
+		msg, errText := newAssistantMessage(t.ID, msgReq)
+		if errText != "" {
+			return nil, errText
+		}
+		t.messages = append(t.messages, msg)
+	}
+	return t, ""
+}
+
+// newAssistantMessage builds a message from a create request. Content is a string or an array
+// of text and image_url parts.
+func newAssistantMessage(threadID string, req messageRequest) (*AssistantMessage, string) {
+	if req.Role != "user" && req.Role != "assistant" {
+		return nil, fmt.Sprintf("Invalid value: '%s'. Supported values are: 'user' and 'assistant'.", req.Role)
+	}
+	now := time.Now().Unix()
+	msg := &AssistantMessage{
+		ID:          newAssistantObjectID("msg_"),
+		Object:      "thread.message",
+		CreatedAt:   now,
+		ThreadID:    threadID,
+		Status:      "completed",
+		CompletedAt: &now,
+		Role:        req.Role,
+		Content:     []AssistantMessageContent{},
+		Attachments: req.Attachments,
+		Metadata:    req.Metadata,
+	}
+	if msg.Attachments == nil {
+		msg.Attachments = []json.RawMessage{}
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]string{}
+	}
+	content := gjson.ParseBytes(req.Content)
+	switch {
+	case content.Type == gjson.String:
+		msg.Content = append(msg.Content, AssistantMessageContent{Type: "text", Text: &AssistantMessageText{Value: content.String(), Annotations: []json.RawMessage{}}})
+	case content.IsArray():
+		for _, part := range content.Array() {
+			switch part.Get("type").String() {
+			case "text":
+				msg.Content = append(msg.Content, AssistantMessageContent{Type: "text", Text: &AssistantMessageText{Value: part.Get("text").String(), Annotations: []json.RawMessage{}}})
+			case "image_url":
+				msg.Content = append(msg.Content, AssistantMessageContent{Type: "image_url", ImageURL: &AssistantMessageImage{URL: part.Get("image_url.url").String(), Detail: part.Get("image_url.detail").String()}})
+			default:
+				return nil, fmt.Sprintf("Unsupported message content type '%s'.", part.Get("type").String())
+			}
+		}
+	default:
+		return nil, "Missing required parameter: 'content'."
+	}
+	return msg, ""
+}
+
+// newAssistantRun builds an in-progress run from the assistant defaults and the request overrides.
+func newAssistantRun(threadID string, assistant Assistant, req runRequest) *AssistantRun {
+	now := time.Now().Unix()
+	run := &AssistantRun{
+		ID:                  newAssistantObjectID("run_"),
+		Object:              "thread.run",
+		CreatedAt:           now,
+		ThreadID:            threadID,
+		AssistantID:         assistant.ID,
+		Status:              runStatusInProgress,
+		StartedAt:           &now,
+		Model:               assistant.Model,
+		Tools:               assistant.Tools,
+		ToolChoice:          req.ToolChoice,
+		ParallelToolCalls:   req.ParallelToolCalls,
+		MaxCompletionTokens: req.MaxCompletionTokens,
+		Metadata:            req.Metadata,
+		Temperature:         assistant.Temperature,
+		TopP:                assistant.TopP,
+	}
+	if assistant.Instructions != nil {
+		run.Instructions = *assistant.Instructions
+	}
+	if req.Model != nil && *req.Model != "" {
+		run.Model = *req.Model
+	}
+	if req.Instructions != nil {
+		run.Instructions = *req.Instructions
+	}
+	if req.AdditionalInstructions != "" {
+		run.Instructions = strings.TrimSpace(run.Instructions + "\n\n" + req.AdditionalInstructions)
+	}
+	if req.Tools != nil {
+		run.Tools = *req.Tools
+	}
+	if req.Temperature != nil {
+		run.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		run.TopP = req.TopP
+	}
+	if run.Metadata == nil {
+		run.Metadata = map[string]string{}
+	}
+	return run
+}
+
+func activeRun(t *AssistantThread) *AssistantRun {
+	for _, run := range t.runs {
+		if run.Status == runStatusInProgress || run.Status == runStatusRequiresAction {
+			return run
+		}
+	}
+	return nil
+}
+
+func findRun(t *AssistantThread, id string) *AssistantRun {
+	for _, run := range t.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func pendingCallIDs(run *AssistantRun) string {
+	var ids []string
+	for _, call := range run.toolCalls {
+		if call.output == nil {
+			ids = append(ids, call.callID)
+		}
+	}
+	return "[" + strings.Join(ids, ", ") + "]"
+}
+
+// writeAssistantsList writes items, ordered by creation, as an Assistants API list page. It
+// honours the limit, order ("desc" by default), after and before query parameters.
+func writeAssistantsList[T any](c *gin.Context, items []T, id func(T) string) {
+	if c.DefaultQuery("order", "desc") == "desc" {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+	}
+	if after := c.Query("after"); after != "" {
+		if idx := slices.IndexFunc(items, func(item T) bool { return id(item) == after }); idx >= 0 {
+			items = items[idx+1:]
+		}
+	}
+	if before := c.Query("before"); before != "" {
+		if idx := slices.IndexFunc(items, func(item T) bool { return id(item) == before }); idx >= 0 {
+			items = items[:idx]
+		}
+	}
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			limit = min(max(parsed, 1), 100)
+		}
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+	var firstID, lastID *string
+	if len(items) > 0 {
+		first, last := id(items[0]), id(items[len(items)-1])
+		firstID, lastID = &first, &last
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": items, "first_id": firstID, "last_id": lastID, "has_more": hasMore})
+}
+
+func bindAssistantsJSON(c *gin.Context, target any) bool {
+	raw, err := c.GetRawData()
+	if err == nil && len(strings.TrimSpace(string(raw))) > 0 {
+		err = json.Unmarshal(raw, target)
+	}
+	if err != nil {
+		writeAssistantsError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeAssistantsNotFound(c *gin.Context, object, id string) {
+	writeAssistantsError(c, http.StatusNotFound, fmt.Sprintf("No %s found with id '%s'.", object, id))
+}
+
+func writeAssistantsError(c *gin.Context, status int, message string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	})
+}
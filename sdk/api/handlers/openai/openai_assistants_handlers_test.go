@@ -0,0 +1,190 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// scriptedResponsesExecutor answers each Execute call with the next scripted Responses payload
+// and records the requests it received.
+type scriptedResponsesExecutor struct {
+	responses []string
+	requests  []string
+}
+
+func (e *scriptedResponsesExecutor) Identifier() string { return "assistants-test-provider" }
+
+func (e *scriptedResponsesExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.requests = append(e.requests, string(req.Payload))
+	if len(e.responses) == 0 {
+		return coreexecutor.Response{}, errors.New("no scripted response left")
+	}
+	payload := e.responses[0]
+	e.responses = e.responses[1:]
+	return coreexecutor.Response{Payload: []byte(payload)}, nil
+}
+
+func (e *scriptedResponsesExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *scriptedResponsesExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *scriptedResponsesExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *scriptedResponsesExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newAssistantsTestRouter(t *testing.T, executor *scriptedResponsesExecutor) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "assistants-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "assistants-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	h := NewOpenAIAssistantsAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("Authorization"))
+	})
+	router.POST("/v1/assistants", h.CreateAssistant)
+	router.GET("/v1/assistants/:assistant_id", h.GetAssistant)
+	router.POST("/v1/threads", h.CreateThread)
+	router.GET("/v1/threads/:thread_id/messages", h.ListMessages)
+	router.GET("/v1/threads/:thread_id/export", h.ExportThread)
+	router.POST("/v1/threads/:thread_id/runs", h.CreateRun)
+	router.POST("/v1/threads/:thread_id/runs/:run_id/submit_tool_outputs", h.SubmitToolOutputs)
+	return router
+}
+
+func doAssistantsRequest(t *testing.T, router *gin.Engine, method, path, key, body string) gjson.Result {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", key)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	result := gjson.Parse(resp.Body.String())
+	if resp.Code != http.StatusOK {
+		t.Fatalf("%s %s: status = %d, body = %s", method, path, resp.Code, resp.Body.String())
+	}
+	return result
+}
+
+func TestAssistantsRunWithToolCalls(t *testing.T) {
+	executor := &scriptedResponsesExecutor{responses: []string{
+		`{"status":"completed","output":[{"type":"function_call","call_id":"call_1","name":"lookup","arguments":"{\"q\":\"x\"}"}],"usage":{"input_tokens":10,"output_tokens":2}}`,
+		`{"status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"found it"}]}],"usage":{"input_tokens":15,"output_tokens":3}}`,
+	}}
+	router := newAssistantsTestRouter(t, executor)
+
+	assistant := doAssistantsRequest(t, router, http.MethodPost, "/v1/assistants", "key-a",
+		`{"model":"assistants-model","instructions":"be brief","tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}}}]}`)
+	thread := doAssistantsRequest(t, router, http.MethodPost, "/v1/threads", "key-a",
+		`{"messages":[{"role":"user","content":"find x"}]}`)
+	threadID := thread.Get("id").String()
+
+	run := doAssistantsRequest(t, router, http.MethodPost, "/v1/threads/"+threadID+"/runs", "key-a",
+		`{"assistant_id":"`+assistant.Get("id").String()+`","additional_instructions":"use tools"}`)
+	if got := run.Get("status").String(); got != runStatusRequiresAction {
+		t.Fatalf("run status = %q, want %q", got, runStatusRequiresAction)
+	}
+	if got := run.Get("required_action.submit_tool_outputs.tool_calls.0.function.name").String(); got != "lookup" {
+		t.Fatalf("tool call name = %q, want lookup", got)
+	}
+
+	first := gjson.Parse(executor.requests[0])
+	if got := first.Get("instructions").String(); got != "be brief\n\nuse tools" {
+		t.Fatalf("instructions = %q", got)
+	}
+	if got := first.Get("prompt_cache_key").String(); got != threadID {
+		t.Fatalf("prompt_cache_key = %q, want %q", got, threadID)
+	}
+	if got := first.Get("tools.0.name").String(); got != "lookup" {
+		t.Fatalf("tool name = %q, want lookup", got)
+	}
+	if got := first.Get("input.0.content.0.text").String(); got != "find x" {
+		t.Fatalf("input text = %q, want find x", got)
+	}
+
+	run = doAssistantsRequest(t, router, http.MethodPost, "/v1/threads/"+threadID+"/runs/"+run.Get("id").String()+"/submit_tool_outputs", "key-a",
+		`{"tool_outputs":[{"tool_call_id":"call_1","output":"x=42"}]}`)
+	if got := run.Get("status").String(); got != runStatusCompleted {
+		t.Fatalf("run status = %q, want %q", got, runStatusCompleted)
+	}
+	if got := run.Get("usage.total_tokens").Int(); got != 30 {
+		t.Fatalf("total tokens = %d, want 30", got)
+	}
+
+	second := gjson.Parse(executor.requests[1])
+	if got := second.Get(`input.#(type=="function_call").call_id`).String(); got != "call_1" {
+		t.Fatalf("replayed call_id = %q, want call_1", got)
+	}
+	if got := second.Get(`input.#(type=="function_call_output").output`).String(); got != "x=42" {
+		t.Fatalf("tool output = %q, want x=42", got)
+	}
+
+	messages := doAssistantsRequest(t, router, http.MethodGet, "/v1/threads/"+threadID+"/messages?order=asc", "key-a", "")
+	if got := messages.Get("data.#").Int(); got != 2 {
+		t.Fatalf("messages = %d, want 2", got)
+	}
+	if got := messages.Get("data.1.content.0.text.value").String(); got != "found it" {
+		t.Fatalf("assistant reply = %q, want found it", got)
+	}
+}
+
+func TestAssistantsScopedToClientKey(t *testing.T) {
+	router := newAssistantsTestRouter(t, &scriptedResponsesExecutor{})
+	assistant := doAssistantsRequest(t, router, http.MethodPost, "/v1/assistants", "key-a", `{"model":"assistants-model"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/assistants/"+assistant.Get("id").String(), nil)
+	req.Header.Set("Authorization", "key-b")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusNotFound)
+	}
+}
+
+func TestAssistantsRejectsStreamingRuns(t *testing.T) {
+	executor := &scriptedResponsesExecutor{}
+	router := newAssistantsTestRouter(t, executor)
+	assistant := doAssistantsRequest(t, router, http.MethodPost, "/v1/assistants", "key-a", `{"model":"assistants-model"}`)
+	thread := doAssistantsRequest(t, router, http.MethodPost, "/v1/threads", "key-a", `{}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/threads/"+thread.Get("id").String()+"/runs",
+		strings.NewReader(`{"assistant_id":"`+assistant.Get("id").String()+`","stream":true}`))
+	req.Header.Set("Authorization", "key-a")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusBadRequest)
+	}
+	if len(executor.requests) != 0 {
+		t.Fatalf("executor requests = %d, want 0", len(executor.requests))
+	}
+}
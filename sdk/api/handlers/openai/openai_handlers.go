@@ -82,6 +82,7 @@ func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
 
 		filteredModels[i] = filteredModel
 	}
+	h.MarkDegradedModels(filteredModels)
 
 	c.JSON(http.StatusOK, gin.H{
 		"object": "list",
@@ -120,6 +121,11 @@ func (h *OpenAIAPIHandler) ChatCompletions(c *gin.Context) {
 		stream = gjson.GetBytes(rawJSON, "stream").Bool()
 	}
 
+	if h.ServerToolsApply(c, rawJSON) {
+		h.handleServerToolsResponse(c, rawJSON, stream)
+		return
+	}
+
 	if stream {
 		h.handleStreamingResponse(c, rawJSON)
 	} else {
@@ -128,6 +134,67 @@ func (h *OpenAIAPIHandler) ChatCompletions(c *gin.Context) {
 
 }
 
+// ChatCompletionsCompact handles the /v1/chat/completions/compact endpoint.
+// It accepts a Chat Completions request body and returns the conversation compacted by the
+// backend's native compaction, as Chat Completions messages. The stream flag is ignored.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) ChatCompletionsCompact(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	stopKeepAlive := h.StartNonStreamingKeepAlive(c, cliCtx)
+	resp, errMsg := h.CompactConversation(cliCtx, h.HandlerType(), modelName, rawJSON)
+	stopKeepAlive()
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
+// Moderations handles the /v1/moderations endpoint by passing the request to the configured
+// moderation backend and relaying its response unchanged.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) Moderations(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	status, resp, errMsg := h.ForwardModeration(cliCtx, rawJSON)
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	c.Data(status, "application/json", resp)
+	cliCancel()
+}
+
 // shouldTreatAsResponsesFormat detects OpenAI Responses-style payloads that are
 // accidentally sent to the Chat Completions endpoint.
 func shouldTreatAsResponsesFormat(rawJSON []byte) bool {
@@ -509,6 +576,102 @@ func (h *OpenAIAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON []byt
 	}
 }
 
+// handleServerToolsResponse runs a chat completions request with the server-side tools. The
+// tool loop executes without streaming, so streaming clients receive the final completion as
+// a single content chunk followed by the finish chunk. With mcp-tools.stream-events, they also
+// receive each server-side tool call and result as a named event while the loop runs.
+func (h *OpenAIAPIHandler) handleServerToolsResponse(c *gin.Context, rawJSON []byte, stream bool) {
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	flusher, _ := c.Writer.(http.Flusher)
+
+	streaming := false
+	startStream := func() {
+		if streaming {
+			return
+		}
+		streaming = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("Access-Control-Allow-Origin", "*")
+	}
+	var onEvent func(handlers.ServerToolEvent)
+	if stream && h.Cfg != nil && h.Cfg.MCPTools.StreamEvents {
+		onEvent = func(event handlers.ServerToolEvent) {
+			startStream()
+			data, _ := json.Marshal(event)
+			_, _ = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	resp, errMsg := h.ExecuteChatWithServerToolEvents(cliCtx, modelName, rawJSON, h.GetAlt(c), onEvent)
+	if errMsg != nil {
+		if streaming {
+			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(h.ErrorMessageBody(c, errMsg)))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		} else {
+			h.WriteErrorResponse(c, errMsg)
+		}
+		cliCancel(errMsg.Error)
+		return
+	}
+	if !stream {
+		c.Header("Content-Type", "application/json")
+		_, _ = c.Writer.Write(resp)
+		cliCancel()
+		return
+	}
+
+	startStream()
+	for _, chunk := range convertChatCompletionToStreamChunks(resp) {
+		_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
+	}
+	_, _ = fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+	cliCancel()
+}
+
+// convertChatCompletionToStreamChunks splits a chat completion into the chat.completion.chunk
+// events a streaming response would have produced: one delta with the message and one with
+// the finish reason and usage.
+func convertChatCompletionToStreamChunks(rawJSON []byte) [][]byte {
+	root := gjson.ParseBytes(rawJSON)
+	base := []byte(`{"id":"","object":"chat.completion.chunk","created":0,"model":"","choices":[]}`)
+	base, _ = sjson.SetBytes(base, "id", root.Get("id").String())
+	base, _ = sjson.SetBytes(base, "created", root.Get("created").Int())
+	base, _ = sjson.SetBytes(base, "model", root.Get("model").String())
+
+	message := root.Get("choices.0.message")
+	delta := []byte(`{"role":"assistant"}`)
+	if content := message.Get("content"); content.Exists() && content.Type != gjson.Null {
+		delta, _ = sjson.SetBytes(delta, "content", content.String())
+	}
+	if reasoning := message.Get("reasoning_content"); reasoning.Exists() {
+		delta, _ = sjson.SetBytes(delta, "reasoning_content", reasoning.String())
+	}
+	for i, call := range message.Get("tool_calls").Array() {
+		toolCall, _ := sjson.SetBytes([]byte(call.Raw), "index", i)
+		delta, _ = sjson.SetRawBytes(delta, "tool_calls.-1", toolCall)
+	}
+	first, _ := sjson.SetRawBytes(base, "choices.-1", []byte(`{"index":0,"delta":{},"finish_reason":null}`))
+	first, _ = sjson.SetRawBytes(first, "choices.0.delta", delta)
+
+	last, _ := sjson.SetRawBytes(base, "choices.-1", []byte(`{"index":0,"delta":{},"finish_reason":""}`))
+	last, _ = sjson.SetBytes(last, "choices.0.finish_reason", root.Get("choices.0.finish_reason").String())
+	if usage := root.Get("usage"); usage.Exists() {
+		last, _ = sjson.SetRawBytes(last, "usage", []byte(usage.Raw))
+	}
+	return [][]byte{first, last}
+}
+
 // handleCompletionsNonStreamingResponse handles non-streaming completions responses.
 // It converts completions request to chat completions format, sends to backend,
 // then converts the response back to completions format before sending to client.
@@ -647,6 +810,7 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 }
 func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
+		SalvageFormat: h.HandlerType(),
 		WriteChunk: func(chunk []byte) {
 			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
 		},
@@ -654,15 +818,7 @@ func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flush
 			if errMsg == nil {
 				return
 			}
-			status := http.StatusInternalServerError
-			if errMsg.StatusCode > 0 {
-				status = errMsg.StatusCode
-			}
-			errText := http.StatusText(status)
-			if errMsg.Error != nil && errMsg.Error.Error() != "" {
-				errText = errMsg.Error.Error()
-			}
-			body := handlers.BuildErrorResponseBody(status, errText)
+			body := h.ErrorMessageBody(c, errMsg)
 			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(body))
 		},
 		WriteDone: func() {
@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func newMCPToolServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		msg := gjson.ParseBytes(body)
+		if !msg.Get("id").Exists() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		result := `{}`
+		switch msg.Get("method").String() {
+		case "tools/list":
+			result = `{"tools":[{"name":"weather","inputSchema":{"type":"object","properties":{"city":{"type":"string"}}}}]}`
+		case "tools/call":
+			result = `{"content":[{"type":"text","text":"sunny in ` + msg.Get("params.arguments.city").String() + `"}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":`+msg.Get("id").Raw+`,"result":`+result+`}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChatCompletionsExecutesMCPTools(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &scriptedResponsesExecutor{responses: []string{
+		`{"id":"c1","object":"chat.completion","model":"mcp-tools-model","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"mcp__tools__weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		`{"id":"c2","object":"chat.completion","model":"mcp-tools-model","choices":[{"index":0,"message":{"role":"assistant","content":"It is sunny."},"finish_reason":"stop"}],"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23}}`,
+	}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "mcp-tools-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "mcp-tools-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	cfg := &sdkconfig.SDKConfig{MCPTools: sdkconfig.MCPToolsConfig{
+		Servers:       []sdkconfig.MCPServer{{Name: "tools", URL: newMCPToolServer(t).URL}},
+		MaxIterations: 4,
+	}}
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(cfg, manager))
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"mcp-tools-model","stream":true,"messages":[{"role":"user","content":"Weather in Paris?"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if len(executor.requests) != 2 {
+		t.Fatalf("executor calls = %d, want 2; body = %s", len(executor.requests), resp.Body.String())
+	}
+	first := gjson.Parse(executor.requests[0])
+	if first.Get("stream").Bool() || first.Get("tools.0.function.name").String() != "mcp__tools__weather" {
+		t.Fatalf("first request = %s", executor.requests[0])
+	}
+	second := gjson.Parse(executor.requests[1])
+	if got := second.Get("messages.2.content").String(); got != "sunny in Paris" || second.Get("messages.2.tool_call_id").String() != "call_1" {
+		t.Fatalf("tool result message = %s", second.Get("messages").Raw)
+	}
+
+	body := resp.Body.String()
+	if !strings.Contains(body, `"content":"It is sunny."`) || !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Fatalf("stream body = %s", body)
+	}
+	if !strings.Contains(body, `"total_tokens":38`) {
+		t.Fatalf("usage was not summed: %s", body)
+	}
+}
+
+func TestChatCompletionsStreamsMCPToolEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &scriptedResponsesExecutor{responses: []string{
+		`{"id":"c1","object":"chat.completion","model":"mcp-events-model","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"mcp__tools__weather","arguments":"{\"city\":\"Oslo\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"id":"c2","object":"chat.completion","model":"mcp-events-model","choices":[{"index":0,"message":{"role":"assistant","content":"It is sunny."},"finish_reason":"stop"}]}`,
+	}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "mcp-events-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "mcp-events-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	cfg := &sdkconfig.SDKConfig{MCPTools: sdkconfig.MCPToolsConfig{
+		Servers:      []sdkconfig.MCPServer{{Name: "tools", URL: newMCPToolServer(t).URL}},
+		StreamEvents: true,
+	}}
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(cfg, manager))
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"mcp-events-model","stream":true,"messages":[{"role":"user","content":"Weather in Oslo?"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	body := resp.Body.String()
+	callAt := strings.Index(body, "event: server_tool.call\ndata: ")
+	resultAt := strings.Index(body, "event: server_tool.result\ndata: ")
+	answerAt := strings.Index(body, `"content":"It is sunny."`)
+	if callAt < 0 || resultAt < callAt || answerAt < resultAt {
+		t.Fatalf("want tool call, tool result and answer in order; body = %s", body)
+	}
+	if !strings.Contains(body, `"call_id":"call_1","name":"mcp__tools__weather","arguments":"{\"city\":\"Oslo\"}"`) {
+		t.Fatalf("tool call event does not describe the call: %s", body)
+	}
+	if !strings.Contains(body, `"output":"sunny in Oslo"`) {
+		t.Fatalf("tool result event does not carry the output: %s", body)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Fatalf("stream body = %s", body)
+	}
+}
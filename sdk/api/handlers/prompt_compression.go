@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// defaultKeepRecentTurns is the number of latest turns kept verbatim when keep-recent is unset.
+const defaultKeepRecentTurns = 6
+
+const promptSummaryInstructions = "Summarize the following excerpt of an ongoing conversation so it can replace the original turns. " +
+	"Keep decisions, facts, file names, code identifiers, tool results that matter later, open tasks and user preferences. " +
+	"Reply with the summary only."
+
+// compressPrompt applies the configured prompt-compression strategy when the estimated input
+// exceeds the threshold. Compression is best effort: the original payload is returned whenever
+// the conversation cannot be compressed.
+func (h *BaseAPIHandler) compressPrompt(ctx context.Context, handlerType, model string, rawJSON []byte) []byte {
+	if h == nil || h.Cfg == nil {
+		return rawJSON
+	}
+	pc := h.Cfg.PromptCompression
+	if pc.Strategy == "" || pc.ThresholdTokens <= 0 || !matchesAnyModelPattern(pc.Models, model) {
+		return rawJSON
+	}
+	tokens := requestInputTokens(ctx, rawJSON)
+	if tokens <= pc.ThresholdTokens {
+		return rawJSON
+	}
+	keepRecent := pc.KeepRecent
+	if keepRecent <= 0 {
+		keepRecent = defaultKeepRecentTurns
+	}
+	path := conversationPath(handlerType)
+	items := gjson.GetBytes(rawJSON, path).Array()
+	start, end := compressibleTurns(items, keepRecent)
+	if end-start < 2 {
+		return rawJSON
+	}
+
+	strategy := pc.Strategy
+	note := fmt.Sprintf("[%d earlier conversation turns were omitted to save context.]", end-start)
+	if strategy == config.PromptCompressionSummarize {
+		summary, errSummary := h.summarizeTurns(ctx, pc.SummaryModel, items[start:end])
+		if errSummary != nil {
+			log.Warnf("prompt compression: summary via %s failed, dropping middle turns instead: %v", pc.SummaryModel, errSummary)
+			strategy = config.PromptCompressionDropMiddle
+		} else {
+			note = "Summary of the earlier conversation:\n" + summary
+		}
+	}
+
+	kept := make([]string, 0, start+1+len(items)-end)
+	for _, item := range items[:start] {
+		kept = append(kept, item.Raw)
+	}
+	kept = append(kept, noteTurn(handlerType, note))
+	for _, item := range items[end:] {
+		kept = append(kept, item.Raw)
+	}
+	updated, errSet := sjson.SetRawBytes(rawJSON, path, []byte("["+strings.Join(kept, ",")+"]"))
+	if errSet != nil {
+		return rawJSON
+	}
+	log.Infof("prompt compression: %s replaced %d turns for model %s (%d -> %d tokens)", strategy, end-start, model, tokens, estimateInputTokens(updated))
+	return updated
+}
+
+// compressibleTurns returns the range [start, end) of turns that may be replaced. Leading system
+// turns, the first user turn, and the latest keepRecent turns are kept; the kept tail never
+// starts with a tool result whose call would be removed.
+func compressibleTurns(items []gjson.Result, keepRecent int) (int, int) {
+	start := 0
+	for start < len(items) && isSystemTurn(items[start]) {
+		start++
+	}
+	if start < len(items) && isUserTurn(items[start]) && !isToolResultTurn(items[start]) {
+		start++
+	}
+	end := len(items) - keepRecent
+	for end > start && isToolResultTurn(items[end]) {
+		end--
+	}
+	return start, end
+}
+
+// noteTurn builds a user turn carrying text in the conversation format of handlerType.
+func noteTurn(handlerType, text string) string {
+	var turn []byte
+	switch handlerType {
+	case constant.Gemini, constant.GeminiCLI:
+		turn, _ = sjson.SetBytes([]byte(`{"role":"user","parts":[{"text":""}]}`), "parts.0.text", text)
+	case constant.OpenaiResponse:
+		turn, _ = sjson.SetBytes([]byte(`{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`), "content.0.text", text)
+	default:
+		turn, _ = sjson.SetBytes([]byte(`{"role":"user","content":""}`), "content", text)
+	}
+	return string(turn)
+}
+
+// summarizeTurns asks model, through the auth manager, for a summary of turns.
+func (h *BaseAPIHandler) summarizeTurns(ctx context.Context, model string, turns []gjson.Result) (string, error) {
+	return h.summarizeText(ctx, model, promptSummaryInstructions, renderTranscript(turns))
+}
+
+// summarizeText asks model, through the auth manager, to rewrite text following instructions.
+func (h *BaseAPIHandler) summarizeText(ctx context.Context, model, instructions, text string) (string, error) {
+	providers, normalizedModel, errMsg := h.getRequestDetails(model)
+	if errMsg != nil {
+		return "", errMsg.Error
+	}
+	payload := []byte(`{"messages":[{"role":"system","content":""},{"role":"user","content":""}]}`)
+	payload, _ = sjson.SetBytes(payload, "model", normalizedModel)
+	payload, _ = sjson.SetBytes(payload, "messages.0.content", instructions)
+	payload, _ = sjson.SetBytes(payload, "messages.1.content", text)
+
+	meta := requestExecutionMetadata(ctx)
+	meta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	req := coreexecutor.Request{Model: normalizedModel, Payload: payload}
+	opts := coreexecutor.Options{
+		OriginalRequest: cloneBytes(payload),
+		SourceFormat:    sdktranslator.FromString(constant.OpenAI),
+		Metadata:        meta,
+	}
+	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(gjson.GetBytes(resp.Payload, "choices.0.message.content").String())
+	if summary == "" {
+		return "", errors.New("empty summary")
+	}
+	return summary, nil
+}
+
+// renderTranscript flattens turns of any client format into "role: text" paragraphs.
+func renderTranscript(turns []gjson.Result) string {
+	var b strings.Builder
+	for _, turn := range turns {
+		role := turn.Get("role").String()
+		if role == "" {
+			role = turn.Get("type").String()
+		}
+		segments := make([]string, 0, 4)
+		for _, key := range []string{"content", "parts", "output", "arguments", "name"} {
+			collectTextSegments(turn.Get(key), &segments)
+		}
+		if len(segments) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(role)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(segments, "\n"))
+	}
+	return b.String()
+}
+
+func matchesAnyModelPattern(patterns []string, model string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if wildcard.Match(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func longConversationPayload() []byte {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	turns := []string{`{"role":"system","content":"be brief"}`, `{"role":"user","content":"task"}`}
+	for i := 0; i < 4; i++ {
+		turns = append(turns, `{"role":"assistant","content":"`+filler+`"}`, `{"role":"user","content":"`+filler+`"}`)
+	}
+	turns = append(turns, `{"role":"user","content":"latest question"}`)
+	return []byte(`{"model":"m","messages":[` + strings.Join(turns, ",") + `]}`)
+}
+
+func TestCompressPromptDropMiddle(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{PromptCompression: sdkconfig.PromptCompressionConfig{
+		Strategy: "drop-middle", ThresholdTokens: 100, KeepRecent: 2,
+	}}, coreauth.NewManager(nil, nil, nil))
+
+	out := h.compressPrompt(context.Background(), "openai", "m", longConversationPayload())
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 5 {
+		t.Fatalf("messages = %d, want 5: %s", len(messages), out)
+	}
+	if messages[0].Get("role").String() != "system" || messages[1].Get("content").String() != "task" {
+		t.Fatalf("leading turns not kept: %s", out)
+	}
+	if note := messages[2].Get("content").String(); !strings.Contains(note, "7 earlier conversation turns") {
+		t.Fatalf("note = %q", note)
+	}
+	if messages[4].Get("content").String() != "latest question" {
+		t.Fatalf("latest turn not kept: %s", out)
+	}
+}
+
+func TestCompressPromptBelowThresholdOrOtherModel(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{PromptCompression: sdkconfig.PromptCompressionConfig{
+		Strategy: "drop-middle", ThresholdTokens: 100000, Models: []string{"claude-*"},
+	}}, coreauth.NewManager(nil, nil, nil))
+
+	payload := longConversationPayload()
+	if out := h.compressPrompt(context.Background(), "openai", "claude-sonnet", payload); string(out) != string(payload) {
+		t.Fatalf("payload below threshold was modified")
+	}
+	h.Cfg.PromptCompression.ThresholdTokens = 100
+	if out := h.compressPrompt(context.Background(), "openai", "gpt-5", payload); string(out) != string(payload) {
+		t.Fatalf("payload for unmatched model was modified")
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// ServedModelHeader names the model that produced a response rerouted away from the requested model.
+	ServedModelHeader = "X-Served-Model"
+	// FallbackReasonHeader explains why the response was served by another model.
+	FallbackReasonHeader = "X-Fallback-Reason"
+
+	fallbackReasonContentPolicy   = "content-policy"
+	fallbackReasonContextOverflow = "context-overflow"
+)
+
+// contentPolicyMarkers are lower-cased fragments providers use when rejecting a request on policy grounds.
+var contentPolicyMarkers = []string{
+	"content_filter",
+	"content filter",
+	"content_policy",
+	"content policy",
+	"content management policy",
+	"responsible ai",
+	"usage policies",
+	"safety system",
+	"prohibited_content",
+	"blocked due to safety",
+}
+
+// refusalFallbackModel returns the configured fallback for model, if refusal fallback is enabled.
+func (h *BaseAPIHandler) refusalFallbackModel(model string) (string, bool) {
+	if h == nil || h.Cfg == nil || !h.Cfg.RefusalFallback.Enable {
+		return "", false
+	}
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return "", false
+	}
+	for _, rule := range h.Cfg.RefusalFallback.Rules {
+		if rule.Fallback == "" || strings.EqualFold(rule.Fallback, model) {
+			continue
+		}
+		if wildcard.Match(rule.Model, model) {
+			return rule.Fallback, true
+		}
+	}
+	return "", false
+}
+
+// executeRefusalFallback retries a refused non-streaming request against the fallback model.
+// attempted is false when the fallback could not be resolved, in which case the original result stands.
+func (h *BaseAPIHandler) executeRefusalFallback(ctx context.Context, fallbackModel string, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, bool, error) {
+	providers, fbReq, fbOpts, ok := h.prepareRefusalFallback(ctx, fallbackModel, req, opts)
+	if !ok {
+		return coreexecutor.Response{}, false, nil
+	}
+	resp, err := h.AuthManager.Execute(ctx, providers, fbReq, fbOpts)
+	if err == nil {
+		annotateServedModel(ctx, fbReq.Model, fallbackReasonContentPolicy)
+	}
+	return resp, true, err
+}
+
+// executeRefusalFallbackStream retries a refused streaming request against the fallback model.
+func (h *BaseAPIHandler) executeRefusalFallbackStream(ctx context.Context, fallbackModel string, req coreexecutor.Request, opts coreexecutor.Options) (<-chan coreexecutor.StreamChunk, bool, error) {
+	providers, fbReq, fbOpts, ok := h.prepareRefusalFallback(ctx, fallbackModel, req, opts)
+	if !ok {
+		return nil, false, nil
+	}
+	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, fbReq, fbOpts)
+	if err == nil {
+		annotateServedModel(ctx, fbReq.Model, fallbackReasonContentPolicy)
+	}
+	return chunks, true, err
+}
+
+func (h *BaseAPIHandler) prepareRefusalFallback(ctx context.Context, fallbackModel string, req coreexecutor.Request, opts coreexecutor.Options) ([]string, coreexecutor.Request, coreexecutor.Options, bool) {
+	if !h.catalogPermits(ctx, fallbackModel) {
+		log.Warnf("refusal fallback: model %s is outside the client's model catalog", fallbackModel)
+		return nil, req, opts, false
+	}
+	providers, normalizedModel, errMsg := h.getRequestDetails(fallbackModel)
+	if errMsg != nil {
+		log.Warnf("refusal fallback: model %s unavailable: %v", fallbackModel, errMsg.Error)
+		return nil, req, opts, false
+	}
+	log.Infof("refusal fallback: model %s refused the request, retrying with %s", req.Model, normalizedModel)
+	req.Model = normalizedModel
+	req.Payload = cloneBytes(req.Payload)
+	meta := make(map[string]any, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	opts.Metadata = meta
+	opts.OriginalRequest = cloneBytes(opts.OriginalRequest)
+	return providers, req, opts, true
+}
+
+// annotateServedModel records on the client response which model answered and why.
+func annotateServedModel(ctx context.Context, servedModel, reason string) {
+	if ctx == nil {
+		return
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	if ginCtx == nil || ginCtx.Writer == nil || ginCtx.Writer.Written() {
+		return
+	}
+	ginCtx.Header(ServedModelHeader, servedModel)
+	ginCtx.Header(FallbackReasonHeader, reason)
+}
+
+// isContentPolicyError reports whether an upstream error is a content-policy refusal.
+func isContentPolicyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch statusFromError(err) {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusUnavailableForLegalReasons:
+	default:
+		return false
+	}
+	text := strings.ToLower(err.Error())
+	for _, marker := range contentPolicyMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isContentPolicyRefusalPayload reports whether a successful response was blocked or refused by the model,
+// covering OpenAI chat/responses, Claude, and Gemini (including the Gemini CLI envelope) payloads.
+func isContentPolicyRefusalPayload(payload []byte) bool {
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return false
+	}
+	root := gjson.ParseBytes(payload)
+	if inner := root.Get("response"); inner.IsObject() {
+		root = inner
+	}
+	for _, choice := range root.Get("choices").Array() {
+		if choice.Get("finish_reason").String() == "content_filter" {
+			return true
+		}
+	}
+	if root.Get("incomplete_details.reason").String() == "content_filter" {
+		return true
+	}
+	if root.Get("stop_reason").String() == "refusal" {
+		return true
+	}
+	if root.Get("promptFeedback.blockReason").String() != "" {
+		return true
+	}
+	for _, candidate := range root.Get("candidates").Array() {
+		switch candidate.Get("finishReason").String() {
+		case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestRefusalFallbackModelMatchesRules(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{RefusalFallback: sdkconfig.RefusalFallbackConfig{
+		Enable: true,
+		Rules: []sdkconfig.RefusalFallbackRule{
+			{Model: "gpt-5*", Fallback: "local-llama"},
+			{Model: "*", Fallback: "gpt-5-mini"},
+		},
+	}}}
+
+	if got, ok := h.refusalFallbackModel("GPT-5-codex"); !ok || got != "local-llama" {
+		t.Fatalf("expected local-llama, got %q ok=%t", got, ok)
+	}
+	if got, ok := h.refusalFallbackModel("claude-sonnet-4"); !ok || got != "gpt-5-mini" {
+		t.Fatalf("expected catch-all fallback, got %q ok=%t", got, ok)
+	}
+	// A fallback model never falls back to itself.
+	if got, ok := h.refusalFallbackModel("local-llama"); !ok || got != "gpt-5-mini" {
+		t.Fatalf("expected next rule for fallback model, got %q ok=%t", got, ok)
+	}
+
+	h.Cfg.RefusalFallback.Enable = false
+	if _, ok := h.refusalFallbackModel("gpt-5"); ok {
+		t.Fatal("expected no fallback when disabled")
+	}
+}
+
+func TestIsContentPolicyError(t *testing.T) {
+	refusal := &coreauth.Error{HTTPStatus: http.StatusBadRequest, Message: `{"error":{"code":"content_filter","message":"The response was filtered"}}`}
+	if !isContentPolicyError(refusal) {
+		t.Fatal("expected content filter error to be detected")
+	}
+	rateLimit := &coreauth.Error{HTTPStatus: http.StatusTooManyRequests, Message: "content policy"}
+	if isContentPolicyError(rateLimit) {
+		t.Fatal("rate limit must not be treated as a refusal")
+	}
+	badRequest := &coreauth.Error{HTTPStatus: http.StatusBadRequest, Message: "invalid max_tokens"}
+	if isContentPolicyError(badRequest) {
+		t.Fatal("plain bad request must not be treated as a refusal")
+	}
+}
+
+func TestIsContentPolicyRefusalPayload(t *testing.T) {
+	cases := map[string]bool{
+		`{"choices":[{"finish_reason":"content_filter"}]}`:                         true,
+		`{"type":"message","stop_reason":"refusal"}`:                               true,
+		`{"candidates":[{"finishReason":"SAFETY"}]}`:                               true,
+		`{"response":{"promptFeedback":{"blockReason":"OTHER"}}}`:                  true,
+		`{"status":"incomplete","incomplete_details":{"reason":"content_filter"}}`: true,
+		`{"choices":[{"finish_reason":"stop"}]}`:                                   false,
+		`not json`:                                                                 false,
+	}
+	for payload, want := range cases {
+		if got := isContentPolicyRefusalPayload([]byte(payload)); got != want {
+			t.Fatalf("payload %s: got %t want %t", payload, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+const (
+	defaultReloginNoticeHeader  = "X-Proxy-Notice"
+	defaultReloginNoticeMessage = "{count} {provider} account(s) need to log in again; capacity is reduced."
+	// reloginNoticeMaxSent bounds the remembered (client key, auth) notices.
+	reloginNoticeMaxSent = 4096
+)
+
+// reloginNoticesSent remembers which client keys were told about which relogin, so each
+// client receives a notice once per account needing a login.
+var reloginNoticesSent = struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}{keys: make(map[string]struct{})}
+
+func reloginNoticeHeader(cfg config.ReloginNoticeConfig) string {
+	if header := strings.TrimSpace(cfg.Header); header != "" {
+		return header
+	}
+	return defaultReloginNoticeHeader
+}
+
+// reloginNotice returns the notice for a client served by auth when other auths of its provider
+// need a new login that the client has not been told about yet, and "" otherwise.
+func (h *BaseAPIHandler) reloginNotice(clientKey string, served *coreauth.Auth) string {
+	if h == nil || h.Cfg == nil || !h.Cfg.ReloginNotice.Enable || h.AuthManager == nil || served == nil {
+		return ""
+	}
+	pending := h.AuthManager.ReloginRequired(served.Provider)
+	count := 0
+	unseen := false
+	reloginNoticesSent.mu.Lock()
+	for _, state := range pending {
+		if state.AuthID == served.ID {
+			continue
+		}
+		count++
+		key := clientKey + "\x00" + state.AuthID + "\x00" + state.Since.Format(time.RFC3339Nano)
+		if _, sent := reloginNoticesSent.keys[key]; sent {
+			continue
+		}
+		if len(reloginNoticesSent.keys) >= reloginNoticeMaxSent {
+			reloginNoticesSent.keys = make(map[string]struct{})
+		}
+		reloginNoticesSent.keys[key] = struct{}{}
+		unseen = true
+	}
+	reloginNoticesSent.mu.Unlock()
+	if !unseen {
+		return ""
+	}
+	message := strings.TrimSpace(h.Cfg.ReloginNotice.Message)
+	if message == "" {
+		message = defaultReloginNoticeMessage
+	}
+	return strings.NewReplacer("{count}", strconv.Itoa(count), "{provider}", served.Provider).Replace(message)
+}
+
+// MarkDegradedModels sets "degraded_capacity": true on the listed models served by a provider
+// with accounts that need to log in again.
+func (h *BaseAPIHandler) MarkDegradedModels(models []map[string]any) {
+	if h == nil || h.AuthManager == nil || len(models) == 0 {
+		return
+	}
+	pending := h.AuthManager.ReloginRequired("")
+	if len(pending) == 0 {
+		return
+	}
+	degraded := make(map[string]struct{}, len(pending))
+	for _, state := range pending {
+		degraded[state.Provider] = struct{}{}
+	}
+	modelRegistry := registry.GetGlobalRegistry()
+	for _, model := range models {
+		id, _ := model["id"].(string)
+		if id == "" {
+			continue
+		}
+		for _, provider := range modelRegistry.GetModelProviders(id) {
+			if _, ok := degraded[provider]; ok {
+				model["degraded_capacity"] = true
+				break
+			}
+		}
+	}
+}
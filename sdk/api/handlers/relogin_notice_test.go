@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func newReloginNoticeTestHandler(t *testing.T) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&staticResponseExecutor{payload: `{"id":"r1","model":"gpt-5"}`})
+	for _, id := range []string{"notice-served", "notice-expired"} {
+		auth := &coreauth.Auth{ID: id, Provider: "codex", Status: coreauth.StatusActive}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("manager.Register: %v", err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(id, "codex", []*registry.ModelInfo{{ID: "gpt-5"}})
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	}
+	manager.MarkResult(context.Background(), coreauth.Result{
+		AuthID:   "notice-expired",
+		Provider: "codex",
+		Model:    "gpt-5",
+		Error:    &coreauth.Error{HTTPStatus: 401, Message: "token revoked"},
+	})
+
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{ReloginNotice: sdkconfig.ReloginNoticeConfig{
+		Enable: true,
+		Field:  "proxy_notice",
+	}}, manager)
+}
+
+func TestExecuteWithAuthManager_SendsReloginNoticeOnce(t *testing.T) {
+	h := newReloginNoticeTestHandler(t)
+
+	ctx, rec := responseRulesTestContext("notice-key")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	want := "1 codex account(s) need to log in again; capacity is reduced."
+	if got := rec.Header().Get("X-Proxy-Notice"); got != want {
+		t.Fatalf("notice header = %q, want %q", got, want)
+	}
+	if got := gjson.GetBytes(out, "proxy_notice").String(); got != want {
+		t.Fatalf("notice field = %q, want %q", got, want)
+	}
+
+	ctx, rec = responseRulesTestContext("notice-key")
+	out, errMsg = h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if rec.Header().Get("X-Proxy-Notice") != "" || gjson.GetBytes(out, "proxy_notice").Exists() {
+		t.Fatalf("notice repeated for the same client: header=%q body=%s", rec.Header().Get("X-Proxy-Notice"), out)
+	}
+}
+
+func TestMarkDegradedModels(t *testing.T) {
+	h := newReloginNoticeTestHandler(t)
+
+	models := []map[string]any{{"id": "gpt-5"}, {"id": "unrelated-model"}}
+	h.MarkDegradedModels(models)
+	if models[0]["degraded_capacity"] != true {
+		t.Fatalf("gpt-5 not marked degraded: %+v", models[0])
+	}
+	if _, ok := models[1]["degraded_capacity"]; ok {
+		t.Fatalf("unrelated model marked degraded: %+v", models[1])
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultRepeatedPromptThreshold = 5
+	defaultRepeatedPromptWindow    = time.Minute
+	defaultRepeatedPromptBaseDelay = 500 * time.Millisecond
+	defaultRepeatedPromptMaxDelay  = 30 * time.Second
+	defaultRepeatedPromptBlock     = 5 * time.Minute
+)
+
+// repeatedPromptVolatileFields are ignored when comparing requests, so a script re-sending the
+// same prompt with a different stream flag or user tag still counts as repeating itself.
+var repeatedPromptVolatileFields = []string{"stream", "stream_options", "user", "metadata"}
+
+// repeatedPromptBlockedError reports a request rejected for repeating the same prompt too often.
+type repeatedPromptBlockedError struct {
+	retryAfter time.Duration
+}
+
+func (e *repeatedPromptBlockedError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf("Too many identical requests: this request was repeated too often and is blocked for %d more seconds.", retryAfterSeconds(e.retryAfter)),
+			"type":    "rate_limit_error",
+			"code":    "repeated_prompt_blocked",
+		},
+	})
+	return string(body)
+}
+
+func (e *repeatedPromptBlockedError) StatusCode() int { return http.StatusTooManyRequests }
+
+func (e *repeatedPromptBlockedError) ProxyGenerated() bool { return true }
+
+func (e *repeatedPromptBlockedError) RetryAfter() *time.Duration { return &e.retryAfter }
+
+// repeatedPromptAudit is the audit record of one delayed or blocked request.
+type repeatedPromptAudit struct {
+	Time         time.Time  `json:"time"`
+	RequestID    string     `json:"request_id,omitempty"`
+	ClientKey    string     `json:"client_key"`
+	Model        string     `json:"model,omitempty"`
+	Fingerprint  string     `json:"fingerprint"`
+	Count        int        `json:"count"`
+	Action       string     `json:"action"`
+	DelayMs      int64      `json:"delay_ms,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+// repeatedPrompt counts the repeats of one request by one client key.
+type repeatedPrompt struct {
+	count        int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+// repeatedPromptTracker counts identical requests per client key.
+type repeatedPromptTracker struct {
+	mu   sync.Mutex
+	keys map[string]map[string]*repeatedPrompt
+}
+
+var defaultRepeatedPrompts = &repeatedPromptTracker{keys: make(map[string]map[string]*repeatedPrompt)}
+
+// observe counts a request with the given fingerprint and returns its repeats within the window
+// and the end of its block, if any. Expired entries of the key are dropped.
+func (t *repeatedPromptTracker) observe(key, fingerprint string, window time.Duration, now time.Time) (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prompts := t.keys[key]
+	if prompts == nil {
+		prompts = make(map[string]*repeatedPrompt)
+		t.keys[key] = prompts
+	}
+	for fp, prompt := range prompts {
+		if now.Sub(prompt.windowStart) >= window && !now.Before(prompt.blockedUntil) {
+			delete(prompts, fp)
+		}
+	}
+	prompt := prompts[fingerprint]
+	if prompt == nil {
+		prompt = &repeatedPrompt{windowStart: now}
+		prompts[fingerprint] = prompt
+	}
+	prompt.count++
+	return prompt.count, prompt.blockedUntil
+}
+
+// block rejects the request with the given fingerprint until the given time.
+func (t *repeatedPromptTracker) block(key, fingerprint string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if prompt := t.keys[key][fingerprint]; prompt != nil {
+		prompt.blockedUntil = until
+	}
+}
+
+// checkRepeatedPrompt counts the request against the repeats of its client key. Past the
+// threshold, the request is delayed exponentially or blocked, and the action is audited.
+func (h *BaseAPIHandler) checkRepeatedPrompt(ctx context.Context, handlerType, modelName string, rawJSON []byte) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || !h.Cfg.RepeatedPrompts.Enabled {
+		return nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	key := clientAPIKeyFromGin(ginCtx)
+	if key == "" {
+		return nil
+	}
+	cfg := h.Cfg.RepeatedPrompts
+	override, _ := config.LookupClientKey(h.Cfg, cfg.Keys, key)
+	if override.Exempt {
+		return nil
+	}
+	threshold := cfg.Threshold
+	if override.Threshold > 0 {
+		threshold = override.Threshold
+	}
+	if threshold <= 0 {
+		threshold = defaultRepeatedPromptThreshold
+	}
+	action := cfg.Action
+	if override.Action != "" {
+		action = override.Action
+	}
+	window := time.Duration(cfg.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultRepeatedPromptWindow
+	}
+
+	fingerprint := repeatedPromptFingerprint(handlerType, modelName, rawJSON)
+	now := time.Now()
+	count, blockedUntil := defaultRepeatedPrompts.observe(key, fingerprint, window, now)
+	if now.Before(blockedUntil) {
+		return errorMessageFromError(&repeatedPromptBlockedError{retryAfter: blockedUntil.Sub(now)})
+	}
+	if count <= threshold {
+		return nil
+	}
+
+	entry := repeatedPromptAudit{
+		Time:        now.UTC(),
+		RequestID:   logging.GetRequestID(ctx),
+		ClientKey:   util.HideAPIKey(key),
+		Model:       modelName,
+		Fingerprint: fingerprint[:16],
+		Count:       count,
+		Action:      config.RepeatedPromptDelay,
+	}
+	if action == config.RepeatedPromptBlock {
+		block := time.Duration(cfg.BlockSeconds) * time.Second
+		if block <= 0 {
+			block = defaultRepeatedPromptBlock
+		}
+		until := now.Add(block)
+		defaultRepeatedPrompts.block(key, fingerprint, until)
+		entry.Action = config.RepeatedPromptBlock
+		entry.BlockedUntil = &until
+		writeAuditLine(cfg.AuditFile, "repeated-prompts", entry)
+		return errorMessageFromError(&repeatedPromptBlockedError{retryAfter: block})
+	}
+
+	delay := repeatedPromptDelay(cfg, count-threshold)
+	entry.DelayMs = delay.Milliseconds()
+	writeAuditLine(cfg.AuditFile, "repeated-prompts", entry)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return nil
+}
+
+// repeatedPromptDelay returns the delay of the excess-th repeat over the threshold: the base
+// delay doubled for every earlier repeat, capped at the maximum delay.
+func repeatedPromptDelay(cfg config.RepeatedPromptConfig, excess int) time.Duration {
+	base := time.Duration(cfg.BaseDelayMs) * time.Millisecond
+	if base <= 0 {
+		base = defaultRepeatedPromptBaseDelay
+	}
+	maxDelay := time.Duration(cfg.MaxDelayMs) * time.Millisecond
+	if maxDelay <= 0 {
+		maxDelay = defaultRepeatedPromptMaxDelay
+	}
+	delay := base
+	for i := 1; i < excess && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// repeatedPromptFingerprint hashes the request without its volatile fields.
+func repeatedPromptFingerprint(handlerType, modelName string, rawJSON []byte) string {
+	body := rawJSON
+	for _, field := range repeatedPromptVolatileFields {
+		if updated, err := sjson.DeleteBytes(body, field); err == nil {
+			body = updated
+		}
+	}
+	sum := sha256.New()
+	sum.Write([]byte(handlerType + "\x00" + modelName + "\x00"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
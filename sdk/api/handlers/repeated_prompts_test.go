@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestCheckRepeatedPromptBlocksAndAudits(t *testing.T) {
+	audit := filepath.Join(t.TempDir(), "repeated.jsonl")
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{RepeatedPrompts: sdkconfig.RepeatedPromptConfig{
+		Enabled:   true,
+		Threshold: 2,
+		Action:    "block",
+		AuditFile: audit,
+		Keys: map[string]sdkconfig.RepeatedPromptKeyConfig{
+			"repeat-exempt-key": {Exempt: true},
+		},
+	}}, coreauth.NewManager(nil, nil, nil))
+	payload := []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"same again"}],"stream":false}`)
+	streamed := []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"same again"}],"stream":true}`)
+
+	for i, body := range [][]byte{payload, streamed} {
+		if errMsg := h.checkRepeatedPrompt(catalogTestContext("repeat-block-key"), "openai", "gpt-5", body); errMsg != nil {
+			t.Fatalf("request %d rejected: %v", i, errMsg.Error)
+		}
+	}
+	errMsg := h.checkRepeatedPrompt(catalogTestContext("repeat-block-key"), "openai", "gpt-5", payload)
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the threshold, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "repeated_prompt_blocked" {
+		t.Fatalf("code = %q", code)
+	}
+	if errMsg = h.checkRepeatedPrompt(catalogTestContext("repeat-block-key"), "openai", "gpt-5", []byte(`{"messages":[{"role":"user","content":"different"}]}`)); errMsg != nil {
+		t.Fatalf("a different prompt must pass: %v", errMsg.Error)
+	}
+	for i := 0; i < 4; i++ {
+		if errMsg = h.checkRepeatedPrompt(catalogTestContext("repeat-exempt-key"), "openai", "gpt-5", payload); errMsg != nil {
+			t.Fatalf("exempt key rejected: %v", errMsg.Error)
+		}
+	}
+
+	data, err := os.ReadFile(audit)
+	if err != nil {
+		t.Fatalf("read audit: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || gjson.Get(lines[0], "action").String() != "block" || gjson.Get(lines[0], "count").Int() != 3 {
+		t.Fatalf("audit = %s", data)
+	}
+}
+
+func TestRepeatedPromptDelay(t *testing.T) {
+	cfg := sdkconfig.RepeatedPromptConfig{BaseDelayMs: 100, MaxDelayMs: 350}
+	for excess, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 350 * time.Millisecond, 10: 350 * time.Millisecond} {
+		if got := repeatedPromptDelay(cfg, excess); got != want {
+			t.Fatalf("delay(%d) = %v, want %v", excess, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestGetContextWithCancelRecordsRequestMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+
+	var meta *coreexecutor.RequestMetadata
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set("apiKey", " client-key ")
+		ctx, cancel := handler.GetContextWithCancel(nil, c, c.Request.Context())
+		defer cancel()
+		meta = coreexecutor.RequestMetadataFromContext(ctx)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("User-Agent", "codex_cli_rs/0.1")
+	req.Header.Set("Anthropic-Beta", "beta-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if meta == nil {
+		t.Fatal("expected request metadata")
+	}
+	if meta.UserAgent != "codex_cli_rs/0.1" || meta.ClientKey != "client-key" {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+	if meta.Method != http.MethodPost || meta.Path != "/v1/chat/completions" {
+		t.Fatalf("unexpected route: %s %s", meta.Method, meta.Path)
+	}
+	if got := meta.Headers.Get("Anthropic-Beta"); got != "beta-1" {
+		t.Fatalf("Anthropic-Beta = %q, want beta-1", got)
+	}
+}
+
+func TestRequestMetadataFromContextWithoutMetadata(t *testing.T) {
+	meta := coreexecutor.RequestMetadataFromContext(context.Background())
+	if meta == nil || meta.Headers != nil || meta.UserAgent != "" {
+		t.Fatalf("expected empty metadata, got %+v", meta)
+	}
+}
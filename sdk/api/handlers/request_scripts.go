@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/policyscript"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// requestScriptCredentialHeaders are withheld from scripts so policies never see client secrets.
+var requestScriptCredentialHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"x-goog-api-key":      true,
+	"cookie":              true,
+}
+
+// requestScriptRejectedError reports a request rejected by a request script.
+type requestScriptRejectedError struct {
+	message string
+	status  int
+}
+
+func (e *requestScriptRejectedError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": e.message,
+			"type":    "invalid_request_error",
+			"code":    "request_policy_violation",
+		},
+	})
+	return string(body)
+}
+
+func (e *requestScriptRejectedError) StatusCode() int { return e.status }
+
+func (e *requestScriptRejectedError) ProxyGenerated() bool { return true }
+
+// requestScriptCache keeps compiled scripts, recompiling a script file when it changes.
+var requestScriptCache = struct {
+	sync.Mutex
+	entries map[string]*cachedRequestScript
+}{entries: make(map[string]*cachedRequestScript)}
+
+type cachedRequestScript struct {
+	source  string
+	modTime time.Time
+	size    int64
+	program *policyscript.Program
+	err     error
+}
+
+// loadRequestScript returns the compiled program of script. Compile errors are cached with the
+// source so a broken script is not recompiled on every request.
+func loadRequestScript(script config.RequestScript) (*policyscript.Program, error) {
+	key := script.Name + "\x00" + script.Path
+	var modTime time.Time
+	var size int64
+	if script.Path != "" {
+		info, err := os.Stat(script.Path)
+		if err != nil {
+			return nil, fmt.Errorf("policy script %s: %w", script.Name, err)
+		}
+		modTime, size = info.ModTime(), info.Size()
+	}
+
+	requestScriptCache.Lock()
+	defer requestScriptCache.Unlock()
+	cached := requestScriptCache.entries[key]
+	if cached != nil && cached.modTime.Equal(modTime) && cached.size == size && (script.Path != "" || cached.source == script.Source) {
+		return cached.program, cached.err
+	}
+	source := script.Source
+	if script.Path != "" {
+		data, err := os.ReadFile(script.Path)
+		if err != nil {
+			return nil, fmt.Errorf("policy script %s: %w", script.Name, err)
+		}
+		source = string(data)
+	}
+	program, err := policyscript.Compile(script.Name, source, requestScriptLimits(script))
+	if err != nil {
+		log.Errorf("failed to load request script: %v", err)
+	} else if cached != nil {
+		log.Infof("request script %s reloaded", script.Name)
+	}
+	requestScriptCache.entries[key] = &cachedRequestScript{source: script.Source, modTime: modTime, size: size, program: program, err: err}
+	return program, err
+}
+
+func requestScriptLimits(script config.RequestScript) policyscript.Limits {
+	return policyscript.Limits{
+		Timeout:       time.Duration(script.TimeoutMs) * time.Millisecond,
+		MaxSteps:      uint64(script.MaxSteps),
+		MaxInputBytes: script.MaxInputKB << 10,
+		MaxAllocBytes: int64(script.MaxAllocMB) << 20,
+	}
+}
+
+// requestScriptClientKeyHash identifies the client key to scripts without handing them the key.
+func requestScriptClientKeyHash(clientKey string) string {
+	if clientKey == "" {
+		return ""
+	}
+	return util.ClientKeyHash(clientKey)
+}
+
+// matchingRequestScripts returns the request scripts applying to model and the client key of ctx.
+func (h *BaseAPIHandler) matchingRequestScripts(ctx context.Context, model string) ([]config.RequestScript, string, *gin.Context) {
+	if h == nil || h.Cfg == nil || len(h.Cfg.RequestScripts) == 0 {
+		return nil, "", nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	clientKey := clientAPIKeyFromGin(ginCtx)
+	var scripts []config.RequestScript
+	for _, script := range h.Cfg.RequestScripts {
+		if responseRuleMatches(config.ResponseRule{Models: script.Models, ClientKeys: script.ClientKeys}, model, clientKey) {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, clientKey, ginCtx
+}
+
+// applyRequestScripts runs the on_request function of the matching request scripts and returns
+// the model and payload they chose. A rejection or, for fail-closed scripts, a script failure
+// ends the request with an error.
+func (h *BaseAPIHandler) applyRequestScripts(ctx context.Context, handlerType, modelName string, rawJSON []byte) (string, []byte, *interfaces.ErrorMessage) {
+	scripts, clientKey, ginCtx := h.matchingRequestScripts(ctx, modelName)
+	if len(scripts) == 0 {
+		return modelName, rawJSON, nil
+	}
+	headers := make(map[string]string)
+	if ginCtx != nil && ginCtx.Request != nil {
+		for name, values := range ginCtx.Request.Header {
+			name = strings.ToLower(name)
+			if len(values) > 0 && !requestScriptCredentialHeaders[name] {
+				headers[name] = values[0]
+			}
+		}
+	}
+	for _, script := range scripts {
+		program, err := loadRequestScript(script)
+		var decision policyscript.Decision
+		if err == nil && program.HasRequestHook() {
+			decision, err = program.OnRequest(ctx, requestScriptLimits(script), policyscript.Request{
+				Model:         modelName,
+				Format:        handlerType,
+				ClientKeyHash: requestScriptClientKeyHash(clientKey),
+				Headers:       headers,
+				Body:          rawJSON,
+			})
+		}
+		if err != nil {
+			if errMsg := requestScriptFailed(script, err); errMsg != nil {
+				return modelName, rawJSON, errMsg
+			}
+			continue
+		}
+		if decision.Reject != "" {
+			status := decision.Status
+			if status < http.StatusBadRequest || status > 599 {
+				status = http.StatusForbidden
+			}
+			return modelName, rawJSON, &interfaces.ErrorMessage{StatusCode: status, Error: &requestScriptRejectedError{message: decision.Reject, status: status}}
+		}
+		if len(decision.Body) > 0 {
+			if !json.Valid(decision.Body) {
+				if errMsg := requestScriptFailed(script, fmt.Errorf("policy script %s: on_request returned a body that is not valid JSON", script.Name)); errMsg != nil {
+					return modelName, rawJSON, errMsg
+				}
+				continue
+			}
+			rawJSON = decision.Body
+		}
+		if decision.Model != "" && decision.Model != modelName {
+			if !h.catalogPermits(ctx, decision.Model) {
+				return modelName, rawJSON, &interfaces.ErrorMessage{StatusCode: http.StatusNotFound, Error: fmt.Errorf("model %s is not available for this API key", decision.Model)}
+			}
+			log.Debugf("request script %s routed %s to %s", script.Name, modelName, decision.Model)
+			modelName = decision.Model
+		}
+	}
+	return modelName, rawJSON, nil
+}
+
+// applyResponseScripts runs the on_response function of the matching request scripts on a
+// complete JSON response.
+func (h *BaseAPIHandler) applyResponseScripts(ctx context.Context, handlerType, modelName string, payload []byte) ([]byte, *interfaces.ErrorMessage) {
+	scripts, clientKey, _ := h.matchingRequestScripts(ctx, modelName)
+	if len(scripts) == 0 || !gjson.ValidBytes(payload) {
+		return payload, nil
+	}
+	for _, script := range scripts {
+		program, err := loadRequestScript(script)
+		var body []byte
+		if err == nil && program.HasResponseHook() {
+			body, err = program.OnResponse(ctx, requestScriptLimits(script), policyscript.Response{
+				Model:         modelName,
+				Format:        handlerType,
+				ClientKeyHash: requestScriptClientKeyHash(clientKey),
+				Body:          payload,
+			})
+		}
+		if err == nil && len(body) > 0 && !json.Valid(body) {
+			err = fmt.Errorf("policy script %s: on_response returned a body that is not valid JSON", script.Name)
+		}
+		if err != nil {
+			if errMsg := requestScriptFailed(script, err); errMsg != nil {
+				return nil, errMsg
+			}
+			continue
+		}
+		if len(body) > 0 {
+			payload = body
+		}
+	}
+	return payload, nil
+}
+
+// requestScriptFailed logs a script failure and returns the error ending the request when the
+// script fails closed.
+func requestScriptFailed(script config.RequestScript, err error) *interfaces.ErrorMessage {
+	if !script.FailClosed {
+		log.Warnf("request script skipped: %v", err)
+		return nil
+	}
+	log.Errorf("request rejected, request script failed: %v", err)
+	return &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: fmt.Errorf("request policy %s is unavailable", script.Name)}
+}
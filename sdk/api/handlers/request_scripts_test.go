@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const testRequestScript = `
+def on_request(req):
+    if req["headers"].get("authorization"):
+        return {"reject": "credentials leaked to the script"}
+    if req["client_key_hash"] == "79891e980747ffbd": # the "trial" key
+        return {"reject": "trial keys are limited to gpt-5-mini", "status": 429}
+    body = req["body"]
+    body["user"] = req["client_key_hash"]
+    return {"body": body}
+
+def on_response(resp):
+    body = resp["body"]
+    body["policy"] = "checked"
+    return {"body": body}
+`
+
+func newRequestScriptsTestHandler(t *testing.T, scripts []sdkconfig.RequestScript) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&staticResponseExecutor{payload: `{"id":"r1","model":"gpt-5"}`})
+	auth := &coreauth.Auth{ID: "scripts-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gpt-5"}, {ID: "gpt-5-mini"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{RequestScripts: scripts}, manager)
+}
+
+func requestScriptFromSource(name, source string) sdkconfig.RequestScript {
+	return sdkconfig.RequestScript{Name: name, Source: source, TimeoutMs: 1000, MaxSteps: 100000, MaxInputKB: 1024}
+}
+
+func TestApplyRequestScripts_ChangesPayloadAndRejects(t *testing.T) {
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{requestScriptFromSource("policy", testRequestScript)})
+
+	ctx, _ := responseRulesTestContext("key-a")
+	model, payload, errMsg := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`))
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if model != "gpt-5" || gjson.GetBytes(payload, "user").String() != util.ClientKeyHash("key-a") {
+		t.Fatalf("model = %q, payload = %s", model, payload)
+	}
+
+	ctx, _ = responseRulesTestContext("trial")
+	_, errMsg = h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("errMsg = %+v, want 429 rejection", errMsg)
+	}
+	var rejected *requestScriptRejectedError
+	if !errors.As(errMsg.Error, &rejected) || gjson.Get(rejected.Error(), "error.code").String() != "request_policy_violation" {
+		t.Fatalf("error = %v", errMsg.Error)
+	}
+}
+
+func TestApplyRequestScripts_HidesCredentialHeaders(t *testing.T) {
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{requestScriptFromSource("policy", testRequestScript)})
+
+	ctx, _ := responseRulesTestContext("key-a")
+	ctx.Value("gin").(*gin.Context).Request.Header.Set("Authorization", "Bearer secret")
+	if _, _, errMsg := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{}`)); errMsg != nil {
+		t.Fatalf("script saw the Authorization header: %+v", errMsg)
+	}
+}
+
+func TestExecuteWithAuthManager_AppliesResponseScripts(t *testing.T) {
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{requestScriptFromSource("policy", testRequestScript)})
+
+	ctx, _ := responseRulesTestContext("key-a")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if gjson.GetBytes(out, "policy").String() != "checked" || gjson.GetBytes(out, "id").String() != "r1" {
+		t.Fatalf("response = %s", out)
+	}
+}
+
+func TestApplyRequestScripts_RoutesToAnotherModel(t *testing.T) {
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{
+		{Name: "router", Models: []string{"gpt-5"}, Source: `
+def on_request(req):
+    return {"model": "gpt-5-mini"}
+`, TimeoutMs: 1000, MaxSteps: 1000},
+	})
+
+	ctx, _ := responseRulesTestContext("key-a")
+	model, _, errMsg := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{}`))
+	if errMsg != nil || model != "gpt-5-mini" {
+		t.Fatalf("model = %q, errMsg = %+v", model, errMsg)
+	}
+	model, _, _ = h.applyRequestScripts(ctx, "openai", "claude-sonnet", []byte(`{}`))
+	if model != "claude-sonnet" {
+		t.Fatalf("script applied to a model it does not match: %q", model)
+	}
+}
+
+func TestApplyRequestScripts_FailurePolicy(t *testing.T) {
+	loop := `
+def on_request(req):
+    for i in range(100000000):
+        pass
+`
+	openScript := requestScriptFromSource("open", loop)
+	openScript.MaxSteps = 1000
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{openScript})
+	ctx, _ := responseRulesTestContext("key-a")
+	if _, payload, errMsg := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{"a":1}`)); errMsg != nil || string(payload) != `{"a":1}` {
+		t.Fatalf("fail-open script: payload = %s, errMsg = %+v", payload, errMsg)
+	}
+
+	closedScript := openScript
+	closedScript.Name = "closed"
+	closedScript.FailClosed = true
+	h = newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{closedScript})
+	if _, _, errMsg := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{}`)); errMsg == nil || errMsg.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("fail-closed script: errMsg = %+v, want 503", errMsg)
+	}
+}
+
+func TestLoadRequestScript_ReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.star")
+	write := func(model string) {
+		if err := os.WriteFile(path, []byte("def on_request(req):\n    return {\"model\": \""+model+"\"}\n"), 0o600); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+	write("first")
+	script := sdkconfig.RequestScript{Name: path, Path: path, TimeoutMs: 1000, MaxSteps: 1000}
+	h := newRequestScriptsTestHandler(t, []sdkconfig.RequestScript{script})
+	ctx, _ := responseRulesTestContext("key-a")
+	if model, _, _ := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{}`)); model != "first" {
+		t.Fatalf("model = %q, want first", model)
+	}
+	write("second-model")
+	if model, _, _ := h.applyRequestScripts(ctx, "openai", "gpt-5", []byte(`{}`)); model != "second-model" {
+		t.Fatalf("model = %q after the script changed, want second-model", model)
+	}
+}
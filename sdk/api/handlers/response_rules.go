@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// responseModelPaths are the fields the supported response formats report the model in.
+var responseModelPaths = []string{"model", "message.model", "response.model", "modelVersion"}
+
+// responseRewriter applies the response rules matching one request to its response.
+// A nil rewriter returns responses unchanged.
+type responseRewriter struct {
+	ctx            context.Context
+	handler        *BaseAPIHandler
+	clientKey      string
+	rules          []config.ResponseRule
+	requestedModel string
+	// watermark identifies the client key and request for watermark rules.
+	watermark   string
+	headersOnce sync.Once
+	// notice is the relogin notice for this response, decided with the headers.
+	notice     string
+	noticeSent bool
+}
+
+// newResponseRewriter selects the response rules for the requested model and client key, plus
+// a model rewrite when echo-requested-model is on. When any apply, or the relogin notice is on,
+// the returned context records the auth that serves the request.
+func (h *BaseAPIHandler) newResponseRewriter(ctx context.Context, requestedModel string) (*responseRewriter, context.Context) {
+	if h == nil || h.Cfg == nil || ctx == nil || (len(h.Cfg.ResponseRules) == 0 && !h.Cfg.EchoRequestedModel && !h.Cfg.ReloginNotice.Enable) {
+		return nil, ctx
+	}
+	clientKey := ""
+	if ginCtx, _ := ctx.Value("gin").(*gin.Context); ginCtx != nil {
+		clientKey = clientAPIKeyFromGin(ginCtx)
+	}
+	var rules []config.ResponseRule
+	if h.Cfg.EchoRequestedModel {
+		rules = append(rules, config.ResponseRule{RewriteModel: true})
+	}
+	for _, rule := range h.Cfg.ResponseRules {
+		if responseRuleMatches(rule, requestedModel, clientKey) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 && !h.Cfg.ReloginNotice.Enable {
+		return nil, ctx
+	}
+	ctx = coreauth.WithServedAuthRecorder(ctx)
+	watermark := util.Watermark(clientKey, logging.GetRequestID(ctx))
+	return &responseRewriter{ctx: ctx, handler: h, clientKey: clientKey, rules: rules, requestedModel: requestedModel, watermark: watermark}, ctx
+}
+
+func responseRuleMatches(rule config.ResponseRule, model, clientKey string) bool {
+	if len(rule.ClientKeys) > 0 {
+		found := false
+		for _, key := range rule.ClientKeys {
+			if key == clientKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(rule.Models) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Models {
+		if wildcard.Match(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyHeaders sets the served-by headers once the serving auth is known. Streaming callers
+// invoke it before handing chunks to another goroutine; later calls do nothing.
+func (r *responseRewriter) applyHeaders() {
+	if r == nil {
+		return
+	}
+	r.headersOnce.Do(func() {
+		served := coreauth.ServedAuthFromContext(r.ctx)
+		r.notice = r.handler.reloginNotice(r.clientKey, served)
+		r.setHeaders(servedByLabel(served))
+	})
+}
+
+// rewrite applies the rules to a complete JSON response or to a chunk of server-sent events.
+func (r *responseRewriter) rewrite(payload []byte) []byte {
+	if r == nil || len(payload) == 0 {
+		return payload
+	}
+	r.applyHeaders()
+	servedBy := servedByLabel(coreauth.ServedAuthFromContext(r.ctx))
+	return mapResponseJSON(payload, func(body []byte) []byte { return r.rewriteJSON(body, servedBy) })
+}
+
+// mapResponseJSON applies fn to a complete JSON response, or to the JSON data of every event
+// in a chunk of server-sent events. Anything else is returned unchanged.
+func mapResponseJSON(payload []byte, fn func(body []byte) []byte) []byte {
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && gjson.ValidBytes(trimmed) {
+		return fn(payload)
+	}
+	if !bytes.Contains(payload, []byte("data:")) {
+		return payload
+	}
+	lines := bytes.Split(payload, []byte("\n"))
+	for i, line := range lines {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 || data[0] != '{' || !gjson.ValidBytes(data) {
+			continue
+		}
+		lines[i] = append([]byte("data: "), fn(data)...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+func (r *responseRewriter) rewriteJSON(body []byte, servedBy string) []byte {
+	out := body
+	for _, rule := range r.rules {
+		for _, path := range rule.Strip {
+			if updated, errDelete := sjson.DeleteBytes(out, path); errDelete == nil {
+				out = updated
+			}
+		}
+		if rule.RewriteModel && r.requestedModel != "" {
+			for _, path := range responseModelPaths {
+				if !gjson.GetBytes(out, path).Exists() {
+					continue
+				}
+				if updated, errSet := sjson.SetBytes(out, path, r.requestedModel); errSet == nil {
+					out = updated
+				}
+			}
+		}
+		if rule.ServedByField != "" && servedBy != "" {
+			if updated, errSet := sjson.SetBytes(out, rule.ServedByField, servedBy); errSet == nil {
+				out = updated
+			}
+		}
+		if rule.WatermarkField != "" {
+			if updated, errSet := sjson.SetBytes(out, rule.WatermarkField, r.watermark); errSet == nil {
+				out = updated
+			}
+		}
+	}
+	if field := r.handler.Cfg.ReloginNotice.Field; r.notice != "" && field != "" && !r.noticeSent {
+		if updated, errSet := sjson.SetBytes(out, field, r.notice); errSet == nil {
+			out = updated
+			r.noticeSent = true
+		}
+	}
+	return out
+}
+
+func (r *responseRewriter) setHeaders(servedBy string) {
+	ginCtx, _ := r.ctx.Value("gin").(*gin.Context)
+	if ginCtx == nil || ginCtx.Writer == nil || ginCtx.Writer.Written() {
+		return
+	}
+	if r.notice != "" {
+		ginCtx.Header(reloginNoticeHeader(r.handler.Cfg.ReloginNotice), r.notice)
+	}
+	for _, rule := range r.rules {
+		if rule.WatermarkHeader != "" {
+			ginCtx.Header(rule.WatermarkHeader, r.watermark)
+		}
+		if rule.ServedByHeader != "" && servedBy != "" {
+			ginCtx.Header(rule.ServedByHeader, servedBy)
+		}
+	}
+}
+
+// servedByLabel identifies the serving auth by its label, or by its opaque index when it has none.
+func servedByLabel(auth *coreauth.Auth) string {
+	if auth == nil {
+		return ""
+	}
+	if label := strings.TrimSpace(auth.Label); label != "" {
+		return label
+	}
+	return auth.EnsureIndex()
+}
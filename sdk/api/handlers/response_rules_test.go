@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type staticResponseExecutor struct {
+	payload string
+}
+
+func (e *staticResponseExecutor) Identifier() string { return "codex" }
+
+func (e *staticResponseExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(e.payload)}, nil
+}
+
+func (e *staticResponseExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	ch <- coreexecutor.StreamChunk{Payload: []byte("event: message\ndata: " + e.payload + "\n\n")}
+	close(ch)
+	return ch, nil
+}
+
+func (e *staticResponseExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *staticResponseExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *staticResponseExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func newResponseRulesTestHandler(t *testing.T) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&staticResponseExecutor{payload: `{"id":"r1","model":"gpt-5-2025-08-07","system_fingerprint":"fp_1","usage":{"total_tokens":3}}`})
+	auth := &coreauth.Auth{ID: "rules-auth", Provider: "codex", Label: "team-a", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gpt-5"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{ResponseRules: []sdkconfig.ResponseRule{
+		{Models: []string{"gpt-5*"}, Strip: []string{"system_fingerprint"}, RewriteModel: true},
+		{ClientKeys: []string{"key-a"}, ServedByHeader: "X-Served-By", ServedByField: "served_by"},
+	}}, manager)
+}
+
+func responseRulesTestContext(clientKey string) (context.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set("apiKey", clientKey)
+	return context.WithValue(context.Background(), "gin", c), rec
+}
+
+func TestExecuteWithAuthManager_AppliesResponseRules(t *testing.T) {
+	h := newResponseRulesTestHandler(t)
+
+	ctx, rec := responseRulesTestContext("key-a")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if gjson.GetBytes(out, "model").String() != "gpt-5" || gjson.GetBytes(out, "system_fingerprint").Exists() {
+		t.Fatalf("expected model rewrite and strip, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "served_by").String(); got != "team-a" {
+		t.Fatalf("served_by = %q", got)
+	}
+	if got := rec.Header().Get("X-Served-By"); got != "team-a" {
+		t.Fatalf("X-Served-By = %q", got)
+	}
+
+	ctx, rec = responseRulesTestContext("key-b")
+	out, errMsg = h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if gjson.GetBytes(out, "served_by").Exists() || rec.Header().Get("X-Served-By") != "" {
+		t.Fatalf("expected key-scoped rule to be skipped, got %s", out)
+	}
+}
+
+func TestExecuteStreamWithAuthManager_AppliesResponseRulesToEvents(t *testing.T) {
+	h := newResponseRulesTestHandler(t)
+
+	ctx, rec := responseRulesTestContext("key-a")
+	dataChan, errChan := h.ExecuteStreamWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	var got []byte
+	for chunk := range dataChan {
+		got = append(got, chunk...)
+	}
+	for msg := range errChan {
+		if msg != nil {
+			t.Fatalf("unexpected error: %+v", msg)
+		}
+	}
+	want := "event: message\ndata: {\"id\":\"r1\",\"model\":\"gpt-5\",\"usage\":{\"total_tokens\":3},\"served_by\":\"team-a\"}\n\n"
+	if string(got) != want {
+		t.Fatalf("stream = %q, want %q", got, want)
+	}
+	if rec.Header().Get("X-Served-By") != "team-a" {
+		t.Fatalf("X-Served-By = %q", rec.Header().Get("X-Served-By"))
+	}
+}
+
+func TestExecuteWithAuthManager_EchoesRequestedModel(t *testing.T) {
+	h := newResponseRulesTestHandler(t)
+	h.Cfg.ResponseRules = nil
+	h.Cfg.EchoRequestedModel = true
+
+	ctx, _ := responseRulesTestContext("key-b")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if got := gjson.GetBytes(out, "model").String(); got != "gpt-5" {
+		t.Fatalf("model = %q", got)
+	}
+	if !gjson.GetBytes(out, "system_fingerprint").Exists() {
+		t.Fatalf("expected other fields untouched, got %s", out)
+	}
+}
+
+func TestExecuteWithAuthManager_WatermarksResponses(t *testing.T) {
+	h := newResponseRulesTestHandler(t)
+	h.Cfg.ResponseRules = []sdkconfig.ResponseRule{{ClientKeys: []string{"key-a"}, WatermarkField: "metadata.wm", WatermarkHeader: "X-Watermark"}}
+
+	ctx, rec := responseRulesTestContext("key-a")
+	ctx = logging.WithRequestID(ctx, "req-123")
+	out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	want := util.Watermark("key-a", "req-123")
+	if got := gjson.GetBytes(out, "metadata.wm").String(); got != want {
+		t.Fatalf("metadata.wm = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("X-Watermark"); got != want {
+		t.Fatalf("X-Watermark = %q, want %q", got, want)
+	}
+	if keyHash, requestID, ok := util.ParseWatermark(want); !ok || keyHash != util.ClientKeyHash("key-a") || requestID != "req-123" {
+		t.Fatalf("ParseWatermark(%q) = %q, %q, %v", want, keyHash, requestID, ok)
+	}
+}
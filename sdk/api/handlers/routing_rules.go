@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/routingrules"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// applyRoutingRules evaluates the routing rules file against the request and narrows its
+// providers to the matching rule's provider. The auth tag and proxy of the rule are returned
+// in the context for the auth manager. A load failure leaves routing unchanged.
+func (h *BaseAPIHandler) applyRoutingRules(ctx context.Context, providers []string, modelName string, rawJSON []byte) ([]string, context.Context, *interfaces.ErrorMessage) {
+	if h == nil || h.Cfg == nil || h.Cfg.RoutingRulesFile == "" {
+		return providers, ctx, nil
+	}
+	rules, err := routingrules.Load(h.Cfg.RoutingRulesFile)
+	if err != nil || rules.Len() == 0 {
+		return providers, ctx, nil
+	}
+	in := routingrules.Input{Model: modelName}
+	if ginCtx, _ := ctx.Value("gin").(*gin.Context); ginCtx != nil {
+		in.ClientKey = clientAPIKeyFromGin(ginCtx)
+	}
+	if rules.UsesTokens() {
+		in.Tokens = requestInputTokens(ctx, rawJSON)
+	}
+	result := rules.Evaluate(in)
+	if !result.Matched {
+		return providers, ctx, nil
+	}
+	log.Debugf("routing rule %s matched model %s", result.Rule, modelName)
+	if provider := result.Route.Provider; provider != "" {
+		found := false
+		for _, candidate := range providers {
+			if strings.EqualFold(candidate, provider) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ctx, &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: fmt.Errorf("routing rule %s routes model %s to provider %s, which does not serve it", result.Rule, modelName, provider)}
+		}
+		providers = []string{provider}
+	}
+	ctx = coreauth.WithRouteConstraints(ctx, coreauth.RouteConstraints{AuthTag: result.Route.AuthTag, ProxyURL: result.Route.Proxy})
+	return providers, ctx, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// servedAuthExecutor answers with the ID and proxy of the auth that served the request.
+type servedAuthExecutor struct {
+	staticResponseExecutor
+}
+
+func (e *servedAuthExecutor) Execute(_ context.Context, auth *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(`{"auth":"` + auth.ID + `","proxy":"` + auth.ProxyURL + `"}`)}, nil
+}
+
+func newRoutingRulesTestHandler(t *testing.T, rules string) *BaseAPIHandler {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routing.yaml")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&servedAuthExecutor{})
+	for _, auth := range []*coreauth.Auth{
+		{ID: "routing-plain", Provider: "codex", Status: coreauth.StatusActive},
+		{ID: "routing-tagged", Provider: "codex", Status: coreauth.StatusActive, Metadata: map[string]any{"tags": []any{"batch"}}},
+	} {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("manager.Register: %v", err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gpt-5"}})
+		id := auth.ID
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	}
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{RoutingRulesFile: path}, manager)
+}
+
+func TestExecuteWithAuthManager_AppliesRoutingRules(t *testing.T) {
+	h := newRoutingRulesTestHandler(t, `
+rules:
+  - when: {client-keys: ["batch-key"]}
+    route: {auth-tag: batch, proxy: "http://127.0.0.1:3128"}
+  - when: {client-keys: ["wrong-provider"]}
+    route: {provider: claude}
+`)
+
+	for i := 0; i < 3; i++ {
+		ctx, _ := responseRulesTestContext("batch-key")
+		out, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), "")
+		if errMsg != nil {
+			t.Fatalf("unexpected error: %+v", errMsg)
+		}
+		if string(out) != `{"auth":"routing-tagged","proxy":"http://127.0.0.1:3128"}` {
+			t.Fatalf("response = %s", out)
+		}
+	}
+
+	ctx, _ := responseRulesTestContext("wrong-provider")
+	if _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "gpt-5", []byte(`{"model":"gpt-5"}`), ""); errMsg == nil || errMsg.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("errMsg = %+v, want 503 for a provider not serving the model", errMsg)
+	}
+}
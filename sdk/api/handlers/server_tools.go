@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// serverTool is a tool the proxy offers to the model and executes itself.
+type serverTool struct {
+	name        string
+	description string
+	schema      json.RawMessage
+	run         func(ctx context.Context, arguments string) (string, error)
+}
+
+// Types of the ServerToolEvent steps reported while the server-side tool loop runs.
+const (
+	ServerToolCallEvent   = "server_tool.call"
+	ServerToolResultEvent = "server_tool.result"
+)
+
+// ServerToolEvent is one step of the server-side tool loop: a call the model made to a
+// server-side tool, or the result the proxy sent back for it.
+type ServerToolEvent struct {
+	Type string `json:"type"`
+	// Iteration is the model call, counted from 1, that made the tool call.
+	Iteration int    `json:"iteration"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+	// IsError reports a result holding the error the tool failed with.
+	IsError bool `json:"is_error,omitempty"`
+}
+
+// ServerToolsApply reports whether server-side tools (MCP tool servers, web search or code
+// execution) are attached to the chat completions request rawJSON of the client in c.
+func (h *BaseAPIHandler) ServerToolsApply(c *gin.Context, rawJSON []byte) bool {
+	key := clientAPIKeyFromGin(c)
+	return h.mcpToolsApply(key) || h.webSearchApply(key) || h.codeExecutionApply(key, rawJSON)
+}
+
+func (h *BaseAPIHandler) mcpToolsApply(clientKey string) bool {
+	if h == nil || h.mcpTools == nil || h.Cfg == nil || len(h.Cfg.MCPTools.Servers) == 0 {
+		return false
+	}
+	keys := h.Cfg.MCPTools.ClientKeys
+	return len(keys) == 0 || slices.Contains(keys, clientKey)
+}
+
+// serverTools returns the tools offered to the client of ctx.
+func (h *BaseAPIHandler) serverTools(ctx context.Context, rawJSON []byte) []serverTool {
+	clientKey := ""
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+		clientKey = clientAPIKeyFromGin(ginCtx)
+	}
+	var tools []serverTool
+	if h.webSearchApply(clientKey) && !gjson.GetBytes(rawJSON, "web_search_options").Exists() {
+		tools = append(tools, h.webSearchTool())
+	}
+	if h.codeExecutionApply(clientKey, rawJSON) {
+		tools = append(tools, h.codeExecutionTool())
+	}
+	if h.mcpToolsApply(clientKey) {
+		for _, tool := range h.mcpTools.Tools(ctx) {
+			tools = append(tools, serverTool{
+				name:        tool.Name,
+				description: tool.Description,
+				schema:      tool.InputSchema,
+				run: func(ctx context.Context, arguments string) (string, error) {
+					return h.mcpTools.Call(ctx, tool, arguments)
+				},
+			})
+		}
+	}
+	return tools
+}
+
+// ExecuteChatWithServerTools executes an OpenAI chat completions request with the server-side
+// tools appended. While the model only calls those tools, the proxy runs the calls, appends
+// the results to the conversation and asks again; the first response without such calls is
+// returned with the token usage of every round. A response that also calls client-defined
+// tools is returned as is. The request is always executed without streaming.
+func (h *BaseAPIHandler) ExecuteChatWithServerTools(ctx context.Context, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	return h.ExecuteChatWithServerToolEvents(ctx, modelName, rawJSON, alt, nil)
+}
+
+// ExecuteChatWithServerToolEvents is ExecuteChatWithServerTools reporting every server-side
+// tool call and its result to onEvent, when set, as the loop runs.
+func (h *BaseAPIHandler) ExecuteChatWithServerToolEvents(ctx context.Context, modelName string, rawJSON []byte, alt string, onEvent func(ServerToolEvent)) ([]byte, *interfaces.ErrorMessage) {
+	if onEvent == nil {
+		onEvent = func(ServerToolEvent) {}
+	}
+	tools := h.serverTools(ctx, rawJSON)
+	payload, _ := sjson.SetBytes(rawJSON, "stream", false)
+	payload, _ = sjson.DeleteBytes(payload, "stream_options")
+	if slices.ContainsFunc(tools, func(tool serverTool) bool { return tool.name == codeExecutionToolName }) {
+		// The proxy's function tool replaces the built-in tool the client opted in with.
+		payload = withoutToolType(payload, codeInterpreterToolType)
+	}
+	if len(tools) == 0 {
+		return h.ExecuteWithAuthManager(ctx, constant.OpenAI, modelName, payload, alt)
+	}
+
+	byName := make(map[string]serverTool, len(tools))
+	for _, tool := range tools {
+		if gjson.GetBytes(payload, `tools.#(function.name=="`+tool.name+`")`).Exists() {
+			continue
+		}
+		fn := []byte(`{"type":"function","function":{"name":"","description":"","parameters":{}}}`)
+		fn, _ = sjson.SetBytes(fn, "function.name", tool.name)
+		fn, _ = sjson.SetBytes(fn, "function.description", tool.description)
+		fn, _ = sjson.SetRawBytes(fn, "function.parameters", tool.schema)
+		payload, _ = sjson.SetRawBytes(payload, "tools.-1", fn)
+		byName[tool.name] = tool
+	}
+
+	maxIterations := h.Cfg.MCPTools.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = 8
+	}
+	var promptTokens, completionTokens int64
+	for iteration := 1; ; iteration++ {
+		if iteration == maxIterations {
+			// Make the last round answer instead of calling tools again.
+			payload, _ = sjson.SetBytes(payload, "tool_choice", "none")
+		}
+		resp, errMsg := h.ExecuteWithAuthManager(ctx, constant.OpenAI, modelName, payload, alt)
+		if errMsg != nil {
+			return nil, errMsg
+		}
+		promptTokens += gjson.GetBytes(resp, "usage.prompt_tokens").Int()
+		completionTokens += gjson.GetBytes(resp, "usage.completion_tokens").Int()
+
+		message := gjson.GetBytes(resp, "choices.0.message")
+		calls := message.Get("tool_calls").Array()
+		ownCalls := len(calls) > 0 && iteration < maxIterations
+		for _, call := range calls {
+			if _, ok := byName[call.Get("function.name").String()]; !ok {
+				ownCalls = false
+			}
+		}
+		if !ownCalls {
+			if iteration > 1 && gjson.GetBytes(resp, "usage").Exists() {
+				resp, _ = sjson.SetBytes(resp, "usage.prompt_tokens", promptTokens)
+				resp, _ = sjson.SetBytes(resp, "usage.completion_tokens", completionTokens)
+				resp, _ = sjson.SetBytes(resp, "usage.total_tokens", promptTokens+completionTokens)
+			}
+			return resp, nil
+		}
+
+		payload, _ = sjson.SetRawBytes(payload, "messages.-1", []byte(message.Raw))
+		for _, call := range calls {
+			name := call.Get("function.name").String()
+			callID := call.Get("id").String()
+			arguments := call.Get("function.arguments").String()
+			onEvent(ServerToolEvent{Type: ServerToolCallEvent, Iteration: iteration, CallID: callID, Name: name, Arguments: arguments})
+			output, err := byName[name].run(ctx, arguments)
+			if err != nil {
+				log.Debugf("server tools: %s failed: %v", name, err)
+				output = "Error: " + err.Error()
+			}
+			onEvent(ServerToolEvent{Type: ServerToolResultEvent, Iteration: iteration, CallID: callID, Name: name, Output: output, IsError: err != nil})
+			debugtrace.Record(ctx, "server_tool", name, map[string]any{
+				"iteration": iteration,
+				"arguments": arguments,
+				"output":    output,
+			})
+			result := []byte(`{"role":"tool","tool_call_id":"","content":""}`)
+			result, _ = sjson.SetBytes(result, "tool_call_id", callID)
+			result, _ = sjson.SetBytes(result, "content", output)
+			payload, _ = sjson.SetRawBytes(payload, "messages.-1", result)
+		}
+	}
+}
+
+// withoutToolType removes the tools of type typ from a chat completions request.
+func withoutToolType(rawJSON []byte, typ string) []byte {
+	kept := []byte(`[]`)
+	for _, tool := range gjson.GetBytes(rawJSON, "tools").Array() {
+		if tool.Get("type").String() != typ {
+			kept, _ = sjson.SetRawBytes(kept, "-1", []byte(tool.Raw))
+		}
+	}
+	if string(kept) == "[]" {
+		out, _ := sjson.DeleteBytes(rawJSON, "tools")
+		return out
+	}
+	out, _ := sjson.SetRawBytes(rawJSON, "tools", kept)
+	return out
+}
@@ -28,6 +28,11 @@ type StreamForwardOptions struct {
 	// WriteKeepAlive optionally writes a keep-alive heartbeat. It should not flush.
 	// When nil, a standard SSE comment heartbeat is used.
 	WriteKeepAlive func()
+
+	// SalvageFormat is the client's API format (a handler type). When set and
+	// streaming.salvage-min-bytes is reached before the stream fails, the error is replaced by
+	// a final chunk in this format marking the response as truncated, followed by WriteDone.
+	SalvageFormat string
 }
 
 func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage, opts StreamForwardOptions) {
@@ -62,6 +67,30 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 		keepAliveC = keepAlive.C
 	}
 
+	var salvage *streamSalvage
+	salvageMinBytes := 0
+	if h.Cfg != nil && h.Cfg.Streaming.SalvageMinBytes > 0 {
+		salvage = newStreamSalvage(opts.SalvageFormat)
+		salvageMinBytes = h.Cfg.Streaming.SalvageMinBytes
+	}
+	written := 0
+	// salvaged ends a failed stream with a truncation chunk when enough output was sent,
+	// reporting whether it did.
+	salvaged := func() bool {
+		if salvage == nil || written < salvageMinBytes {
+			return false
+		}
+		final := salvage.final()
+		if final == nil {
+			return false
+		}
+		_, _ = c.Writer.Write(final)
+		if opts.WriteDone != nil {
+			opts.WriteDone()
+		}
+		return true
+	}
+
 	var terminalErr *interfaces.ErrorMessage
 	for {
 		select {
@@ -82,7 +111,7 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 				}
 				if terminalErr != nil {
 					h.LoggingAPIResponseError(context.WithValue(c.Request.Context(), "gin", c), terminalErr)
-					if opts.WriteTerminalError != nil {
+					if !salvaged() && opts.WriteTerminalError != nil {
 						opts.WriteTerminalError(terminalErr)
 					}
 					flusher.Flush()
@@ -98,6 +127,10 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 			}
 			writeChunk(chunk)
 			flusher.Flush()
+			if salvage != nil {
+				salvage.observe(chunk)
+				written += len(chunk)
+			}
 		case errMsg, ok := <-errs:
 			if !ok {
 				continue
@@ -105,7 +138,9 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 			if errMsg != nil {
 				terminalErr = errMsg
 				h.LoggingAPIResponseError(context.WithValue(c.Request.Context(), "gin", c), errMsg)
-				if opts.WriteTerminalError != nil {
+				if salvaged() {
+					flusher.Flush()
+				} else if opts.WriteTerminalError != nil {
 					opts.WriteTerminalError(errMsg)
 					flusher.Flush()
 				}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+)
+
+// streamSlots counts the streams open at once, in total and per client API key.
+type streamSlots struct {
+	mu     sync.Mutex
+	total  int
+	perKey map[string]int
+}
+
+var openStreams = &streamSlots{perKey: make(map[string]int)}
+
+// tooManyStreamsError reports a stream rejected because too many are already open.
+type tooManyStreamsError struct {
+	limit  int
+	perKey bool
+}
+
+func (e *tooManyStreamsError) Error() string {
+	message := fmt.Sprintf("Too many concurrent streams: the server allows %d open streams at once. Wait for a stream to finish before opening another.", e.limit)
+	if e.perKey {
+		message = fmt.Sprintf("Too many concurrent streams: this API key may have %d streams open at once. Wait for a stream to finish before opening another.", e.limit)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    "rate_limit_error",
+			"code":    "too_many_streams",
+		},
+	})
+	return string(body)
+}
+
+func (e *tooManyStreamsError) StatusCode() int { return http.StatusTooManyRequests }
+
+func (e *tooManyStreamsError) ProxyGenerated() bool { return true }
+
+// acquire takes a stream slot for key unless the global limit or the key's limit is reached.
+// A limit <= 0 is unlimited. The returned release function frees the slot; calling it more
+// than once is safe.
+func (s *streamSlots) acquire(key string, maxTotal, maxPerKey int) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if maxTotal > 0 && s.total >= maxTotal {
+		return nil, &tooManyStreamsError{limit: maxTotal}
+	}
+	if key != "" && maxPerKey > 0 && s.perKey[key] >= maxPerKey {
+		return nil, &tooManyStreamsError{limit: maxPerKey, perKey: true}
+	}
+	s.total++
+	if key != "" {
+		s.perKey[key]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.total--
+			if key != "" {
+				if s.perKey[key]--; s.perKey[key] <= 0 {
+					delete(s.perKey, key)
+				}
+			}
+		})
+	}, nil
+}
+
+// open returns the number of streams open for key.
+func (s *streamSlots) open(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.perKey[key]
+}
+
+// acquireStreamSlot takes a slot for a new stream of the request's client key, enforcing the
+// global and per-key concurrent stream limits. The returned release function must be called
+// once the stream has finished.
+func (h *BaseAPIHandler) acquireStreamSlot(ctx context.Context) (func(), *interfaces.ErrorMessage) {
+	noop := func() {}
+	if h == nil || h.Cfg == nil {
+		return noop, nil
+	}
+	limits := h.Cfg.Streaming
+	if limits.MaxStreams <= 0 && limits.MaxStreamsPerKey <= 0 && len(limits.KeyMaxStreams) == 0 {
+		return noop, nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	key := clientAPIKeyFromGin(ginCtx)
+	perKey := limits.MaxStreamsPerKey
+	if override, ok := config.LookupClientKey(h.Cfg, limits.KeyMaxStreams, key); ok && key != "" {
+		perKey = override
+	}
+	release, err := openStreams.acquire(key, limits.MaxStreams, perKey)
+	if err != nil {
+		return noop, errorMessageFromError(err)
+	}
+	return release, nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestAcquireStreamSlotLimits(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{Streaming: sdkconfig.StreamingConfig{
+		MaxStreams:       3,
+		MaxStreamsPerKey: 1,
+		KeyMaxStreams:    map[string]int{"stream-fleet-key": 2},
+	}}, coreauth.NewManager(nil, nil, nil))
+
+	releaseA, errMsg := h.acquireStreamSlot(catalogTestContext("stream-key-a"))
+	if errMsg != nil {
+		t.Fatalf("first stream rejected: %v", errMsg.Error)
+	}
+	_, errMsg = h.acquireStreamSlot(catalogTestContext("stream-key-a"))
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the per-key limit, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "too_many_streams" {
+		t.Fatalf("code = %q", code)
+	}
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, errFleet := h.acquireStreamSlot(catalogTestContext("stream-fleet-key"))
+		if errFleet != nil {
+			t.Fatalf("fleet stream %d rejected: %v", i, errFleet.Error)
+		}
+		releases = append(releases, release)
+	}
+	if _, errMsg = h.acquireStreamSlot(catalogTestContext("stream-key-b")); errMsg == nil {
+		t.Fatal("expected the global limit to reject a fourth stream")
+	}
+
+	releaseA()
+	releaseA()
+	releaseB, errMsg := h.acquireStreamSlot(catalogTestContext("stream-key-b"))
+	if errMsg != nil {
+		t.Fatalf("stream after release rejected: %v", errMsg.Error)
+	}
+	releaseB()
+	for _, release := range releases {
+		release()
+	}
+}
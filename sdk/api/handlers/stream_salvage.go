@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamSalvage follows the chunks of a stream so that, when the upstream fails after output
+// was sent, it can end the stream with a final chunk in the client's format marking the
+// response as truncated, the same way a response cut off by the output token limit ends.
+type streamSalvage struct {
+	format string
+	// last is the most recent JSON payload carrying the response identity.
+	last []byte
+	// openBlock is the index of the Claude content block left open, or -1.
+	openBlock int64
+}
+
+func newStreamSalvage(format string) *streamSalvage {
+	switch format {
+	case constant.OpenAI, constant.OpenaiResponse, constant.Claude, constant.Gemini, constant.GeminiCLI:
+		return &streamSalvage{format: format, openBlock: -1}
+	}
+	return nil
+}
+
+// observe records the response identity and open blocks from a chunk sent to the client.
+func (s *streamSalvage) observe(chunk []byte) {
+	for _, line := range bytes.Split(chunk, []byte("\n")) {
+		payload := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+		if len(payload) == 0 || payload[0] != '{' || !gjson.ValidBytes(payload) {
+			continue
+		}
+		switch s.format {
+		case constant.Claude:
+			switch gjson.GetBytes(payload, "type").String() {
+			case "message_start":
+				s.last = bytes.Clone(payload)
+			case "content_block_start":
+				s.openBlock = gjson.GetBytes(payload, "index").Int()
+			case "content_block_stop":
+				s.openBlock = -1
+			}
+		case constant.OpenaiResponse:
+			if gjson.GetBytes(payload, "response.id").Exists() {
+				s.last = bytes.Clone(payload)
+			}
+		default:
+			s.last = bytes.Clone(payload)
+		}
+	}
+}
+
+// final returns the chunk ending the stream, already framed for the client, or nil when the
+// stream carried nothing to build it from.
+func (s *streamSalvage) final() []byte {
+	if len(s.last) == 0 {
+		return nil
+	}
+	switch s.format {
+	case constant.OpenAI:
+		return s.finalOpenAI()
+	case constant.OpenaiResponse:
+		return s.finalResponses()
+	case constant.Claude:
+		return s.finalClaude()
+	default:
+		return s.finalGemini()
+	}
+}
+
+// finalOpenAI ends a chat completion or completion stream with finish_reason "length".
+func (s *streamSalvage) finalOpenAI() []byte {
+	choice := []byte(`{"index":0,"finish_reason":"length"}`)
+	if gjson.GetBytes(s.last, "object").String() == "text_completion" {
+		choice, _ = sjson.SetBytes(choice, "text", "")
+	} else {
+		choice, _ = sjson.SetRawBytes(choice, "delta", []byte(`{}`))
+	}
+	out, _ := sjson.DeleteBytes(s.last, "usage")
+	out, _ = sjson.SetRawBytes(out, "choices", append(append([]byte("["), choice...), ']'))
+	return []byte(fmt.Sprintf("data: %s\n\n", out))
+}
+
+// finalResponses ends a Responses stream with response.incomplete for max_output_tokens.
+func (s *streamSalvage) finalResponses() []byte {
+	response := []byte(gjson.GetBytes(s.last, "response").Raw)
+	response, _ = sjson.SetBytes(response, "status", "incomplete")
+	response, _ = sjson.SetRawBytes(response, "incomplete_details", []byte(`{"reason":"max_output_tokens"}`))
+	event := []byte(`{"type":"response.incomplete"}`)
+	event, _ = sjson.SetRawBytes(event, "response", response)
+	return []byte(fmt.Sprintf("\nevent: response.incomplete\ndata: %s\n\n", event))
+}
+
+// finalClaude closes the open content block and ends the message with stop_reason max_tokens.
+func (s *streamSalvage) finalClaude() []byte {
+	var out bytes.Buffer
+	if s.openBlock >= 0 {
+		fmt.Fprintf(&out, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":%d}\n\n", s.openBlock)
+	}
+	out.WriteString("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":0}}\n\n")
+	out.WriteString("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	return out.Bytes()
+}
+
+// finalGemini ends a Gemini stream with a candidate finished for MAX_TOKENS. Gemini CLI
+// chunks wrap the response in a "response" field, which is kept.
+func (s *streamSalvage) finalGemini() []byte {
+	prefix := ""
+	if gjson.GetBytes(s.last, "response").IsObject() {
+		prefix = "response."
+	}
+	out := []byte(`{}`)
+	if prefix != "" {
+		out = []byte(`{"response":{}}`)
+	}
+	for _, field := range []string{"modelVersion", "responseId"} {
+		if value := gjson.GetBytes(s.last, prefix+field); value.Exists() {
+			out, _ = sjson.SetBytes(out, prefix+field, value.String())
+		}
+	}
+	out, _ = sjson.SetRawBytes(out, prefix+"candidates", []byte(`[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"MAX_TOKENS","index":0}]`))
+	return []byte(fmt.Sprintf("data: %s\n\n", out))
+}
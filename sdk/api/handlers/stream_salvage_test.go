@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// forwardFailingStream forwards chunks and then an upstream error, returning the response body.
+func forwardFailingStream(t *testing.T, salvageMinBytes int, format string, chunks ...string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{Streaming: sdkconfig.StreamingConfig{SalvageMinBytes: salvageMinBytes}}, coreauth.NewManager(nil, nil, nil))
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	// Unbuffered channels make the error arrive only after every chunk was forwarded.
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage)
+	go func() {
+		for _, chunk := range chunks {
+			data <- []byte(chunk)
+		}
+		errs <- &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: errors.New("upstream reset")}
+	}()
+	h.ForwardStream(c, recorder, func(error) {}, data, errs, StreamForwardOptions{
+		SalvageFormat: format,
+		WriteChunk: func(chunk []byte) {
+			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", chunk)
+		},
+		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
+			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errMsg.Error.Error())
+		},
+		WriteDone: func() {
+			_, _ = fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+		},
+	})
+	return recorder.Body.String()
+}
+
+func TestForwardStreamSalvagesTruncatedOpenAIStream(t *testing.T) {
+	chunk := `{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-x","choices":[{"index":0,"delta":{"content":"partial answer"}}]}`
+
+	body := forwardFailingStream(t, 10, "openai", chunk)
+	if strings.Contains(body, "event: error") {
+		t.Fatalf("salvaged stream still carries the error:\n%s", body)
+	}
+	want := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-x","choices":[{"index":0,"finish_reason":"length","delta":{}}]}`
+	if !strings.Contains(body, want) || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("body = \n%s\nwant truncation chunk and [DONE]", body)
+	}
+
+	body = forwardFailingStream(t, 1<<20, "openai", chunk)
+	if !strings.Contains(body, "event: error") || strings.Contains(body, "[DONE]") {
+		t.Fatalf("stream below the threshold should end with the error:\n%s", body)
+	}
+}
+
+func TestStreamSalvageClaudeClosesOpenBlock(t *testing.T) {
+	salvage := newStreamSalvage("claude")
+	salvage.observe([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\nevent: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"))
+
+	final := string(salvage.final())
+	for _, want := range []string{`"type":"content_block_stop","index":1`, `"stop_reason":"max_tokens"`, "event: message_stop"} {
+		if !strings.Contains(final, want) {
+			t.Fatalf("final = %s, want %s", final, want)
+		}
+	}
+}
+
+func TestStreamSalvageResponsesAndGemini(t *testing.T) {
+	responses := newStreamSalvage("openai-response")
+	responses.observe([]byte(`data: {"type":"response.created","response":{"id":"resp_1","model":"gpt-x","status":"in_progress"}}`))
+	responses.observe([]byte(`data: {"type":"response.output_text.delta","delta":"partial"}`))
+	final := string(responses.final())
+	if !strings.Contains(final, "event: response.incomplete") || !strings.Contains(final, `"id":"resp_1"`) || !strings.Contains(final, `"status":"incomplete"`) {
+		t.Fatalf("responses final = %s", final)
+	}
+
+	gemini := newStreamSalvage("gemini-cli")
+	gemini.observe([]byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"partial"}]}}],"modelVersion":"gemini-x"}}`))
+	final = string(gemini.final())
+	if !strings.Contains(final, `"response":{"modelVersion":"gemini-x","candidates":[`) || !strings.Contains(final, `"finishReason":"MAX_TOKENS"`) {
+		t.Fatalf("gemini final = %s", final)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamUsageInjector fills in token usage that a translated stream dropped, using the usage
+// the executor observed upstream. Each client format carries usage in its own place: OpenAI
+// chat streams end with a usage chunk when stream_options.include_usage is set, Claude streams
+// report it on message_delta, Gemini streams on the chunk that carries the finish reason, and
+// Responses streams on response.completed.
+type streamUsageInjector struct {
+	ctx          context.Context
+	format       string
+	includeUsage bool
+	seen         bool
+	id           string
+	model        string
+	created      int64
+}
+
+// newStreamUsageInjector returns an injector for the client format, with a context in which
+// executors record the usage they observe. It returns nil for formats it does not handle.
+func newStreamUsageInjector(ctx context.Context, handlerType string, rawJSON []byte) (*streamUsageInjector, context.Context) {
+	switch handlerType {
+	case constant.OpenAI, constant.Claude, constant.Gemini, constant.GeminiCLI, constant.OpenaiResponse:
+	default:
+		return nil, ctx
+	}
+	if ctx == nil {
+		return nil, ctx
+	}
+	ctx = usage.WithDetailRecorder(ctx)
+	return &streamUsageInjector{
+		ctx:          ctx,
+		format:       handlerType,
+		includeUsage: gjson.GetBytes(rawJSON, "stream_options.include_usage").Bool(),
+	}, ctx
+}
+
+// process adds missing usage to a stream chunk.
+func (u *streamUsageInjector) process(chunk []byte) []byte {
+	if u == nil || len(chunk) == 0 {
+		return chunk
+	}
+	return mapResponseJSON(chunk, u.processEvent)
+}
+
+func (u *streamUsageInjector) processEvent(event []byte) []byte {
+	switch u.format {
+	case constant.OpenAI:
+		if usageNode := gjson.GetBytes(event, "usage"); usageNode.Exists() && usageNode.Type != gjson.Null {
+			u.seen = true
+		}
+		if id := gjson.GetBytes(event, "id").String(); id != "" {
+			u.id = id
+		}
+		if model := gjson.GetBytes(event, "model").String(); model != "" {
+			u.model = model
+		}
+		if created := gjson.GetBytes(event, "created").Int(); created != 0 {
+			u.created = created
+		}
+		return event
+	case constant.Claude:
+		if gjson.GetBytes(event, "type").String() != "message_delta" {
+			return event
+		}
+		if gjson.GetBytes(event, "usage").Exists() {
+			return event
+		}
+		detail, ok := usage.DetailFromContext(u.ctx)
+		if !ok {
+			return event
+		}
+		return setUsageJSON(event, "usage", map[string]any{
+			"input_tokens":            detail.InputTokens - detail.CachedTokens,
+			"output_tokens":           detail.OutputTokens,
+			"cache_read_input_tokens": detail.CachedTokens,
+		})
+	case constant.Gemini, constant.GeminiCLI:
+		root := ""
+		if gjson.GetBytes(event, "response").IsObject() {
+			root = "response."
+		}
+		if gjson.GetBytes(event, root+"candidates.0.finishReason").String() == "" {
+			return event
+		}
+		if gjson.GetBytes(event, root+"usageMetadata").Exists() || gjson.GetBytes(event, root+"usage_metadata").Exists() {
+			return event
+		}
+		detail, ok := usage.DetailFromContext(u.ctx)
+		if !ok {
+			return event
+		}
+		return setUsageJSON(event, root+"usageMetadata", map[string]any{
+			"promptTokenCount":        detail.InputTokens,
+			"candidatesTokenCount":    detail.OutputTokens,
+			"thoughtsTokenCount":      detail.ReasoningTokens,
+			"cachedContentTokenCount": detail.CachedTokens,
+			"totalTokenCount":         totalTokens(detail),
+		})
+	case constant.OpenaiResponse:
+		if gjson.GetBytes(event, "type").String() != "response.completed" {
+			return event
+		}
+		if usageNode := gjson.GetBytes(event, "response.usage"); usageNode.Exists() && usageNode.Type != gjson.Null {
+			return event
+		}
+		detail, ok := usage.DetailFromContext(u.ctx)
+		if !ok {
+			return event
+		}
+		return setUsageJSON(event, "response.usage", map[string]any{
+			"input_tokens":          detail.InputTokens,
+			"input_tokens_details":  map[string]any{"cached_tokens": detail.CachedTokens},
+			"output_tokens":         detail.OutputTokens,
+			"output_tokens_details": map[string]any{"reasoning_tokens": detail.ReasoningTokens},
+			"total_tokens":          totalTokens(detail),
+		})
+	}
+	return event
+}
+
+// finish returns a trailing usage chunk for OpenAI chat streams that asked for usage but did
+// not receive it, or nil.
+func (u *streamUsageInjector) finish() []byte {
+	if u == nil || u.format != constant.OpenAI || !u.includeUsage || u.seen {
+		return nil
+	}
+	detail, ok := usage.DetailFromContext(u.ctx)
+	if !ok {
+		return nil
+	}
+	created := u.created
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	chunk := []byte(`{"object":"chat.completion.chunk","choices":[]}`)
+	chunk, _ = sjson.SetBytes(chunk, "id", u.id)
+	chunk, _ = sjson.SetBytes(chunk, "created", created)
+	chunk, _ = sjson.SetBytes(chunk, "model", u.model)
+	return setUsageJSON(chunk, "usage", map[string]any{
+		"prompt_tokens":             detail.InputTokens,
+		"completion_tokens":         detail.OutputTokens,
+		"total_tokens":              totalTokens(detail),
+		"prompt_tokens_details":     map[string]any{"cached_tokens": detail.CachedTokens},
+		"completion_tokens_details": map[string]any{"reasoning_tokens": detail.ReasoningTokens},
+	})
+}
+
+func setUsageJSON(event []byte, path string, value map[string]any) []byte {
+	updated, errSet := sjson.SetBytes(event, path, value)
+	if errSet != nil {
+		return event
+	}
+	return updated
+}
+
+func totalTokens(detail usage.Detail) int64 {
+	if detail.TotalTokens > 0 {
+		return detail.TotalTokens
+	}
+	return detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
+}
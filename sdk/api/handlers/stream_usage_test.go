@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+)
+
+func TestStreamUsageInjector_SynthesizesOpenAIUsageChunk(t *testing.T) {
+	injector, ctx := newStreamUsageInjector(context.Background(), constant.OpenAI, []byte(`{"stream_options":{"include_usage":true}}`))
+	injector.process([]byte(`{"id":"chatcmpl-1","created":1700000000,"model":"gpt-5","choices":[{"delta":{"content":"hi"}}]}`))
+	usage.ObserveDetail(ctx, usage.Detail{InputTokens: 10, OutputTokens: 4})
+
+	tail := injector.finish()
+	if gjson.GetBytes(tail, "id").String() != "chatcmpl-1" || gjson.GetBytes(tail, "model").String() != "gpt-5" {
+		t.Fatalf("unexpected tail chunk %s", tail)
+	}
+	if gjson.GetBytes(tail, "usage.prompt_tokens").Int() != 10 || gjson.GetBytes(tail, "usage.total_tokens").Int() != 14 {
+		t.Fatalf("unexpected usage %s", tail)
+	}
+
+	injector.process([]byte(`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":10}}`))
+	if tail = injector.finish(); tail != nil {
+		t.Fatalf("expected no synthesized chunk once usage was streamed, got %s", tail)
+	}
+
+	withoutOption, _ := newStreamUsageInjector(context.Background(), constant.OpenAI, []byte(`{}`))
+	if tail = withoutOption.finish(); tail != nil {
+		t.Fatalf("expected no usage chunk without include_usage, got %s", tail)
+	}
+}
+
+func TestStreamUsageInjector_FillsFinalEvents(t *testing.T) {
+	detail := usage.Detail{InputTokens: 7, OutputTokens: 3, CachedTokens: 2}
+
+	claude, ctx := newStreamUsageInjector(context.Background(), constant.Claude, nil)
+	usage.ObserveDetail(ctx, detail)
+	out := claude.process([]byte("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n"))
+	want := "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"cache_read_input_tokens\":2,\"input_tokens\":5,\"output_tokens\":3}}\n\n"
+	if string(out) != want {
+		t.Fatalf("claude event = %q", out)
+	}
+
+	gemini, ctx := newStreamUsageInjector(context.Background(), constant.GeminiCLI, nil)
+	usage.ObserveDetail(ctx, detail)
+	out = gemini.process([]byte(`{"response":{"candidates":[{"finishReason":"STOP"}]}}`))
+	if gjson.GetBytes(out, "response.usageMetadata.totalTokenCount").Int() != 10 {
+		t.Fatalf("gemini chunk = %s", out)
+	}
+
+	responses, ctx := newStreamUsageInjector(context.Background(), constant.OpenaiResponse, nil)
+	usage.ObserveDetail(ctx, detail)
+	out = responses.process([]byte("event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\"}}"))
+	if got := gjson.Get(string(out[len("event: response.completed\ndata: "):]), "response.usage.input_tokens_details.cached_tokens").Int(); got != 2 {
+		t.Fatalf("responses event = %s", out)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const systemPromptSummaryInstructions = "Rewrite the following system prompt as a compact version that preserves every rule, " +
+	"constraint, tool usage instruction, output format requirement and identifier it contains. Drop examples, repetition " +
+	"and filler. Reply with the rewritten system prompt only."
+
+// systemPromptCacheKeyPrefix prefixes the prompt caching keys assigned to repeated system prompts.
+const systemPromptCacheKeyPrefix = "cliproxy-sys-"
+
+// systemPromptSweepSize is the number of remembered prompts above which expired entries are purged.
+const systemPromptSweepSize = 4096
+
+// systemPromptMemory remembers the system prompts seen per session and the summaries written for them.
+type systemPromptMemory struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	summaries map[string]systemPromptSummary
+}
+
+type systemPromptSummary struct {
+	text   string
+	expire time.Time
+}
+
+var systemPrompts = &systemPromptMemory{
+	seen:      make(map[string]time.Time),
+	summaries: make(map[string]systemPromptSummary),
+}
+
+// observe records key until now+ttl and reports whether it was already remembered.
+func (m *systemPromptMemory) observe(key string, now time.Time, ttl time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expire, ok := m.seen[key]
+	repeated := ok && now.Before(expire)
+	m.seen[key] = now.Add(ttl)
+	if len(m.seen) > systemPromptSweepSize {
+		for k, exp := range m.seen {
+			if !now.Before(exp) {
+				delete(m.seen, k)
+			}
+		}
+		for k, summary := range m.summaries {
+			if !now.Before(summary.expire) {
+				delete(m.summaries, k)
+			}
+		}
+	}
+	return repeated
+}
+
+func (m *systemPromptMemory) summary(hash string, now time.Time) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summary, ok := m.summaries[hash]
+	if !ok || !now.Before(summary.expire) {
+		return "", false
+	}
+	return summary.text, true
+}
+
+func (m *systemPromptMemory) storeSummary(hash, text string, expire time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.summaries[hash] = systemPromptSummary{text: text, expire: expire}
+}
+
+// dedupSystemPrompt applies the system-prompt-dedup strategy to a system prompt the session has
+// already sent. "cache-key" leaves the payload alone and records a prompt caching key in meta for
+// the executors; "summarize" swaps the prompt for a cached compact rewrite.
+func (h *BaseAPIHandler) dedupSystemPrompt(ctx context.Context, handlerType, model, sessionID string, meta map[string]any, rawJSON []byte) []byte {
+	if h == nil || h.Cfg == nil {
+		return rawJSON
+	}
+	sd := h.Cfg.SystemPromptDedup
+	if sd.Strategy == "" || !matchesAnyModelPattern(sd.Models, model) {
+		return rawJSON
+	}
+	text := systemPromptText(handlerType, rawJSON)
+	if text == "" || estimateTokens(gjson.Result{Type: gjson.String, Str: text}) < sd.MinTokens {
+		return rawJSON
+	}
+	session := sessionID
+	if session == "" {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+			if key := clientAPIKeyFromGin(ginCtx); key != "" {
+				session = key + "|" + model
+			}
+		}
+	}
+	if session == "" {
+		return rawJSON
+	}
+
+	hash := systemPromptHash(text)
+	now := time.Now()
+	ttl := time.Duration(sd.TTLSeconds) * time.Second
+	if !systemPrompts.observe(session+"|"+hash, now, ttl) {
+		return rawJSON
+	}
+
+	if sd.Strategy == config.SystemPromptDedupSummarize {
+		summary, ok := systemPrompts.summary(hash, now)
+		if !ok {
+			var errSummary error
+			summary, errSummary = h.summarizeText(ctx, sd.SummaryModel, systemPromptSummaryInstructions, text)
+			if errSummary != nil {
+				log.Warnf("system prompt dedup: summary via %s failed, using a cache key instead: %v", sd.SummaryModel, errSummary)
+			} else {
+				systemPrompts.storeSummary(hash, summary, now.Add(ttl))
+				ok = true
+			}
+		}
+		if ok {
+			if updated := replaceSystemPrompt(handlerType, rawJSON, summary); updated != nil {
+				log.Debugf("system prompt dedup: replaced repeated system prompt for model %s (%d -> %d bytes)", model, len(text), len(summary))
+				return updated
+			}
+		}
+	}
+	meta[coreexecutor.SystemPromptCacheKeyMetadataKey] = systemPromptCacheKeyPrefix + hash[:32]
+	return rawJSON
+}
+
+func systemPromptHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// systemPromptText returns the system prompt of rawJSON in the client format of handlerType.
+func systemPromptText(handlerType string, rawJSON []byte) string {
+	segments := make([]string, 0, 4)
+	switch handlerType {
+	case constant.Claude:
+		appendSystemText(gjson.GetBytes(rawJSON, "system"), &segments)
+	case constant.Gemini, constant.GeminiCLI:
+		prefix := ""
+		if handlerType == constant.GeminiCLI {
+			prefix = "request."
+		}
+		system := gjson.GetBytes(rawJSON, prefix+"systemInstruction")
+		if !system.Exists() {
+			system = gjson.GetBytes(rawJSON, prefix+"system_instruction")
+		}
+		appendSystemText(system.Get("parts"), &segments)
+	default:
+		if handlerType == constant.OpenaiResponse {
+			appendSystemText(gjson.GetBytes(rawJSON, "instructions"), &segments)
+		}
+		for _, turn := range gjson.GetBytes(rawJSON, conversationPath(handlerType)).Array() {
+			if !isSystemTurn(turn) {
+				break
+			}
+			appendSystemText(turn.Get("content"), &segments)
+		}
+	}
+	return strings.Join(segments, "\n")
+}
+
+// appendSystemText appends the text of a system prompt value: a string or an array of
+// strings and text blocks.
+func appendSystemText(value gjson.Result, segments *[]string) {
+	items := []gjson.Result{value}
+	if value.IsArray() {
+		items = value.Array()
+	}
+	for _, item := range items {
+		text := item.Get("text").String()
+		if item.Type == gjson.String {
+			text = item.String()
+		}
+		if text != "" {
+			*segments = append(*segments, text)
+		}
+	}
+}
+
+// replaceSystemPrompt replaces the system prompt of rawJSON with text, returning nil when the
+// payload cannot be updated.
+func replaceSystemPrompt(handlerType string, rawJSON []byte, text string) []byte {
+	var err error
+	out := rawJSON
+	switch handlerType {
+	case constant.Claude:
+		out, err = sjson.SetBytes(out, "system", text)
+	case constant.Gemini, constant.GeminiCLI:
+		prefix := ""
+		if handlerType == constant.GeminiCLI {
+			prefix = "request."
+		}
+		out, _ = sjson.DeleteBytes(out, prefix+"system_instruction")
+		out, err = sjson.SetBytes(out, prefix+"systemInstruction", map[string]any{"parts": []map[string]string{{"text": text}}})
+	default:
+		path := conversationPath(handlerType)
+		items := gjson.GetBytes(out, path).Array()
+		kept := make([]string, 0, len(items)+1)
+		switch {
+		case handlerType == constant.OpenaiResponse && gjson.GetBytes(out, "instructions").Exists():
+			if out, err = sjson.SetBytes(out, "instructions", text); err != nil {
+				return nil
+			}
+		case handlerType == constant.OpenaiResponse:
+			turn, _ := sjson.SetBytes([]byte(`{"type":"message","role":"developer","content":[{"type":"input_text","text":""}]}`), "content.0.text", text)
+			kept = append(kept, string(turn))
+		default:
+			turn, _ := sjson.SetBytes([]byte(`{"role":"system","content":""}`), "content", text)
+			kept = append(kept, string(turn))
+		}
+		start := 0
+		for start < len(items) && isSystemTurn(items[start]) {
+			start++
+		}
+		for _, item := range items[start:] {
+			kept = append(kept, item.Raw)
+		}
+		out, err = sjson.SetRawBytes(out, path, []byte("["+strings.Join(kept, ",")+"]"))
+	}
+	if err != nil {
+		return nil
+	}
+	return out
+}
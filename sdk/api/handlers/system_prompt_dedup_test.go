@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestDedupSystemPromptCacheKey(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{SystemPromptDedup: sdkconfig.SystemPromptDedupConfig{
+		Strategy: "cache-key", MinTokens: 50, TTLSeconds: 60,
+	}}, coreauth.NewManager(nil, nil, nil))
+	system := strings.Repeat("Follow the repository conventions. ", 40)
+	payload := []byte(`{"model":"m","system":"` + system + `","messages":[{"role":"user","content":"hi"}]}`)
+
+	first := map[string]any{}
+	if out := h.dedupSystemPrompt(context.Background(), "claude", "m", "session-cache-key", first, payload); string(out) != string(payload) {
+		t.Fatalf("first request modified: %s", out)
+	}
+	if _, ok := first[coreexecutor.SystemPromptCacheKeyMetadataKey]; ok {
+		t.Fatal("first request got a cache key")
+	}
+
+	second := map[string]any{}
+	h.dedupSystemPrompt(context.Background(), "claude", "m", "session-cache-key", second, payload)
+	key, _ := second[coreexecutor.SystemPromptCacheKeyMetadataKey].(string)
+	if !strings.HasPrefix(key, systemPromptCacheKeyPrefix) {
+		t.Fatalf("cache key = %q", key)
+	}
+
+	short := map[string]any{}
+	shortPayload := []byte(`{"model":"m","system":"be brief","messages":[{"role":"user","content":"hi"}]}`)
+	h.dedupSystemPrompt(context.Background(), "claude", "m", "session-cache-key", short, shortPayload)
+	h.dedupSystemPrompt(context.Background(), "claude", "m", "session-cache-key", short, shortPayload)
+	if _, ok := short[coreexecutor.SystemPromptCacheKeyMetadataKey]; ok {
+		t.Fatal("system prompt below min-tokens got a cache key")
+	}
+}
+
+func TestDedupSystemPromptSummarize(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{SystemPromptDedup: sdkconfig.SystemPromptDedupConfig{
+		Strategy: "summarize", SummaryModel: "cheap", MinTokens: 50, TTLSeconds: 60,
+	}}, coreauth.NewManager(nil, nil, nil))
+	system := strings.Repeat("Always answer in English. ", 40)
+	payload := []byte(`{"model":"m","messages":[{"role":"system","content":"` + system + `"},{"role":"developer","content":"extra"},{"role":"user","content":"hi"}]}`)
+	text := systemPromptText("openai", payload)
+	if !strings.HasSuffix(text, "\nextra") {
+		t.Fatalf("system text = %q", text)
+	}
+	h.dedupSystemPrompt(context.Background(), "openai", "m", "session-summarize", map[string]any{}, payload)
+	systemPrompts.storeSummary(systemPromptHash(text), "Answer in English.", time.Now().Add(time.Minute))
+
+	meta := map[string]any{}
+	out := h.dedupSystemPrompt(context.Background(), "openai", "m", "session-summarize", meta, payload)
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 2 || messages[0].Get("content").String() != "Answer in English." || messages[1].Get("role").String() != "user" {
+		t.Fatalf("unexpected messages: %s", out)
+	}
+	if _, ok := meta[coreexecutor.SystemPromptCacheKeyMetadataKey]; ok {
+		t.Fatal("summarized request also got a cache key")
+	}
+}
+
+func TestReplaceSystemPromptFormats(t *testing.T) {
+	cases := []struct {
+		handlerType, payload, path string
+	}{
+		{"claude", `{"system":[{"type":"text","text":"long"}],"messages":[]}`, "system"},
+		{"gemini", `{"system_instruction":{"parts":[{"text":"long"}]},"contents":[]}`, "systemInstruction.parts.0.text"},
+		{"gemini-cli", `{"request":{"systemInstruction":{"parts":[{"text":"long"}]},"contents":[]}}`, "request.systemInstruction.parts.0.text"},
+		{"openai-response", `{"instructions":"long","input":[{"type":"message","role":"user","content":[]}]}`, "instructions"},
+		{"openai-response", `{"input":[{"role":"developer","content":"long"},{"type":"message","role":"user","content":[]}]}`, "input.0.content.0.text"},
+	}
+	for _, tc := range cases {
+		if got := systemPromptText(tc.handlerType, []byte(tc.payload)); got != "long" {
+			t.Errorf("%s: system text = %q", tc.handlerType, got)
+		}
+		out := replaceSystemPrompt(tc.handlerType, []byte(tc.payload), "short")
+		if got := gjson.GetBytes(out, tc.path).String(); got != "short" {
+			t.Errorf("%s: %s = %q in %s", tc.handlerType, tc.path, got, out)
+		}
+		if got := systemPromptText(tc.handlerType, out); got != "short" {
+			t.Errorf("%s: replaced system text = %q", tc.handlerType, got)
+		}
+	}
+}
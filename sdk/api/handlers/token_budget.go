@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
+)
+
+const (
+	defaultTokenBudgetWindow       = 24 * time.Hour
+	defaultReserveOutputTokens     = 4096
+	defaultTokenBudgetAlertPercent = 80
+)
+
+// tokenBudgetError reports a request that does not fit the remaining token budget of its client key.
+type tokenBudgetError struct {
+	limit    int64
+	used     int64
+	reserved int64
+	// estimated is the estimated input tokens plus the requested or reserved output tokens.
+	estimated int64
+}
+
+func (e *tokenBudgetError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message":          fmt.Sprintf("Token budget exceeded: this request needs about %d tokens but only %d of %d remain in the current window (%d reserved by requests in flight).", e.estimated, max(e.limit-e.used-e.reserved, 0), e.limit, e.reserved),
+			"type":             "insufficient_quota",
+			"code":             "token_budget_exceeded",
+			"estimated_tokens": e.estimated,
+		},
+	})
+	return string(body)
+}
+
+func (e *tokenBudgetError) StatusCode() int { return http.StatusTooManyRequests }
+
+func (e *tokenBudgetError) ProxyGenerated() bool { return true }
+
+// reserveTokenBudget reserves the estimated tokens of the request against the budget of its
+// client key. The returned release function must be called once the request has finished;
+// actual usage is then counted from the usage records of the request.
+func (h *BaseAPIHandler) reserveTokenBudget(ctx context.Context, rawJSON []byte) (func(), *interfaces.ErrorMessage) {
+	noop := func() {}
+	if h == nil || h.Cfg == nil || len(h.Cfg.TokenBudgets.Keys) == 0 {
+		return noop, nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	key := clientAPIKeyFromGin(ginCtx)
+	limit, ok := config.LookupClientKey(h.Cfg, h.Cfg.TokenBudgets.Keys, key)
+	if !ok || key == "" {
+		return noop, nil
+	}
+	window := time.Duration(h.Cfg.TokenBudgets.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultTokenBudgetWindow
+	}
+	outputTokens := requestedOutputTokens(rawJSON)
+	if outputTokens <= 0 {
+		outputTokens = h.Cfg.TokenBudgets.ReserveOutputTokens
+	}
+	if outputTokens <= 0 {
+		outputTokens = defaultReserveOutputTokens
+	}
+	estimated := int64(requestInputTokens(ctx, rawJSON) + outputTokens)
+
+	release, ok := usage.ReserveTokens(key, estimated, limit, window, time.Now())
+	used, reserved := usage.TokenBudgetUsage(key)
+	if !ok {
+		notifyTokenBudget(key, limit, used, reserved, 100)
+		return noop, errorMessageFromError(&tokenBudgetError{limit: limit, used: used, reserved: reserved, estimated: estimated})
+	}
+	alertPercent := h.Cfg.TokenBudgets.AlertPercent
+	if alertPercent <= 0 {
+		alertPercent = defaultTokenBudgetAlertPercent
+	}
+	if limit > 0 && (used+reserved)*100 >= limit*int64(alertPercent) {
+		notifyTokenBudget(key, limit, used, reserved, alertPercent)
+	}
+	return release, nil
+}
+
+// notifyTokenBudget fires a budget.threshold webhook the first time in a window that key
+// reaches percent of its budget. A percent of 100 reports an exhausted budget.
+func notifyTokenBudget(key string, limit, used, reserved int64, percent int) {
+	if !usage.ClaimTokenBudgetAlert(key, percent) {
+		return
+	}
+	webhook.Notify(webhook.EventBudgetThreshold, map[string]any{
+		"api_key":   util.HideAPIKey(key),
+		"percent":   percent,
+		"limit":     limit,
+		"used":      used,
+		"reserved":  reserved,
+		"exhausted": percent >= 100,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestReserveTokenBudget(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{TokenBudgets: sdkconfig.TokenBudgetConfig{
+		Keys: map[string]int64{"budget-test-key": 1500},
+	}}, coreauth.NewManager(nil, nil, nil))
+	payload := []byte(`{"messages":[{"role":"user","content":"hello"}],"max_tokens":1000}`)
+
+	release, errMsg := h.reserveTokenBudget(catalogTestContext("budget-test-key"), payload)
+	if errMsg != nil {
+		t.Fatalf("first reservation failed: %v", errMsg.Error)
+	}
+	_, errMsg = h.reserveTokenBudget(catalogTestContext("budget-test-key"), payload)
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while the first request is in flight, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "token_budget_exceeded" {
+		t.Fatalf("code = %q", code)
+	}
+	release()
+
+	releaseAgain, errMsg := h.reserveTokenBudget(catalogTestContext("budget-test-key"), payload)
+	if errMsg != nil {
+		t.Fatalf("reservation after release failed: %v", errMsg.Error)
+	}
+	releaseAgain()
+	if _, errMsg = h.reserveTokenBudget(catalogTestContext("unlisted-key"), payload); errMsg != nil {
+		t.Fatalf("unlisted keys are unlimited: %v", errMsg.Error)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUsageAnomalyMultiplier     = 5
+	defaultUsageAnomalyTrailingHours  = 24
+	defaultUsageAnomalyMinTokens      = 10000
+	defaultUsageAnomalyRequestsPerMin = 5
+)
+
+// usageAnomalyThrottledError reports a request rejected by the stricter rate limit applied to a
+// client key after a usage spike.
+type usageAnomalyThrottledError struct {
+	perMinute  int
+	retryAfter time.Duration
+}
+
+func (e *usageAnomalyThrottledError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf("Rate limited: unusual token usage was detected for this API key, which is limited to %d requests per minute for now.", e.perMinute),
+			"type":    "rate_limit_error",
+			"code":    "usage_anomaly_throttled",
+		},
+	})
+	return string(body)
+}
+
+func (e *usageAnomalyThrottledError) StatusCode() int { return http.StatusTooManyRequests }
+
+func (e *usageAnomalyThrottledError) ProxyGenerated() bool { return true }
+
+func (e *usageAnomalyThrottledError) RetryAfter() *time.Duration { return &e.retryAfter }
+
+// checkUsageAnomaly flags the client key of the request when its token usage this hour spikes
+// above its trailing average, firing a usage.anomaly webhook and, when configured, throttling
+// the key. Requests of a throttled key beyond its rate limit are rejected.
+func (h *BaseAPIHandler) checkUsageAnomaly(ctx context.Context) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || !h.Cfg.UsageAnomaly.Enabled {
+		return nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	key := clientAPIKeyFromGin(ginCtx)
+	if key == "" {
+		return nil
+	}
+	cfg := h.Cfg.UsageAnomaly
+	multiplier := cfg.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultUsageAnomalyMultiplier
+	}
+	trailingHours := cfg.TrailingHours
+	if trailingHours <= 0 {
+		trailingHours = defaultUsageAnomalyTrailingHours
+	}
+	minTokens := cfg.MinTokens
+	if minTokens <= 0 {
+		minTokens = defaultUsageAnomalyMinTokens
+	}
+	perMinute := cfg.ThrottleRequestsPerMinute
+	if perMinute <= 0 {
+		perMinute = defaultUsageAnomalyRequestsPerMin
+	}
+
+	now := time.Now()
+	if anomaly, flagged := usage.CheckUsageAnomaly(key, multiplier, trailingHours, minTokens, now); flagged {
+		data := map[string]any{
+			"api_key":          util.HideAPIKey(key),
+			"hour_tokens":      anomaly.HourTokens,
+			"trailing_average": math.Round(anomaly.TrailingAverage),
+			"trailing_hours":   anomaly.TrailingHours,
+		}
+		if anomaly.TrailingAverage > 0 {
+			data["ratio"] = math.Round(float64(anomaly.HourTokens)/anomaly.TrailingAverage*10) / 10
+		}
+		if cfg.ThrottleMinutes > 0 {
+			until := now.Add(time.Duration(cfg.ThrottleMinutes) * time.Minute)
+			usage.ThrottleClientKey(key, until)
+			data["throttled_until"] = until.UTC().Format(time.RFC3339)
+		}
+		log.Warnf("usage anomaly: key %s used %d tokens this hour against a trailing average of %.0f", util.HideAPIKey(key), anomaly.HourTokens, anomaly.TrailingAverage)
+		webhook.Notify(webhook.EventUsageAnomaly, data)
+	}
+	if throttled, ok, retryAfter := usage.AllowThrottledRequest(key, perMinute, now); throttled && !ok {
+		return errorMessageFromError(&usageAnomalyThrottledError{perMinute: perMinute, retryAfter: retryAfter})
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestCheckUsageAnomalyEnforcesThrottle(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{UsageAnomaly: sdkconfig.UsageAnomalyConfig{
+		Enabled:                   true,
+		ThrottleMinutes:           10,
+		ThrottleRequestsPerMinute: 1,
+	}}, coreauth.NewManager(nil, nil, nil))
+	usage.ThrottleClientKey("anomaly-test-key", time.Now().Add(time.Minute))
+
+	if errMsg := h.checkUsageAnomaly(catalogTestContext("anomaly-test-key")); errMsg != nil {
+		t.Fatalf("first request failed: %v", errMsg.Error)
+	}
+	errMsg := h.checkUsageAnomaly(catalogTestContext("anomaly-test-key"))
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the throttled key, got %v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "usage_anomaly_throttled" {
+		t.Fatalf("code = %q", code)
+	}
+	if errMsg.Addon.Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After")
+	}
+	if errMsg = h.checkUsageAnomaly(catalogTestContext("other-key")); errMsg != nil {
+		t.Fatalf("other keys are not throttled: %v", errMsg.Error)
+	}
+}
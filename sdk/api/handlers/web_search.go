@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// webSearchToolName is the function name of the built-in web search tool.
+const webSearchToolName = "web_search"
+
+// Default search API endpoints, overridden by web-search.base-url.
+const (
+	braveSearchURL = "https://api.search.brave.com/res/v1/web/search"
+	bingSearchURL  = "https://api.bing.microsoft.com/v7.0/search"
+)
+
+// webSearchResult is one search hit.
+type webSearchResult struct {
+	title   string
+	url     string
+	snippet string
+}
+
+// webSearchApply reports whether the web search tool is offered to clientKey.
+func (h *BaseAPIHandler) webSearchApply(clientKey string) bool {
+	if h == nil || h.Cfg == nil || h.Cfg.WebSearch.Provider == "" {
+		return false
+	}
+	keys := h.Cfg.WebSearch.ClientKeys
+	return len(keys) == 0 || slices.Contains(keys, clientKey)
+}
+
+func (h *BaseAPIHandler) webSearchTool() serverTool {
+	maxResults := h.Cfg.WebSearch.MaxResults
+	return serverTool{
+		name:        webSearchToolName,
+		description: "Search the web and return the top results with their titles, URLs and snippets. Use it for current events and facts you are unsure about.",
+		schema: []byte(`{"type":"object","properties":{"query":{"type":"string","description":"The search query."},` +
+			`"count":{"type":"integer","description":"Number of results, at most ` + strconv.Itoa(maxResults) + `."}},"required":["query"]}`),
+		run: h.runWebSearch,
+	}
+}
+
+// runWebSearch executes a web_search call and formats the results for the model.
+func (h *BaseAPIHandler) runWebSearch(ctx context.Context, arguments string) (string, error) {
+	ws := h.Cfg.WebSearch
+	query := strings.TrimSpace(gjson.Get(arguments, "query").String())
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	count := ws.MaxResults
+	if requested := int(gjson.Get(arguments, "count").Int()); requested > 0 && requested < count {
+		count = requested
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(ws.TimeoutSeconds)*time.Second)
+	defer cancel()
+	results, err := h.searchWeb(ctx, ws, query, count)
+	if err != nil {
+		return "", err
+	}
+	return formatWebSearchResults(results, ws.MaxResultBytes), nil
+}
+
+// searchWeb queries the configured backend and returns at most count results.
+func (h *BaseAPIHandler) searchWeb(ctx context.Context, ws config.WebSearchConfig, query string, count int) ([]webSearchResult, error) {
+	params := url.Values{"q": {query}}
+	var endpoint, resultsPath, titleKey, snippetKey string
+	header := http.Header{}
+	switch ws.Provider {
+	case config.WebSearchProviderSearxNG:
+		endpoint = ws.BaseURL + "/search"
+		params.Set("format", "json")
+		resultsPath, titleKey, snippetKey = "results", "title", "content"
+	case config.WebSearchProviderBrave:
+		endpoint = braveSearchURL
+		params.Set("count", strconv.Itoa(count))
+		header.Set("X-Subscription-Token", ws.APIKey)
+		resultsPath, titleKey, snippetKey = "web.results", "title", "description"
+	case config.WebSearchProviderBing:
+		endpoint = bingSearchURL
+		params.Set("count", strconv.Itoa(count))
+		header.Set("Ocp-Apim-Subscription-Key", ws.APIKey)
+		resultsPath, titleKey, snippetKey = "webPages.value", "name", "snippet"
+	default:
+		return nil, fmt.Errorf("unsupported web search provider %q", ws.Provider)
+	}
+	if ws.BaseURL != "" && ws.Provider != config.WebSearchProviderSearxNG {
+		endpoint = ws.BaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Accept", "application/json")
+	resp, err := util.SetProxy(h.Cfg, &http.Client{}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("web search: close response body error: %v", errClose)
+		}
+	}()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s search returned status %d", ws.Provider, resp.StatusCode)
+	}
+
+	var results []webSearchResult
+	for _, item := range gjson.GetBytes(data, resultsPath).Array() {
+		if len(results) == count {
+			break
+		}
+		results = append(results, webSearchResult{
+			title:   item.Get(titleKey).String(),
+			url:     item.Get("url").String(),
+			snippet: item.Get(snippetKey).String(),
+		})
+	}
+	return results, nil
+}
+
+// formatWebSearchResults renders results as a numbered list truncated to maxBytes.
+func formatWebSearchResults(results []webSearchResult, maxBytes int) string {
+	if len(results) == 0 {
+		return "No results found."
+	}
+	var b strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&b, "%d. %s\n%s\n", i+1, strings.TrimSpace(result.title), result.url)
+		if snippet := strings.TrimSpace(result.snippet); snippet != "" {
+			b.WriteString(snippet)
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+	text := strings.TrimSpace(b.String())
+	if maxBytes > 0 && len(text) > maxBytes {
+		text = text[:maxBytes]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+		text += "\n[results truncated]"
+	}
+	return text
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestRunWebSearchProviders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		switch {
+		case r.URL.Path == "/search" && r.URL.Query().Get("format") == "json":
+			_, _ = io.WriteString(w, `{"results":[{"title":"Searx `+query+`","url":"https://a.example","content":"first"},{"title":"Two","url":"https://b.example","content":"second"},{"title":"Three","url":"https://c.example"}]}`)
+		case r.Header.Get("X-Subscription-Token") == "brave-key":
+			_, _ = io.WriteString(w, `{"web":{"results":[{"title":"Brave `+query+`","url":"https://brave.example","description":"from brave"}]}}`)
+		case r.Header.Get("Ocp-Apim-Subscription-Key") == "bing-key":
+			_, _ = io.WriteString(w, `{"webPages":{"value":[{"name":"Bing `+query+`","url":"https://bing.example","snippet":"from bing"}]}}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer backend.Close()
+
+	cases := []struct {
+		ws   sdkconfig.WebSearchConfig
+		want string
+	}{
+		{sdkconfig.WebSearchConfig{Provider: "searxng", BaseURL: backend.URL}, "1. Searx go\nhttps://a.example\nfirst\n\n2. Two"},
+		{sdkconfig.WebSearchConfig{Provider: "brave", BaseURL: backend.URL, APIKey: "brave-key"}, "1. Brave go\nhttps://brave.example\nfrom brave"},
+		{sdkconfig.WebSearchConfig{Provider: "bing", BaseURL: backend.URL, APIKey: "bing-key"}, "1. Bing go\nhttps://bing.example\nfrom bing"},
+	}
+	for _, tc := range cases {
+		tc.ws.MaxResults, tc.ws.TimeoutSeconds = 5, 5
+		h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{WebSearch: tc.ws}, nil)
+		out, err := h.runWebSearch(context.Background(), `{"query":"go","count":2}`)
+		if err != nil {
+			t.Fatalf("%s: runWebSearch: %v", tc.ws.Provider, err)
+		}
+		if !strings.HasPrefix(out, tc.want) || strings.Contains(out, "Three") {
+			t.Fatalf("%s: output = %q", tc.ws.Provider, out)
+		}
+	}
+
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{WebSearch: sdkconfig.WebSearchConfig{Provider: "brave", BaseURL: backend.URL, APIKey: "wrong", MaxResults: 5, TimeoutSeconds: 5}}, nil)
+	if _, err := h.runWebSearch(context.Background(), `{"query":"go"}`); err == nil {
+		t.Fatal("runWebSearch with a rejected key succeeded")
+	}
+	if _, err := h.runWebSearch(context.Background(), `{}`); err == nil {
+		t.Fatal("runWebSearch without a query succeeded")
+	}
+}
+
+func TestFormatWebSearchResultsTruncates(t *testing.T) {
+	results := []webSearchResult{{title: "Ünïcode title", url: "https://example.com", snippet: strings.Repeat("é", 100)}}
+	out := formatWebSearchResults(results, 40)
+	if !strings.HasSuffix(out, "[results truncated]") || len(out) > 40+len("\n[results truncated]") {
+		t.Fatalf("output = %q", out)
+	}
+	if formatWebSearchResults(nil, 40) != "No results found." {
+		t.Fatal("empty results not reported")
+	}
+}
+
+func TestWebSearchApplyClientKeys(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{WebSearch: sdkconfig.WebSearchConfig{Provider: "searxng", ClientKeys: []string{"k1"}}}, nil)
+	if !h.webSearchApply("k1") || h.webSearchApply("k2") {
+		t.Fatal("web search client key filter not applied")
+	}
+
+	h = NewBaseAPIHandlers(&sdkconfig.SDKConfig{WebSearch: sdkconfig.WebSearchConfig{Provider: "searxng", MaxResults: 5}}, nil)
+	if tools := h.serverTools(context.Background(), []byte(`{}`)); len(tools) != 1 || tools[0].name != webSearchToolName {
+		t.Fatalf("server tools = %+v, want web_search", tools)
+	}
+	if tools := h.serverTools(context.Background(), []byte(`{"web_search_options":{}}`)); len(tools) != 0 {
+		t.Fatalf("server tools with native search = %d, want 0", len(tools))
+	}
+}
@@ -0,0 +1,327 @@
+// Package client provides a typed Go client for a running CLI Proxy API server.
+//
+// It covers the OpenAI-compatible chat completions, responses and models endpoints,
+// including streaming, as well as common management operations. Requests are
+// authenticated with a client API key (or management key for /v0/management) and
+// retried on transient failures.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManagementBasePath is the path prefix of the management API.
+const ManagementBasePath = "/v0/management"
+
+// RetryPolicy controls how transient failures are retried.
+// Requests are retried on network errors and on 429, 502, 503 and 504 responses.
+// Streaming requests are only retried before the response stream is opened.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 1 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles for every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays requested via Retry-After.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy is configured with WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// Client calls a CLI Proxy API server. It is safe for concurrent use.
+type Client struct {
+	baseURL       *url.URL
+	apiKey        string
+	managementKey string
+	httpClient    *http.Client
+	retry         RetryPolicy
+	userAgent     string
+	headers       http.Header
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sets the client API key sent with OpenAI-compatible requests.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = strings.TrimSpace(key) }
+}
+
+// WithManagementKey sets the key sent with management API requests.
+func WithManagementKey(key string) Option {
+	return func(c *Client) { c.managementKey = strings.TrimSpace(key) }
+}
+
+// WithHTTPClient replaces the HTTP client used to send requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers.Add(key, value) }
+}
+
+// New creates a client for the proxy listening at baseURL (e.g. "http://127.0.0.1:8317").
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL must use http or https, got %q", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		userAgent:  "cliproxy-go-client",
+		headers:    make(http.Header),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c, nil
+}
+
+// do sends a JSON request with retries and decodes a successful JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	resp, err := c.send(ctx, method, path, in, "application/json")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if errDecode := json.NewDecoder(resp.Body).Decode(out); errDecode != nil {
+		return fmt.Errorf("client: decode %s %s response: %w", method, path, errDecode)
+	}
+	return nil
+}
+
+// send issues the request, retrying transient failures, and returns a 2xx response.
+// Non-2xx responses are converted to *APIError.
+func (c *Client) send(ctx context.Context, method, path string, in any, accept string) (*http.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var body []byte
+	if in != nil {
+		var errMarshal error
+		body, errMarshal = json.Marshal(in)
+		if errMarshal != nil {
+			return nil, fmt.Errorf("client: encode %s %s request: %w", method, path, errMarshal)
+		}
+	}
+
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		req, errReq := c.newRequest(ctx, method, path, body, accept)
+		if errReq != nil {
+			return nil, errReq
+		}
+		resp, errDo := c.httpClient.Do(req)
+		var retryAfter time.Duration
+		switch {
+		case errDo != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("client: %s %s: %w", method, path, errDo)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return resp, nil
+		default:
+			lastErr = newAPIError(resp)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !retryableStatus(resp.StatusCode) {
+				return nil, lastErr
+			}
+		}
+		if attempt == attempts {
+			break
+		}
+		if errWait := c.wait(ctx, attempt, retryAfter); errWait != nil {
+			return nil, errWait
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte, accept string) (*http.Request, error) {
+	endpoint := *c.baseURL
+	rawPath, rawQuery, _ := strings.Cut(path, "?")
+	endpoint.Path += rawPath
+	endpoint.RawQuery = rawQuery
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: build %s %s request: %w", method, path, err)
+	}
+	for key, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	key := c.apiKey
+	if strings.HasPrefix(rawPath, ManagementBasePath) {
+		key = c.managementKey
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return req, nil
+}
+
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = c.retry.InitialBackoff << (attempt - 1)
+	}
+	if c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff {
+		delay = c.retry.MaxBackoff
+	}
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// APIError is returned when the proxy answers with a non-2xx status.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the error message reported by the proxy.
+	Message string
+	// Type is the OpenAI-style error type, when present.
+	Type string
+	// Code is the OpenAI-style error code, when present.
+	Code string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("client: proxy returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("client: proxy returned %d", e.StatusCode)
+}
+
+// IsAPIError reports whether err is an *APIError with the given status code.
+func IsAPIError(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// newAPIError reads and closes the response body. It understands both the
+// OpenAI-style {"error":{"message":...}} and management-style {"error":"..."} bodies.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode, Body: body}
+
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && len(envelope.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		}
+		var message string
+		if json.Unmarshal(envelope.Error, &message) == nil {
+			apiErr.Message = message
+		} else if json.Unmarshal(envelope.Error, &detail) == nil {
+			apiErr.Message = detail.Message
+			apiErr.Type = detail.Type
+			if detail.Code != nil {
+				apiErr.Code = fmt.Sprint(detail.Code)
+			}
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	opts = append([]Option{
+		WithAPIKey("client-key"),
+		WithManagementKey("management-key"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}),
+	}, opts...)
+	c, err := New(server.URL, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestCreateChatCompletion(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer client-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "gpt-5" || body["stream"] != nil || body["user"] != "alice" {
+			t.Errorf("unexpected body: %v", body)
+		}
+		_, _ = io.WriteString(w, `{"id":"c1","model":"gpt-5","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	})
+
+	resp, err := c.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:    "gpt-5",
+		Messages: []ChatMessage{{Role: "user", Content: "hello"}},
+		Extra:    map[string]any{"user": "alice"},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Text() != "hi" || resp.Usage.TotalTokens != 4 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "text/event-stream" {
+			t.Errorf("Accept = %q", got)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, ": keep-alive\n\n")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}}]}\r\n\r\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := c.StreamChatCompletion(context.Background(), ChatCompletionRequest{Model: "gpt-5"})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+	var text string
+	for stream.Next() {
+		text += stream.Current().Choices[0].Delta.Text()
+	}
+	if err = stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if text != "Hello" {
+		t.Fatalf("streamed text = %q, want Hello", text)
+	}
+}
+
+func TestStreamResponseEvents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"Hi\"}\n\n")
+		_, _ = io.WriteString(w, "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"r1\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi\"}]}]}}\n\n")
+	})
+
+	stream, err := c.StreamResponse(context.Background(), ResponseRequest{Model: "gpt-5", Input: "hi"})
+	if err != nil {
+		t.Fatalf("StreamResponse: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+	var events []ResponseStreamEvent
+	for stream.Next() {
+		events = append(events, stream.Current())
+	}
+	if err = stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if len(events) != 2 || events[0].Delta != "Hi" || events[1].Response.OutputText() != "Hi" || len(events[1].Raw) == 0 {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestRetriesTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"data":[{"id":"gpt-5","object":"model"}]}`)
+	})
+
+	models, err := c.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if calls.Load() != 3 || len(models) != 1 || models[0].ID != "gpt-5" {
+		t.Fatalf("calls=%d models=%+v", calls.Load(), models)
+	}
+}
+
+func TestAPIErrorIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":{"message":"model not found","type":"invalid_request_error","code":"model_not_found"}}`)
+	})
+
+	_, err := c.CreateChatCompletion(context.Background(), ChatCompletionRequest{Model: "missing"})
+	if !IsAPIError(err, http.StatusNotFound) {
+		t.Fatalf("expected 404 APIError, got %v", err)
+	}
+	apiErr := err.(*APIError)
+	if apiErr.Message != "model not found" || apiErr.Code != "model_not_found" || calls.Load() != 1 {
+		t.Fatalf("unexpected error %+v after %d calls", apiErr, calls.Load())
+	}
+}
+
+func TestManagementUsesManagementKey(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer management-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch r.URL.Path {
+		case "/v0/management/auth-files":
+			_, _ = io.WriteString(w, `{"files":[{"id":"a1","name":"codex.json","provider":"codex","status":"active"}]}`)
+		case "/v0/management/auth-files/status":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if r.Method != http.MethodPatch || body["name"] != "codex.json" || body["disabled"] != true {
+				t.Errorf("unexpected status update %s %v", r.Method, body)
+			}
+			_, _ = io.WriteString(w, `{"status":"ok"}`)
+		case "/v0/management/routing/strategy":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error":"invalid management key"}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	files, err := c.ListAuthFiles(context.Background())
+	if err != nil || len(files) != 1 || files[0].Provider != "codex" {
+		t.Fatalf("ListAuthFiles = %+v, %v", files, err)
+	}
+	if err = c.SetAuthFileDisabled(context.Background(), "codex.json", true); err != nil {
+		t.Fatalf("SetAuthFileDisabled: %v", err)
+	}
+	_, err = c.RoutingStrategy(context.Background())
+	if !IsAPIError(err, http.StatusUnauthorized) || err.(*APIError).Message != "invalid management key" {
+		t.Fatalf("expected management error, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// UsageStatistics is the aggregated request and token usage reported by the proxy.
+type UsageStatistics struct {
+	TotalRequests  int64                  `json:"total_requests"`
+	SuccessCount   int64                  `json:"success_count"`
+	FailureCount   int64                  `json:"failure_count"`
+	TotalTokens    int64                  `json:"total_tokens"`
+	APIs           map[string]APIKeyUsage `json:"apis"`
+	RequestsByDay  map[string]int64       `json:"requests_by_day"`
+	RequestsByHour map[string]int64       `json:"requests_by_hour"`
+	TokensByDay    map[string]int64       `json:"tokens_by_day"`
+	TokensByHour   map[string]int64       `json:"tokens_by_hour"`
+}
+
+// APIKeyUsage summarises usage of a single client API key.
+type APIKeyUsage struct {
+	TotalRequests int64                 `json:"total_requests"`
+	TotalTokens   int64                 `json:"total_tokens"`
+	Models        map[string]ModelUsage `json:"models"`
+}
+
+// ModelUsage summarises usage of a single model.
+type ModelUsage struct {
+	TotalRequests int64 `json:"total_requests"`
+	TotalTokens   int64 `json:"total_tokens"`
+}
+
+// AuthFile describes a credential loaded by the proxy.
+type AuthFile struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Provider       string    `json:"provider"`
+	Label          string    `json:"label,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	Account        string    `json:"account,omitempty"`
+	Status         string    `json:"status"`
+	StatusMessage  string    `json:"status_message,omitempty"`
+	Disabled       bool      `json:"disabled"`
+	Unavailable    bool      `json:"unavailable"`
+	RuntimeOnly    bool      `json:"runtime_only"`
+	CooldownActive bool      `json:"cooldown_active,omitempty"`
+	CooldownUntil  time.Time `json:"cooldown_until,omitempty"`
+	LastRefresh    time.Time `json:"last_refresh,omitempty"`
+}
+
+// Management sends a request to the management API and decodes the JSON response into out.
+// path is relative to /v0/management (e.g. "/config"). in and out may be nil.
+func (c *Client) Management(ctx context.Context, method, path string, in, out any) error {
+	return c.do(ctx, method, ManagementBasePath+path, in, out)
+}
+
+// ListAuthFiles returns the credentials currently loaded by the proxy.
+func (c *Client) ListAuthFiles(ctx context.Context) ([]AuthFile, error) {
+	var out struct {
+		Files []AuthFile `json:"files"`
+	}
+	if err := c.Management(ctx, http.MethodGet, "/auth-files", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Files, nil
+}
+
+// SetAuthFileDisabled enables or disables a credential by file name.
+func (c *Client) SetAuthFileDisabled(ctx context.Context, name string, disabled bool) error {
+	body := map[string]any{"name": name, "disabled": disabled}
+	return c.Management(ctx, http.MethodPatch, "/auth-files/status", body, nil)
+}
+
+// UsageStatistics returns the proxy's in-memory usage statistics.
+func (c *Client) UsageStatistics(ctx context.Context) (*UsageStatistics, error) {
+	var out struct {
+		Usage UsageStatistics `json:"usage"`
+	}
+	if err := c.Management(ctx, http.MethodGet, "/usage", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Usage, nil
+}
+
+// RoutingStrategy returns the active credential routing strategy.
+func (c *Client) RoutingStrategy(ctx context.Context) (string, error) {
+	var out struct {
+		Strategy string `json:"strategy"`
+	}
+	if err := c.Management(ctx, http.MethodGet, "/routing/strategy", nil, &out); err != nil {
+		return "", err
+	}
+	return out.Strategy, nil
+}
+
+// SetRoutingStrategy changes the credential routing strategy (e.g. "round-robin", "fill-first").
+func (c *Client) SetRoutingStrategy(ctx context.Context, strategy string) error {
+	return c.Management(ctx, http.MethodPut, "/routing/strategy", map[string]string{"value": strategy}, nil)
+}
@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ChatMessage is a chat completion message.
+type ChatMessage struct {
+	Role string `json:"role,omitempty"`
+	// Content is a string or a list of content parts (e.g. text and image_url objects).
+	Content          any        `json:"content,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string     `json:"tool_call_id,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+}
+
+// Text returns the message content when it is a plain string, or the concatenated
+// text parts when it is a list of content parts.
+func (m ChatMessage) Text() string {
+	switch content := m.Content.(type) {
+	case string:
+		return content
+	case []any:
+		var b strings.Builder
+		for _, part := range content {
+			if obj, ok := part.(map[string]any); ok {
+				if text, okText := obj["text"].(string); okText {
+					b.WriteString(text)
+				}
+			}
+		}
+		return b.String()
+	default:
+		return ""
+	}
+}
+
+// Tool declares a function the model may call.
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a callable function.
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON schema of the function arguments.
+	Parameters any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	// Index identifies the call being assembled across streaming chunks.
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds the function name and JSON-encoded arguments of a tool call.
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// ChatCompletionRequest is the body of POST /v1/chat/completions.
+type ChatCompletionRequest struct {
+	Model           string        `json:"model"`
+	Messages        []ChatMessage `json:"messages"`
+	MaxTokens       *int          `json:"max_tokens,omitempty"`
+	Temperature     *float64      `json:"temperature,omitempty"`
+	TopP            *float64      `json:"top_p,omitempty"`
+	Stop            []string      `json:"stop,omitempty"`
+	Tools           []Tool        `json:"tools,omitempty"`
+	ToolChoice      any           `json:"tool_choice,omitempty"`
+	ReasoningEffort string        `json:"reasoning_effort,omitempty"`
+	Stream          bool          `json:"stream,omitempty"`
+	// Extra holds additional top-level fields sent as-is (e.g. provider-specific parameters).
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON merges Extra into the encoded request.
+func (r ChatCompletionRequest) MarshalJSON() ([]byte, error) {
+	type plain ChatCompletionRequest
+	return marshalWithExtra(plain(r), r.Extra)
+}
+
+// Usage reports token consumption of a chat completion.
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// ChatCompletion is the response of a non-streaming chat completion.
+type ChatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *Usage       `json:"usage,omitempty"`
+}
+
+// ChatChoice is one completion alternative.
+type ChatChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// ChatCompletionChunk is one event of a streaming chat completion.
+type ChatCompletionChunk struct {
+	ID      string            `json:"id"`
+	Object  string            `json:"object"`
+	Created int64             `json:"created"`
+	Model   string            `json:"model"`
+	Choices []ChatChunkChoice `json:"choices"`
+	Usage   *Usage            `json:"usage,omitempty"`
+}
+
+// ChatChunkChoice carries the incremental delta of one completion alternative.
+type ChatChunkChoice struct {
+	Index        int         `json:"index"`
+	Delta        ChatMessage `json:"delta"`
+	FinishReason *string     `json:"finish_reason,omitempty"`
+}
+
+// CreateChatCompletion sends a non-streaming chat completion request.
+func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletion, error) {
+	req.Stream = false
+	var out ChatCompletion
+	if err := c.do(ctx, http.MethodPost, "/v1/chat/completions", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamChatCompletion sends a streaming chat completion request. The caller must close the stream.
+func (c *Client) StreamChatCompletion(ctx context.Context, req ChatCompletionRequest) (*Stream[ChatCompletionChunk], error) {
+	req.Stream = true
+	resp, err := c.send(ctx, http.MethodPost, "/v1/chat/completions", req, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp, func(_ string, data []byte) (ChatCompletionChunk, error) {
+		var chunk ChatCompletionChunk
+		if errDecode := json.Unmarshal(data, &chunk); errDecode != nil {
+			return chunk, fmt.Errorf("client: decode chat completion chunk: %w", errDecode)
+		}
+		return chunk, nil
+	}), nil
+}
+
+// ResponseRequest is the body of POST /v1/responses.
+type ResponseRequest struct {
+	Model string `json:"model"`
+	// Input is a string prompt or a list of input items.
+	Input              any              `json:"input"`
+	Instructions       string           `json:"instructions,omitempty"`
+	MaxOutputTokens    *int             `json:"max_output_tokens,omitempty"`
+	Temperature        *float64         `json:"temperature,omitempty"`
+	Tools              []any            `json:"tools,omitempty"`
+	ToolChoice         any              `json:"tool_choice,omitempty"`
+	Reasoning          *ReasoningConfig `json:"reasoning,omitempty"`
+	PreviousResponseID string           `json:"previous_response_id,omitempty"`
+	Store              *bool            `json:"store,omitempty"`
+	Stream             bool             `json:"stream,omitempty"`
+	// Extra holds additional top-level fields sent as-is.
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON merges Extra into the encoded request.
+func (r ResponseRequest) MarshalJSON() ([]byte, error) {
+	type plain ResponseRequest
+	return marshalWithExtra(plain(r), r.Extra)
+}
+
+// ReasoningConfig controls reasoning for the responses API.
+type ReasoningConfig struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// Response is the result of a responses API call.
+type Response struct {
+	ID        string               `json:"id"`
+	Object    string               `json:"object"`
+	CreatedAt int64                `json:"created_at"`
+	Model     string               `json:"model"`
+	Status    string               `json:"status"`
+	Output    []ResponseOutputItem `json:"output"`
+	Usage     *ResponseUsage       `json:"usage,omitempty"`
+}
+
+// OutputText concatenates the text of all output_text parts of message items.
+func (r *Response) OutputText() string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, item := range r.Output {
+		if item.Type != "message" {
+			continue
+		}
+		for _, part := range item.Content {
+			if part.Type == "output_text" {
+				b.WriteString(part.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ResponseOutputItem is a message, reasoning or function call item of a response.
+type ResponseOutputItem struct {
+	Type      string            `json:"type"`
+	ID        string            `json:"id,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Content   []ResponseContent `json:"content,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Arguments string            `json:"arguments,omitempty"`
+	CallID    string            `json:"call_id,omitempty"`
+}
+
+// ResponseContent is one content part of a response output item.
+type ResponseContent struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// ResponseUsage reports token consumption of a response.
+type ResponseUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+}
+
+// ResponseStreamEvent is one event of a streaming responses API call.
+type ResponseStreamEvent struct {
+	Type           string `json:"type"`
+	SequenceNumber int64  `json:"sequence_number,omitempty"`
+	ItemID         string `json:"item_id,omitempty"`
+	OutputIndex    int    `json:"output_index,omitempty"`
+	// Delta is the text increment of *.delta events.
+	Delta string `json:"delta,omitempty"`
+	// Response is set on response.created, response.completed and similar events.
+	Response *Response `json:"response,omitempty"`
+	// Raw is the undecoded event payload.
+	Raw json.RawMessage `json:"-"`
+}
+
+// CreateResponse sends a non-streaming responses API request.
+func (c *Client) CreateResponse(ctx context.Context, req ResponseRequest) (*Response, error) {
+	req.Stream = false
+	var out Response
+	if err := c.do(ctx, http.MethodPost, "/v1/responses", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamResponse sends a streaming responses API request. The caller must close the stream.
+func (c *Client) StreamResponse(ctx context.Context, req ResponseRequest) (*Stream[ResponseStreamEvent], error) {
+	req.Stream = true
+	resp, err := c.send(ctx, http.MethodPost, "/v1/responses", req, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp, func(event string, data []byte) (ResponseStreamEvent, error) {
+		var ev ResponseStreamEvent
+		if errDecode := json.Unmarshal(data, &ev); errDecode != nil {
+			return ev, fmt.Errorf("client: decode response event: %w", errDecode)
+		}
+		if ev.Type == "" {
+			ev.Type = event
+		}
+		ev.Raw = append(json.RawMessage(nil), data...)
+		return ev, nil
+	}), nil
+}
+
+// Model is an entry of GET /v1/models.
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// ListModels returns the models available to the configured API key.
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	var out struct {
+		Data []Model `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/models", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// marshalWithExtra encodes v and adds extra top-level fields that are not already set.
+func marshalWithExtra(v any, extra map[string]any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var merged map[string]json.RawMessage
+	if err = json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, exists := merged[key]; exists {
+			continue
+		}
+		raw, errMarshal := json.Marshal(value)
+		if errMarshal != nil {
+			return nil, fmt.Errorf("encode extra field %q: %w", key, errMarshal)
+		}
+		merged[key] = raw
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Stream reads server-sent events from a streaming response.
+//
+// Typical use:
+//
+//	for stream.Next() {
+//		chunk := stream.Current()
+//		...
+//	}
+//	if err := stream.Err(); err != nil { ... }
+//	stream.Close()
+type Stream[T any] struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	decode  func(event string, data []byte) (T, error)
+	current T
+	err     error
+	done    bool
+}
+
+func newStream[T any](resp *http.Response, decode func(event string, data []byte) (T, error)) *Stream[T] {
+	return &Stream[T]{body: resp.Body, reader: bufio.NewReader(resp.Body), decode: decode}
+}
+
+// Next advances to the next event. It returns false at the end of the stream or on error.
+func (s *Stream[T]) Next() bool {
+	if s.done || s.err != nil {
+		return false
+	}
+	for {
+		event, data, err := s.readEvent()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.err = err
+			}
+			s.done = true
+			return false
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if bytes.Equal(data, []byte("[DONE]")) {
+			s.done = true
+			return false
+		}
+		value, errDecode := s.decode(event, data)
+		if errDecode != nil {
+			s.err = errDecode
+			s.done = true
+			return false
+		}
+		s.current = value
+		return true
+	}
+}
+
+// Current returns the event read by the last successful call to Next.
+func (s *Stream[T]) Current() T {
+	return s.current
+}
+
+// Err returns the first error encountered while reading the stream.
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Close releases the underlying connection. It is safe to call more than once.
+func (s *Stream[T]) Close() error {
+	s.done = true
+	if s.body == nil {
+		return nil
+	}
+	err := s.body.Close()
+	s.body = nil
+	return err
+}
+
+// readEvent reads one SSE event, joining multi-line data fields with newlines.
+func (s *Stream[T]) readEvent() (string, []byte, error) {
+	var event string
+	var data []byte
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if len(data) > 0 && errors.Is(err, io.EOF) {
+				return event, data, nil
+			}
+			return "", nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if len(data) > 0 {
+				return event, data, nil
+			}
+			event = ""
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "event":
+			event = string(value)
+		case "data":
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		}
+		if err != nil {
+			if len(data) > 0 {
+				return event, data, nil
+			}
+			return "", nil, err
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cliproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditAnchorInterval = 5 * time.Minute
+	auditAnchorTimeout         = 30 * time.Second
+)
+
+// auditAnchor is what an anchor publishes: the head of the audit chain at a point in time.
+type auditAnchor struct {
+	Time time.Time `json:"time"`
+	File string    `json:"file"`
+	Seq  int64     `json:"seq"`
+	Hash string    `json:"hash"`
+}
+
+// auditAnchors periodically publishes the audit chain head of the current configuration.
+type auditAnchors struct {
+	cfg    config.AuditChainConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+	// current is the latest configuration; anchors read proxy settings from it.
+	current atomic.Pointer[config.Config]
+}
+
+func (s *Service) applyAuditChainConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	if s.auditAnchors == nil {
+		s.auditAnchors = &auditAnchors{}
+	}
+	s.auditAnchors.Apply(cfg)
+}
+
+func (s *Service) shutdownAuditAnchors() {
+	if s == nil || s.auditAnchors == nil {
+		return
+	}
+	s.auditAnchors.Stop()
+}
+
+// Apply restarts the anchor loop when the audit chain configuration changed.
+func (a *auditAnchors) Apply(cfg *config.Config) {
+	a.current.Store(cfg)
+	if a.cancel != nil && reflect.DeepEqual(a.cfg, cfg.AuditChain) {
+		return
+	}
+	a.Stop()
+	a.cfg = cfg.AuditChain
+	if a.cfg.File == "" || (a.cfg.AnchorFile == "" && a.cfg.AnchorURL == "") {
+		return
+	}
+	interval := time.Duration(a.cfg.AnchorIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultAuditAnchorInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	a.cancel = cancel
+	a.done = done
+	go func(chain config.AuditChainConfig) {
+		defer close(done)
+		a.run(ctx, chain, interval)
+	}(a.cfg)
+}
+
+func (a *auditAnchors) Stop() {
+	if a == nil || a.cancel == nil {
+		return
+	}
+	a.cancel()
+	<-a.done
+	a.cancel = nil
+	a.done = nil
+}
+
+// run anchors the chain head every interval, skipping anchors when the head did not move.
+func (a *auditAnchors) run(ctx context.Context, chain config.AuditChainConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last logging.AuditChainHead
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		head, err := logging.AuditChainHeadOf(chain.File)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Errorf("audit chain anchor: %v", err)
+			}
+			continue
+		}
+		if head == last {
+			continue
+		}
+		anchor := auditAnchor{Time: time.Now().UTC(), File: chain.File, Seq: head.Seq, Hash: head.Hash}
+		if err = publishAuditAnchor(ctx, a.current.Load(), chain, anchor); err != nil {
+			log.Errorf("audit chain anchor: %v", err)
+			continue
+		}
+		last = head
+	}
+}
+
+// publishAuditAnchor appends anchor to the anchor file and posts it to the anchor URL.
+func publishAuditAnchor(ctx context.Context, cfg *config.Config, chain config.AuditChainConfig, anchor auditAnchor) error {
+	body, err := json.Marshal(anchor)
+	if err != nil {
+		return err
+	}
+	if chain.AnchorFile != "" {
+		f, errOpen := os.OpenFile(chain.AnchorFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if errOpen != nil {
+			return errOpen
+		}
+		_, err = f.Write(append(body, '\n'))
+		if errClose := f.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if chain.AnchorURL == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, auditAnchorTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, chain.AnchorURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{}
+	if cfg != nil {
+		client = util.SetProxy(&cfg.SDKConfig, client)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("audit chain anchor: close response body error: %v", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("anchor url: status %d", resp.StatusCode)
+	}
+	return nil
+}
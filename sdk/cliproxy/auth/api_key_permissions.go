@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 )
 
@@ -37,10 +38,16 @@ func (m *Manager) allowedAuthRefsForClientKey(clientKey string) (map[string]stru
 		return nil, false
 	}
 	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
-	if cfg == nil || len(cfg.APIKeyAuth) == 0 {
+	if cfg == nil {
 		return nil, false
 	}
-	refs, ok := cfg.APIKeyAuth[clientKey]
+	if link, ok := cfg.ShareLink(clientKey); ok && link.Auth != "" {
+		return map[string]struct{}{link.Auth: {}}, true
+	}
+	if len(cfg.APIKeyAuth) == 0 {
+		return nil, false
+	}
+	refs, ok := internalconfig.LookupClientKey(&cfg.SDKConfig, cfg.APIKeyAuth, clientKey)
 	if !ok {
 		return nil, false
 	}
@@ -55,50 +62,158 @@ func (m *Manager) allowedAuthRefsForClientKey(clientKey string) (map[string]stru
 	return allowed, true
 }
 
-// AllowedAuthIDsForClientKey resolves the auth IDs permitted for a client API key.
-// When the client key is not listed in api-key-auth, restricted is false.
+// AllowedAuthIDsForClientKey resolves the auth IDs permitted for a client API key, combining
+// api-key-auth with the allowed-clients lists of the auths.
+// When neither restricts the client key, restricted is false.
 // When restricted is true but the returned map is empty, the client has no allowed accounts.
 func (m *Manager) AllowedAuthIDsForClientKey(clientKey string) (allowed map[string]struct{}, restricted bool) {
 	allowedRefs, restricted := m.allowedAuthRefsForClientKey(clientKey)
-	if !restricted {
-		return nil, false
-	}
-	if len(allowedRefs) == 0 {
+	if restricted && len(allowedRefs) == 0 {
 		return map[string]struct{}{}, true
 	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	out := make(map[string]struct{})
+	denied := false
 	for _, auth := range m.auths {
-		if authMatchesAllowedRefs(auth, allowedRefs) {
+		if authPermitsClient(auth, clientKey, allowedRefs, restricted) {
 			out[auth.ID] = struct{}{}
+		} else {
+			denied = true
 		}
 	}
+	if !restricted && !denied {
+		return nil, false
+	}
 	return out, true
 }
 
-func authMatchesAllowedRefs(auth *Auth, allowed map[string]struct{}) bool {
-	if auth == nil || len(allowed) == 0 {
+// AuthAllowedClients returns the client API keys an auth is limited to, read from the
+// "allowed_clients" attribute (comma separated) and the "allowed-clients" or
+// "allowed_clients" metadata entry (a list or a comma separated string) of its auth file.
+// An empty result leaves the auth open to every client key.
+func AuthAllowedClients(auth *Auth) []string {
+	return authListValues(auth, "allowed_clients", "allowed-clients", "allowed_clients")
+}
+
+// authPermitsClient runs the permission check of a client key against auth: api-key-auth
+// when the key is restricted there, and the allowed-clients list of the auth.
+func authPermitsClient(auth *Auth, clientKey string, allowedRefs map[string]struct{}, restricted bool) bool {
+	if auth == nil {
 		return false
 	}
-	if id := strings.TrimSpace(auth.ID); id != "" {
-		if _, ok := allowed[id]; ok {
+	if restricted && !authMatchesAllowedRefs(auth, allowedRefs) {
+		return false
+	}
+	clients := AuthAllowedClients(auth)
+	if len(clients) == 0 {
+		return true
+	}
+	clientKey = strings.TrimSpace(clientKey)
+	for _, client := range clients {
+		if clientKey != "" && client == clientKey {
 			return true
 		}
 	}
-	if idx := authIndexForMatch(auth); idx != "" {
-		if _, ok := allowed[idx]; ok {
-			return true
+	return false
+}
+
+// AuthMatchesRef reports whether ref selects auth, using the references accepted by
+// api-key-auth: an auth ID, auth index or file name, a wildcard pattern such as
+// "codex-*.json", "provider:<name>", "tag:<tag>" or "label:<label>". A leading "!" is
+// ignored, so an exclusion matches the auths it excludes.
+func AuthMatchesRef(auth *Auth, ref string) bool {
+	ref = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(ref), "!"))
+	return authMatchesAllowedRefs(auth, map[string]struct{}{ref: {}})
+}
+
+// AuthAllowedByRefs reports whether an api-key-auth entry allows auth: it must match one of
+// the references, or the entry must hold only exclusions, and it must match no exclusion.
+func AuthAllowedByRefs(auth *Auth, refs []string) bool {
+	allowed := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			allowed[ref] = struct{}{}
 		}
 	}
-	if name := strings.TrimSpace(auth.FileName); name != "" {
-		if _, ok := allowed[name]; ok {
-			return true
+	return authMatchesAllowedRefs(auth, allowed)
+}
+
+func authMatchesAllowedRefs(auth *Auth, allowed map[string]struct{}) bool {
+	if auth == nil || len(allowed) == 0 {
+		return false
+	}
+	id := strings.TrimSpace(auth.ID)
+	idx := authIndexForMatch(auth)
+	name := strings.TrimSpace(auth.FileName)
+	included := false
+	onlyExclusions := true
+	for ref := range allowed {
+		if excluded, ok := strings.CutPrefix(ref, "!"); ok {
+			if authMatchesSingleRef(auth, strings.TrimSpace(excluded), id, idx, name) {
+				return false
+			}
+			continue
 		}
+		onlyExclusions = false
+		if !included && authMatchesSingleRef(auth, ref, id, idx, name) {
+			included = true
+		}
+	}
+	return included || onlyExclusions
+}
+
+// authMatchesSingleRef matches one reference against the auth ID, index and file name, then
+// as a pattern.
+func authMatchesSingleRef(auth *Auth, ref, id, idx, name string) bool {
+	if ref == "" {
+		return false
+	}
+	if (id != "" && ref == id) || (idx != "" && ref == idx) || (name != "" && ref == name) {
+		return true
+	}
+	return authMatchesRefPattern(auth, ref, id, name)
+}
+
+// IsAuthRefPattern reports whether ref selects auths by pattern, provider, tag or label
+// rather than naming a single auth.
+func IsAuthRefPattern(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	if strings.Contains(ref, "*") {
+		return true
+	}
+	kind, _, ok := strings.Cut(ref, ":")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(kind) {
+	case "provider", "tag", "label":
+		return true
 	}
 	return false
 }
 
+// authMatchesRefPattern matches the pattern references: "provider:", "tag:" and "label:"
+// prefixes, and wildcards against the auth ID and file name.
+func authMatchesRefPattern(auth *Auth, ref, id, name string) bool {
+	if kind, value, ok := strings.Cut(ref, ":"); ok {
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(kind) {
+		case "provider":
+			return value != "" && strings.EqualFold(strings.TrimSpace(auth.Provider), value)
+		case "tag":
+			return value != "" && authHasTag(auth, value)
+		case "label":
+			label := strings.TrimSpace(auth.Label)
+			return value != "" && label != "" && wildcard.Match(value, label)
+		}
+	}
+	if !strings.Contains(ref, "*") {
+		return false
+	}
+	return (id != "" && wildcard.Match(ref, id)) || (name != "" && wildcard.Match(ref, name))
+}
+
 func authIndexForMatch(auth *Auth) string {
 	if auth == nil {
 		return ""
@@ -110,3 +110,137 @@ func TestAPIKeyAuthPermissions_DenyAll(t *testing.T) {
 		t.Fatalf("Execute() StatusCode = %v, want %d", statusCodeFromError(err), http.StatusForbidden)
 	}
 }
+
+func TestAPIKeyAuthPermissions_PatternRefs(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	ctx := context.Background()
+	_, _ = manager.Register(ctx, &Auth{ID: "codex-a", FileName: "codex-a.json", Provider: "codex", Status: StatusActive})
+	_, _ = manager.Register(ctx, &Auth{ID: "codex-b", FileName: "codex-b.json", Provider: "codex", Label: "Work Account", Status: StatusActive})
+	_, _ = manager.Register(ctx, &Auth{ID: "claude-a", FileName: "claude-a.json", Provider: "claude", Status: StatusActive, Metadata: map[string]any{"tags": []any{"team-a"}}})
+	_, _ = manager.Register(ctx, &Auth{ID: "gemini-a", FileName: "gemini-a.json", Provider: "gemini", Status: StatusActive})
+
+	cases := map[string][]string{
+		"codex-*.json":    {"codex-a", "codex-b"},
+		"*-a.JSON":        {"claude-a", "codex-a", "gemini-a"},
+		"provider:claude": {"claude-a"},
+		"tag:TEAM-A":      {"claude-a"},
+		"label:work*":     {"codex-b"},
+		"provider:":       {},
+		"unknown:value":   {},
+	}
+	for ref, want := range cases {
+		manager.SetConfig(&internalconfig.Config{APIKeyAuth: map[string][]string{"client": {ref}}})
+		allowed, restricted := manager.AllowedAuthIDsForClientKey("client")
+		if !restricted {
+			t.Fatalf("%s: restricted = false", ref)
+		}
+		if len(allowed) != len(want) {
+			t.Fatalf("%s: allowed = %v, want %v", ref, allowed, want)
+		}
+		for _, id := range want {
+			if _, ok := allowed[id]; !ok {
+				t.Fatalf("%s: allowed = %v, missing %s", ref, allowed, id)
+			}
+		}
+	}
+}
+
+func TestIsAuthRefPattern(t *testing.T) {
+	t.Parallel()
+
+	for ref, want := range map[string]bool{
+		"codex-*.json":    true,
+		"provider:codex":  true,
+		"Tag:team":        true,
+		"label:work":      true,
+		"codex-a.json":    false,
+		"gemini:project1": false,
+	} {
+		if got := IsAuthRefPattern(ref); got != want {
+			t.Errorf("IsAuthRefPattern(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestAPIKeyAuthPermissions_Exclusions(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	ctx := context.Background()
+	_, _ = manager.Register(ctx, &Auth{ID: "codex-work", FileName: "codex-work.json", Provider: "codex", Status: StatusActive})
+	_, _ = manager.Register(ctx, &Auth{ID: "codex-personal", FileName: "codex-personal.json", Provider: "codex", Status: StatusActive})
+	_, _ = manager.Register(ctx, &Auth{ID: "claude-a", FileName: "claude-a.json", Provider: "claude", Status: StatusActive})
+
+	cases := []struct {
+		refs []string
+		want []string
+	}{
+		{refs: []string{"!codex-personal.json"}, want: []string{"codex-work", "claude-a"}},
+		{refs: []string{"provider:codex", "!codex-personal.json"}, want: []string{"codex-work"}},
+		{refs: []string{"codex-personal", "!provider:codex"}, want: []string{}},
+		{refs: []string{"!codex-*"}, want: []string{"claude-a"}},
+	}
+	for _, tc := range cases {
+		manager.SetConfig(&internalconfig.Config{APIKeyAuth: map[string][]string{"client": tc.refs}})
+		allowed, restricted := manager.AllowedAuthIDsForClientKey("client")
+		if !restricted {
+			t.Fatalf("%v: restricted = false", tc.refs)
+		}
+		if len(allowed) != len(tc.want) {
+			t.Fatalf("%v: allowed = %v, want %v", tc.refs, allowed, tc.want)
+		}
+		for _, id := range tc.want {
+			if _, ok := allowed[id]; !ok {
+				t.Fatalf("%v: allowed = %v, missing %s", tc.refs, allowed, id)
+			}
+		}
+	}
+}
+
+func TestAPIKeyAuthPermissions_AuthAllowedClients(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	exec := &recordingExecutor{provider: "codex"}
+	manager.RegisterExecutor(exec)
+	manager.SetConfig(&internalconfig.Config{})
+
+	ctx := context.Background()
+	_, _ = manager.Register(ctx, &Auth{ID: "owned", Provider: "codex", Status: StatusActive, Metadata: map[string]any{"allowed-clients": []any{"client-a", "client-b"}}})
+
+	allowed, restricted := manager.AllowedAuthIDsForClientKey("client-a")
+	if restricted {
+		t.Fatalf("client-a: restricted = true, allowed = %v", allowed)
+	}
+	allowed, restricted = manager.AllowedAuthIDsForClientKey("client-c")
+	if !restricted || len(allowed) != 0 {
+		t.Fatalf("client-c: allowed = %v, restricted = %v", allowed, restricted)
+	}
+
+	execute := func(clientKey string) error {
+		opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ClientAPIKeyMetadataKey: clientKey}}
+		_, err := manager.Execute(ctx, []string{"codex"}, cliproxyexecutor.Request{}, opts)
+		return err
+	}
+	if err := execute("client-b"); err != nil {
+		t.Fatalf("client-b: Execute() error = %v", err)
+	}
+	var authErr *Error
+	if err := execute("client-c"); !errors.As(err, &authErr) || authErr.HTTPStatus != http.StatusForbidden {
+		t.Fatalf("client-c: Execute() error = %v, want 403", err)
+	}
+
+	_, _ = manager.Register(ctx, &Auth{ID: "open", Provider: "codex", Status: StatusActive})
+	if err := execute("client-c"); err != nil {
+		t.Fatalf("client-c with open auth: Execute() error = %v", err)
+	}
+	if got := exec.lastAuthID(); got != "open" {
+		t.Fatalf("client-c served by %q, want open", got)
+	}
+	allowed, restricted = manager.AllowedAuthIDsForClientKey("client-c")
+	if _, ok := allowed["open"]; !restricted || len(allowed) != 1 || !ok {
+		t.Fatalf("client-c: allowed = %v, restricted = %v", allowed, restricted)
+	}
+}
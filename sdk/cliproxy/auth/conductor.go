@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -20,7 +21,9 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -41,6 +44,12 @@ type ProviderExecutor interface {
 	HttpRequest(ctx context.Context, auth *Auth, req *http.Request) (*http.Response, error)
 }
 
+// LeaderElector reports whether this instance should run fleet-wide background jobs.
+// Managers without an elector behave as the sole leader.
+type LeaderElector interface {
+	IsLeader() bool
+}
+
 // RefreshEvaluator allows runtime state to override refresh decisions.
 type RefreshEvaluator interface {
 	ShouldRefresh(now time.Time, auth *Auth) bool
@@ -144,8 +153,20 @@ type Manager struct {
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
+	// leaderElector gates background refresh in multi-instance deployments.
+	leaderElector LeaderElector
+
+	// middleware wraps every executor call; see ExecutorMiddleware.
+	middleware []ExecutorMiddleware
+
 	// Auto refresh state
 	refreshCancel context.CancelFunc
+
+	// inlineRefresh deduplicates refreshes triggered by unauthorized upstream responses.
+	inlineRefresh inlineRefreshes
+
+	// reloginSince records when each auth needing a new login was first rejected; guarded by mu.
+	reloginSince map[string]time.Time
 }
 
 // NewManager constructs a manager with optional custom selector and hook.
@@ -219,6 +240,31 @@ func (m *Manager) SetRoundTripperProvider(p RoundTripperProvider) {
 	m.mu.Unlock()
 }
 
+// SetLeaderElector installs the elector consulted before running background refreshes.
+// Passing nil makes this instance always act as leader.
+func (m *Manager) SetLeaderElector(elector LeaderElector) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.leaderElector = elector
+	m.mu.Unlock()
+}
+
+// IsLeader reports whether this manager should run fleet-wide background jobs.
+func (m *Manager) IsLeader() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	elector := m.leaderElector
+	m.mu.RUnlock()
+	if elector == nil {
+		return true
+	}
+	return elector.IsLeader()
+}
+
 // SetConfig updates the runtime config snapshot used by request-time helpers.
 // Callers should provide the latest config on reload so per-credential alias mapping stays in sync.
 func (m *Manager) SetConfig(cfg *internalconfig.Config) {
@@ -460,6 +506,10 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	}
 	auth.EnsureIndex()
 	m.auths[auth.ID] = auth.Clone()
+	if auth.LastError == nil {
+		// Replaced credentials, such as those of a new login, clear a pending relogin.
+		m.clearReloginRequiredLocked(auth.ID)
+	}
 	m.mu.Unlock()
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 	_ = m.persist(ctx, auth)
@@ -503,6 +553,7 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 	}
 
 	_, maxWait := m.retrySettings()
+	ctx = trackLatencyBudget(ctx)
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
@@ -515,8 +566,12 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 		if !shouldRetry {
 			break
 		}
+		traceRetry(ctx, attempt, wait, errExec)
+		if budget := latencyBudgetFrom(ctx); !budget.allowWait(wait) {
+			return cliproxyexecutor.Response{}, budget.exceeded(fmt.Sprintf("no auth is available before the deadline, the next one cools down in %s", wait.Round(time.Millisecond)))
+		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
-			return cliproxyexecutor.Response{}, errWait
+			return cliproxyexecutor.Response{}, latencyBudgetError(ctx, errWait)
 		}
 	}
 	if lastErr != nil {
@@ -534,6 +589,7 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	}
 
 	_, maxWait := m.retrySettings()
+	ctx = trackLatencyBudget(ctx)
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
@@ -546,8 +602,12 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 		if !shouldRetry {
 			break
 		}
+		traceRetry(ctx, attempt, wait, errExec)
+		if budget := latencyBudgetFrom(ctx); !budget.allowWait(wait) {
+			return cliproxyexecutor.Response{}, budget.exceeded(fmt.Sprintf("no auth is available before the deadline, the next one cools down in %s", wait.Round(time.Millisecond)))
+		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
-			return cliproxyexecutor.Response{}, errWait
+			return cliproxyexecutor.Response{}, latencyBudgetError(ctx, errWait)
 		}
 	}
 	if lastErr != nil {
@@ -565,6 +625,7 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	}
 
 	_, maxWait := m.retrySettings()
+	ctx = trackLatencyBudget(ctx)
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
@@ -577,8 +638,12 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 		if !shouldRetry {
 			break
 		}
+		traceRetry(ctx, attempt, wait, errStream)
+		if budget := latencyBudgetFrom(ctx); !budget.allowWait(wait) {
+			return nil, budget.exceeded(fmt.Sprintf("no auth is available before the deadline, the next one cools down in %s", wait.Round(time.Millisecond)))
+		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
-			return nil, errWait
+			return nil, latencyBudgetError(ctx, errWait)
 		}
 	}
 	if lastErr != nil {
@@ -593,6 +658,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
+	middleware := m.executorMiddleware()
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
@@ -606,9 +672,15 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
+		traceAuthSelection(ctx, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
+		budget := latencyBudgetFrom(ctx)
+		if budget.skipAttempt(auth, provider, routeModel) {
+			lastErr = budget.exceeded("no remaining auth can answer before the deadline")
+			continue
+		}
+		execCtx := cliproxyexecutor.WithEndpoint(ctx, cliproxyexecutor.EndpointExecute)
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
@@ -617,11 +689,22 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
-		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+		call := &ExecutionCall{Auth: auth, Provider: provider, Endpoint: cliproxyexecutor.EndpointExecute, Request: execReq, Options: opts}
+		if errVeto := beforeExecute(execCtx, middleware, call); errVeto != nil {
+			return cliproxyexecutor.Response{}, errVeto
+		}
+		finishAttempt := budget.beginAttempt(auth, provider)
+		resp, errExec := executor.Execute(execCtx, auth, call.Request, call.Options)
+		if refreshed := m.refreshAfterUnauthorized(execCtx, auth, errExec); refreshed != nil {
+			auth, call.Auth = refreshed, refreshed
+			resp, errExec = executor.Execute(execCtx, auth, call.Request, call.Options)
+		}
+		finishAttempt(errExec)
+		errAfter := afterExecute(execCtx, middleware, call, &resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
-				return cliproxyexecutor.Response{}, errCtx
+				return cliproxyexecutor.Response{}, latencyBudgetError(ctx, errCtx)
 			}
 			result.Error = &Error{Message: errExec.Error()}
 			var se cliproxyexecutor.StatusError
@@ -633,6 +716,9 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			}
 			result.QuotaReason = quotaReasonFromError(errExec)
 			m.MarkResult(execCtx, result)
+			if errAfter != nil {
+				return cliproxyexecutor.Response{}, errAfter
+			}
 			lastErr = errExec
 			if !shouldRotateAuthOnError(errExec) {
 				return cliproxyexecutor.Response{}, errExec
@@ -640,6 +726,10 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		if errAfter != nil {
+			return cliproxyexecutor.Response{}, errAfter
+		}
+		recordServedAuth(ctx, auth)
 		return resp, nil
 	}
 }
@@ -650,6 +740,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
+	middleware := m.executorMiddleware()
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
@@ -663,9 +754,15 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
+		traceAuthSelection(ctx, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
+		budget := latencyBudgetFrom(ctx)
+		if budget.skipAttempt(auth, provider, routeModel) {
+			lastErr = budget.exceeded("no remaining auth can answer before the deadline")
+			continue
+		}
+		execCtx := cliproxyexecutor.WithEndpoint(ctx, cliproxyexecutor.EndpointCountTokens)
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
@@ -674,11 +771,22 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
-		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
+		call := &ExecutionCall{Auth: auth, Provider: provider, Endpoint: cliproxyexecutor.EndpointCountTokens, Request: execReq, Options: opts}
+		if errVeto := beforeExecute(execCtx, middleware, call); errVeto != nil {
+			return cliproxyexecutor.Response{}, errVeto
+		}
+		finishAttempt := budget.beginAttempt(auth, provider)
+		resp, errExec := executor.CountTokens(execCtx, auth, call.Request, call.Options)
+		if refreshed := m.refreshAfterUnauthorized(execCtx, auth, errExec); refreshed != nil {
+			auth, call.Auth = refreshed, refreshed
+			resp, errExec = executor.CountTokens(execCtx, auth, call.Request, call.Options)
+		}
+		finishAttempt(errExec)
+		errAfter := afterExecute(execCtx, middleware, call, &resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
-				return cliproxyexecutor.Response{}, errCtx
+				return cliproxyexecutor.Response{}, latencyBudgetError(ctx, errCtx)
 			}
 			result.Error = &Error{Message: errExec.Error()}
 			var se cliproxyexecutor.StatusError
@@ -690,6 +798,9 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			}
 			result.QuotaReason = quotaReasonFromError(errExec)
 			m.MarkResult(execCtx, result)
+			if errAfter != nil {
+				return cliproxyexecutor.Response{}, errAfter
+			}
 			lastErr = errExec
 			if !shouldRotateAuthOnError(errExec) {
 				return cliproxyexecutor.Response{}, errExec
@@ -697,6 +808,9 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		if errAfter != nil {
+			return cliproxyexecutor.Response{}, errAfter
+		}
 		return resp, nil
 	}
 }
@@ -707,6 +821,9 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
+	middleware := m.executorMiddleware()
+	// The usage recorder lets stream latency samples compute output rates.
+	ctx = usage.WithDetailRecorder(ctx)
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
@@ -720,31 +837,39 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
+		traceAuthSelection(ctx, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+		budget := latencyBudgetFrom(ctx)
+		if budget.skipAttempt(auth, provider, routeModel) {
+			lastErr = budget.exceeded("no remaining auth can answer before the deadline")
+			continue
 		}
-		execReq := req
-		execReq.Model = rewriteModelForAuth(routeModel, auth)
-		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
-		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
-		if errStream != nil {
-			if errCtx := execCtx.Err(); errCtx != nil {
-				return nil, errCtx
+		started := time.Now()
+		attempt, errVeto := m.prepareStreamAttempt(ctx, auth, provider, routeModel, req, opts, middleware)
+		if errVeto != nil {
+			return nil, errVeto
+		}
+		finishAttempt := budget.beginAttempt(auth, provider)
+		if delay := m.hedgeDelay(provider); delay > 0 {
+			attempt = m.hedgeStream(ctx, attempt, executor, delay, providers, routeModel, req, opts, middleware, tried)
+		} else {
+			attempt.chunks, attempt.err = executor.ExecuteStream(attempt.ctx, auth, attempt.call.Request, attempt.call.Options)
+		}
+		if refreshed := m.refreshAfterUnauthorized(attempt.ctx, attempt.auth, attempt.err); refreshed != nil {
+			if retryExec := m.executorFor(attempt.provider); retryExec != nil {
+				attempt.auth, attempt.call.Auth = refreshed, refreshed
+				attempt.chunks, attempt.err = retryExec.ExecuteStream(attempt.ctx, refreshed, attempt.call.Request, attempt.call.Options)
 			}
-			rerr := &Error{Message: errStream.Error()}
-			var se cliproxyexecutor.StatusError
-			if errors.As(errStream, &se) && se != nil {
-				rerr.HTTPStatus = se.StatusCode()
+		}
+		finishAttempt(attempt.err)
+		if errStream := attempt.err; errStream != nil {
+			if errCtx := ctx.Err(); errCtx != nil {
+				attempt.cancel()
+				return nil, latencyBudgetError(ctx, errCtx)
 			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr}
-			result.RetryAfter = retryAfterFromError(errStream)
-			result.QuotaReason = quotaReasonFromError(errStream)
-			m.MarkResult(execCtx, result)
+			m.MarkResult(attempt.ctx, streamErrorResult(attempt.auth, attempt.provider, routeModel, errStream))
+			attempt.cancel()
 			lastErr = errStream
 			if !shouldRotateAuthOnError(errStream) {
 				return nil, errStream
@@ -752,26 +877,26 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			continue
 		}
 		out := make(chan cliproxyexecutor.StreamChunk)
-		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
+		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, call *ExecutionCall, streamChunks <-chan cliproxyexecutor.StreamChunk, release context.CancelFunc) {
+			defer release()
 			defer close(out)
 			var failed bool
+			var first time.Time
 			forward := true
 			for chunk := range streamChunks {
 				if chunk.Err != nil && !failed {
 					failed = true
-					rerr := &Error{Message: chunk.Err.Error()}
-					var se cliproxyexecutor.StatusError
-					if errors.As(chunk.Err, &se) && se != nil {
-						rerr.HTTPStatus = se.StatusCode()
-					}
-					result := Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr}
-					result.RetryAfter = retryAfterFromError(chunk.Err)
-					result.QuotaReason = quotaReasonFromError(chunk.Err)
-					m.MarkResult(streamCtx, result)
+					m.MarkResult(streamCtx, streamErrorResult(streamAuth, streamProvider, routeModel, chunk.Err))
+				}
+				if first.IsZero() && len(chunk.Payload) > 0 {
+					first = time.Now()
 				}
 				if !forward {
 					continue
 				}
+				for _, mw := range middleware {
+					mw.OnStreamChunk(streamCtx, call, &chunk)
+				}
 				if streamCtx == nil {
 					out <- chunk
 					continue
@@ -784,12 +909,46 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			}
 			if !failed {
 				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true})
+				recordStreamLatency(streamCtx, streamAuth, streamProvider, routeModel, started, first, time.Now())
 			}
-		}(execCtx, auth.Clone(), provider, chunks)
+		}(attempt.ctx, attempt.auth.Clone(), attempt.provider, attempt.call, attempt.chunks, attempt.cancel)
+		recordServedAuth(ctx, attempt.auth)
 		return out, nil
 	}
 }
 
+// prepareStreamAttempt builds the execution context and call for a streaming attempt on auth
+// and runs BeforeExecute middleware. A returned error is a middleware veto.
+func (m *Manager) prepareStreamAttempt(ctx context.Context, auth *Auth, provider, routeModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, middleware []ExecutorMiddleware) (*streamAttempt, error) {
+	execCtx := cliproxyexecutor.WithEndpoint(ctx, cliproxyexecutor.EndpointStream)
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	execReq := req
+	execReq.Model = rewriteModelForAuth(routeModel, auth)
+	execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
+	execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+	call := &ExecutionCall{Auth: auth, Provider: provider, Endpoint: cliproxyexecutor.EndpointStream, Request: execReq, Options: opts}
+	if errVeto := beforeExecute(execCtx, middleware, call); errVeto != nil {
+		return nil, errVeto
+	}
+	return &streamAttempt{ctx: execCtx, cancel: func() {}, auth: auth, provider: provider, call: call}, nil
+}
+
+// streamErrorResult builds the failed Result recorded for a streaming error on auth.
+func streamErrorResult(auth *Auth, provider, model string, err error) Result {
+	rerr := &Error{Message: err.Error()}
+	var se cliproxyexecutor.StatusError
+	if errors.As(err, &se) && se != nil {
+		rerr.HTTPStatus = se.StatusCode()
+	}
+	result := Result{AuthID: auth.ID, Provider: provider, Model: model, Success: false, Error: rerr}
+	result.RetryAfter = retryAfterFromError(err)
+	result.QuotaReason = quotaReasonFromError(err)
+	return result
+}
+
 func ensureRequestedModelMetadata(opts cliproxyexecutor.Options, requestedModel string) cliproxyexecutor.Options {
 	requestedModel = strings.TrimSpace(requestedModel)
 	if requestedModel == "" {
@@ -1147,6 +1306,10 @@ func (m *Manager) shouldRetryAfterError(err error, attempt int, providers []stri
 	if maxWait <= 0 {
 		return 0, false
 	}
+	var budgetErr *LatencyBudgetError
+	if errors.As(err, &budgetErr) {
+		return 0, false
+	}
 	if status := statusCodeFromError(err); status == http.StatusOK {
 		return 0, false
 	}
@@ -1179,18 +1342,21 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if result.AuthID == "" {
 		return
 	}
+	traceResult(ctx, result)
 
 	shouldResumeModel := false
 	shouldSuspendModel := false
 	suspendReason := ""
 	clearModelQuota := false
 	setModelQuota := false
+	var quotaExhausted, reloginRequired map[string]any
 
 	m.mu.Lock()
 	if auth, ok := m.auths[result.AuthID]; ok && auth != nil {
 		now := time.Now()
 
 		if result.Success {
+			m.clearReloginRequiredLocked(auth.ID)
 			if result.Model != "" {
 				state := ensureModelState(auth, result.Model)
 				resetModelState(state, now)
@@ -1207,6 +1373,12 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 				clearAuthStateOnSuccess(auth, now)
 			}
 		} else {
+			if statusCodeFromResult(result.Error) == 401 {
+				if statusCodeFromResult(auth.LastError) != 401 {
+					reloginRequired = reloginRequiredEvent(auth, result.Error.Message)
+				}
+				m.markReloginRequiredLocked(auth.ID, now)
+			}
 			if result.Model != "" {
 				state := ensureModelState(auth, result.Model)
 				state.Unavailable = true
@@ -1253,6 +1425,9 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 						backoffLevel = nextLevel
 					}
 					state.NextRetryAfter = next
+					if !state.Quota.Exceeded {
+						quotaExhausted = quotaExhaustedEvent(auth, result.Model, quotaReason, next)
+					}
 					state.Quota = QuotaState{
 						Exceeded:      true,
 						Reason:        quotaReason,
@@ -1277,7 +1452,11 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 				auth.UpdatedAt = now
 				updateAggregatedAvailability(auth, now)
 			} else {
+				wasExceeded := auth.Quota.Exceeded
 				applyAuthFailureState(auth, result.Error, result.RetryAfter, result.QuotaReason, now)
+				if auth.Quota.Exceeded && !wasExceeded {
+					quotaExhausted = quotaExhaustedEvent(auth, "", auth.Quota.Reason, auth.Quota.NextRecoverAt)
+				}
 			}
 		}
 
@@ -1296,10 +1475,57 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	} else if shouldSuspendModel {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
+	if quotaExhausted != nil {
+		webhook.Notify(webhook.EventQuotaExhausted, quotaExhausted)
+	}
+	if reloginRequired != nil {
+		webhook.Notify(webhook.EventAuthReloginRequired, reloginRequired)
+	}
 
 	m.hook.OnResult(ctx, result)
 }
 
+// quotaExhaustedEvent describes an auth, or one of its models, entering quota cooldown.
+func quotaExhaustedEvent(auth *Auth, model, reason string, recoverAt time.Time) map[string]any {
+	data := map[string]any{
+		"auth_id":  auth.ID,
+		"provider": auth.Provider,
+		"reason":   reason,
+	}
+	if model != "" {
+		data["model"] = model
+	}
+	if !recoverAt.IsZero() {
+		data["recover_at"] = recoverAt.UTC()
+	}
+	if strings.Contains(reason, "weekly") {
+		data["weekly"] = true
+	}
+	return data
+}
+
+// reloginRequiredEvent describes an auth whose credentials were rejected and must be renewed
+// by logging in again.
+func reloginRequiredEvent(auth *Auth, message string) map[string]any {
+	return map[string]any{
+		"auth_id":  auth.ID,
+		"provider": auth.Provider,
+		"label":    auth.Label,
+		"error":    message,
+	}
+}
+
+// refreshNeedsRelogin reports whether a refresh error means the refresh token itself was
+// rejected, so retrying cannot succeed without a new login.
+func refreshNeedsRelogin(err error) bool {
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "invalid_grant") || strings.Contains(msg, "refresh_token_reused")
+}
+
 // SyncQuotaProbe reconciles runtime quota cooldown state from an out-of-band quota probe.
 // When exceeded is false, quota-derived cooldown state is cleared from the auth and any
 // affected model states. When exceeded is true, the cooldown is applied to the auth and
@@ -1764,6 +1990,9 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	}
 	candidates := make([]*Auth, 0, len(m.auths))
 	allowedMatch := false
+	denied := false
+	route := RouteConstraintsFromContext(ctx)
+	tagFiltered := false
 	modelKey := strings.TrimSpace(model)
 	// Always use base model name (without thinking suffix) for auth matching.
 	if modelKey != "" {
@@ -1777,30 +2006,35 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if candidate.Provider != provider {
 			continue
 		}
-		if restricted {
-			if authMatchesAllowedRefs(candidate, allowedRefs) {
-				allowedMatch = true
-			} else {
-				continue
-			}
+		if !authPermitsClient(candidate, clientKey, allowedRefs, restricted) {
+			denied = true
+			continue
 		}
+		allowedMatch = true
 		if candidate.Disabled {
 			continue
 		}
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if route.AuthTag != "" && !authHasTag(candidate, route.AuthTag) {
+			tagFiltered = true
+			continue
+		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
-		if restricted && !allowedMatch {
+		if (restricted || denied) && !allowedMatch {
 			m.mu.RUnlock()
 			return nil, nil, &Error{Code: "access_denied", Message: "API key is not authorized for this provider", HTTPStatus: http.StatusForbidden}
 		}
 		m.mu.RUnlock()
+		if tagFiltered {
+			return nil, nil, &Error{Code: "auth_not_found", Message: "no auth tagged " + route.AuthTag + " available"}
+		}
 		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
 	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
@@ -1822,6 +2056,9 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		}
 		m.mu.Unlock()
 	}
+	if route.ProxyURL != "" {
+		authCopy.ProxyURL = route.ProxyURL
+	}
 	return authCopy, executor, nil
 }
 
@@ -1847,6 +2084,9 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 	}
 	candidates := make([]*Auth, 0, len(m.auths))
 	allowedMatch := false
+	denied := false
+	route := RouteConstraintsFromContext(ctx)
+	tagFiltered := false
 	modelKey := strings.TrimSpace(model)
 	// Always use base model name (without thinking suffix) for auth matching.
 	if modelKey != "" {
@@ -1867,19 +2107,21 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		if _, ok := providerSet[providerKey]; !ok {
 			continue
 		}
-		if restricted {
-			if authMatchesAllowedRefs(candidate, allowedRefs) {
-				allowedMatch = true
-			} else {
-				continue
-			}
+		if !authPermitsClient(candidate, clientKey, allowedRefs, restricted) {
+			denied = true
+			continue
 		}
+		allowedMatch = true
 		if candidate.Disabled {
 			continue
 		}
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if route.AuthTag != "" && !authHasTag(candidate, route.AuthTag) {
+			tagFiltered = true
+			continue
+		}
 		if _, ok := m.executors[providerKey]; !ok {
 			continue
 		}
@@ -1889,11 +2131,14 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
-		if restricted && !allowedMatch {
+		if (restricted || denied) && !allowedMatch {
 			m.mu.RUnlock()
 			return nil, nil, "", &Error{Code: "access_denied", Message: "API key is not authorized for this provider", HTTPStatus: http.StatusForbidden}
 		}
 		m.mu.RUnlock()
+		if tagFiltered {
+			return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth tagged " + route.AuthTag + " available"}
+		}
 		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
 	selected, errPick := m.selector.Pick(ctx, "mixed", model, opts, candidates)
@@ -1921,6 +2166,9 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		}
 		m.mu.Unlock()
 	}
+	if route.ProxyURL != "" {
+		authCopy.ProxyURL = route.ProxyURL
+	}
 	return authCopy, executor, providerKey, nil
 }
 
@@ -1984,6 +2232,9 @@ func (m *Manager) StopAutoRefresh() {
 
 func (m *Manager) checkRefreshes(ctx context.Context) {
 	// log.Debugf("checking refreshes")
+	if !m.IsLeader() {
+		return
+	}
 	now := time.Now()
 	snapshot := m.snapshotAuths()
 	for _, a := range snapshot {
@@ -2236,6 +2487,11 @@ func (m *Manager) markRefreshPending(id string, now time.Time) bool {
 }
 
 func (m *Manager) refreshAuth(ctx context.Context, id string) {
+	_, _ = m.refreshAuthNow(ctx, id)
+}
+
+// refreshAuthNow refreshes the credentials of auth id and returns the updated auth.
+func (m *Manager) refreshAuthNow(ctx context.Context, id string) (*Auth, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -2247,25 +2503,37 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	}
 	m.mu.RUnlock()
 	if auth == nil || exec == nil {
-		return
+		return nil, &Error{Code: "auth_not_found", Message: "auth or executor not registered"}
 	}
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
 	if err != nil && errors.Is(err, context.Canceled) {
 		log.Debugf("refresh canceled for %s, %s", auth.Provider, auth.ID)
-		return
+		return nil, err
 	}
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
 	if err != nil {
+		needsRelogin := refreshNeedsRelogin(err)
 		m.mu.Lock()
 		if current := m.auths[id]; current != nil {
 			current.NextRefreshAfter = now.Add(refreshFailureBackoff)
 			current.LastError = &Error{Message: err.Error()}
 			m.auths[id] = current
+			if needsRelogin {
+				m.markReloginRequiredLocked(id, now)
+			}
 		}
 		m.mu.Unlock()
-		return
+		webhook.Notify(webhook.EventAuthRefreshFailed, map[string]any{
+			"auth_id":  auth.ID,
+			"provider": auth.Provider,
+			"error":    err.Error(),
+		})
+		if needsRelogin {
+			webhook.Notify(webhook.EventAuthReloginRequired, reloginRequiredEvent(auth, err.Error()))
+		}
+		return nil, err
 	}
 	if updated == nil {
 		updated = cloned
@@ -2279,7 +2547,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	updated.NextRefreshAfter = time.Time{}
 	updated.LastError = nil
 	updated.UpdatedAt = now
-	_, _ = m.Update(ctx, updated)
+	return m.Update(ctx, updated)
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -2493,3 +2761,36 @@ func (m *Manager) HttpRequest(ctx context.Context, auth *Auth, req *http.Request
 	}
 	return exec.HttpRequest(ctx, auth, req)
 }
+
+// ExecuteWithAuth runs a non-streaming request on auth itself, bypassing selection, retries and
+// executor middleware. Model aliases and the auth's round tripper apply as for routed requests.
+// The outcome is not recorded in the auth's runtime state, so callers can use it to probe an
+// auth without cooling it down.
+func (m *Manager) ExecuteWithAuth(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if m == nil {
+		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "manager is nil"}
+	}
+	if auth == nil {
+		return cliproxyexecutor.Response{}, &Error{Code: "auth_not_found", Message: "auth is nil"}
+	}
+	providerKey := executorKeyFromAuth(auth)
+	exec := m.executorFor(providerKey)
+	if exec == nil {
+		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "executor not registered for provider: " + providerKey}
+	}
+	opts = ensureRequestedModelMetadata(opts, req.Model)
+	execCtx := cliproxyexecutor.WithEndpoint(ctx, cliproxyexecutor.EndpointExecute)
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	execReq := req
+	execReq.Model = rewriteModelForAuth(req.Model, auth)
+	execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
+	execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+	resp, err := exec.Execute(execCtx, auth, execReq, opts)
+	if refreshed := m.refreshAfterUnauthorized(execCtx, auth, err); refreshed != nil {
+		resp, err = exec.Execute(execCtx, refreshed, execReq, opts)
+	}
+	return resp, err
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/debugtrace"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// traceAuthSelection records the auth picked for an attempt in the request's debug trace.
+func traceAuthSelection(ctx context.Context, auth *Auth, provider, model string) {
+	if auth == nil || !debugtrace.Enabled(ctx) {
+		return
+	}
+	accountType, accountInfo := auth.AccountInfo()
+	if accountType == "api_key" {
+		accountInfo = util.HideAPIKey(accountInfo)
+	}
+	debugtrace.Record(ctx, "auth", "selected auth", map[string]any{
+		"auth_id":      auth.ID,
+		"label":        auth.Label,
+		"provider":     provider,
+		"model":        model,
+		"account_type": accountType,
+		"account":      accountInfo,
+		"proxy":        auth.ProxyInfo(),
+	})
+}
+
+// traceResult records the outcome of an attempt in the request's debug trace.
+func traceResult(ctx context.Context, result Result) {
+	if !debugtrace.Enabled(ctx) {
+		return
+	}
+	data := map[string]any{
+		"auth_id":  result.AuthID,
+		"provider": result.Provider,
+		"model":    result.Model,
+		"success":  result.Success,
+	}
+	if result.Error != nil {
+		data["error"] = result.Error.Message
+		if result.Error.HTTPStatus > 0 {
+			data["status"] = result.Error.HTTPStatus
+		}
+	}
+	if result.RetryAfter != nil {
+		data["retry_after_ms"] = result.RetryAfter.Milliseconds()
+	}
+	if result.QuotaReason != "" {
+		data["quota_reason"] = result.QuotaReason
+	}
+	debugtrace.Record(ctx, "attempt", "attempt finished", data)
+}
+
+// traceRetry records a retry of the whole request after every auth failed.
+func traceRetry(ctx context.Context, attempt int, wait time.Duration, err error) {
+	if !debugtrace.Enabled(ctx) {
+		return
+	}
+	data := map[string]any{"attempt": attempt + 1, "wait_ms": wait.Milliseconds()}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	debugtrace.Record(ctx, "retry", "retrying after cooldown", data)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+)
+
+// StatusMessageDuplicate prefixes the status message of auths disabled as duplicates.
+const StatusMessageDuplicate = "duplicate of "
+
+// DuplicateGroup lists file-backed auths that sign in to the same upstream account, so
+// requests spread across them draw from a single quota.
+type DuplicateGroup struct {
+	Provider string `json:"provider"`
+	// Account is the shared account identity (account ID or email).
+	Account string `json:"account"`
+	// AuthIDs lists the duplicate auths, sorted by ID.
+	AuthIDs []string `json:"auth_ids"`
+}
+
+// AccountIdentity returns the upstream account a file-backed auth signs in to, or "" when it
+// cannot be determined. Account IDs take precedence over emails; Gemini CLI identities include
+// the project, since each project is billed separately.
+func (a *Auth) AccountIdentity() string {
+	if a == nil || a.Metadata == nil || a.Attributes == nil {
+		return ""
+	}
+	if a.Attributes["path"] == "" || a.Attributes["runtime_only"] == "true" || a.Attributes["gemini_virtual_primary"] == "true" {
+		return ""
+	}
+	identity := ""
+	for _, key := range []string{"account_id", "chatgpt_account_id", "email"} {
+		if v, ok := a.Metadata[key].(string); ok && strings.TrimSpace(v) != "" {
+			identity = strings.ToLower(strings.TrimSpace(v))
+			break
+		}
+	}
+	if identity == "" {
+		return ""
+	}
+	if strings.EqualFold(a.Provider, "gemini-cli") {
+		if project, ok := a.Metadata["project_id"].(string); ok && strings.TrimSpace(project) != "" {
+			identity += " (" + strings.TrimSpace(project) + ")"
+		}
+	}
+	return identity
+}
+
+// FindDuplicates groups auths sharing a provider and account identity.
+func FindDuplicates(auths []*Auth) []DuplicateGroup {
+	type groupKey struct{ provider, account string }
+	groups := make(map[groupKey][]string)
+	for _, a := range auths {
+		account := a.AccountIdentity()
+		if account == "" {
+			continue
+		}
+		key := groupKey{strings.ToLower(a.Provider), account}
+		groups[key] = append(groups[key], a.ID)
+	}
+	out := make([]DuplicateGroup, 0)
+	for key, ids := range groups {
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		out = append(out, DuplicateGroup{Provider: key.provider, Account: key.account, AuthIDs: ids})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Account < out[j].Account
+	})
+	return out
+}
+
+// DuplicateGroups reports the registered auths that share an upstream account.
+func (m *Manager) DuplicateGroups() []DuplicateGroup {
+	return FindDuplicates(m.List())
+}
+
+// ActiveDuplicateOf returns the ID of an enabled auth, other than auth itself, that signs in
+// to the same upstream account as auth.
+func (m *Manager) ActiveDuplicateOf(auth *Auth) (string, bool) {
+	account := auth.AccountIdentity()
+	if m == nil || account == "" {
+		return "", false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, 1)
+	for id, other := range m.auths {
+		if id == auth.ID || other.Disabled || !strings.EqualFold(other.Provider, auth.Provider) {
+			continue
+		}
+		if other.AccountIdentity() == account {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return "", false
+	}
+	sort.Strings(ids)
+	return ids[0], true
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func fileAuth(id, provider string, metadata map[string]any) *Auth {
+	return &Auth{
+		ID:         id,
+		Provider:   provider,
+		Status:     StatusActive,
+		Attributes: map[string]string{"path": "/auths/" + id},
+		Metadata:   metadata,
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	auths := []*Auth{
+		fileAuth("codex-b.json", "codex", map[string]any{"email": "a@example.com", "account_id": "acct-1"}),
+		fileAuth("codex-a.json", "codex", map[string]any{"email": "other@example.com", "account_id": "acct-1"}),
+		fileAuth("claude-a.json", "claude", map[string]any{"email": "A@example.com"}),
+		fileAuth("claude-b.json", "claude", map[string]any{"email": "a@example.com "}),
+		fileAuth("gemini-a.json", "gemini-cli", map[string]any{"email": "a@example.com", "project_id": "p1"}),
+		fileAuth("gemini-b.json", "gemini-cli", map[string]any{"email": "a@example.com", "project_id": "p2"}),
+		{ID: "claude-runtime", Provider: "claude", Attributes: map[string]string{"runtime_only": "true", "path": "x"}, Metadata: map[string]any{"email": "a@example.com"}},
+	}
+
+	groups := FindDuplicates(auths)
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	if groups[0].Provider != "claude" || len(groups[0].AuthIDs) != 2 || groups[0].AuthIDs[0] != "claude-a.json" {
+		t.Fatalf("claude group = %+v", groups[0])
+	}
+	if groups[1].Provider != "codex" || groups[1].Account != "acct-1" || groups[1].AuthIDs[0] != "codex-a.json" {
+		t.Fatalf("codex group = %+v", groups[1])
+	}
+}
+
+func TestActiveDuplicateOf(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	ctx := context.Background()
+	first := fileAuth("claude-a.json", "claude", map[string]any{"email": "a@example.com"})
+	if _, err := m.Register(ctx, first); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	second := fileAuth("claude-b.json", "claude", map[string]any{"email": "a@example.com"})
+
+	if id, ok := m.ActiveDuplicateOf(second); !ok || id != "claude-a.json" {
+		t.Fatalf("duplicate = %q, %v", id, ok)
+	}
+	if _, ok := m.ActiveDuplicateOf(first); ok {
+		t.Fatal("auth reported as its own duplicate")
+	}
+
+	first.Disabled = true
+	if _, err := m.Update(ctx, first); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, ok := m.ActiveDuplicateOf(second); ok {
+		t.Fatal("disabled auth should not count as the active original")
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// defaultHedgeDelay applies when hedging is enabled without an explicit delay.
+const defaultHedgeDelay = 2 * time.Second
+
+// streamAttempt is one ExecuteStream call on a single auth.
+type streamAttempt struct {
+	ctx context.Context
+	// runCtx is the context a hedged attempt streams under. cancel cancels it and leaves ctx,
+	// which results are recorded with, intact.
+	runCtx   context.Context
+	cancel   context.CancelFunc
+	auth     *Auth
+	provider string
+	call     *ExecutionCall
+	chunks   <-chan cliproxyexecutor.StreamChunk
+	err      error
+}
+
+// hedgeDelay returns how long a streaming request on provider may go without a first chunk
+// before a duplicate is sent on another auth, or 0 when hedging does not apply.
+func (m *Manager) hedgeDelay(provider string) time.Duration {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.Hedging.Enabled {
+		return 0
+	}
+	if len(cfg.Hedging.Providers) > 0 {
+		key := strings.ToLower(strings.TrimSpace(provider))
+		matched := false
+		for _, p := range cfg.Hedging.Providers {
+			if p == key {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0
+		}
+	}
+	if cfg.Hedging.DelayMs <= 0 {
+		return defaultHedgeDelay
+	}
+	return time.Duration(cfg.Hedging.DelayMs) * time.Millisecond
+}
+
+// hedgeStream runs primary and, when it has produced no chunk after delay, starts a duplicate
+// attempt on the next available auth. The attempt whose first chunk arrives first is returned
+// and the other one is canceled; the winner's context is canceled once its stream drains. When
+// both fail, the first error is returned and the other failure is recorded against its auth.
+func (m *Manager) hedgeStream(ctx context.Context, primary *streamAttempt, executor ProviderExecutor, delay time.Duration, providers []string, routeModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, middleware []ExecutorMiddleware, tried map[string]struct{}) *streamAttempt {
+	results := make(chan *streamAttempt, 2)
+	primary.runCtx, primary.cancel = context.WithCancel(primary.ctx)
+	go primary.start(executor, results)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case winner := <-results:
+		return winner
+	case <-timer.C:
+	}
+
+	auth, hedgeExecutor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+	if errPick != nil {
+		return <-results
+	}
+	tried[auth.ID] = struct{}{}
+	hedge, errVeto := m.prepareStreamAttempt(ctx, auth, provider, routeModel, req, opts, middleware)
+	if errVeto != nil {
+		return <-results
+	}
+	logEntryWithRequestID(ctx).Debugf("hedging stream request for model %s: no first chunk from auth %s after %s, trying auth %s", routeModel, primary.auth.ID, delay, auth.ID)
+	hedge.runCtx, hedge.cancel = context.WithCancel(hedge.ctx)
+	go hedge.start(hedgeExecutor, results)
+
+	winner := <-results
+	if winner.err != nil {
+		other := <-results
+		if other.err != nil {
+			m.MarkResult(other.ctx, streamErrorResult(other.auth, other.provider, routeModel, other.err))
+			other.cancel()
+			return winner
+		}
+		m.MarkResult(winner.ctx, streamErrorResult(winner.auth, winner.provider, routeModel, winner.err))
+		winner.cancel()
+		return other
+	}
+	loser := primary
+	if winner == primary {
+		loser = hedge
+	}
+	loser.cancel()
+	go func() {
+		<-results
+		drainStream(loser.chunks)
+	}()
+	return winner
+}
+
+// start calls ExecuteStream and waits for the first chunk before reporting the attempt on results.
+// An error in the first chunk is reported as the attempt's error. A successful attempt cancels
+// its run context once the upstream stream is drained.
+func (a *streamAttempt) start(executor ProviderExecutor, results chan<- *streamAttempt) {
+	defer func() { results <- a }()
+	chunks, err := executor.ExecuteStream(a.runCtx, a.auth, a.call.Request, a.call.Options)
+	if err != nil {
+		a.err = err
+		return
+	}
+	first, ok := <-chunks
+	if ok && first.Err != nil {
+		a.err = first.Err
+		go drainStream(chunks)
+		return
+	}
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer a.cancel()
+		defer close(out)
+		if !ok {
+			return
+		}
+		out <- first
+		for chunk := range chunks {
+			out <- chunk
+		}
+	}()
+	a.chunks = out
+}
+
+func drainStream(chunks <-chan cliproxyexecutor.StreamChunk) {
+	if chunks == nil {
+		return
+	}
+	for range chunks {
+	}
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// hedgeTestExecutor streams immediately for fast auths and stalls slow auths until canceled.
+type hedgeTestExecutor struct {
+	mu       sync.Mutex
+	slow     map[string]bool
+	calls    []string
+	ctxs     map[string]context.Context
+	canceled chan string
+}
+
+func (e *hedgeTestExecutor) Identifier() string { return "hedge" }
+
+func (e *hedgeTestExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *hedgeTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, auth.ID)
+	if e.ctxs == nil {
+		e.ctxs = make(map[string]context.Context)
+	}
+	e.ctxs[auth.ID] = ctx
+	slow := e.slow[auth.ID]
+	e.mu.Unlock()
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		if slow {
+			<-ctx.Done()
+			e.canceled <- auth.ID
+			out <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+			return
+		}
+		out <- cliproxyexecutor.StreamChunk{Payload: []byte(auth.ID)}
+		out <- cliproxyexecutor.StreamChunk{Payload: []byte("done")}
+	}()
+	return out, nil
+}
+
+func (e *hedgeTestExecutor) Refresh(context.Context, *Auth) (*Auth, error) { return nil, nil }
+
+func (e *hedgeTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *hedgeTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func newHedgeTestManager(t *testing.T, slow map[string]bool) (*Manager, *hedgeTestExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	manager.SetConfig(&internalconfig.Config{Hedging: internalconfig.HedgingConfig{Enabled: true, DelayMs: 20}})
+	exec := &hedgeTestExecutor{slow: slow, canceled: make(chan string, 2)}
+	manager.RegisterExecutor(exec)
+	for _, id := range []string{"a-primary", "b-hedge"} {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "hedge", Status: StatusActive}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+	}
+	return manager, exec
+}
+
+func collectStream(t *testing.T, chunks <-chan cliproxyexecutor.StreamChunk) []string {
+	t.Helper()
+	var got []string
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Err)
+		}
+		got = append(got, string(chunk.Payload))
+	}
+	return got
+}
+
+func TestExecuteStream_HedgeWinsWhenPrimaryStalls(t *testing.T) {
+	manager, exec := newHedgeTestManager(t, map[string]bool{"a-primary": true})
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"hedge"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if got := collectStream(t, chunks); len(got) != 2 || got[0] != "b-hedge" || got[1] != "done" {
+		t.Fatalf("chunks = %q, want hedge stream", got)
+	}
+	select {
+	case id := <-exec.canceled:
+		if id != "a-primary" {
+			t.Fatalf("canceled %s, want a-primary", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("stalled primary was not canceled")
+	}
+	if primary, ok := manager.GetByID("a-primary"); !ok || primary.Unavailable {
+		t.Fatalf("canceled primary should not be marked as failed: %+v", primary)
+	}
+}
+
+func TestHedgeStream_CancelsWinnerAfterDrain(t *testing.T) {
+	manager, exec := newHedgeTestManager(t, map[string]bool{"a-primary": true})
+	primaryAuth, _ := manager.GetByID("a-primary")
+	ctx := context.Background()
+	primary, err := manager.prepareStreamAttempt(ctx, primaryAuth, "hedge", "", cliproxyexecutor.Request{}, cliproxyexecutor.Options{}, nil)
+	if err != nil {
+		t.Fatalf("prepareStreamAttempt() error = %v", err)
+	}
+	tried := map[string]struct{}{"a-primary": {}}
+	winner := manager.hedgeStream(ctx, primary, exec, 20*time.Millisecond, []string{"hedge"}, "", cliproxyexecutor.Request{}, cliproxyexecutor.Options{}, nil, tried)
+	if winner.err != nil || winner.auth.ID != "b-hedge" {
+		t.Fatalf("winner = %s, err = %v, want b-hedge", winner.auth.ID, winner.err)
+	}
+	exec.mu.Lock()
+	runCtx := exec.ctxs["b-hedge"]
+	exec.mu.Unlock()
+	if runCtx.Err() != nil {
+		t.Fatalf("winner canceled before its stream drained")
+	}
+	drainStream(winner.chunks)
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("winner context was not canceled after its stream drained")
+	}
+	if winner.ctx.Err() != nil {
+		t.Fatalf("the context results are recorded with must stay usable")
+	}
+}
+
+func TestExecuteStream_NoHedgeWhenPrimaryIsFast(t *testing.T) {
+	manager, exec := newHedgeTestManager(t, nil)
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"hedge"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if got := collectStream(t, chunks); len(got) != 2 || got[0] != "a-primary" {
+		t.Fatalf("chunks = %q, want primary stream", got)
+	}
+	time.Sleep(40 * time.Millisecond)
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if len(exec.calls) != 1 {
+		t.Fatalf("executor calls = %v, want only the primary", exec.calls)
+	}
+}
+
+func TestHedgeDelayHonoursProviderFilter(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetConfig(&internalconfig.Config{Hedging: internalconfig.HedgingConfig{Enabled: true, Providers: []string{"codex"}}})
+	if got := manager.hedgeDelay("codex"); got != defaultHedgeDelay {
+		t.Fatalf("hedgeDelay(codex) = %s, want default", got)
+	}
+	if got := manager.hedgeDelay("claude"); got != 0 {
+		t.Fatalf("hedgeDelay(claude) = %s, want disabled", got)
+	}
+}
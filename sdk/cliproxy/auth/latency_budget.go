@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	internalusage "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+)
+
+type latencyBudgetContextKey struct{}
+
+// BudgetAttempt is the timing of one upstream attempt made under a latency budget.
+type BudgetAttempt struct {
+	AuthIndex string `json:"auth_index"`
+	Provider  string `json:"provider"`
+	// StartedMs is the offset of the attempt from the start of the budget.
+	StartedMs  int64  `json:"started_ms"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// Skipped reports an attempt that was not made because it could not finish in time.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// latencyBudget tracks the upstream attempts of a request that has a deadline.
+type latencyBudget struct {
+	start    time.Time
+	deadline time.Time
+
+	mu       sync.Mutex
+	attempts []BudgetAttempt
+	waited   time.Duration
+}
+
+// LatencyBudgetError reports a request whose deadline passed, or could not be met, before an
+// upstream answered. It carries the timing of every attempt made under the budget.
+type LatencyBudgetError struct {
+	Reason    string
+	BudgetMs  int64
+	ElapsedMs int64
+	// WaitedMs is the time spent waiting for auth cooldowns between retries.
+	WaitedMs int64
+	Attempts []BudgetAttempt
+}
+
+func (e *LatencyBudgetError) Error() string {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf("The request could not be completed within its latency budget of %dms: %s.", e.BudgetMs, e.Reason),
+			"type":    "timeout_error",
+			"code":    "latency_budget_exceeded",
+			"timing": map[string]any{
+				"budget_ms":  e.BudgetMs,
+				"elapsed_ms": e.ElapsedMs,
+				"waited_ms":  e.WaitedMs,
+				"attempts":   e.Attempts,
+			},
+		},
+	})
+	return string(body)
+}
+
+func (e *LatencyBudgetError) StatusCode() int { return http.StatusGatewayTimeout }
+
+func (e *LatencyBudgetError) ProxyGenerated() bool { return true }
+
+// WithLatencyBudget returns a context that expires after timeout and records the upstream
+// attempts made before then, so a request that runs out of time fails with a
+// LatencyBudgetError instead of a bare context error.
+func WithLatencyBudget(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(ctx, start.Add(timeout))
+	return withLatencyBudgetFrom(ctx, start), cancel
+}
+
+// trackLatencyBudget installs an attempt tracker when ctx has a deadline and none is installed
+// yet, so deadlines set by SDK callers are enforced like the X-CLIProxy-Timeout header.
+func trackLatencyBudget(ctx context.Context) context.Context {
+	if ctx == nil || latencyBudgetFrom(ctx) != nil {
+		return ctx
+	}
+	return withLatencyBudgetFrom(ctx, time.Now())
+}
+
+func withLatencyBudgetFrom(ctx context.Context, start time.Time) context.Context {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, latencyBudgetContextKey{}, &latencyBudget{start: start, deadline: deadline})
+}
+
+func latencyBudgetFrom(ctx context.Context) *latencyBudget {
+	if ctx == nil {
+		return nil
+	}
+	budget, _ := ctx.Value(latencyBudgetContextKey{}).(*latencyBudget)
+	return budget
+}
+
+// beginAttempt records an attempt on auth starting now; the returned function completes it
+// with the attempt's error.
+func (b *latencyBudget) beginAttempt(auth *Auth, provider string) func(error) {
+	if b == nil {
+		return func(error) {}
+	}
+	started := time.Now()
+	return func(err error) {
+		attempt := BudgetAttempt{
+			AuthIndex:  auth.EnsureIndex(),
+			Provider:   provider,
+			StartedMs:  started.Sub(b.start).Milliseconds(),
+			DurationMs: time.Since(started).Milliseconds(),
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		b.mu.Lock()
+		b.attempts = append(b.attempts, attempt)
+		b.mu.Unlock()
+	}
+}
+
+// exceeded builds the error returned when the budget cannot be met for reason.
+func (b *latencyBudget) exceeded(reason string) *LatencyBudgetError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &LatencyBudgetError{
+		Reason:    reason,
+		BudgetMs:  b.deadline.Sub(b.start).Milliseconds(),
+		ElapsedMs: time.Since(b.start).Milliseconds(),
+		WaitedMs:  b.waited.Milliseconds(),
+		Attempts:  append([]BudgetAttempt(nil), b.attempts...),
+	}
+}
+
+// skipAttempt reports whether an attempt on auth cannot finish in the time left, recording it
+// as skipped. The fastest time to first token seen on the auth and model is the lower bound
+// of how long the attempt takes; auths without samples are always tried.
+func (b *latencyBudget) skipAttempt(auth *Auth, provider, model string) bool {
+	if b == nil || auth == nil {
+		return false
+	}
+	fastest, ok := internalusage.FastestStreamTTFT(auth.EnsureIndex(), provider, model)
+	remaining := time.Until(b.deadline)
+	if !ok || fastest < remaining {
+		return false
+	}
+	b.mu.Lock()
+	b.attempts = append(b.attempts, BudgetAttempt{
+		AuthIndex: auth.EnsureIndex(),
+		Provider:  provider,
+		StartedMs: time.Since(b.start).Milliseconds(),
+		Error:     fmt.Sprintf("fastest observed response takes %dms but only %dms remain", fastest.Milliseconds(), remaining.Milliseconds()),
+		Skipped:   true,
+	})
+	b.mu.Unlock()
+	return true
+}
+
+// allowWait reports whether a cooldown wait still leaves time for another attempt, adding it
+// to the waited time when it does.
+func (b *latencyBudget) allowWait(wait time.Duration) bool {
+	if b == nil {
+		return true
+	}
+	if wait >= time.Until(b.deadline) {
+		return false
+	}
+	b.mu.Lock()
+	b.waited += wait
+	b.mu.Unlock()
+	return true
+}
+
+// latencyBudgetError turns a deadline error of a budgeted request into a LatencyBudgetError.
+// Other errors are returned unchanged.
+func latencyBudgetError(ctx context.Context, err error) error {
+	budget := latencyBudgetFrom(ctx)
+	if budget == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return budget.exceeded("the deadline passed while waiting for the upstream")
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalusage "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// budgetTestExecutor answers immediately for fast auths and blocks slow auths until canceled.
+type budgetTestExecutor struct {
+	mu    sync.Mutex
+	slow  map[string]bool
+	calls []string
+}
+
+func (e *budgetTestExecutor) Identifier() string { return "budget" }
+
+func (e *budgetTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, auth.ID)
+	slow := e.slow[auth.ID]
+	e.mu.Unlock()
+	if slow {
+		<-ctx.Done()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *budgetTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *budgetTestExecutor) Refresh(context.Context, *Auth) (*Auth, error) { return nil, nil }
+
+func (e *budgetTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *budgetTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func newBudgetTestManager(t *testing.T, slow map[string]bool, ttft map[string]time.Duration) (*Manager, *budgetTestExecutor) {
+	t.Helper()
+	internalusage.SetStatisticsEnabled(true)
+	t.Cleanup(func() { internalusage.SetStatisticsEnabled(false) })
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	exec := &budgetTestExecutor{slow: slow}
+	manager.RegisterExecutor(exec)
+	for _, id := range []string{"a-first", "b-second"} {
+		// File names make the auth indexes unique per test, since latency samples are kept globally.
+		auth := &Auth{ID: id, FileName: t.Name() + "-" + id, Provider: "budget", Status: StatusActive}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		if sample, ok := ttft[id]; ok {
+			internalusage.RecordStreamLatency(internalusage.StreamLatencySample{AuthIndex: auth.EnsureIndex(), Provider: "budget", TTFT: sample})
+		}
+	}
+	return manager, exec
+}
+
+func TestExecute_LatencyBudgetSkipsAuthsThatCannotFinish(t *testing.T) {
+	manager, exec := newBudgetTestManager(t, nil, map[string]time.Duration{"a-first": time.Minute, "b-second": time.Millisecond})
+	ctx, cancel := WithLatencyBudget(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := manager.Execute(ctx, []string{"budget"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "b-second" {
+		t.Fatalf("payload = %q, want b-second", resp.Payload)
+	}
+	if len(exec.calls) != 1 {
+		t.Fatalf("calls = %v, want only b-second", exec.calls)
+	}
+}
+
+func TestExecute_LatencyBudgetFailsFastWhenNoAuthCanFinish(t *testing.T) {
+	manager, exec := newBudgetTestManager(t, nil, map[string]time.Duration{"a-first": time.Minute, "b-second": time.Minute})
+	ctx, cancel := WithLatencyBudget(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := manager.Execute(ctx, []string{"budget"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var budgetErr *LatencyBudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Execute() error = %v, want LatencyBudgetError", err)
+	}
+	if budgetErr.StatusCode() != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", budgetErr.StatusCode())
+	}
+	if len(exec.calls) != 0 {
+		t.Fatalf("calls = %v, want none", exec.calls)
+	}
+	if len(budgetErr.Attempts) != 2 || !budgetErr.Attempts[0].Skipped || !budgetErr.Attempts[1].Skipped {
+		t.Fatalf("attempts = %+v, want two skipped", budgetErr.Attempts)
+	}
+}
+
+func TestExecute_LatencyBudgetDeadlineReportsTiming(t *testing.T) {
+	manager, _ := newBudgetTestManager(t, map[string]bool{"a-first": true}, nil)
+	ctx, cancel := WithLatencyBudget(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := manager.Execute(ctx, []string{"budget"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var budgetErr *LatencyBudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Execute() error = %v, want LatencyBudgetError", err)
+	}
+	if budgetErr.BudgetMs != 50 || budgetErr.ElapsedMs < 50 {
+		t.Fatalf("timing = %dms of %dms, want the 50ms budget spent", budgetErr.ElapsedMs, budgetErr.BudgetMs)
+	}
+	if len(budgetErr.Attempts) != 1 || budgetErr.Attempts[0].Error == "" || budgetErr.Attempts[0].Skipped {
+		t.Fatalf("attempts = %+v, want one failed attempt", budgetErr.Attempts)
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ExecutionCall describes one upstream attempt as seen by executor middleware.
+type ExecutionCall struct {
+	// Auth is the credential selected for this attempt.
+	Auth *Auth
+	// Provider is the provider key of the executor handling the attempt.
+	Provider string
+	// Endpoint identifies the kind of call (execute, stream, count-tokens).
+	Endpoint cliproxyexecutor.Endpoint
+	// Request is the payload passed to the executor. BeforeExecute may modify it.
+	Request cliproxyexecutor.Request
+	// Options carries execution flags passed to the executor. BeforeExecute may modify it.
+	Options cliproxyexecutor.Options
+}
+
+// ExecutorMiddleware wraps every executor call made by the manager, so policies such as
+// payload rewriting, auditing or request vetoes can be implemented once for all providers.
+// Middleware runs in registration order for each attempt, including retries on other auths.
+type ExecutorMiddleware interface {
+	// BeforeExecute runs before the executor is invoked and may modify call.Request and call.Options.
+	// Returning an error vetoes the request: the error is returned to the caller and no
+	// further auths are tried.
+	BeforeExecute(ctx context.Context, call *ExecutionCall) error
+	// AfterExecute runs after a non-streaming or count-tokens attempt with the executor's result.
+	// It may modify resp. Returning an error stops execution and returns that error to the caller;
+	// the attempt's own outcome is still recorded against the auth.
+	AfterExecute(ctx context.Context, call *ExecutionCall, resp *cliproxyexecutor.Response, err error) error
+	// OnStreamChunk runs for every chunk of a streaming attempt before it is forwarded and may modify it.
+	OnStreamChunk(ctx context.Context, call *ExecutionCall, chunk *cliproxyexecutor.StreamChunk)
+}
+
+// ExecutorMiddlewareFunc adapts optional callbacks to ExecutorMiddleware.
+type ExecutorMiddlewareFunc struct {
+	Before func(context.Context, *ExecutionCall) error
+	After  func(context.Context, *ExecutionCall, *cliproxyexecutor.Response, error) error
+	Stream func(context.Context, *ExecutionCall, *cliproxyexecutor.StreamChunk)
+}
+
+// BeforeExecute implements ExecutorMiddleware.
+func (f ExecutorMiddlewareFunc) BeforeExecute(ctx context.Context, call *ExecutionCall) error {
+	if f.Before != nil {
+		return f.Before(ctx, call)
+	}
+	return nil
+}
+
+// AfterExecute implements ExecutorMiddleware.
+func (f ExecutorMiddlewareFunc) AfterExecute(ctx context.Context, call *ExecutionCall, resp *cliproxyexecutor.Response, err error) error {
+	if f.After != nil {
+		return f.After(ctx, call, resp, err)
+	}
+	return nil
+}
+
+// OnStreamChunk implements ExecutorMiddleware.
+func (f ExecutorMiddlewareFunc) OnStreamChunk(ctx context.Context, call *ExecutionCall, chunk *cliproxyexecutor.StreamChunk) {
+	if f.Stream != nil {
+		f.Stream(ctx, call, chunk)
+	}
+}
+
+// UseExecutorMiddleware appends middleware wrapped around every executor call.
+func (m *Manager) UseExecutorMiddleware(middleware ...ExecutorMiddleware) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mw := range middleware {
+		if mw != nil {
+			m.middleware = append(m.middleware, mw)
+		}
+	}
+}
+
+func (m *Manager) executorMiddleware() []ExecutorMiddleware {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.middleware
+}
+
+// beforeExecute runs BeforeExecute of every middleware and stops at the first veto.
+func beforeExecute(ctx context.Context, middleware []ExecutorMiddleware, call *ExecutionCall) error {
+	for _, mw := range middleware {
+		if err := mw.BeforeExecute(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterExecute runs AfterExecute of every middleware and returns the first error.
+func afterExecute(ctx context.Context, middleware []ExecutorMiddleware, call *ExecutionCall, resp *cliproxyexecutor.Response, errExec error) error {
+	for _, mw := range middleware {
+		if err := mw.AfterExecute(ctx, call, resp, errExec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
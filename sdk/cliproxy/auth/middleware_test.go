@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type echoPayloadExecutor struct {
+	calls int
+}
+
+func (e *echoPayloadExecutor) Identifier() string { return "echo" }
+
+func (e *echoPayloadExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.calls++
+	return cliproxyexecutor.Response{Payload: req.Payload}, nil
+}
+
+func (e *echoPayloadExecutor) ExecuteStream(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.calls++
+	out := make(chan cliproxyexecutor.StreamChunk, 2)
+	out <- cliproxyexecutor.StreamChunk{Payload: req.Payload}
+	out <- cliproxyexecutor.StreamChunk{Payload: []byte("tail")}
+	close(out)
+	return out, nil
+}
+
+func (e *echoPayloadExecutor) Refresh(context.Context, *Auth) (*Auth, error) { return nil, nil }
+
+func (e *echoPayloadExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *echoPayloadExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func newMiddlewareTestManager(t *testing.T) (*Manager, *echoPayloadExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	exec := &echoPayloadExecutor{}
+	manager.RegisterExecutor(exec)
+	if _, err := manager.Register(context.Background(), &Auth{ID: "echo-1", Provider: "echo", Status: StatusActive}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	return manager, exec
+}
+
+func TestExecutorMiddleware_MutatesRequestAndResponse(t *testing.T) {
+	manager, _ := newMiddlewareTestManager(t)
+	var seen *ExecutionCall
+	manager.UseExecutorMiddleware(ExecutorMiddlewareFunc{
+		Before: func(_ context.Context, call *ExecutionCall) error {
+			call.Request.Payload = []byte("rewritten")
+			return nil
+		},
+		After: func(_ context.Context, call *ExecutionCall, resp *cliproxyexecutor.Response, err error) error {
+			seen = call
+			resp.Payload = append(resp.Payload, []byte("+inspected")...)
+			return err
+		},
+	})
+
+	resp, err := manager.Execute(context.Background(), []string{"echo"}, cliproxyexecutor.Request{Payload: []byte("original")}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "rewritten+inspected" {
+		t.Fatalf("payload = %q", resp.Payload)
+	}
+	if seen == nil || seen.Auth.ID != "echo-1" || seen.Provider != "echo" || seen.Endpoint != cliproxyexecutor.EndpointExecute {
+		t.Fatalf("unexpected call: %+v", seen)
+	}
+}
+
+func TestExecutorMiddleware_VetoSkipsExecutor(t *testing.T) {
+	manager, exec := newMiddlewareTestManager(t)
+	errBlocked := errors.New("blocked by policy")
+	manager.UseExecutorMiddleware(ExecutorMiddlewareFunc{
+		Before: func(context.Context, *ExecutionCall) error { return errBlocked },
+	})
+
+	_, err := manager.Execute(context.Background(), []string{"echo"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if !errors.Is(err, errBlocked) {
+		t.Fatalf("Execute() error = %v, want veto", err)
+	}
+	if _, err = manager.ExecuteStream(context.Background(), []string{"echo"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); !errors.Is(err, errBlocked) {
+		t.Fatalf("ExecuteStream() error = %v, want veto", err)
+	}
+	if exec.calls != 0 {
+		t.Fatalf("executor called %d times after veto", exec.calls)
+	}
+}
+
+func TestExecutorMiddleware_InspectsStreamChunks(t *testing.T) {
+	manager, _ := newMiddlewareTestManager(t)
+	manager.UseExecutorMiddleware(ExecutorMiddlewareFunc{
+		Stream: func(_ context.Context, call *ExecutionCall, chunk *cliproxyexecutor.StreamChunk) {
+			if call.Endpoint == cliproxyexecutor.EndpointStream {
+				chunk.Payload = append([]byte("mw:"), chunk.Payload...)
+			}
+		},
+	})
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"echo"}, cliproxyexecutor.Request{Payload: []byte("head")}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var got []string
+	for chunk := range chunks {
+		got = append(got, string(chunk.Payload))
+	}
+	if len(got) != 2 || got[0] != "mw:head" || got[1] != "mw:tail" {
+		t.Fatalf("chunks = %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"sort"
+	"time"
+)
+
+// ReloginState describes an auth whose credentials were rejected and must be renewed by
+// logging in again.
+type ReloginState struct {
+	AuthID   string    `json:"auth_id"`
+	Provider string    `json:"provider"`
+	Label    string    `json:"label,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// markReloginRequiredLocked records that auth id needs a new login. The caller holds m.mu.
+func (m *Manager) markReloginRequiredLocked(id string, now time.Time) {
+	if m.reloginSince == nil {
+		m.reloginSince = make(map[string]time.Time)
+	}
+	if _, ok := m.reloginSince[id]; !ok {
+		m.reloginSince[id] = now
+	}
+}
+
+// clearReloginRequiredLocked records that auth id works again. The caller holds m.mu.
+func (m *Manager) clearReloginRequiredLocked(id string) {
+	delete(m.reloginSince, id)
+}
+
+// ReloginRequired lists the enabled auths of provider that need a new login, oldest first.
+// An empty provider lists them for every provider.
+func (m *Manager) ReloginRequired(provider string) []ReloginState {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make([]ReloginState, 0, len(m.reloginSince))
+	for id, since := range m.reloginSince {
+		auth := m.auths[id]
+		if auth == nil || auth.Disabled || auth.Status == StatusDisabled {
+			continue
+		}
+		if provider != "" && auth.Provider != provider {
+			continue
+		}
+		states = append(states, ReloginState{AuthID: id, Provider: auth.Provider, Label: auth.Label, Since: since})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if !states[i].Since.Equal(states[j].Since) {
+			return states[i].Since.Before(states[j].Since)
+		}
+		return states[i].AuthID < states[j].AuthID
+	})
+	return states
+}
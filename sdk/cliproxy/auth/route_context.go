@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+type routeContextKey struct{}
+
+// RouteConstraints narrow how a single request is served. Empty fields leave routing unchanged.
+type RouteConstraints struct {
+	// AuthTag limits selection to auths carrying this tag.
+	AuthTag string
+	// ProxyURL overrides the proxy of the selected auth.
+	ProxyURL string
+}
+
+// WithRouteConstraints stores route constraints in the context.
+func WithRouteConstraints(ctx context.Context, route RouteConstraints) context.Context {
+	if ctx == nil || route == (RouteConstraints{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, routeContextKey{}, route)
+}
+
+// RouteConstraintsFromContext returns the route constraints stored in the context.
+func RouteConstraintsFromContext(ctx context.Context) RouteConstraints {
+	if ctx == nil {
+		return RouteConstraints{}
+	}
+	route, _ := ctx.Value(routeContextKey{}).(RouteConstraints)
+	return route
+}
+
+// AuthTags returns the tags of an auth, read from the "tags" attribute (comma separated) and
+// the "tags" metadata entry (a list or a comma separated string) of its auth file.
+func AuthTags(auth *Auth) []string {
+	return authListValues(auth, "tags", "tags")
+}
+
+// authListValues collects the values of a comma separated attribute and of metadata entries
+// holding a list or a comma separated string.
+func authListValues(auth *Auth, attribute string, metadataKeys ...string) []string {
+	if auth == nil {
+		return nil
+	}
+	var values []string
+	add := func(value string) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	if auth.Attributes != nil {
+		add(auth.Attributes[attribute])
+	}
+	for _, key := range metadataKeys {
+		switch typed := auth.Metadata[key].(type) {
+		case string:
+			add(typed)
+		case []string:
+			for _, item := range typed {
+				add(item)
+			}
+		case []any:
+			for _, item := range typed {
+				if s, ok := item.(string); ok {
+					add(s)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// authHasTag reports whether auth carries tag, ignoring case.
+func authHasTag(auth *Auth, tag string) bool {
+	for _, candidate := range AuthTags(auth) {
+		if strings.EqualFold(candidate, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// SelectorFactory builds a custom selector from the current configuration. A selector that
+// also implements Hook receives the manager's auth lifecycle and result callbacks.
+type SelectorFactory func(cfg *internalconfig.Config) (Selector, error)
+
+// builtinSelectorStrategies are the routing strategies that cannot be replaced by a
+// registered selector.
+var builtinSelectorStrategies = map[string]struct{}{
+	"":            {},
+	"round-robin": {},
+	"roundrobin":  {},
+	"rr":          {},
+	"fill-first":  {},
+	"fillfirst":   {},
+	"ff":          {},
+	"session":     {},
+}
+
+var (
+	selectorRegistryMu sync.RWMutex
+	selectorRegistry   = make(map[string]SelectorFactory)
+)
+
+// RegisterSelector makes a custom selector available as routing.strategy name, so embedders
+// can supply their own selection logic (geo-aware, cost-aware, ...) without forking. Names are
+// case-insensitive; registering a built-in strategy name or a nil factory does nothing.
+func RegisterSelector(name string, factory SelectorFactory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if factory == nil {
+		return
+	}
+	if _, builtin := builtinSelectorStrategies[name]; builtin {
+		return
+	}
+	selectorRegistryMu.Lock()
+	selectorRegistry[name] = factory
+	selectorRegistryMu.Unlock()
+}
+
+// UnregisterSelector removes a selector registered with RegisterSelector.
+func UnregisterSelector(name string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	selectorRegistryMu.Lock()
+	delete(selectorRegistry, name)
+	selectorRegistryMu.Unlock()
+}
+
+// LookupSelector returns the factory registered for routing strategy name.
+func LookupSelector(name string) (SelectorFactory, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	selectorRegistryMu.RLock()
+	defer selectorRegistryMu.RUnlock()
+	factory, ok := selectorRegistry[name]
+	return factory, ok
+}
+
+// AvailableAuths filters the candidates passed to Selector.Pick down to the auths that may serve
+// model now: those not disabled or cooling down, in the highest priority tier, ordered by ID.
+// When none is available the error is the one the built-in selectors return, including the
+// cooldown error that tells the client when to retry.
+func AvailableAuths(auths []*Auth, provider, model string) ([]*Auth, error) {
+	return getAvailableAuths(auths, provider, model, time.Now())
+}
+
+// SelectionRequest describes the request a selector is picking an auth for.
+type SelectionRequest struct {
+	// Provider is the provider key the candidates belong to, or "mixed" when they span providers.
+	Provider string
+	// Model is the routed model the auth is picked for, before per-auth model aliases apply.
+	Model string
+	// RequestedModel is the model name the client sent.
+	RequestedModel string
+	// ClientAPIKey is the authenticated client API key, empty when access is open.
+	ClientAPIKey string
+	// SessionID identifies the client session, when one was resolved.
+	SessionID string
+	// Stream reports whether the client asked for a streaming response.
+	Stream bool
+	// Headers are the client request headers forwarded to the executor.
+	Headers http.Header
+	// Metadata carries the execution hints shared across selection and executors.
+	Metadata map[string]any
+}
+
+// NewSelectionRequest collects what is known about the request from the arguments of
+// Selector.Pick, so custom selectors need not decode Options.Metadata themselves.
+func NewSelectionRequest(ctx context.Context, provider, model string, opts cliproxyexecutor.Options) SelectionRequest {
+	req := SelectionRequest{
+		Provider:       provider,
+		Model:          model,
+		RequestedModel: model,
+		Stream:         opts.Stream,
+		Headers:        opts.Headers,
+		Metadata:       opts.Metadata,
+	}
+	if requested, ok := opts.Metadata[cliproxyexecutor.RequestedModelMetadataKey].(string); ok && requested != "" {
+		req.RequestedModel = requested
+	}
+	if key, ok := opts.Metadata[cliproxyexecutor.ClientAPIKeyMetadataKey].(string); ok {
+		req.ClientAPIKey = key
+	}
+	if session, ok := opts.Metadata[cliproxyexecutor.SessionIDMetadataKey].(string); ok && session != "" {
+		req.SessionID = session
+	} else {
+		req.SessionID = SessionIDFromContext(ctx)
+	}
+	return req
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// lastAuthSelector picks the last available auth and records what it was asked for.
+type lastAuthSelector struct {
+	seen SelectionRequest
+}
+
+func (s *lastAuthSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	available, err := AvailableAuths(auths, provider, model)
+	if err != nil {
+		return nil, err
+	}
+	s.seen = NewSelectionRequest(ctx, provider, model, opts)
+	return available[len(available)-1], nil
+}
+
+func TestRegisterSelector(t *testing.T) {
+	factory := func(*internalconfig.Config) (Selector, error) { return &lastAuthSelector{}, nil }
+	RegisterSelector(" Pick-Last ", factory)
+	t.Cleanup(func() { UnregisterSelector("pick-last") })
+
+	if _, ok := LookupSelector("PICK-LAST"); !ok {
+		t.Fatalf("registered selector not found")
+	}
+	RegisterSelector("fill-first", factory)
+	if _, ok := LookupSelector("fill-first"); ok {
+		t.Fatalf("built-in strategy was replaced")
+	}
+	UnregisterSelector("pick-last")
+	if _, ok := LookupSelector("pick-last"); ok {
+		t.Fatalf("selector still registered after UnregisterSelector")
+	}
+}
+
+func TestCustomSelectorSeesRequestMetadata(t *testing.T) {
+	selector := &lastAuthSelector{}
+	manager := NewManager(nil, selector, NoopHook{})
+	manager.RegisterExecutor(&unauthorizedTestExecutor{})
+	for _, id := range []string{"a", "b", "c"} {
+		auth := &Auth{ID: id, Provider: "unauthorized", Status: StatusActive, Metadata: map[string]any{"access_token": "fresh"}}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+	}
+
+	headers := http.Header{"X-Client-Region": []string{"eu"}}
+	opts := cliproxyexecutor.Options{
+		Headers: headers,
+		Metadata: map[string]any{
+			cliproxyexecutor.RequestedModelMetadataKey: "alias-model",
+			cliproxyexecutor.ClientAPIKeyMetadataKey:   "client-1",
+		},
+	}
+	ctx := WithSessionID(context.Background(), "session-1")
+	if _, err := manager.Execute(ctx, []string{"unauthorized"}, cliproxyexecutor.Request{}, opts); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	seen := selector.seen
+	if seen.RequestedModel != "alias-model" {
+		t.Fatalf("selection request = %+v, want requested model", seen)
+	}
+	if seen.ClientAPIKey != "client-1" || seen.SessionID != "session-1" || seen.Headers.Get("X-Client-Region") != "eu" {
+		t.Fatalf("selection request = %+v, want client key, session and headers", seen)
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"sync"
+)
+
+type servedAuthContextKey struct{}
+
+// servedAuthHolder receives the auth that completed a request.
+type servedAuthHolder struct {
+	mu   sync.Mutex
+	auth *Auth
+}
+
+// WithServedAuthRecorder returns a context in which the manager records the auth that
+// successfully served the request, for later retrieval with ServedAuthFromContext.
+func WithServedAuthRecorder(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(servedAuthContextKey{}).(*servedAuthHolder); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, servedAuthContextKey{}, &servedAuthHolder{})
+}
+
+// ServedAuthFromContext returns a snapshot of the auth recorded for the request, or nil when
+// no recorder is installed or nothing has succeeded yet.
+func ServedAuthFromContext(ctx context.Context) *Auth {
+	if ctx == nil {
+		return nil
+	}
+	holder, ok := ctx.Value(servedAuthContextKey{}).(*servedAuthHolder)
+	if !ok {
+		return nil
+	}
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	return holder.auth
+}
+
+func recordServedAuth(ctx context.Context, auth *Auth) {
+	if ctx == nil || auth == nil {
+		return
+	}
+	holder, ok := ctx.Value(servedAuthContextKey{}).(*servedAuthHolder)
+	if !ok {
+		return
+	}
+	snapshot := auth.Clone()
+	holder.mu.Lock()
+	holder.auth = snapshot
+	holder.mu.Unlock()
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	internalusage "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// recordStreamLatency records the time to first token and output rate of a successful stream
+// in the usage statistics and on the request's monitor entry. first is zero when the stream
+// carried no payload, in which case nothing is recorded.
+func recordStreamLatency(ctx context.Context, auth *Auth, provider, model string, started, first, finished time.Time) {
+	if auth == nil || first.IsZero() {
+		return
+	}
+	ttft := first.Sub(started)
+	var rate float64
+	if detail, ok := usage.DetailFromContext(ctx); ok && detail.OutputTokens > 0 {
+		if generation := finished.Sub(first); generation > 0 {
+			rate = float64(detail.OutputTokens) / generation.Seconds()
+		}
+	}
+	internalusage.RecordStreamLatency(internalusage.StreamLatencySample{
+		AuthIndex:       auth.EnsureIndex(),
+		Provider:        provider,
+		Model:           model,
+		TTFT:            ttft,
+		TokensPerSecond: rate,
+	})
+	internalusage.UpdateRequestLog(logging.GetRequestID(ctx), internalusage.RequestLogUpdate{
+		TTFTMs:          ttft.Milliseconds(),
+		TokensPerSecond: rate,
+	})
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// invalidTokenMarkers are error message fragments upstreams use for an expired or revoked
+// access token, sometimes without a 401 status.
+var invalidTokenMarkers = []string{"invalid_token", "token_expired", "token has expired", "token is expired", "expired token"}
+
+// refreshFlight is an inline refresh of one auth shared by concurrent unauthorized requests.
+type refreshFlight struct {
+	done chan struct{}
+	auth *Auth
+	err  error
+}
+
+// inlineRefreshes tracks the inline refreshes in flight, keyed by auth ID.
+type inlineRefreshes struct {
+	mu      sync.Mutex
+	flights map[string]*refreshFlight
+}
+
+// isUnauthorizedError reports whether err means the upstream rejected the access token.
+func isUnauthorizedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if statusCodeFromError(err) == http.StatusUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range invalidTokenMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshAfterUnauthorized refreshes auth inline when err shows its access token was rejected,
+// so the caller can retry the request once with the renewed credentials. It returns nil when
+// err is not an unauthorized error, auth holds an API key or the refresh fails. Concurrent
+// requests share one refresh, and an auth already refreshed since it was picked is reused.
+func (m *Manager) refreshAfterUnauthorized(ctx context.Context, auth *Auth, err error) *Auth {
+	if m == nil || auth == nil || !isUnauthorizedError(err) || ctx.Err() != nil {
+		return nil
+	}
+	if auth.Attributes != nil && strings.TrimSpace(auth.Attributes["api_key"]) != "" {
+		return nil
+	}
+
+	m.inlineRefresh.mu.Lock()
+	if m.inlineRefresh.flights == nil {
+		m.inlineRefresh.flights = make(map[string]*refreshFlight)
+	}
+	flight, running := m.inlineRefresh.flights[auth.ID]
+	if !running {
+		flight = &refreshFlight{done: make(chan struct{})}
+		m.inlineRefresh.flights[auth.ID] = flight
+	}
+	m.inlineRefresh.mu.Unlock()
+
+	if !running {
+		flight.auth, flight.err = m.refreshUnauthorized(context.WithoutCancel(ctx), auth)
+		m.inlineRefresh.mu.Lock()
+		delete(m.inlineRefresh.flights, auth.ID)
+		m.inlineRefresh.mu.Unlock()
+		close(flight.done)
+	}
+	select {
+	case <-flight.done:
+	case <-ctx.Done():
+		return nil
+	}
+	if flight.err != nil {
+		log.Warnf("inline refresh of %s auth %s after an unauthorized response failed: %v", auth.Provider, auth.ID, flight.err)
+		return nil
+	}
+	logEntryWithRequestID(ctx).Infof("refreshed %s auth %s after an unauthorized response, retrying", auth.Provider, auth.ID)
+	return flight.auth
+}
+
+// refreshUnauthorized returns the current credentials of auth when another request refreshed
+// them since auth was picked, and refreshes them otherwise.
+func (m *Manager) refreshUnauthorized(ctx context.Context, auth *Auth) (*Auth, error) {
+	m.mu.RLock()
+	current := m.auths[auth.ID]
+	m.mu.RUnlock()
+	if current == nil {
+		return nil, errors.New("auth no longer registered")
+	}
+	if current.LastRefreshedAt.After(auth.LastRefreshedAt) {
+		return current.Clone(), nil
+	}
+	return m.refreshAuthNow(ctx, auth.ID)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type unauthorizedTestError struct{}
+
+func (unauthorizedTestError) Error() string   { return "401 Unauthorized: token expired" }
+func (unauthorizedTestError) StatusCode() int { return http.StatusUnauthorized }
+
+// unauthorizedTestExecutor rejects requests until the auth carries a refreshed access token.
+type unauthorizedTestExecutor struct {
+	mu        sync.Mutex
+	executes  int
+	refreshes int
+}
+
+func (e *unauthorizedTestExecutor) Identifier() string { return "unauthorized" }
+
+func (e *unauthorizedTestExecutor) Execute(_ context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.executes++
+	e.mu.Unlock()
+	if auth.Metadata["access_token"] != "fresh" {
+		return cliproxyexecutor.Response{}, unauthorizedTestError{}
+	}
+	return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+}
+
+func (e *unauthorizedTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, nil
+}
+
+func (e *unauthorizedTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	e.mu.Lock()
+	e.refreshes++
+	e.mu.Unlock()
+	auth.Metadata["access_token"] = "fresh"
+	return auth, nil
+}
+
+func (e *unauthorizedTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e *unauthorizedTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func newUnauthorizedTestManager(t *testing.T, auth *Auth) (*Manager, *unauthorizedTestExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, NoopHook{})
+	exec := &unauthorizedTestExecutor{}
+	manager.RegisterExecutor(exec)
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	return manager, exec
+}
+
+func TestExecute_RefreshesAndRetriesAfterUnauthorized(t *testing.T) {
+	manager, exec := newUnauthorizedTestManager(t, &Auth{
+		ID:       "oauth",
+		Provider: "unauthorized",
+		Status:   StatusActive,
+		Metadata: map[string]any{"access_token": "stale"},
+	})
+
+	resp, err := manager.Execute(context.Background(), []string{"unauthorized"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "ok" {
+		t.Fatalf("payload = %q, want ok", resp.Payload)
+	}
+	if exec.executes != 2 || exec.refreshes != 1 {
+		t.Fatalf("executes = %d, refreshes = %d, want 2 and 1", exec.executes, exec.refreshes)
+	}
+	stored, _ := manager.GetByID("oauth")
+	if stored.Metadata["access_token"] != "fresh" || stored.Unavailable {
+		t.Fatalf("stored auth = %+v, want refreshed and available", stored)
+	}
+}
+
+func TestExecute_DoesNotRefreshAPIKeyAfterUnauthorized(t *testing.T) {
+	manager, exec := newUnauthorizedTestManager(t, &Auth{
+		ID:         "key",
+		Provider:   "unauthorized",
+		Status:     StatusActive,
+		Attributes: map[string]string{"api_key": "sk-test"},
+		Metadata:   map[string]any{"access_token": "stale"},
+	})
+
+	if _, err := manager.Execute(context.Background(), []string{"unauthorized"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatalf("Execute() error = nil, want unauthorized error")
+	}
+	if exec.refreshes != 0 {
+		t.Fatalf("refreshes = %d, want 0 for API key auth", exec.refreshes)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
 )
 
 // Builder constructs a Service instance with customizable providers.
@@ -48,6 +49,9 @@ type Builder struct {
 
 	// serverOptions contains additional server configuration options.
 	serverOptions []api.ServerOption
+
+	// executorMiddleware wraps every provider executor call.
+	executorMiddleware []coreauth.ExecutorMiddleware
 }
 
 // Hooks allows callers to plug into service lifecycle stages.
@@ -138,6 +142,12 @@ func (b *Builder) WithCoreAuthManager(mgr *coreauth.Manager) *Builder {
 	return b
 }
 
+// WithExecutorMiddleware appends middleware run around every provider executor call.
+func (b *Builder) WithExecutorMiddleware(middleware ...coreauth.ExecutorMiddleware) *Builder {
+	b.executorMiddleware = append(b.executorMiddleware, middleware...)
+	return b
+}
+
 // WithServerOptions appends server configuration options used during construction.
 func (b *Builder) WithServerOptions(opts ...api.ServerOption) *Builder {
 	b.serverOptions = append(b.serverOptions, opts...)
@@ -207,6 +217,7 @@ func (b *Builder) Build() (*Service, error) {
 	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
 	coreManager.SetConfig(b.cfg)
 	coreManager.SetOAuthModelAlias(b.cfg.OAuthModelAlias)
+	coreManager.UseExecutorMiddleware(b.executorMiddleware...)
 
 	service := &Service{
 		cfg:            b.cfg,
@@ -259,7 +270,14 @@ func buildSelectorAndHook(cfg *config.Config) (coreauth.Selector, coreauth.Hook)
 	switch strategy {
 	case "fill-first", "fillfirst", "ff":
 		return &coreauth.FillFirstSelector{}, nil
-	default:
-		return &coreauth.RoundRobinSelector{}, nil
 	}
+	if factory, ok := coreauth.LookupSelector(strategy); ok {
+		selector, err := factory(cfg)
+		if err == nil && selector != nil {
+			hook, _ := selector.(coreauth.Hook)
+			return selector, hook
+		}
+		log.Errorf("routing strategy %q: failed to build selector, using round-robin: %v", strategy, err)
+	}
+	return &coreauth.RoundRobinSelector{}, nil
 }
@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+)
+
+// Endpoint identifies the kind of upstream call an executor is serving.
+type Endpoint string
+
+const (
+	// EndpointExecute marks non-streaming generation requests.
+	EndpointExecute Endpoint = "execute"
+	// EndpointStream marks streaming generation requests.
+	EndpointStream Endpoint = "stream"
+	// EndpointCountTokens marks token counting requests.
+	EndpointCountTokens Endpoint = "count-tokens"
+)
+
+type endpointContextKey struct{}
+
+// WithEndpoint returns a context tagged with the endpoint kind being executed.
+func WithEndpoint(ctx context.Context, endpoint Endpoint) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, endpointContextKey{}, endpoint)
+}
+
+// EndpointFromContext returns the endpoint kind recorded by WithEndpoint, or "" when unset.
+func EndpointFromContext(ctx context.Context) Endpoint {
+	if ctx == nil {
+		return ""
+	}
+	endpoint, _ := ctx.Value(endpointContextKey{}).(Endpoint)
+	return endpoint
+}
+
+// RequestMetadata describes the inbound client request that triggered an upstream call.
+// The HTTP layer records it with WithRequestMetadata; applications embedding the SDK
+// without gin can populate it themselves to pass client headers through to executors.
+type RequestMetadata struct {
+	// UserAgent is the client's User-Agent header.
+	UserAgent string
+	// Headers holds the inbound request headers. Executors must treat them as read-only.
+	Headers http.Header
+	// ClientKey is the authenticated client API key, if any.
+	ClientKey string
+	// Method is the inbound HTTP method.
+	Method string
+	// Path is the matched route pattern, or the request path when no route matched.
+	Path string
+}
+
+type requestMetadataContextKey struct{}
+
+// WithRequestMetadata returns a context carrying metadata about the inbound client request.
+func WithRequestMetadata(ctx context.Context, meta *RequestMetadata) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestMetadataContextKey{}, meta)
+}
+
+// RequestMetadataFromContext returns the metadata recorded by WithRequestMetadata.
+// It never returns nil; a zero RequestMetadata is returned when none was recorded.
+func RequestMetadataFromContext(ctx context.Context) *RequestMetadata {
+	if ctx != nil {
+		if meta, ok := ctx.Value(requestMetadataContextKey{}).(*RequestMetadata); ok && meta != nil {
+			return meta
+		}
+	}
+	return &RequestMetadata{}
+}
@@ -13,6 +13,10 @@ const RequestedModelMetadataKey = "requested_model"
 // SessionIDMetadataKey stores the resolved session identifier in Options.Metadata.
 const SessionIDMetadataKey = "session_id"
 
+// SystemPromptCacheKeyMetadataKey stores the prompt caching key assigned to a system prompt
+// repeated within a session in Options.Metadata.
+const SystemPromptCacheKeyMetadataKey = "system_prompt_cache_key"
+
 // ClientAPIKeyMetadataKey stores the authenticated client API key in Options.Metadata.
 const ClientAPIKeyMetadataKey = "client_api_key"
 
@@ -0,0 +1,98 @@
+package cliproxy
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/plugin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// executorPlugins tracks the executors loaded from executor-plugins and the provider keys they
+// serve, so built-in executors are not bound over them.
+type executorPlugins struct {
+	mu     sync.RWMutex
+	loaded map[string]*loadedExecutorPlugin
+}
+
+type loadedExecutorPlugin struct {
+	cfg      config.ExecutorPluginConfig
+	provider string
+	closer   io.Closer
+}
+
+func (s *Service) applyExecutorPluginsConfig(cfg *config.Config) {
+	if s == nil || cfg == nil || s.coreManager == nil {
+		return
+	}
+	if s.executorPlugins == nil {
+		if len(cfg.ExecutorPlugins) == 0 {
+			return
+		}
+		s.executorPlugins = &executorPlugins{loaded: make(map[string]*loadedExecutorPlugin)}
+	}
+	s.executorPlugins.Apply(cfg.ExecutorPlugins, s.coreManager)
+}
+
+func (s *Service) shutdownExecutorPlugins() {
+	if s == nil || s.executorPlugins == nil {
+		return
+	}
+	s.executorPlugins.Apply(nil, s.coreManager)
+}
+
+// Apply loads the newly declared plugins and stops the ones no longer declared. Go plugins
+// stay mapped in the process once loaded; removing one only unregisters its executor.
+func (p *executorPlugins) Apply(plugins []config.ExecutorPluginConfig, manager *coreauth.Manager) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wanted := make(map[string]config.ExecutorPluginConfig, len(plugins))
+	for _, cfg := range plugins {
+		wanted[cfg.Path] = cfg
+	}
+	for path, loaded := range p.loaded {
+		if cfg, ok := wanted[path]; ok && reflect.DeepEqual(cfg, loaded.cfg) {
+			continue
+		}
+		manager.UnregisterExecutor(loaded.provider)
+		if loaded.closer != nil {
+			_ = loaded.closer.Close()
+		}
+		delete(p.loaded, path)
+		log.Infof("executor plugin %s for provider %s unloaded", path, loaded.provider)
+	}
+	for _, cfg := range plugins {
+		if _, ok := p.loaded[cfg.Path]; ok {
+			continue
+		}
+		executor, closer, err := plugin.Load(cfg)
+		if err != nil {
+			log.Errorf("failed to load %v", err)
+			continue
+		}
+		provider := strings.ToLower(executor.Identifier())
+		manager.RegisterExecutor(executor)
+		p.loaded[cfg.Path] = &loadedExecutorPlugin{cfg: cfg, provider: provider, closer: closer}
+		log.Infof("executor plugin %s loaded for provider %s", cfg.Path, provider)
+	}
+}
+
+// Provides reports whether a loaded plugin serves provider.
+func (p *executorPlugins) Provides(provider string) bool {
+	if p == nil {
+		return false
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, loaded := range p.loaded {
+		if loaded.provider == provider {
+			return true
+		}
+	}
+	return false
+}
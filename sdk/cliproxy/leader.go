@@ -0,0 +1,119 @@
+package cliproxy
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/leader"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultLeaderLockFile = ".leader.lock"
+
+type leaderElection struct {
+	mu      sync.Mutex
+	elector *leader.FileElector
+	path    string
+	lease   time.Duration
+}
+
+// IsLeader reports whether this instance currently runs fleet-wide background jobs.
+// It always returns true when leader election is disabled.
+func (s *Service) IsLeader() bool {
+	if s == nil || s.coreManager == nil {
+		return true
+	}
+	return s.coreManager.IsLeader()
+}
+
+func (s *Service) applyLeaderElectionConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	if s.leaderElection == nil {
+		s.leaderElection = &leaderElection{}
+	}
+	s.leaderElection.Apply(cfg, s)
+}
+
+func (s *Service) shutdownLeaderElection() {
+	if s == nil || s.leaderElection == nil {
+		return
+	}
+	s.leaderElection.Stop(s)
+}
+
+func (l *leaderElection) Apply(cfg *config.Config, s *Service) {
+	if l == nil || cfg == nil {
+		return
+	}
+	if !cfg.LeaderElection.Enable {
+		l.Stop(s)
+		return
+	}
+	path := resolveLeaderLockFile(cfg)
+	lease := time.Duration(cfg.LeaderElection.LeaseSeconds) * time.Second
+	if lease <= 0 {
+		lease = leader.DefaultLease
+	}
+
+	l.mu.Lock()
+	if l.elector != nil && l.path == path && l.lease == lease {
+		l.mu.Unlock()
+		return
+	}
+	previous := l.elector
+	elector := leader.NewFileElector(path, lease)
+	l.elector = elector
+	l.path = path
+	l.lease = lease
+	l.mu.Unlock()
+
+	if previous != nil {
+		previous.Stop()
+	}
+	elector.Start(context.Background())
+	if s != nil && s.coreManager != nil {
+		s.coreManager.SetLeaderElector(elector)
+	}
+	log.Infof("leader election enabled (lock=%s, lease=%s, id=%s)", path, lease, elector.ID())
+}
+
+func (l *leaderElection) Stop(s *Service) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	elector := l.elector
+	l.elector = nil
+	l.path = ""
+	l.lease = 0
+	l.mu.Unlock()
+	if elector == nil {
+		return
+	}
+	if s != nil && s.coreManager != nil {
+		s.coreManager.SetLeaderElector(nil)
+	}
+	elector.Stop()
+	log.Info("leader election disabled")
+}
+
+func resolveLeaderLockFile(cfg *config.Config) string {
+	if path := strings.TrimSpace(cfg.LeaderElection.LockFile); path != "" {
+		if resolved, errResolve := util.ResolveAuthDir(path); errResolve == nil && resolved != "" {
+			return resolved
+		}
+		return path
+	}
+	authDir := cfg.AuthDir
+	if resolved, errResolve := util.ResolveAuthDir(authDir); errResolve == nil && resolved != "" {
+		authDir = resolved
+	}
+	return filepath.Join(authDir, defaultLeaderLockFile)
+}
@@ -0,0 +1,201 @@
+package cliproxy
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// modelDiscoveryTimeout bounds a single upstream model-list request.
+const modelDiscoveryTimeout = 15 * time.Second
+
+type modelDiscovery struct {
+	mu       sync.Mutex
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (s *Service) applyModelDiscoveryConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	registry.GetGlobalRegistry().SetHideExhaustedModels(cfg.ModelDiscovery.HideExhausted)
+	if s.modelDiscovery == nil {
+		s.modelDiscovery = &modelDiscovery{}
+	}
+	s.modelDiscovery.Apply(time.Duration(cfg.ModelDiscovery.RefreshIntervalSeconds)*time.Second, s)
+}
+
+func (s *Service) shutdownModelDiscovery() {
+	if s == nil || s.modelDiscovery == nil {
+		return
+	}
+	s.modelDiscovery.Stop()
+}
+
+func (d *modelDiscovery) Apply(interval time.Duration, s *Service) {
+	if d == nil {
+		return
+	}
+	if interval <= 0 {
+		d.Stop()
+		return
+	}
+	d.mu.Lock()
+	if d.cancel != nil && d.interval == interval {
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+	d.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	d.mu.Lock()
+	d.interval = interval
+	d.cancel = cancel
+	d.done = done
+	d.mu.Unlock()
+
+	log.Infof("model discovery: refreshing model lists every %s", interval)
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshModelRegistrations(ctx)
+			}
+		}
+	}()
+}
+
+func (d *modelDiscovery) Stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	cancel := d.cancel
+	done := d.done
+	d.cancel = nil
+	d.done = nil
+	d.interval = 0
+	d.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// refreshModelRegistrations re-runs model discovery for every known auth and drops
+// registry entries whose backing auth has been deleted.
+func (s *Service) refreshModelRegistrations(ctx context.Context) {
+	if s == nil || s.coreManager == nil {
+		return
+	}
+	auths := s.coreManager.List()
+	known := make(map[string]struct{}, len(auths))
+	for _, a := range auths {
+		if ctx.Err() != nil {
+			return
+		}
+		if a == nil || a.ID == "" {
+			continue
+		}
+		known[a.ID] = struct{}{}
+		s.registerModelsForAuth(a)
+	}
+	modelRegistry := registry.GetGlobalRegistry()
+	for _, clientID := range modelRegistry.ClientIDs() {
+		if _, ok := known[clientID]; !ok {
+			log.Debugf("model discovery: removing models of deleted auth %s", clientID)
+			modelRegistry.UnregisterClient(clientID)
+		}
+	}
+}
+
+// discoverOpenAICompatModels queries an OpenAI-compatible provider for the models available to an auth.
+func (s *Service) discoverOpenAICompatModels(a *coreauth.Auth, ownedBy string) []*ModelInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+	defer cancel()
+	return executor.FetchOpenAICompatModels(ctx, a, s.cfg, ownedBy)
+}
+
+// discoverProviderModels queries a provider's model-list endpoint for the models available to an auth.
+func (s *Service) discoverProviderModels(a *coreauth.Auth, fetch func(context.Context, *coreauth.Auth, *config.Config) []*ModelInfo) []*ModelInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+	defer cancel()
+	return fetch(ctx, a, s.cfg)
+}
+
+// restrictToDiscoveredModels keeps the built-in models an account listed, with their
+// metadata, and appends listed models missing from the built-in list. The built-in list
+// stands when discovery returned nothing.
+func restrictToDiscoveredModels(builtin, discovered []*ModelInfo) []*ModelInfo {
+	if len(discovered) == 0 {
+		return builtin
+	}
+	listed := make(map[string]*ModelInfo, len(discovered))
+	for _, m := range discovered {
+		if m != nil {
+			listed[strings.ToLower(m.ID)] = m
+		}
+	}
+	out := make([]*ModelInfo, 0, len(discovered))
+	for _, m := range builtin {
+		key := strings.ToLower(m.ID)
+		if _, ok := listed[key]; ok {
+			out = append(out, m)
+			delete(listed, key)
+		}
+	}
+	for _, m := range discovered {
+		if m == nil {
+			continue
+		}
+		if _, ok := listed[strings.ToLower(m.ID)]; ok {
+			out = append(out, m)
+			delete(listed, strings.ToLower(m.ID))
+		}
+	}
+	return out
+}
+
+// mergeDiscoveredCompatModels appends discovered models that are not already covered by
+// configured models, matching either the upstream name or the client-visible alias.
+func mergeDiscoveredCompatModels(configured []*ModelInfo, compat *config.OpenAICompatibility, discovered []*ModelInfo) []*ModelInfo {
+	if len(discovered) == 0 {
+		return configured
+	}
+	seen := make(map[string]struct{}, len(configured)+len(compat.Models))
+	for _, m := range configured {
+		seen[strings.ToLower(m.ID)] = struct{}{}
+	}
+	for _, m := range compat.Models {
+		seen[strings.ToLower(strings.TrimSpace(m.Name))] = struct{}{}
+	}
+	out := configured
+	for _, m := range discovered {
+		if m == nil {
+			continue
+		}
+		key := strings.ToLower(m.ID)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, m)
+	}
+	return out
+}
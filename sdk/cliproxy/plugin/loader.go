@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	stdplugin "plugin"
+	"strings"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// NewExecutorSymbol is the function a Go plugin exports to build its executor:
+//
+//	func NewExecutor() auth.ProviderExecutor
+const NewExecutorSymbol = "NewExecutor"
+
+// Load builds the executor declared by cfg. The returned closer stops a sidecar process; it is
+// nil for Go plugins, which cannot be unloaded.
+func Load(cfg config.ExecutorPluginConfig) (coreauth.ProviderExecutor, io.Closer, error) {
+	switch cfg.Type {
+	case config.ExecutorPluginSharedObject:
+		executor, err := loadSharedObject(cfg.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("executor plugin %s: %w", cfg.Path, err)
+		}
+		if cfg.Provider != "" && !strings.EqualFold(executor.Identifier(), cfg.Provider) {
+			return nil, nil, fmt.Errorf("executor plugin %s: serves provider %q, configured for %q", cfg.Path, executor.Identifier(), cfg.Provider)
+		}
+		return executor, nil, nil
+	case config.ExecutorPluginSidecar:
+		sidecar, err := startSidecar(cfg.Provider, cfg.Path, cfg.Args, cfg.Env)
+		if err != nil {
+			return nil, nil, fmt.Errorf("executor plugin %s: %w", cfg.Path, err)
+		}
+		return sidecar, sidecar, nil
+	default:
+		return nil, nil, fmt.Errorf("executor plugin %s: unsupported type %q", cfg.Path, cfg.Type)
+	}
+}
+
+// loadSharedObject opens a Go plugin and calls its NewExecutor function. Go plugins only load
+// on platforms with plugin support and must be built with the same toolchain and module
+// versions as the proxy.
+func loadSharedObject(path string) (coreauth.ProviderExecutor, error) {
+	p, err := stdplugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup(NewExecutorSymbol)
+	if err != nil {
+		return nil, err
+	}
+	newExecutor, ok := symbol.(func() coreauth.ProviderExecutor)
+	if !ok {
+		return nil, fmt.Errorf("%s has type %T, want func() auth.ProviderExecutor", NewExecutorSymbol, symbol)
+	}
+	executor := newExecutor()
+	if executor == nil {
+		return nil, fmt.Errorf("%s returned nil", NewExecutorSymbol)
+	}
+	return executor, nil
+}
@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// echoExecutor answers with the model and the auth's token, and fails models named "fail".
+type echoExecutor struct{}
+
+func (echoExecutor) Identifier() string { return "echo" }
+
+func (echoExecutor) Execute(_ context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if req.Model == "fail" {
+		return cliproxyexecutor.Response{}, &coreauth.Error{Code: "rate_limited", Message: "slow down", HTTPStatus: http.StatusTooManyRequests}
+	}
+	return cliproxyexecutor.Response{Payload: []byte(req.Model + ":" + auth.Attributes["api_key"])}, nil
+}
+
+func (echoExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	out := make(chan cliproxyexecutor.StreamChunk, 3)
+	out <- cliproxyexecutor.StreamChunk{Payload: []byte("data: one\n\n")}
+	out <- cliproxyexecutor.StreamChunk{Payload: []byte("data: " + req.Model + "\n\n")}
+	out <- cliproxyexecutor.StreamChunk{Err: errors.New("upstream closed")}
+	close(out)
+	return out, nil
+}
+
+func (echoExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	auth.Metadata = map[string]any{"access_token": "renewed"}
+	return auth, nil
+}
+
+func (echoExecutor) CountTokens(context.Context, *coreauth.Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{Payload: []byte(`{"input_tokens":7}`)}, nil
+}
+
+func (echoExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody}, nil
+}
+
+func newTestSidecar(t *testing.T, token string) *sidecarExecutor {
+	t.Helper()
+	server := httptest.NewServer(Handler(echoExecutor{}, token))
+	t.Cleanup(server.Close)
+	return newSidecarClient("echo", "tcp", strings.TrimPrefix(server.URL, "http://"), token)
+}
+
+func testAuth() *coreauth.Auth {
+	return &coreauth.Auth{ID: "a1", Provider: "echo", Attributes: map[string]string{"api_key": "k1"}}
+}
+
+func TestSidecarExecute(t *testing.T) {
+	sidecar := newTestSidecar(t, "secret")
+
+	resp, err := sidecar.Execute(context.Background(), testAuth(), cliproxyexecutor.Request{Model: "m1"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "m1:k1" {
+		t.Fatalf("payload = %q, want m1:k1", resp.Payload)
+	}
+
+	_, err = sidecar.Execute(context.Background(), testAuth(), cliproxyexecutor.Request{Model: "fail"}, cliproxyexecutor.Options{})
+	var authErr *coreauth.Error
+	if !errors.As(err, &authErr) || authErr.StatusCode() != http.StatusTooManyRequests || authErr.Code != "rate_limited" {
+		t.Fatalf("Execute() error = %v, want rate_limited 429", err)
+	}
+}
+
+func TestSidecarRejectsWrongToken(t *testing.T) {
+	sidecar := newTestSidecar(t, "secret")
+	sidecar.token = "guess"
+
+	_, err := sidecar.Execute(context.Background(), testAuth(), cliproxyexecutor.Request{Model: "m1"}, cliproxyexecutor.Options{})
+	var authErr *coreauth.Error
+	if !errors.As(err, &authErr) || authErr.StatusCode() != http.StatusUnauthorized {
+		t.Fatalf("Execute() error = %v, want 401", err)
+	}
+}
+
+func TestSidecarExecuteStream(t *testing.T) {
+	sidecar := newTestSidecar(t, "")
+
+	chunks, err := sidecar.ExecuteStream(context.Background(), testAuth(), cliproxyexecutor.Request{Model: "m2"}, cliproxyexecutor.Options{Stream: true})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var payloads []string
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+			continue
+		}
+		payloads = append(payloads, string(chunk.Payload))
+	}
+	if len(payloads) != 2 || payloads[1] != "data: m2\n\n" {
+		t.Fatalf("payloads = %q", payloads)
+	}
+	if streamErr == nil || streamErr.Error() != "upstream closed" {
+		t.Fatalf("stream error = %v, want upstream closed", streamErr)
+	}
+}
+
+func TestSidecarRefreshKeepsLocalState(t *testing.T) {
+	sidecar := newTestSidecar(t, "")
+	auth := testAuth()
+	auth.FileName = "echo.json"
+	auth.Runtime = struct{}{}
+
+	updated, err := sidecar.Refresh(context.Background(), auth)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if updated.Metadata["access_token"] != "renewed" || updated.FileName != "echo.json" || updated.Runtime == nil {
+		t.Fatalf("refreshed auth = %+v", updated)
+	}
+}
+
+func TestParseHandshake(t *testing.T) {
+	network, address, err := parseHandshake("1|1|tcp|127.0.0.1:4000|http\n")
+	if err != nil || network != "tcp" || address != "127.0.0.1:4000" {
+		t.Fatalf("parseHandshake() = %q, %q, %v", network, address, err)
+	}
+	for _, line := range []string{"", "1|1|tcp|127.0.0.1:4000", "2|1|tcp|127.0.0.1:4000|http", "1|1|tcp|127.0.0.1:4000|grpc", "1|1|udp|127.0.0.1:4000|http"} {
+		if _, _, err = parseHandshake(line); err == nil {
+			t.Fatalf("parseHandshake(%q) succeeded, want error", line)
+		}
+	}
+}
+
+// TestSidecarHelperProcess is the sidecar started by TestSidecarProcess; it does nothing
+// when run as a normal test.
+func TestSidecarHelperProcess(t *testing.T) {
+	if os.Getenv("CLIPROXY_PLUGIN_TEST_HELPER") != "1" {
+		return
+	}
+	if err := Serve(echoExecutor{}); err != nil {
+		os.Exit(2)
+	}
+}
+
+func TestSidecarProcess(t *testing.T) {
+	sidecar, err := startSidecar("echo", os.Args[0], []string{"-test.run=^TestSidecarHelperProcess$"}, []string{"CLIPROXY_PLUGIN_TEST_HELPER=1"})
+	if err != nil {
+		t.Fatalf("startSidecar() error = %v", err)
+	}
+	defer func() { _ = sidecar.Close() }()
+
+	resp, err := sidecar.CountTokens(context.Background(), testAuth(), cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("CountTokens() error = %v", err)
+	}
+	if string(resp.Payload) != `{"input_tokens":7}` {
+		t.Fatalf("payload = %q", resp.Payload)
+	}
+}
+
+func TestServeRefusesToRunStandalone(t *testing.T) {
+	t.Setenv(MagicCookieKey, "")
+	if err := Serve(echoExecutor{}); err == nil {
+		t.Fatalf("Serve() without the magic cookie succeeded")
+	}
+}
+
+func TestSidecarRestartsAfterExit(t *testing.T) {
+	minBackoff := sidecarRestartMinBackoff
+	sidecarRestartMinBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { sidecarRestartMinBackoff = minBackoff })
+
+	sidecar, err := startSidecar("echo", os.Args[0], []string{"-test.run=^TestSidecarHelperProcess$"}, []string{"CLIPROXY_PLUGIN_TEST_HELPER=1"})
+	if err != nil {
+		t.Fatalf("startSidecar() error = %v", err)
+	}
+	defer func() { _ = sidecar.Close() }()
+
+	sidecar.mu.RLock()
+	first := sidecar.cmd
+	sidecar.mu.RUnlock()
+	_ = first.Process.Kill()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		sidecar.mu.RLock()
+		current := sidecar.cmd
+		sidecar.mu.RUnlock()
+		if current != first {
+			if _, errCount := sidecar.CountTokens(context.Background(), testAuth(), cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); errCount == nil {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("sidecar was not restarted after it exited")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSidecarEnvOmitsProxyEnvironment(t *testing.T) {
+	t.Setenv("CLIPROXY_PLUGIN_TEST_SECRET", "secret")
+	for _, entry := range sidecarEnv() {
+		if strings.HasPrefix(entry, "CLIPROXY_PLUGIN_TEST_SECRET=") {
+			t.Fatalf("sidecar environment includes %q", entry)
+		}
+	}
+}
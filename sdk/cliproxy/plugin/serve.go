@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// Serve runs executor as a sidecar: it listens on a loopback port, prints the handshake line
+// the proxy waits for and serves executor calls until the process is stopped. It refuses to
+// run when the process was not started by the proxy.
+func Serve(executor coreauth.ProviderExecutor) error {
+	if executor == nil {
+		return errors.New("plugin: executor is nil")
+	}
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("plugin: this binary is an executor plugin and is started by the proxy, not directly")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("plugin: listen: %w", err)
+	}
+	fmt.Printf("%d|%d|tcp|%s|http\n", CoreProtocolVersion, ProtocolVersion, listener.Addr().String())
+	return http.Serve(listener, Handler(executor, os.Getenv(TokenEnvKey)))
+}
+
+// Handler serves the sidecar protocol for executor. Calls must carry token as a bearer token
+// unless token is empty.
+func Handler(executor coreauth.ProviderExecutor, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathExecute, func(w http.ResponseWriter, r *http.Request) {
+		var call wireCall
+		if !decodeWire(w, r, &call) {
+			return
+		}
+		resp, err := executor.Execute(r.Context(), call.Auth, call.request(), call.options())
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		writeWire(w, wireResponse{Payload: resp.Payload, Metadata: jsonMetadata(resp.Metadata)})
+	})
+	mux.HandleFunc(pathCountTokens, func(w http.ResponseWriter, r *http.Request) {
+		var call wireCall
+		if !decodeWire(w, r, &call) {
+			return
+		}
+		resp, err := executor.CountTokens(r.Context(), call.Auth, call.request(), call.options())
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		writeWire(w, wireResponse{Payload: resp.Payload, Metadata: jsonMetadata(resp.Metadata)})
+	})
+	mux.HandleFunc(pathExecuteStream, func(w http.ResponseWriter, r *http.Request) {
+		var call wireCall
+		if !decodeWire(w, r, &call) {
+			return
+		}
+		chunks, err := executor.ExecuteStream(r.Context(), call.Auth, call.request(), call.options())
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		for chunk := range chunks {
+			line := wireChunk{Payload: chunk.Payload}
+			if chunk.Err != nil {
+				line.Error = wireErrorFrom(chunk.Err)
+			}
+			if errEncode := encoder.Encode(line); errEncode != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	})
+	mux.HandleFunc(pathRefresh, func(w http.ResponseWriter, r *http.Request) {
+		var call wireRefresh
+		if !decodeWire(w, r, &call) {
+			return
+		}
+		updated, err := executor.Refresh(r.Context(), call.Auth)
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		writeWire(w, wireRefresh{Auth: updated})
+	})
+	mux.HandleFunc(pathHTTPRequest, func(w http.ResponseWriter, r *http.Request) {
+		var call wireHTTPRequest
+		if !decodeWire(w, r, &call) {
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), call.Method, call.URL, bytes.NewReader(call.Body))
+		if err != nil {
+			writeWireError(w, &coreauth.Error{Code: "invalid_request", Message: err.Error(), HTTPStatus: http.StatusBadRequest})
+			return
+		}
+		req.Header = call.Header
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		resp, err := executor.HttpRequest(r.Context(), call.Auth, req)
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			writeWireError(w, err)
+			return
+		}
+		writeWire(w, wireHTTPResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: body})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeWireError(w, &coreauth.Error{Code: "unauthorized", Message: "invalid plugin token", HTTPStatus: http.StatusUnauthorized})
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeWireError(w, &coreauth.Error{Code: "method_not_allowed", Message: "use POST", HTTPStatus: http.StatusMethodNotAllowed})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func decodeWire(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(bufio.NewReader(r.Body)).Decode(v); err != nil {
+		writeWireError(w, &coreauth.Error{Code: "invalid_request", Message: err.Error(), HTTPStatus: http.StatusBadRequest})
+		return false
+	}
+	return true
+}
+
+func writeWire(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeWireError answers with the error's status, or 502 when the executor gave none, so the
+// proxy can tell failed calls from successful ones.
+func writeWireError(w http.ResponseWriter, err error) {
+	wire := wireErrorFrom(err)
+	status := wire.HTTPStatus
+	if status < http.StatusBadRequest {
+		status = http.StatusBadGateway
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(wireError{Error: wire})
+}
@@ -0,0 +1,428 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sidecarHandshakeTimeout bounds how long a sidecar may take to print its handshake.
+	sidecarHandshakeTimeout = 10 * time.Second
+	// sidecarStopTimeout is how long a sidecar has to exit after an interrupt before it is killed.
+	sidecarStopTimeout = 3 * time.Second
+)
+
+// sidecarRestartMinBackoff and sidecarRestartMaxBackoff bound the delay before a sidecar that
+// exited on its own is started again. The delay doubles after every failed start.
+var (
+	sidecarRestartMinBackoff = time.Second
+	sidecarRestartMaxBackoff = time.Minute
+)
+
+// sidecarEnvKeys are the variables of the proxy's environment a sidecar inherits; everything
+// else it needs is passed through the plugin's env setting.
+var sidecarEnvKeys = []string{"PATH", "HOME", "USER", "LANG", "TZ", "TMPDIR", "TMP", "TEMP", "SYSTEMROOT", "USERPROFILE"}
+
+// sidecarExecutor forwards executor calls to a sidecar process.
+type sidecarExecutor struct {
+	provider string
+	path     string
+	args     []string
+	env      []string
+
+	mu      sync.RWMutex
+	baseURL string
+	token   string
+	client  *http.Client
+	cmd     *exec.Cmd
+	exited  chan struct{}
+
+	closed   chan struct{}
+	stopOnce sync.Once
+}
+
+// sidecarProcess is a started sidecar that has completed its handshake.
+type sidecarProcess struct {
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	network string
+	address string
+	token   string
+}
+
+// parseHandshake parses a "core|app|network|address|protocol" handshake line.
+func parseHandshake(line string) (network, address string, err error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return "", "", fmt.Errorf("malformed handshake %q", line)
+	}
+	if core, errCore := strconv.Atoi(parts[0]); errCore != nil || core != CoreProtocolVersion {
+		return "", "", fmt.Errorf("unsupported core protocol version %q", parts[0])
+	}
+	if app, errApp := strconv.Atoi(parts[1]); errApp != nil || app != ProtocolVersion {
+		return "", "", fmt.Errorf("unsupported protocol version %q", parts[1])
+	}
+	network, address = parts[2], parts[3]
+	if network != "tcp" && network != "unix" {
+		return "", "", fmt.Errorf("unsupported network %q", network)
+	}
+	if parts[4] != "http" {
+		return "", "", fmt.Errorf("unsupported transport %q; sidecars must serve http", parts[4])
+	}
+	return network, address, nil
+}
+
+// newSidecarClient returns an executor talking to a sidecar listening on network/address.
+func newSidecarClient(provider, network, address, token string) *sidecarExecutor {
+	transport := &http.Transport{}
+	baseURL := "http://" + address
+	if network == "unix" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", address)
+		}
+		baseURL = "http://plugin"
+	}
+	return &sidecarExecutor{
+		provider: provider,
+		baseURL:  baseURL,
+		token:    token,
+		client:   &http.Client{Transport: transport},
+		closed:   make(chan struct{}),
+	}
+}
+
+// startSidecar starts the executable at path and connects to it once it has printed its handshake.
+// A sidecar that exits while the executor is in use is started again with backoff.
+func startSidecar(provider, path string, args, env []string) (*sidecarExecutor, error) {
+	proc, err := launchSidecar(provider, path, args, env)
+	if err != nil {
+		return nil, err
+	}
+	sidecar := newSidecarClient(provider, proc.network, proc.address, proc.token)
+	sidecar.path, sidecar.args, sidecar.env = path, args, env
+	sidecar.cmd, sidecar.exited = proc.cmd, proc.exited
+	go sidecar.supervise()
+	return sidecar, nil
+}
+
+// launchSidecar starts one sidecar process and waits for its handshake.
+func launchSidecar(provider, path string, args, env []string) (*sidecarProcess, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(append(sidecarEnv(), env...), MagicCookieKey+"="+MagicCookieValue, TokenEnvKey+"="+token)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		errWait := cmd.Wait()
+		close(exited)
+		log.Warnf("executor plugin %s (%s) exited: %v", provider, path, errWait)
+	}()
+	go logSidecarOutput(provider, stderr)
+
+	lines := make(chan string, 1)
+	reader := bufio.NewReader(stdout)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+		logSidecarOutput(provider, reader)
+	}()
+
+	stop := func() { _ = cmd.Process.Kill() }
+	select {
+	case line := <-lines:
+		network, address, errHandshake := parseHandshake(line)
+		if errHandshake != nil {
+			stop()
+			return nil, errHandshake
+		}
+		return &sidecarProcess{cmd: cmd, exited: exited, network: network, address: address, token: token}, nil
+	case <-exited:
+		return nil, fmt.Errorf("exited before the handshake")
+	case <-time.After(sidecarHandshakeTimeout):
+		stop()
+		return nil, fmt.Errorf("no handshake within %s", sidecarHandshakeTimeout)
+	}
+}
+
+// sidecarEnv returns the part of the proxy's environment passed on to sidecars.
+func sidecarEnv() []string {
+	env := make([]string, 0, len(sidecarEnvKeys))
+	for _, key := range sidecarEnvKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// supervise starts the sidecar again whenever it exits before Close was called.
+func (e *sidecarExecutor) supervise() {
+	for {
+		e.mu.RLock()
+		exited := e.exited
+		e.mu.RUnlock()
+		select {
+		case <-e.closed:
+			return
+		case <-exited:
+		}
+		if !e.restart() {
+			return
+		}
+	}
+}
+
+// restart launches the sidecar until it completes a handshake, doubling the delay between
+// attempts. It returns false when Close was called first.
+func (e *sidecarExecutor) restart() bool {
+	backoff := sidecarRestartMinBackoff
+	for {
+		log.Warnf("executor plugin %s: restarting in %s", e.provider, backoff)
+		select {
+		case <-e.closed:
+			return false
+		case <-time.After(backoff):
+		}
+		proc, err := launchSidecar(e.provider, e.path, e.args, e.env)
+		if err != nil {
+			log.Warnf("executor plugin %s: restart failed: %v", e.provider, err)
+			backoff = min(backoff*2, sidecarRestartMaxBackoff)
+			continue
+		}
+		if !e.replace(proc) {
+			_ = proc.cmd.Process.Kill()
+			return false
+		}
+		log.Infof("executor plugin %s: restarted", e.provider)
+		return true
+	}
+}
+
+// replace points the executor at a restarted sidecar. It returns false once Close was called.
+func (e *sidecarExecutor) replace(proc *sidecarProcess) bool {
+	next := newSidecarClient(e.provider, proc.network, proc.address, proc.token)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	select {
+	case <-e.closed:
+		return false
+	default:
+	}
+	e.client.CloseIdleConnections()
+	e.baseURL, e.token, e.client = next.baseURL, next.token, next.client
+	e.cmd, e.exited = proc.cmd, proc.exited
+	return true
+}
+
+// connection returns the address, token and client of the current sidecar process.
+func (e *sidecarExecutor) connection() (baseURL, token string, client *http.Client) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.baseURL, e.token, e.client
+}
+
+func logSidecarOutput(provider string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Debugf("executor plugin %s: %s", provider, scanner.Text())
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Close stops the sidecar process, killing it when it does not exit after an interrupt.
+func (e *sidecarExecutor) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.stopOnce.Do(func() {
+		e.mu.Lock()
+		close(e.closed)
+		cmd, exited := e.cmd, e.exited
+		e.mu.Unlock()
+		if cmd == nil || cmd.Process == nil {
+			return
+		}
+		if errSignal := cmd.Process.Signal(os.Interrupt); errSignal != nil {
+			_ = cmd.Process.Kill()
+			return
+		}
+		select {
+		case <-exited:
+		case <-time.After(sidecarStopTimeout):
+			_ = cmd.Process.Kill()
+		}
+	})
+	return nil
+}
+
+func (e *sidecarExecutor) Identifier() string { return e.provider }
+
+func (e *sidecarExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.call(ctx, pathExecute, auth, req, opts)
+}
+
+func (e *sidecarExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.call(ctx, pathCountTokens, auth, req, opts)
+}
+
+func (e *sidecarExecutor) call(ctx context.Context, path string, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	var out wireResponse
+	if err := e.roundTrip(ctx, path, toWireCall(auth, req, opts), &out); err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	return cliproxyexecutor.Response{Payload: out.Payload, Metadata: out.Metadata}, nil
+}
+
+func (e *sidecarExecutor) ExecuteStream(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	resp, err := e.post(ctx, pathExecuteStream, toWireCall(auth, req, opts))
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer func() { _ = resp.Body.Close() }()
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var line wireChunk
+			if errDecode := decoder.Decode(&line); errDecode != nil {
+				if errDecode != io.EOF && ctx.Err() == nil {
+					out <- cliproxyexecutor.StreamChunk{Err: fmt.Errorf("executor plugin %s: read stream: %w", e.provider, errDecode)}
+				}
+				return
+			}
+			chunk := cliproxyexecutor.StreamChunk{Payload: line.Payload}
+			if line.Error != nil {
+				chunk.Err = line.Error
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (e *sidecarExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	var out wireRefresh
+	if err := e.roundTrip(ctx, pathRefresh, wireRefresh{Auth: auth}, &out); err != nil {
+		return nil, err
+	}
+	if out.Auth == nil {
+		return auth, nil
+	}
+	// Keep the in-memory state that does not cross the process boundary.
+	out.Auth.Storage = auth.Storage
+	out.Auth.Runtime = auth.Runtime
+	out.Auth.FileName = auth.FileName
+	return out.Auth, nil
+}
+
+func (e *sidecarExecutor) HttpRequest(ctx context.Context, auth *coreauth.Auth, req *http.Request) (*http.Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("executor plugin %s: request is nil", e.provider)
+	}
+	call := wireHTTPRequest{Auth: auth, Method: req.Method, URL: req.URL.String(), Header: req.Header}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		call.Body = body
+	}
+	var out wireHTTPResponse
+	if err := e.roundTrip(ctx, pathHTTPRequest, call, &out); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: out.StatusCode,
+		Status:     fmt.Sprintf("%d %s", out.StatusCode, http.StatusText(out.StatusCode)),
+		Header:     out.Header,
+		Body:       io.NopCloser(bytes.NewReader(out.Body)),
+		Request:    req,
+	}, nil
+}
+
+func (e *sidecarExecutor) roundTrip(ctx context.Context, path string, in, out any) error {
+	resp, err := e.post(ctx, path, in)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("executor plugin %s: decode response: %w", e.provider, err)
+	}
+	return nil
+}
+
+// post sends a call and returns the response when the sidecar accepted it, or the error it reported.
+func (e *sidecarExecutor) post(ctx context.Context, path string, in any) (*http.Response, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("executor plugin %s: encode request: %w", e.provider, err)
+	}
+	baseURL, token, client := e.connection()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executor plugin %s: %w", e.provider, err)
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var wire wireError
+	if errDecode := json.NewDecoder(resp.Body).Decode(&wire); errDecode != nil || wire.Error == nil {
+		return nil, &coreauth.Error{Code: "plugin_error", Message: fmt.Sprintf("executor plugin %s returned %s", e.provider, resp.Status), HTTPStatus: resp.StatusCode}
+	}
+	return nil, wire.Error
+}
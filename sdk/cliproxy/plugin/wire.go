@@ -0,0 +1,169 @@
+// Package plugin loads provider executors at runtime, from Go plugins (.so files) or from
+// sidecar processes, so third-party providers can be added without rebuilding the proxy.
+//
+// A sidecar is an executable that calls Serve with its executor. The proxy starts it with a
+// magic cookie in the environment, reads a handshake line from its stdout in the style of
+// hashicorp/go-plugin ("1|1|tcp|127.0.0.1:port|http") and then sends executor calls as JSON
+// over HTTP to that address.
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// Sidecar protocol constants.
+const (
+	// CoreProtocolVersion is the handshake framing version.
+	CoreProtocolVersion = 1
+	// ProtocolVersion is the version of the executor call protocol.
+	ProtocolVersion = 1
+	// MagicCookieKey and MagicCookieValue are set in the sidecar environment so a sidecar
+	// started by hand can tell it is not running under the proxy.
+	MagicCookieKey   = "CLIPROXY_EXECUTOR_PLUGIN"
+	MagicCookieValue = "d4c3b3a0-executor-v1"
+	// TokenEnvKey carries the bearer token the proxy sends with every call.
+	TokenEnvKey = "CLIPROXY_EXECUTOR_PLUGIN_TOKEN"
+)
+
+// Sidecar endpoints.
+const (
+	pathExecute       = "/v1/execute"
+	pathExecuteStream = "/v1/execute-stream"
+	pathCountTokens   = "/v1/count-tokens"
+	pathRefresh       = "/v1/refresh"
+	pathHTTPRequest   = "/v1/http-request"
+)
+
+type wireRequest struct {
+	Model    string         `json:"model,omitempty"`
+	Payload  []byte         `json:"payload,omitempty"`
+	Format   string         `json:"format,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type wireOptions struct {
+	Stream          bool           `json:"stream,omitempty"`
+	Alt             string         `json:"alt,omitempty"`
+	Headers         http.Header    `json:"headers,omitempty"`
+	Query           url.Values     `json:"query,omitempty"`
+	OriginalRequest []byte         `json:"original_request,omitempty"`
+	SourceFormat    string         `json:"source_format,omitempty"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+}
+
+// wireCall is the body of execute, execute-stream and count-tokens calls.
+type wireCall struct {
+	Auth    *coreauth.Auth `json:"auth"`
+	Request wireRequest    `json:"request"`
+	Options wireOptions    `json:"options"`
+}
+
+type wireResponse struct {
+	Payload  []byte         `json:"payload,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// wireChunk is one line of a streamed response.
+type wireChunk struct {
+	Payload []byte          `json:"payload,omitempty"`
+	Error   *coreauth.Error `json:"error,omitempty"`
+}
+
+type wireRefresh struct {
+	Auth *coreauth.Auth `json:"auth"`
+}
+
+type wireHTTPRequest struct {
+	Auth   *coreauth.Auth `json:"auth"`
+	Method string         `json:"method"`
+	URL    string         `json:"url"`
+	Header http.Header    `json:"header,omitempty"`
+	Body   []byte         `json:"body,omitempty"`
+}
+
+type wireHTTPResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+type wireError struct {
+	Error *coreauth.Error `json:"error"`
+}
+
+func toWireCall(auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) wireCall {
+	return wireCall{
+		Auth: auth,
+		Request: wireRequest{
+			Model:    req.Model,
+			Payload:  req.Payload,
+			Format:   req.Format.String(),
+			Metadata: jsonMetadata(req.Metadata),
+		},
+		Options: wireOptions{
+			Stream:          opts.Stream,
+			Alt:             opts.Alt,
+			Headers:         opts.Headers,
+			Query:           opts.Query,
+			OriginalRequest: opts.OriginalRequest,
+			SourceFormat:    opts.SourceFormat.String(),
+			Metadata:        jsonMetadata(opts.Metadata),
+		},
+	}
+}
+
+func (c wireCall) request() cliproxyexecutor.Request {
+	return cliproxyexecutor.Request{
+		Model:    c.Request.Model,
+		Payload:  c.Request.Payload,
+		Format:   sdktranslator.FromString(c.Request.Format),
+		Metadata: c.Request.Metadata,
+	}
+}
+
+func (c wireCall) options() cliproxyexecutor.Options {
+	return cliproxyexecutor.Options{
+		Stream:          c.Options.Stream,
+		Alt:             c.Options.Alt,
+		Headers:         c.Options.Headers,
+		Query:           c.Options.Query,
+		OriginalRequest: c.Options.OriginalRequest,
+		SourceFormat:    sdktranslator.FromString(c.Options.SourceFormat),
+		Metadata:        c.Options.Metadata,
+	}
+}
+
+// jsonMetadata drops the metadata values that cannot cross the process boundary.
+func jsonMetadata(metadata map[string]any) map[string]any {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		if _, err := json.Marshal(value); err == nil {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// wireErrorFrom converts an executor error into its wire form, keeping its status code.
+func wireErrorFrom(err error) *coreauth.Error {
+	var authErr *coreauth.Error
+	if errors.As(err, &authErr) && authErr != nil {
+		return authErr
+	}
+	out := &coreauth.Error{Message: err.Error()}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		out.HTTPStatus = statusErr.StatusCode()
+	}
+	return out
+}
@@ -15,7 +15,10 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/startupreport"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util/wildcard"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/wsrelay"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -60,6 +63,15 @@ type Service struct {
 	// pprofServer manages the optional pprof HTTP debug server.
 	pprofServer *pprofServer
 
+	// leaderElection gates fleet-wide background jobs in multi-instance mode.
+	leaderElection *leaderElection
+	modelDiscovery *modelDiscovery
+	usageReports   *usageReports
+	auditAnchors   *auditAnchors
+
+	// executorPlugins holds the executors loaded from executor-plugins.
+	executorPlugins *executorPlugins
+
 	// serverErr channel for server startup/shutdown errors.
 	serverErr chan error
 
@@ -277,6 +289,7 @@ func (s *Service) applyCoreAuthAddOrUpdate(ctx context.Context, auth *coreauth.A
 		return
 	}
 	auth = auth.Clone()
+	s.checkDuplicateAuth(auth)
 	s.ensureExecutorsForAuth(auth)
 
 	// IMPORTANT: Update coreManager FIRST, before model registration.
@@ -311,6 +324,29 @@ func (s *Service) applyCoreAuthAddOrUpdate(ctx context.Context, auth *coreauth.A
 	s.registerModelsForAuth(auth)
 }
 
+// checkDuplicateAuth warns when auth signs in to the same account as another enabled auth and,
+// when disable-duplicate-auths is set, disables it so the account's quota is not double-counted.
+func (s *Service) checkDuplicateAuth(auth *coreauth.Auth) {
+	if auth.Disabled {
+		return
+	}
+	original, ok := s.coreManager.ActiveDuplicateOf(auth)
+	if !ok {
+		return
+	}
+	s.cfgMu.RLock()
+	disable := s.cfg != nil && s.cfg.DisableDuplicateAuths
+	s.cfgMu.RUnlock()
+	if !disable {
+		log.Warnf("auth %s signs in to the same %s account as %s; both draw from one quota", auth.ID, auth.Provider, original)
+		return
+	}
+	log.Warnf("auth %s disabled: it signs in to the same %s account as %s", auth.ID, auth.Provider, original)
+	auth.Disabled = true
+	auth.Status = coreauth.StatusDisabled
+	auth.StatusMessage = coreauth.StatusMessageDuplicate + original
+}
+
 func (s *Service) applyCoreAuthRemoval(ctx context.Context, id string) {
 	if s == nil || id == "" {
 		return
@@ -326,6 +362,15 @@ func (s *Service) applyCoreAuthRemoval(ctx context.Context, id string) {
 			log.Errorf("failed to disable auth %s: %v", id, err)
 		}
 	}
+	// Re-enable auths that were only disabled as duplicates of the removed one.
+	for _, other := range s.coreManager.List() {
+		if other.Disabled && other.StatusMessage == coreauth.StatusMessageDuplicate+id {
+			other.Disabled = false
+			other.Status = coreauth.StatusActive
+			other.StatusMessage = ""
+			s.applyCoreAuthAddOrUpdate(ctx, other)
+		}
+	}
 }
 
 func (s *Service) applyRetryConfig(cfg *config.Config) {
@@ -336,6 +381,42 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
 }
 
+func (s *Service) applyModelMetadataConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	overrides := make([]registry.ModelMetadataOverride, 0, len(cfg.ModelMetadata))
+	for _, entry := range cfg.ModelMetadata {
+		overrides = append(overrides, registry.ModelMetadataOverride{
+			Pattern:           entry.Model,
+			ContextWindow:     entry.ContextWindow,
+			MaxOutputTokens:   entry.MaxOutputTokens,
+			SupportsTools:     entry.SupportsTools,
+			SupportsVision:    entry.SupportsVision,
+			SupportsReasoning: entry.SupportsReasoning,
+		})
+	}
+	registry.GetGlobalRegistry().SetModelMetadataOverrides(overrides)
+}
+
+func (s *Service) applyThinkingSuffixConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	aliases := make([]thinking.SuffixAlias, 0, len(cfg.ThinkingSuffixes))
+	for _, entry := range cfg.ThinkingSuffixes {
+		aliases = append(aliases, thinking.SuffixAlias{
+			Name:            entry.Name,
+			Providers:       entry.Providers,
+			Thinking:        entry.Thinking,
+			MaxOutputTokens: entry.MaxOutputTokens,
+		})
+	}
+	for _, err := range thinking.SetSuffixAliases(aliases) {
+		log.Warnf("thinking-suffixes: %v", err)
+	}
+}
+
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
 	if a == nil {
 		return "", "", false
@@ -366,6 +447,9 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 	if a.Disabled {
 		return
 	}
+	if s.executorPlugins.Provides(a.Provider) {
+		return
+	}
 	if compatProviderKey, _, isCompat := openAICompatInfoFromAuth(a); isCompat {
 		if compatProviderKey == "" {
 			compatProviderKey = strings.ToLower(strings.TrimSpace(a.Provider))
@@ -398,6 +482,8 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 		s.coreManager.RegisterExecutor(executor.NewQwenExecutor(s.cfg))
 	case "iflow":
 		s.coreManager.RegisterExecutor(executor.NewIFlowExecutor(s.cfg))
+	case "mock":
+		s.coreManager.RegisterExecutor(executor.NewMockExecutor(s.cfg))
 	default:
 		providerKey := strings.ToLower(strings.TrimSpace(a.Provider))
 		if providerKey == "" {
@@ -450,12 +536,19 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	s.applyRetryConfig(s.cfg)
+	s.applyModelMetadataConfig(s.cfg)
+	s.applyThinkingSuffixConfig(s.cfg)
+	executor.SetCodexClientConfig(s.cfg)
+	s.applyModelDiscoveryConfig(s.cfg)
+	s.applyUsageReportsConfig(s.cfg)
+	s.applyAuditChainConfig(s.cfg)
 
 	if s.coreManager != nil {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
 			log.Warnf("failed to load auth store: %v", errLoad)
 		}
 	}
+	s.applyExecutorPluginsConfig(s.cfg)
 
 	tokenResult, err := s.tokenProvider.Load(ctx, s.cfg)
 	if err != nil && !errors.Is(err, context.Canceled) {
@@ -591,7 +684,14 @@ func (s *Service) Run(ctx context.Context) error {
 		}
 
 		s.applyRetryConfig(newCfg)
+		s.applyModelMetadataConfig(newCfg)
+		s.applyThinkingSuffixConfig(newCfg)
+		executor.SetCodexClientConfig(newCfg)
+		s.applyModelDiscoveryConfig(newCfg)
+		s.applyUsageReportsConfig(newCfg)
+		s.applyAuditChainConfig(newCfg)
 		s.applyPprofConfig(newCfg)
+		s.applyLeaderElectionConfig(newCfg)
 		if s.server != nil {
 			s.server.UpdateClients(newCfg)
 		}
@@ -602,6 +702,7 @@ func (s *Service) Run(ctx context.Context) error {
 			s.coreManager.SetConfig(newCfg)
 			s.coreManager.SetOAuthModelAlias(newCfg.OAuthModelAlias)
 		}
+		s.applyExecutorPluginsConfig(newCfg)
 		s.rebindExecutors()
 	}
 
@@ -623,6 +724,9 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 	log.Info("file watcher started for config and auth directory changes")
 
+	s.applyLeaderElectionConfig(s.cfg)
+	startupreport.Record(s.cfg, s.coreManager)
+
 	// Prefer core auth manager auto refresh if available.
 	if s.coreManager != nil {
 		interval := 15 * time.Minute
@@ -666,6 +770,10 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
 		}
+		s.shutdownLeaderElection()
+		s.shutdownModelDiscovery()
+		s.shutdownUsageReports()
+		s.shutdownAuditAnchors()
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
 				log.Errorf("failed to stop file watcher: %v", err)
@@ -704,6 +812,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 				}
 			}
 		}
+		s.shutdownExecutorPlugins()
 
 		usage.StopDefault()
 	})
@@ -770,6 +879,8 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 		if entry := s.resolveConfigGeminiKey(a); entry != nil {
 			if len(entry.Models) > 0 {
 				models = buildGeminiConfigModels(entry)
+			} else if entry.DiscoverModels && authKind == "apikey" {
+				models = restrictToDiscoveredModels(models, s.discoverProviderModels(a, executor.FetchGeminiAPIKeyModels))
 			}
 			if authKind == "apikey" {
 				excluded = entry.ExcludedModels
@@ -801,6 +912,8 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 		if entry := s.resolveConfigClaudeKey(a); entry != nil {
 			if len(entry.Models) > 0 {
 				models = buildClaudeConfigModels(entry)
+			} else if entry.DiscoverModels && authKind == "apikey" {
+				models = restrictToDiscoveredModels(models, s.discoverProviderModels(a, executor.FetchClaudeAPIKeyModels))
 			}
 			if authKind == "apikey" {
 				excluded = entry.ExcludedModels
@@ -830,6 +943,8 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 	case "iflow":
 		models = registry.GetIFlowModels()
 		models = applyExcludedModels(models, excluded)
+	case "mock":
+		models = s.buildMockModels(a)
 	default:
 		// Handle OpenAI-compatibility providers by name using config
 		if s.cfg != nil {
@@ -892,6 +1007,9 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 							UserDefined: true,
 						})
 					}
+					if compat.DiscoverModels {
+						ms = mergeDiscoveredCompatModels(ms, compat, s.discoverOpenAICompatModels(a, compat.Name))
+					}
 					// Register and return
 					if len(ms) > 0 {
 						if providerKey == "" {
@@ -1086,7 +1204,7 @@ func applyExcludedModels(models []*ModelInfo, excluded []string) []*ModelInfo {
 		modelID := strings.ToLower(strings.TrimSpace(model.ID))
 		blocked := false
 		for _, pattern := range patterns {
-			if matchWildcard(pattern, modelID) {
+			if wildcard.Match(pattern, modelID) {
 				blocked = true
 				break
 			}
@@ -1140,50 +1258,6 @@ func applyModelPrefixes(models []*ModelInfo, prefix string, forceModelPrefix boo
 	return out
 }
 
-// matchWildcard performs case-insensitive wildcard matching where '*' matches any substring.
-func matchWildcard(pattern, value string) bool {
-	if pattern == "" {
-		return false
-	}
-
-	// Fast path for exact match (no wildcard present).
-	if !strings.Contains(pattern, "*") {
-		return pattern == value
-	}
-
-	parts := strings.Split(pattern, "*")
-	// Handle prefix.
-	if prefix := parts[0]; prefix != "" {
-		if !strings.HasPrefix(value, prefix) {
-			return false
-		}
-		value = value[len(prefix):]
-	}
-
-	// Handle suffix.
-	if suffix := parts[len(parts)-1]; suffix != "" {
-		if !strings.HasSuffix(value, suffix) {
-			return false
-		}
-		value = value[:len(value)-len(suffix)]
-	}
-
-	// Handle middle segments in order.
-	for i := 1; i < len(parts)-1; i++ {
-		segment := parts[i]
-		if segment == "" {
-			continue
-		}
-		idx := strings.Index(value, segment)
-		if idx < 0 {
-			return false
-		}
-		value = value[idx+len(segment):]
-	}
-
-	return true
-}
-
 type modelEntry interface {
 	GetName() string
 	GetAlias() string
@@ -1255,6 +1329,35 @@ func buildClaudeConfigModels(entry *config.ClaudeKey) []*ModelInfo {
 	return buildConfigModels(entry.Models, "anthropic", "claude")
 }
 
+// buildMockModels lists the models of the mock provider behind auth.
+func (s *Service) buildMockModels(auth *coreauth.Auth) []*ModelInfo {
+	if auth == nil || auth.Attributes == nil || s.cfg == nil {
+		return nil
+	}
+	name := auth.Attributes["mock_name"]
+	for i := range s.cfg.MockProviders {
+		provider := &s.cfg.MockProviders[i]
+		if provider.Name != name {
+			continue
+		}
+		out := make([]*ModelInfo, 0, len(provider.Models))
+		now := time.Now().Unix()
+		for _, model := range provider.Models {
+			out = append(out, &ModelInfo{
+				ID:          model.Name,
+				Object:      "model",
+				Created:     now,
+				OwnedBy:     provider.Name,
+				Type:        "mock",
+				DisplayName: model.Name,
+				UserDefined: true,
+			})
+		}
+		return out
+	}
+	return nil
+}
+
 func buildCodexConfigModels(entry *config.CodexKey) []*ModelInfo {
 	if entry == nil {
 		return nil
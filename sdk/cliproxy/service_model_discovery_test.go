@@ -0,0 +1,118 @@
+package cliproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestRegisterModelsForAuth_OpenAICompatDiscoversUpstreamModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"id":"upstream-large","context_length":131072},{"id":"upstream-small"},{"id":"configured-name"}]}`))
+	}))
+	defer server.Close()
+
+	svc := &Service{cfg: &config.Config{
+		OpenAICompatibility: []config.OpenAICompatibility{{
+			Name:           "discovery-test",
+			BaseURL:        server.URL + "/v1",
+			DiscoverModels: true,
+			Models:         []config.OpenAICompatibilityModel{{Name: "configured-name", Alias: "configured-alias"}},
+		}},
+	}}
+	auth := &coreauth.Auth{
+		ID:       "discovery-test-auth",
+		Provider: "discovery-test",
+		Attributes: map[string]string{
+			"compat_name":  "discovery-test",
+			"provider_key": "discovery-test",
+			"base_url":     server.URL + "/v1",
+			"api_key":      "sk-test",
+		},
+	}
+
+	reg := registry.GetGlobalRegistry()
+	defer reg.UnregisterClient(auth.ID)
+
+	svc.registerModelsForAuth(auth)
+
+	got := make(map[string]*ModelInfo)
+	for _, m := range reg.GetModelsForClient(auth.ID) {
+		got[m.ID] = m
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected configured alias plus two discovered models, got %v", got)
+	}
+	if _, ok := got["configured-name"]; ok {
+		t.Fatalf("discovered model already covered by configured alias must not be duplicated")
+	}
+	if m := got["upstream-large"]; m == nil || m.ContextLength != 131072 {
+		t.Fatalf("expected upstream-large with context length, got %+v", m)
+	}
+}
+
+func TestRegisterModelsForAuth_ClaudeKeyDiscoversAccessibleModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-ant-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after_id") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-5-20250929"}],"has_more":true,"last_id":"claude-sonnet-4-5-20250929"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"claude-upstream-new","display_name":"Claude Upstream New"}],"has_more":false}`))
+	}))
+	defer server.Close()
+
+	svc := &Service{cfg: &config.Config{
+		ClaudeKey: []config.ClaudeKey{{APIKey: "sk-ant-test", BaseURL: server.URL, DiscoverModels: true}},
+	}}
+	auth := &coreauth.Auth{
+		ID:         "claude-discovery-test-auth",
+		Provider:   "claude",
+		Attributes: map[string]string{"auth_kind": "apikey", "api_key": "sk-ant-test", "base_url": server.URL},
+	}
+
+	reg := registry.GetGlobalRegistry()
+	defer reg.UnregisterClient(auth.ID)
+
+	svc.registerModelsForAuth(auth)
+
+	got := make(map[string]*ModelInfo)
+	for _, m := range reg.GetModelsForClient(auth.ID) {
+		got[m.ID] = m
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected only the listed models, got %v", got)
+	}
+	if m := got["claude-sonnet-4-5-20250929"]; m == nil || m.Thinking == nil {
+		t.Fatalf("expected built-in metadata for a listed model, got %+v", m)
+	}
+	if m := got["claude-upstream-new"]; m == nil || m.DisplayName != "Claude Upstream New" {
+		t.Fatalf("expected unknown listed model to be added, got %+v", m)
+	}
+}
+
+func TestRestrictToDiscoveredModelsKeepsBuiltinOnFailure(t *testing.T) {
+	builtin := registry.GetGeminiModels()
+	if got := restrictToDiscoveredModels(builtin, nil); len(got) != len(builtin) {
+		t.Fatalf("expected the built-in list when discovery returned nothing, got %d models", len(got))
+	}
+}
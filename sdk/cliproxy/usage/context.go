@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"context"
+	"sync"
+)
+
+type detailRecorderContextKey struct{}
+
+// detailRecorder accumulates the token usage observed for one client request.
+type detailRecorder struct {
+	mu     sync.Mutex
+	detail Detail
+	seen   bool
+}
+
+// WithDetailRecorder returns a context in which executors record the token usage they observe,
+// for later retrieval with DetailFromContext.
+func WithDetailRecorder(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(detailRecorderContextKey{}).(*detailRecorder); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, detailRecorderContextKey{}, &detailRecorder{})
+}
+
+// ObserveDetail merges detail into the recorder carried by ctx, keeping the largest value seen
+// for each counter, since streams report usage cumulatively and sometimes split it across events.
+func ObserveDetail(ctx context.Context, detail Detail) {
+	if ctx == nil {
+		return
+	}
+	recorder, ok := ctx.Value(detailRecorderContextKey{}).(*detailRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.detail.InputTokens = max(recorder.detail.InputTokens, detail.InputTokens)
+	recorder.detail.OutputTokens = max(recorder.detail.OutputTokens, detail.OutputTokens)
+	recorder.detail.ReasoningTokens = max(recorder.detail.ReasoningTokens, detail.ReasoningTokens)
+	recorder.detail.CachedTokens = max(recorder.detail.CachedTokens, detail.CachedTokens)
+	recorder.detail.TotalTokens = max(recorder.detail.TotalTokens, detail.TotalTokens)
+	recorder.seen = true
+}
+
+// DetailFromContext returns the token usage recorded for the request so far.
+func DetailFromContext(ctx context.Context) (Detail, bool) {
+	if ctx == nil {
+		return Detail{}, false
+	}
+	recorder, ok := ctx.Value(detailRecorderContextKey{}).(*detailRecorder)
+	if !ok {
+		return Detail{}, false
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.detail, recorder.seen
+}
@@ -10,28 +10,34 @@ import (
 
 // Record contains the usage statistics captured for a single provider request.
 type Record struct {
-	Provider    string
-	Model       string
-	APIKey      string
-	RequestID   string
-	AuthID      string
-	AuthIndex   string
-	SessionID   string
-	Source      string
-	RequestedAt time.Time
-	Failed      bool
-	StatusCode  int
-	DurationMs  int64
-	Detail      Detail
+	Provider  string
+	Model     string
+	APIKey    string
+	RequestID string
+	AuthID    string
+	AuthIndex string
+	SessionID string
+	Source    string
+	// SystemFingerprint is the upstream system_fingerprint, when the provider reports one.
+	SystemFingerprint string
+	RequestedAt       time.Time
+	Failed            bool
+	StatusCode        int
+	DurationMs        int64
+	Detail            Detail
 }
 
 // Detail holds the token usage breakdown.
 type Detail struct {
-	InputTokens     int64
-	OutputTokens    int64
+	// InputTokens counts every input token, including those served from the prompt cache.
+	InputTokens  int64
+	OutputTokens int64
+	// ReasoningTokens counts thinking tokens, which some providers include in OutputTokens.
 	ReasoningTokens int64
-	CachedTokens    int64
-	TotalTokens     int64
+	// CachedTokens is the part of InputTokens read from the prompt cache, usually billed at a
+	// lower rate.
+	CachedTokens int64
+	TotalTokens  int64
 }
 
 // Plugin consumes usage records emitted by the proxy runtime.
@@ -0,0 +1,233 @@
+package cliproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultDailyReportSchedule  = "0 0 * * *"
+	defaultWeeklyReportSchedule = "0 0 * * 1"
+	usageReportTimeout          = 30 * time.Second
+)
+
+// usageReports runs the scheduled usage report jobs of the current configuration.
+type usageReports struct {
+	cfg    config.UsageReportsConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+	// current is the latest configuration; jobs read pricing and proxy settings from it.
+	current atomic.Pointer[config.Config]
+	// isLeader reports whether this instance sends the reports of the fleet.
+	isLeader func() bool
+}
+
+func (s *Service) applyUsageReportsConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	if s.usageReports == nil {
+		s.usageReports = &usageReports{isLeader: s.IsLeader}
+	}
+	s.usageReports.Apply(cfg)
+}
+
+func (s *Service) shutdownUsageReports() {
+	if s == nil || s.usageReports == nil {
+		return
+	}
+	s.usageReports.Stop()
+}
+
+// Apply restarts the report jobs when the report configuration changed.
+func (r *usageReports) Apply(cfg *config.Config) {
+	r.current.Store(cfg)
+	if r.cancel != nil && reflect.DeepEqual(r.cfg, cfg.UsageReports) {
+		return
+	}
+	r.Stop()
+	r.cfg = cfg.UsageReports
+	if len(cfg.UsageReports.Reports) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	r.cancel = cancel
+	r.done = done
+	jobs := 0
+	finished := make(chan struct{}, len(cfg.UsageReports.Reports))
+	for _, report := range cfg.UsageReports.Reports {
+		spec := report.Schedule
+		if spec == "" {
+			spec = defaultDailyReportSchedule
+			if report.Period == config.UsageReportWeekly {
+				spec = defaultWeeklyReportSchedule
+			}
+		}
+		schedule, err := usage.ParseReportSchedule(spec)
+		if err != nil {
+			log.Warnf("usage report %q disabled: %v", report.Name, err)
+			continue
+		}
+		jobs++
+		go func(report config.UsageReport) {
+			defer func() { finished <- struct{}{} }()
+			r.run(ctx, report, schedule)
+		}(report)
+	}
+	log.Infof("usage reports: %d scheduled", jobs)
+	go func() {
+		defer close(done)
+		for i := 0; i < jobs; i++ {
+			<-finished
+		}
+	}()
+}
+
+func (r *usageReports) Stop() {
+	if r == nil || r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+	r.done = nil
+}
+
+// run sends report at every time matching schedule until ctx is canceled.
+func (r *usageReports) run(ctx context.Context, report config.UsageReport, schedule usage.ReportSchedule) {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if r.isLeader != nil && !r.isLeader() {
+			continue
+		}
+		start := next.AddDate(0, 0, -1)
+		if report.Period == config.UsageReportWeekly {
+			start = next.AddDate(0, 0, -7)
+		}
+		cfg := r.current.Load()
+		snapshot := usage.GetRequestStatistics().Snapshot()
+		built := usage.BuildReport(report.Name, snapshot, start, next, cfg.CostCeiling.Pricing)
+		if err := sendUsageReport(ctx, cfg, report, built); err != nil {
+			log.Warnf("usage report %q: %v", report.Name, err)
+		}
+	}
+}
+
+// sendUsageReport delivers built to the webhook and email recipients of report.
+func sendUsageReport(ctx context.Context, cfg *config.Config, report config.UsageReport, built usage.Report) error {
+	var errs []string
+	if report.WebhookURL != "" {
+		if err := postUsageReport(ctx, cfg, report, built); err != nil {
+			errs = append(errs, "webhook: "+err.Error())
+		}
+	}
+	if len(report.EmailTo) > 0 {
+		if err := mailUsageReport(cfg.UsageReports.SMTP, report, built); err != nil {
+			errs = append(errs, "email: "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func postUsageReport(ctx context.Context, cfg *config.Config, report config.UsageReport, built usage.Report) error {
+	body, err := json.Marshal(map[string]any{
+		"type":   "usage.report",
+		"report": built,
+		"text":   built.Text(),
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, usageReportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, report.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", "usage.report")
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if report.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+webhook.Sign(report.Secret, timestamp, body))
+	}
+	resp, err := util.SetProxy(&cfg.SDKConfig, &http.Client{}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("usage report: close response body error: %v", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func mailUsageReport(smtpCfg config.SMTPConfig, report config.UsageReport, built usage.Report) error {
+	if smtpCfg.Host == "" || smtpCfg.From == "" {
+		return fmt.Errorf("smtp host and from address are required")
+	}
+	sender, err := mail.ParseAddress(smtpCfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	port := smtpCfg.Port
+	if port <= 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(smtpCfg.Host, strconv.Itoa(port)), auth, sender.Address, report.EmailTo, usageReportMessage(smtpCfg.From, report.EmailTo, built))
+}
+
+// usageReportMessage builds the plain text email carrying built.
+func usageReportMessage(from string, to []string, built usage.Report) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s (%s)\r\n", built.Name, built.End.Format("2006-01-02"))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(built.Text(), "\n", "\r\n"))
+	return msg.Bytes()
+}
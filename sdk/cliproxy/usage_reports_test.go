@@ -0,0 +1,62 @@
+package cliproxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/webhook"
+)
+
+func TestPostUsageReportSignsPayload(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	report := config.UsageReport{Name: "daily usage", WebhookURL: server.URL, Secret: "s"}
+	built := usage.Report{Name: "daily usage", End: time.Now(), Totals: usage.ReportTotals{Requests: 3}}
+	if err := sendUsageReport(context.Background(), &config.Config{}, report, built); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := "sha256=" + webhook.Sign("s", gotHeader.Get("X-Webhook-Timestamp"), gotBody)
+	if gotHeader.Get("X-Webhook-Signature") != want {
+		t.Fatalf("signature = %q, want %q", gotHeader.Get("X-Webhook-Signature"), want)
+	}
+	var payload struct {
+		Type   string       `json:"type"`
+		Report usage.Report `json:"report"`
+		Text   string       `json:"text"`
+	}
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Type != "usage.report" || payload.Report.Totals.Requests != 3 || !strings.Contains(payload.Text, "3 requests") {
+		t.Fatalf("payload = %+v", payload)
+	}
+}
+
+func TestUsageReportMessageHeaders(t *testing.T) {
+	built := usage.Report{Name: "weekly usage", End: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)}
+	msg := string(usageReportMessage("Proxy <r@example.com>", []string{"a@example.com", "b@example.com"}, built))
+	for _, want := range []string{"To: a@example.com, b@example.com\r\n", "Subject: weekly usage (2026-03-09)\r\n", "\r\n\r\nweekly usage:"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("message missing %q:\n%s", want, msg)
+		}
+	}
+	if err := mailUsageReport(config.SMTPConfig{}, config.UsageReport{EmailTo: []string{"a@example.com"}}, built); err == nil {
+		t.Fatal("expected an error without SMTP settings")
+	}
+}
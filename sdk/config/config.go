@@ -13,7 +13,57 @@ type AccessProvider = internalconfig.AccessProvider
 type Config = internalconfig.Config
 
 type StreamingConfig = internalconfig.StreamingConfig
+type ErrorResponseConfig = internalconfig.ErrorResponseConfig
+type ResponseRule = internalconfig.ResponseRule
+type RequestScript = internalconfig.RequestScript
+type ReloginNoticeConfig = internalconfig.ReloginNoticeConfig
+type ExecutorPluginConfig = internalconfig.ExecutorPluginConfig
+type RefusalFallbackConfig = internalconfig.RefusalFallbackConfig
+type RefusalFallbackRule = internalconfig.RefusalFallbackRule
+type ContextOverflowConfig = internalconfig.ContextOverflowConfig
+type ContextOverflowSibling = internalconfig.ContextOverflowSibling
+type PromptCompressionConfig = internalconfig.PromptCompressionConfig
+type SystemPromptDedupConfig = internalconfig.SystemPromptDedupConfig
+type ModerationConfig = internalconfig.ModerationConfig
+type MCPConfig = internalconfig.MCPConfig
+type MCPToolsConfig = internalconfig.MCPToolsConfig
+type MCPServer = internalconfig.MCPServer
+type WebSearchConfig = internalconfig.WebSearchConfig
+type CodeExecutionConfig = internalconfig.CodeExecutionConfig
+type JSONValidationConfig = internalconfig.JSONValidationConfig
+type CostCeilingConfig = internalconfig.CostCeilingConfig
+type ModelPricing = internalconfig.ModelPricing
+type TokenBudgetConfig = internalconfig.TokenBudgetConfig
+type UsageAnomalyConfig = internalconfig.UsageAnomalyConfig
+type RepeatedPromptConfig = internalconfig.RepeatedPromptConfig
+type RepeatedPromptKeyConfig = internalconfig.RepeatedPromptKeyConfig
+type MemoryGuardConfig = internalconfig.MemoryGuardConfig
+type ModelCatalog = internalconfig.ModelCatalog
+type ModelCatalogEntry = internalconfig.ModelCatalogEntry
+type ModelMetadataEntry = internalconfig.ModelMetadataEntry
+type ThinkingSuffix = internalconfig.ThinkingSuffix
+type CodexClientConfig = internalconfig.CodexClientConfig
+type ModelDiscoveryConfig = internalconfig.ModelDiscoveryConfig
+type RequestTimeouts = internalconfig.RequestTimeouts
+type RequestTimeoutConfig = internalconfig.RequestTimeoutConfig
+type ProviderRequestTimeouts = internalconfig.ProviderRequestTimeouts
+type TransportTuning = internalconfig.TransportTuning
+type UpstreamTransportConfig = internalconfig.UpstreamTransportConfig
+type HedgingConfig = internalconfig.HedgingConfig
+type WebhookConfig = internalconfig.WebhookConfig
+type APIKeyMetadata = internalconfig.APIKeyMetadata
+type ShareLink = internalconfig.ShareLink
+type ProxyHostOverride = internalconfig.ProxyHostOverride
+type TLSPin = internalconfig.TLSPin
+type NotifiersConfig = internalconfig.NotifiersConfig
+type TelegramNotifier = internalconfig.TelegramNotifier
+type DiscordNotifier = internalconfig.DiscordNotifier
+type UsageReportsConfig = internalconfig.UsageReportsConfig
+type AuditChainConfig = internalconfig.AuditChainConfig
+type UsageReport = internalconfig.UsageReport
+type SMTPConfig = internalconfig.SMTPConfig
 type TLSConfig = internalconfig.TLSConfig
+type LeaderElectionConfig = internalconfig.LeaderElectionConfig
 type RemoteManagement = internalconfig.RemoteManagement
 type AmpCode = internalconfig.AmpCode
 type OAuthModelAlias = internalconfig.OAuthModelAlias
@@ -21,6 +71,12 @@ type PayloadConfig = internalconfig.PayloadConfig
 type PayloadRule = internalconfig.PayloadRule
 type PayloadFilterRule = internalconfig.PayloadFilterRule
 type PayloadModelRule = internalconfig.PayloadModelRule
+type PayloadTransformRule = internalconfig.PayloadTransformRule
+type PayloadCondition = internalconfig.PayloadCondition
+type PayloadProfilesConfig = internalconfig.PayloadProfilesConfig
+type PayloadProfile = internalconfig.PayloadProfile
+type PayloadProfileBinding = internalconfig.PayloadProfileBinding
+type ClientKeyGroup = internalconfig.ClientKeyGroup
 
 type GeminiKey = internalconfig.GeminiKey
 type CodexKey = internalconfig.CodexKey
@@ -30,15 +86,24 @@ type VertexCompatModel = internalconfig.VertexCompatModel
 type OpenAICompatibility = internalconfig.OpenAICompatibility
 type OpenAICompatibilityAPIKey = internalconfig.OpenAICompatibilityAPIKey
 type OpenAICompatibilityModel = internalconfig.OpenAICompatibilityModel
+type MockProvider = internalconfig.MockProvider
+type MockModel = internalconfig.MockModel
 
 type TLS = internalconfig.TLSConfig
 
 const (
 	AccessProviderTypeConfigAPIKey = internalconfig.AccessProviderTypeConfigAPIKey
 	DefaultAccessProviderName      = internalconfig.DefaultAccessProviderName
+	AccessProviderTypeShareLink    = internalconfig.AccessProviderTypeShareLink
+	ProxyDirect                    = internalconfig.ProxyDirect
 	DefaultPanelGitHubRepository   = internalconfig.DefaultPanelGitHubRepository
+	ExecutorPluginSharedObject     = internalconfig.ExecutorPluginSharedObject
+	ExecutorPluginSidecar          = internalconfig.ExecutorPluginSidecar
 )
 
+// ErrConfigReadOnly is returned by SaveConfigPreserveComments for configs in read-only mode.
+var ErrConfigReadOnly = internalconfig.ErrConfigReadOnly
+
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
 	return internalconfig.MakeInlineAPIKeyProvider(keys)
 }
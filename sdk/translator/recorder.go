@@ -0,0 +1,62 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+)
+
+// ResponseRecord describes one response translation performed under a context carrying a
+// recorder attached with WithResponseRecorder.
+type ResponseRecord struct {
+	// From is the upstream format the response was translated from.
+	From Format
+	// To is the client format the response was translated to.
+	To Format
+	// Model is the model name passed to the translator.
+	Model string
+	// OriginalRequest and Request are the client request and the translated upstream request.
+	OriginalRequest []byte
+	Request         []byte
+	// Response is the upstream chunk or body that was translated.
+	Response []byte
+	// Output holds the translated chunks, or the single translated body for non-streaming calls.
+	Output []string
+	// Stream reports whether the streaming translator was used.
+	Stream bool
+	// Alt is the "alt" context value some translators read, if any.
+	Alt string
+	// State is the translator state pointer; calls sharing it belong to the same response.
+	State *any
+}
+
+type responseRecorderKey struct{}
+
+// WithResponseRecorder returns a context under which every TranslateStream and
+// TranslateNonStream call is reported to record after it completes. Recorders already
+// attached to ctx keep receiving records.
+func WithResponseRecorder(ctx context.Context, record func(ResponseRecord)) context.Context {
+	if parent, ok := ctx.Value(responseRecorderKey{}).(func(ResponseRecord)); ok && parent != nil {
+		next := record
+		record = func(rec ResponseRecord) {
+			parent(rec)
+			next(rec)
+		}
+	}
+	return context.WithValue(ctx, responseRecorderKey{}, record)
+}
+
+func recordResponse(ctx context.Context, rec ResponseRecord) {
+	if ctx == nil {
+		return
+	}
+	record, ok := ctx.Value(responseRecorderKey{}).(func(ResponseRecord))
+	if !ok || record == nil {
+		return
+	}
+	rec.Alt, _ = ctx.Value("alt").(string)
+	rec.OriginalRequest = bytes.Clone(rec.OriginalRequest)
+	rec.Request = bytes.Clone(rec.Request)
+	rec.Response = bytes.Clone(rec.Response)
+	rec.Output = append([]string(nil), rec.Output...)
+	record(rec)
+}
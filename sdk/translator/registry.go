@@ -52,6 +52,19 @@ func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byt
 	return rawJSON
 }
 
+// HasRequestTransformer indicates whether a request translator exists.
+func (r *Registry) HasRequestTransformer(from, to Format) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if byTarget, ok := r.requests[from]; ok {
+		if fn, isOk := byTarget[to]; isOk && fn != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // HasResponseTransformer indicates whether a response translator exists.
 func (r *Registry) HasResponseTransformer(from, to Format) bool {
 	r.mu.RLock()
@@ -67,6 +80,12 @@ func (r *Registry) HasResponseTransformer(from, to Format) bool {
 
 // TranslateStream applies the registered streaming response translator.
 func (r *Registry) TranslateStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
+	out := r.translateStream(ctx, from, to, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+	recordResponse(ctx, ResponseRecord{From: from, To: to, Model: model, OriginalRequest: originalRequestRawJSON, Request: requestRawJSON, Response: rawJSON, Output: out, Stream: true, State: param})
+	return out
+}
+
+func (r *Registry) translateStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -80,6 +99,12 @@ func (r *Registry) TranslateStream(ctx context.Context, from, to Format, model s
 
 // TranslateNonStream applies the registered non-stream response translator.
 func (r *Registry) TranslateNonStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) string {
+	out := r.translateNonStream(ctx, from, to, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+	recordResponse(ctx, ResponseRecord{From: from, To: to, Model: model, OriginalRequest: originalRequestRawJSON, Request: requestRawJSON, Response: rawJSON, Output: []string{out}, State: param})
+	return out
+}
+
+func (r *Registry) translateNonStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -121,6 +146,11 @@ func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
 }
 
+// HasRequestTransformer inspects the default registry.
+func HasRequestTransformer(from, to Format) bool {
+	return defaultRegistry.HasRequestTransformer(from, to)
+}
+
 // HasResponseTransformer inspects the default registry.
 func HasResponseTransformer(from, to Format) bool {
 	return defaultRegistry.HasResponseTransformer(from, to)
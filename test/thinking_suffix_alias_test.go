@@ -0,0 +1,57 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestThinkingSuffixAliases(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	uid := fmt.Sprintf("thinking-suffix-alias-%d", time.Now().UnixNano())
+	reg.RegisterClient(uid, "test", getTestModels())
+	defer reg.UnregisterClient(uid)
+
+	errs := thinking.SetSuffixAliases([]thinking.SuffixAlias{
+		{Name: "max", Providers: []string{"claude"}, Thinking: "8192", MaxOutputTokens: 100000},
+		{Name: "max", Providers: []string{"codex"}, Thinking: "high", MaxOutputTokens: 100000},
+		{Name: "high", Thinking: "low"},
+		{Name: "turbo", Providers: []string{"unknown"}, Thinking: "low"},
+		{Name: "ultra", Thinking: "ultra"},
+	})
+	defer thinking.SetSuffixAliases(nil)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 validation errors, got %v", errs)
+	}
+
+	claudeBody := sdktranslator.TranslateRequest(sdktranslator.FromString("claude"), sdktranslator.FromString("claude"), "claude-budget-model",
+		[]byte(`{"model":"claude-budget-model","max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`), true)
+	out, err := thinking.ApplyThinking(claudeBody, "claude-budget-model(max)", "claude", "claude", "claude")
+	if err != nil {
+		t.Fatalf("claude: %v", err)
+	}
+	if got := gjson.GetBytes(out, "thinking.budget_tokens").Int(); got != 8192 {
+		t.Fatalf("claude budget = %d, body=%s", got, out)
+	}
+	if got := gjson.GetBytes(out, "max_tokens").Int(); got != 100000 {
+		t.Fatalf("claude max_tokens = %d, body=%s", got, out)
+	}
+
+	codexBody := sdktranslator.TranslateRequest(sdktranslator.FromString("openai"), sdktranslator.FromString("codex"), "level-model",
+		[]byte(`{"model":"level-model","messages":[{"role":"user","content":"hi"}]}`), true)
+	out, err = thinking.ApplyThinking(codexBody, "level-model(max)", "openai", "codex", "codex")
+	if err != nil {
+		t.Fatalf("codex: %v", err)
+	}
+	if got := gjson.GetBytes(out, "reasoning.effort").String(); got != "high" {
+		t.Fatalf("codex effort = %q, body=%s", got, out)
+	}
+	if gjson.GetBytes(out, "max_output_tokens").Exists() {
+		t.Fatalf("codex request gained an output limit, body=%s", out)
+	}
+}